@@ -35,9 +35,12 @@ package scanner
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"go.temporal.io/sdk/activity"
@@ -58,8 +61,78 @@ import (
 //
 // But in our Python version we used standalone functions, which is also fine.
 // The Go SDK docs recommend the struct pattern for anything with dependencies.
+// The worker builds it with NewActivities, which tunes the HTTP client;
+// tests fill in the fields directly.
 type Activities struct {
 	HTTPClient *http.Client
+
+	// BaseURL is the GitHub REST API root. Empty means DefaultGitHubAPI;
+	// tests point it at an httptest.Server.
+	BaseURL string
+
+	// BlobStore holds offloaded result chunks (see StoreResults) and each
+	// org's scan history. Optional: without it results stay inline.
+	BlobStore BlobStore
+
+	// GitLabURL is the GitLab REST API root for ScanInput.Provider
+	// "gitlab". Empty means DefaultGitLabAPI.
+	GitLabURL string
+
+	// APIUsage counts API requests per scan run and enforces
+	// ScanInput.MaxAPIRequests. Optional; its Interceptor must be
+	// registered on the worker too.
+	APIUsage *APIUsageTracker
+
+	// Secrets supplies the GitHub token for scans that were started
+	// without one. Optional; see secrets.go.
+	Secrets SecretSource
+
+	// Credentials resolves the Reference of a scan's Credential. Optional;
+	// see credential.go.
+	Credentials CredentialSource
+
+	// HEC is the Splunk HTTP Event Collector ForwardFindings sends findings
+	// to. Optional; see forward.go.
+	HEC *HECConfig
+
+	// Datadog is where EmitComplianceMetrics sends the scan's metrics.
+	// Optional; see metrics.go.
+	Datadog *DatadogConfig
+
+	// Jira is where CreateJiraIssues files remediation issues. Optional;
+	// see jira.go.
+	Jira *JiraConfig
+
+	// PagerDuty is the service TriggerPagerDuty pages when compliance
+	// regresses. Optional; see pagerduty.go.
+	PagerDuty *PagerDutyConfig
+
+	// LatestReports keeps each org's last completed report for the
+	// worker's /metrics. Optional; see prometheus.go.
+	LatestReports *LatestReports
+
+	// AuditLog records each scan's start and end. Optional; see audit.go.
+	AuditLog AuditLog
+
+	// RateLimiter paces GitHub requests by their token's remaining budget.
+	// Optional; see ratelimiter.go.
+	RateLimiter *RateLimiter
+
+	// OrgConfigs are the per-org scan defaults ResolveScanConfig applies.
+	// Optional; see orgconfig.go.
+	OrgConfigs *OrgConfigRegistry
+}
+
+// DefaultGitHubAPI is the public GitHub REST API root.
+const DefaultGitHubAPI = "https://api.github.com"
+
+// apiURL joins a path onto BaseURL (or DefaultGitHubAPI).
+func (a *Activities) apiURL(format string, args ...interface{}) string {
+	base := a.BaseURL
+	if base == "" {
+		base = DefaultGitHubAPI
+	}
+	return strings.TrimRight(base, "/") + fmt.Sprintf(format, args...)
 }
 
 // FetchOrgRepos fetches all repositories for a GitHub organization.
@@ -76,109 +149,189 @@ type Activities struct {
 //
 // KEY DIFFERENCES:
 //
-// 1. CONTEXT: Go activities receive context.Context as the first arg (required).
-//    Python activities get an implicit context via contextvars.
+//  1. CONTEXT: Go activities receive context.Context as the first arg (required).
+//     Python activities get an implicit context via contextvars.
+//
+//  2. RETURN TYPE: Go returns ([]RepoInfo, error). Python returns list[RepoInfo]
+//     and raises exceptions on failure. Go's explicit error return means every
+//     caller must handle the error — no silent exception swallowing.
 //
-// 2. RETURN TYPE: Go returns ([]RepoInfo, error). Python returns list[RepoInfo]
-//    and raises exceptions on failure. Go's explicit error return means every
-//    caller must handle the error — no silent exception swallowing.
+//  3. HEARTBEAT: Both SDKs heartbeat the same way conceptually.
+//     Go:     activity.RecordHeartbeat(ctx, fmt.Sprintf("page %d", page))
+//     Python: activity.heartbeat(f"Fetching page {page}")
 //
-// 3. HEARTBEAT: Both SDKs heartbeat the same way conceptually.
-//    Go:     activity.RecordHeartbeat(ctx, fmt.Sprintf("page %d", page))
-//    Python: activity.heartbeat(f"Fetching page {page}")
+//  4. NON-RETRYABLE ERRORS: In Python, we list types in the RetryPolicy:
+//     non_retryable_error_types=["ValueError"]
+//     In Go, we wrap errors with temporal.NewNonRetryableApplicationError().
+//     This gives finer control — you decide at the point of failure, not globally.
 //
-// 4. NON-RETRYABLE ERRORS: In Python, we list types in the RetryPolicy:
-//        non_retryable_error_types=["ValueError"]
-//    In Go, we wrap errors with temporal.NewNonRetryableApplicationError().
-//    This gives finer control — you decide at the point of failure, not globally.
+// The listing comes from input.Provider (GitHub by default). New scans of
+// a GitHub org list it a page per FetchOrgReposPage instead (see
+// repopages.go); this activity stays for GitLab and for runs recorded
+// before.
 func (a *Activities) FetchOrgRepos(ctx context.Context, input ScanInput) ([]RepoInfo, error) {
-	var repos []RepoInfo
-	page := 1
+	p, err := a.provider(input.Provider)
+	if err != nil {
+		return nil, err
+	}
+	return p.ListRepos(ctx, input)
+}
 
-	for {
+// fetchGitHubRepos is FetchOrgRepos for GitHub.
+func (a *Activities) fetchGitHubRepos(ctx context.Context, input ScanInput) ([]RepoInfo, error) {
+	// Org doesn't exist — NOT retryable (retrying won't help)
+	// In Python: raise ValueError("Organization not found")
+	// In Go: wrap with temporal.NewNonRetryableApplicationError
+	notFound := temporal.NewNonRetryableApplicationError(
+		fmt.Sprintf("organization '%s' not found", input.Org),
+		"NOT_FOUND",
+		nil,
+	)
+	cred, err := a.githubToken(ctx, input.credential())
+	if err != nil {
+		return nil, err
+	}
+	repos, err := a.listGitHubRepos(ctx, "/orgs/"+input.Org+"/repos", cred, notFound, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := activity.GetLogger(ctx)
+	if to := orgRenamedTo(input.Org, repos); to != "" {
+		logger.Info("Organization was renamed", "org", input.Org, "renamed_to", to)
+	}
+	logger.Info("Fetched repositories", "count", len(repos), "org", input.Org)
+	return repos, nil
+}
+
+// listGitHubRepos pages through the repo listing at path. notFound is
+// returned for a 404 and denied, if not nil, for a 403 refusing the token;
+// any other 403 is a rate limit and retryable.
+func (a *Activities) listGitHubRepos(ctx context.Context, path string, cred *Credential, notFound, denied error) ([]RepoInfo, error) {
+	var repos []RepoInfo
+	for page := 1; ; page++ {
 		// Heartbeat to tell Temporal we're still alive during pagination
 		activity.RecordHeartbeat(ctx, fmt.Sprintf("Fetching page %d", page))
 
-		url := fmt.Sprintf("https://api.github.com/orgs/%s/repos?per_page=100&page=%d", input.Org, page)
+		pageRepos, served, more, err := a.listGitHubRepoPage(ctx, path, page, cred, notFound, denied)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, pageRepos...)
+		if !more {
+			return repos, nil
+		}
+		path = served
+	}
+}
+
+// listGitHubRepoPage fetches one page of the repo listing at path. served
+// is the path the page was served from, where later pages are asked for,
+// and more whether there may be another page. notFound and denied are as
+// for listGitHubRepos.
+func (a *Activities) listGitHubRepoPage(ctx context.Context, path string, page int, cred *Credential, notFound, denied error) (repos []RepoInfo, served string, more bool, err error) {
+	redirects := 0
+	for {
+		url := a.apiURL("%s?per_page=100&page=%d", path, page)
 		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
-			return nil, fmt.Errorf("creating request: %w", err)
+			return nil, "", false, fmt.Errorf("creating request: %w", err)
 		}
 
 		req.Header.Set("Accept", "application/vnd.github+json")
-		if input.Token != nil {
-			req.Header.Set("Authorization", "token "+*input.Token)
+		if auth := cred.Authorization(); auth != "" {
+			req.Header.Set("Authorization", auth)
 		}
 
-		resp, err := a.HTTPClient.Do(req)
+		resp, err := a.do(req)
 		if err != nil {
 			// Network error — this IS retryable (Temporal will retry automatically)
-			return nil, fmt.Errorf("fetching repos page %d: %w", page, err)
+			return nil, "", false, fmt.Errorf("fetching repos page %d: %w", page, err)
 		}
 		defer resp.Body.Close()
 
+		// A renamed org's listing has moved (see orgrename.go). The client
+		// followed the redirect, so later pages are asked for where this
+		// one was served from; a redirect it handed back, or a 404 naming
+		// where the org went, is followed here.
+		if served := a.apiPath(resp.Request.URL); served != "" {
+			path = served
+		}
+		if resp.StatusCode == http.StatusNotFound || isRedirect(resp.StatusCode) {
+			body, _ := io.ReadAll(resp.Body)
+			if to := a.movedTo(resp, body); to != "" && to != path && redirects < maxListingRedirects {
+				activity.GetLogger(ctx).Info("Repo listing moved", "from", path, "to", to, "status", resp.StatusCode)
+				redirects++
+				path = to
+				continue
+			}
+			if resp.StatusCode == http.StatusNotFound {
+				return nil, "", false, notFound
+			}
+			return nil, "", false, wrapGitHubError(resp, body)
+		}
+
 		switch resp.StatusCode {
-		case http.StatusNotFound:
-			// Org doesn't exist — NOT retryable (retrying won't help)
-			// In Python: raise ValueError("Organization not found")
-			// In Go: wrap with temporal.NewNonRetryableApplicationError
-			return nil, temporal.NewNonRetryableApplicationError(
-				fmt.Sprintf("organization '%s' not found", input.Org),
-				"NOT_FOUND",
-				nil,
-			)
 		case http.StatusUnauthorized:
-			return nil, temporal.NewNonRetryableApplicationError(
+			return nil, "", false, temporal.NewNonRetryableApplicationError(
 				"invalid GitHub API token",
 				"UNAUTHORIZED",
 				nil,
 			)
-		case http.StatusForbidden:
-			// Rate limited — retryable (Temporal backs off and tries again)
-			return nil, fmt.Errorf("GitHub API rate limit exceeded")
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
 		}
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("reading response: %w", err)
+			return nil, "", false, fmt.Errorf("reading response: %w", err)
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusForbidden && denied != nil && permissionDenied(body):
+			return nil, "", false, denied
+		case resp.StatusCode == http.StatusForbidden:
+			// Rate limited — retryable (Temporal backs off and tries again)
+			return nil, "", false, fmt.Errorf("GitHub API rate limit exceeded")
+		case resp.StatusCode != http.StatusOK:
+			return nil, "", false, wrapGitHubError(resp, body)
 		}
 
 		var pageRepos []struct {
-			Name     string `json:"name"`
-			FullName string `json:"full_name"`
-			Private  bool   `json:"private"`
-			Archived bool   `json:"archived"`
+			Name          string     `json:"name"`
+			FullName      string     `json:"full_name"`
+			Private       bool       `json:"private"`
+			Archived      bool       `json:"archived"`
+			PushedAt      *time.Time `json:"pushed_at"`
+			UpdatedAt     *time.Time `json:"updated_at"`
+			DefaultBranch string     `json:"default_branch"`
+			Fork          bool       `json:"fork"`
+			Visibility    string     `json:"visibility"`
+			Language      string     `json:"language"`
+			Topics        []string   `json:"topics"`
 		}
 		if err := json.Unmarshal(body, &pageRepos); err != nil {
-			return nil, fmt.Errorf("parsing response: %w", err)
-		}
-
-		if len(pageRepos) == 0 {
-			break
+			return nil, "", false, fmt.Errorf("parsing response: %w", err)
 		}
 
 		for _, r := range pageRepos {
 			repos = append(repos, RepoInfo{
-				Name:     r.Name,
-				FullName: r.FullName,
-				Private:  r.Private,
-				Archived: r.Archived,
+				Name:      r.Name,
+				FullName:  r.FullName,
+				Private:   r.Private,
+				Archived:  r.Archived,
+				PushedAt:  r.PushedAt,
+				UpdatedAt: r.UpdatedAt,
+				RepoMetadata: RepoMetadata{
+					DefaultBranch: r.DefaultBranch,
+					Fork:          r.Fork,
+					Visibility:    visibility(r.Visibility, r.Private),
+					Language:      r.Language,
+					Topics:        r.Topics,
+				},
 			})
 		}
-
-		if len(pageRepos) < 100 {
-			break
-		}
-		page++
+		// A short page is the last one.
+		return repos, path, len(pageRepos) == 100, nil
 	}
-
-	logger := activity.GetLogger(ctx)
-	logger.Info("Fetched repositories", "count", len(repos), "org", input.Org)
-	return repos, nil
 }
 
 // CheckRepoSecurity checks all security settings for a single repository.
@@ -201,7 +354,21 @@ func (a *Activities) FetchOrgRepos(ctx context.Context, input ScanInput) ([]Repo
 // the retry semantics at the point of failure, not in a separate policy config.
 //
 // Both approaches work. Go's is more granular. Python's is more centralized.
-func (a *Activities) CheckRepoSecurity(ctx context.Context, org, repoName string, token *string) (*RepoSecurityResult, error) {
+//
+// checks selects which checks run (empty means DefaultChecks); the rest stay
+// StatusUnknown or nil. The repo lookup always runs so deleted repos are
+// still reported.
+//
+// It always checks a GitHub repo; other providers use CheckProviderRepo.
+func (a *Activities) CheckRepoSecurity(ctx context.Context, org, repoName string, cred *Credential, checks []string) (*RepoSecurityResult, error) {
+	return githubProvider{a}.CheckRepo(ctx, RepoCheckInput{
+		Provider: ProviderGitHub, Org: org, Repo: repoName, Credential: cred, Checks: checks,
+	})
+}
+
+// checkGitHubRepo is CheckRepoSecurity's implementation.
+func (a *Activities) checkGitHubRepo(ctx context.Context, org, repoName string, cred *Credential, checks []string) (*RepoSecurityResult, error) {
+	selected := newCheckSet(checks)
 	result := &RepoSecurityResult{
 		Repository:       repoName,
 		SecretScanning:   StatusUnknown,
@@ -210,72 +377,429 @@ func (a *Activities) CheckRepoSecurity(ctx context.Context, org, repoName string
 		ScannedAt:        time.Now().UTC().Format(time.RFC3339),
 	}
 
-	headers := map[string]string{"Accept": "application/vnd.github+json"}
-	if token != nil {
-		headers["Authorization"] = "token " + *token
+	headers, err := a.githubHeaders(ctx, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Check secret scanning via the repo's security_and_analysis block.
+	// The block is null or absent for repos without GHAS, which Python
+	// handles with `or {}`; here the nil pointers fall through to disabled.
+	// The repo's GET also tells whether it disappeared after the listing
+	// (see disappeared.go), so it runs before the other checks.
+	lookupCtx := evidenceFor(ctx, CheckSecretScanning)
+	status, body, err := a.checkEndpoint(lookupCtx, a.apiURL("/repos/%s/%s", org, repoName), headers)
+	if err != nil {
+		return nil, err
+	}
+	if isRedirect(status) {
+		// The client handed the redirect back; follow it once to learn
+		// whether the repo left the org or was only renamed.
+		to := a.redirectURL(body)
+		if to == "" {
+			result.setDisappeared(DisappearedTransferred, "", status)
+			return result, nil
+		}
+		if status, body, err = a.checkEndpoint(lookupCtx, to, headers); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case status == http.StatusOK:
+		if movedTo := transferredTo(org, body); movedTo != "" {
+			result.setDisappeared(DisappearedTransferred, movedTo, status)
+			return result, nil
+		}
+		var repo struct {
+			SecurityAndAnalysis *struct {
+				SecretScanning *struct {
+					Status SecurityStatus `json:"status"`
+				} `json:"secret_scanning"`
+			} `json:"security_and_analysis"`
+		}
+		if err := json.Unmarshal(body, &repo); err != nil {
+			return nil, parseError("repo "+repoName, err)
+		}
+		if selected[CheckSecretScanning] {
+			secret := StatusDisabled
+			if sa := repo.SecurityAndAnalysis; sa != nil && sa.SecretScanning != nil && sa.SecretScanning.Status != "" {
+				secret = sa.SecretScanning.Status
+			}
+			result.setCheck(CheckSecretScanning, CheckResult{Status: secret})
+		}
+	case status == http.StatusNotFound && perRepoToken(headers):
+		result.setTokenLacksRepoAccess(checks)
+		return result, nil
+	case status == http.StatusNotFound:
+		result.setDisappeared(DisappearedDeleted, "", status)
+		return result, nil
+	case accessRevoked(status, body):
+		result.setDisappeared(DisappearedAccessRevoked, "", status)
+		return result, nil
+	}
+
+	// 2–5. The other checks are independent GETs and run concurrently
+	// (see repochecks.go).
+	var steps []repoCheck
+	if selected[CheckDependabot] {
+		steps = append(steps, repoCheck{CheckDependabot, a.checkDependabotAlerts})
+	}
+	if selected[CheckCodeScanning] {
+		steps = append(steps, repoCheck{CheckCodeScanning, a.checkCodeScanning})
+	}
+	if selected[CheckFiles] {
+		steps = append(steps, repoCheck{CheckFiles, a.checkRepoFiles})
+	}
+	if selected[CheckSecurityUpdates] {
+		steps = append(steps, repoCheck{CheckSecurityUpdates, a.checkSecurityUpdates})
+	}
+	if selected[CheckDependabotConfig] {
+		steps = append(steps, repoCheck{CheckDependabotConfig, a.checkDependabotConfig})
+	}
+	if selected[CheckWebhooks] {
+		steps = append(steps, repoCheck{CheckWebhooks, a.checkWebhooks})
+	}
+	if err := a.runRepoChecks(ctx, org, repoName, headers, steps, result); err != nil {
+		return nil, err
+	}
+
+	logger := activity.GetLogger(ctx)
+	logger.Info("Checked repo security",
+		"repo", repoName,
+		"secret_scanning", result.SecretScanning,
+		"dependabot", result.DependabotAlerts,
+		"code_scanning", result.CodeScanning,
+	)
+	return result, nil
+}
+
+// CheckActionsSecurity reads a repository's GitHub Actions hardening
+// settings: whether Actions is enabled, which actions may run, and the
+// default GITHUB_TOKEN permissions.
+//
+// It returns nil (unknown) when the token cannot read the settings, which
+// needs admin access to the repo. A 404 means Actions is disabled for the
+// repo (e.g. by org policy), which is reported rather than treated as an error.
+func (a *Activities) CheckActionsSecurity(ctx context.Context, org, repoName string, cred *Credential) (*ActionsSecurity, error) {
+	headers, err := a.githubHeaders(ctx, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	status, body, err := a.checkEndpoint(ctx, a.apiURL("/repos/%s/%s/actions/permissions", org, repoName), headers)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return &ActionsSecurity{ActionsEnabled: false}, nil
+	default:
+		activity.GetLogger(ctx).Warn("Cannot read Actions permissions", "repo", repoName, "status", status)
+		return nil, nil
+	}
+
+	var perms struct {
+		Enabled        bool   `json:"enabled"`
+		AllowedActions string `json:"allowed_actions"`
+	}
+	if err := json.Unmarshal(body, &perms); err != nil {
+		return nil, fmt.Errorf("parsing Actions permissions for %s: %w", repoName, err)
+	}
+	result := &ActionsSecurity{ActionsEnabled: perms.Enabled, AllowedActions: perms.AllowedActions}
+	if !perms.Enabled {
+		return result, nil
 	}
 
-	// 1. Check secret scanning
-	status, err := a.checkEndpoint(ctx, fmt.Sprintf("https://api.github.com/repos/%s/%s", org, repoName), headers)
+	status, body, err = a.checkEndpoint(ctx, a.apiURL("/repos/%s/%s/actions/permissions/workflow", org, repoName), headers)
 	if err != nil {
 		return nil, err
 	}
 	if status == http.StatusOK {
-		// Parse security_and_analysis from response (simplified)
-		result.SecretScanning = StatusEnabled // Simplified for comparison
+		var wf struct {
+			DefaultWorkflowPermissions string `json:"default_workflow_permissions"`
+		}
+		if err := json.Unmarshal(body, &wf); err != nil {
+			return nil, fmt.Errorf("parsing workflow permissions for %s: %w", repoName, err)
+		}
+		result.DefaultWorkflowPermissions = wf.DefaultWorkflowPermissions
+	}
+
+	activity.GetLogger(ctx).Info("Checked Actions security",
+		"repo", repoName,
+		"allowed_actions", result.AllowedActions,
+		"default_workflow_permissions", result.DefaultWorkflowPermissions,
+	)
+	return result, nil
+}
+
+// AuditRepoAccess lists a repository's deploy keys and outside
+// collaborators, flagging read-write or stale keys and collaborators with
+// write or admin permission.
+//
+// Both listings need admin scope. GitHub answers 403 — or 404 on private
+// repos — when the token lacks it; that listing is recorded in NoAccess
+// instead of failing the repo.
+func (a *Activities) AuditRepoAccess(ctx context.Context, org, repoName string, cred *Credential, maxKeyAgeDays int) (*AccessAudit, error) {
+	headers, err := a.githubHeaders(ctx, cred)
+	if err != nil {
+		return nil, err
+	}
+	if maxKeyAgeDays <= 0 {
+		maxKeyAgeDays = DefaultDeployKeyMaxAgeDays
+	}
+	staleBefore := time.Now().AddDate(0, 0, -maxKeyAgeDays)
+	audit := &AccessAudit{}
+
+	keys, ok, err := listAll[deployKey](ctx, a, a.apiURL("/repos/%s/%s/keys", org, repoName), headers)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		audit.NoAccess = append(audit.NoAccess, AccessDeployKeys)
+	}
+	audit.DeployKeys = len(keys)
+	for _, k := range keys {
+		stale := k.CreatedAt.Before(staleBefore)
+		if !k.ReadOnly || stale {
+			audit.FlaggedDeployKeys = append(audit.FlaggedDeployKeys, FlaggedDeployKey{
+				ID:        k.ID,
+				Title:     k.Title,
+				ReadOnly:  k.ReadOnly,
+				CreatedAt: k.CreatedAt.UTC().Format(time.RFC3339),
+				Stale:     stale,
+			})
+		}
 	}
 
-	// 2. Check Dependabot (same pattern as Python — check 204 vs 404)
-	status, err = a.checkEndpoint(ctx, fmt.Sprintf("https://api.github.com/repos/%s/%s/vulnerability-alerts", org, repoName), headers)
+	collaborators, ok, err := listAll[collaborator](ctx, a, a.apiURL("/repos/%s/%s/collaborators?affiliation=outside", org, repoName), headers)
 	if err != nil {
 		return nil, err
 	}
+	if !ok {
+		audit.NoAccess = append(audit.NoAccess, AccessCollaborators)
+	}
+	for _, c := range collaborators {
+		var perm string
+		switch {
+		case c.Permissions.Admin:
+			perm = "admin"
+		case c.Permissions.Maintain:
+			perm = "maintain"
+		case c.Permissions.Push:
+			perm = "write"
+		default:
+			continue
+		}
+		audit.OutsideCollaborators = append(audit.OutsideCollaborators, OutsideCollaborator{Login: c.Login, Permission: perm})
+	}
+
+	activity.GetLogger(ctx).Info("Audited repo access",
+		"repo", repoName,
+		"deploy_keys", audit.DeployKeys,
+		"flagged_deploy_keys", len(audit.FlaggedDeployKeys),
+		"outside_collaborators", len(audit.OutsideCollaborators),
+		"no_access", audit.NoAccess,
+	)
+	return audit, nil
+}
+
+// deployKey is an item of GET /repos/{org}/{repo}/keys.
+type deployKey struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	ReadOnly  bool      `json:"read_only"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// collaborator is an item of GET /repos/{org}/{repo}/collaborators.
+type collaborator struct {
+	Login       string `json:"login"`
+	Permissions struct {
+		Admin    bool `json:"admin"`
+		Maintain bool `json:"maintain"`
+		Push     bool `json:"push"`
+	} `json:"permissions"`
+}
+
+// listAll GETs every page of a GitHub listing, 100 items at a time. It
+// returns ok=false when the token is not allowed to read it (403/404).
+func listAll[T any](ctx context.Context, a *Activities, url string, headers map[string]string) (items []T, ok bool, err error) {
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	for page := 1; ; page++ {
+		status, body, err := a.checkEndpoint(ctx, fmt.Sprintf("%s%sper_page=100&page=%d", url, sep, page), headers)
+		if err != nil {
+			return nil, false, err
+		}
+		switch status {
+		case http.StatusOK:
+		case http.StatusForbidden, http.StatusNotFound:
+			return nil, false, nil
+		default:
+			return nil, false, statusError("listing "+url, status, body)
+		}
+
+		var pageItems []T
+		if err := json.Unmarshal(body, &pageItems); err != nil {
+			return nil, false, fmt.Errorf("parsing %s: %w", url, err)
+		}
+		items = append(items, pageItems...)
+		if len(pageItems) < 100 {
+			return items, true, nil
+		}
+	}
+}
+
+// checkDependabotAlerts records the dependabot result: GitHub answers 204
+// when vulnerability alerts are on and 404 when they are off (same pattern
+// as Python).
+func (a *Activities) checkDependabotAlerts(ctx context.Context, org, repoName string, headers map[string]string, result *RepoSecurityResult) error {
+	status, _, err := a.checkEndpoint(evidenceFor(ctx, CheckDependabot), a.apiURL("/repos/%s/%s/vulnerability-alerts", org, repoName), headers)
+	if err != nil {
+		return err
+	}
+	dependabot := StatusUnknown
 	switch status {
 	case http.StatusNoContent:
-		result.DependabotAlerts = StatusEnabled
+		dependabot = StatusEnabled
 	case http.StatusNotFound:
-		result.DependabotAlerts = StatusDisabled
+		dependabot = StatusDisabled
 	}
+	result.setCheck(CheckDependabot, CheckResult{Status: dependabot})
+	return nil
+}
 
-	// 3. Check code scanning
-	status, err = a.checkEndpoint(ctx, fmt.Sprintf("https://api.github.com/repos/%s/%s/code-scanning/alerts", org, repoName), headers)
+// checkCodeScanning records the code_scanning result from whether the
+// repo's code scanning alerts can be listed.
+func (a *Activities) checkCodeScanning(ctx context.Context, org, repoName string, headers map[string]string, result *RepoSecurityResult) error {
+	status, _, err := a.checkEndpoint(evidenceFor(ctx, CheckCodeScanning), a.apiURL("/repos/%s/%s/code-scanning/alerts", org, repoName), headers)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	codeScanning := StatusUnknown
 	switch status {
 	case http.StatusOK:
-		result.CodeScanning = StatusEnabled
+		codeScanning = StatusEnabled
 	case http.StatusNotFound:
-		result.CodeScanning = StatusNotConfigured
+		codeScanning = StatusNotConfigured
 	case http.StatusForbidden:
-		result.CodeScanning = StatusNoAccess
+		codeScanning = StatusNoAccess
 	}
+	result.setCheck(CheckCodeScanning, CheckResult{Status: codeScanning})
+	return nil
+}
 
-	logger := activity.GetLogger(ctx)
-	logger.Info("Checked repo security",
-		"repo", repoName,
-		"secret_scanning", result.SecretScanning,
-		"dependabot", result.DependabotAlerts,
-		"code_scanning", result.CodeScanning,
-	)
-	return result, nil
+// repoFileDirs are the locations GitHub recognizes for CODEOWNERS and
+// SECURITY.md, in the order it looks them up.
+var repoFileDirs = []string{"", ".github/", "docs/"}
+
+// checkRepoFiles records the codeowners and security_policy results.
+//
+// SECURITY.md is looked up via the community profile first, which reports
+// it wherever it lives; the contents API then confirms it is not empty. A
+// profile the token cannot read falls back to the contents API alone.
+func (a *Activities) checkRepoFiles(ctx context.Context, org, repoName string, headers map[string]string, result *RepoSecurityResult) error {
+	codeowners, err := a.probeRepoFile(evidenceFor(ctx, ResultCodeowners), org, repoName, "CODEOWNERS", headers)
+	if err != nil {
+		return err
+	}
+	result.setCheck(ResultCodeowners, presenceResult(codeowners))
+
+	ctx = evidenceFor(ctx, ResultSecurityPolicy)
+	status, body, err := a.checkEndpoint(ctx, a.apiURL("/repos/%s/%s/community/profile", org, repoName), headers)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		var profile struct {
+			Files map[string]json.RawMessage `json:"files"`
+		}
+		if err := json.Unmarshal(body, &profile); err != nil {
+			return parseError("community profile for "+repoName, err)
+		}
+		if f := profile.Files["security"]; len(f) == 0 || string(f) == "null" {
+			result.setCheck(ResultSecurityPolicy, CheckResult{Status: StatusDisabled})
+			return nil
+		}
+	}
+	securityPolicy, err := a.probeRepoFile(ctx, org, repoName, "SECURITY.md", headers)
+	if err != nil {
+		return err
+	}
+	result.setCheck(ResultSecurityPolicy, presenceResult(securityPolicy))
+	return nil
+}
+
+// probeRepoFile reports whether a non-empty file named name exists in any of
+// repoFileDirs. It returns nil (unknown) when a location could not be read
+// and the file was not found elsewhere.
+func (a *Activities) probeRepoFile(ctx context.Context, org, repoName, name string, headers map[string]string) (*bool, error) {
+	unknown := false
+	for _, dir := range repoFileDirs {
+		status, body, err := a.checkEndpoint(ctx, a.apiURL("/repos/%s/%s/contents/%s%s", org, repoName, dir, name), headers)
+		if err != nil {
+			return nil, err
+		}
+		switch status {
+		case http.StatusOK:
+			var file struct {
+				Type string `json:"type"`
+				Size int    `json:"size"`
+			}
+			if err := json.Unmarshal(body, &file); err != nil {
+				return nil, parseError("contents of "+repoName+"/"+dir+name, err)
+			}
+			if file.Type == "file" && file.Size > 0 {
+				found := true
+				return &found, nil
+			}
+		case http.StatusNotFound:
+		default:
+			unknown = true
+		}
+	}
+	if unknown {
+		return nil, nil
+	}
+	found := false
+	return &found, nil
 }
 
-// checkEndpoint is a helper that makes a GET request and returns the status code.
-func (a *Activities) checkEndpoint(ctx context.Context, url string, headers map[string]string) (int, error) {
+// checkEndpoint is a helper that makes a GET request and returns the status
+// code and body.
+func (a *Activities) checkEndpoint(ctx context.Context, url string, headers map[string]string) (int, []byte, error) {
+	at := time.Now()
+	resp, body, err := a.get(ctx, url, headers)
+	if err != nil {
+		return 0, nil, requestError(err)
+	}
+	evidenceFrom(ctx).add(ctx, url, resp.StatusCode, body, at)
+	return resp.StatusCode, body, nil
+}
+
+// get makes a GET request and returns the response, whose body is already
+// read and closed, and the body.
+func (a *Activities) get(ctx context.Context, url string, headers map[string]string) (*http.Response, []byte, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	resp, err := a.HTTPClient.Do(req)
+	resp, err := a.do(req)
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
-	return resp.StatusCode, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", url, err)
+	}
+	return resp, body, nil
 }
 
 // GenerateReport creates a summary from scan results.
@@ -289,44 +813,417 @@ func (a *Activities) checkEndpoint(ctx context.Context, url string, headers map[
 // Go returns a typed struct (rigid, compile-time checked).
 // For a report that might evolve, Python's dict is arguably easier to iterate on.
 // For a stable API, Go's struct catches mistakes earlier.
-func (a *Activities) GenerateReport(ctx context.Context, org string, results []RepoSecurityResult) (map[string]interface{}, error) {
+//
+// refs are result chunks the workflow offloaded via StoreResults; they are
+// loaded here and aggregated together with the inline results. policy decides
+// which repos count as fully compliant and how they are scored; only the
+// selected checks are aggregated into the report. suppressions excuse failed
+// checks; the workflow has already dropped expired ones. repo_failures maps
+// every scanned repo to the required checks it failed, for CompareReports.
+// Repos whose failed checks could only not be read are indeterminate, not
+// non-compliant, unless the policy says IndeterminateFails. Results that
+// carry RepoMetadata are also broken down by visibility and language (see
+// breakdown.go). The report is checked with Report.Validate before it is returned.
+func (a *Activities) GenerateReport(ctx context.Context, org string, results []RepoSecurityResult, refs []BlobRef, policy CompliancePolicy, checks []string, suppressions []Suppression) (map[string]interface{}, error) {
+	selected := newCheckSet(checks)
+	for _, ref := range refs {
+		chunk, err := a.LoadResults(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunk...)
+	}
+	// A resumed scan holds results of more than one run.
+	results = newestResults(results)
+
 	total := len(results)
 	compliant := 0
-	secretEnabled := 0
-	dependabotEnabled := 0
-	codeScanningEnabled := 0
-	var nonCompliant []string
+	enabled := make(map[string]int, len(reportCounts))
+	actionsRestricted, misconfigured := 0, 0
+	access := &accessTotals{}
+	scoring := DefaultScoringPolicy()
+	if policy.Scoring != nil {
+		scoring = *policy.Scoring
+	}
+	scores := &scoreTotals{}
+	var suppressed []SuppressedFinding
+	var indeterminate, noRepoAccess []string
+	// non_compliant_repos is a list even when empty, as the Python
+	// scanner writes it; its clients iterate it without a null check.
+	nonCompliant := []string{}
+	repoFailures := make(map[string][]string, total)
+	byVisibility, byLanguage, byTopic := newBreakdownTotals(), newBreakdownTotals(), newBreakdownTotals()
+	withMetadata := false
+	evidence := EvidenceAppendix{}
+	webhookIssues := webhookIssueCounts{}
 
 	for _, r := range results {
-		if r.IsFullyCompliant() {
+		r := r
+		policy.applyWebhookAllowlist(&r)
+		failed, excused := policy.evaluate(&r, suppressions)
+		outcome := policy.outcome(&r, failed)
+		switch {
+		case outcome == OutcomeCompliant:
 			compliant++
-		} else if r.Error == nil {
+		case r.Error != nil:
+			// Counted in the errors by addRunMetadata.
+		case outcome == OutcomeIndeterminate:
+			indeterminate = append(indeterminate, r.Repository)
+		default:
 			nonCompliant = append(nonCompliant, r.Repository)
 		}
-		if r.SecretScanning == StatusEnabled {
-			secretEnabled++
+		if r.Error == nil {
+			repoFailures[r.Repository] = append([]string{}, failed...)
+		}
+		if r.TokenLacksRepoAccess {
+			noRepoAccess = append(noRepoAccess, r.Repository)
+		}
+		suppressed = append(suppressed, excused...)
+		for _, c := range reportCounts {
+			if r.Check(c.result).Status == StatusEnabled {
+				enabled[c.result]++
+			}
+		}
+		actionsRestricted += r.Check(CheckActions).Details["restricted"]
+		if r.Check(CheckDependabotConfig).Status == StatusMisconfigured {
+			misconfigured++
 		}
-		if r.DependabotAlerts == StatusEnabled {
-			dependabotEnabled++
+		webhookIssues.add(&r)
+		score := scoring.score(&r, selected)
+		scores.add(r.Repository, score)
+		var meta RepoMetadata
+		if r.Metadata != nil {
+			meta, withMetadata = *r.Metadata, true
 		}
-		if r.CodeScanning == StatusEnabled {
-			codeScanningEnabled++
+		byVisibility.add(meta.Visibility, r.Repository, outcome, r.Error != nil, score)
+		byLanguage.add(meta.Language, r.Repository, outcome, r.Error != nil, score)
+		for _, topic := range topicBuckets(meta) {
+			byTopic.add(topic, r.Repository, outcome, r.Error != nil, score)
 		}
+		if r.Access != nil {
+			access.add(r.Repository, r.Access)
+		}
+		evidence.add(&r)
 	}
 
-	rate := "N/A"
-	if total > 0 {
-		rate = fmt.Sprintf("%.1f%%", float64(compliant)/float64(total)*100)
+	// Indeterminate repos count neither way in the rate.
+	rate := complianceRate(compliant, total, len(indeterminate))
+
+	report := map[string]interface{}{
+		"schema_version":      ReportSchemaVersion,
+		"org":                 org,
+		"checks":              selected.names(),
+		"total_repos":         total,
+		"fully_compliant":     compliant,
+		"compliance_rate":     rate,
+		"compliance_score":    scores.orgScore(),
+		"worst_scoring_repos": scores.worst(),
+		"scoring":             scoring,
+		"non_compliant_repos": nonCompliant,
+		"repo_failures":       repoFailures,
+		"worker_version":      Version,
+	}
+	for _, c := range reportCounts {
+		if selected[c.check] {
+			report[c.field] = enabled[c.result]
+		}
+	}
+	if selected[CheckActions] {
+		report["actions_restricted"] = actionsRestricted
 	}
+	if selected[CheckDependabotConfig] {
+		report["dependabot_config_misconfigured"] = misconfigured
+	}
+	if selected[CheckWebhooks] {
+		report["webhook_findings"] = map[string]int(webhookIssues)
+	}
+	if len(indeterminate) > 0 {
+		report["indeterminate"] = len(indeterminate)
+		report["indeterminate_repos"] = indeterminate
+	}
+	if len(noRepoAccess) > 0 {
+		sort.Strings(noRepoAccess)
+		report["token_lacks_repo_access"] = len(noRepoAccess)
+		report["token_lacks_repo_access_repos"] = noRepoAccess
+		if warning := repoAccessWarning(len(noRepoAccess), total); warning != "" {
+			report["repo_access_warning"] = warning
+		}
+	}
+	if withMetadata {
+		report["by_visibility"] = byVisibility.sections()
+		report["by_language"] = byLanguage.sections()
+		report["by_topic"] = byTopic.sections()
+	}
+	if len(suppressed) > 0 {
+		report["suppressed"] = suppressed
+	}
+	if selected[CheckAccessAudit] {
+		report["access_audit"] = access.summary()
+	}
+	if err := a.reportEvidence(ctx, report, evidence); err != nil {
+		return nil, err
+	}
+	if err := validateReport(report); err != nil {
+		// Retrying would aggregate the same results the same way.
+		return nil, temporal.NewNonRetryableApplicationError(
+			"generated report is inconsistent: "+err.Error(), ErrTypeInvalidReport, nil)
+	}
+	return report, nil
+}
+
+// BuildReport is GenerateReport plus the run metadata in, so the workflow
+// can run it as a local activity and use the report as returned.
+func (a *Activities) BuildReport(ctx context.Context, in ReportInput) (map[string]interface{}, error) {
+	report, err := a.GenerateReport(ctx, in.Org, in.Results, in.Refs, in.Policy, in.Checks, in.Suppressions)
+	if err != nil {
+		return nil, err
+	}
+	in.addRunMetadata(report)
+	return report, nil
+}
+
+// addRunMetadata adds what GenerateReport cannot know from the results:
+// errored repos, skipped repos, claim checks, timing, and cancellation.
+func (in ReportInput) addRunMetadata(report map[string]interface{}) {
+	// GenerateReport only sees successful results, so errors are added here.
+	report["errors"] = in.Errors
+	if in.Errors > 0 {
+		report["errors_by_category"] = in.ErrorsByCategory
+		retryLater := append([]string{}, in.RetryLater...)
+		sort.Strings(retryLater)
+		report["retry_later_repos"] = retryLater
+	}
+	report["estimated_api_calls"] = in.EstimatedAPICalls
+	if in.APIUsage != nil {
+		report["api_usage"] = in.APIUsage
+	}
+	if len(in.ExpiredSuppressions) > 0 {
+		report["expired_suppressions"] = in.ExpiredSuppressions
+	}
+	if in.ActiveWithinDays > 0 {
+		report["skipped_inactive"] = len(in.SkippedInactive)
+		sample := in.SkippedInactive
+		if len(sample) > skippedSampleSize {
+			sample = sample[:skippedSampleSize]
+		}
+		report["skipped_inactive_sample"] = sample
+	}
+	if len(in.ArchivedRepos) > 0 {
+		report["archived"] = len(in.ArchivedRepos)
+		report["archived_repos"] = in.ArchivedRepos
+	}
+	if len(in.IncludeTopics) > 0 || len(in.ExcludeTopics) > 0 {
+		if len(in.IncludeTopics) > 0 {
+			report["include_topics"] = in.IncludeTopics
+		}
+		if len(in.ExcludeTopics) > 0 {
+			report["exclude_topics"] = in.ExcludeTopics
+		}
+		report["skipped_by_topic"] = len(in.SkippedByTopic)
+	}
+	if in.RepoList {
+		report["repo_list"] = true
+	}
+	if len(in.Teams) > 0 {
+		report["teams"] = in.Teams
+	}
+	if len(in.TeamRepos) > 0 {
+		report["team_repos"] = in.TeamRepos
+	}
+	if in.DuplicateRepos > 0 {
+		report["duplicate_repos"] = in.DuplicateRepos
+	}
+	if len(in.Refs) > 0 {
+		report["results_blob_refs"] = in.Refs
+	}
+
+	if in.Provider != "" {
+		report["provider"] = in.Provider
+	}
+
+	// Run metadata so a saved report can be correlated to Temporal history.
+	report["workflow_id"] = in.WorkflowID
+	report["run_id"] = in.RunID
+	report["started_at"] = in.StartedAt.UTC().Format(time.RFC3339)
+	report["completed_at"] = in.CompletedAt.UTC().Format(time.RFC3339)
 
-	return map[string]interface{}{
-		"org":                     org,
-		"total_repos":             total,
-		"fully_compliant":         compliant,
-		"compliance_rate":         rate,
-		"secret_scanning_enabled": secretEnabled,
-		"dependabot_enabled":      dependabotEnabled,
-		"code_scanning_enabled":   codeScanningEnabled,
-		"non_compliant_repos":     nonCompliant,
-	}, nil
+	if in.TokenCapabilities != nil {
+		report["token_capabilities"] = in.TokenCapabilities
+	}
+
+	if in.Cancelled {
+		report["cancelled"] = true
+		report["cancel_reason"] = in.CancelReason
+		report["repos_scanned_before_cancel"] = in.ReposScannedBeforeCancel
+		if len(in.CancelledInFlight) > 0 {
+			inFlight := append([]string(nil), in.CancelledInFlight...)
+			sort.Strings(inFlight)
+			report["cancelled_in_flight_repos"] = inFlight
+		}
+	}
+
+	if in.Deadline != nil {
+		report["deadline"] = in.Deadline.UTC().Format(time.RFC3339)
+	}
+	if in.DeadlineReached {
+		report["deadline_reached"] = true
+	}
+	if len(in.UnscannedRepos) > 0 {
+		unscanned := append([]string(nil), in.UnscannedRepos...)
+		sort.Strings(unscanned)
+		report["unscanned_repos"] = unscanned
+	}
+	if len(in.TimedOutInBatch) > 0 {
+		timedOut := append([]string(nil), in.TimedOutInBatch...)
+		sort.Strings(timedOut)
+		report["timed_out_in_batch_repos"] = timedOut
+	}
+	if in.PriorityRepos > 0 {
+		report["priority_repos"] = in.PriorityRepos
+		report["priority_repos_scanned"] = in.PriorityReposScanned
+	}
+	if len(in.ResumedFromRunIDs) > 0 {
+		report["resumed_from_run_ids"] = in.ResumedFromRunIDs
+	}
+	if in.OrgRenamedTo != "" {
+		report["org_renamed_to"] = in.OrgRenamedTo
+	}
+	if in.WorkerSessions > 0 {
+		report["worker_sessions"] = in.WorkerSessions
+	}
+	if in.InitiatedBy != "" {
+		report["initiated_by"] = in.InitiatedBy
+	}
+	if len(in.Disappeared) > 0 {
+		report["disappeared"] = len(in.Disappeared)
+		report["disappeared_repos"] = sortedDisappeared(in.Disappeared)
+	}
+	if in.Reason != "" {
+		report["reason"] = in.Reason
+	}
+}
+
+// reportCounts maps the check results counted in the report to the report
+// field holding the number of repos where the result is StatusEnabled.
+var reportCounts = []struct{ check, result, field string }{
+	{CheckSecretScanning, CheckSecretScanning, "secret_scanning_enabled"},
+	{CheckDependabot, CheckDependabot, "dependabot_enabled"},
+	{CheckCodeScanning, CheckCodeScanning, "code_scanning_enabled"},
+	{CheckFiles, ResultCodeowners, "codeowners_present"},
+	{CheckFiles, ResultSecurityPolicy, "security_policy_present"},
+	{CheckActions, CheckActions, "actions_enabled"},
+	{CheckActions, ResultReadOnlyWorkflowToken, "read_only_workflow_token"},
+	{CheckSecurityUpdates, CheckSecurityUpdates, "security_updates_enabled"},
+	{CheckDependabotConfig, CheckDependabotConfig, "dependabot_config_present"},
+	{CheckWebhooks, CheckWebhooks, "webhooks_secure"},
+}
+
+// worstOffendersLimit caps the repos listed in the report's access audit.
+const worstOffendersLimit = 10
+
+// accessTotals aggregates AccessAudit results across the org.
+type accessTotals struct {
+	audited, noAccess                      int
+	deployKeys, flaggedKeys, collaborators int
+	offenders                              []AccessOffender
+}
+
+// AccessOffender is one entry of the access audit's worst_offenders.
+type AccessOffender struct {
+	Repository           string `json:"repository"`
+	FlaggedDeployKeys    int    `json:"flagged_deploy_keys"`
+	OutsideCollaborators int    `json:"outside_collaborators"`
+}
+
+func (t *accessTotals) add(repo string, a *AccessAudit) {
+	t.audited++
+	if len(a.NoAccess) > 0 {
+		t.noAccess++
+	}
+	t.deployKeys += a.DeployKeys
+	t.flaggedKeys += len(a.FlaggedDeployKeys)
+	t.collaborators += len(a.OutsideCollaborators)
+	if a.Findings() > 0 {
+		t.offenders = append(t.offenders, AccessOffender{
+			Repository:           repo,
+			FlaggedDeployKeys:    len(a.FlaggedDeployKeys),
+			OutsideCollaborators: len(a.OutsideCollaborators),
+		})
+	}
+}
+
+// summary returns the report section, with the repos that have the most
+// findings first (ties by name, so the report is stable).
+func (t *accessTotals) summary() *AccessAuditSummary {
+	sort.Slice(t.offenders, func(i, j int) bool {
+		fi := t.offenders[i].FlaggedDeployKeys + t.offenders[i].OutsideCollaborators
+		fj := t.offenders[j].FlaggedDeployKeys + t.offenders[j].OutsideCollaborators
+		if fi != fj {
+			return fi > fj
+		}
+		return t.offenders[i].Repository < t.offenders[j].Repository
+	})
+	if len(t.offenders) > worstOffendersLimit {
+		t.offenders = t.offenders[:worstOffendersLimit]
+	}
+	return &AccessAuditSummary{
+		ReposAudited:         t.audited,
+		ReposNoAccess:        t.noAccess,
+		DeployKeys:           t.deployKeys,
+		FlaggedDeployKeys:    t.flaggedKeys,
+		OutsideCollaborators: t.collaborators,
+		WorstOffenders:       t.offenders,
+	}
+}
+
+// StoreResults writes a chunk of results to the blob store and returns its
+// claim check. The key is derived from the workflow run and chunk index, so
+// a retried attempt overwrites the same blob instead of leaking a new one.
+// A worker without a blob store returns no claim check, and the workflow
+// keeps its results inline.
+func (a *Activities) StoreResults(ctx context.Context, chunk int, results []RepoSecurityResult) (*BlobRef, error) {
+	if a.BlobStore == nil {
+		activity.GetLogger(ctx).Warn("Results exceed the offload threshold but the worker has no blob store; keeping them inline",
+			"count", len(results))
+		return nil, nil
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, fmt.Errorf("encoding results: %w", err)
+	}
+
+	info := activity.GetInfo(ctx)
+	key := fmt.Sprintf("%s/%s/results-%04d.json",
+		info.WorkflowExecution.ID, info.WorkflowExecution.RunID, chunk)
+	uri, err := a.BlobStore.Put(ctx, key, data)
+	if err != nil {
+		return nil, fmt.Errorf("storing results chunk %d: %w", chunk, err)
+	}
+
+	activity.GetLogger(ctx).Info("Offloaded results", "uri", uri, "count", len(results), "bytes", len(data))
+	return &BlobRef{URI: uri, Count: len(results), Bytes: len(data), SHA256: sha256Hex(data)}, nil
+}
+
+// LoadResults resolves a claim check back into results, verifying the
+// checksum recorded when it was stored.
+func (a *Activities) LoadResults(ctx context.Context, ref BlobRef) ([]RepoSecurityResult, error) {
+	if a.BlobStore == nil {
+		return nil, temporal.NewNonRetryableApplicationError("worker has no blob store configured", "NO_BLOB_STORE", nil)
+	}
+	data, err := a.BlobStore.Get(ctx, ref.URI)
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), "BLOB_NOT_FOUND", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %w", ref.URI, err)
+	}
+	if ref.SHA256 != "" && sha256Hex(data) != ref.SHA256 {
+		return nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("checksum mismatch for %s", ref.URI), "BLOB_CORRUPT", nil)
+	}
+	var results []RepoSecurityResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", ref.URI, err)
+	}
+	return results, nil
 }
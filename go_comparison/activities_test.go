@@ -0,0 +1,559 @@
+package scanner
+
+// =============================================================================
+// Activity tests — a fake GitHub behind httptest.Server
+// =============================================================================
+//
+// Activities are plain methods on a struct, so the test just points
+// Activities.BaseURL at an httptest.Server that serves the JSON fixtures in
+// testdata/github. The TestActivityEnvironment supplies the activity context
+// (heartbeats, logger) without a worker.
+// =============================================================================
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// fakeResponse is one canned GitHub reply. Fixture names a file in
+// testdata/github; an empty Fixture sends no body (e.g. 204).
+type fakeResponse struct {
+	Status  int
+	Fixture string
+}
+
+// fakeGitHub serves canned responses keyed by request path plus raw query
+// ("/orgs/acme-corp/repos?per_page=100&page=1") and records the requests.
+type fakeGitHub struct {
+	t      *testing.T
+	routes map[string]fakeResponse
+	// Header is added to every response, e.g. X-OAuth-Scopes.
+	Header http.Header
+	// dir is the testdata directory fixtures are read from.
+	dir string
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newFakeGitHub(t *testing.T, routes map[string]fakeResponse) (*fakeGitHub, *Activities) {
+	t.Helper()
+	f := &fakeGitHub{t: t, routes: routes, dir: "github"}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, &Activities{HTTPClient: srv.Client(), BaseURL: srv.URL}
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r)
+	f.mu.Unlock()
+
+	key := r.URL.EscapedPath()
+	if r.URL.RawQuery != "" {
+		key += "?" + r.URL.RawQuery
+	}
+	resp, ok := f.routes[key]
+	if !ok {
+		f.t.Errorf("unexpected request: %s %s", r.Method, key)
+		http.NotFound(w, r)
+		return
+	}
+	for k, v := range f.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(resp.Status)
+	if resp.Fixture != "" {
+		b, err := os.ReadFile(filepath.Join("testdata", f.dir, resp.Fixture))
+		if err != nil {
+			f.t.Errorf("reading fixture: %v", err)
+			return
+		}
+		_, _ = w.Write(b)
+	}
+}
+
+func (f *fakeGitHub) Requests() []*http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*http.Request(nil), f.requests...)
+}
+
+func newActivityEnv(a *Activities) *testsuite.TestActivityEnvironment {
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestActivityEnvironment()
+	env.RegisterActivity(a)
+	return env
+}
+
+func reposPage(page string) string {
+	return "/orgs/acme-corp/repos?per_page=100&page=" + page
+}
+
+func TestFetchOrgRepos(t *testing.T) {
+	tests := []struct {
+		name      string
+		routes    map[string]fakeResponse
+		wantCount int
+		wantType  string // ApplicationError type; "" means success
+		retryable bool
+	}{
+		{
+			name: "multi-page",
+			routes: map[string]fakeResponse{
+				reposPage("1"): {http.StatusOK, "org_repos_page1.json"},
+				reposPage("2"): {http.StatusOK, "org_repos_page2.json"},
+			},
+			wantCount: 103,
+		},
+		{
+			name: "exact multiple of page size stops on empty page",
+			routes: map[string]fakeResponse{
+				reposPage("1"): {http.StatusOK, "org_repos_page1.json"},
+				reposPage("2"): {http.StatusOK, "org_repos_empty.json"},
+			},
+			wantCount: 100,
+		},
+		{
+			name: "org not found",
+			routes: map[string]fakeResponse{
+				reposPage("1"): {http.StatusNotFound, "not_found.json"},
+			},
+			wantType: "NOT_FOUND",
+		},
+		{
+			name: "bad token",
+			routes: map[string]fakeResponse{
+				reposPage("1"): {http.StatusUnauthorized, "bad_credentials.json"},
+			},
+			wantType: "UNAUTHORIZED",
+		},
+		{
+			name: "rate limited is retryable",
+			routes: map[string]fakeResponse{
+				reposPage("1"): {http.StatusForbidden, "rate_limited.json"},
+			},
+			retryable: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, a := newFakeGitHub(t, tc.routes)
+			env := newActivityEnv(a)
+
+			val, err := env.ExecuteActivity(a.FetchOrgRepos, ScanInput{Org: "acme-corp"})
+
+			if tc.wantType == "" && !tc.retryable {
+				require.NoError(t, err)
+				var repos []RepoInfo
+				require.NoError(t, val.Get(&repos))
+				require.Len(t, repos, tc.wantCount)
+				require.Equal(t, "service-000", repos[0].Name)
+				require.Equal(t, "acme-corp/service-000", repos[0].FullName)
+				require.True(t, repos[0].Private)
+				require.True(t, repos[42].Archived)
+				require.Equal(t, time.Date(2026, 2, 27, 17, 3, 8, 0, time.UTC), *repos[0].PushedAt)
+				require.Equal(t, time.Date(2026, 2, 27, 17, 3, 11, 0, time.UTC), *repos[0].UpdatedAt)
+				require.Equal(t, RepoMetadata{DefaultBranch: "main", Visibility: "private", Language: "Go"}, repos[0].RepoMetadata)
+				return
+			}
+
+			require.Error(t, err)
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(err, &appErr), "want ApplicationError, got %T: %v", err, err)
+			require.Equal(t, tc.retryable, !appErr.NonRetryable())
+			if tc.wantType != "" {
+				require.Equal(t, tc.wantType, appErr.Type())
+			}
+		})
+	}
+}
+
+func TestFetchOrgReposMetadata(t *testing.T) {
+	_, a := newFakeGitHub(t, map[string]fakeResponse{
+		reposPage("1"): {http.StatusOK, "org_repos_page2.json"},
+	})
+	env := newActivityEnv(a)
+
+	val, err := env.ExecuteActivity(a.FetchOrgRepos, ScanInput{Org: "acme-corp"})
+	require.NoError(t, err)
+	var repos []RepoInfo
+	require.NoError(t, val.Get(&repos))
+	require.Len(t, repos, 3)
+	require.Equal(t, RepoMetadata{DefaultBranch: "main", Visibility: "internal", Language: "HCL", Topics: []string{"platform", "terraform"}}, repos[0].RepoMetadata)
+	require.Equal(t, RepoMetadata{DefaultBranch: "main", Fork: true, Visibility: "public"}, repos[1].RepoMetadata, "a null language is empty")
+	require.Equal(t, "master", repos[2].DefaultBranch)
+
+	var old RepoInfo
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"api","full_name":"acme-corp/api","private":true,"archived":false}`), &old))
+	require.True(t, old.RepoMetadata.isZero(), "payloads without metadata still decode")
+}
+
+func TestFetchOrgReposSendsTokenHeader(t *testing.T) {
+	f, a := newFakeGitHub(t, map[string]fakeResponse{
+		reposPage("1"): {http.StatusOK, "org_repos_page2.json"},
+	})
+	env := newActivityEnv(a)
+	token := "ghp_test"
+
+	_, err := env.ExecuteActivity(a.FetchOrgRepos, ScanInput{Org: "acme-corp", Token: &token})
+	require.NoError(t, err)
+
+	reqs := f.Requests()
+	require.Len(t, reqs, 1)
+	require.Equal(t, "token ghp_test", reqs[0].Header.Get("Authorization"))
+	require.Equal(t, "application/vnd.github+json", reqs[0].Header.Get("Accept"))
+}
+
+func TestCheckRepoSecurity(t *testing.T) {
+	const repoPath = "/repos/acme-corp/payments-api"
+
+	tests := []struct {
+		name           string
+		repo           fakeResponse
+		dependabot     fakeResponse
+		codeScanning   fakeResponse
+		wantSecret     SecurityStatus
+		wantDependabot SecurityStatus
+		wantCode       SecurityStatus
+		wantCompliant  bool
+	}{
+		{
+			name:           "everything enabled",
+			repo:           fakeResponse{http.StatusOK, "repo_secret_scanning_enabled.json"},
+			dependabot:     fakeResponse{http.StatusNoContent, ""},
+			codeScanning:   fakeResponse{http.StatusOK, "code_scanning_alerts.json"},
+			wantSecret:     StatusEnabled,
+			wantDependabot: StatusEnabled,
+			wantCode:       StatusEnabled,
+			wantCompliant:  true,
+		},
+		{
+			name:           "code scanning enabled with no alerts",
+			repo:           fakeResponse{http.StatusOK, "repo_secret_scanning_enabled.json"},
+			dependabot:     fakeResponse{http.StatusNoContent, ""},
+			codeScanning:   fakeResponse{http.StatusOK, "code_scanning_alerts_empty.json"},
+			wantSecret:     StatusEnabled,
+			wantDependabot: StatusEnabled,
+			wantCode:       StatusEnabled,
+			wantCompliant:  true,
+		},
+		{
+			name:           "secret scanning disabled",
+			repo:           fakeResponse{http.StatusOK, "repo_secret_scanning_disabled.json"},
+			dependabot:     fakeResponse{http.StatusNoContent, ""},
+			codeScanning:   fakeResponse{http.StatusOK, "code_scanning_alerts.json"},
+			wantSecret:     StatusDisabled,
+			wantDependabot: StatusEnabled,
+			wantCode:       StatusEnabled,
+		},
+		{
+			name:           "security_and_analysis absent",
+			repo:           fakeResponse{http.StatusOK, "repo_no_security_and_analysis.json"},
+			dependabot:     fakeResponse{http.StatusNotFound, "not_found.json"},
+			codeScanning:   fakeResponse{http.StatusNotFound, "code_scanning_no_analysis.json"},
+			wantSecret:     StatusDisabled,
+			wantDependabot: StatusDisabled,
+			wantCode:       StatusNotConfigured,
+		},
+		{
+			name:           "security_and_analysis null",
+			repo:           fakeResponse{http.StatusOK, "repo_security_and_analysis_null.json"},
+			dependabot:     fakeResponse{http.StatusNoContent, ""},
+			codeScanning:   fakeResponse{http.StatusForbidden, "code_scanning_ghas_disabled.json"},
+			wantSecret:     StatusDisabled,
+			wantDependabot: StatusEnabled,
+			wantCode:       StatusNoAccess,
+		},
+		{
+			name:           "unexpected statuses stay unknown",
+			repo:           fakeResponse{http.StatusForbidden, "rate_limited.json"},
+			dependabot:     fakeResponse{http.StatusForbidden, "rate_limited.json"},
+			codeScanning:   fakeResponse{http.StatusInternalServerError, ""},
+			wantSecret:     StatusUnknown,
+			wantDependabot: StatusUnknown,
+			wantCode:       StatusUnknown,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			routes := noRepoFiles(repoPath)
+			routes[repoPath] = tc.repo
+			routes[repoPath+"/vulnerability-alerts"] = tc.dependabot
+			routes[repoPath+"/code-scanning/alerts"] = tc.codeScanning
+			_, a := newFakeGitHub(t, routes)
+			env := newActivityEnv(a)
+
+			val, err := env.ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), []string(nil))
+			require.NoError(t, err)
+
+			var result RepoSecurityResult
+			require.NoError(t, val.Get(&result))
+			require.Equal(t, "payments-api", result.Repository)
+			require.Equal(t, ProviderGitHub, result.Provider)
+			require.Equal(t, tc.wantSecret, result.SecretScanning)
+			require.Equal(t, tc.wantDependabot, result.DependabotAlerts)
+			require.Equal(t, tc.wantCode, result.CodeScanning)
+			require.Equal(t, tc.wantSecret, result.Check(CheckSecretScanning).Status)
+			require.Equal(t, tc.wantDependabot, result.Check(CheckDependabot).Status)
+			require.Equal(t, tc.wantCode, result.Check(CheckCodeScanning).Status)
+			require.Equal(t, tc.wantCompliant, result.IsFullyCompliant())
+			require.Nil(t, result.Error)
+			require.NotEmpty(t, result.ScannedAt)
+		})
+	}
+}
+
+// noRepoFiles returns routes for a repo with neither CODEOWNERS nor SECURITY.md.
+func noRepoFiles(repoPath string) map[string]fakeResponse {
+	routes := map[string]fakeResponse{
+		repoPath + "/community/profile": {http.StatusOK, "community_profile_no_security.json"},
+	}
+	for _, dir := range repoFileDirs {
+		routes[repoPath+"/contents/"+dir+"CODEOWNERS"] = fakeResponse{http.StatusNotFound, "not_found.json"}
+	}
+	return routes
+}
+
+func TestCheckRepoSecurityFiles(t *testing.T) {
+	const repoPath = "/repos/acme-corp/payments-api"
+	notFound := fakeResponse{http.StatusNotFound, "not_found.json"}
+	forbidden := fakeResponse{http.StatusForbidden, "contents_forbidden.json"}
+	yes, no := true, false
+
+	tests := []struct {
+		name           string
+		routes         map[string]fakeResponse
+		wantCodeowners *bool
+		wantSecurity   *bool
+	}{
+		{
+			name:           "neither file",
+			wantCodeowners: &no,
+			wantSecurity:   &no,
+		},
+		{
+			name: "CODEOWNERS in .github and SECURITY.md at root",
+			routes: map[string]fakeResponse{
+				repoPath + "/contents/.github/CODEOWNERS": {http.StatusOK, "contents_codeowners.json"},
+				repoPath + "/community/profile":           {http.StatusOK, "community_profile_security.json"},
+				repoPath + "/contents/SECURITY.md":        {http.StatusOK, "contents_security_md.json"},
+			},
+			wantCodeowners: &yes,
+			wantSecurity:   &yes,
+		},
+		{
+			name: "empty files count as absent",
+			routes: map[string]fakeResponse{
+				repoPath + "/contents/CODEOWNERS":          {http.StatusOK, "contents_empty_file.json"},
+				repoPath + "/community/profile":            {http.StatusOK, "community_profile_security.json"},
+				repoPath + "/contents/SECURITY.md":         {http.StatusOK, "contents_empty_file.json"},
+				repoPath + "/contents/.github/SECURITY.md": notFound,
+				repoPath + "/contents/docs/SECURITY.md":    notFound,
+			},
+			wantCodeowners: &no,
+			wantSecurity:   &no,
+		},
+		{
+			name: "403 is unknown",
+			routes: map[string]fakeResponse{
+				repoPath + "/contents/CODEOWNERS":          forbidden,
+				repoPath + "/contents/.github/CODEOWNERS":  forbidden,
+				repoPath + "/contents/docs/CODEOWNERS":     forbidden,
+				repoPath + "/community/profile":            forbidden,
+				repoPath + "/contents/SECURITY.md":         forbidden,
+				repoPath + "/contents/.github/SECURITY.md": forbidden,
+				repoPath + "/contents/docs/SECURITY.md":    forbidden,
+			},
+		},
+		{
+			name: "found elsewhere despite a 403",
+			routes: map[string]fakeResponse{
+				repoPath + "/contents/CODEOWNERS":         forbidden,
+				repoPath + "/contents/.github/CODEOWNERS": {http.StatusOK, "contents_codeowners.json"},
+			},
+			wantCodeowners: &yes,
+			wantSecurity:   &no,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			routes := noRepoFiles(repoPath)
+			routes[repoPath] = fakeResponse{http.StatusOK, "repo_secret_scanning_enabled.json"}
+			routes[repoPath+"/vulnerability-alerts"] = fakeResponse{http.StatusNoContent, ""}
+			routes[repoPath+"/code-scanning/alerts"] = fakeResponse{http.StatusOK, "code_scanning_alerts.json"}
+			for k, v := range tc.routes {
+				routes[k] = v
+			}
+			_, a := newFakeGitHub(t, routes)
+			env := newActivityEnv(a)
+
+			val, err := env.ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), append(DefaultChecks(), CheckFiles))
+			require.NoError(t, err)
+
+			var result RepoSecurityResult
+			require.NoError(t, val.Get(&result))
+			require.Equal(t, tc.wantCodeowners, result.HasCodeowners)
+			require.Equal(t, tc.wantSecurity, result.HasSecurityPolicy)
+			require.Equal(t, presenceResult(tc.wantCodeowners), result.Checks[ResultCodeowners])
+			require.Equal(t, presenceResult(tc.wantSecurity), result.Checks[ResultSecurityPolicy])
+			require.True(t, result.IsFullyCompliant(), "file checks are not required by default")
+
+			strict := DefaultCompliancePolicy()
+			strict.RequireCodeowners = true
+			strict.RequireSecurityPolicy = true
+			wantStrict := tc.wantCodeowners != nil && *tc.wantCodeowners && tc.wantSecurity != nil && *tc.wantSecurity
+			require.Equal(t, wantStrict, strict.IsCompliant(&result))
+		})
+	}
+}
+
+func TestCheckActionsSecurity(t *testing.T) {
+	const repoPath = "/repos/acme-corp/payments-api"
+
+	tests := []struct {
+		name     string
+		perms    fakeResponse
+		workflow *fakeResponse // nil: must not be requested
+		want     *ActionsSecurity
+	}{
+		{
+			name:     "selected actions, read-only token",
+			perms:    fakeResponse{http.StatusOK, "actions_permissions_selected.json"},
+			workflow: &fakeResponse{http.StatusOK, "actions_workflow_permissions_read.json"},
+			want:     &ActionsSecurity{ActionsEnabled: true, AllowedActions: AllowedActionsSelected, DefaultWorkflowPermissions: WorkflowPermissionsRead},
+		},
+		{
+			name:     "all actions, write token",
+			perms:    fakeResponse{http.StatusOK, "actions_permissions_all.json"},
+			workflow: &fakeResponse{http.StatusOK, "actions_workflow_permissions_write.json"},
+			want:     &ActionsSecurity{ActionsEnabled: true, AllowedActions: AllowedActionsAll, DefaultWorkflowPermissions: WorkflowPermissionsWrite},
+		},
+		{
+			name:  "actions disabled",
+			perms: fakeResponse{http.StatusOK, "actions_permissions_disabled.json"},
+			want:  &ActionsSecurity{ActionsEnabled: false},
+		},
+		{
+			name:  "404 means disabled",
+			perms: fakeResponse{http.StatusNotFound, "not_found.json"},
+			want:  &ActionsSecurity{ActionsEnabled: false},
+		},
+		{
+			name:  "no admin access is unknown",
+			perms: fakeResponse{http.StatusForbidden, "actions_forbidden.json"},
+		},
+		{
+			name:     "workflow permissions unreadable",
+			perms:    fakeResponse{http.StatusOK, "actions_permissions_selected.json"},
+			workflow: &fakeResponse{http.StatusForbidden, "actions_forbidden.json"},
+			want:     &ActionsSecurity{ActionsEnabled: true, AllowedActions: AllowedActionsSelected},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			routes := map[string]fakeResponse{repoPath + "/actions/permissions": tc.perms}
+			if tc.workflow != nil {
+				routes[repoPath+"/actions/permissions/workflow"] = *tc.workflow
+			}
+			_, a := newFakeGitHub(t, routes)
+			env := newActivityEnv(a)
+
+			val, err := env.ExecuteActivity(a.CheckActionsSecurity, "acme-corp", "payments-api", (*Credential)(nil))
+			require.NoError(t, err)
+
+			// A nil result travels as an empty payload.
+			var got *ActionsSecurity
+			if val.HasValue() {
+				require.NoError(t, val.Get(&got))
+			}
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestAuditRepoAccess(t *testing.T) {
+	const repoPath = "/repos/acme-corp/payments-api"
+	keysPage := repoPath + "/keys?per_page=100&page=1"
+	collabPage := repoPath + "/collaborators?affiliation=outside&per_page=100&page=1"
+
+	// Keys created before 2020 are stale, whenever the test runs.
+	maxAgeDays := int(time.Since(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+
+	t.Run("flags read-write and stale keys and privileged collaborators", func(t *testing.T) {
+		_, a := newFakeGitHub(t, map[string]fakeResponse{
+			keysPage:   {http.StatusOK, "deploy_keys.json"},
+			collabPage: {http.StatusOK, "collaborators_outside.json"},
+		})
+		env := newActivityEnv(a)
+
+		val, err := env.ExecuteActivity(a.AuditRepoAccess, "acme-corp", "payments-api", (*Credential)(nil), maxAgeDays)
+		require.NoError(t, err)
+		var audit AccessAudit
+		require.NoError(t, val.Get(&audit))
+
+		require.Equal(t, 3, audit.DeployKeys)
+		require.Equal(t, []FlaggedDeployKey{
+			{ID: 9002, Title: "release-bot", ReadOnly: false, CreatedAt: "2026-01-12T08:00:00Z"},
+			{ID: 9003, Title: "legacy-mirror", ReadOnly: true, CreatedAt: "2019-03-04T12:00:00Z", Stale: true},
+		}, audit.FlaggedDeployKeys)
+		require.Equal(t, []OutsideCollaborator{
+			{Login: "contractor-alice", Permission: "write"},
+			{Login: "agency-carol", Permission: "admin"},
+		}, audit.OutsideCollaborators)
+		require.Empty(t, audit.NoAccess)
+		require.Equal(t, 4, audit.Findings())
+	})
+
+	t.Run("missing admin scope is no access, not an error", func(t *testing.T) {
+		_, a := newFakeGitHub(t, map[string]fakeResponse{
+			keysPage:   {http.StatusNotFound, "not_found.json"},
+			collabPage: {http.StatusForbidden, "admin_required.json"},
+		})
+		env := newActivityEnv(a)
+
+		val, err := env.ExecuteActivity(a.AuditRepoAccess, "acme-corp", "payments-api", (*Credential)(nil), 0)
+		require.NoError(t, err)
+		var audit AccessAudit
+		require.NoError(t, val.Get(&audit))
+		require.Equal(t, []string{AccessDeployKeys, AccessCollaborators}, audit.NoAccess)
+		require.Zero(t, audit.DeployKeys)
+		require.Zero(t, audit.Findings())
+	})
+}
+
+func TestCheckRepoSecurityRepoNotFound(t *testing.T) {
+	f, a := newFakeGitHub(t, map[string]fakeResponse{
+		"/repos/acme-corp/gone": {http.StatusNotFound, "not_found.json"},
+	})
+	env := newActivityEnv(a)
+
+	val, err := env.ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "gone", (*Credential)(nil), []string(nil))
+	require.NoError(t, err)
+
+	var result RepoSecurityResult
+	require.NoError(t, val.Get(&result))
+	require.Nil(t, result.Error, "a repo deleted since the listing is no error")
+	require.Equal(t, DisappearedDeleted, result.Disappeared.Reason)
+	require.Len(t, f.Requests(), 1, "should stop after the repo lookup 404s")
+}
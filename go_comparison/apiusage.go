@@ -0,0 +1,379 @@
+package scanner
+
+// =============================================================================
+// API usage — who spent the rate limit, and an optional budget per scan
+// =============================================================================
+//
+// Every GitHub or GitLab request an activity makes goes through
+// Activities.do, which counts it by endpoint category against the scan run
+// that scheduled the activity. The run comes from APIUsageTracker's worker
+// interceptor: the workflow stamps its ID, run ID, and ScanInput.MaxAPIRequests
+// on its context, the interceptor carries them in the header of every
+// activity and ScanBatchWorkflow child it starts, and hands the activity its
+// run's counter. Requests from a child's activities therefore count toward
+// the parent scan.
+//
+// Totals are served in the Prometheus text format (see ServeHTTP) and read
+// back by the GetAPIUsage local activity into the report's api_usage
+// section. Once a scan has made MaxAPIRequests requests, each further
+// request fails with ErrTypeAPIBudgetExceeded; the workflow stops between
+// batches and reports what it scanned.
+//
+// Counts live in the worker process. With several workers, each counts and
+// budgets only the requests it made, and the report shows the share of the
+// worker that built it; Prometheus, scraping every worker, has the sum.
+// =============================================================================
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// ErrTypeAPIBudgetExceeded is the ApplicationError type of a request refused
+// because the scan has made ScanInput.MaxAPIRequests requests.
+const ErrTypeAPIBudgetExceeded = "API_BUDGET_EXCEEDED"
+
+// APIUsage is a scan's API requests, the report's api_usage section.
+type APIUsage struct {
+	Requests   int            `json:"requests"`
+	ByCategory map[string]int `json:"by_category"`
+
+	// MaxRequests is ScanInput.MaxAPIRequests; 0 means no budget.
+	MaxRequests int `json:"max_requests,omitempty"`
+	// BudgetExceeded is set once a request was refused for the budget.
+	BudgetExceeded bool `json:"budget_exceeded,omitempty"`
+}
+
+// apiScope is the scan run a request is counted against.
+type apiScope struct {
+	WorkflowID  string `json:"workflow_id"`
+	RunID       string `json:"run_id"`
+	MaxRequests int    `json:"max_requests,omitempty"`
+}
+
+func (s apiScope) key() string { return s.WorkflowID + "/" + s.RunID }
+
+// apiScopeHeader is the activity and child workflow header holding the
+// apiScope.
+const apiScopeHeader = "scanner-api-scope"
+
+type apiScopeKey struct{}
+
+// withAPIScope marks ctx so activities and children started from it count
+// their requests against scope.
+func withAPIScope(ctx workflow.Context, scope apiScope) workflow.Context {
+	return workflow.WithValue(ctx, apiScopeKey{}, scope)
+}
+
+// runUsageRetention is how long a run's counts are kept after its last
+// request.
+const runUsageRetention = time.Hour
+
+// APIUsageTracker counts the API requests of every scan run on a worker.
+// Register Interceptor on the worker and set Activities.APIUsage to the same
+// tracker.
+type APIUsageTracker struct {
+	mu       sync.Mutex
+	runs     map[string]*runUsage
+	totals   map[string]int64 // by category, for the lifetime of the process
+	rejected int64
+	now      func() time.Time
+}
+
+type runUsage struct {
+	scope      apiScope
+	requests   int
+	byCategory map[string]int
+	exceeded   bool
+	updated    time.Time
+}
+
+// NewAPIUsageTracker returns an empty tracker.
+func NewAPIUsageTracker() *APIUsageTracker {
+	return &APIUsageTracker{
+		runs:   make(map[string]*runUsage),
+		totals: make(map[string]int64),
+		now:    time.Now,
+	}
+}
+
+// run returns scope's counter, creating it and dropping runs idle for
+// longer than runUsageRetention.
+func (t *APIUsageTracker) run(scope apiScope) *runUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	if u, ok := t.runs[scope.key()]; ok {
+		if scope.MaxRequests > 0 {
+			u.scope.MaxRequests = scope.MaxRequests
+		}
+		return u
+	}
+	for k, u := range t.runs {
+		if now.Sub(u.updated) > runUsageRetention {
+			delete(t.runs, k)
+		}
+	}
+	u := &runUsage{scope: scope, byCategory: make(map[string]int), updated: now}
+	t.runs[scope.key()] = u
+	return u
+}
+
+// count records one request in category, or refuses it if the run has
+// spent its budget.
+func (t *APIUsageTracker) count(u *runUsage, category string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u.updated = t.now()
+	if max := u.scope.MaxRequests; max > 0 && u.requests >= max {
+		u.exceeded = true
+		t.rejected++
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("API budget of %d requests for this scan is spent", max),
+			ErrTypeAPIBudgetExceeded, nil)
+	}
+	u.requests++
+	u.byCategory[category]++
+	t.totals[category]++
+	return nil
+}
+
+// Usage returns the counts for a run; a run that made no requests has none.
+func (t *APIUsageTracker) Usage(workflowID, runID string) APIUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage := APIUsage{ByCategory: map[string]int{}}
+	u, ok := t.runs[apiScope{WorkflowID: workflowID, RunID: runID}.key()]
+	if !ok {
+		return usage
+	}
+	usage.Requests = u.requests
+	for c, n := range u.byCategory {
+		usage.ByCategory[c] = n
+	}
+	usage.MaxRequests = u.scope.MaxRequests
+	usage.BudgetExceeded = u.exceeded
+	return usage
+}
+
+// ServeHTTP writes the counts in the Prometheus text exposition format:
+// lifetime totals by category, refused requests, and per-run counts for
+// the runs still tracked.
+func (t *APIUsageTracker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = writePromGauges(w, t.promGauges())
+}
+
+func (t *APIUsageTracker) promGauges() []promGauge {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	totals := promGauge{name: "scanner_api_requests_total", kind: "counter",
+		help: "API requests made by scan activities, by endpoint category."}
+	for _, c := range sortedKeys(t.totals) {
+		totals.samples = append(totals.samples, promSample{labels: []string{"category", c}, value: float64(t.totals[c])})
+	}
+	rejected := promGauge{name: "scanner_api_budget_rejections_total", kind: "counter",
+		help:    "API requests refused because the scan's budget was spent.",
+		samples: []promSample{{value: float64(t.rejected)}}}
+	runs := promGauge{name: "scanner_api_run_requests",
+		help: "API requests of each recent scan run, by endpoint category."}
+	for _, k := range sortedKeys(t.runs) {
+		u := t.runs[k]
+		for _, c := range sortedKeys(u.byCategory) {
+			runs.samples = append(runs.samples, promSample{
+				labels: []string{"workflow_id", u.scope.WorkflowID, "run_id", u.scope.RunID, "category", c},
+				value:  float64(u.byCategory[c]),
+			})
+		}
+	}
+	return []promGauge{totals, rejected, runs}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetAPIUsage returns the API requests the run has made on this worker, or
+// nil when the worker does not track them.
+func (a *Activities) GetAPIUsage(ctx context.Context, workflowID, runID string) (*APIUsage, error) {
+	if a.APIUsage == nil {
+		return nil, nil
+	}
+	usage := a.APIUsage.Usage(workflowID, runID)
+	return &usage, nil
+}
+
+type runUsageKey struct{}
+
+// do sends an API request, counting it against the activity's scan run.
+// Activities run without the tracker's interceptor are not counted. With a
+// RateLimiter, the request waits for its token's turn (see ratelimiter.go).
+func (a *Activities) do(req *http.Request) (*http.Response, error) {
+	if u, ok := req.Context().Value(runUsageKey{}).(*runUsage); ok && a.APIUsage != nil {
+		if err := a.APIUsage.count(u, apiCategory(req.URL.EscapedPath())); err != nil {
+			return nil, err
+		}
+	}
+	if a.RateLimiter == nil {
+		return a.HTTPClient.Do(req)
+	}
+	if err := a.RateLimiter.Wait(req.Context(), req); err != nil {
+		return nil, err
+	}
+	resp, err := a.HTTPClient.Do(req)
+	if err == nil {
+		a.RateLimiter.Observe(req, resp.Header)
+	}
+	return resp, err
+}
+
+// apiCategory groups a GitHub or GitLab API path by what it asks for, e.g.
+// "repo" for /repos/{owner}/{repo} and "code_scanning" for its alerts.
+func apiCategory(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	// GitLab paths sit under /api/v4, and a project or group is one
+	// escaped segment.
+	if len(parts) > 2 && parts[0] == "api" {
+		parts = parts[2:]
+	}
+	switch {
+	case parts[0] == "rate_limit":
+		return "rate_limit"
+	case parts[0] == "orgs" || parts[0] == "groups":
+		if len(parts) < 3 {
+			return "org"
+		}
+		if parts[2] == "repos" || parts[2] == "projects" || parts[len(parts)-1] == "repos" {
+			return "list_repos"
+		}
+		return "org_" + parts[2]
+	case parts[0] == "repos" && len(parts) == 3, parts[0] == "projects" && len(parts) == 2:
+		return "repo"
+	case parts[0] == "repos" && len(parts) > 3:
+		return strings.ReplaceAll(parts[3], "-", "_")
+	case parts[0] == "projects" && len(parts) > 2 && parts[2] == "repository":
+		return "contents"
+	case parts[0] == "projects" && len(parts) > 2:
+		return parts[2]
+	}
+	return "other"
+}
+
+// isBudgetExceeded reports whether err is, or wraps, an
+// ErrTypeAPIBudgetExceeded error.
+func isBudgetExceeded(err error) bool {
+	var appErr *temporal.ApplicationError
+	return errors.As(err, &appErr) && appErr.Type() == ErrTypeAPIBudgetExceeded
+}
+
+// Interceptor returns the worker interceptor that carries the scan run to
+// activities and children and gives each activity its run's counter.
+func (t *APIUsageTracker) Interceptor() interceptor.WorkerInterceptor {
+	return &apiUsageInterceptor{tracker: t}
+}
+
+type apiUsageInterceptor struct {
+	interceptor.WorkerInterceptorBase
+	tracker *APIUsageTracker
+}
+
+func (i *apiUsageInterceptor) InterceptActivity(ctx context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	a := &apiUsageActivityInbound{tracker: i.tracker}
+	a.Next = next
+	return a
+}
+
+func (i *apiUsageInterceptor) InterceptWorkflow(ctx workflow.Context, next interceptor.WorkflowInboundInterceptor) interceptor.WorkflowInboundInterceptor {
+	w := &apiUsageWorkflowInbound{}
+	w.Next = next
+	return w
+}
+
+type apiUsageActivityInbound struct {
+	interceptor.ActivityInboundInterceptorBase
+	tracker *APIUsageTracker
+}
+
+// ExecuteActivity counts the activity's requests against the run in its
+// header, or against its own workflow run when there is none.
+func (a *apiUsageActivityInbound) ExecuteActivity(ctx context.Context, in *interceptor.ExecuteActivityInput) (interface{}, error) {
+	ctx = context.WithValue(ctx, runUsageKey{}, a.tracker.run(activityScope(ctx)))
+	return a.Next.ExecuteActivity(ctx, in)
+}
+
+// activityScope is the scan run in the activity's header, or the
+// activity's own workflow run when there is none.
+func activityScope(ctx context.Context) apiScope {
+	var scope apiScope
+	if p, ok := interceptor.Header(ctx)[apiScopeHeader]; !ok || converter.GetDefaultDataConverter().FromPayload(p, &scope) != nil {
+		info := activity.GetInfo(ctx)
+		scope = apiScope{WorkflowID: info.WorkflowExecution.ID, RunID: info.WorkflowExecution.RunID}
+	}
+	return scope
+}
+
+type apiUsageWorkflowInbound struct {
+	interceptor.WorkflowInboundInterceptorBase
+}
+
+func (w *apiUsageWorkflowInbound) Init(outbound interceptor.WorkflowOutboundInterceptor) error {
+	o := &apiUsageWorkflowOutbound{}
+	o.Next = outbound
+	return w.Next.Init(o)
+}
+
+// ExecuteWorkflow gives a child the scope its parent sent.
+func (w *apiUsageWorkflowInbound) ExecuteWorkflow(ctx workflow.Context, in *interceptor.ExecuteWorkflowInput) (interface{}, error) {
+	var scope apiScope
+	if p, ok := interceptor.WorkflowHeader(ctx)[apiScopeHeader]; ok && converter.GetDefaultDataConverter().FromPayload(p, &scope) == nil {
+		ctx = withAPIScope(ctx, scope)
+	}
+	return w.Next.ExecuteWorkflow(ctx, in)
+}
+
+type apiUsageWorkflowOutbound struct {
+	interceptor.WorkflowOutboundInterceptorBase
+}
+
+func (o *apiUsageWorkflowOutbound) ExecuteActivity(ctx workflow.Context, activityType string, args ...interface{}) workflow.Future {
+	writeAPIScope(ctx)
+	return o.Next.ExecuteActivity(ctx, activityType, args...)
+}
+
+func (o *apiUsageWorkflowOutbound) ExecuteChildWorkflow(ctx workflow.Context, childWorkflowType string, args ...interface{}) workflow.ChildWorkflowFuture {
+	writeAPIScope(ctx)
+	return o.Next.ExecuteChildWorkflow(ctx, childWorkflowType, args...)
+}
+
+// writeAPIScope copies the scope on ctx, if any, into the header of the
+// call being made.
+func writeAPIScope(ctx workflow.Context) {
+	scope, ok := ctx.Value(apiScopeKey{}).(apiScope)
+	if !ok {
+		return
+	}
+	header := interceptor.WorkflowHeader(ctx)
+	if header == nil {
+		return
+	}
+	if p, err := converter.GetDefaultDataConverter().ToPayload(scope); err == nil {
+		header[apiScopeHeader] = p
+	}
+}
@@ -0,0 +1,190 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+)
+
+const paymentsAPI = "/repos/acme-corp/payments-api"
+
+// compliantRepoRoutes answers the DefaultChecks requests for payments-api.
+func compliantRepoRoutes() map[string]fakeResponse {
+	return map[string]fakeResponse{
+		paymentsAPI:                           {http.StatusOK, "repo_secret_scanning_enabled.json"},
+		paymentsAPI + "/vulnerability-alerts": {http.StatusNoContent, ""},
+		paymentsAPI + "/code-scanning/alerts": {http.StatusOK, "code_scanning_alerts.json"},
+	}
+}
+
+// trackedActivityEnv is newActivityEnv with a tracker and its interceptor,
+// running activities as part of scope.
+func trackedActivityEnv(t *testing.T, a *Activities, scope apiScope) *testsuite.TestActivityEnvironment {
+	t.Helper()
+	a.APIUsage = NewAPIUsageTracker()
+	env := newActivityEnv(a)
+	env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{a.APIUsage.Interceptor()}})
+	p, err := converter.GetDefaultDataConverter().ToPayload(scope)
+	require.NoError(t, err)
+	env.SetHeader(&commonpb.Header{Fields: map[string]*commonpb.Payload{apiScopeHeader: p}})
+	return env
+}
+
+func TestAPIUsageCountsRequestsByCategory(t *testing.T) {
+	_, a := newFakeGitHub(t, compliantRepoRoutes())
+	env := trackedActivityEnv(t, a, apiScope{WorkflowID: "security-scan-acme-corp", RunID: "run-1"})
+
+	_, err := env.ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), DefaultChecks())
+	require.NoError(t, err)
+
+	usage := a.APIUsage.Usage("security-scan-acme-corp", "run-1")
+	require.Equal(t, 3, usage.Requests)
+	require.Equal(t, map[string]int{"repo": 1, "vulnerability_alerts": 1, "code_scanning": 1}, usage.ByCategory)
+	require.False(t, usage.BudgetExceeded)
+
+	require.Zero(t, a.APIUsage.Usage("security-scan-acme-corp", "run-2").Requests)
+}
+
+func TestAPIUsageBudget(t *testing.T) {
+	_, a := newFakeGitHub(t, compliantRepoRoutes())
+	env := trackedActivityEnv(t, a, apiScope{WorkflowID: "security-scan-acme-corp", RunID: "run-1", MaxRequests: 2})
+
+	_, err := env.ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), DefaultChecks())
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr), "got %v", err)
+	require.Equal(t, ErrTypeAPIBudgetExceeded, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+
+	usage := a.APIUsage.Usage("security-scan-acme-corp", "run-1")
+	require.Equal(t, 2, usage.Requests, "the refused request is not counted")
+	require.Equal(t, 2, usage.MaxRequests)
+	require.True(t, usage.BudgetExceeded)
+}
+
+func TestAPIUsageMetrics(t *testing.T) {
+	tracker := NewAPIUsageTracker()
+	run := tracker.run(apiScope{WorkflowID: "security-scan-acme-corp", RunID: "run-1", MaxRequests: 1})
+	require.NoError(t, tracker.count(run, "repo"))
+	require.Error(t, tracker.count(run, "repo"))
+
+	rec := httptest.NewRecorder()
+	tracker.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	require.Contains(t, body, "# TYPE scanner_api_requests_total counter\n")
+	require.Contains(t, body, `scanner_api_requests_total{category="repo"} 1`+"\n")
+	require.Contains(t, body, "scanner_api_budget_rejections_total 1\n")
+	require.Contains(t, body, `scanner_api_run_requests{workflow_id="security-scan-acme-corp",run_id="run-1",category="repo"} 1`+"\n")
+
+	// Label values are escaped as the exposition format wants, not as Go
+	// strings.
+	tracker.run(apiScope{WorkflowID: "scan \"é\"\\\n", RunID: "run-2"}).byCategory["repo"] = 1
+	rec = httptest.NewRecorder()
+	tracker.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, rec.Body.String(), `scanner_api_run_requests{workflow_id="scan \"é\"\\\n",run_id="run-2",category="repo"} 1`+"\n")
+}
+
+func TestAPICategory(t *testing.T) {
+	for path, want := range map[string]string{
+		"/orgs/acme-corp/repos":                                     "list_repos",
+		"/orgs/acme-corp":                                           "org",
+		"/orgs/acme-corp/teams/platform/repos":                      "list_repos",
+		"/repos/acme-corp/payments-api":                             "repo",
+		"/repos/acme-corp/payments-api/vulnerability-alerts":        "vulnerability_alerts",
+		"/repos/acme-corp/payments-api/contents/.github/CODEOWNERS": "contents",
+		"/repos/acme-corp/payments-api/actions/permissions":         "actions",
+		"/rate_limit": "rate_limit",
+		"/api/v4/groups/acme%2Fplatform/projects":                  "list_repos",
+		"/api/v4/projects/acme%2Fapi":                              "repo",
+		"/api/v4/projects/acme%2Fapi/repository/files/SECURITY.md": "contents",
+	} {
+		require.Equal(t, want, apiCategory(path), path)
+	}
+}
+
+// TestWorkflowReportsAPIUsage runs real activities through the interceptor
+// and expects their requests in the report, including those made by a
+// batch child's activities.
+func TestWorkflowReportsAPIUsage(t *testing.T) {
+	for _, childPerBatch := range []bool{false, true} {
+		childPerBatch := childPerBatch
+		t.Run(fmt.Sprintf("child per batch %t", childPerBatch), func(t *testing.T) {
+			_, a := newFakeGitHub(t, compliantRepoRoutes())
+			a.APIUsage = NewAPIUsageTracker()
+			var s testsuite.WorkflowTestSuite
+			env := s.NewTestWorkflowEnvironment()
+			env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{a.APIUsage.Interceptor()}})
+			env.RegisterActivity(a)
+			env.RegisterWorkflow(ScanBatchWorkflow)
+
+			env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+				Org: "acme-corp", Repos: []string{"acme-corp/payments-api"}, ChildPerBatch: childPerBatch,
+			})
+			require.NoError(t, env.GetWorkflowError())
+
+			var report struct {
+				APIUsage *APIUsage `json:"api_usage"`
+			}
+			require.NoError(t, env.GetWorkflowResult(&report))
+			require.NotNil(t, report.APIUsage)
+			require.Equal(t, 3, report.APIUsage.Requests)
+			require.Equal(t, 1, report.APIUsage.ByCategory["code_scanning"])
+		})
+	}
+}
+
+// TestWorkflowStopsWhenBudgetExceeded has the budget run out in the second
+// batch: the scan finishes that batch, skips the rest, and reports what it
+// scanned without counting the cut-short repos as errors.
+func TestWorkflowStopsWhenBudgetExceeded(t *testing.T) {
+	var repos []RepoInfo
+	for i := 0; i < 25; i++ {
+		repos = append(repos, RepoInfo{Name: fmt.Sprintf("repo-%02d", i)})
+	}
+	env := newTestEnv(t)
+	onListOrgRepos(env, repos)
+	compliant := compliantUnless()
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, org, repo string, cred *Credential, checks []string) (*RepoSecurityResult, error) {
+			if repo >= "repo-12" {
+				return nil, temporal.NewNonRetryableApplicationError("API budget of 36 requests for this scan is spent", ErrTypeAPIBudgetExceeded, nil)
+			}
+			return compliant(ctx, org, repo, cred, checks)
+		})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme-corp", MaxAPIRequests: 36})
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 20)
+
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 12, report.TotalRepos)
+	require.Zero(t, report.Errors)
+	require.NotNil(t, report.APIUsage)
+	require.True(t, report.APIUsage.BudgetExceeded)
+	require.Equal(t, 36, report.APIUsage.MaxRequests)
+	// The eight refused in flight and the five never started.
+	require.Len(t, report.UnscannedRepos, 13)
+	require.Equal(t, "repo-12", report.UnscannedRepos[0])
+	require.Equal(t, "repo-24", report.UnscannedRepos[12])
+}
+
+func TestWorkflowRejectsNegativeAPIBudget(t *testing.T) {
+	env := newTestEnv(t)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme-corp", MaxAPIRequests: -1})
+
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+}
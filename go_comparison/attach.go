@@ -0,0 +1,113 @@
+package scanner
+
+// =============================================================================
+// Attaching to a running scan
+// =============================================================================
+//
+// "Make sure org X is being scanned with these parameters" is one command
+// (the starter's --ensure): start the scan, or, when one already runs
+// under the workflow ID, send it the update_scan_config update with the
+// caller's ScanInput. The update changes nothing; its result is the scan's
+// current ScanProgress.
+//
+// The update's validator rejects a caller whose input describes a
+// different scan: other checks, repos, teams, provider, policies or
+// suppressions. Settings that only change how the scan runs (token, batch
+// delay, batching, progress interval, offload size, deadline, worker
+// affinity) do not count, nor do who started it and why: a second caller
+// with its own --initiated-by and --reason attaches to the same scan.
+// Rejected updates leave no trace in the workflow's history.
+// =============================================================================
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// UpdateScanConfig is the update that attaches a caller to a running scan.
+const UpdateScanConfig = "update_scan_config"
+
+// ErrTypeScanConfigMismatch is the ApplicationError type of an
+// UpdateScanConfig whose input describes a different scan.
+const ErrTypeScanConfigMismatch = "SCAN_CONFIG_MISMATCH"
+
+// scanDefinition is in without the settings that do not change what is
+// scanned, and with defaults filled in, as JSON fields.
+func (in ScanInput) scanDefinition() map[string]interface{} {
+	def := in
+	def.Token, def.Credential = nil, nil
+	def.ResultsOffloadBytes = 0
+	def.ChildPerBatch = false
+	def.ActivityBatching = false
+	def.Concurrency = 0
+	def.ProgressIntervalSeconds = 0
+	def.ProgressWebhook = nil
+	def.Sinks = nil
+	def.RetryPolicies = nil
+	def.Timeouts = nil
+	def.StragglerTimeout = nil
+	def.BatchDelay = nil
+	def.InitiatedBy, def.Reason = "", ""
+	def.Deadline, def.MaxDurationSeconds = nil, 0
+	def.WorkerAffinity = false
+	def.Checks = in.checks().names()
+	def.IncludeAccessAudit = false
+	def.Provider = providerName(in.Provider)
+	if def.DeployKeyMaxAgeDays == 0 {
+		def.DeployKeyMaxAgeDays = DefaultDeployKeyMaxAgeDays
+	}
+	b, _ := json.Marshal(def)
+	var m map[string]interface{}
+	_ = json.Unmarshal(b, &m)
+	return m
+}
+
+// sameScan returns an error naming the fields in which in describes a
+// different scan than running.
+func (in ScanInput) sameScan(running ScanInput) error {
+	want, have := in.scanDefinition(), running.scanDefinition()
+	var differ []string
+	for _, field := range sortedKeys(mergeKeys(want, have)) {
+		if !reflect.DeepEqual(want[field], have[field]) {
+			differ = append(differ, field)
+		}
+	}
+	if len(differ) == 0 {
+		return nil
+	}
+	return temporal.NewNonRetryableApplicationError(
+		"the running scan has different "+strings.Join(differ, ", "), ErrTypeScanConfigMismatch, nil, differ)
+}
+
+func mergeKeys(a, b map[string]interface{}) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	return keys
+}
+
+// setScanConfigUpdate registers UpdateScanConfig for the scan of input.
+func setScanConfigUpdate(ctx workflow.Context, input ScanInput, progress func() ScanProgress) error {
+	err := workflow.SetUpdateHandlerWithOptions(ctx, UpdateScanConfig,
+		func(ctx workflow.Context, caller ScanInput) (ScanProgress, error) {
+			return progress(), nil
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(ctx workflow.Context, caller ScanInput) error {
+				return caller.sameScan(input)
+			},
+		})
+	if err != nil {
+		return fmt.Errorf("registering %s update: %w", UpdateScanConfig, err)
+	}
+	return nil
+}
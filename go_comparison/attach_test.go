@@ -0,0 +1,99 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+// updateResult records the outcome of a test environment update.
+type updateResult struct {
+	rejected error
+	progress ScanProgress
+	err      error
+}
+
+func (u *updateResult) Accept()          {}
+func (u *updateResult) Reject(err error) { u.rejected = err }
+func (u *updateResult) Complete(success interface{}, err error) {
+	u.err = err
+	if p, ok := success.(ScanProgress); ok {
+		u.progress = p
+	}
+}
+
+func TestScanSameScan(t *testing.T) {
+	token := "ghp_other"
+	running := ScanInput{Org: "acme", Checks: []string{CheckSecretScanning, CheckDependabot}}
+	require.NoError(t, ScanInput{
+		Org: "acme", Checks: []string{CheckDependabot, CheckSecretScanning}, Token: &token,
+		BatchDelay: &BatchDelay{Seconds: 5}, ActivityBatching: true, Provider: ProviderGitHub,
+	}.sameScan(running), "only how the scan runs differs")
+	require.NoError(t, ScanInput{Org: "acme"}.sameScan(ScanInput{Org: "acme", Checks: DefaultChecks()}))
+	require.NoError(t, ScanInput{Org: "acme", Checks: []string{CheckSecretScanning}, IncludeAccessAudit: true}.
+		sameScan(ScanInput{Org: "acme", Checks: []string{CheckSecretScanning, CheckAccessAudit}}))
+	deadline := time.Date(2026, 3, 2, 6, 0, 0, 0, time.UTC)
+	require.NoError(t, ScanInput{
+		Org: "acme", InitiatedBy: "oncall", Reason: "incident INC-1234",
+		Deadline: &deadline, MaxDurationSeconds: 3600, WorkerAffinity: true,
+	}.sameScan(ScanInput{Org: "acme", InitiatedBy: "schedule:weekly"}), "who started it, why and its time box do not count")
+
+	err := ScanInput{Org: "acme", Teams: []string{"platform"}, MaxAPIRequests: 100}.sameScan(running)
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, ErrTypeScanConfigMismatch, appErr.Type())
+	require.ErrorContains(t, err, "the running scan has different checks, max_api_requests, teams")
+}
+
+func TestWorkflowScanConfigUpdate(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(25))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	// During the first pause between batches, one caller attaches and
+	// another asks for a different scan.
+	var attached, other updateResult
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(UpdateScanConfig, "attach-1", &attached, ScanInput{Org: "acme"})
+		env.UpdateWorkflow(UpdateScanConfig, "attach-2", &other, ScanInput{Org: "acme", Checks: []string{CheckDependabot}})
+	}, 10*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", BatchDelay: &BatchDelay{Seconds: 60}})
+
+	require.NoError(t, env.GetWorkflowError())
+	require.NoError(t, attached.rejected)
+	require.NoError(t, attached.err)
+	require.Equal(t, ScanPaused, attached.progress.Status)
+	require.Equal(t, 10, attached.progress.ScannedRepos)
+	require.Equal(t, 25, attached.progress.TotalRepos)
+
+	require.ErrorContains(t, other.rejected, "the running scan has different checks")
+}
+
+func TestWorkflowScanConfigUpdateOtherInitiator(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(25))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	var attached updateResult
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(UpdateScanConfig, "attach-1", &attached,
+			ScanInput{Org: "acme", InitiatedBy: "oncall", Reason: "incident INC-1234"})
+	}, 10*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org: "acme", InitiatedBy: "schedule:weekly", Reason: "weekly scan",
+		BatchDelay: &BatchDelay{Seconds: 60},
+	})
+
+	require.NoError(t, env.GetWorkflowError())
+	require.NoError(t, attached.rejected, "a different initiator and reason attach")
+	require.NoError(t, attached.err)
+	require.Equal(t, 10, attached.progress.ScannedRepos)
+}
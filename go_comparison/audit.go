@@ -0,0 +1,315 @@
+package scanner
+
+// =============================================================================
+// Audit log — an append-only record of every scan's execution
+// =============================================================================
+//
+// Auditors ask what was scanned, by whom, with which parameters, and what
+// came of it. Workers with an audit log configured get two records per run
+// from AppendAuditLog: scan_started once the input has validated, and
+// scan_finished however the run ends — completed, cancelled, degraded or
+// failed. A run that fails validation scanned nothing and records nothing.
+// The workflow writes scan_finished on each of its returns, not from a
+// defer, which would also run when the worker evicts the workflow from its
+// cache. ResolveScanConfig tells the workflow whether the worker keeps a
+// log; without one, it schedules no AppendAuditLog at all.
+//
+// Records never carry the token: the input's token is dropped and only its
+// SHA-256 kept, which is enough to tell two tokens apart. scan_finished
+// carries the SHA-256 of the report the run returned (ReportDigest), so a
+// saved report can be checked against the log.
+//
+// The log is a local NDJSON file the worker only ever appends to, or, for
+// s3://bucket/prefix, one object per record under
+// prefix/<workflow ID>/<run ID>/<event>.json; S3 has no append, so make
+// the bucket immutable with Object Lock. A retried append does not write a
+// record twice.
+// =============================================================================
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Audit record events.
+const (
+	AuditScanStarted  = "scan_started"
+	AuditScanFinished = "scan_finished"
+)
+
+// Outcomes of scan_finished records besides the run's final ScanStatus.
+const (
+	AuditOutcomeFailed    = "failed"
+	AuditOutcomeCancelled = "cancelled"
+)
+
+// AuditRecord is one line of the audit log.
+type AuditRecord struct {
+	Event string `json:"event"`
+	// ID is unique per record: the run and the event.
+	ID         string    `json:"id"`
+	At         time.Time `json:"at"`
+	WorkflowID string    `json:"workflow_id"`
+	RunID      string    `json:"run_id"`
+	Org        string    `json:"org"`
+	Provider   string    `json:"provider"`
+
+	InitiatedBy string `json:"initiated_by,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+
+	// Input is the scan's parameters without the token, on scan_started.
+	Input *ScanInput `json:"input,omitempty"`
+	// TokenSHA256 is the hex SHA-256 of the token the scan was started
+	// with; empty when it used the worker's.
+	TokenSHA256 string `json:"token_sha256,omitempty"`
+
+	// Outcome is the run's final ScanStatus, or AuditOutcomeFailed or
+	// AuditOutcomeCancelled when it returned an error; Error is that error.
+	Outcome string       `json:"outcome,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Counts  *AuditCounts `json:"counts,omitempty"`
+	// ReportSHA256 is ReportDigest of the report the run returned, or of
+	// the partial report a degraded run returned with its error.
+	ReportSHA256 string `json:"report_sha256,omitempty"`
+
+	// Worker, the host that wrote the record, and RecordedAt are stamped
+	// by AppendAuditLog.
+	Worker     string    `json:"worker,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// AuditCounts are the scan's progress counts when it finished.
+type AuditCounts struct {
+	TotalRepos         int `json:"total_repos"`
+	ScannedRepos       int `json:"scanned_repos"`
+	CompliantRepos     int `json:"compliant_repos"`
+	NonCompliantRepos  int `json:"non_compliant_repos"`
+	IndeterminateRepos int `json:"indeterminate_repos"`
+	Errors             int `json:"errors"`
+	DisappearedRepos   int `json:"disappeared_repos,omitempty"`
+	CancelledInFlight  int `json:"cancelled_in_flight,omitempty"`
+}
+
+// AuditLog stores audit records. Append must not write a record whose ID
+// it already holds.
+type AuditLog interface {
+	Append(ctx context.Context, rec AuditRecord) error
+}
+
+// OpenAuditLog builds an AuditLog from a URI:
+//
+//	/var/log/scanner/audit.ndjson   (or file:///…)
+//	s3://bucket/optional/prefix     (credentials from AWS_* env vars)
+func OpenAuditLog(uri string) (AuditLog, error) {
+	switch {
+	case strings.HasPrefix(uri, "s3://"):
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, fmt.Errorf("parsing audit log URI: %w", err)
+		}
+		store, err := NewS3BlobStoreFromEnv(u.Host, strings.Trim(u.Path, "/"))
+		if err != nil {
+			return nil, err
+		}
+		return &BlobAuditLog{Store: store}, nil
+	case strings.HasPrefix(uri, "file://"):
+		return &FileAuditLog{Path: strings.TrimPrefix(uri, "file://")}, nil
+	case strings.Contains(uri, "://"):
+		return nil, fmt.Errorf("unsupported audit log URI %q", uri)
+	default:
+		return &FileAuditLog{Path: uri}, nil
+	}
+}
+
+// FileAuditLog appends records to an NDJSON file, one line each, and syncs
+// the file before returning.
+type FileAuditLog struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (l *FileAuditLog) Append(_ context.Context, rec AuditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.Path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if found, err := hasAuditRecord(f, rec.ID); err != nil || found {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// hasAuditRecord reports whether the log holds a record with the given ID.
+func hasAuditRecord(f *os.File, id string) (bool, error) {
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		var rec struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(sc.Bytes(), &rec) == nil && rec.ID == id {
+			return true, nil
+		}
+	}
+	return false, sc.Err()
+}
+
+// BlobAuditLog writes each record as its own blob, keyed by its ID.
+// Writing a record again replaces it with the same content.
+type BlobAuditLog struct {
+	Store BlobStore
+}
+
+func (l *BlobAuditLog) Append(ctx context.Context, rec AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = l.Store.Put(ctx, rec.ID+".json", append(data, '\n'))
+	return err
+}
+
+// ReportDigest is the hex SHA-256 of a JSON report in canonical form —
+// compact, with object keys sorted — so the indented file the starter
+// saves has the digest the audit log recorded for it.
+func ReportDigest(report []byte) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(report, &v); err != nil {
+		return "", fmt.Errorf("parsing report: %w", err)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return sha256Hex(canonical), nil
+}
+
+// AppendAuditLog writes rec to the worker's audit log, or does nothing
+// when the worker keeps none.
+func (a *Activities) AppendAuditLog(ctx context.Context, rec AuditRecord) error {
+	if a.AuditLog == nil {
+		return nil
+	}
+	rec.Worker, _ = os.Hostname()
+	rec.RecordedAt = time.Now().UTC()
+	if err := a.AuditLog.Append(ctx, rec); err != nil {
+		return fmt.Errorf("appending %s to the audit log: %w", rec.Event, err)
+	}
+	return nil
+}
+
+// auditRecord is the part of a record the workflow knows for both events.
+func auditRecord(ctx workflow.Context, event string, input ScanInput, progress *ScanProgress) AuditRecord {
+	return AuditRecord{
+		Event:       event,
+		ID:          progress.WorkflowID + "/" + progress.RunID + "/" + event,
+		At:          workflow.Now(ctx),
+		WorkflowID:  progress.WorkflowID,
+		RunID:       progress.RunID,
+		Org:         input.Org,
+		Provider:    providerName(input.Provider),
+		InitiatedBy: input.InitiatedBy,
+		Reason:      input.Reason,
+	}
+}
+
+// auditScanStarted appends the scan_started record. A worker that cannot
+// record the scan does not run it.
+func auditScanStarted(ctx workflow.Context, input ScanInput, progress *ScanProgress) error {
+	rec := auditRecord(ctx, AuditScanStarted, input, progress)
+	redacted := input
+	redacted.Token, redacted.Credential = nil, input.Credential.redacted()
+	rec.Input = &redacted
+	if cred := input.credential(); cred != nil && cred.Value != "" {
+		sum := sha256.Sum256([]byte(cred.Value))
+		rec.TokenSHA256 = hex.EncodeToString(sum[:])
+	}
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy:         githubRetryPolicy(),
+	})
+	if err := workflow.ExecuteActivity(actCtx, "AppendAuditLog", rec).Get(ctx, nil); err != nil {
+		return fmt.Errorf("recording the scan in the audit log: %w", err)
+	}
+	return nil
+}
+
+// auditScanFinished appends the scan_finished record for a run returning
+// report and err. It runs on a disconnected context, so a run cancelled
+// through Temporal is recorded too, and retries for up to an hour; a record
+// that still cannot be written is logged, and the run's result stands.
+func auditScanFinished(ctx workflow.Context, input ScanInput, progress *ScanProgress, report map[string]interface{}, err error) {
+	rec := auditRecord(ctx, AuditScanFinished, input, progress)
+	rec.Counts = &AuditCounts{
+		TotalRepos:         progress.TotalRepos,
+		ScannedRepos:       progress.ScannedRepos,
+		CompliantRepos:     progress.CompliantRepos,
+		NonCompliantRepos:  progress.NonCompliantRepos,
+		IndeterminateRepos: progress.IndeterminateRepos,
+		Errors:             progress.Errors,
+		DisappearedRepos:   progress.DisappearedRepos,
+		CancelledInFlight:  progress.CancelledInFlight,
+	}
+	var appErr *temporal.ApplicationError
+	switch {
+	case err == nil:
+		rec.Outcome = string(progress.Status)
+	case errors.As(err, &appErr) && appErr.Type() == ErrTypeScanDegraded:
+		rec.Outcome, rec.Error = string(ScanDegraded), err.Error()
+		if appErr.HasDetails() && appErr.Details(&report) != nil {
+			report = nil
+		}
+	case temporal.IsCanceledError(err):
+		rec.Outcome, rec.Error = AuditOutcomeCancelled, err.Error()
+	default:
+		rec.Outcome, rec.Error = AuditOutcomeFailed, err.Error()
+	}
+	logger := workflow.GetLogger(ctx)
+	if report != nil {
+		data, jsonErr := json.Marshal(report)
+		if jsonErr == nil {
+			rec.ReportSHA256, jsonErr = ReportDigest(data)
+		}
+		if jsonErr != nil {
+			logger.Warn("Could not digest the report for the audit log", "error", jsonErr)
+		}
+	}
+
+	ctx, _ = workflow.NewDisconnectedContext(ctx)
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout:    30 * time.Second,
+		ScheduleToCloseTimeout: time.Hour,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    5 * time.Minute,
+		},
+	})
+	if err := workflow.ExecuteActivity(actCtx, "AppendAuditLog", rec).Get(ctx, nil); err != nil {
+		logger.Error("Could not record the scan's end in the audit log", "error", err)
+	}
+}
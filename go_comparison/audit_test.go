@@ -0,0 +1,312 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+// readAuditLog returns the records in an NDJSON audit log.
+func readAuditLog(t *testing.T, path string) []AuditRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	require.NoError(t, err)
+	defer f.Close()
+	var recs []AuditRecord
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var rec AuditRecord
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &rec))
+		recs = append(recs, rec)
+	}
+	require.NoError(t, sc.Err())
+	return recs
+}
+
+func auditEvents(recs []AuditRecord) []string {
+	var events []string
+	for _, r := range recs {
+		events = append(events, r.Event+" "+r.Outcome)
+	}
+	return events
+}
+
+// newAuditTestEnv is newTestEnv with an audit log at the returned path.
+func newAuditTestEnv(t *testing.T) (*testsuite.TestWorkflowEnvironment, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{AuditLog: &FileAuditLog{Path: path}})
+	mockActionsSecurity(env)
+	return env, path
+}
+
+func TestFileAuditLogAppendsEachRecordOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	log := &FileAuditLog{Path: path}
+	ctx := context.Background()
+	started := AuditRecord{Event: AuditScanStarted, ID: "scan/run-1/scan_started", Org: "acme"}
+	finished := AuditRecord{Event: AuditScanFinished, ID: "scan/run-1/scan_finished", Org: "acme", Outcome: "completed"}
+
+	require.NoError(t, log.Append(ctx, started))
+	require.NoError(t, log.Append(ctx, finished))
+	require.NoError(t, log.Append(ctx, started), "a retried append")
+	require.NoError(t, (&FileAuditLog{Path: path}).Append(ctx, AuditRecord{Event: AuditScanStarted, ID: "scan/run-2/scan_started"}))
+
+	recs := readAuditLog(t, path)
+	require.Len(t, recs, 3)
+	require.Equal(t, []string{"scan/run-1/scan_started", "scan/run-1/scan_finished", "scan/run-2/scan_started"},
+		[]string{recs[0].ID, recs[1].ID, recs[2].ID})
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestBlobAuditLogWritesARecordPerKey(t *testing.T) {
+	dir := t.TempDir()
+	log := &BlobAuditLog{Store: &FileBlobStore{Dir: dir}}
+	rec := AuditRecord{Event: AuditScanFinished, ID: "scan/run-1/scan_finished", Outcome: "completed"}
+	require.NoError(t, log.Append(context.Background(), rec))
+
+	data, err := os.ReadFile(filepath.Join(dir, "scan", "run-1", "scan_finished.json"))
+	require.NoError(t, err)
+	var got AuditRecord
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, rec, got)
+}
+
+func TestOpenAuditLog(t *testing.T) {
+	l, err := OpenAuditLog("/var/log/scanner/audit.ndjson")
+	require.NoError(t, err)
+	require.Equal(t, "/var/log/scanner/audit.ndjson", l.(*FileAuditLog).Path)
+	l, err = OpenAuditLog("file:///var/log/scanner/audit.ndjson")
+	require.NoError(t, err)
+	require.Equal(t, "/var/log/scanner/audit.ndjson", l.(*FileAuditLog).Path)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	l, err = OpenAuditLog("s3://audit-bucket/scanner")
+	require.NoError(t, err)
+	store := l.(*BlobAuditLog).Store.(*S3BlobStore)
+	require.Equal(t, "audit-bucket", store.Bucket)
+	require.Equal(t, "scanner", store.Prefix)
+
+	_, err = OpenAuditLog("gs://audit-bucket")
+	require.ErrorContains(t, err, "unsupported")
+}
+
+func TestReportDigestIgnoresFormatting(t *testing.T) {
+	compact, err := ReportDigest([]byte(`{"org":"acme","total_repos":3,"rate":"66.7%"}`))
+	require.NoError(t, err)
+	indented, err := ReportDigest([]byte("{\n  \"total_repos\": 3,\n  \"org\": \"acme\",\n  \"rate\": \"66.7%\"\n}\n"))
+	require.NoError(t, err)
+	require.Equal(t, compact, indented)
+
+	changed, err := ReportDigest([]byte(`{"org":"acme","total_repos":4,"rate":"66.7%"}`))
+	require.NoError(t, err)
+	require.NotEqual(t, compact, changed)
+
+	_, err = ReportDigest([]byte("not json"))
+	require.Error(t, err)
+}
+
+func TestWorkflowAuditLogRecordsStartAndEnd(t *testing.T) {
+	env, path := newAuditTestEnv(t)
+	onListOrgRepos(env, fakeRepos(4))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "acme", mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("repo-002"))
+
+	token := "ghp_audit-test-token"
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Token: &token, InitiatedBy: "alice", Reason: "quarterly review"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&result))
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), token)
+
+	recs := readAuditLog(t, path)
+	require.Equal(t, []string{"scan_started ", "scan_finished completed"}, auditEvents(recs))
+	started, finished := recs[0], recs[1]
+	sum := sha256.Sum256([]byte(token))
+	require.Equal(t, hex.EncodeToString(sum[:]), started.TokenSHA256)
+	require.NotNil(t, started.Input)
+	require.Nil(t, started.Input.Token)
+	require.Equal(t, "acme", started.Input.Org)
+	for _, rec := range recs {
+		require.Equal(t, "alice", rec.InitiatedBy)
+		require.Equal(t, "quarterly review", rec.Reason)
+		require.Equal(t, ProviderGitHub, rec.Provider)
+		require.NotEmpty(t, rec.RunID)
+		require.NotEmpty(t, rec.Worker)
+	}
+	require.Equal(t, &AuditCounts{TotalRepos: 4, ScannedRepos: 4, CompliantRepos: 3, NonCompliantRepos: 1}, finished.Counts)
+	require.Empty(t, finished.Error)
+
+	// The starter saves the report indented; it still has the recorded
+	// digest.
+	saved := filepath.Join(t.TempDir(), "report.json")
+	data, err := json.MarshalIndent(result, "", "  ")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(saved, data, 0o644))
+	data, err = os.ReadFile(saved)
+	require.NoError(t, err)
+	digest, err := ReportDigest(data)
+	require.NoError(t, err)
+	require.Equal(t, digest, finished.ReportSHA256)
+}
+
+func TestWorkflowAuditLogRecordsEveryEnding(t *testing.T) {
+	t.Run("cancel_scan", func(t *testing.T) {
+		env, path := newAuditTestEnv(t)
+		onListOrgRepos(env, fakeRepos(30))
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			After(time.Minute).Return(compliantUnless())
+		env.RegisterDelayedCallback(func() { env.SignalWorkflow("cancel_scan", "change freeze") }, 30*time.Second)
+
+		env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+		require.NoError(t, env.GetWorkflowError())
+		var result map[string]interface{}
+		require.NoError(t, env.GetWorkflowResult(&result))
+		data, err := json.Marshal(result)
+		require.NoError(t, err)
+		digest, err := ReportDigest(data)
+		require.NoError(t, err)
+		recs := readAuditLog(t, path)
+		require.Equal(t, []string{"scan_started ", "scan_finished cancelled"}, auditEvents(recs))
+		require.Equal(t, digest, recs[1].ReportSHA256)
+	})
+
+	t.Run("degraded", func(t *testing.T) {
+		env, path := newAuditTestEnv(t)
+		onListOrgRepos(env, fakeRepos(3))
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, temporal.NewNonRetryableApplicationError("bad credentials", "UNAUTHORIZED", nil))
+
+		env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+		var appErr *temporal.ApplicationError
+		require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+		var partial map[string]interface{}
+		require.NoError(t, appErr.Details(&partial))
+		data, err := json.Marshal(partial)
+		require.NoError(t, err)
+		digest, err := ReportDigest(data)
+		require.NoError(t, err)
+		recs := readAuditLog(t, path)
+		require.Equal(t, []string{"scan_started ", "scan_finished degraded"}, auditEvents(recs))
+		require.Contains(t, recs[1].Error, "scan degraded")
+		require.Equal(t, 3, recs[1].Counts.Errors)
+		require.Equal(t, digest, recs[1].ReportSHA256)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		env, path := newAuditTestEnv(t)
+		env.OnActivity("FetchOrgReposPage", mock.Anything, mock.Anything).
+			Return(nil, temporal.NewNonRetryableApplicationError("org not found", "NOT_FOUND", nil))
+
+		env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+		require.Error(t, env.GetWorkflowError())
+		recs := readAuditLog(t, path)
+		require.Equal(t, []string{"scan_started ", "scan_finished failed"}, auditEvents(recs))
+		require.Contains(t, recs[1].Error, "org not found")
+		require.Empty(t, recs[1].ReportSHA256)
+	})
+
+	t.Run("workflow cancelled", func(t *testing.T) {
+		env, path := newAuditTestEnv(t)
+		onListOrgRepos(env, fakeRepos(30))
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			After(time.Minute).Return(compliantUnless())
+		env.RegisterDelayedCallback(env.CancelWorkflow, 30*time.Second)
+
+		env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+		require.True(t, temporal.IsCanceledError(env.GetWorkflowError()))
+		recs := readAuditLog(t, path)
+		require.Equal(t, []string{"scan_started ", "scan_finished cancelled"}, auditEvents(recs))
+		require.NotEmpty(t, recs[1].Error)
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		env, path := newAuditTestEnv(t)
+		env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "not an org!"})
+
+		require.Error(t, env.GetWorkflowError())
+		require.Empty(t, readAuditLog(t, path), "a scan that never started is not recorded")
+	})
+}
+
+func TestWorkflowFailsWhenItCannotBeAudited(t *testing.T) {
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	// A directory cannot be appended to.
+	env.RegisterActivity(&Activities{AuditLog: &FileAuditLog{Path: t.TempDir()}})
+	mockActionsSecurity(env)
+	fetched := false
+	env.OnActivity("FetchOrgReposPage", mock.Anything, mock.Anything).
+		Return(func(context.Context, RepoPageInput) (*RepoPage, error) {
+			fetched = true
+			return &RepoPage{}, nil
+		})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.ErrorContains(t, env.GetWorkflowError(), "recording the scan in the audit log")
+	require.False(t, fetched)
+}
+
+func TestWorkflowWithoutAuditLogSchedulesNoAppend(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		version  workflow.Version
+		appended int
+	}{
+		{"current", workflowChanges[changeAuditLog], 0},
+		{"recorded before version 2", 1, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.OnGetVersion(changeAuditLog, workflow.DefaultVersion, workflowChanges[changeAuditLog]).Return(tc.version)
+			onListOrgRepos(env, fakeRepos(3))
+			env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(compliantUnless())
+			appended := 0
+			env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, _ converter.EncodedValues) {
+				if info.ActivityType.Name == "AppendAuditLog" {
+					appended++
+				}
+			})
+
+			env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+			require.NoError(t, env.GetWorkflowError())
+			require.Equal(t, tc.appended, appended)
+		})
+	}
+}
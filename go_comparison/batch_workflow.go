@@ -0,0 +1,384 @@
+package scanner
+
+// =============================================================================
+// Batch scanning — in the parent workflow, or in a child per batch
+// =============================================================================
+//
+// scanBatch is the fan-out both modes share: one workflow.Go per repo, with
+// results collected over a channel. By default SecurityScanWorkflow calls it
+// directly. With ScanInput.ChildPerBatch, each batch runs in its own
+// ScanBatchWorkflow, so retries and failures are isolated and visible per
+// batch, and the parent's history holds one child per batch instead of three
+// events per activity.
+//
+// Python would use workflow.execute_child_workflow; Go's
+// workflow.ExecuteChildWorkflow returns a ChildWorkflowFuture that can also
+// be signalled, which is how cancellation reaches a running batch.
+// =============================================================================
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// scanBatchSize is the number of repos scanned concurrently.
+	scanBatchSize = 10
+	// childBatchSize is the number of repos per ScanBatchWorkflow child,
+	// scanned scanBatchSize at a time.
+	childBatchSize = 100
+)
+
+// BatchDelay spaces batches out, so each batch's concurrent requests do not
+// land right after the last one's and trip GitHub's secondary rate limits.
+type BatchDelay struct {
+	// Seconds is the pause before every batch but the first.
+	Seconds float64 `json:"seconds"`
+	// Jitter is the fraction of Seconds the pause varies by either way: 0.2
+	// pauses between 0.8 and 1.2 times Seconds.
+	Jitter float64 `json:"jitter,omitempty"`
+}
+
+func (d *BatchDelay) validate() error {
+	if d.Seconds < 0 {
+		return fmt.Errorf("batch delay must not be negative, got %gs", d.Seconds)
+	}
+	if d.Jitter < 0 || d.Jitter > 1 {
+		return fmt.Errorf("batch delay jitter must be between 0 and 1, got %g", d.Jitter)
+	}
+	return nil
+}
+
+// next draws the pause before the next batch. The random draw is a
+// SideEffect, so replays wait as long as the original run did.
+func (d *BatchDelay) next(ctx workflow.Context) time.Duration {
+	factor := 1.0
+	if d.Jitter > 0 {
+		var r float64
+		_ = workflow.SideEffect(ctx, func(workflow.Context) interface{} {
+			return rand.Float64()
+		}).Get(&r)
+		factor += d.Jitter * (2*r - 1)
+	}
+	return time.Duration(d.Seconds * factor * float64(time.Second))
+}
+
+// pauseBetweenBatches waits wait on a workflow timer, returning as soon as
+// stop reports true, e.g. because the scan was cancelled.
+func pauseBetweenBatches(ctx workflow.Context, wait time.Duration, stop func() bool) error {
+	if wait <= 0 {
+		return nil
+	}
+	_, err := workflow.AwaitWithTimeout(ctx, wait, stop)
+	return err
+}
+
+// scanBatch scans in.Repos concurrently and calls onResult for each result
+// in completion order, or hands them to CheckRepoSecurityBatch with
+// in.ActivityBatching. A repo whose CheckRepoSecurity failed is passed with
+// Error set. It reports whether the scan's API budget ran out; repos cut
+// short by it are not passed to onResult.
+//
+// Once stop reports true, e.g. because the scan was cancelled, scanBatch
+// stops waiting: it cancels the repos' activities and returns the repos
+// still in flight, which are not passed to onResult. Runs from before
+// batch-collection wait for every repo.
+//
+// With in.Stragglers, the repos still running at the batch's soft deadline
+// are cancelled the same way and returned as stragglers (see straggler.go).
+func scanBatch(ctx, scanCtx workflow.Context, in ScanBatchInput, actionsVersion workflow.Version, stop func() bool, onResult func(*RepoSecurityResult)) (budgetExceeded bool, inFlight, stragglers []string) {
+	logger := workflow.GetLogger(ctx)
+
+	// Checks the token cannot evaluate are not run at all.
+	runChecks := in.Checks
+	if len(in.NoAccess) > 0 {
+		runChecks = subtract(in.Checks, in.NoAccess)
+	}
+	if in.ActivityBatching {
+		budgetExceeded, inFlight = scanBatchWithActivities(ctx, scanCtx, in, runChecks, stop, onResult)
+		return budgetExceeded, inFlight, nil
+	}
+	checks := newCheckSet(runChecks)
+
+	// Cancelling activityCtx cancels every activity of the batch.
+	activityCtx, cancelActivities := workflow.WithCancel(scanCtx)
+	defer cancelActivities()
+	collectVersion := changeVersion(ctx, changeBatchCollection)
+	if collectVersion == workflow.DefaultVersion {
+		activityCtx = scanCtx
+	}
+
+	// Create a channel to collect results from concurrent activities
+	resultCh := workflow.NewChannel(ctx)
+	send := func(gCtx workflow.Context, repo string, result *RepoSecurityResult) {
+		if collectVersion == workflow.DefaultVersion {
+			resultCh.Send(gCtx, result)
+			return
+		}
+		resultCh.Send(gCtx, repoDone{repo: repo, result: result})
+	}
+
+	// Each repo's activities can be cancelled on their own, for stragglers.
+	cancelRepo := make(map[string]workflow.CancelFunc, len(in.Repos))
+	started := workflow.Now(ctx)
+
+	// Launch concurrent activities using workflow.Go (NOT native goroutines)
+	for _, repoName := range in.Repos {
+		// Capture loop variable (same reason as Python's closure gotcha)
+		repoName := repoName
+		activityCtx, cancel := workflow.WithCancel(activityCtx)
+		cancelRepo[repoName] = cancel
+		workflow.Go(ctx, func(gCtx workflow.Context) {
+			result, exceeded := checkRepo(gCtx, activityCtx, in, runChecks, checks, actionsVersion, repoName)
+			if exceeded {
+				budgetExceeded = true
+			}
+			send(gCtx, repoName, result)
+		})
+	}
+
+	// Collect all results from this batch
+	if collectVersion == workflow.DefaultVersion {
+		for i := 0; i < len(in.Repos); i++ {
+			var result *RepoSecurityResult
+			resultCh.Receive(ctx, &result)
+			if result != nil {
+				onResult(result)
+			}
+		}
+		return budgetExceeded, nil, nil
+	}
+
+	// A channel receive cannot be interrupted, so a selector waits for
+	// either the next result or stop. Cut-short repos send nil, so pending
+	// tracks every repo that has sent nothing yet.
+	pending := make(map[string]bool, len(in.Repos))
+	for _, repo := range in.Repos {
+		pending[repo] = true
+	}
+	stopped, settle := workflow.NewFuture(ctx)
+	collected := false
+	workflow.Go(ctx, func(gCtx workflow.Context) {
+		_ = workflow.Await(gCtx, func() bool { return collected || stop() })
+		settle.Set(nil, nil)
+	})
+	sel := workflow.NewSelector(ctx)
+	// The soft deadline is set once half the batch is done, and its timer
+	// cancelled if the rest finish first.
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	defer cancelTimer()
+	released := false
+	sel.AddReceive(resultCh, func(c workflow.ReceiveChannel, _ bool) {
+		var done repoDone
+		c.Receive(ctx, &done)
+		delete(pending, done.repo)
+		if done.result != nil {
+			onResult(done.result)
+		}
+		if in.Stragglers != nil && len(pending) > 0 && len(in.Repos)-len(pending) == (len(in.Repos)+1)/2 {
+			now := workflow.Now(ctx)
+			releaseAt := started.Add(in.Stragglers.releaseAfter(now.Sub(started)))
+			sel.AddFuture(workflow.NewTimer(timerCtx, releaseAt.Sub(now)), func(f workflow.Future) {
+				released = f.Get(ctx, nil) == nil
+			})
+		}
+	})
+	halted := false
+	sel.AddFuture(stopped, func(workflow.Future) { halted = true })
+	for len(pending) > 0 && !halted && !released {
+		sel.Select(ctx)
+	}
+	collected = true
+	switch {
+	case halted:
+		cancelActivities()
+		for _, repo := range in.Repos {
+			if pending[repo] {
+				inFlight = append(inFlight, repo)
+			}
+		}
+	case released:
+		for _, repo := range in.Repos {
+			if pending[repo] {
+				cancelRepo[repo]()
+				stragglers = append(stragglers, repo)
+			}
+		}
+		logger.Info("Released stragglers from their batch", "repos", stragglers)
+	}
+	return budgetExceeded, inFlight, stragglers
+}
+
+// checkRepo runs repo's check activities on activityCtx and returns its
+// result, with Error set when CheckRepoSecurity failed. It returns a nil
+// result and budgetExceeded when the scan's API budget cut it short.
+func checkRepo(ctx, activityCtx workflow.Context, in ScanBatchInput, runChecks []string, checks checkSet, actionsVersion workflow.Version, repoName string) (_ *RepoSecurityResult, budgetExceeded bool) {
+	logger := workflow.GetLogger(ctx)
+
+	// GitHub keeps the original activity so its histories replay; only
+	// CheckProviderRepo can ask for evidence.
+	var result RepoSecurityResult
+	var err error
+	if providerName(in.Provider) == ProviderGitHub && !in.IncludeEvidence {
+		err = workflow.ExecuteActivity(activityCtx, "CheckRepoSecurity",
+			in.Org, repoName, in.Credential, runChecks,
+		).Get(ctx, &result)
+	} else {
+		err = workflow.ExecuteActivity(activityCtx, "CheckProviderRepo", RepoCheckInput{
+			Provider: providerName(in.Provider), Org: in.Org, Repo: repoName, Credential: in.Credential, Checks: runChecks,
+			IncludeEvidence: in.IncludeEvidence,
+		}).Get(ctx, &result)
+	}
+
+	if isBudgetExceeded(err) {
+		return nil, true
+	}
+	if err != nil {
+		return scanErrorResult(repoName, err), false
+	}
+	// A repo gone since the listing has nothing more to check. Runs
+	// recorded before disappeared repos went on to its other checks.
+	if result.Disappeared != nil && changeVersion(ctx, changeDisappearedRepos) >= 1 {
+		return &result, false
+	}
+	// Nor has a repo the token cannot read (see repoaccess.go).
+	if result.TokenLacksRepoAccess && changeVersion(ctx, changeRepoAccess) >= 1 {
+		for _, c := range in.NoAccess {
+			result.setNoAccess(c, "token lacks the scope or permission for this check")
+		}
+		return &result, false
+	}
+
+	// Actions settings are a separate activity so a failure there leaves
+	// them unknown instead of failing the whole repo.
+	if actionsVersion >= 1 && checks[CheckActions] && result.Error == nil {
+		var actions *ActionsSecurity
+		err := workflow.ExecuteActivity(activityCtx, "CheckActionsSecurity",
+			in.Org, repoName, in.Credential,
+		).Get(ctx, &actions)
+		if isBudgetExceeded(err) {
+			return nil, true
+		}
+		if err != nil {
+			logger.Warn("Actions check failed", "repo", repoName, "error", err)
+		}
+		result.setActions(actions)
+	}
+
+	if checks[CheckAccessAudit] && result.Error == nil {
+		var access *AccessAudit
+		err := workflow.ExecuteActivity(activityCtx, "AuditRepoAccess",
+			in.Org, repoName, in.Credential, in.DeployKeyMaxAgeDays,
+		).Get(ctx, &access)
+		if isBudgetExceeded(err) {
+			return nil, true
+		}
+		if err != nil {
+			logger.Warn("Access audit failed", "repo", repoName, "error", err)
+		}
+		result.setAccess(access)
+	}
+	if result.Error == nil {
+		for _, c := range in.NoAccess {
+			result.setNoAccess(c, "token lacks the scope or permission for this check")
+		}
+	}
+	return &result, false
+}
+
+// repoDone is what a repo's goroutine in scanBatch sends once it is done;
+// result is nil when the API budget cut it short.
+type repoDone struct {
+	repo   string
+	result *RepoSecurityResult
+}
+
+// ScanBatchWorkflow scans one batch of repos for SecurityScanWorkflow, in
+// groups of scanBatchSize (activityBatchSize with in.ActivityBatching),
+// pausing for in.BatchDelay between groups. Stragglers a group releases are
+// returned in TimedOutInBatch for the parent's second pass. A "cancel_scan" signal stops it
+// between groups, or mid-group with the repos still in flight in
+// CancelledInFlight; it returns the results so far with Cancelled set. Running
+// out of API budget stops it the same way, with BudgetExceeded set.
+func ScanBatchWorkflow(ctx workflow.Context, in ScanBatchInput) (ScanBatchResult, error) {
+	logger := workflow.GetLogger(ctx)
+
+	cancelled := false
+	workflow.Go(ctx, func(gCtx workflow.Context) {
+		var reason string
+		workflow.GetSignalChannel(gCtx, "cancel_scan").Receive(gCtx, &reason)
+		cancelled = true
+		logger.Info("Batch cancellation requested", "reason", reason)
+	})
+
+	scanCtx := workflow.WithActivityOptions(ctx, scanActivityOptions(defaultScanTimeouts.merge(in.ScanTimeouts), in.ScanRetry.apply(ScanRetryPolicy())))
+	actionsVersion := changeVersion(ctx, changeActionsSecurity)
+
+	groupSize := scanBatchSize
+	if in.ActivityBatching {
+		groupSize = activityBatchSize
+	}
+
+	var out ScanBatchResult
+	for start := 0; start < len(in.Repos); start += groupSize {
+		if start > 0 && in.BatchDelay != nil {
+			if err := pauseBetweenBatches(ctx, in.BatchDelay.next(ctx), func() bool { return cancelled }); err != nil {
+				return out, err
+			}
+		}
+		if cancelled {
+			out.Cancelled = true
+			break
+		}
+		end := start + groupSize
+		if end > len(in.Repos) {
+			end = len(in.Repos)
+		}
+		group := in
+		group.Repos = in.Repos[start:end]
+		budgetExceeded, inFlight, stragglers := scanBatch(ctx, scanCtx, group, actionsVersion, func() bool { return cancelled }, func(r *RepoSecurityResult) {
+			out.Results = append(out.Results, *r)
+		})
+		out.TimedOutInBatch = append(out.TimedOutInBatch, stragglers...)
+		if len(inFlight) > 0 {
+			out.Cancelled = true
+			out.CancelledInFlight = inFlight
+			break
+		}
+		if budgetExceeded {
+			out.BudgetExceeded = true
+			break
+		}
+	}
+	return out, nil
+}
+
+// batchChildID names the child for a batch after its parent, org, and index.
+func batchChildID(parentID, org string, index int) string {
+	return fmt.Sprintf("%s/%s/batch-%04d", parentID, org, index)
+}
+
+// runBatchChild runs ScanBatchWorkflow for one batch and waits for it.
+// current holds the running child while it runs so the parent's cancel
+// handler can signal it.
+func runBatchChild(ctx workflow.Context, in ScanBatchInput, index int, current *workflow.ChildWorkflowFuture) (ScanBatchResult, error) {
+	parentID := workflow.GetInfo(ctx).WorkflowExecution.ID
+	childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+		WorkflowID:        batchChildID(parentID, in.Org, index),
+		ParentClosePolicy: enums.PARENT_CLOSE_POLICY_TERMINATE,
+	})
+
+	future := workflow.ExecuteChildWorkflow(childCtx, ScanBatchWorkflow, in)
+	*current = future
+	defer func() { *current = nil }()
+
+	var result ScanBatchResult
+	if err := future.Get(ctx, &result); err != nil {
+		return ScanBatchResult{}, fmt.Errorf("scanning batch %d: %w", index, err)
+	}
+	return result, nil
+}
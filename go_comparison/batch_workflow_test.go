@@ -0,0 +1,204 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+// batchRun is what one scan of 250 repos looked like from the outside.
+type batchRun struct {
+	report map[string]interface{}
+	// activities counts activities by the workflow that scheduled them.
+	activities map[string]int
+	children   []string
+}
+
+func runBatchScan(t *testing.T, childPerBatch bool) batchRun {
+	t.Helper()
+	env := newTestEnv(t)
+	env.RegisterWorkflow(ScanBatchWorkflow)
+	onListOrgRepos(env, fakeRepos(250))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("repo-007", "repo-150"))
+
+	run := batchRun{activities: map[string]int{}}
+	env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, _ converter.EncodedValues) {
+		run.activities[info.WorkflowExecution.ID]++
+	})
+	env.SetOnChildWorkflowStartedListener(func(info *workflow.Info, _ workflow.Context, _ converter.EncodedValues) {
+		run.children = append(run.children, info.WorkflowExecution.ID)
+	})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ChildPerBatch: childPerBatch})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.NoError(t, env.GetWorkflowResult(&run.report))
+	return run
+}
+
+func TestWorkflowChildPerBatch(t *testing.T) {
+	inline := runBatchScan(t, false)
+	child := runBatchScan(t, true)
+
+	const parentID = "default-test-workflow-id"
+	require.Equal(t, []string{
+		parentID + "/acme/batch-0000",
+		parentID + "/acme/batch-0001",
+		parentID + "/acme/batch-0002",
+	}, child.children)
+
+	// Each activity adds three events to the history that schedules it. The
+	// parent keeps the three FetchOrgReposPage and the four post-report
+	// steps (ForwardFindings, CreateJiraIssues, TriggerPagerDuty,
+	// EmitComplianceMetrics); the report and scan history are local
+	// activities, so they are not counted, and the worker keeps no audit
+	// log. The children take the 250 per-repo checks, at most 100 each.
+	require.Equal(t, 257, inline.activities[parentID])
+	require.Equal(t, 7, child.activities[parentID])
+	require.Equal(t, 100, child.activities[parentID+"/acme/batch-0000"])
+	require.Equal(t, 50, child.activities[parentID+"/acme/batch-0002"])
+
+	for _, key := range []string{"total_repos", "fully_compliant", "compliance_rate", "errors", "compliance_score"} {
+		require.Equal(t, inline.report[key], child.report[key], key)
+	}
+	require.ElementsMatch(t, inline.report["non_compliant_repos"], child.report["non_compliant_repos"])
+	require.ElementsMatch(t, []interface{}{"repo-007", "repo-150"}, child.report["non_compliant_repos"])
+}
+
+func TestWorkflowChildPerBatchCancel(t *testing.T) {
+	env := newTestEnv(t)
+	env.RegisterWorkflow(ScanBatchWorkflow)
+	onListOrgRepos(env, fakeRepos(250))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(time.Minute).Return(compliantUnless())
+
+	// Arrives while the first child is scanning its first group; the child
+	// should stop waiting for that group, and the parent start no more.
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("cancel_scan", "change freeze")
+	}, 30*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ChildPerBatch: true})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, true, report["cancelled"])
+	require.EqualValues(t, 0, report["repos_scanned_before_cancel"])
+	require.Len(t, report["cancelled_in_flight_repos"], 10)
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 10)
+}
+
+func TestScanBatchWorkflowReportsErrors(t *testing.T) {
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{})
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "bad", mock.Anything, mock.Anything).
+		Return(nil, temporal.NewNonRetryableApplicationError("boom", "TEST", nil))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(ScanBatchWorkflow, ScanBatchInput{Org: "acme", Repos: []string{"good", "bad"}, Checks: DefaultChecks()})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var out ScanBatchResult
+	require.NoError(t, env.GetWorkflowResult(&out))
+	require.Len(t, out.Results, 2)
+	require.False(t, out.Cancelled)
+	for _, r := range out.Results {
+		require.Equal(t, r.Repository == "bad", r.Error != nil, r.Repository)
+	}
+}
+
+func TestWorkflowBatchDelay(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(25))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	var pauses []time.Duration
+	env.SetOnTimerScheduledListener(func(_ string, d time.Duration) {
+		pauses = append(pauses, d)
+	})
+	// Mid-way through the first pause, the progress query says why
+	// nothing is happening.
+	env.RegisterDelayedCallback(func() {
+		val, err := env.QueryWorkflow("progress")
+		require.NoError(t, err)
+		var p ScanProgress
+		require.NoError(t, val.Get(&p))
+		require.Equal(t, ScanPaused, p.Status)
+		require.NotNil(t, p.NextBatchAt)
+		require.Equal(t, 10, p.ScannedRepos)
+	}, 10*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", BatchDelay: &BatchDelay{Seconds: 60, Jitter: 0.5}})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Len(t, pauses, 2, "one pause between each of the three batches")
+	for _, d := range pauses {
+		require.GreaterOrEqual(t, d, 30*time.Second)
+		require.LessOrEqual(t, d, 90*time.Second)
+	}
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 25, report["total_repos"])
+}
+
+func TestWorkflowCancelInterruptsBatchDelay(t *testing.T) {
+	for _, childPerBatch := range []bool{false, true} {
+		childPerBatch := childPerBatch
+		t.Run(fmt.Sprintf("child per batch %t", childPerBatch), func(t *testing.T) {
+			env := newTestEnv(t)
+			env.RegisterWorkflow(ScanBatchWorkflow)
+			onListOrgRepos(env, fakeRepos(25))
+			env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(compliantUnless())
+
+			start := env.Now()
+			env.RegisterDelayedCallback(func() {
+				env.SignalWorkflow("cancel_scan", "change freeze")
+			}, time.Minute)
+
+			env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+				Org: "acme", ChildPerBatch: childPerBatch, BatchDelay: &BatchDelay{Seconds: 3600},
+			})
+
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+			require.Less(t, env.Now().Sub(start), time.Hour, "the pause ended at the cancel, not the timer")
+
+			var report map[string]interface{}
+			require.NoError(t, env.GetWorkflowResult(&report))
+			require.Equal(t, true, report["cancelled"])
+			require.EqualValues(t, 10, report["repos_scanned_before_cancel"])
+		})
+	}
+}
+
+func TestWorkflowRejectsBadBatchDelay(t *testing.T) {
+	for _, delay := range []BatchDelay{{Seconds: -1}, {Seconds: 5, Jitter: 1.5}} {
+		env := newTestEnv(t)
+		env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", BatchDelay: &delay})
+
+		var appErr *temporal.ApplicationError
+		require.True(t, errors.As(env.GetWorkflowError(), &appErr), "%+v", delay)
+		require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+	}
+}
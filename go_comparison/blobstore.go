@@ -0,0 +1,302 @@
+package scanner
+
+// =============================================================================
+// Blob storage for the claim-check pattern
+// =============================================================================
+//
+// Temporal payloads are capped (2 MiB per payload, ~4 MiB per gRPC message).
+// A 6,000-repo scan produces more RepoSecurityResult data than that, so the
+// workflow hands large result sets to StoreResults and passes around only a
+// BlobRef — the "claim check" — from then on.
+//
+// The store is a worker-side dependency like the HTTP client: it lives on
+// the Activities struct and never touches workflow code.
+// =============================================================================
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BlobStore persists opaque blobs and returns a URI that Get can resolve.
+// URI is the URI Put returns for key, so a blob at a well-known key can be
+// read without having stored it in this run.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) (uri string, err error)
+	Get(ctx context.Context, uri string) ([]byte, error)
+	URI(key string) string
+}
+
+// ErrBlobNotFound is returned by Get when the URI does not resolve.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// OpenBlobStore builds a BlobStore from a URI:
+//
+//	file:///var/lib/scanner/results   (or a bare path)
+//	s3://bucket/optional/prefix       (credentials from AWS_* env vars)
+func OpenBlobStore(uri string) (BlobStore, error) {
+	switch {
+	case strings.HasPrefix(uri, "s3://"):
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, fmt.Errorf("parsing blob store URI: %w", err)
+		}
+		return NewS3BlobStoreFromEnv(u.Host, strings.Trim(u.Path, "/"))
+	case strings.HasPrefix(uri, "file://"):
+		return &FileBlobStore{Dir: strings.TrimPrefix(uri, "file://")}, nil
+	case strings.Contains(uri, "://"):
+		return nil, fmt.Errorf("unsupported blob store URI %q", uri)
+	default:
+		return &FileBlobStore{Dir: uri}, nil
+	}
+}
+
+// FileBlobStore keeps blobs as files under Dir. Suitable for a single worker
+// host or a shared volume.
+type FileBlobStore struct {
+	Dir string
+}
+
+func (s *FileBlobStore) Put(_ context.Context, key string, data []byte) (string, error) {
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	// Write-then-rename so a retried activity never leaves a torn blob.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	return s.URI(key), nil
+}
+
+func (s *FileBlobStore) URI(key string) string {
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return "file://" + filepath.ToSlash(path)
+}
+
+func (s *FileBlobStore) Get(_ context.Context, uri string) ([]byte, error) {
+	if !strings.HasPrefix(uri, "file://") {
+		return nil, fmt.Errorf("file blob store cannot resolve %q", uri)
+	}
+	data, err := os.ReadFile(filepath.FromSlash(strings.TrimPrefix(uri, "file://")))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, uri)
+	}
+	return data, err
+}
+
+// S3BlobStore stores blobs in an S3 (or S3-compatible) bucket using plain
+// HTTP and AWS Signature Version 4, so the worker needs no AWS SDK.
+type S3BlobStore struct {
+	Bucket string
+	Prefix string
+	Region string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Endpoint overrides the virtual-hosted AWS endpoint, e.g. for MinIO
+	// ("http://localhost:9000"). Path-style addressing is used when set.
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+// NewS3BlobStoreFromEnv reads AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN, AWS_REGION (default us-east-1) and AWS_ENDPOINT_URL.
+func NewS3BlobStoreFromEnv(bucket, prefix string) (*S3BlobStore, error) {
+	creds := awsCredentialsFromEnv()
+	s := &S3BlobStore{
+		Bucket:          bucket,
+		Prefix:          prefix,
+		Region:          creds.Region,
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Endpoint:        os.Getenv("AWS_ENDPOINT_URL"),
+	}
+	if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return nil, errors.New("s3 blob store needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return s, nil
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	uri := s.URI(key)
+	if s.Prefix != "" {
+		key = s.Prefix + "/" + key
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("s3 put %s: status %d", key, resp.StatusCode)
+	}
+	return uri, nil
+}
+
+func (s *S3BlobStore) URI(key string) string {
+	if s.Prefix != "" {
+		key = s.Prefix + "/" + key
+	}
+	return "s3://" + s.Bucket + "/" + key
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, uri string) ([]byte, error) {
+	prefix := "s3://" + s.Bucket + "/"
+	if !strings.HasPrefix(uri, prefix) {
+		return nil, fmt.Errorf("s3 blob store for bucket %q cannot resolve %q", s.Bucket, uri)
+	}
+	resp, err := s.do(ctx, http.MethodGet, strings.TrimPrefix(uri, prefix), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, uri)
+	default:
+		return nil, fmt.Errorf("s3 get %s: status %d", uri, resp.StatusCode)
+	}
+}
+
+func (s *S3BlobStore) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	var u string
+	if s.Endpoint != "" {
+		u = strings.TrimRight(s.Endpoint, "/") + "/" + s.Bucket + "/" + key
+	} else {
+		u = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.Bucket, s.Region, key)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, body, time.Now().UTC())
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// sign adds AWS Signature Version 4 headers for the S3 service.
+func (s *S3BlobStore) sign(req *http.Request, body []byte, now time.Time) {
+	awsCredentials{
+		Region:          s.Region,
+		AccessKeyID:     s.AccessKeyID,
+		SecretAccessKey: s.SecretAccessKey,
+		SessionToken:    s.SessionToken,
+	}.sign(req, body, now, "s3")
+}
+
+// awsCredentials sign requests to AWS services with Signature Version 4.
+type awsCredentials struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// awsCredentialsFromEnv reads AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN and AWS_REGION (default us-east-1).
+func awsCredentialsFromEnv() awsCredentials {
+	c := awsCredentials{
+		Region:          os.Getenv("AWS_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	return c
+}
+
+// sign adds AWS Signature Version 4 headers for service.
+func (c awsCredentials) sign(req *http.Request, body []byte, now time.Time, service string) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if c.SessionToken != "" {
+		signed = append(signed, "x-amz-security-token")
+	}
+	var canonHeaders strings.Builder
+	for _, h := range signed {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		canonHeaders.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + c.Region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), day)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,141 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestFileBlobStoreRoundTrip(t *testing.T) {
+	store := &FileBlobStore{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	uri, err := store.Put(ctx, "wf/run/results-0000.json", []byte(`[{"repository":"a"}]`))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(uri, "file://"), uri)
+
+	data, err := store.Get(ctx, uri)
+	require.NoError(t, err)
+	require.Equal(t, `[{"repository":"a"}]`, string(data))
+
+	_, err = store.Get(ctx, uri+".missing")
+	require.True(t, errors.Is(err, ErrBlobNotFound), "got %v", err)
+}
+
+// fakeS3 is an in-memory path-style S3 endpoint that requires SigV4 headers.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+		r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		b, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = b
+	case http.MethodGet:
+		b, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(b)
+	}
+}
+
+func TestS3BlobStoreRoundTrip(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	store := &S3BlobStore{
+		Bucket:          "scans",
+		Prefix:          "results",
+		Region:          "us-east-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Endpoint:        srv.URL,
+		HTTPClient:      srv.Client(),
+	}
+	ctx := context.Background()
+
+	uri, err := store.Put(ctx, "wf/run/results-0000.json", []byte("[]"))
+	require.NoError(t, err)
+	require.Equal(t, "s3://scans/results/wf/run/results-0000.json", uri)
+	require.Contains(t, fake.objects, "/scans/results/wf/run/results-0000.json")
+
+	data, err := store.Get(ctx, uri)
+	require.NoError(t, err)
+	require.Equal(t, "[]", string(data))
+
+	_, err = store.Get(ctx, "s3://scans/results/nope.json")
+	require.True(t, errors.Is(err, ErrBlobNotFound), "got %v", err)
+
+	_, err = store.Get(ctx, "s3://other-bucket/x.json")
+	require.Error(t, err)
+}
+
+func TestOpenBlobStore(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "")
+
+	s, err := OpenBlobStore("s3://scans/prefix/")
+	require.NoError(t, err)
+	require.Equal(t, &S3BlobStore{Bucket: "scans", Prefix: "prefix", Region: "us-east-1",
+		AccessKeyID: "AKID", SecretAccessKey: "secret"}, s)
+
+	s, err = OpenBlobStore("file:///var/lib/scanner")
+	require.NoError(t, err)
+	require.Equal(t, &FileBlobStore{Dir: "/var/lib/scanner"}, s)
+
+	s, err = OpenBlobStore("./blobs")
+	require.NoError(t, err)
+	require.Equal(t, &FileBlobStore{Dir: "./blobs"}, s)
+
+	_, err = OpenBlobStore("gs://bucket")
+	require.Error(t, err)
+}
+
+func TestStoreAndLoadResults(t *testing.T) {
+	store := &FileBlobStore{Dir: t.TempDir()}
+	env := newActivityEnv(&Activities{BlobStore: store})
+	results := []RepoSecurityResult{
+		{Repository: "repo-000", SecretScanning: StatusEnabled},
+		{Repository: "repo-001", SecretScanning: StatusDisabled},
+	}
+
+	val, err := env.ExecuteActivity("StoreResults", 0, results)
+	require.NoError(t, err)
+	var ref BlobRef
+	require.NoError(t, val.Get(&ref))
+	require.Equal(t, 2, ref.Count)
+
+	val, err = env.ExecuteActivity("LoadResults", ref)
+	require.NoError(t, err)
+	var loaded []RepoSecurityResult
+	require.NoError(t, val.Get(&loaded))
+	require.Equal(t, results, loaded)
+
+	// A tampered blob is rejected rather than silently skewing the report.
+	ref.SHA256 = strings.Repeat("0", 64)
+	_, err = env.ExecuteActivity("LoadResults", ref)
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, "BLOB_CORRUPT", appErr.Type())
+}
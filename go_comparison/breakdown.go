@@ -0,0 +1,142 @@
+package scanner
+
+// =============================================================================
+// Report breakdowns — compliance per repo visibility, language and topic
+// =============================================================================
+//
+// "What is our compliance on public repos?" and "is it the Java repos that
+// are behind?" are answered by the report's by_visibility and by_language
+// sections. Each buckets the scanned repos by the RepoMetadata the workflow
+// copied into their results, and gives every bucket the org summary in
+// small: counts, a compliance rate over its determinate repos, and its
+// lowest-scoring non-compliant repos. Repos without the value — no detected
+// language, or no metadata at all — are bucketed as "unknown". Scans whose
+// results carry no metadata have no breakdown sections. by_topic is built
+// the same way, with overlapping buckets (see topics.go). RenderReport
+// shows the breakdowns when verbose.
+// =============================================================================
+
+import "fmt"
+
+// BucketUnknown is the breakdown bucket of repos without the value.
+const BucketUnknown = "unknown"
+
+// breakdownTopLimit caps the repos listed in a bucket's
+// top_non_compliant_repos.
+const breakdownTopLimit = 5
+
+// ComplianceBreakdown is one bucket of by_visibility or by_language.
+type ComplianceBreakdown struct {
+	Repos          int    `json:"repos"`
+	FullyCompliant int    `json:"fully_compliant"`
+	NonCompliant   int    `json:"non_compliant"`
+	Indeterminate  int    `json:"indeterminate,omitempty"`
+	ComplianceRate string `json:"compliance_rate"`
+	// TopNonCompliantRepos are the bucket's lowest-scoring non-compliant
+	// repos, lowest first.
+	TopNonCompliantRepos []string `json:"top_non_compliant_repos,omitempty"`
+}
+
+// complianceRate is the rate the report gives compliant repos out of
+// total, leaving out the indeterminate ones.
+func complianceRate(compliant, total, indeterminate int) string {
+	if determinate := total - indeterminate; determinate > 0 {
+		return fmt.Sprintf("%.1f%%", float64(compliant)/float64(determinate)*100)
+	}
+	return "N/A"
+}
+
+// breakdownTotals aggregates results into the buckets of one breakdown.
+type breakdownTotals struct {
+	buckets map[string]*ComplianceBreakdown
+	worst   map[string]*scoreTotals
+}
+
+func newBreakdownTotals() *breakdownTotals {
+	return &breakdownTotals{buckets: map[string]*ComplianceBreakdown{}, worst: map[string]*scoreTotals{}}
+}
+
+// add counts repo in bucket key with its outcome; score ranks it among the
+// bucket's non-compliant repos. Errored repos count only in Repos, as they
+// count only in the report's total_repos.
+func (t *breakdownTotals) add(key, repo string, outcome ComplianceOutcome, errored bool, score float64) {
+	if key == "" {
+		key = BucketUnknown
+	}
+	b := t.buckets[key]
+	if b == nil {
+		b = &ComplianceBreakdown{}
+		t.buckets[key] = b
+		t.worst[key] = &scoreTotals{}
+	}
+	b.Repos++
+	switch {
+	case outcome == OutcomeCompliant:
+		b.FullyCompliant++
+	case errored:
+		// Counted in the report's errors.
+	case outcome == OutcomeIndeterminate:
+		b.Indeterminate++
+	default:
+		b.NonCompliant++
+		t.worst[key].add(repo, score)
+	}
+}
+
+// sections returns the breakdown as the report writes it.
+func (t *breakdownTotals) sections() map[string]ComplianceBreakdown {
+	out := make(map[string]ComplianceBreakdown, len(t.buckets))
+	for key, b := range t.buckets {
+		b.ComplianceRate = complianceRate(b.FullyCompliant, b.Repos, b.Indeterminate)
+		for _, s := range t.worst[key].ranked(breakdownTopLimit) {
+			b.TopNonCompliantRepos = append(b.TopNonCompliantRepos, s.Repository)
+		}
+		out[key] = *b
+	}
+	return out
+}
+
+// validateBreakdown checks that the buckets of a breakdown add up to the
+// report's totals and that each bucket's rate matches its counts.
+func validateBreakdown(name string, buckets map[string]ComplianceBreakdown, r Report) error {
+	repos, compliant := 0, 0
+	for key, b := range buckets {
+		if b.FullyCompliant+b.NonCompliant+b.Indeterminate > b.Repos {
+			return fmt.Errorf("%s[%s] counts more compliant, non-compliant and indeterminate repos than its %d", name, key, b.Repos)
+		}
+		if want := complianceRate(b.FullyCompliant, b.Repos, b.Indeterminate); b.ComplianceRate != want {
+			return fmt.Errorf("%s[%s] compliance_rate %s does not match its counts (%s)", name, key, b.ComplianceRate, want)
+		}
+		repos += b.Repos
+		compliant += b.FullyCompliant
+	}
+	if repos != r.TotalRepos || compliant != r.FullyCompliant {
+		return fmt.Errorf("%s adds up to %d repos, %d compliant, not %d and %d", name, repos, compliant, r.TotalRepos, r.FullyCompliant)
+	}
+	return nil
+}
+
+// validateTopicBreakdown checks by_topic, whose buckets overlap: each
+// holds at most the report's repos, and together at least all of them.
+func validateTopicBreakdown(buckets map[string]ComplianceBreakdown, r Report) error {
+	if buckets == nil {
+		return nil
+	}
+	repos := 0
+	for key, b := range buckets {
+		if b.FullyCompliant+b.NonCompliant+b.Indeterminate > b.Repos {
+			return fmt.Errorf("by_topic[%s] counts more compliant, non-compliant and indeterminate repos than its %d", key, b.Repos)
+		}
+		if want := complianceRate(b.FullyCompliant, b.Repos, b.Indeterminate); b.ComplianceRate != want {
+			return fmt.Errorf("by_topic[%s] compliance_rate %s does not match its counts (%s)", key, b.ComplianceRate, want)
+		}
+		if b.Repos > r.TotalRepos || b.FullyCompliant > r.FullyCompliant {
+			return fmt.Errorf("by_topic[%s] has %d repos, %d compliant, more than the report's %d and %d", key, b.Repos, b.FullyCompliant, r.TotalRepos, r.FullyCompliant)
+		}
+		repos += b.Repos
+	}
+	if repos < r.TotalRepos {
+		return fmt.Errorf("by_topic adds up to %d repos, fewer than %d", repos, r.TotalRepos)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateReportBreakdowns(t *testing.T) {
+	repo := func(name, visibility, language string, code SecurityStatus) RepoSecurityResult {
+		r := RepoSecurityResult{Repository: name, Metadata: &RepoMetadata{Visibility: visibility, Language: language}}
+		r.setCheck(CheckSecretScanning, CheckResult{Status: StatusEnabled})
+		r.setCheck(CheckDependabot, CheckResult{Status: StatusEnabled})
+		r.setCheck(CheckCodeScanning, CheckResult{Status: code})
+		return r
+	}
+	results := []RepoSecurityResult{
+		repo("api", "public", "Go", StatusEnabled),
+		repo("web", "public", "TypeScript", StatusNotConfigured),
+		repo("billing", "private", "Java", StatusNotConfigured),
+		repo("ledger", "private", "Java", StatusNoAccess),
+		repo("infra", "internal", "", StatusEnabled),
+	}
+	env := newActivityEnv(&Activities{})
+	val, err := env.ExecuteActivity("GenerateReport", "acme", results,
+		[]BlobRef(nil), DefaultCompliancePolicy(), []string(nil), []Suppression(nil))
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, val.Get(&report))
+
+	require.Equal(t, map[string]ComplianceBreakdown{
+		"public":   {Repos: 2, FullyCompliant: 1, NonCompliant: 1, ComplianceRate: "50.0%", TopNonCompliantRepos: []string{"web"}},
+		"private":  {Repos: 2, NonCompliant: 1, Indeterminate: 1, ComplianceRate: "0.0%", TopNonCompliantRepos: []string{"billing"}},
+		"internal": {Repos: 1, FullyCompliant: 1, ComplianceRate: "100.0%"},
+	}, report.ByVisibility)
+	require.Equal(t, []string{"Go", "Java", "TypeScript", BucketUnknown}, sortedKeys(report.ByLanguage))
+	require.Equal(t, ComplianceBreakdown{Repos: 1, FullyCompliant: 1, ComplianceRate: "100.0%"}, report.ByLanguage[BucketUnknown],
+		"no detected language is unknown")
+
+	// Without metadata there is nothing to break down.
+	for i := range results {
+		results[i].Metadata = nil
+	}
+	val, err = env.ExecuteActivity("GenerateReport", "acme", results,
+		[]BlobRef(nil), DefaultCompliancePolicy(), []string(nil), []Suppression(nil))
+	require.NoError(t, err)
+	var bare map[string]interface{}
+	require.NoError(t, val.Get(&bare))
+	require.NotContains(t, bare, "by_visibility")
+	require.NotContains(t, bare, "by_language")
+}
+
+func TestBreakdownTopNonCompliantLimit(t *testing.T) {
+	totals := newBreakdownTotals()
+	for _, repo := range []string{"g", "f", "e", "d", "c", "b", "a"} {
+		totals.add("Go", repo, OutcomeNonCompliant, false, 50)
+	}
+	totals.add("Go", "worst", OutcomeNonCompliant, false, 10)
+	require.Equal(t, []string{"worst", "a", "b", "c", "d"}, totals.sections()["Go"].TopNonCompliantRepos)
+}
+
+func TestValidateBreakdown(t *testing.T) {
+	r := Report{TotalRepos: 3, FullyCompliant: 1, ComplianceRate: "33.3%", NonCompliantRepos: []string{"a", "b"}}
+	r.ByVisibility = map[string]ComplianceBreakdown{
+		"public":  {Repos: 2, FullyCompliant: 1, NonCompliant: 1, ComplianceRate: "50.0%"},
+		"private": {Repos: 1, NonCompliant: 1, ComplianceRate: "0.0%"},
+	}
+	require.NoError(t, r.Validate())
+
+	r.ByVisibility["private"] = ComplianceBreakdown{Repos: 2, NonCompliant: 1, ComplianceRate: "0.0%"}
+	require.ErrorContains(t, r.Validate(), "by_visibility adds up to 4 repos")
+
+	r.ByVisibility["private"] = ComplianceBreakdown{Repos: 1, NonCompliant: 1, ComplianceRate: "100.0%"}
+	require.ErrorContains(t, r.Validate(), "by_visibility[private] compliance_rate 100.0% does not match its counts (0.0%)")
+}
+
+func TestRenderReportBreakdowns(t *testing.T) {
+	r := renderFixture()
+	r.ByVisibility = map[string]ComplianceBreakdown{
+		"public":  {Repos: 5, FullyCompliant: 1, NonCompliant: 4, ComplianceRate: "20.0%", TopNonCompliantRepos: []string{"svc-01", "svc-02"}},
+		"private": {Repos: 20, FullyCompliant: 2, NonCompliant: 18, ComplianceRate: "10.0%"},
+	}
+
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.NotContains(t, buf.String(), "By visibility", "only verbose output has the breakdowns")
+
+	buf.Reset()
+	RenderReport(&buf, r, RenderOptions{Verbose: true})
+	require.Contains(t, buf.String(), "\n  By visibility:\n"+
+		"    private        20 repos   10.0%\n"+
+		"    public          5 repos   20.0%  behind: svc-01, svc-02\n")
+	require.NotContains(t, buf.String(), "By language")
+}
@@ -0,0 +1,198 @@
+package scanner
+
+// =============================================================================
+// Check registry — which security checks a scan runs
+// =============================================================================
+//
+// Every check costs GitHub API calls per repo, so scans choose the checks
+// they need via ScanInput.Checks. The registry is the single list of valid
+// names; the workflow, activities, report, and starter all key off it.
+// =============================================================================
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Check names accepted in ScanInput.Checks.
+const (
+	CheckSecretScanning = "secret_scanning"
+	CheckDependabot     = "dependabot"
+	CheckCodeScanning   = "code_scanning"
+	CheckFiles          = "files"
+	CheckActions        = "actions"
+	CheckAccessAudit    = "access_audit"
+
+	// Dependabot security updates and dependabot.yml; see dependabot.go.
+	CheckSecurityUpdates  = "security_updates"
+	CheckDependabotConfig = "dependabot_config"
+
+	// Insecure repository webhooks; see webhooks.go.
+	CheckWebhooks = "webhooks"
+)
+
+// Keys in RepoSecurityResult.Checks besides the check names themselves. The
+// files check records each file separately, and the actions check records
+// the default GITHUB_TOKEN permission on its own so a policy can require it.
+const (
+	ResultCodeowners            = "codeowners"
+	ResultSecurityPolicy        = "security_policy"
+	ResultReadOnlyWorkflowToken = "read_only_workflow_token"
+)
+
+// resultChecks maps each RepoSecurityResult.Checks key to the check that
+// records it.
+var resultChecks = map[string]string{
+	CheckSecretScanning:         CheckSecretScanning,
+	CheckDependabot:             CheckDependabot,
+	CheckCodeScanning:           CheckCodeScanning,
+	ResultCodeowners:            CheckFiles,
+	ResultSecurityPolicy:        CheckFiles,
+	CheckActions:                CheckActions,
+	ResultReadOnlyWorkflowToken: CheckActions,
+	CheckAccessAudit:            CheckAccessAudit,
+	CheckSecurityUpdates:        CheckSecurityUpdates,
+	CheckDependabotConfig:       CheckDependabotConfig,
+	CheckWebhooks:               CheckWebhooks,
+}
+
+// ErrTypeInvalidInput is the ApplicationError type for a ScanInput the
+// workflow rejects before scanning, e.g. an unknown check name.
+const ErrTypeInvalidInput = "INVALID_INPUT"
+
+// CheckInfo describes one registered check.
+type CheckInfo struct {
+	Name        string
+	Description string
+	// APICalls is the worst-case number of extra GitHub requests per repo.
+	APICalls int
+}
+
+// CheckRegistry lists every known check in display order.
+var CheckRegistry = []CheckInfo{
+	{CheckSecretScanning, "Secret scanning enabled (read from the repo itself)", 0},
+	{CheckDependabot, "Dependabot vulnerability alerts enabled", 1},
+	{CheckCodeScanning, "Code scanning (GHAS) configured", 1},
+	{CheckFiles, "CODEOWNERS and SECURITY.md present and non-empty", 7},
+	{CheckActions, "GitHub Actions allowed actions and default GITHUB_TOKEN permissions", 2},
+	{CheckAccessAudit, "Deploy keys and outside collaborators (needs admin scope)", 2},
+	{CheckSecurityUpdates, "Dependabot security updates enabled (needs admin scope)", 1},
+	{CheckDependabotConfig, ".github/dependabot.yml present with a valid update schedule", 2},
+	{CheckWebhooks, "Webhooks use HTTPS, a secret and allowed domains (needs admin scope)", 1},
+}
+
+// DefaultChecks are the checks run when ScanInput.Checks is empty: the three
+// GHAS features the Python version checks.
+func DefaultChecks() []string {
+	return []string{CheckSecretScanning, CheckDependabot, CheckCodeScanning}
+}
+
+// ValidateChecks returns an error naming any check not in CheckRegistry.
+func ValidateChecks(names []string) error {
+	var unknown []string
+	for _, n := range names {
+		if lookupCheck(n) == nil {
+			unknown = append(unknown, n)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	known := make([]string, len(CheckRegistry))
+	for i, c := range CheckRegistry {
+		known[i] = c.Name
+	}
+	return fmt.Errorf("unknown check(s) %s; known checks: %s",
+		strings.Join(unknown, ", "), strings.Join(known, ", "))
+}
+
+func lookupCheck(name string) *CheckInfo {
+	for i := range CheckRegistry {
+		if CheckRegistry[i].Name == name {
+			return &CheckRegistry[i]
+		}
+	}
+	return nil
+}
+
+// checkSet is a set of selected check names.
+type checkSet map[string]bool
+
+// newCheckSet builds the set for names, falling back to DefaultChecks when
+// names is empty.
+func newCheckSet(names []string) checkSet {
+	if len(names) == 0 {
+		names = DefaultChecks()
+	}
+	set := make(checkSet, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+	return set
+}
+
+// names returns the selected checks in registry order.
+func (s checkSet) names() []string {
+	out := make([]string, 0, len(s))
+	for _, c := range CheckRegistry {
+		if s[c.Name] {
+			out = append(out, c.Name)
+		}
+	}
+	return out
+}
+
+// checks returns the checks this input selects.
+// IncludeAccessAudit is shorthand for adding CheckAccessAudit.
+func (in ScanInput) checks() checkSet {
+	set := newCheckSet(in.Checks)
+	if in.IncludeAccessAudit {
+		set[CheckAccessAudit] = true
+	}
+	return set
+}
+
+// requiredChecks lists the checks a compliance policy depends on.
+func (p CompliancePolicy) requiredChecks() []string {
+	var out []string
+	if p.RequireSecretScanning {
+		out = append(out, CheckSecretScanning)
+	}
+	if p.RequireDependabot {
+		out = append(out, CheckDependabot)
+	}
+	if p.RequireCodeScanning {
+		out = append(out, CheckCodeScanning)
+	}
+	if p.RequireCodeowners || p.RequireSecurityPolicy {
+		out = append(out, CheckFiles)
+	}
+	if p.RequireReadOnlyWorkflowToken {
+		out = append(out, CheckActions)
+	}
+	if p.RequireSecurityUpdates {
+		out = append(out, CheckSecurityUpdates)
+	}
+	if p.RequireDependabotConfig {
+		out = append(out, CheckDependabotConfig)
+	}
+	if p.RequireSecureWebhooks {
+		out = append(out, CheckWebhooks)
+	}
+	return out
+}
+
+// forChecks drops requirements on checks that are not selected, so the
+// default policy works with any check set.
+func (p CompliancePolicy) forChecks(set checkSet) CompliancePolicy {
+	p.RequireSecretScanning = p.RequireSecretScanning && set[CheckSecretScanning]
+	p.RequireDependabot = p.RequireDependabot && set[CheckDependabot]
+	p.RequireCodeScanning = p.RequireCodeScanning && set[CheckCodeScanning]
+	p.RequireCodeowners = p.RequireCodeowners && set[CheckFiles]
+	p.RequireSecurityPolicy = p.RequireSecurityPolicy && set[CheckFiles]
+	p.RequireReadOnlyWorkflowToken = p.RequireReadOnlyWorkflowToken && set[CheckActions]
+	p.RequireSecurityUpdates = p.RequireSecurityUpdates && set[CheckSecurityUpdates]
+	p.RequireDependabotConfig = p.RequireDependabotConfig && set[CheckDependabotConfig]
+	p.RequireSecureWebhooks = p.RequireSecureWebhooks && set[CheckWebhooks]
+	return p
+}
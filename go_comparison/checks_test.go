@@ -0,0 +1,69 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestValidateChecks(t *testing.T) {
+	require.NoError(t, ValidateChecks(nil))
+	require.NoError(t, ValidateChecks([]string{CheckFiles, CheckActions}))
+
+	err := ValidateChecks([]string{CheckFiles, "branch_protection"})
+	require.ErrorContains(t, err, "branch_protection")
+	require.ErrorContains(t, err, CheckAccessAudit, "the error lists the known checks")
+}
+
+func TestAPICallsPerRepo(t *testing.T) {
+	require.Equal(t, 3, ScanInput{}.APICallsPerRepo())
+	require.Equal(t, 3, ScanInput{Checks: DefaultChecks()}.APICallsPerRepo())
+	require.Equal(t, 1, ScanInput{Checks: []string{CheckSecretScanning}}.APICallsPerRepo())
+	require.Equal(t, 9, ScanInput{Checks: []string{CheckCodeScanning, CheckFiles}}.APICallsPerRepo())
+	require.Equal(t, 5, ScanInput{IncludeAccessAudit: true}.APICallsPerRepo())
+}
+
+func TestWorkflowRejectsInvalidChecks(t *testing.T) {
+	strict := DefaultCompliancePolicy()
+	strict.RequireCodeowners = true
+
+	for name, input := range map[string]ScanInput{
+		"unknown check":        {Org: "acme", Checks: []string{"branch_protection"}},
+		"unselected by policy": {Org: "acme", CompliancePolicy: &strict},
+	} {
+		input := input
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.ExecuteWorkflow(SecurityScanWorkflow, input)
+
+			require.True(t, env.IsWorkflowCompleted())
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(env.GetWorkflowError(), &appErr), "got %v", env.GetWorkflowError())
+			require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+		})
+	}
+}
+
+func TestWorkflowSelectedChecksOnly(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "acme", mock.Anything, mock.Anything, []string{CheckSecretScanning}).
+		Return(&RepoSecurityResult{SecretScanning: StatusEnabled, DependabotAlerts: StatusUnknown, CodeScanning: StatusUnknown}, nil)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Checks: []string{CheckSecretScanning}})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, []interface{}{CheckSecretScanning}, report["checks"])
+	require.EqualValues(t, 3, report["secret_scanning_enabled"])
+	require.EqualValues(t, 3, report["fully_compliant"], "deselected checks are not required")
+	require.EqualValues(t, 3, report["estimated_api_calls"])
+	require.NotContains(t, report, "dependabot_enabled")
+	require.NotContains(t, report, "code_scanning_enabled")
+}
@@ -0,0 +1,122 @@
+package scanner
+
+// =============================================================================
+// Payload compression — smaller histories for big orgs
+// =============================================================================
+//
+// Scan results are repetitive JSON, and for large orgs they are most of a
+// scan's history. CompressionCodec zlib-compresses payloads at or above a
+// size threshold on their way to the server; smaller ones are left alone
+// because compressing them saves nothing. Compressed payloads carry the
+// "binary/zlib" encoding, the same marker the SDK's zlib codec uses, and
+// anything without it is passed through on decode. So histories recorded
+// before compression was turned on, or with it off, decode and replay
+// unchanged.
+//
+// Compression is opt-in (SCAN_PAYLOAD_COMPRESSION=on, or a threshold in
+// bytes): a compressed payload is unreadable to any client without the
+// codec, such as the temporal CLI or the Web UI with no codec server, so
+// a deployment turns it on once those are set up. Decoding is always on,
+// so the worker and the starter, which both use NewDataConverter, read
+// what others compressed whatever their own setting.
+// NewCodecServer serves the codec over Temporal's codec server HTTP
+// contract (POST /encode and /decode), which lets the Web UI and the CLI
+// show compressed payloads.
+// =============================================================================
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultCompressionThreshold is the encoded payload size, in bytes, from
+// which payloads are compressed when compression is turned on.
+const DefaultCompressionThreshold = 1024
+
+// EncodingZlib is the encoding metadata of a compressed payload.
+const EncodingZlib = "binary/zlib"
+
+// CompressionCodec is a converter.PayloadCodec that compresses payloads of
+// at least Threshold bytes. A negative Threshold turns compression off but
+// still decodes compressed payloads.
+type CompressionCodec struct {
+	Threshold int
+}
+
+var zlibCodec = converter.NewZlibCodec(converter.ZlibCodecOptions{})
+
+// Encode compresses each payload that is large enough and gets smaller.
+func (c CompressionCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	if c.Threshold < 0 {
+		return payloads, nil
+	}
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		if proto.Size(p) < c.Threshold {
+			result[i] = p
+			continue
+		}
+		encoded, err := zlibCodec.Encode([]*commonpb.Payload{p})
+		if err != nil {
+			return payloads, err
+		}
+		result[i] = encoded[0]
+	}
+	return result, nil
+}
+
+// Decode decompresses payloads marked EncodingZlib and passes the rest
+// through.
+func (c CompressionCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	return zlibCodec.Decode(payloads)
+}
+
+// ParseCompressionThreshold reads a SCAN_PAYLOAD_COMPRESSION setting:
+// empty or "off" for no compression, "on" for DefaultCompressionThreshold,
+// or a size in bytes.
+func ParseCompressionThreshold(s string) (int, error) {
+	switch s = strings.TrimSpace(s); strings.ToLower(s) {
+	case "", "off", "false", "0":
+		return -1, nil
+	case "on", "true":
+		return DefaultCompressionThreshold, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("payload compression %q: want on, off or a size in bytes", s)
+	}
+	return n, nil
+}
+
+// NewDataConverter is the default data converter with CompressionCodec
+// applied. Pass it as client.Options.DataConverter on the worker and every
+// client.
+func NewDataConverter(threshold int) converter.DataConverter {
+	return converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), CompressionCodec{Threshold: threshold})
+}
+
+// NewCodecServer serves CompressionCodec over the codec server HTTP
+// contract. allowOrigin, when set, is the Web UI origin allowed to call it
+// from the browser, e.g. http://localhost:8233.
+func NewCodecServer(threshold int, allowOrigin string) http.Handler {
+	codec := converter.NewPayloadCodecHTTPHandler(CompressionCodec{Threshold: threshold})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if allowOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type,X-Namespace,Authorization")
+			w.Header().Set("Access-Control-Allow-Methods", "POST,OPTIONS")
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		codec.ServeHTTP(w, r)
+	})
+}
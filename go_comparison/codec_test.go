@@ -0,0 +1,203 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/worker"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// largeResults is a batch of scan results well over the default threshold.
+func largeResults() []RepoSecurityResult {
+	results := make([]RepoSecurityResult, 50)
+	for i := range results {
+		results[i] = RepoSecurityResult{
+			Repository:       fmt.Sprintf("service-%02d", i),
+			SecretScanning:   StatusEnabled,
+			DependabotAlerts: StatusDisabled,
+			CodeScanning:     StatusNotConfigured,
+		}
+	}
+	return results
+}
+
+func TestCompressionRoundTrip(t *testing.T) {
+	dc := NewDataConverter(DefaultCompressionThreshold)
+
+	payload, err := dc.ToPayload(largeResults())
+	require.NoError(t, err)
+	require.Equal(t, EncodingZlib, string(payload.Metadata[converter.MetadataEncoding]))
+
+	raw, err := converter.GetDefaultDataConverter().ToPayload(largeResults())
+	require.NoError(t, err)
+	require.Less(t, len(payload.Data), len(raw.Data)/4, "scan results should compress well")
+
+	var got []RepoSecurityResult
+	require.NoError(t, dc.FromPayload(payload, &got))
+	require.Equal(t, largeResults(), got)
+}
+
+func TestCompressionSkipsSmallPayloads(t *testing.T) {
+	dc := NewDataConverter(DefaultCompressionThreshold)
+
+	payload, err := dc.ToPayload("acme-corp")
+	require.NoError(t, err)
+	require.Equal(t, converter.MetadataEncodingJSON, string(payload.Metadata[converter.MetadataEncoding]))
+}
+
+func TestCompressionOffStillDecodes(t *testing.T) {
+	compressed, err := NewDataConverter(0).ToPayload(largeResults())
+	require.NoError(t, err)
+	require.Equal(t, EncodingZlib, string(compressed.Metadata[converter.MetadataEncoding]))
+
+	off := NewDataConverter(-1)
+	payload, err := off.ToPayload(largeResults())
+	require.NoError(t, err)
+	require.Equal(t, converter.MetadataEncodingJSON, string(payload.Metadata[converter.MetadataEncoding]))
+
+	var got []RepoSecurityResult
+	require.NoError(t, off.FromPayload(compressed, &got))
+	require.Equal(t, largeResults(), got)
+}
+
+func TestParseCompressionThreshold(t *testing.T) {
+	for in, want := range map[string]int{
+		"": -1, "off": -1, "OFF": -1, "0": -1,
+		"on": DefaultCompressionThreshold, "true": DefaultCompressionThreshold, "4096": 4096,
+	} {
+		got, err := ParseCompressionThreshold(in)
+		require.NoError(t, err, in)
+		require.Equal(t, want, got, in)
+	}
+	for _, bad := range []string{"yes", "-5", "1kb"} {
+		_, err := ParseCompressionThreshold(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestCodecServer(t *testing.T) {
+	srv := httptest.NewServer(NewCodecServer(DefaultCompressionThreshold, "http://localhost:8233"))
+	t.Cleanup(srv.Close)
+
+	raw, err := converter.GetDefaultDataConverter().ToPayload(largeResults())
+	require.NoError(t, err)
+	post := func(path string, payloads []*commonpb.Payload) []*commonpb.Payload {
+		body, err := protojson.Marshal(&commonpb.Payloads{Payloads: payloads})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, srv.URL+path, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Namespace", "default")
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "http://localhost:8233", resp.Header.Get("Access-Control-Allow-Origin"))
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var out commonpb.Payloads
+		require.NoError(t, protojson.Unmarshal(b, &out))
+		return out.Payloads
+	}
+
+	encoded := post("/encode", []*commonpb.Payload{raw})
+	require.Equal(t, EncodingZlib, string(encoded[0].Metadata[converter.MetadataEncoding]))
+	decoded := post("/decode", encoded)
+	require.Equal(t, raw.Data, decoded[0].Data)
+
+	// The Web UI sends a preflight before calling from the browser.
+	req, err := http.NewRequest(http.MethodOptions, srv.URL+"/decode", nil)
+	require.NoError(t, err)
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "X-Namespace")
+}
+
+// TestReplayUncompressedHistoriesWithCodec replays the goldens, recorded
+// before compression existed, through the compressing converter: a worker
+// upgraded mid-scan must still read them.
+func TestReplayUncompressedHistoriesWithCodec(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "histories", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			replayer := codecReplayer(t)
+			require.NoError(t, replayer.ReplayWorkflowHistoryFromJSONFile(nil, file))
+		})
+	}
+}
+
+// TestReplayMixedHistory compresses the activity results of a golden and
+// leaves the rest as recorded, as in a scan that was running when
+// compression was turned on.
+func TestReplayMixedHistory(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "histories", "completed.json"))
+	require.NoError(t, err)
+	defer f.Close()
+	hist, err := client.HistoryFromJSON(f, client.HistoryJSONOptions{})
+	require.NoError(t, err)
+
+	codec := CompressionCodec{Threshold: 0}
+	compressed := 0
+	for _, event := range hist.Events {
+		attrs := event.GetActivityTaskCompletedEventAttributes()
+		if attrs == nil || attrs.GetResult() == nil {
+			continue
+		}
+		encoded, err := codec.Encode(attrs.Result.Payloads)
+		require.NoError(t, err)
+		for _, p := range encoded {
+			if string(p.Metadata[converter.MetadataEncoding]) == EncodingZlib {
+				compressed++
+			}
+		}
+		attrs.Result.Payloads = encoded
+	}
+	require.Positive(t, compressed, "expected some activity results to compress")
+	require.True(t, mixedEncodings(t, hist))
+
+	require.NoError(t, codecReplayer(t).ReplayWorkflowHistory(nil, hist))
+
+	plain := worker.NewWorkflowReplayer()
+	plain.RegisterWorkflow(SecurityScanWorkflow)
+	require.Error(t, plain.ReplayWorkflowHistory(nil, hist), "without the codec the compressed results are unreadable")
+}
+
+func codecReplayer(t *testing.T) worker.WorkflowReplayer {
+	t.Helper()
+	replayer, err := worker.NewWorkflowReplayerWithOptions(worker.WorkflowReplayerOptions{
+		DataConverter: NewDataConverter(DefaultCompressionThreshold),
+	})
+	require.NoError(t, err)
+	replayer.RegisterWorkflow(SecurityScanWorkflow)
+	return replayer
+}
+
+// mixedEncodings reports whether hist holds both compressed and plain JSON
+// payloads.
+func mixedEncodings(t *testing.T, hist *historypb.History) bool {
+	b, err := protojson.Marshal(hist)
+	require.NoError(t, err)
+	// Metadata values are base64 in protojson.
+	s := string(b)
+	return strings.Contains(s, `"YmluYXJ5L3psaWI="`) && strings.Contains(s, `"anNvbi9wbGFpbg=="`)
+}
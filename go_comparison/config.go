@@ -0,0 +1,423 @@
+package scanner
+
+// =============================================================================
+// Config files — the starter's and worker's settings, checked into git
+// =============================================================================
+//
+// The starter (--config scan.yaml) and the worker (SCANNER_CONFIG) read a
+// YAML config file whose keys are their flag names:
+//
+//	org: temporalio
+//	checks: [secret_scanning, dependabot]
+//	batch-delay: 5s
+//	team: [platform, payments]  # a repeatable flag takes a list
+//	env:
+//	  SCAN_BLOB_STORE: s3://scans/prod
+//
+// The env section sets the environment variables the program reads, and
+// only those. A setting comes from, in order: its flag on the command
+// line, its environment variable, the file, its default. So the file sets
+// a variable only when it is unset, and a flag that falls back to a
+// variable (the starter's --token and GITHUB_TOKEN) only when that is
+// unset too. JSON is YAML, so a JSON file works as well.
+//
+// Unknown keys are errors that suggest the nearest known key, so a typo
+// fails loudly instead of scanning with the default. Config.Print
+// (--print-config) writes the merged settings back out as a config file,
+// noting where each value came from, with the settings ConfigOptions names
+// as secrets redacted.
+// =============================================================================
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFlag and PrintConfigFlag are the flags that read a config file and
+// print the merged settings. A config file cannot set them.
+const (
+	ConfigFlag      = "config"
+	PrintConfigFlag = "print-config"
+)
+
+// Where a setting's value came from, in order of precedence.
+const (
+	ConfigSourceFlag    = "flag"
+	ConfigSourceEnv     = "env"
+	ConfigSourceFile    = "file"
+	ConfigSourceDefault = "default"
+)
+
+// configEnvKey is the config file's section of environment variables.
+const configEnvKey = "env"
+
+// ConfigFile is a parsed config file.
+type ConfigFile struct {
+	Path string
+	// Flags are the flags' values by flag name: one value, or a
+	// repeatable flag's values in order.
+	Flags map[string][]string
+	// Env are the env section's variables.
+	Env map[string]string
+	// lines are where each key is, for errors.
+	lines map[string]int
+}
+
+// LoadConfigFile reads and parses the config file at path.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	c, err := ParseConfigFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c.Path = path
+	return c, nil
+}
+
+// ParseConfigFile parses a config file. It checks the file's shape; which
+// keys are known is up to ApplyConfig.
+func ParseConfigFile(data []byte) (*ConfigFile, error) {
+	c := &ConfigFile{Flags: map[string][]string{}, Env: map[string]string{}, lines: map[string]int{}}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return c, nil // empty
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: a config file is a mapping of settings to values", root.Line)
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		name := key.Value
+		if _, dup := c.lines[name]; dup {
+			return nil, fmt.Errorf("line %d: %q is set twice", key.Line, name)
+		}
+		c.lines[name] = key.Line
+		if name == configEnvKey {
+			env, err := configEnv(value)
+			if err != nil {
+				return nil, err
+			}
+			c.Env = env
+			continue
+		}
+		values, err := configValues(name, value)
+		if err != nil {
+			return nil, err
+		}
+		c.Flags[name] = values
+	}
+	return c, nil
+}
+
+// configValues is a flag's value or list of values.
+func configValues(name string, n *yaml.Node) ([]string, error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return []string{n.Value}, nil
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(n.Content))
+		for _, v := range n.Content {
+			if v.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: %q takes a value or a list of values", v.Line, name)
+			}
+			values = append(values, v.Value)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("line %d: %q takes a value or a list of values", n.Line, name)
+}
+
+// configEnv is the env section: variable names to values.
+func configEnv(n *yaml.Node) (map[string]string, error) {
+	if n.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: %q is a mapping of environment variables to values", n.Line, configEnvKey)
+	}
+	env := make(map[string]string, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: environment variable %s takes a single value", value.Line, key.Value)
+		}
+		if _, dup := env[key.Value]; dup {
+			return nil, fmt.Errorf("line %d: environment variable %s is set twice", key.Line, key.Value)
+		}
+		env[key.Value] = value.Value
+	}
+	return env, nil
+}
+
+// ConfigOptions are a program's settings besides its flags.
+type ConfigOptions struct {
+	// Env are the environment variables the program reads. A config
+	// file's env section may set only these.
+	Env []string
+	// FlagEnv are the flags that fall back to environment variables, and
+	// those variables. A file value for such a flag is used only when
+	// none of its variables is set.
+	FlagEnv map[string][]string
+	// Secrets are the flags and environment variables whose values Print
+	// redacts.
+	Secrets []string
+}
+
+// Config is a program's merged settings.
+type Config struct {
+	fs     *flag.FlagSet
+	opts   ConfigOptions
+	source map[string]string
+}
+
+// LoadConfig reads the config file at path, unless path is empty, and
+// applies it to the parsed fs as ApplyConfig does.
+func LoadConfig(fs *flag.FlagSet, path string, opts ConfigOptions) (*Config, error) {
+	var file *ConfigFile
+	if path != "" {
+		var err error
+		if file, err = LoadConfigFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return ApplyConfig(fs, file, opts)
+}
+
+// ApplyConfig sets the flags of the parsed fs that its command line left
+// unset, and the unset environment variables, from file, which may be
+// nil. Unknown keys in file, and values its flags reject, are errors.
+func ApplyConfig(fs *flag.FlagSet, file *ConfigFile, opts ConfigOptions) (*Config, error) {
+	c := &Config{fs: fs, opts: opts, source: map[string]string{}}
+	if file == nil {
+		file = &ConfigFile{}
+	}
+	if err := file.checkKeys(fs, opts); err != nil {
+		return nil, err
+	}
+
+	// Whether a variable is set is decided before the file sets any, so
+	// its env section does not override FlagEnv flags it also sets.
+	setInEnv := map[string]bool{}
+	envNames := opts.Env
+	for _, names := range opts.FlagEnv {
+		envNames = append(envNames[:len(envNames):len(envNames)], names...)
+	}
+	for _, name := range envNames {
+		if _, ok := os.LookupEnv(name); ok {
+			setInEnv[name] = true
+			c.source[name] = ConfigSourceEnv
+		}
+	}
+	for name, value := range file.Env {
+		if setInEnv[name] {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return nil, fmt.Errorf("%s: setting %s: %w", file.describe(name), name, err)
+		}
+		c.source[name] = ConfigSourceFile
+	}
+
+	onCommandLine := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { onCommandLine[f.Name] = true })
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		values, inFile := file.Flags[f.Name]
+		switch {
+		case onCommandLine[f.Name]:
+			c.source[f.Name] = ConfigSourceFlag
+		case anySet(setInEnv, opts.FlagEnv[f.Name]):
+			c.source[f.Name] = ConfigSourceEnv
+		case inFile:
+			if err := setFlag(f, values); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", file.describe(f.Name), f.Name, err))
+			}
+			c.source[f.Name] = ConfigSourceFile
+		default:
+			c.source[f.Name] = ConfigSourceDefault
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkKeys reports every key of c that is neither a flag of fs nor, in
+// the env section, one of opts.Env.
+func (c *ConfigFile) checkKeys(fs *flag.FlagSet, opts ConfigOptions) error {
+	var flags []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name != ConfigFlag && f.Name != PrintConfigFlag {
+			flags = append(flags, f.Name)
+		}
+	})
+	var errs []error
+	for _, name := range sortedKeys(c.Flags) {
+		switch {
+		case name == ConfigFlag || name == PrintConfigFlag:
+			errs = append(errs, fmt.Errorf("%s: %q cannot be set in a config file", c.describe(name), name))
+		case fs.Lookup(name) == nil:
+			errs = append(errs, fmt.Errorf("%s: unknown setting %q%s", c.describe(name), name, didYouMean(name, append(flags, configEnvKey))))
+		}
+	}
+	for _, name := range sortedKeys(c.Env) {
+		if !slices.Contains(opts.Env, name) {
+			errs = append(errs, fmt.Errorf("%s: %s is not an environment variable this program reads%s",
+				c.describe(configEnvKey), name, didYouMean(name, opts.Env)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// describe locates key in c for an error.
+func (c *ConfigFile) describe(key string) string {
+	where := c.Path
+	if where == "" {
+		where = "config file"
+	}
+	if line, ok := c.lines[key]; ok {
+		return fmt.Sprintf("%s:%d", where, line)
+	}
+	return where
+}
+
+// setFlag sets f from a config file's values: a list sets a repeatable
+// flag once per value, and a plain string flag to the values joined with
+// commas, as its comma-separated lists are written on the command line.
+func setFlag(f *flag.Flag, values []string) error {
+	if len(values) > 1 {
+		if g, ok := f.Value.(flag.Getter); ok {
+			if _, isString := g.Get().(string); isString {
+				return f.Value.Set(strings.Join(values, ","))
+			}
+			return fmt.Errorf("takes a single value, got %d", len(values))
+		}
+	}
+	for _, v := range values {
+		if err := f.Value.Set(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Source is where the flag or environment variable name got its value:
+// ConfigSourceFlag, ConfigSourceEnv, ConfigSourceFile or
+// ConfigSourceDefault.
+func (c *Config) Source(name string) string {
+	if s, ok := c.source[name]; ok {
+		return s
+	}
+	return ConfigSourceDefault
+}
+
+// Print writes the merged settings as a config file, each with where its
+// value came from. The values of ConfigOptions.Secrets are redacted.
+func (c *Config) Print(w io.Writer) {
+	fmt.Fprintln(w, "# Effective configuration: command line > environment > config file > defaults.")
+	c.fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == ConfigFlag || f.Name == PrintConfigFlag {
+			return
+		}
+		value := f.Value.String()
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			value = configValue(value)
+		}
+		if slices.Contains(c.opts.Secrets, f.Name) && f.Value.String() != "" {
+			value = "REDACTED"
+		}
+		fmt.Fprintf(w, "%s: %s # %s\n", f.Name, value, c.Source(f.Name))
+	})
+
+	var env []string
+	for _, name := range c.opts.Env {
+		if _, ok := os.LookupEnv(name); ok {
+			env = append(env, name)
+		}
+	}
+	if len(env) == 0 {
+		return
+	}
+	sort.Strings(env)
+	fmt.Fprintf(w, "%s:\n", configEnvKey)
+	for _, name := range env {
+		value := configValue(os.Getenv(name))
+		if slices.Contains(c.opts.Secrets, name) {
+			value = "REDACTED"
+		}
+		fmt.Fprintf(w, "  %s: %s # %s\n", name, value, c.Source(name))
+	}
+}
+
+// configValue quotes s where YAML would not read it back as s. Values are
+// read as text, so numbers and booleans need no quotes.
+func configValue(s string) string {
+	var n yaml.Node
+	if err := yaml.Unmarshal([]byte(s), &n); err == nil && len(n.Content) == 1 {
+		if v := n.Content[0]; v.Kind == yaml.ScalarNode && v.Style == 0 && v.Value == s {
+			return s
+		}
+	}
+	b, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf("%q", s)
+	}
+	return strings.TrimSuffix(string(b), "\n")
+}
+
+// didYouMean suggests the known name nearest to name, if any is near.
+func didYouMean(name string, known []string) string {
+	best, bestDistance := "", len(name)/3+2
+	for _, k := range known {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(k)); d < bestDistance {
+			best, bestDistance = k, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func anySet(set map[string]bool, names []string) bool {
+	for _, n := range names {
+		if set[n] {
+			return true
+		}
+	}
+	return false
+}
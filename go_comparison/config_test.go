@@ -0,0 +1,290 @@
+package scanner
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	c, err := ParseConfigFile([]byte(`
+# nightly scan
+org: acme
+checks: [secret_scanning, dependabot]
+team:
+  - platform
+  - payments
+batch-delay: 5s
+child-per-batch: true
+suppressions: ""
+env:
+  SCAN_BLOB_STORE: s3://scans/prod
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"org":             {"acme"},
+		"checks":          {"secret_scanning", "dependabot"},
+		"team":            {"platform", "payments"},
+		"batch-delay":     {"5s"},
+		"child-per-batch": {"true"},
+		"suppressions":    {""},
+	}, c.Flags)
+	require.Equal(t, map[string]string{"SCAN_BLOB_STORE": "s3://scans/prod"}, c.Env)
+
+	empty, err := ParseConfigFile(nil)
+	require.NoError(t, err)
+	require.Empty(t, empty.Flags)
+
+	json, err := ParseConfigFile([]byte(`{"org": "acme", "concurrency": 20}`))
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"org": {"acme"}, "concurrency": {"20"}}, json.Flags, "JSON is YAML")
+
+	for name, tc := range map[string]struct{ data, err string }{
+		"not a mapping":     {"- org\n- acme\n", "line 1: a config file is a mapping of settings to values"},
+		"nested value":      {"org: acme\nchecks:\n  secret: true\n", `line 3: "checks" takes a value or a list of values`},
+		"nested list entry": {"team: [[a, b]]\n", `line 1: "team" takes a value or a list of values`},
+		"repeated key":      {"org: acme\norg: other\n", `line 2: "org" is set twice`},
+		"env not a mapping": {"env: [A, B]\n", `line 1: "env" is a mapping of environment variables to values`},
+		"env nested value":  {"env:\n  SCAN_BLOB_STORE: [a]\n", "line 2: environment variable SCAN_BLOB_STORE takes a single value"},
+		"bad YAML":          {"org: [acme\n", "parsing config file"},
+	} {
+		_, err := ParseConfigFile([]byte(tc.data))
+		require.ErrorContains(t, err, tc.err, name)
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("org: acme\norg: again\n"), 0o600))
+	_, err := LoadConfigFile(path)
+	require.EqualError(t, err, path+`: line 2: "org" is set twice`)
+
+	_, err = LoadConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorContains(t, err, "reading config file")
+}
+
+// testList is a repeatable flag, like the starter's --team.
+type testList []string
+
+func (l *testList) String() string     { return strings.Join(*l, ",") }
+func (l *testList) Set(v string) error { *l = append(*l, v); return nil }
+
+type testFlags struct {
+	fs          *flag.FlagSet
+	org, checks *string
+	token       *string
+	teams       testList
+	batchDelay  *time.Duration
+	childBatch  *bool
+	concurrency *int
+}
+
+func newTestFlags(t *testing.T, args ...string) *testFlags {
+	f := &testFlags{fs: flag.NewFlagSet("test", flag.ContinueOnError)}
+	f.fs.String(ConfigFlag, "", "")
+	f.fs.Bool(PrintConfigFlag, false, "")
+	f.org = f.fs.String("org", "", "")
+	f.checks = f.fs.String("checks", "", "")
+	f.token = f.fs.String("token", "", "")
+	f.fs.Var(&f.teams, "team", "")
+	f.batchDelay = f.fs.Duration("batch-delay", 0, "")
+	f.childBatch = f.fs.Bool("child-per-batch", false, "")
+	f.concurrency = f.fs.Int("concurrency", 0, "")
+	require.NoError(t, f.fs.Parse(args))
+	return f
+}
+
+var testConfigOptions = ConfigOptions{
+	Env:     []string{"SCAN_BLOB_STORE", "GITHUB_API_URL", "JIRA_API_TOKEN"},
+	FlagEnv: map[string][]string{"token": {"GITHUB_TOKEN"}},
+	Secrets: []string{"token", "GITHUB_TOKEN", "JIRA_API_TOKEN"},
+}
+
+// unsetenv unsets name for the test, restoring it afterwards.
+func unsetenv(t *testing.T, name string) {
+	t.Setenv(name, "")
+	require.NoError(t, os.Unsetenv(name))
+}
+
+func TestApplyConfigPrecedence(t *testing.T) {
+	t.Setenv("SCAN_BLOB_STORE", "/from/env")
+	t.Setenv("GITHUB_TOKEN", "env-token")
+	unsetenv(t, "GITHUB_API_URL")
+	unsetenv(t, "JIRA_API_TOKEN")
+	file, err := ParseConfigFile([]byte(`
+org: file-org
+checks: [secret_scanning, dependabot]
+team: [platform, payments]
+batch-delay: 5s
+child-per-batch: true
+token: file-token
+env:
+  SCAN_BLOB_STORE: /from/file
+  GITHUB_API_URL: http://ghe.internal/api/v3
+`))
+	require.NoError(t, err)
+	f := newTestFlags(t, "--org", "flag-org")
+
+	c, err := ApplyConfig(f.fs, file, testConfigOptions)
+	require.NoError(t, err)
+
+	require.Equal(t, "flag-org", *f.org, "the command line beats the file")
+	require.Equal(t, "secret_scanning,dependabot", *f.checks, "a list for a string flag is comma-separated")
+	require.Equal(t, testList{"platform", "payments"}, f.teams, "a list for a repeatable flag sets it per value")
+	require.Equal(t, 5*time.Second, *f.batchDelay)
+	require.True(t, *f.childBatch)
+	require.Equal(t, 0, *f.concurrency)
+	require.Equal(t, "", *f.token, "GITHUB_TOKEN beats the file")
+	require.Equal(t, "/from/env", os.Getenv("SCAN_BLOB_STORE"), "the environment beats the file")
+	require.Equal(t, "http://ghe.internal/api/v3", os.Getenv("GITHUB_API_URL"))
+
+	for name, want := range map[string]string{
+		"org":             ConfigSourceFlag,
+		"checks":          ConfigSourceFile,
+		"token":           ConfigSourceEnv,
+		"concurrency":     ConfigSourceDefault,
+		"SCAN_BLOB_STORE": ConfigSourceEnv,
+		"GITHUB_API_URL":  ConfigSourceFile,
+		"JIRA_API_TOKEN":  ConfigSourceDefault,
+	} {
+		require.Equal(t, want, c.Source(name), name)
+	}
+
+	// Without GITHUB_TOKEN, the file's token is used.
+	unsetenv(t, "GITHUB_TOKEN")
+	f = newTestFlags(t)
+	_, err = ApplyConfig(f.fs, file, testConfigOptions)
+	require.NoError(t, err)
+	require.Equal(t, "file-token", *f.token)
+
+	// No file leaves everything as it was.
+	f = newTestFlags(t, "--concurrency", "4")
+	c, err = ApplyConfig(f.fs, nil, testConfigOptions)
+	require.NoError(t, err)
+	require.Equal(t, 4, *f.concurrency)
+	require.Equal(t, ConfigSourceDefault, c.Source("org"))
+}
+
+func TestApplyConfigRejectsUnknownAndBadSettings(t *testing.T) {
+	unsetenv(t, "GITHUB_API_URL")
+	for name, tc := range map[string]struct {
+		data string
+		errs []string
+	}{
+		"typo": {"org: acme\nchekcs: dependabot\n",
+			[]string{`config file:2: unknown setting "chekcs" (did you mean "checks"?)`}},
+		"nothing near": {"frobnicate-everything: yes\n",
+			[]string{`unknown setting "frobnicate-everything"`}},
+		"env typo": {"env:\n  GITHUB_API_UR: http://x\n",
+			[]string{`GITHUB_API_UR is not an environment variable this program reads (did you mean "GITHUB_API_URL"?)`}},
+		"config itself": {"config: other.yaml\n",
+			[]string{`"config" cannot be set in a config file`}},
+		"every error at once": {"chekcs: a\nbatch-dely: 5s\n",
+			[]string{`"chekcs"`, `"batch-dely" (did you mean "batch-delay"?)`}},
+		"bad value": {"org: acme\nconcurrency: many\n",
+			[]string{`config file:2: concurrency: parse error`}},
+		"list for a single value": {"concurrency: [1, 2]\n",
+			[]string{"concurrency: takes a single value, got 2"}},
+	} {
+		file, err := ParseConfigFile([]byte(tc.data))
+		require.NoError(t, err, name)
+		_, err = ApplyConfig(newTestFlags(t).fs, file, testConfigOptions)
+		require.Error(t, err, name)
+		for _, want := range tc.errs {
+			require.Contains(t, err.Error(), want, name)
+		}
+	}
+	require.Empty(t, os.Getenv("GITHUB_API_URL"), "a rejected file sets nothing")
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("org: acme\nconcurency: 4\n"), 0o600))
+	_, err := LoadConfig(newTestFlags(t).fs, path, testConfigOptions)
+	require.EqualError(t, err, path+`:2: unknown setting "concurency" (did you mean "concurrency"?)`)
+
+	require.NoError(t, os.WriteFile(path, []byte("org: acme\nconcurrency: 4\n"), 0o600))
+	f := newTestFlags(t, "--config", path)
+	c, err := LoadConfig(f.fs, path, testConfigOptions)
+	require.NoError(t, err)
+	require.Equal(t, "acme", *f.org)
+	require.Equal(t, 4, *f.concurrency)
+	require.Equal(t, ConfigSourceFlag, c.Source(ConfigFlag))
+
+	f = newTestFlags(t)
+	c, err = LoadConfig(f.fs, "", testConfigOptions)
+	require.NoError(t, err, "no path, no file")
+	require.Equal(t, ConfigSourceDefault, c.Source("org"))
+}
+
+func TestConfigPrint(t *testing.T) {
+	unsetenv(t, "GITHUB_TOKEN")
+	unsetenv(t, "SCAN_BLOB_STORE")
+	unsetenv(t, "GITHUB_API_URL")
+	t.Setenv("JIRA_API_TOKEN", "jira-secret")
+	file, err := ParseConfigFile([]byte(`
+checks: [secret_scanning, dependabot]
+token: ghp_filetoken
+team: [platform]
+env:
+  GITHUB_API_URL: "http://ghe.internal/api/v3"
+`))
+	require.NoError(t, err)
+	f := newTestFlags(t, "--org", "acme", "--child-per-batch")
+	c, err := ApplyConfig(f.fs, file, testConfigOptions)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	c.Print(&buf)
+	out := buf.String()
+	for _, line := range []string{
+		"org: acme # flag",
+		"checks: secret_scanning,dependabot # file",
+		"token: REDACTED # file",
+		"team: platform # file",
+		"child-per-batch: true # flag",
+		"batch-delay: 0s # default",
+		"env:",
+		"  GITHUB_API_URL: http://ghe.internal/api/v3 # file",
+		"  JIRA_API_TOKEN: REDACTED # env",
+	} {
+		require.Contains(t, out, line+"\n")
+	}
+	require.NotContains(t, out, "ghp_filetoken")
+	require.NotContains(t, out, "jira-secret")
+	require.NotContains(t, out, "SCAN_BLOB_STORE", "unset variables are left out")
+	require.NotContains(t, out, "print-config")
+
+	// The output is a config file itself.
+	printed, err := ParseConfigFile(buf.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, []string{"acme"}, printed.Flags["org"])
+	require.Equal(t, []string{"secret_scanning,dependabot"}, printed.Flags["checks"])
+	require.Equal(t, []string{"REDACTED"}, printed.Flags["token"])
+}
+
+func TestConfigValue(t *testing.T) {
+	for in, want := range map[string]string{
+		"acme":              "acme",
+		"0.2":               "0.2",
+		"true":              "true",
+		"":                  `""`,
+		"a: b":              `'a: b'`,
+		"x # not a comment": `'x # not a comment'`,
+		"[a]":               `'[a]'`,
+		"  padded":          `'  padded'`,
+	} {
+		got := configValue(in)
+		require.Equal(t, want, got, in)
+		var back string
+		require.NoError(t, yaml.Unmarshal([]byte(got), &back))
+		require.Equal(t, in, back, in)
+	}
+}
@@ -0,0 +1,214 @@
+package scanner
+
+// =============================================================================
+// Credentials — what a scan authenticates with, and how
+// =============================================================================
+//
+// ScanInput.Token is a bare string: it does not say what kind of token it
+// is, and it is recorded in the workflow's history. ScanInput.Credential
+// says both what it is and where it is:
+//
+//	pat           classic personal access token       Authorization: token …
+//	fine-grained  fine-grained personal access token  Authorization: token …
+//	app           GitHub App installation token       Authorization: Bearer …
+//	none          unauthenticated, even where the worker has a token
+//
+// The secret is inline in Value, recorded in history like Token, or named
+// by Reference, which the worker looks up in Activities.Credentials so
+// that it never reaches history. With neither, the worker's own token
+// (Activities.Secrets) is used, as for a scan without a token. GitLab
+// scans take pat credentials only, sent as PRIVATE-TOKEN.
+//
+// Token keeps working: it is a pat credential whose Value is the token.
+// Activities take a *Credential where they took the token, and a
+// credential decodes from a bare JSON string as that pat, so activities
+// and batch children recorded with a token retry and replay unchanged.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// CredentialType is the kind of token a Credential holds.
+type CredentialType string
+
+// Credential types.
+const (
+	CredentialPAT         CredentialType = "pat"
+	CredentialFineGrained CredentialType = "fine-grained"
+	CredentialApp         CredentialType = "app"
+	CredentialNone        CredentialType = "none"
+)
+
+// ErrTypeUnknownCredential marks a Credential.Reference the worker cannot
+// resolve. It is not retryable: the worker's configuration has to change.
+const ErrTypeUnknownCredential = "UNKNOWN_CREDENTIAL"
+
+// DefaultCredentialEnvPrefix is where EnvCredentialSource looks by default.
+const DefaultCredentialEnvPrefix = "SCANNER_CREDENTIAL_"
+
+// credentialReference is what a Reference may look like.
+var credentialReference = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Credential is how a scan authenticates to its provider.
+type Credential struct {
+	Type CredentialType `json:"type"`
+	// Reference names a secret the worker looks up (see
+	// Activities.Credentials).
+	Reference string `json:"reference,omitempty"`
+	// Value is the token itself, recorded in the workflow's history. Kept
+	// for callers that have always sent one.
+	Value string `json:"value,omitempty"`
+}
+
+// PATCredential is the credential of a ScanInput.Token, or nil without one.
+func PATCredential(token *string) *Credential {
+	if token == nil {
+		return nil
+	}
+	return &Credential{Type: CredentialPAT, Value: *token}
+}
+
+// UnmarshalJSON also accepts a bare string, the token of a pat credential,
+// which is how activity inputs recorded before Credential carried it.
+func (c *Credential) UnmarshalJSON(b []byte) error {
+	var token string
+	if json.Unmarshal(b, &token) == nil {
+		*c = Credential{Type: CredentialPAT, Value: token}
+		return nil
+	}
+	type plain Credential
+	return json.Unmarshal(b, (*plain)(c))
+}
+
+// credential is the credential in.Credential or in.Token describes, or nil
+// when the scan brought neither.
+func (in ScanInput) credential() *Credential {
+	if in.Credential != nil {
+		return in.Credential
+	}
+	return PATCredential(in.Token)
+}
+
+// validateCredential checks in.Credential against in.Token and the
+// provider.
+func (in ScanInput) validateCredential() error {
+	c := in.Credential
+	if c == nil {
+		return nil
+	}
+	if in.Token != nil {
+		return errors.New("set token or credential, not both")
+	}
+	switch c.Type {
+	case CredentialPAT, CredentialFineGrained, CredentialApp:
+	case CredentialNone:
+		if c.Value != "" || c.Reference != "" {
+			return errors.New("a none credential has no value or reference")
+		}
+		return nil
+	case "":
+		return errors.New("credential type must be set: want pat, fine-grained, app or none")
+	default:
+		return fmt.Errorf("unknown credential type %q: want pat, fine-grained, app or none", c.Type)
+	}
+	if c.Value != "" && c.Reference != "" {
+		return errors.New("set the credential's value or reference, not both")
+	}
+	if c.Reference != "" && !credentialReference.MatchString(c.Reference) {
+		return fmt.Errorf("invalid credential reference %q: want letters, digits, '_', '.' and '-'", c.Reference)
+	}
+	if p := providerName(in.Provider); p != ProviderGitHub && c.Type != CredentialPAT {
+		return fmt.Errorf("%s credentials are GitHub's; %s takes pat", c.Type, p)
+	}
+	return nil
+}
+
+// kind is c's Type; pat for a credential recorded without one.
+func (c *Credential) kind() CredentialType {
+	if c == nil || c.Type == "" {
+		return CredentialPAT
+	}
+	return c.Type
+}
+
+// redacted is c without its Value, or nil.
+func (c *Credential) redacted() *Credential {
+	if c == nil {
+		return nil
+	}
+	r := *c
+	r.Value = ""
+	return &r
+}
+
+// Authorization is the Authorization header of a resolved c, or "" for
+// none.
+func (c *Credential) Authorization() string {
+	if c == nil || c.Value == "" {
+		return ""
+	}
+	if c.kind() == CredentialApp {
+		return "Bearer " + c.Value
+	}
+	return "token " + c.Value
+}
+
+// CredentialSource resolves a Credential's Reference to its secret on the
+// worker. An empty secret means the reference is unknown.
+type CredentialSource interface {
+	GetCredential(ctx context.Context, reference string) (string, error)
+}
+
+// EnvCredentialSource reads each reference from an environment variable:
+// Prefix followed by the reference in upper case, with '.' and '-' as '_'.
+type EnvCredentialSource struct {
+	// Prefix defaults to DefaultCredentialEnvPrefix.
+	Prefix string
+}
+
+func (s EnvCredentialSource) GetCredential(_ context.Context, reference string) (string, error) {
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = DefaultCredentialEnvPrefix
+	}
+	name := prefix + strings.NewReplacer(".", "_", "-", "_").Replace(strings.ToUpper(reference))
+	return os.Getenv(name), nil
+}
+
+// resolveCredential returns c with its Value looked up when it has a
+// Reference, or nil when c authenticates nothing by itself: nil, none, or
+// a type with neither value nor reference.
+func (a *Activities) resolveCredential(ctx context.Context, c *Credential) (*Credential, error) {
+	if c == nil || c.kind() == CredentialNone {
+		return nil, nil
+	}
+	if c.Reference == "" {
+		if c.Value == "" {
+			return nil, nil
+		}
+		return c, nil
+	}
+	if a.Credentials == nil {
+		return nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("credential reference %q: the worker has no credential source", c.Reference), ErrTypeUnknownCredential, nil)
+	}
+	secret, err := a.Credentials.GetCredential(ctx, c.Reference)
+	if err != nil {
+		return nil, temporal.NewApplicationErrorWithCause(
+			fmt.Sprintf("looking up credential %q: %v", c.Reference, err), ErrTypeSecretSource, err)
+	}
+	if secret == "" {
+		return nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("credential reference %q is not known to the worker", c.Reference), ErrTypeUnknownCredential, nil)
+	}
+	return &Credential{Type: c.kind(), Reference: c.Reference, Value: secret}, nil
+}
@@ -0,0 +1,172 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestScanInputTokenRoundTrip(t *testing.T) {
+	var in ScanInput
+	require.NoError(t, json.Unmarshal([]byte(`{"org":"acme","token":"ghp_old"}`), &in))
+	require.Equal(t, "ghp_old", *in.Token)
+	require.Nil(t, in.Credential)
+	require.Equal(t, &Credential{Type: CredentialPAT, Value: "ghp_old"}, in.credential())
+
+	b, err := json.Marshal(in)
+	require.NoError(t, err)
+	var again ScanInput
+	require.NoError(t, json.Unmarshal(b, &again))
+	require.Equal(t, in, again)
+	require.NotContains(t, string(b), `"credential"`)
+}
+
+func TestScanInputCredentialRoundTrip(t *testing.T) {
+	var in ScanInput
+	require.NoError(t, json.Unmarshal([]byte(`{"org":"acme","credential":{"type":"app","reference":"acme-app"}}`), &in))
+	require.Nil(t, in.Token)
+	require.Equal(t, &Credential{Type: CredentialApp, Reference: "acme-app"}, in.Credential)
+	require.Equal(t, in.Credential, in.credential())
+
+	b, err := json.Marshal(in)
+	require.NoError(t, err)
+	var again ScanInput
+	require.NoError(t, json.Unmarshal(b, &again))
+	require.Equal(t, in, again)
+	require.NotContains(t, string(b), `"token"`)
+}
+
+func TestCredentialDecodesRecordedToken(t *testing.T) {
+	// A batch child recorded when its token was a bare string.
+	var in ScanBatchInput
+	require.NoError(t, json.Unmarshal([]byte(`{"org":"acme","token":"ghp_recorded"}`), &in))
+	require.Equal(t, PATCredential(stringPtr("ghp_recorded")), in.Credential)
+
+	var rc RepoCheckInput
+	require.NoError(t, json.Unmarshal([]byte(`{"org":"acme","repo":"api","token":{"type":"fine-grained","value":"github_pat_x"}}`), &rc))
+	require.Equal(t, &Credential{Type: CredentialFineGrained, Value: "github_pat_x"}, rc.Credential)
+}
+
+func stringPtr(s string) *string { return &s }
+
+func TestValidateCredential(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in      ScanInput
+		wantErr string
+	}{
+		"token only":      {in: ScanInput{Org: "acme", Token: stringPtr("ghp_x")}},
+		"pat value":       {in: ScanInput{Org: "acme", Credential: &Credential{Type: CredentialPAT, Value: "ghp_x"}}},
+		"app reference":   {in: ScanInput{Org: "acme", Credential: &Credential{Type: CredentialApp, Reference: "acme.app-1"}}},
+		"worker's token":  {in: ScanInput{Org: "acme", Credential: &Credential{Type: CredentialFineGrained}}},
+		"none":            {in: ScanInput{Org: "acme", Credential: &Credential{Type: CredentialNone}}},
+		"gitlab pat":      {in: ScanInput{Org: "acme", Provider: ProviderGitLab, Credential: &Credential{Type: CredentialPAT, Value: "glpat-x"}}},
+		"both":            {ScanInput{Org: "acme", Token: stringPtr("ghp_x"), Credential: &Credential{Type: CredentialPAT}}, "not both"},
+		"no type":         {ScanInput{Org: "acme", Credential: &Credential{Value: "ghp_x"}}, "type must be set"},
+		"unknown type":    {ScanInput{Org: "acme", Credential: &Credential{Type: "oauth", Value: "x"}}, `unknown credential type "oauth"`},
+		"value and ref":   {ScanInput{Org: "acme", Credential: &Credential{Type: CredentialPAT, Value: "x", Reference: "y"}}, "value or reference"},
+		"none with value": {ScanInput{Org: "acme", Credential: &Credential{Type: CredentialNone, Value: "x"}}, "no value"},
+		"bad reference":   {ScanInput{Org: "acme", Credential: &Credential{Type: CredentialPAT, Reference: "../etc"}}, "invalid credential reference"},
+		"gitlab app":      {ScanInput{Org: "acme", Provider: ProviderGitLab, Credential: &Credential{Type: CredentialApp, Value: "x"}}, "gitlab takes pat"},
+	} {
+		err := tc.in.Validate()
+		if tc.wantErr == "" {
+			require.NoError(t, err, name)
+			continue
+		}
+		require.ErrorContains(t, err, "credential: ", name)
+		require.ErrorContains(t, err, tc.wantErr, name)
+	}
+}
+
+func TestCredentialAuthorization(t *testing.T) {
+	require.Equal(t, "token ghp_x", PATCredential(stringPtr("ghp_x")).Authorization())
+	require.Equal(t, "token github_pat_x", (&Credential{Type: CredentialFineGrained, Value: "github_pat_x"}).Authorization())
+	require.Equal(t, "Bearer ghs_x", (&Credential{Type: CredentialApp, Value: "ghs_x"}).Authorization())
+	require.Equal(t, "token ghp_x", (&Credential{Value: "ghp_x"}).Authorization(), "recorded without a type")
+	require.Empty(t, (&Credential{Type: CredentialApp, Reference: "unresolved"}).Authorization())
+	require.Empty(t, (*Credential)(nil).Authorization())
+}
+
+func TestEnvCredentialSource(t *testing.T) {
+	t.Setenv("SCANNER_CREDENTIAL_ACME_APP_1", "ghs_env")
+	got, err := EnvCredentialSource{}.GetCredential(context.Background(), "acme.app-1")
+	require.NoError(t, err)
+	require.Equal(t, "ghs_env", got)
+
+	t.Setenv("CREDS_ACME", "ghp_prefixed")
+	got, err = EnvCredentialSource{Prefix: "CREDS_"}.GetCredential(context.Background(), "acme")
+	require.NoError(t, err)
+	require.Equal(t, "ghp_prefixed", got)
+}
+
+// credentialFunc is a CredentialSource backed by a function.
+type credentialFunc func(string) (string, error)
+
+func (f credentialFunc) GetCredential(_ context.Context, reference string) (string, error) {
+	return f(reference)
+}
+
+func TestGitHubTokenResolvesCredential(t *testing.T) {
+	t.Setenv("CREDENTIAL_TEST_TOKEN", "ghp_worker")
+	a := &Activities{
+		Secrets: EnvSecretSource{Var: "CREDENTIAL_TEST_TOKEN"},
+		Credentials: credentialFunc(func(ref string) (string, error) {
+			switch ref {
+			case "acme-app":
+				return "ghs_installation", nil
+			case "broken":
+				return "", errors.New("vault sealed")
+			}
+			return "", nil
+		}),
+	}
+	ctx := context.Background()
+	headers := func(cred *Credential) string {
+		h, err := a.githubHeaders(ctx, cred)
+		require.NoError(t, err)
+		return h["Authorization"]
+	}
+
+	require.Equal(t, "Bearer ghs_installation", headers(&Credential{Type: CredentialApp, Reference: "acme-app"}))
+	require.Equal(t, "token ghp_inline", headers(PATCredential(stringPtr("ghp_inline"))))
+	require.Equal(t, "token ghp_worker", headers(nil), "no credential: the worker's token")
+	require.Equal(t, "token ghp_worker", headers(&Credential{Type: CredentialFineGrained}), "a type alone: the worker's token")
+	require.Empty(t, headers(&Credential{Type: CredentialNone}), "none skips the worker's token")
+
+	_, err := a.githubHeaders(ctx, &Credential{Type: CredentialPAT, Reference: "missing"})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, ErrTypeUnknownCredential, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+
+	_, err = a.githubHeaders(ctx, &Credential{Type: CredentialPAT, Reference: "broken"})
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, ErrTypeSecretSource, appErr.Type())
+	require.False(t, appErr.NonRetryable(), "the source may come back")
+
+	_, err = (&Activities{}).githubHeaders(ctx, &Credential{Type: CredentialApp, Reference: "acme-app"})
+	require.ErrorContains(t, err, "no credential source")
+}
+
+func TestValidateTokenReportsCredentialType(t *testing.T) {
+	f, a := newFakeGitHub(t, map[string]fakeResponse{
+		"/orgs/acme-corp": {http.StatusOK, "org.json"},
+	})
+	f.Header = http.Header{"X-Oauth-Scopes": {"repo"}}
+	cred := &Credential{Type: CredentialApp, Value: "ghs_installation"}
+	val, err := newActivityEnv(a).ExecuteActivity(a.ValidateToken, "acme-corp", cred, []string{CheckSecretScanning})
+	require.NoError(t, err)
+	var caps TokenCapabilities
+	require.NoError(t, val.Get(&caps))
+	require.Equal(t, CredentialApp, caps.CredentialType)
+	require.Equal(t, "Bearer ghs_installation", f.requests[0].Header.Get("Authorization"))
+
+	caps2, err := validateToken(t, a, []string{CheckSecretScanning})
+	require.NoError(t, err)
+	require.Equal(t, CredentialPAT, caps2.CredentialType, "a plain token is a pat")
+}
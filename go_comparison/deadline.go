@@ -0,0 +1,73 @@
+package scanner
+
+// =============================================================================
+// Time-boxed scans — stopping at a deadline with a partial report
+// =============================================================================
+//
+// Heavy API traffic may only be allowed in a change window. ScanInput's
+// Deadline and MaxDurationSeconds end the scan gracefully when the window
+// closes, unlike a WorkflowExecutionTimeout, which kills it without a
+// report. When the deadline passes, no further batch is started; the batch
+// in flight gets deadlineGrace to finish, after which its remaining repos
+// are cancelled. The report is flagged deadline_reached and lists the
+// repos it did not scan in unscanned_repos, which a later scan can resume
+// (see resume.go).
+//
+// The deadline is a workflow timer, so replays stop at the same batch.
+// Scans without a deadline start no timer.
+// =============================================================================
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// deadlineGrace is how long the batch in flight at the deadline may keep
+// running. Batches with ActivityBatching are not cut short; their
+// activities are bounded by their own timeouts.
+const deadlineGrace = time.Minute
+
+// deadline is when a scan started at start must stop: the earlier of
+// Deadline and start plus MaxDurationSeconds. ok is false without either.
+func (in ScanInput) deadline(start time.Time) (d time.Time, ok bool) {
+	if in.Deadline != nil {
+		d, ok = *in.Deadline, true
+	}
+	if in.MaxDurationSeconds > 0 {
+		if end := start.Add(time.Duration(in.MaxDurationSeconds) * time.Second); !ok || end.Before(d) {
+			d, ok = end, true
+		}
+	}
+	return d, ok
+}
+
+// deadlineTimer tracks a scan's deadline. reached is set when it passes,
+// expired when the grace of the batch in flight has run out as well.
+type deadlineTimer struct {
+	reached, expired bool
+}
+
+// startDeadline starts the timers for deadline and returns the tracker and
+// a function that stops them. A deadline already past is reached at once.
+// onExpired is called when the grace runs out, to stop a batch that does
+// not watch expired.
+func startDeadline(ctx workflow.Context, deadline time.Time, onExpired func(workflow.Context)) (*deadlineTimer, workflow.CancelFunc) {
+	t := &deadlineTimer{}
+	timerCtx, stop := workflow.WithCancel(ctx)
+	workflow.Go(timerCtx, func(gCtx workflow.Context) {
+		if wait := deadline.Sub(workflow.Now(gCtx)); wait > 0 {
+			if err := workflow.NewTimer(gCtx, wait).Get(gCtx, nil); err != nil {
+				return // stopped
+			}
+		}
+		t.reached = true
+		workflow.GetLogger(gCtx).Info("Deadline reached", "deadline", deadline)
+		if err := workflow.NewTimer(gCtx, deadlineGrace).Get(gCtx, nil); err != nil {
+			return
+		}
+		t.expired = true
+		onExpired(gCtx)
+	})
+	return t, stop
+}
@@ -0,0 +1,125 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestScanInputDeadline(t *testing.T) {
+	start := time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC)
+	at := func(h, m int) *time.Time {
+		t := time.Date(2026, 3, 2, h, m, 0, 0, time.UTC)
+		return &t
+	}
+	for name, tc := range map[string]struct {
+		in   ScanInput
+		want *time.Time
+	}{
+		"none":                  {ScanInput{}, nil},
+		"deadline":              {ScanInput{Deadline: at(4, 0)}, at(4, 0)},
+		"max duration":          {ScanInput{MaxDurationSeconds: 5400}, at(3, 30)},
+		"duration earlier":      {ScanInput{Deadline: at(4, 0), MaxDurationSeconds: 5400}, at(3, 30)},
+		"deadline earlier":      {ScanInput{Deadline: at(3, 0), MaxDurationSeconds: 5400}, at(3, 0)},
+		"deadline already past": {ScanInput{Deadline: at(1, 0)}, at(1, 0)},
+	} {
+		d, ok := tc.in.deadline(start)
+		if tc.want == nil {
+			require.False(t, ok, name)
+			continue
+		}
+		require.True(t, ok, name)
+		require.Equal(t, *tc.want, d, name)
+	}
+}
+
+func TestWorkflowStopsAtDeadline(t *testing.T) {
+	env := newTestEnv(t)
+	env.SetStartTime(time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC))
+	onListOrgRepos(env, fakeRepos(25))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(30 * time.Second).Return(compliantUnless())
+
+	// The deadline passes while the second batch runs; it finishes within
+	// its grace and the third is never started.
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", MaxDurationSeconds: 45})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 20)
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, true, report["deadline_reached"])
+	require.Equal(t, "2026-03-02T02:00:45Z", report["deadline"])
+	require.EqualValues(t, 20, report["total_repos"])
+	require.Equal(t, []interface{}{"repo-020", "repo-021", "repo-022", "repo-023", "repo-024"}, report["unscanned_repos"])
+	require.NotContains(t, report, "cancelled")
+
+	val, err := env.QueryWorkflow("progress")
+	require.NoError(t, err)
+	var progress ScanProgress
+	require.NoError(t, val.Get(&progress))
+	require.Equal(t, ScanDeadlineReached, progress.Status)
+}
+
+func TestWorkflowDeadlineCutsInFlightBatchShort(t *testing.T) {
+	env := newTestEnv(t)
+	env.SetStartTime(time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC))
+	onListOrgRepos(env, fakeRepos(12))
+	for _, repo := range []string{"repo-000", "repo-001", "repo-002"} {
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, repo, mock.Anything, mock.Anything).
+			Return(compliantUnless())
+	}
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(time.Hour).Return(compliantUnless())
+
+	deadline := time.Date(2026, 3, 2, 2, 0, 30, 0, time.UTC)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Deadline: &deadline})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, true, report["deadline_reached"])
+	require.EqualValues(t, 3, report["total_repos"])
+	require.Len(t, report["unscanned_repos"], 9, "the repos cut short and the batch never started")
+	require.Equal(t, "2026-03-02T02:01:30Z", report["completed_at"], "the in-flight batch got its grace, not the hour")
+}
+
+func TestWorkflowResumesUnscannedRepos(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(10))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org:        "acme",
+		ResumeFrom: &ResumeState{UnscannedRepos: []string{"repo-007", "repo-003", "repo-deleted"}},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 2)
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 2, report["total_repos"])
+	require.NotContains(t, report, "deadline_reached")
+}
+
+func TestWorkflowRejectsBadDeadlineInput(t *testing.T) {
+	for name, in := range map[string]ScanInput{
+		"negative duration": {Org: "acme", MaxDurationSeconds: -1},
+		"empty resume":      {Org: "acme", ResumeFrom: &ResumeState{}},
+	} {
+		env := newTestEnv(t)
+		env.ExecuteWorkflow(SecurityScanWorkflow, in)
+		var appErr *temporal.ApplicationError
+		require.True(t, errors.As(env.GetWorkflowError(), &appErr), name)
+		require.Equal(t, ErrTypeInvalidInput, appErr.Type(), name)
+	}
+}
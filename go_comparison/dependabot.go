@@ -0,0 +1,197 @@
+package scanner
+
+// =============================================================================
+// Dependabot updates — are vulnerable dependencies actually fixed?
+// =============================================================================
+//
+// CheckDependabot only says alerts are raised. Two more checks say whether
+// anything acts on them:
+//
+//   - CheckSecurityUpdates reads GET /repos/{org}/{repo}/automated-security-fixes,
+//     i.e. whether Dependabot opens pull requests for vulnerable dependencies.
+//     Paused security updates count as disabled.
+//   - CheckDependabotConfig reads .github/dependabot.yml (or .yaml) through
+//     the contents API and parses it: version 2, and at least one update
+//     entry, each naming a package ecosystem and a schedule interval. A file
+//     that does not parse or fails those rules is StatusMisconfigured, with
+//     the reason in the result's message; the repo is still scanned.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dependabotConfigPaths are where Dependabot looks for its configuration.
+var dependabotConfigPaths = []string{".github/dependabot.yml", ".github/dependabot.yaml"}
+
+// dependabotIntervals are the schedule intervals Dependabot accepts.
+var dependabotIntervals = map[string]bool{
+	"daily": true, "weekly": true, "monthly": true, "quarterly": true,
+	"semiannually": true, "yearly": true, "cron": true,
+}
+
+// DependabotConfig is what the dependabot_config check read from a repo's
+// Dependabot configuration.
+type DependabotConfig struct {
+	Path    string             `json:"path"`
+	Updates []DependabotUpdate `json:"updates,omitempty"`
+	// Problem says why the file is misconfigured.
+	Problem string `json:"problem,omitempty"`
+}
+
+// DependabotUpdate is one entry of a dependabot.yml's updates.
+type DependabotUpdate struct {
+	Ecosystem string `json:"ecosystem"`
+	Directory string `json:"directory,omitempty"`
+	Interval  string `json:"interval"`
+}
+
+// parseDependabotConfig parses the dependabot.yml at path. Problems are
+// reported in the returned config's Problem rather than as an error.
+func parseDependabotConfig(path string, data []byte) *DependabotConfig {
+	config := &DependabotConfig{Path: path}
+	var file struct {
+		Version int `yaml:"version"`
+		Updates []struct {
+			PackageEcosystem string   `yaml:"package-ecosystem"`
+			Directory        string   `yaml:"directory"`
+			Directories      []string `yaml:"directories"`
+			Schedule         struct {
+				Interval string `yaml:"interval"`
+			} `yaml:"schedule"`
+		} `yaml:"updates"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		config.Problem = "invalid YAML: " + err.Error()
+		return config
+	}
+	if file.Version != 2 {
+		config.Problem = fmt.Sprintf("version is %d, want 2", file.Version)
+		return config
+	}
+	if len(file.Updates) == 0 {
+		config.Problem = "no update ecosystems"
+		return config
+	}
+	for i, u := range file.Updates {
+		switch {
+		case u.PackageEcosystem == "":
+			config.Problem = fmt.Sprintf("update %d has no package-ecosystem", i+1)
+		case !dependabotIntervals[u.Schedule.Interval]:
+			config.Problem = fmt.Sprintf("update %d (%s) has schedule interval %q", i+1, u.PackageEcosystem, u.Schedule.Interval)
+		}
+		if config.Problem != "" {
+			config.Updates = nil
+			return config
+		}
+		dir := u.Directory
+		if dir == "" {
+			dir = strings.Join(u.Directories, ",")
+		}
+		config.Updates = append(config.Updates, DependabotUpdate{
+			Ecosystem: u.PackageEcosystem, Directory: dir, Interval: u.Schedule.Interval,
+		})
+	}
+	return config
+}
+
+// result is the dependabot_config CheckResult for c.
+func (c *DependabotConfig) result() CheckResult {
+	if c.Problem != "" {
+		return CheckResult{Status: StatusMisconfigured, Message: c.Path + ": " + c.Problem}
+	}
+	return CheckResult{Status: StatusEnabled, Details: map[string]int{"updates": len(c.Updates)}}
+}
+
+// checkSecurityUpdates records the security_updates result. The endpoint
+// answers 200 with enabled and paused flags; older API versions answer
+// 204 when enabled, and a 404 means the repo has them off.
+func (a *Activities) checkSecurityUpdates(ctx context.Context, org, repoName string, headers map[string]string, result *RepoSecurityResult) error {
+	ctx = evidenceFor(ctx, CheckSecurityUpdates)
+	status, body, err := a.checkEndpoint(ctx, a.apiURL("/repos/%s/%s/automated-security-fixes", org, repoName), headers)
+	if err != nil {
+		return err
+	}
+	c := CheckResult{Status: StatusUnknown}
+	switch status {
+	case http.StatusOK:
+		var fixes struct {
+			Enabled bool `json:"enabled"`
+			Paused  bool `json:"paused"`
+		}
+		if err := json.Unmarshal(body, &fixes); err != nil {
+			return parseError("automated security fixes for "+repoName, err)
+		}
+		switch {
+		case fixes.Enabled && fixes.Paused:
+			c = CheckResult{Status: StatusDisabled, Message: "paused"}
+		case fixes.Enabled:
+			c.Status = StatusEnabled
+		default:
+			c.Status = StatusDisabled
+		}
+	case http.StatusNoContent:
+		c.Status = StatusEnabled
+	case http.StatusNotFound:
+		c.Status = StatusDisabled
+	case http.StatusForbidden:
+		c.Status = StatusNoAccess
+	}
+	result.setCheck(CheckSecurityUpdates, c)
+	return nil
+}
+
+// checkDependabotConfig records the dependabot_config result: enabled for
+// a valid configuration, StatusMisconfigured for one that is not, and
+// StatusNotConfigured when there is none.
+func (a *Activities) checkDependabotConfig(ctx context.Context, org, repoName string, headers map[string]string, result *RepoSecurityResult) error {
+	ctx = evidenceFor(ctx, CheckDependabotConfig)
+	unknown := false
+	for _, path := range dependabotConfigPaths {
+		status, body, err := a.checkEndpoint(ctx, a.apiURL("/repos/%s/%s/contents/%s", org, repoName, path), headers)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case http.StatusOK:
+			var file struct {
+				Type     string `json:"type"`
+				Encoding string `json:"encoding"`
+				Content  string `json:"content"`
+			}
+			if err := json.Unmarshal(body, &file); err != nil {
+				return parseError("contents of "+repoName+"/"+path, err)
+			}
+			if file.Type != "file" {
+				continue
+			}
+			if file.Encoding != "base64" {
+				return parseError("contents of "+repoName+"/"+path, fmt.Errorf("unexpected encoding %q", file.Encoding))
+			}
+			data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+			if err != nil {
+				return parseError("contents of "+repoName+"/"+path, err)
+			}
+			config := parseDependabotConfig(path, data)
+			result.DependabotConfig = config
+			result.setCheck(CheckDependabotConfig, config.result())
+			return nil
+		case http.StatusNotFound:
+		default:
+			unknown = true
+		}
+	}
+	if unknown {
+		result.setCheck(CheckDependabotConfig, CheckResult{Status: StatusNoAccess, Message: "cannot read " + dependabotConfigPaths[0]})
+		return nil
+	}
+	result.setCheck(CheckDependabotConfig, CheckResult{Status: StatusNotConfigured})
+	return nil
+}
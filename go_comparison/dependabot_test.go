@@ -0,0 +1,168 @@
+package scanner
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDependabotConfig(t *testing.T) {
+	config := parseDependabotConfig(".github/dependabot.yml", []byte(`
+version: 2
+updates:
+  - package-ecosystem: gomod
+    directory: /
+    schedule:
+      interval: weekly
+  - package-ecosystem: docker
+    directories: [/api, /worker]
+    schedule:
+      interval: cron
+      cronjob: "0 6 * * 1"
+`))
+	require.Empty(t, config.Problem)
+	require.Equal(t, []DependabotUpdate{
+		{Ecosystem: "gomod", Directory: "/", Interval: "weekly"},
+		{Ecosystem: "docker", Directory: "/api,/worker", Interval: "cron"},
+	}, config.Updates)
+	require.Equal(t, CheckResult{Status: StatusEnabled, Details: map[string]int{"updates": 2}}, config.result())
+
+	for want, yml := range map[string]string{
+		"invalid YAML":                                  "version: 2\nupdates: [",
+		"version is 1, want 2":                          "version: 1\nupdates: []",
+		"no update ecosystems":                          "version: 2\n",
+		"update 1 has no package-ecosystem":             "version: 2\nupdates:\n  - directory: /\n    schedule: {interval: daily}",
+		`update 2 (npm) has schedule interval ""`:       "version: 2\nupdates:\n  - {package-ecosystem: pip, schedule: {interval: daily}}\n  - {package-ecosystem: npm}",
+		`update 1 (pip) has schedule interval "hourly"`: "version: 2\nupdates:\n  - {package-ecosystem: pip, schedule: {interval: hourly}}",
+	} {
+		config := parseDependabotConfig(".github/dependabot.yml", []byte(yml))
+		require.Contains(t, config.Problem, want)
+		require.Empty(t, config.Updates, want)
+		c := config.result()
+		require.Equal(t, StatusMisconfigured, c.Status)
+		require.Contains(t, c.Message, ".github/dependabot.yml: "+want)
+	}
+}
+
+func TestCheckRepoSecurityDependabotUpdates(t *testing.T) {
+	const repoPath = "/repos/acme-corp/payments-api"
+	notFound := fakeResponse{http.StatusNotFound, "not_found.json"}
+	forbidden := fakeResponse{http.StatusForbidden, "contents_forbidden.json"}
+
+	tests := []struct {
+		name        string
+		routes      map[string]fakeResponse
+		wantUpdates SecurityStatus
+		wantConfig  SecurityStatus
+		wantPath    string
+	}{
+		{
+			name: "enabled with a valid dependabot.yml",
+			routes: map[string]fakeResponse{
+				repoPath + "/automated-security-fixes":        {http.StatusOK, "automated_security_fixes_enabled.json"},
+				repoPath + "/contents/.github/dependabot.yml": {http.StatusOK, "contents_dependabot_yml.json"},
+			},
+			wantUpdates: StatusEnabled,
+			wantConfig:  StatusEnabled,
+			wantPath:    ".github/dependabot.yml",
+		},
+		{
+			name: "paused, with dependabot.yaml",
+			routes: map[string]fakeResponse{
+				repoPath + "/automated-security-fixes":         {http.StatusOK, "automated_security_fixes_paused.json"},
+				repoPath + "/contents/.github/dependabot.yml":  notFound,
+				repoPath + "/contents/.github/dependabot.yaml": {http.StatusOK, "contents_dependabot_yml.json"},
+			},
+			wantUpdates: StatusDisabled,
+			wantConfig:  StatusEnabled,
+			wantPath:    ".github/dependabot.yaml",
+		},
+		{
+			name: "malformed YAML is misconfigured, not an error",
+			routes: map[string]fakeResponse{
+				repoPath + "/automated-security-fixes":        notFound,
+				repoPath + "/contents/.github/dependabot.yml": {http.StatusOK, "contents_dependabot_yml_malformed.json"},
+			},
+			wantUpdates: StatusDisabled,
+			wantConfig:  StatusMisconfigured,
+			wantPath:    ".github/dependabot.yml",
+		},
+		{
+			name: "no dependabot.yml",
+			routes: map[string]fakeResponse{
+				repoPath + "/automated-security-fixes":         notFound,
+				repoPath + "/contents/.github/dependabot.yml":  notFound,
+				repoPath + "/contents/.github/dependabot.yaml": notFound,
+			},
+			wantUpdates: StatusDisabled,
+			wantConfig:  StatusNotConfigured,
+		},
+		{
+			name: "403 is no access",
+			routes: map[string]fakeResponse{
+				repoPath + "/automated-security-fixes":         {http.StatusForbidden, "admin_required.json"},
+				repoPath + "/contents/.github/dependabot.yml":  forbidden,
+				repoPath + "/contents/.github/dependabot.yaml": forbidden,
+			},
+			wantUpdates: StatusNoAccess,
+			wantConfig:  StatusNoAccess,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			routes := map[string]fakeResponse{repoPath: {http.StatusOK, "repo_secret_scanning_enabled.json"}}
+			for k, v := range tc.routes {
+				routes[k] = v
+			}
+			_, a := newFakeGitHub(t, routes)
+			env := newActivityEnv(a)
+
+			checks := []string{CheckSecretScanning, CheckSecurityUpdates, CheckDependabotConfig}
+			val, err := env.ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), checks)
+			require.NoError(t, err)
+
+			var result RepoSecurityResult
+			require.NoError(t, val.Get(&result))
+			require.Nil(t, result.Error)
+			require.Equal(t, tc.wantUpdates, result.SecurityUpdates)
+			require.Equal(t, tc.wantUpdates, result.Check(CheckSecurityUpdates).Status)
+			require.Equal(t, tc.wantConfig, result.Check(CheckDependabotConfig).Status)
+			if tc.wantPath == "" {
+				require.Nil(t, result.DependabotConfig)
+			} else {
+				require.Equal(t, tc.wantPath, result.DependabotConfig.Path)
+			}
+
+			policy := CompliancePolicy{RequireSecurityUpdates: true, RequireDependabotConfig: true}
+			require.Equal(t, tc.wantUpdates == StatusEnabled && tc.wantConfig == StatusEnabled, policy.IsCompliant(&result))
+		})
+	}
+}
+
+func TestReportCountsDependabotUpdates(t *testing.T) {
+	results := []RepoSecurityResult{{Repository: "a"}, {Repository: "b"}, {Repository: "c"}}
+	results[0].setCheck(CheckSecurityUpdates, CheckResult{Status: StatusEnabled})
+	results[0].setCheck(CheckDependabotConfig, CheckResult{Status: StatusEnabled})
+	results[1].setCheck(CheckSecurityUpdates, CheckResult{Status: StatusEnabled})
+	results[1].setCheck(CheckDependabotConfig, CheckResult{Status: StatusMisconfigured})
+	results[2].setCheck(CheckSecurityUpdates, CheckResult{Status: StatusDisabled})
+	results[2].setCheck(CheckDependabotConfig, CheckResult{Status: StatusNotConfigured})
+
+	policy := CompliancePolicy{RequireDependabotConfig: true}
+	checks := []string{CheckSecurityUpdates, CheckDependabotConfig}
+	env := newActivityEnv(&Activities{})
+	val, err := env.ExecuteActivity("GenerateReport", "acme", results, []BlobRef(nil), policy, checks, []Suppression(nil))
+	require.NoError(t, err)
+	var report map[string]interface{}
+	require.NoError(t, val.Get(&report))
+	require.EqualValues(t, 2, report["security_updates_enabled"])
+	require.EqualValues(t, 1, report["dependabot_config_present"])
+	require.EqualValues(t, 1, report["dependabot_config_misconfigured"])
+	require.EqualValues(t, 1, report["fully_compliant"])
+
+	// Requirements on checks the scan did not run are dropped.
+	require.Empty(t, policy.forChecks(newCheckSet(DefaultChecks())).requiredResults())
+}
@@ -0,0 +1,115 @@
+package scanner
+
+// =============================================================================
+// Disappeared repos — repos gone between the listing and their check
+// =============================================================================
+//
+// A large org's scan can run for hours after FetchOrgRepos listed its repos,
+// and in that time a repo can be deleted, transferred to another owner, or
+// hidden from the scanner's token. Its check then says nothing about the
+// repo's security, and it is no failure of the scan either, so the repo is
+// neither an error nor non-compliant: checkGitHubRepo marks its result
+// Disappeared, with its checks StatusGone, and the workflow lists it in the
+// report's disappeared_repos instead of counting it anywhere else. It is
+// not in total_repos, so it is not in the compliance rate either.
+//
+// How the repo's GET answers says what happened:
+//
+//	404                    deleted; GitHub also answers 404 for a private
+//	                       repo the token cannot see, so a repo made
+//	                       private to it is reported deleted. A
+//	                       fine-grained token's 404 is read as the token
+//	                       lacking access instead (see repoaccess.go)
+//	301, or 200 naming     transferred; GitHub redirects a transferred
+//	another owner          repo's old name to /repositories/{id}, which the
+//	                       HTTP client usually follows itself
+//	403 "Resource not      access_revoked; the token, or the GitHub App
+//	accessible", or 451    installation, may no longer read the repo, or
+//	                       GitHub has blocked it
+//
+// A repo renamed within the org is still the org's, and is scanned as usual.
+// =============================================================================
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DisappearReason is why a listed repo could not be found when checked.
+type DisappearReason string
+
+const (
+	DisappearedDeleted       DisappearReason = "deleted"
+	DisappearedTransferred   DisappearReason = "transferred"
+	DisappearedAccessRevoked DisappearReason = "access_revoked"
+)
+
+// DisappearedRepo is a repo listed for the scan that was gone when its
+// check ran.
+type DisappearedRepo struct {
+	Repository string          `json:"repository"`
+	Reason     DisappearReason `json:"reason"`
+	// MovedTo is a transferred repo's new full name, when GitHub said it.
+	MovedTo    string `json:"moved_to,omitempty"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+}
+
+// setDisappeared marks r as gone for reason: it has a Disappeared record
+// and its checks are StatusGone.
+func (r *RepoSecurityResult) setDisappeared(reason DisappearReason, movedTo string, status int) {
+	r.Disappeared = &DisappearedRepo{Repository: r.Repository, Reason: reason, MovedTo: movedTo, HTTPStatus: status}
+	r.SecretScanning, r.DependabotAlerts, r.CodeScanning = StatusGone, StatusGone, StatusGone
+}
+
+// accessRevoked reports whether a repo GET's status and body say the token
+// may no longer read the repo.
+func accessRevoked(status int, body []byte) bool {
+	return status == http.StatusUnavailableForLegalReasons ||
+		status == http.StatusForbidden && permissionDenied(body)
+}
+
+// transferredTo is the full name a repo's GET body gives it, when that is
+// under an owner other than org.
+func transferredTo(org string, body []byte) string {
+	var repo struct {
+		FullName string `json:"full_name"`
+	}
+	if json.Unmarshal(body, &repo) != nil {
+		return ""
+	}
+	owner, _, ok := strings.Cut(repo.FullName, "/")
+	if !ok || strings.EqualFold(owner, org) {
+		return ""
+	}
+	return repo.FullName
+}
+
+// redirectURL is the API URL a redirect's body names in its "url", or ""
+// when it names none under the API.
+func (a *Activities) redirectURL(body []byte) string {
+	var moved struct {
+		URL string `json:"url"`
+	}
+	if json.Unmarshal(body, &moved) != nil || moved.URL == "" {
+		return ""
+	}
+	u, err := url.Parse(moved.URL)
+	if err != nil {
+		return ""
+	}
+	path := a.apiPath(u)
+	if path == "" {
+		return ""
+	}
+	return a.apiURL("%s", path)
+}
+
+// sortedDisappeared orders repos by name.
+func sortedDisappeared(repos []DisappearedRepo) []DisappearedRepo {
+	sorted := append([]DisappearedRepo(nil), repos...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Repository < sorted[j].Repository })
+	return sorted
+}
@@ -0,0 +1,132 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRepoSecurityDisappearedRepos(t *testing.T) {
+	const repoPath = "/repos/acme-corp/payments-api"
+
+	tests := []struct {
+		name        string
+		response    fakeResponse
+		wantReason  DisappearReason
+		wantMovedTo string
+	}{
+		{"deleted", fakeResponse{http.StatusNotFound, "not_found.json"}, DisappearedDeleted, ""},
+		{"transferred", fakeResponse{http.StatusOK, "repo_transferred.json"}, DisappearedTransferred, "globex/payments-api"},
+		{"access revoked", fakeResponse{http.StatusForbidden, "repo_access_revoked.json"}, DisappearedAccessRevoked, ""},
+		{"access blocked", fakeResponse{http.StatusUnavailableForLegalReasons, "repo_access_blocked.json"}, DisappearedAccessRevoked, ""},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			f, a := newFakeGitHub(t, map[string]fakeResponse{repoPath: tc.response})
+			env := newActivityEnv(a)
+
+			val, err := env.ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), DefaultChecks())
+			require.NoError(t, err)
+
+			var result RepoSecurityResult
+			require.NoError(t, val.Get(&result))
+			require.Nil(t, result.Error, "a repo gone since the listing is no error")
+			require.Equal(t, &DisappearedRepo{
+				Repository: "payments-api", Reason: tc.wantReason, MovedTo: tc.wantMovedTo, HTTPStatus: tc.response.Status,
+			}, result.Disappeared)
+			require.Equal(t, StatusGone, result.SecretScanning)
+			require.Len(t, f.Requests(), 1, "nothing else is checked")
+		})
+	}
+}
+
+func TestCheckRepoSecurityFollowsRepoRedirect(t *testing.T) {
+	for name, tc := range map[string]struct {
+		fullName    string
+		wantMovedTo string
+	}{
+		"transferred out of the org": {"globex/payments", "globex/payments"},
+		"renamed within the org":     {"acme-corp/payments", ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/acme-corp/payments-api":
+					w.Header().Set("Location", "/repositories/42")
+					w.WriteHeader(http.StatusMovedPermanently)
+					fmt.Fprintf(w, `{"message":"Moved Permanently","url":"http://%s/repositories/42"}`, r.Host)
+				case "/repositories/42":
+					fmt.Fprintf(w, `{"full_name":%q,"security_and_analysis":{"secret_scanning":{"status":"enabled"}}}`, tc.fullName)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			t.Cleanup(srv.Close)
+			client := srv.Client()
+			client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+			a := &Activities{HTTPClient: client, BaseURL: srv.URL}
+			env := newActivityEnv(a)
+
+			val, err := env.ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), []string{CheckSecretScanning})
+			require.NoError(t, err)
+			var result RepoSecurityResult
+			require.NoError(t, val.Get(&result))
+			require.Nil(t, result.Error)
+			if tc.wantMovedTo == "" {
+				require.Nil(t, result.Disappeared)
+				require.Equal(t, StatusEnabled, result.SecretScanning)
+				return
+			}
+			require.Equal(t, DisappearedTransferred, result.Disappeared.Reason)
+			require.Equal(t, tc.wantMovedTo, result.Disappeared.MovedTo)
+		})
+	}
+}
+
+func TestWorkflowReportsDisappearedRepos(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(5))
+	gone := func(repo string, reason DisappearReason, movedTo string, status int) *RepoSecurityResult {
+		r := &RepoSecurityResult{Repository: repo}
+		r.setDisappeared(reason, movedTo, status)
+		return r
+	}
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-003", mock.Anything, mock.Anything).
+		Return(gone("repo-003", DisappearedTransferred, "globex/repo-003", http.StatusOK), nil)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-001", mock.Anything, mock.Anything).
+		Return(gone("repo-001", DisappearedDeleted, "", http.StatusNotFound), nil)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("repo-004"))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Checks: []string{CheckSecretScanning, CheckCodeScanning, CheckActions}})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.NoError(t, report.Validate())
+	require.Equal(t, 0, report.Errors)
+	require.Equal(t, 3, report.TotalRepos, "disappeared repos are not in the total")
+	require.Equal(t, 2, report.FullyCompliant)
+	require.Equal(t, []string{"repo-004"}, report.NonCompliantRepos)
+	require.Equal(t, "66.7%", report.ComplianceRate)
+	require.Equal(t, 2, report.Disappeared)
+	require.Equal(t, []DisappearedRepo{
+		{Repository: "repo-001", Reason: DisappearedDeleted, HTTPStatus: http.StatusNotFound},
+		{Repository: "repo-003", Reason: DisappearedTransferred, MovedTo: "globex/repo-003", HTTPStatus: http.StatusOK},
+	}, report.DisappearedRepos)
+	require.NotContains(t, report.RepoFailures, "repo-001")
+	// Gone repos have no Actions settings to read.
+	env.AssertNumberOfCalls(t, "CheckActionsSecurity", 3)
+
+	var out bytes.Buffer
+	RenderReport(&out, report, RenderOptions{})
+	require.Contains(t, out.String(), "Disappeared:          2 (deleted 1, transferred 1)")
+}
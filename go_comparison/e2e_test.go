@@ -0,0 +1,121 @@
+package scanner
+
+// =============================================================================
+// End-to-end scan against the mock GitHub API
+// =============================================================================
+//
+// The `make demo` of this repo, minus the Temporal server: the real workflow
+// and real activities run in the testsuite, talking HTTP to the deterministic
+// fake from internal/githubmock. The expected report is computed from the
+// fake's own repo list, so the test pins the whole pipeline — pagination,
+// per-repo checks, aggregation — without a token or network access.
+//
+//	go test ./go_comparison -run TestEndToEnd -v
+// =============================================================================
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/salkimmich/temporal-security-scanner/go_comparison/internal/githubmock"
+)
+
+func TestEndToEndAgainstMockGitHub(t *testing.T) {
+	// 250 repos spans three pages of 100.
+	gh := githubmock.New(githubmock.Config{Org: "acme-corp", Repos: 250, Seed: 7, Token: "demo-token"})
+	srv := httptest.NewServer(gh)
+	t.Cleanup(srv.Close)
+
+	var s testsuite.WorkflowTestSuite
+	s.SetLogger(nopLogger{})
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{HTTPClient: srv.Client(), BaseURL: srv.URL})
+
+	token := "demo-token"
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org:    "acme-corp",
+		Token:  &token,
+		Checks: append(DefaultChecks(), CheckFiles, CheckActions),
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var want struct{ compliant, secret, dependabot, codeScanning, codeowners, securityPolicy, actions, readOnlyToken int }
+	var nonCompliant, indeterminate, archived []interface{}
+	for _, r := range gh.Repos() {
+		if r.Archived {
+			archived = append(archived, r.Name)
+			continue
+		}
+		secret := r.SecretScanning == "enabled"
+		code := r.CodeScanning == http.StatusOK
+		if secret {
+			want.secret++
+		}
+		if r.Dependabot {
+			want.dependabot++
+		}
+		if code {
+			want.codeScanning++
+		}
+		if r.HasCodeowners {
+			want.codeowners++
+		}
+		if r.SecurityPolicy {
+			want.securityPolicy++
+		}
+		if r.ActionsEnabled {
+			want.actions++
+		}
+		if !r.ActionsEnabled || r.WorkflowPermissions == "read" {
+			want.readOnlyToken++
+		}
+		switch {
+		case secret && r.Dependabot && code:
+			want.compliant++
+		case secret && r.Dependabot && r.CodeScanning == http.StatusForbidden:
+			// Code scanning could not be read: neither way.
+			indeterminate = append(indeterminate, r.Name)
+		default:
+			nonCompliant = append(nonCompliant, r.Name)
+		}
+	}
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.NotEmpty(t, archived, "the fake has archived repos")
+	require.EqualValues(t, 250-len(archived), report["total_repos"])
+	require.EqualValues(t, len(archived), report["archived"])
+	require.ElementsMatch(t, archived, report["archived_repos"])
+	require.EqualValues(t, 0, report["errors"])
+	require.EqualValues(t, want.compliant, report["fully_compliant"])
+	require.EqualValues(t, want.secret, report["secret_scanning_enabled"])
+	require.EqualValues(t, want.dependabot, report["dependabot_enabled"])
+	require.EqualValues(t, want.codeScanning, report["code_scanning_enabled"])
+	require.Equal(t, fmt.Sprintf("%.1f%%", float64(want.compliant)/float64(250-len(archived)-len(indeterminate))*100), report["compliance_rate"])
+	require.EqualValues(t, want.codeowners, report["codeowners_present"])
+	require.EqualValues(t, want.securityPolicy, report["security_policy_present"])
+	require.EqualValues(t, want.actions, report["actions_enabled"])
+	require.EqualValues(t, want.readOnlyToken, report["read_only_workflow_token"])
+	require.ElementsMatch(t, nonCompliant, report["non_compliant_repos"])
+	require.NotEmpty(t, indeterminate, "the fake has repos without code scanning access")
+	require.ElementsMatch(t, indeterminate, report["indeterminate_repos"])
+
+	// The listing's metadata reaches the report's breakdowns.
+	byVisibility := map[string]int{}
+	for _, r := range gh.Repos() {
+		if !r.Archived {
+			byVisibility[r.Visibility()]++
+		}
+	}
+	for visibility, n := range byVisibility {
+		require.EqualValues(t, n, report["by_visibility"].(map[string]interface{})[visibility].(map[string]interface{})["repos"], visibility)
+	}
+	require.Contains(t, report["by_language"], BucketUnknown, "the fake has repos without a language")
+}
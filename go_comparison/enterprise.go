@@ -0,0 +1,421 @@
+package scanner
+
+// =============================================================================
+// Enterprise scans — every org of a GitHub Enterprise account
+// =============================================================================
+//
+// A GitHub Enterprise account owns many orgs. EnterpriseScanWorkflow lists
+// them with FetchEnterpriseOrgs, then runs the ordinary SecurityScanWorkflow
+// for each org as a child, a few at a time, and aggregates the org reports
+// into an EnterpriseReport: enterprise-wide totals and rate, and each org's
+// full report as its own section. An org whose scan fails is listed with
+// its error; the others still count.
+//
+// GitHub's REST API has no listing of an enterprise's orgs, so
+// FetchEnterpriseOrgs asks the GraphQL API. The enterprise object needs
+// other permissions than the org endpoints: a classic token needs the
+// read:enterprise scope, and fine-grained tokens cannot read it at all. The
+// activity checks the scopes before listing and fails non-retryably with
+// the reason, so the scan stops before any org is started; each org scan
+// then runs its own token pre-flight against its org.
+//
+// A "cancel_scan" signal stops starting orgs and is forwarded to every
+// running org scan, which stops and reports what it scanned. The orgs never
+// started are listed in skipped_orgs.
+// =============================================================================
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// DefaultEnterpriseOrgConcurrency is how many org scans an enterprise scan
+// runs at once unless EnterpriseScanInput.MaxConcurrentOrgs says otherwise.
+const DefaultEnterpriseOrgConcurrency = 4
+
+// enterpriseScopes are the classic scopes that can read an enterprise's
+// orgs.
+var enterpriseScopes = []string{"read:enterprise", "admin:enterprise"}
+
+// enterpriseWorkflowIDPrefix starts every EnterpriseScanWorkflow ID. It
+// is not a ScanWorkflowID, so WorkflowIDOrg does not mistake it for one.
+const enterpriseWorkflowIDPrefix = "security-enterprise-scan-"
+
+// EnterpriseWorkflowID returns the workflow ID for a scan of enterprise.
+func EnterpriseWorkflowID(enterprise string) string {
+	return enterpriseWorkflowIDPrefix + enterprise
+}
+
+// enterpriseOrgWorkflowID names an enterprise scan's child for org, e.g.
+// security-scan-acme/enterprise-globex, so it does not replace the org's
+// ad-hoc scan and --list --org finds it.
+func enterpriseOrgWorkflowID(enterprise, org string) string {
+	return ScanWorkflowID(org, WithSuffix("enterprise-"+enterprise))
+}
+
+// ValidateEnterpriseSlug checks that s can be a GitHub enterprise slug.
+func ValidateEnterpriseSlug(s string) error {
+	if !repoNamePart.MatchString(s) {
+		return fmt.Errorf("%q is not an enterprise slug", s)
+	}
+	return nil
+}
+
+// EnterpriseScanInput is the input to EnterpriseScanWorkflow.
+type EnterpriseScanInput struct {
+	Enterprise string  `json:"enterprise"`
+	Token      *string `json:"token,omitempty"`
+
+	// Scan is the scan every org gets, with its Org and Token set per org.
+	// Repos and Teams name one org's repos and must be empty.
+	Scan ScanInput `json:"scan"`
+
+	// MaxConcurrentOrgs is how many org scans run at once. 0 means
+	// DefaultEnterpriseOrgConcurrency.
+	MaxConcurrentOrgs int `json:"max_concurrent_orgs,omitempty"`
+}
+
+// EnterpriseReport is what EnterpriseScanWorkflow returns. The totals add
+// up the org reports in OrgReports; orgs in FailedOrgs and SkippedOrgs are
+// not in them.
+type EnterpriseReport struct {
+	Enterprise     string   `json:"enterprise"`
+	Orgs           []string `json:"orgs"`
+	TotalRepos     int      `json:"total_repos"`
+	FullyCompliant int      `json:"fully_compliant"`
+	NonCompliant   int      `json:"non_compliant"`
+	Indeterminate  int      `json:"indeterminate,omitempty"`
+	Errors         int      `json:"errors"`
+	ComplianceRate string   `json:"compliance_rate"`
+
+	// OrgReports holds each scanned org's report, cancelled ones included.
+	OrgReports map[string]Report `json:"org_reports"`
+	// FailedOrgs maps each org whose scan failed to its error.
+	FailedOrgs map[string]string `json:"failed_orgs,omitempty"`
+	// SkippedOrgs were not started because the scan was cancelled.
+	SkippedOrgs []string `json:"skipped_orgs,omitempty"`
+
+	Cancelled    bool   `json:"cancelled,omitempty"`
+	CancelReason string `json:"cancel_reason,omitempty"`
+	WorkflowID   string `json:"workflow_id,omitempty"`
+	RunID        string `json:"run_id,omitempty"`
+	StartedAt    string `json:"started_at,omitempty"`
+	CompletedAt  string `json:"completed_at,omitempty"`
+}
+
+// EnterpriseProgress is the answer to an enterprise scan's "progress"
+// query.
+type EnterpriseProgress struct {
+	Enterprise string `json:"enterprise"`
+	// Orgs is how many orgs were found; 0 until they are listed.
+	Orgs        int      `json:"orgs"`
+	OrgsDone    int      `json:"orgs_done"`
+	RunningOrgs []string `json:"running_orgs,omitempty"`
+	Cancelled   bool     `json:"cancelled,omitempty"`
+}
+
+// addOrg counts r's repos in the enterprise totals.
+func (e *EnterpriseReport) addOrg(org string, r Report) {
+	e.OrgReports[org] = r
+	e.TotalRepos += r.TotalRepos
+	e.FullyCompliant += r.FullyCompliant
+	e.NonCompliant += len(r.NonCompliantRepos)
+	e.Indeterminate += r.Indeterminate
+	e.Errors += r.Errors
+}
+
+// summary is the enterprise totals as a Report, for its Rate and colors.
+func (e EnterpriseReport) summary() Report {
+	return Report{
+		TotalRepos:     e.TotalRepos,
+		FullyCompliant: e.FullyCompliant,
+		Indeterminate:  e.Indeterminate,
+		ComplianceRate: e.ComplianceRate,
+	}
+}
+
+// Rate is the enterprise compliance rate in percent, or 0 for an empty
+// scan.
+func (e EnterpriseReport) Rate() float64 {
+	return e.summary().Rate()
+}
+
+// graphqlURL is the GraphQL endpoint next to BaseURL: /graphql on
+// api.github.com, /api/graphql on GitHub Enterprise Server, whose REST API
+// is under /api/v3.
+func (a *Activities) graphqlURL() string {
+	base := strings.TrimRight(a.BaseURL, "/")
+	if base == "" {
+		base = DefaultGitHubAPI
+	}
+	if strings.HasSuffix(base, "/api/v3") {
+		return strings.TrimSuffix(base, "/v3") + "/graphql"
+	}
+	return base + "/graphql"
+}
+
+const enterpriseOrgsQuery = `query($slug: String!, $cursor: String) {
+  enterprise(slug: $slug) {
+    organizations(first: 100, after: $cursor) {
+      nodes { login }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+// FetchEnterpriseOrgs lists the logins of the orgs in enterprise that the
+// token can see. A missing enterprise, or a token that cannot read it,
+// fails non-retryably with the reason.
+func (a *Activities) FetchEnterpriseOrgs(ctx context.Context, enterprise string, cred *Credential) ([]string, error) {
+	cred, err := a.githubToken(ctx, cred)
+	if err != nil {
+		return nil, err
+	}
+	if cred == nil {
+		return nil, temporal.NewNonRetryableApplicationError(
+			"listing the orgs of an enterprise needs a token with the read:enterprise scope", "UNAUTHORIZED", nil)
+	}
+	denied := temporal.NewNonRetryableApplicationError(
+		fmt.Sprintf("token cannot read enterprise '%s' (needs the read:enterprise scope on a classic token)", enterprise),
+		"UNAUTHORIZED", nil)
+
+	var orgs []string
+	var cursor *string
+	for page := 1; ; page++ {
+		activity.RecordHeartbeat(ctx, fmt.Sprintf("Fetching page %d", page))
+		payload, err := json.Marshal(map[string]interface{}{
+			"query":     enterpriseOrgsQuery,
+			"variables": map[string]interface{}{"slug": enterprise, "cursor": cursor},
+		})
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, "POST", a.graphqlURL(), bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+cred.Value)
+		resp, err := a.do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetching orgs page %d: %w", page, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized:
+			return nil, temporal.NewNonRetryableApplicationError("invalid GitHub API token", "UNAUTHORIZED", nil)
+		case resp.StatusCode == http.StatusForbidden:
+			return nil, fmt.Errorf("GitHub API rate limit exceeded")
+		case resp.StatusCode != http.StatusOK:
+			return nil, wrapGitHubError(resp, body)
+		}
+		if scopes, classic := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; classic && !hasAnyScope(parseScopes(strings.Join(scopes, ",")), enterpriseScopes) {
+			return nil, denied
+		}
+
+		var result struct {
+			Data struct {
+				Enterprise *struct {
+					Organizations struct {
+						Nodes []struct {
+							Login string `json:"login"`
+						} `json:"nodes"`
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+					} `json:"organizations"`
+				} `json:"enterprise"`
+			} `json:"data"`
+			Errors []struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+		for _, e := range result.Errors {
+			switch e.Type {
+			case "NOT_FOUND":
+				return nil, temporal.NewNonRetryableApplicationError(
+					fmt.Sprintf("enterprise '%s' not found", enterprise), "NOT_FOUND", nil)
+			case "FORBIDDEN", "INSUFFICIENT_SCOPES":
+				return nil, denied
+			}
+		}
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("listing the orgs of %s: %s", enterprise, result.Errors[0].Message)
+		}
+		if result.Data.Enterprise == nil {
+			return nil, temporal.NewNonRetryableApplicationError(
+				fmt.Sprintf("enterprise '%s' not found", enterprise), "NOT_FOUND", nil)
+		}
+
+		conn := result.Data.Enterprise.Organizations
+		for _, n := range conn.Nodes {
+			orgs = append(orgs, n.Login)
+		}
+		if !conn.PageInfo.HasNextPage {
+			break
+		}
+		end := conn.PageInfo.EndCursor
+		cursor = &end
+	}
+
+	activity.GetLogger(ctx).Info("Fetched enterprise organizations", "count", len(orgs), "enterprise", enterprise)
+	return orgs, nil
+}
+
+func hasAnyScope(scopes, want []string) bool {
+	for _, s := range scopes {
+		for _, w := range want {
+			if s == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// orgScan is a running org scan of an enterprise scan.
+type orgScan struct {
+	org    string
+	future workflow.ChildWorkflowFuture
+}
+
+// EnterpriseScanWorkflow scans every org of in.Enterprise with a
+// SecurityScanWorkflow child, at most in.MaxConcurrentOrgs at a time, and
+// returns their reports aggregated. A "cancel_scan" signal is forwarded to
+// the running org scans and no more are started.
+func EnterpriseScanWorkflow(ctx workflow.Context, in EnterpriseScanInput) (EnterpriseReport, error) {
+	logger := workflow.GetLogger(ctx)
+	info := workflow.GetInfo(ctx)
+	report := EnterpriseReport{
+		Enterprise: in.Enterprise,
+		OrgReports: map[string]Report{},
+		WorkflowID: info.WorkflowExecution.ID,
+		RunID:      info.WorkflowExecution.RunID,
+		StartedAt:  workflow.Now(ctx).UTC().Format(time.RFC3339),
+	}
+	if err := in.validation(changeVersion(ctx, changeInputRules) >= 1).err(); err != nil {
+		return report, invalidInputError(err)
+	}
+
+	var running []orgScan
+	progress := func() EnterpriseProgress {
+		p := EnterpriseProgress{
+			Enterprise: in.Enterprise,
+			Orgs:       len(report.Orgs),
+			OrgsDone:   len(report.OrgReports) + len(report.FailedOrgs),
+			Cancelled:  report.Cancelled,
+		}
+		for _, s := range running {
+			p.RunningOrgs = append(p.RunningOrgs, s.org)
+		}
+		return p
+	}
+	if err := workflow.SetQueryHandler(ctx, "progress", func() (EnterpriseProgress, error) {
+		return progress(), nil
+	}); err != nil {
+		return report, fmt.Errorf("registering progress query: %w", err)
+	}
+
+	workflow.Go(ctx, func(gCtx workflow.Context) {
+		var reason string
+		workflow.GetSignalChannel(gCtx, "cancel_scan").Receive(gCtx, &reason)
+		report.Cancelled = true
+		report.CancelReason = reason
+		logger.Info("Enterprise scan cancellation requested", "reason", reason)
+		for _, s := range append([]orgScan(nil), running...) {
+			if err := s.future.GetChildWorkflowExecution().Get(gCtx, nil); err == nil {
+				_ = s.future.SignalChildWorkflow(gCtx, "cancel_scan", reason).Get(gCtx, nil)
+			}
+		}
+	})
+
+	fetchCtx := workflow.WithActivityOptions(ctx, in.Scan.Timeouts.fetch().options(in.Scan.RetryPolicies.fetchRetryPolicy()))
+	var orgs []string
+	if err := workflow.ExecuteActivity(fetchCtx, "FetchEnterpriseOrgs", in.Enterprise, PATCredential(in.Token)).Get(ctx, &orgs); err != nil {
+		return report, fmt.Errorf("listing the orgs of enterprise %s: %w", in.Enterprise, err)
+	}
+	report.Orgs = orgs
+	logger.Info("Scanning enterprise", "enterprise", in.Enterprise, "orgs", len(orgs))
+
+	limit := in.MaxConcurrentOrgs
+	if limit == 0 {
+		limit = DefaultEnterpriseOrgConcurrency
+	}
+	selector := workflow.NewSelector(ctx)
+	start := func(org string) {
+		scan := in.Scan
+		scan.Org = org
+		scan.Token = in.Token
+		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+			WorkflowID:        enterpriseOrgWorkflowID(in.Enterprise, org),
+			ParentClosePolicy: enums.PARENT_CLOSE_POLICY_TERMINATE,
+			Memo:              EnterpriseScanMemo(in, org),
+		})
+		future := workflow.ExecuteChildWorkflow(childCtx, SecurityScanWorkflow, scan)
+		running = append(running, orgScan{org: org, future: future})
+		selector.AddFuture(future, func(f workflow.Future) {
+			for i, s := range running {
+				if s.org == org {
+					running = append(running[:i], running[i+1:]...)
+					break
+				}
+			}
+			var r Report
+			if err := f.Get(ctx, &r); err != nil {
+				msg := err.Error()
+				var appErr *temporal.ApplicationError
+				if errors.As(err, &appErr) {
+					msg = appErr.Message()
+				}
+				if report.FailedOrgs == nil {
+					report.FailedOrgs = map[string]string{}
+				}
+				report.FailedOrgs[org] = msg
+				logger.Warn("Org scan failed", "org", org, "error", err)
+				return
+			}
+			report.addOrg(org, r)
+		})
+	}
+
+	next := 0
+	for {
+		for !report.Cancelled && next < len(orgs) && len(running) < limit {
+			start(orgs[next])
+			next++
+		}
+		if len(running) == 0 {
+			break
+		}
+		selector.Select(ctx)
+	}
+	if report.Cancelled {
+		report.SkippedOrgs = orgs[next:]
+	}
+
+	report.ComplianceRate = complianceRate(report.FullyCompliant, report.TotalRepos, report.Indeterminate)
+	report.CompletedAt = workflow.Now(ctx).UTC().Format(time.RFC3339)
+	return report, nil
+}
@@ -0,0 +1,201 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+// newFakeGraphQL serves the enterprise organizations query for enterprise
+// "acme", two orgs per page, with scopes as the token's classic scopes.
+func newFakeGraphQL(t *testing.T, orgs []string, scopes string) *Activities {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/graphql", r.URL.Path)
+		require.Equal(t, http.MethodPost, r.Method)
+		var req struct {
+			Variables struct {
+				Slug   string  `json:"slug"`
+				Cursor *string `json:"cursor"`
+			} `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if scopes != "" {
+			w.Header().Set("X-OAuth-Scopes", scopes)
+		}
+		if req.Variables.Slug != "acme" {
+			fmt.Fprint(w, `{"data":{"enterprise":null},"errors":[{"type":"NOT_FOUND","message":"Could not resolve"}]}`)
+			return
+		}
+		start := 0
+		if req.Variables.Cursor != nil {
+			fmt.Sscan(*req.Variables.Cursor, &start)
+		}
+		end := start + 2
+		if end > len(orgs) {
+			end = len(orgs)
+		}
+		var page struct {
+			Nodes    []map[string]string    `json:"nodes"`
+			PageInfo map[string]interface{} `json:"pageInfo"`
+		}
+		for _, org := range orgs[start:end] {
+			page.Nodes = append(page.Nodes, map[string]string{"login": org})
+		}
+		page.PageInfo = map[string]interface{}{"hasNextPage": end < len(orgs), "endCursor": fmt.Sprint(end)}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"enterprise": map[string]interface{}{"organizations": page}},
+		}))
+	}))
+	t.Cleanup(srv.Close)
+	return &Activities{HTTPClient: srv.Client(), BaseURL: srv.URL}
+}
+
+func TestFetchEnterpriseOrgs(t *testing.T) {
+	a := newFakeGraphQL(t, []string{"alpha", "beta", "gamma"}, "repo, read:enterprise")
+	token := "t"
+	val, err := newActivityEnv(a).ExecuteActivity(a.FetchEnterpriseOrgs, "acme", PATCredential(&token))
+	require.NoError(t, err)
+	var orgs []string
+	require.NoError(t, val.Get(&orgs))
+	require.Equal(t, []string{"alpha", "beta", "gamma"}, orgs, "both pages")
+}
+
+func TestFetchEnterpriseOrgsFailures(t *testing.T) {
+	token := "t"
+	for name, tc := range map[string]struct {
+		slug, scopes string
+		token        *string
+		wantType     string
+		wantMsg      string
+	}{
+		"unknown enterprise": {"globex", "read:enterprise", &token, "NOT_FOUND", "enterprise 'globex' not found"},
+		"missing scope":      {"acme", "repo, read:org", &token, "UNAUTHORIZED", "read:enterprise"},
+		"no token":           {"acme", "", nil, "UNAUTHORIZED", "needs a token"},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			a := newFakeGraphQL(t, []string{"alpha"}, tc.scopes)
+			_, err := newActivityEnv(a).ExecuteActivity(a.FetchEnterpriseOrgs, tc.slug, PATCredential(tc.token))
+			require.ErrorContains(t, err, tc.wantMsg)
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(err, &appErr))
+			require.Equal(t, tc.wantType, appErr.Type())
+			require.True(t, appErr.NonRetryable())
+		})
+	}
+}
+
+func TestGraphQLURL(t *testing.T) {
+	require.Equal(t, "https://api.github.com/graphql", (&Activities{}).graphqlURL())
+	require.Equal(t, "https://ghe.example.com/api/graphql", (&Activities{BaseURL: "https://ghe.example.com/api/v3/"}).graphqlURL())
+}
+
+// mockEnterprise mocks the listing of enterprise "acme" and gives every org
+// ten repos, of which repo-003 is non-compliant. An org named "gone" fails
+// its listing.
+func mockEnterprise(t *testing.T, orgs ...string) (*testsuite.TestWorkflowEnvironment, *[]string) {
+	t.Helper()
+	env := newTestEnv(t)
+	env.RegisterWorkflow(SecurityScanWorkflow)
+	env.OnActivity("FetchEnterpriseOrgs", mock.Anything, "acme", mock.Anything).Return(orgs, nil)
+	env.OnActivity("FetchOrgReposPage", mock.Anything, mock.Anything).Return(func(_ context.Context, in RepoPageInput) (*RepoPage, error) {
+		if in.Org == "gone" {
+			return nil, temporal.NewNonRetryableApplicationError("organization 'gone' not found", "NOT_FOUND", nil)
+		}
+		return &RepoPage{Repos: fakeRepos(10)}, nil
+	})
+	var children []string
+	env.SetOnChildWorkflowStartedListener(func(info *workflow.Info, _ workflow.Context, _ converter.EncodedValues) {
+		children = append(children, info.WorkflowExecution.ID)
+	})
+	return env, &children
+}
+
+func TestEnterpriseScanWorkflow(t *testing.T) {
+	env, children := mockEnterprise(t, "alpha", "beta", "gone")
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("repo-003"))
+
+	env.ExecuteWorkflow(EnterpriseScanWorkflow, EnterpriseScanInput{Enterprise: "acme", MaxConcurrentOrgs: 2})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report EnterpriseReport
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, []string{
+		"security-scan-alpha/enterprise-acme",
+		"security-scan-beta/enterprise-acme",
+		"security-scan-gone/enterprise-acme",
+	}, *children)
+	require.Equal(t, []string{"alpha", "beta", "gone"}, report.Orgs)
+	require.Equal(t, 20, report.TotalRepos)
+	require.Equal(t, 18, report.FullyCompliant)
+	require.Equal(t, 2, report.NonCompliant)
+	require.Equal(t, "90.0%", report.ComplianceRate)
+	require.Len(t, report.OrgReports, 2)
+	require.Equal(t, []string{"repo-003"}, report.OrgReports["beta"].NonCompliantRepos)
+	require.Contains(t, report.FailedOrgs["gone"], "organization 'gone' not found")
+	require.False(t, report.Cancelled)
+}
+
+func TestEnterpriseScanWorkflowCancel(t *testing.T) {
+	env, children := mockEnterprise(t, "alpha", "beta")
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(time.Minute).Return(compliantUnless())
+
+	// Arrives while alpha's first batch is in flight; alpha stops and
+	// beta is never started.
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("cancel_scan", "change freeze")
+	}, 30*time.Second)
+
+	env.ExecuteWorkflow(EnterpriseScanWorkflow, EnterpriseScanInput{Enterprise: "acme", MaxConcurrentOrgs: 1})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report EnterpriseReport
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, []string{"security-scan-alpha/enterprise-acme"}, *children)
+	require.True(t, report.Cancelled)
+	require.Equal(t, "change freeze", report.CancelReason)
+	require.True(t, report.OrgReports["alpha"].Cancelled, "the signal reached the org scan")
+	require.Len(t, report.OrgReports["alpha"].CancelledInFlightRepos, 10)
+	require.Equal(t, []string{"beta"}, report.SkippedOrgs)
+
+	val, err := env.QueryWorkflow("progress")
+	require.NoError(t, err)
+	var progress EnterpriseProgress
+	require.NoError(t, val.Get(&progress))
+	require.Equal(t, EnterpriseProgress{Enterprise: "acme", Orgs: 2, OrgsDone: 1, Cancelled: true}, progress)
+}
+
+func TestEnterpriseScanWorkflowRejectsOrgInput(t *testing.T) {
+	for name, in := range map[string]EnterpriseScanInput{
+		"bad slug":  {Enterprise: "acme corp"},
+		"org set":   {Enterprise: "acme", Scan: ScanInput{Org: "alpha"}},
+		"repos set": {Enterprise: "acme", Scan: ScanInput{Repos: []string{"alpha/api"}}},
+		"gitlab":    {Enterprise: "acme", Scan: ScanInput{Provider: ProviderGitLab}},
+	} {
+		in := in
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.ExecuteWorkflow(EnterpriseScanWorkflow, in)
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+			require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package scanner
+
+// =============================================================================
+// Evidence — what the provider's API said, for audit trails
+// =============================================================================
+//
+// A check's status is our reading of the provider's responses. Auditors
+// want the responses too, so with ScanInput.IncludeEvidence each check's
+// CheckResult carries its Evidence: the URL of every request the check
+// made, the status it got, when it asked, and the part of the response
+// the status was read from, e.g. a repo's security_and_analysis object.
+//
+// Only the fragments listed in evidenceFields are kept, each at most
+// maxEvidenceFragment bytes: never request headers, so never the token,
+// and never file contents. URLs lose any userinfo and credential-like
+// query parameters. Evidence makes results several times larger, so the
+// workflow's claim check (ResultsOffloadBytes) offloads them sooner, and
+// GenerateReport keeps the report's evidence appendix in the blob store
+// when it is large (see reportEvidence).
+//
+// GitHub scans with evidence check repos through CheckProviderRepo, as its
+// RepoCheckInput can ask for evidence; CheckRepoSecurity's arguments
+// cannot change. The Actions and access checks do not record evidence.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// maxEvidenceFragment is the largest response fragment kept; larger ones
+// are dropped and the evidence marked Truncated.
+const maxEvidenceFragment = 2048
+
+// Evidence is one request a check made and what it got back.
+type Evidence struct {
+	URL         string    `json:"url"`
+	HTTPStatus  int       `json:"http_status"`
+	RequestedAt time.Time `json:"requested_at"`
+	// Fragment is the part of the response the check read, when the
+	// response is JSON and the endpoint has fields worth keeping.
+	Fragment  json.RawMessage `json:"fragment,omitempty"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// evidenceFields are the response fields kept as the fragment, by a
+// pattern of the request's path. Endpoints not listed keep none.
+var evidenceFields = []struct {
+	path   *regexp.Regexp
+	fields []string
+}{
+	// GitHub
+	{regexp.MustCompile(`^/repos/[^/]+/[^/]+$`), []string{"security_and_analysis", "archived", "visibility"}},
+	{regexp.MustCompile(`/community/profile$`), []string{"files"}},
+	{regexp.MustCompile(`^/repos/[^/]+/[^/]+/contents/`), []string{"type", "path", "size", "sha"}},
+	// GitLab
+	{regexp.MustCompile(`^/projects/[^/]+$`), []string{"auto_devops_enabled", "ci_config_path", "default_branch"}},
+	{regexp.MustCompile(`/repository/files/[^/]+$`), []string{"file_path", "size", "blob_id"}},
+}
+
+// secretQueryParam matches query parameters that may carry a credential.
+var secretQueryParam = regexp.MustCompile(`(?i)token|key|secret|signature|password`)
+
+// evidenceLog collects the evidence of one repo's checks.
+type evidenceLog struct {
+	mu      sync.Mutex
+	byCheck map[string][]Evidence
+}
+
+type (
+	evidenceKey       struct{}
+	evidenceChecksKey struct{}
+)
+
+// withEvidence returns ctx with a log that checkEndpoint records into.
+func withEvidence(ctx context.Context) (context.Context, *evidenceLog) {
+	l := &evidenceLog{byCheck: make(map[string][]Evidence)}
+	return context.WithValue(ctx, evidenceKey{}, l), l
+}
+
+// evidenceFrom returns ctx's log, or nil when evidence is off.
+func evidenceFrom(ctx context.Context) *evidenceLog {
+	l, _ := ctx.Value(evidenceKey{}).(*evidenceLog)
+	return l
+}
+
+// evidenceFor returns ctx whose requests are recorded as evidence for
+// checks. Checks that run concurrently each have their own ctx.
+func evidenceFor(ctx context.Context, checks ...string) context.Context {
+	if evidenceFrom(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, evidenceChecksKey{}, checks)
+}
+
+// add records a request made with ctx for ctx's checks (see evidenceFor).
+// l may be nil.
+func (l *evidenceLog) add(ctx context.Context, rawURL string, status int, body []byte, at time.Time) {
+	if l == nil {
+		return
+	}
+	checks, _ := ctx.Value(evidenceChecksKey{}).([]string)
+	if len(checks) == 0 {
+		return
+	}
+	e := Evidence{URL: redactURL(rawURL), HTTPStatus: status, RequestedAt: at.UTC()}
+	if u, err := url.Parse(rawURL); err == nil {
+		e.Fragment, e.Truncated = evidenceFragment(u.EscapedPath(), body)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range checks {
+		l.byCheck[c] = append(l.byCheck[c], e)
+	}
+}
+
+// attach sets the evidence of each check r has a result for. Checks the
+// scan did not select have no result, so their requests are dropped.
+func (l *evidenceLog) attach(r *RepoSecurityResult) {
+	if l == nil || r == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for name, c := range r.Checks {
+		if e := l.byCheck[name]; len(e) > 0 {
+			c.Evidence = e
+			r.Checks[name] = c
+		}
+	}
+}
+
+// evidenceFragment picks the fields evidenceFields keeps for path out of
+// a JSON object body.
+func evidenceFragment(path string, body []byte) (json.RawMessage, bool) {
+	// API base URLs may carry a prefix, e.g. /api/v3 or /api/v4.
+	for _, prefix := range []string{"/api/v3", "/api/v4"} {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	for _, f := range evidenceFields {
+		if !f.path.MatchString(path) {
+			continue
+		}
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(body, &obj) != nil {
+			return nil, false
+		}
+		kept := make(map[string]json.RawMessage, len(f.fields))
+		for _, name := range f.fields {
+			if v, ok := obj[name]; ok {
+				kept[name] = v
+			}
+		}
+		if len(kept) == 0 {
+			return nil, false
+		}
+		b, err := json.Marshal(kept)
+		if err != nil || len(b) > maxEvidenceFragment {
+			return nil, err == nil
+		}
+		return b, false
+	}
+	return nil, false
+}
+
+// redactURL drops rawURL's userinfo and the values of credential-like
+// query parameters.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			if secretQueryParam.MatchString(k) {
+				q.Set(k, "REDACTED")
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// maxInlineEvidence is the largest evidence appendix kept in the report
+// itself; a larger one goes to the blob store.
+const maxInlineEvidence = 256 << 10
+
+// EvidenceAppendix is the report's evidence: each repo's, by check.
+type EvidenceAppendix map[string]map[string][]Evidence
+
+// add adds r's evidence, if it has any.
+func (a EvidenceAppendix) add(r *RepoSecurityResult) {
+	for name, c := range r.Checks {
+		if len(c.Evidence) == 0 {
+			continue
+		}
+		if a[r.Repository] == nil {
+			a[r.Repository] = make(map[string][]Evidence)
+		}
+		a[r.Repository][name] = c.Evidence
+	}
+}
+
+// reportEvidence adds appendix to report: inline as "evidence" when it is
+// small, otherwise stored in the blob store with its claim check in
+// "evidence_ref". Without a blob store, "evidence_omitted" counts the
+// repos whose evidence was left out, so the report still fits a payload.
+func (a *Activities) reportEvidence(ctx context.Context, report map[string]interface{}, appendix EvidenceAppendix) error {
+	if len(appendix) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(appendix)
+	if err != nil {
+		return fmt.Errorf("encoding evidence: %w", err)
+	}
+	switch {
+	case len(data) <= maxInlineEvidence:
+		report["evidence"] = appendix
+	case a.BlobStore == nil:
+		activity.GetLogger(ctx).Warn("Evidence too large for the report and no blob store to keep it",
+			"repos", len(appendix), "bytes", len(data))
+		report["evidence_omitted"] = len(appendix)
+	default:
+		info := activity.GetInfo(ctx)
+		key := fmt.Sprintf("%s/%s/evidence.json", info.WorkflowExecution.ID, info.WorkflowExecution.RunID)
+		uri, err := a.BlobStore.Put(ctx, key, data)
+		if err != nil {
+			return fmt.Errorf("storing evidence: %w", err)
+		}
+		report["evidence_ref"] = BlobRef{URI: uri, Count: len(appendix), Bytes: len(data), SHA256: sha256Hex(data)}
+	}
+	return nil
+}
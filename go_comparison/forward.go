@@ -0,0 +1,421 @@
+package scanner
+
+// =============================================================================
+// Findings forwarding — pushing results to Splunk's HTTP Event Collector
+// =============================================================================
+//
+// The SOC wants findings pushed rather than pulling report files. When the
+// worker has an HEC configured (Activities.HEC), the workflow's last step is
+// ForwardFindings, which sends the report to Splunk as one event per finding
+// (the OCSF events of ocsf.go) or one event per scanned repo. Events go in
+// POSTs of at most HECConfig.MaxBatchBytes; with UseAck each POST waits for
+// its indexer acknowledgment before the next one is sent.
+//
+// Forwarding never fails the scan. The report's forwarding section records
+// how many events were sent and how many failed:
+//
+//	5xx, 429     the batch is retried by the activity's retry policy, and the
+//	             heartbeat keeps the batches already sent so they are not
+//	             sent twice; on the last attempt the batch counts as failed
+//	401, 403     the token is wrong, so nothing else is sent (non-retryable)
+//	other 4xx    the batch counts as failed and the rest are still sent
+//	no ack       the batch counts as failed after AckTimeout
+//
+// Workers without an HEC return no result and the report has no forwarding
+// section.
+// =============================================================================
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// ForwardMode is what one forwarded event describes.
+type ForwardMode string
+
+// Forward modes.
+const (
+	ForwardPerFinding ForwardMode = "finding"
+	ForwardPerRepo    ForwardMode = "repo"
+)
+
+// Defaults for HECConfig's zero values.
+const (
+	// DefaultHECBatchBytes stays well under HEC's default max_content_length
+	// of 1 MB.
+	DefaultHECBatchBytes    = 512 * 1024
+	DefaultHECSource        = "temporal-security-scanner"
+	DefaultHECAckTimeout    = 30 * time.Second
+	DefaultHECAckPoll       = time.Second
+	hecEventPath            = "/services/collector/event"
+	hecAckPath              = "/services/collector/ack"
+	defaultHECSourceFinding = "ocsf:compliance_finding"
+	defaultHECSourceRepo    = "security_scan:repo"
+)
+
+// ErrTypeForwardUnauthorized is the ApplicationError type for an HEC token
+// Splunk rejects.
+const ErrTypeForwardUnauthorized = "FORWARD_UNAUTHORIZED"
+
+// HECConfig is a Splunk HTTP Event Collector to forward findings to.
+type HECConfig struct {
+	// URL is the collector's root, e.g. https://splunk.example.com:8088.
+	URL   string
+	Token string
+	// Index, Source and SourceType are set on every event. Empty Index uses
+	// the token's default index; empty SourceType depends on Mode.
+	Index      string
+	Source     string
+	SourceType string
+	Mode       ForwardMode
+	// UseAck waits for indexer acknowledgment of each batch, which the
+	// token must have enabled.
+	UseAck          bool
+	AckTimeout      time.Duration
+	AckPollInterval time.Duration
+	MaxBatchBytes   int
+	// HTTPClient defaults to the Activities' client.
+	HTTPClient *http.Client
+}
+
+// ForwardInput is the input to ForwardFindings.
+type ForwardInput struct {
+	Report map[string]interface{} `json:"report"`
+	// MaxAttempts is the activity's retry policy's; on that attempt a
+	// batch that fails with a 5xx counts as failed instead of retrying.
+	MaxAttempts int32 `json:"max_attempts,omitempty"`
+}
+
+// ForwardResult is the report's forwarding section.
+type ForwardResult struct {
+	Destination  string      `json:"destination"`
+	Mode         ForwardMode `json:"mode"`
+	EventsSent   int         `json:"events_sent"`
+	EventsFailed int         `json:"events_failed"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// forwardProgress is ForwardFindings' heartbeat.
+type forwardProgress struct {
+	NextBatch int           `json:"next_batch"`
+	Result    ForwardResult `json:"result"`
+}
+
+// ForwardFindings sends the report's findings to the worker's HEC. It
+// returns nil when the worker has none.
+func (a *Activities) ForwardFindings(ctx context.Context, in ForwardInput) (*ForwardResult, error) {
+	cfg := a.HEC
+	if cfg == nil || cfg.URL == "" {
+		return nil, nil
+	}
+	b, err := json.Marshal(in.Report)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("encoding report: "+err.Error(), ErrTypeInvalidInput, nil)
+	}
+	report, err := ParseReport(b)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("decoding report: "+err.Error(), ErrTypeInvalidInput, nil)
+	}
+	mode := cfg.Mode
+	if mode == "" {
+		mode = ForwardPerFinding
+	}
+	batches, err := cfg.batches(report, mode)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidInput, nil)
+	}
+
+	progress := forwardProgress{Result: ForwardResult{Destination: cfg.URL, Mode: mode}}
+	if activity.HasHeartbeatDetails(ctx) {
+		var prev forwardProgress
+		if err := activity.GetHeartbeatDetails(ctx, &prev); err == nil {
+			progress = prev
+		}
+	}
+	lastAttempt := in.MaxAttempts > 0 && activity.GetInfo(ctx).Attempt >= in.MaxAttempts
+	client := cfg.HTTPClient
+	if client == nil {
+		client = a.HTTPClient
+	}
+	channel := newHECChannel()
+	heartbeat := func() { activity.RecordHeartbeat(ctx, progress) }
+
+	for ; progress.NextBatch < len(batches); progress.NextBatch++ {
+		batch := batches[progress.NextBatch]
+		err := cfg.send(ctx, client, channel, batch.body, heartbeat)
+		var retryable *hecRetryableError
+		switch {
+		case err == nil:
+			progress.Result.EventsSent += batch.events
+		case errors.As(err, &retryable) && !lastAttempt:
+			return nil, err
+		case isNonRetryable(err):
+			// A rejected token fails every batch; stop here.
+			for _, rest := range batches[progress.NextBatch:] {
+				progress.Result.EventsFailed += rest.events
+			}
+			progress.Result.Error = err.Error()
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeForwardUnauthorized, nil, progress.Result)
+		default:
+			activity.GetLogger(ctx).Warn("HEC batch failed", "batch", progress.NextBatch, "events", batch.events, "error", err)
+			progress.Result.EventsFailed += batch.events
+			if progress.Result.Error == "" {
+				progress.Result.Error = err.Error()
+			}
+		}
+		// Record the batch as done before moving on, so a retry after a
+		// 5xx on the next one does not send it again.
+		activity.RecordHeartbeat(ctx, forwardProgress{NextBatch: progress.NextBatch + 1, Result: progress.Result})
+	}
+	return &progress.Result, nil
+}
+
+// hecBatch is one POST's worth of events.
+type hecBatch struct {
+	body   []byte
+	events int
+}
+
+// hecEvent is HEC's event envelope.
+type hecEvent struct {
+	Time       float64     `json:"time,omitempty"`
+	Source     string      `json:"source"`
+	SourceType string      `json:"sourcetype"`
+	Index      string      `json:"index,omitempty"`
+	Event      interface{} `json:"event"`
+}
+
+// RepoEvent is a forwarded event in ForwardPerRepo mode.
+type RepoEvent struct {
+	Org              string   `json:"org"`
+	Repository       string   `json:"repository"`
+	Provider         string   `json:"provider"`
+	Compliant        bool     `json:"compliant"`
+	FailedChecks     []string `json:"failed_checks"`
+	SuppressedChecks []string `json:"suppressed_checks,omitempty"`
+	RunID            string   `json:"run_id,omitempty"`
+	WorkflowID       string   `json:"workflow_id,omitempty"`
+}
+
+// repoEvents is one RepoEvent per repo in r's repo_failures, in name order.
+func repoEvents(r Report) []RepoEvent {
+	provider := r.Provider
+	if provider == "" {
+		provider = ProviderGitHub
+	}
+	suppressed := map[string][]string{}
+	for _, f := range r.Suppressed {
+		suppressed[f.Repository] = append(suppressed[f.Repository], f.Check)
+	}
+	failures := r.failures(r.RepoFailures == nil)
+	var events []RepoEvent
+	for _, repo := range sortedKeys(failures) {
+		events = append(events, RepoEvent{
+			Org:              r.Org,
+			Repository:       repo,
+			Provider:         provider,
+			Compliant:        len(failures[repo]) == 0,
+			FailedChecks:     append([]string{}, failures[repo]...),
+			SuppressedChecks: suppressed[repo],
+			RunID:            r.RunID,
+			WorkflowID:       r.WorkflowID,
+		})
+	}
+	return events
+}
+
+// batches encodes r's events in mode and packs them into batches.
+func (cfg *HECConfig) batches(r Report, mode ForwardMode) ([]hecBatch, error) {
+	var events []interface{}
+	sourceType := cfg.SourceType
+	switch mode {
+	case ForwardPerFinding:
+		for _, e := range OCSFFindings(r) {
+			events = append(events, e)
+		}
+		if sourceType == "" {
+			sourceType = defaultHECSourceFinding
+		}
+	case ForwardPerRepo:
+		for _, e := range repoEvents(r) {
+			events = append(events, e)
+		}
+		if sourceType == "" {
+			sourceType = defaultHECSourceRepo
+		}
+	default:
+		return nil, fmt.Errorf("unknown forward mode %q: want finding or repo", mode)
+	}
+	source := cfg.Source
+	if source == "" {
+		source = DefaultHECSource
+	}
+	var eventTime float64
+	if t, err := time.Parse(time.RFC3339, r.CompletedAt); err == nil {
+		eventTime = float64(t.Unix())
+	}
+	limit := cfg.MaxBatchBytes
+	if limit <= 0 {
+		limit = DefaultHECBatchBytes
+	}
+
+	var batches []hecBatch
+	var cur hecBatch
+	for _, e := range events {
+		b, err := json.Marshal(hecEvent{Time: eventTime, Source: source, SourceType: sourceType, Index: cfg.Index, Event: e})
+		if err != nil {
+			return nil, err
+		}
+		if cur.events > 0 && len(cur.body)+len(b)+1 > limit {
+			batches = append(batches, cur)
+			cur = hecBatch{}
+		}
+		cur.body = append(append(cur.body, b...), '\n')
+		cur.events++
+	}
+	if cur.events > 0 {
+		batches = append(batches, cur)
+	}
+	return batches, nil
+}
+
+// hecRetryableError is a batch HEC may accept if it is sent again.
+type hecRetryableError struct{ msg string }
+
+func (e *hecRetryableError) Error() string { return e.msg }
+
+// hecResponse is HEC's reply to an event POST.
+type hecResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+// send POSTs one batch and, with UseAck, waits for it to be indexed,
+// calling heartbeat while it waits.
+func (cfg *HECConfig) send(ctx context.Context, client *http.Client, channel string, body []byte, heartbeat func()) error {
+	resp, data, err := cfg.post(ctx, client, hecEventPath, channel, body)
+	if err != nil {
+		return &hecRetryableError{msg: "sending to HEC: " + err.Error()}
+	}
+	var out hecResponse
+	_ = json.Unmarshal(data, &out)
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("HEC rejected the token: %s (code %d)", out.Text, out.Code), ErrTypeForwardUnauthorized, nil)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return &hecRetryableError{msg: fmt.Sprintf("HEC returned %d: %s", resp.StatusCode, out.Text)}
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("HEC returned %d: %s (code %d)", resp.StatusCode, out.Text, out.Code)
+	}
+	if !cfg.UseAck {
+		return nil
+	}
+	if out.AckID == nil {
+		return fmt.Errorf("HEC returned no ackId; is indexer acknowledgment enabled for the token?")
+	}
+	return cfg.waitForAck(ctx, client, channel, *out.AckID, heartbeat)
+}
+
+// waitForAck polls the ack endpoint until id is acknowledged.
+func (cfg *HECConfig) waitForAck(ctx context.Context, client *http.Client, channel string, id int64, heartbeat func()) error {
+	timeout, interval := cfg.AckTimeout, cfg.AckPollInterval
+	if timeout <= 0 {
+		timeout = DefaultHECAckTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultHECAckPoll
+	}
+	body, _ := json.Marshal(map[string][]int64{"acks": {id}})
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, data, err := cfg.post(ctx, client, hecAckPath, channel, body)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			var out struct {
+				Acks map[string]bool `json:"acks"`
+			}
+			if json.Unmarshal(data, &out) == nil && out.Acks[strconv.FormatInt(id, 10)] {
+				return nil
+			}
+		}
+		heartbeat()
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("HEC did not acknowledge batch %d within %s", id, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (cfg *HECConfig) post(ctx context.Context, client *http.Client, path, channel string, body []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(cfg.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", "Splunk "+cfg.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Splunk-Request-Channel", channel)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return resp, data, err
+}
+
+// newHECChannel is a random channel GUID; HEC needs one for
+// acknowledgments.
+func newHECChannel() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// forwardFindings runs ForwardFindings on the report and returns what the
+// report's forwarding section should say, or nil if the worker forwards
+// nowhere. Failures are logged and recorded, never returned.
+func forwardFindings(ctx workflow.Context, report map[string]interface{}) *ForwardResult {
+	retryPolicy := githubRetryPolicy()
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		HeartbeatTimeout:    DefaultHECAckTimeout + time.Minute,
+		RetryPolicy:         retryPolicy,
+	})
+	var result *ForwardResult
+	err := workflow.ExecuteActivity(actCtx, "ForwardFindings", ForwardInput{
+		Report:      report,
+		MaxAttempts: retryPolicy.MaximumAttempts,
+	}).Get(ctx, &result)
+	if err == nil {
+		return result
+	}
+	workflow.GetLogger(ctx).Warn("Forwarding findings failed", "error", err)
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) && appErr.HasDetails() {
+		var partial ForwardResult
+		if appErr.Details(&partial) == nil {
+			return &partial
+		}
+	}
+	return &ForwardResult{Error: err.Error()}
+}
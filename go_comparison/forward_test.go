@@ -0,0 +1,303 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+)
+
+// fakeHEC is an httptest Splunk HTTP Event Collector. Each POST to the
+// event endpoint takes the next status from statuses (200 once they run
+// out); acked decides whether the ack endpoint confirms a batch.
+type fakeHEC struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	acked    bool
+	batches  [][]map[string]interface{}
+	auth     []string
+	ackPolls int
+}
+
+func newFakeHEC(t *testing.T, statuses ...int) (*fakeHEC, *HECConfig) {
+	t.Helper()
+	f := &fakeHEC{t: t, statuses: statuses, acked: true}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, &HECConfig{URL: srv.URL, Token: "hec-token", HTTPClient: srv.Client(), AckPollInterval: time.Millisecond}
+}
+
+func (f *fakeHEC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	switch r.URL.Path {
+	case hecAckPath:
+		f.ackPolls++
+		var req struct {
+			Acks []int64 `json:"acks"`
+		}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+		acks := map[string]bool{}
+		for _, id := range req.Acks {
+			acks[strconv.FormatInt(id, 10)] = f.acked
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"acks": acks})
+	case hecEventPath:
+		status := http.StatusOK
+		if len(f.statuses) > 0 {
+			status, f.statuses = f.statuses[0], f.statuses[1:]
+		}
+		w.WriteHeader(status)
+		switch status {
+		case http.StatusOK:
+			var events []map[string]interface{}
+			dec := json.NewDecoder(r.Body)
+			for {
+				var e map[string]interface{}
+				if err := dec.Decode(&e); errors.Is(err, io.EOF) {
+					break
+				} else {
+					require.NoError(f.t, err)
+				}
+				events = append(events, e)
+			}
+			f.batches = append(f.batches, events)
+			_, _ = w.Write([]byte(`{"text":"Success","code":0,"ackId":` + strconv.Itoa(len(f.batches)-1) + `}`))
+		case http.StatusForbidden:
+			_, _ = w.Write([]byte(`{"text":"Invalid token","code":4}`))
+		case http.StatusBadRequest:
+			_, _ = w.Write([]byte(`{"text":"Invalid data format","code":6}`))
+		default:
+			_, _ = w.Write([]byte(`{"text":"Server is busy","code":9}`))
+		}
+	default:
+		f.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeHEC) Batches() [][]map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.batches
+}
+
+// forwardReport is ocsfFixture as the workflow passes it on.
+func forwardReport(t *testing.T) map[string]interface{} {
+	t.Helper()
+	b, err := json.Marshal(ocsfFixture())
+	require.NoError(t, err)
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &m))
+	return m
+}
+
+func runForward(t *testing.T, cfg *HECConfig, in ForwardInput) (*ForwardResult, error) {
+	t.Helper()
+	a := &Activities{HEC: cfg}
+	val, err := newActivityEnv(a).ExecuteActivity(a.ForwardFindings, in)
+	if err != nil {
+		return nil, err
+	}
+	var out *ForwardResult
+	if val.HasValue() {
+		require.NoError(t, val.Get(&out))
+	}
+	return out, nil
+}
+
+func TestForwardFindingsBatches(t *testing.T) {
+	f, cfg := newFakeHEC(t)
+	// Room for two of the fixture's four findings per POST.
+	cfg.MaxBatchBytes = 2200
+	cfg.Index = "security"
+
+	out, err := runForward(t, cfg, ForwardInput{Report: forwardReport(t)})
+	require.NoError(t, err)
+	require.Equal(t, &ForwardResult{Destination: cfg.URL, Mode: ForwardPerFinding, EventsSent: 4}, out)
+
+	batches := f.Batches()
+	require.Len(t, batches, 2)
+	require.Len(t, batches[0], 2)
+	first := batches[0][0]
+	require.Equal(t, "security", first["index"])
+	require.Equal(t, DefaultHECSource, first["source"])
+	require.Equal(t, "ocsf:compliance_finding", first["sourcetype"])
+	require.Equal(t, float64(1772460200), first["time"])
+	require.Equal(t, "github:acme/api:codeowners", first["event"].(map[string]interface{})["finding_info"].(map[string]interface{})["uid"])
+	for _, auth := range f.auth {
+		require.Equal(t, "Splunk hec-token", auth)
+	}
+}
+
+func TestForwardFindingsPerRepo(t *testing.T) {
+	f, cfg := newFakeHEC(t)
+	cfg.Mode = ForwardPerRepo
+
+	out, err := runForward(t, cfg, ForwardInput{Report: forwardReport(t)})
+	require.NoError(t, err)
+	require.Equal(t, 4, out.EventsSent, "one event per scanned repo")
+
+	var repos []string
+	for _, e := range f.Batches()[0] {
+		event := e["event"].(map[string]interface{})
+		repos = append(repos, event["repository"].(string))
+		require.Equal(t, "security_scan:repo", e["sourcetype"])
+		if event["repository"] == "mirror" {
+			require.Equal(t, true, event["compliant"])
+			require.Equal(t, []interface{}{CheckCodeScanning}, event["suppressed_checks"])
+		}
+	}
+	require.Equal(t, []string{"api", "docs", "mirror", "web"}, repos)
+}
+
+func TestForwardFindingsAck(t *testing.T) {
+	f, cfg := newFakeHEC(t)
+	cfg.UseAck = true
+	out, err := runForward(t, cfg, ForwardInput{Report: forwardReport(t)})
+	require.NoError(t, err)
+	require.Equal(t, 4, out.EventsSent)
+	require.Equal(t, 1, f.ackPolls)
+
+	// Unacknowledged batches count as failed once AckTimeout passes.
+	f, cfg = newFakeHEC(t)
+	f.acked = false
+	cfg.UseAck = true
+	cfg.AckTimeout = 20 * time.Millisecond
+	out, err = runForward(t, cfg, ForwardInput{Report: forwardReport(t)})
+	require.NoError(t, err)
+	require.Equal(t, 0, out.EventsSent)
+	require.Equal(t, 4, out.EventsFailed)
+	require.Contains(t, out.Error, "did not acknowledge")
+}
+
+func TestForwardFindingsServerErrors(t *testing.T) {
+	// Before the last attempt a 5xx fails the activity so it is retried,
+	// and the heartbeat says which batches were already sent.
+	f, cfg := newFakeHEC(t, http.StatusOK, http.StatusServiceUnavailable)
+	cfg.MaxBatchBytes = 2200
+	a := &Activities{HEC: cfg}
+	env := newActivityEnv(a)
+	var heartbeat forwardProgress
+	env.SetOnActivityHeartbeatListener(func(_ *activity.Info, details converter.EncodedValues) {
+		require.NoError(t, details.Get(&heartbeat))
+	})
+	_, err := env.ExecuteActivity(a.ForwardFindings, ForwardInput{Report: forwardReport(t), MaxAttempts: 5})
+	require.Error(t, err)
+	require.Equal(t, 1, heartbeat.NextBatch)
+	require.Equal(t, 2, heartbeat.Result.EventsSent)
+
+	// The retry resumes from the heartbeat.
+	env = newActivityEnv(a)
+	env.SetHeartbeatDetails(heartbeat)
+	val, err := env.ExecuteActivity(a.ForwardFindings, ForwardInput{Report: forwardReport(t), MaxAttempts: 5})
+	require.NoError(t, err)
+	var out ForwardResult
+	require.NoError(t, val.Get(&out))
+	require.Equal(t, 4, out.EventsSent)
+	require.Len(t, f.Batches(), 2, "the first batch is not sent twice")
+
+	// On the last attempt the batch counts as failed.
+	_, cfg = newFakeHEC(t, http.StatusServiceUnavailable)
+	cfg.MaxBatchBytes = 2200
+	result, err := runForward(t, cfg, ForwardInput{Report: forwardReport(t), MaxAttempts: 1})
+	require.NoError(t, err)
+	require.Equal(t, 2, result.EventsSent)
+	require.Equal(t, 2, result.EventsFailed)
+	require.Contains(t, result.Error, "HEC returned 503")
+}
+
+func TestForwardFindingsClientErrors(t *testing.T) {
+	// A bad batch is skipped; the rest are still sent.
+	_, cfg := newFakeHEC(t, http.StatusBadRequest)
+	cfg.MaxBatchBytes = 2200
+	out, err := runForward(t, cfg, ForwardInput{Report: forwardReport(t), MaxAttempts: 5})
+	require.NoError(t, err)
+	require.Equal(t, 2, out.EventsSent)
+	require.Equal(t, 2, out.EventsFailed)
+	require.Contains(t, out.Error, "Invalid data format")
+
+	// A rejected token stops forwarding without retries.
+	f, cfg := newFakeHEC(t, http.StatusForbidden)
+	_, err = runForward(t, cfg, ForwardInput{Report: forwardReport(t), MaxAttempts: 5})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, ErrTypeForwardUnauthorized, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+	var partial ForwardResult
+	require.NoError(t, appErr.Details(&partial))
+	require.Equal(t, 4, partial.EventsFailed)
+	require.Empty(t, f.Batches())
+}
+
+func TestForwardFindingsNotConfigured(t *testing.T) {
+	out, err := runForward(t, nil, ForwardInput{Report: forwardReport(t)})
+	require.NoError(t, err)
+	require.Nil(t, out)
+}
+
+func TestWorkflowRecordsForwarding(t *testing.T) {
+	for name, tc := range map[string]struct {
+		result *ForwardResult
+		err    error
+		want   map[string]interface{}
+	}{
+		"sent": {
+			result: &ForwardResult{Destination: "https://splunk:8088", Mode: ForwardPerFinding, EventsSent: 3, EventsFailed: 1, Error: "HEC returned 400"},
+			want: map[string]interface{}{"destination": "https://splunk:8088", "mode": "finding",
+				"events_sent": float64(3), "events_failed": float64(1), "error": "HEC returned 400"},
+		},
+		"token rejected": {
+			err: temporal.NewNonRetryableApplicationError("HEC rejected the token", ErrTypeForwardUnauthorized, nil,
+				ForwardResult{Destination: "https://splunk:8088", Mode: ForwardPerFinding, EventsFailed: 4, Error: "HEC rejected the token"}),
+			want: map[string]interface{}{"destination": "https://splunk:8088", "mode": "finding",
+				"events_sent": float64(0), "events_failed": float64(4), "error": "HEC rejected the token"},
+		},
+		"not configured": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnv(t)
+			onListOrgRepos(env, fakeRepos(3))
+			env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(compliantUnless("repo-001"))
+			env.OnActivity("ForwardFindings", mock.Anything, mock.Anything).
+				Return(func(_ context.Context, in ForwardInput) (*ForwardResult, error) {
+					require.Equal(t, []interface{}{"repo-001"}, in.Report["non_compliant_repos"])
+					return tc.result, tc.err
+				})
+
+			env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+			require.NoError(t, env.GetWorkflowError(), "forwarding never fails the scan")
+			var report map[string]interface{}
+			require.NoError(t, env.GetWorkflowResult(&report))
+			if tc.want == nil {
+				require.NotContains(t, report, "forwarding")
+				return
+			}
+			require.Equal(t, tc.want, report["forwarding"])
+		})
+	}
+}
+
+func TestNewHECChannel(t *testing.T) {
+	c := newHECChannel()
+	require.Len(t, c, 36)
+	require.Equal(t, 4, strings.Count(c, "-"))
+	require.NotEqual(t, c, newHECChannel())
+}
@@ -0,0 +1,125 @@
+package scanner
+
+// =============================================================================
+// Provider error bodies — what of a failed response goes into an error
+// =============================================================================
+//
+// Activity errors are recorded in the workflow history, and from there in
+// the report's repo errors. A raw response body does not belong there: an
+// HTML error page or a large JSON document bloats the history, and a proxy
+// or misconfigured setup can echo the request URL, token query parameters
+// included. Errors for an unexpected response therefore carry only the
+// status, the provider's "message" and GitHub's "documentation_url", with
+// anything that looks like a credential redacted and the whole message
+// capped at maxErrorMessageLength. They are ApplicationErrors of the
+// ErrorCategory the status falls under (see scanerror.go), with the status
+// as details.
+// =============================================================================
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxErrorMessageLength caps an error message built from a response, in
+// bytes.
+const maxErrorMessageLength = 300
+
+// redacted replaces a credential in an error message.
+const redacted = "[REDACTED]"
+
+// credentialPatterns match credentials that may appear in a response body
+// or a URL, each with what replaces it.
+var credentialPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// GitHub tokens (classic, fine-grained, app, OAuth) and GitLab tokens.
+	{regexp.MustCompile(`\b(?:gh[pousr]_|github_pat_|glpat-)[A-Za-z0-9_-]{8,}`), redacted},
+	// Credentials in a query string.
+	{regexp.MustCompile(`(?i)\b((?:access_token|private_token|client_secret|token|key|sig|signature|password)=)[^&\s"'<>]+`), "${1}" + redacted},
+	// Authorization header values.
+	{regexp.MustCompile(`(?i)\b((?:bearer|token|basic)\s+)[A-Za-z0-9._~+/=-]{16,}`), "${1}" + redacted},
+	// User info in a URL.
+	{regexp.MustCompile(`://[^/\s:@]+:[^/\s@]+@`), "://" + redacted + "@"},
+}
+
+// redactCredentials replaces everything in s that looks like a credential.
+func redactCredentials(s string) string {
+	for _, p := range credentialPatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
+
+// sanitizeErrorMessage redacts s and cuts it to maxErrorMessageLength,
+// on a rune boundary.
+func sanitizeErrorMessage(s string) string {
+	s = redactCredentials(s)
+	if len(s) <= maxErrorMessageLength {
+		return s
+	}
+	cut := maxErrorMessageLength - len("...")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+// responseSummary is "unexpected status N", followed by the body's message
+// and documentation URL when it is a JSON error.
+func responseSummary(status int, body []byte) string {
+	summary := fmt.Sprintf("unexpected status %d", status)
+	var e struct {
+		Message          string `json:"message"`
+		DocumentationURL string `json:"documentation_url"`
+	}
+	if json.Unmarshal(body, &e) != nil {
+		return summary
+	}
+	if msg := strings.Join(strings.Fields(e.Message), " "); msg != "" {
+		summary += ": " + msg
+	}
+	if e.DocumentationURL != "" {
+		summary += " (" + e.DocumentationURL + ")"
+	}
+	return summary
+}
+
+// statusCategory is the ErrorCategory of an unexpected response status.
+// body tells a GitHub permission 403 from a rate limit.
+func statusCategory(status int, body []byte) ErrorCategory {
+	switch {
+	case status == http.StatusNotFound:
+		return ErrorNotFound
+	case status == http.StatusUnauthorized, status == http.StatusForbidden && permissionDenied(body):
+		return ErrorNoAccess
+	case status == http.StatusForbidden, status == http.StatusTooManyRequests:
+		return ErrorRateLimit
+	case status < 500:
+		return ErrorUnknown
+	}
+	return ErrorServerError
+}
+
+// statusError is the error for an unexpected response status to a request
+// about what.
+func statusError(what string, status int, body []byte) error {
+	msg := sanitizeErrorMessage(what + ": " + responseSummary(status, body))
+	return newCheckError(statusCategory(status, body), status, nil, "%s", msg)
+}
+
+// wrapGitHubError is the error for GitHub's unexpected response resp, whose
+// body has been read. It names the request by method and path; the query
+// string is left out.
+func wrapGitHubError(resp *http.Response, body []byte) error {
+	what := "GitHub request"
+	if req := resp.Request; req != nil && req.URL != nil {
+		what = req.Method + " " + req.URL.Path
+	}
+	return statusError(what, resp.StatusCode, body)
+}
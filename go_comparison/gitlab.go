@@ -0,0 +1,372 @@
+package scanner
+
+// =============================================================================
+// GitLab — the scanner's checks mapped onto GitLab's closest equivalents
+// =============================================================================
+//
+// GitLab has no per-project switch for its security scanners: Secret
+// Detection, Dependency Scanning, and SAST run when the project's pipeline
+// includes them. A project counts as having a scanner when Auto DevOps is on
+// (which runs all three) or when its CI configuration includes the
+// scanner's template, CI/CD component, or job. Files the configuration
+// includes from elsewhere are not followed, so a scanner pulled in that way
+// reads as disabled.
+//
+//	secret_scanning  Secret Detection
+//	dependabot       Dependency Scanning
+//	code_scanning    SAST
+//	files            CODEOWNERS and SECURITY.md (root, docs/, .gitlab/)
+//
+// ScanInput.Org is a group path ("acme" or "acme/platform") and repo names
+// are project paths relative to it, so a subgroup's project reads
+// "team/app". The token is sent as PRIVATE-TOKEN and needs read_api.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// DefaultGitLabAPI is the GitLab.com REST API root.
+const DefaultGitLabAPI = "https://gitlab.com/api/v4"
+
+// GitLabOptions are the ScanInput fields specific to ProviderGitLab.
+type GitLabOptions struct {
+	// IncludeSubgroups also scans the projects of the group's subgroups.
+	IncludeSubgroups bool `json:"include_subgroups,omitempty"`
+}
+
+// gitlabGroupPart matches one segment of a GitLab group path.
+var gitlabGroupPart = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// ValidateGitLabGroup checks that group is a possible GitLab group path:
+// one or more "/"-separated segments of letters, digits, "_", "-", and ".".
+func ValidateGitLabGroup(group string) error {
+	if group == "" {
+		return fmt.Errorf("group path is empty")
+	}
+	for _, part := range strings.Split(group, "/") {
+		if !gitlabGroupPart.MatchString(part) || strings.HasSuffix(part, ".") {
+			return fmt.Errorf("group %q is not a GitLab group path", group)
+		}
+	}
+	return nil
+}
+
+// gitlabProvider is the GitLab implementation of Provider.
+type gitlabProvider struct{ a *Activities }
+
+// apiURL joins a path onto Activities.GitLabURL (or DefaultGitLabAPI).
+func (p gitlabProvider) apiURL(format string, args ...interface{}) string {
+	base := p.a.GitLabURL
+	if base == "" {
+		base = DefaultGitLabAPI
+	}
+	return strings.TrimRight(base, "/") + fmt.Sprintf(format, args...)
+}
+
+// headers are the headers of a request made with cred, whose reference
+// is resolved. Only pat credentials reach here (see validateCredential).
+func (p gitlabProvider) headers(ctx context.Context, cred *Credential) (map[string]string, error) {
+	cred, err := p.a.resolveCredential(ctx, cred)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{"Accept": "application/json"}
+	if cred != nil {
+		headers["PRIVATE-TOKEN"] = cred.Value
+	}
+	return headers, nil
+}
+
+// gitlabProject is the part of GitLab's project resource the scanner reads.
+type gitlabProject struct {
+	Path              string     `json:"path"`
+	PathWithNamespace string     `json:"path_with_namespace"`
+	Visibility        string     `json:"visibility"`
+	Archived          bool       `json:"archived"`
+	LastActivityAt    *time.Time `json:"last_activity_at"`
+	DefaultBranch     string     `json:"default_branch"`
+	Topics            []string   `json:"topics"`
+	ForkedFrom        *struct{}  `json:"forked_from_project"`
+	AutoDevOpsEnabled bool       `json:"auto_devops_enabled"`
+	CIConfigPath      string     `json:"ci_config_path"`
+}
+
+// repoInfo names the project relative to group. GitLab reports no push
+// time, so last activity stands in for it, and no language in a listing.
+func (pr gitlabProject) repoInfo(group string) RepoInfo {
+	name := pr.Path
+	prefix := group + "/"
+	if len(pr.PathWithNamespace) > len(prefix) && strings.EqualFold(pr.PathWithNamespace[:len(prefix)], prefix) {
+		name = pr.PathWithNamespace[len(prefix):]
+	}
+	return RepoInfo{
+		Name:      name,
+		FullName:  pr.PathWithNamespace,
+		Private:   pr.Visibility != "public",
+		Archived:  pr.Archived,
+		PushedAt:  pr.LastActivityAt,
+		UpdatedAt: pr.LastActivityAt,
+		RepoMetadata: RepoMetadata{
+			DefaultBranch: pr.DefaultBranch,
+			Fork:          pr.ForkedFrom != nil,
+			Visibility:    pr.Visibility,
+			Topics:        pr.Topics,
+		},
+	}
+}
+
+// ListRepos lists the group's projects, and its subgroups' when
+// GitLabOptions.IncludeSubgroups is set.
+func (p gitlabProvider) ListRepos(ctx context.Context, input ScanInput) ([]RepoInfo, error) {
+	subgroups := input.GitLab != nil && input.GitLab.IncludeSubgroups
+	headers, err := p.headers(ctx, input.credential())
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []RepoInfo
+	for page := 1; ; page++ {
+		activity.RecordHeartbeat(ctx, fmt.Sprintf("Fetching page %d", page))
+
+		status, body, err := p.a.checkEndpoint(ctx, p.apiURL("/groups/%s/projects?per_page=100&page=%d&include_subgroups=%t",
+			url.PathEscape(input.Org), page, subgroups), headers)
+		if err != nil {
+			return nil, fmt.Errorf("fetching projects page %d: %w", page, err)
+		}
+		switch status {
+		case http.StatusOK:
+		case http.StatusNotFound:
+			return nil, temporal.NewNonRetryableApplicationError(
+				fmt.Sprintf("group '%s' not found", input.Org), "NOT_FOUND", nil)
+		case http.StatusUnauthorized:
+			return nil, temporal.NewNonRetryableApplicationError("invalid GitLab API token", "UNAUTHORIZED", nil)
+		default:
+			return nil, statusError("listing the projects of "+input.Org, status, body)
+		}
+
+		var projects []gitlabProject
+		if err := json.Unmarshal(body, &projects); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+		for _, pr := range projects {
+			repos = append(repos, pr.repoInfo(input.Org))
+		}
+		if len(projects) < 100 {
+			break
+		}
+	}
+
+	activity.GetLogger(ctx).Info("Fetched projects", "count", len(repos), "group", input.Org)
+	return repos, nil
+}
+
+// CheckRepo runs the selected checks on one project. A project that does
+// not exist is reported with Error set, as on GitHub.
+func (p gitlabProvider) CheckRepo(ctx context.Context, in RepoCheckInput) (*RepoSecurityResult, error) {
+	selected := newCheckSet(in.Checks)
+	result := &RepoSecurityResult{
+		Repository:       in.Repo,
+		Provider:         ProviderGitLab,
+		SecretScanning:   StatusUnknown,
+		DependabotAlerts: StatusUnknown,
+		CodeScanning:     StatusUnknown,
+		ScannedAt:        time.Now().UTC().Format(time.RFC3339),
+	}
+	headers, err := p.headers(ctx, in.Credential)
+	if err != nil {
+		return nil, err
+	}
+	projectURL := p.apiURL("/projects/%s", url.PathEscape(in.Org+"/"+in.Repo))
+
+	// The project and its CI configuration decide the scanner checks.
+	scannersCtx := evidenceFor(ctx, CheckSecretScanning, CheckDependabot, CheckCodeScanning)
+	status, body, err := p.a.checkEndpoint(scannersCtx, projectURL, headers)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// Deleted, or hidden from the token, since the listing.
+		result.setDisappeared(DisappearedDeleted, "", status)
+		return result, nil
+	case http.StatusUnauthorized:
+		return nil, temporal.NewNonRetryableApplicationError("invalid GitLab API token", "UNAUTHORIZED", nil)
+	default:
+		return nil, statusError("reading project "+in.Repo, status, body)
+	}
+	var project gitlabProject
+	if err := json.Unmarshal(body, &project); err != nil {
+		return nil, parseError("project "+in.Repo, err)
+	}
+
+	if selected[CheckSecretScanning] || selected[CheckDependabot] || selected[CheckCodeScanning] {
+		ci, err := p.readCIConfig(scannersCtx, projectURL, project, headers)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range gitlabScanners {
+			if selected[s.check] {
+				result.setCheck(s.check, ci.result(s))
+			}
+		}
+	}
+
+	if selected[CheckFiles] {
+		for _, f := range []struct{ key, name string }{
+			{ResultCodeowners, "CODEOWNERS"},
+			{ResultSecurityPolicy, "SECURITY.md"},
+		} {
+			found, err := p.probeFile(evidenceFor(ctx, f.key), projectURL, project.DefaultBranch, f.name, headers)
+			if err != nil {
+				return nil, err
+			}
+			result.setCheck(f.key, presenceResult(found))
+		}
+	}
+
+	activity.GetLogger(ctx).Info("Checked project security",
+		"repo", in.Repo,
+		"secret_scanning", result.SecretScanning,
+		"dependabot", result.DependabotAlerts,
+		"code_scanning", result.CodeScanning,
+	)
+	return result, nil
+}
+
+// gitlabScanner maps a check onto a GitLab security scanner.
+type gitlabScanner struct {
+	check string
+	name  string
+	// markers are lowercase strings whose presence in the CI configuration
+	// means the scanner runs: its templates, its component, or its job.
+	markers []string
+	// missing is the status when the scanner does not run, matching what
+	// the GitHub check reports for the same situation.
+	missing SecurityStatus
+}
+
+var gitlabScanners = []gitlabScanner{
+	{CheckSecretScanning, "Secret Detection",
+		[]string{"secret-detection.gitlab-ci.yml", "components/secret-detection", "secret_detection:"}, StatusDisabled},
+	{CheckDependabot, "Dependency Scanning",
+		[]string{"dependency-scanning.gitlab-ci.yml", "components/dependency-scanning", "dependency_scanning:"}, StatusDisabled},
+	{CheckCodeScanning, "SAST",
+		[]string{"sast.gitlab-ci.yml", "components/sast", "sast:"}, StatusNotConfigured},
+}
+
+// gitlabCI is what a project's pipeline says about its scanners.
+type gitlabCI struct {
+	autoDevOps bool
+	path       string
+	// status is the response to reading the configuration; 0 means it
+	// could not be read from this project.
+	status int
+	config string
+}
+
+// readCIConfig reads the project's CI configuration from its default
+// branch. Auto DevOps makes the configuration irrelevant, and a project
+// without a default branch is empty and has none.
+func (p gitlabProvider) readCIConfig(ctx context.Context, projectURL string, project gitlabProject, headers map[string]string) (gitlabCI, error) {
+	ci := gitlabCI{autoDevOps: project.AutoDevOpsEnabled, path: project.CIConfigPath}
+	if ci.path == "" {
+		ci.path = ".gitlab-ci.yml"
+	}
+	switch {
+	case ci.autoDevOps:
+		return ci, nil
+	case project.DefaultBranch == "":
+		ci.status = http.StatusNotFound
+		return ci, nil
+	case strings.Contains(ci.path, "@") || strings.Contains(ci.path, "://"):
+		// Kept in another project or served remotely.
+		return ci, nil
+	}
+	status, body, err := p.a.checkEndpoint(ctx, fmt.Sprintf("%s/repository/files/%s/raw?ref=%s",
+		projectURL, url.PathEscape(ci.path), url.QueryEscape(project.DefaultBranch)), headers)
+	if err != nil {
+		return ci, err
+	}
+	ci.status = status
+	if status == http.StatusOK {
+		ci.config = strings.ToLower(string(body))
+	}
+	return ci, nil
+}
+
+// result maps the pipeline onto the check's SecurityStatus.
+func (ci gitlabCI) result(s gitlabScanner) CheckResult {
+	if ci.autoDevOps {
+		return CheckResult{Status: StatusEnabled, Message: "Auto DevOps"}
+	}
+	switch ci.status {
+	case http.StatusOK:
+		for _, m := range s.markers {
+			if strings.Contains(ci.config, m) {
+				return CheckResult{Status: StatusEnabled}
+			}
+		}
+		return CheckResult{Status: s.missing, Message: fmt.Sprintf("%s is not in %s", s.name, ci.path)}
+	case http.StatusNotFound:
+		return CheckResult{Status: s.missing, Message: "no CI configuration"}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return CheckResult{Status: StatusNoAccess, Message: "cannot read " + ci.path}
+	case 0:
+		return CheckResult{Status: StatusUnknown, Message: "CI configuration is outside the project: " + ci.path}
+	}
+	return CheckResult{Status: StatusUnknown}
+}
+
+// gitlabFileDirs are the locations GitLab recognizes for CODEOWNERS, in
+// the order it looks them up; SECURITY.md is looked for in the same places.
+var gitlabFileDirs = []string{"", "docs/", ".gitlab/"}
+
+// probeFile reports whether a non-empty file named name exists on ref in
+// any of gitlabFileDirs, or nil (unknown) when a location could not be
+// read and the file was not found elsewhere. An empty project has no files.
+func (p gitlabProvider) probeFile(ctx context.Context, projectURL, ref, name string, headers map[string]string) (*bool, error) {
+	found := false
+	if ref == "" {
+		return &found, nil
+	}
+	unknown := false
+	for _, dir := range gitlabFileDirs {
+		status, body, err := p.a.checkEndpoint(ctx, fmt.Sprintf("%s/repository/files/%s?ref=%s",
+			projectURL, url.PathEscape(dir+name), url.QueryEscape(ref)), headers)
+		if err != nil {
+			return nil, err
+		}
+		switch status {
+		case http.StatusOK:
+			var file struct {
+				Size int `json:"size"`
+			}
+			if err := json.Unmarshal(body, &file); err != nil {
+				return nil, parseError("file "+dir+name, err)
+			}
+			if file.Size > 0 {
+				found = true
+				return &found, nil
+			}
+		case http.StatusNotFound:
+		default:
+			unknown = true
+		}
+	}
+	if unknown {
+		return nil, nil
+	}
+	return &found, nil
+}
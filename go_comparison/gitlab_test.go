@@ -0,0 +1,262 @@
+package scanner
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+// newFakeGitLab is newFakeGitHub for GitLab: fixtures come from
+// testdata/gitlab and Activities.GitLabURL points at the server.
+func newFakeGitLab(t *testing.T, routes map[string]fakeResponse) (*fakeGitHub, *Activities) {
+	t.Helper()
+	f := &fakeGitHub{t: t, routes: routes, dir: "gitlab"}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, &Activities{HTTPClient: srv.Client(), GitLabURL: srv.URL + "/api/v4"}
+}
+
+const gitlabProjectPath = "/api/v4/projects/acme%2Fapi"
+
+// gitlabNoFiles returns routes for a project on main with neither
+// CODEOWNERS nor SECURITY.md.
+func gitlabNoFiles() map[string]fakeResponse {
+	routes := map[string]fakeResponse{}
+	for _, dir := range gitlabFileDirs {
+		for _, name := range []string{"CODEOWNERS", "SECURITY.md"} {
+			routes[gitlabFilePath(dir+name)] = fakeResponse{http.StatusNotFound, "file_not_found.json"}
+		}
+	}
+	return routes
+}
+
+func gitlabFilePath(path string) string {
+	return gitlabProjectPath + "/repository/files/" + strings.ReplaceAll(path, "/", "%2F") + "?ref=main"
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestGitLabFetchOrgRepos(t *testing.T) {
+	f, a := newFakeGitLab(t, map[string]fakeResponse{
+		"/api/v4/groups/acme/projects?per_page=100&page=1&include_subgroups=true": {http.StatusOK, "group_projects.json"},
+	})
+	env := newActivityEnv(a)
+
+	token := "glpat-test"
+	input := ScanInput{Org: "acme", Token: &token, Provider: ProviderGitLab, GitLab: &GitLabOptions{IncludeSubgroups: true}}
+	val, err := env.ExecuteActivity(a.FetchOrgRepos, input)
+	require.NoError(t, err)
+
+	var repos []RepoInfo
+	require.NoError(t, val.Get(&repos))
+	require.Len(t, repos, 3)
+	require.Equal(t, "api", repos[0].Name)
+	require.False(t, repos[0].Private)
+	require.Equal(t, "platform/billing", repos[1].Name, "subgroup projects are named relative to the group")
+	require.Equal(t, "acme/platform/billing", repos[1].FullName)
+	require.True(t, repos[1].Private)
+	require.True(t, repos[2].Private, "internal projects are not public")
+	require.True(t, repos[2].Archived)
+	require.NotNil(t, repos[0].PushedAt)
+	require.Equal(t, RepoMetadata{DefaultBranch: "main", Visibility: "public", Topics: []string{"payments"}}, repos[0].RepoMetadata)
+	require.True(t, repos[1].Fork)
+	require.Equal(t, "internal", repos[2].Visibility)
+	require.Equal(t, "master", repos[2].DefaultBranch)
+
+	require.Equal(t, token, f.Requests()[0].Header.Get("PRIVATE-TOKEN"))
+}
+
+func TestGitLabFetchOrgReposErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     fakeResponse
+		wantType string
+	}{
+		{"group not found", fakeResponse{http.StatusNotFound, "group_not_found.json"}, "NOT_FOUND"},
+		{"bad token", fakeResponse{http.StatusUnauthorized, "unauthorized.json"}, "UNAUTHORIZED"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, a := newFakeGitLab(t, map[string]fakeResponse{
+				"/api/v4/groups/acme%2Fplatform/projects?per_page=100&page=1&include_subgroups=false": tc.resp,
+			})
+			env := newActivityEnv(a)
+
+			_, err := env.ExecuteActivity(a.FetchOrgRepos, ScanInput{Org: "acme/platform", Provider: ProviderGitLab})
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(err, &appErr))
+			require.Equal(t, tc.wantType, appErr.Type())
+			require.True(t, appErr.NonRetryable())
+		})
+	}
+}
+
+// TestGitLabStatusMapping pins how a project's pipeline maps onto the
+// provider-neutral statuses, scanner by scanner.
+func TestGitLabStatusMapping(t *testing.T) {
+	secret, dependency, sast := gitlabScanners[0], gitlabScanners[1], gitlabScanners[2]
+	tests := []struct {
+		name    string
+		ci      gitlabCI
+		scanner gitlabScanner
+		want    SecurityStatus
+	}{
+		{"auto devops runs secret detection", gitlabCI{autoDevOps: true}, secret, StatusEnabled},
+		{"auto devops runs sast", gitlabCI{autoDevOps: true}, sast, StatusEnabled},
+		{"template include", gitlabCI{status: 200, config: "include:\n  - template: security/secret-detection.gitlab-ci.yml\n"}, secret, StatusEnabled},
+		{"component include", gitlabCI{status: 200, config: "include:\n  - component: gitlab.com/components/dependency-scanning/main@1\n"}, dependency, StatusEnabled},
+		{"job defined directly", gitlabCI{status: 200, config: "sast:\n  stage: test\n"}, sast, StatusEnabled},
+		{"pipeline without secret detection", gitlabCI{status: 200, config: "build:\n  script: make\n"}, secret, StatusDisabled},
+		{"pipeline without dependency scanning", gitlabCI{status: 200, config: "build:\n  script: make\n"}, dependency, StatusDisabled},
+		{"pipeline without sast", gitlabCI{status: 200, config: "build:\n  script: make\n"}, sast, StatusNotConfigured},
+		{"no pipeline", gitlabCI{status: 404}, secret, StatusDisabled},
+		{"no pipeline, sast", gitlabCI{status: 404}, sast, StatusNotConfigured},
+		{"pipeline unreadable", gitlabCI{status: 403}, dependency, StatusNoAccess},
+		{"pipeline in another project", gitlabCI{path: "ci.yml@acme/templates"}, sast, StatusUnknown},
+		{"server error", gitlabCI{status: 502}, secret, StatusUnknown},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.ci.result(tc.scanner).Status)
+		})
+	}
+}
+
+func TestGitLabCheckRepo(t *testing.T) {
+	const ciPath = gitlabProjectPath + "/repository/files/.gitlab-ci.yml/raw?ref=main"
+	tests := []struct {
+		name           string
+		project        fakeResponse
+		ci             *fakeResponse
+		files          map[string]fakeResponse
+		wantSecret     SecurityStatus
+		wantDependency SecurityStatus
+		wantSAST       SecurityStatus
+		wantCodeowners *bool
+		wantPolicy     *bool
+		wantCompliant  bool
+	}{
+		{
+			name:    "security templates and both files",
+			project: fakeResponse{http.StatusOK, "project.json"},
+			ci:      &fakeResponse{http.StatusOK, "ci_security.yml"},
+			files: map[string]fakeResponse{
+				gitlabFilePath("CODEOWNERS"):         {http.StatusOK, "file_empty.json"},
+				gitlabFilePath("docs/CODEOWNERS"):    {http.StatusNotFound, "file_not_found.json"},
+				gitlabFilePath(".gitlab/CODEOWNERS"): {http.StatusOK, "file_codeowners.json"},
+				gitlabFilePath("SECURITY.md"):        {http.StatusOK, "file_security_md.json"},
+			},
+			wantSecret:     StatusEnabled,
+			wantDependency: StatusEnabled,
+			wantSAST:       StatusEnabled,
+			wantCodeowners: boolPtr(true),
+			wantPolicy:     boolPtr(true),
+			wantCompliant:  true,
+		},
+		{
+			name:           "pipeline without scanners",
+			project:        fakeResponse{http.StatusOK, "project.json"},
+			ci:             &fakeResponse{http.StatusOK, "ci_build_only.yml"},
+			wantSecret:     StatusDisabled,
+			wantDependency: StatusDisabled,
+			wantSAST:       StatusNotConfigured,
+			wantCodeowners: boolPtr(false),
+			wantPolicy:     boolPtr(false),
+		},
+		{
+			name:           "auto devops",
+			project:        fakeResponse{http.StatusOK, "project_auto_devops.json"},
+			wantSecret:     StatusEnabled,
+			wantDependency: StatusEnabled,
+			wantSAST:       StatusEnabled,
+			wantCodeowners: boolPtr(false),
+			wantPolicy:     boolPtr(false),
+			wantCompliant:  true,
+		},
+		{
+			name:           "empty project",
+			project:        fakeResponse{http.StatusOK, "project_empty.json"},
+			wantSecret:     StatusDisabled,
+			wantDependency: StatusDisabled,
+			wantSAST:       StatusNotConfigured,
+			wantCodeowners: boolPtr(false),
+			wantPolicy:     boolPtr(false),
+		},
+		{
+			name:    "pipeline and files unreadable",
+			project: fakeResponse{http.StatusOK, "project.json"},
+			ci:      &fakeResponse{http.StatusForbidden, "forbidden.json"},
+			files: map[string]fakeResponse{
+				gitlabFilePath("CODEOWNERS"):  {http.StatusForbidden, "forbidden.json"},
+				gitlabFilePath("SECURITY.md"): {http.StatusForbidden, "forbidden.json"},
+			},
+			wantSecret:     StatusNoAccess,
+			wantDependency: StatusNoAccess,
+			wantSAST:       StatusNoAccess,
+		},
+	}
+
+	checks := []string{CheckSecretScanning, CheckDependabot, CheckCodeScanning, CheckFiles}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			routes := gitlabNoFiles()
+			routes[gitlabProjectPath] = tc.project
+			if tc.ci != nil {
+				routes[ciPath] = *tc.ci
+			}
+			for k, v := range tc.files {
+				routes[k] = v
+			}
+			_, a := newFakeGitLab(t, routes)
+			env := newActivityEnv(a)
+
+			val, err := env.ExecuteActivity(a.CheckProviderRepo, RepoCheckInput{
+				Provider: ProviderGitLab, Org: "acme", Repo: "api", Checks: checks,
+			})
+			require.NoError(t, err)
+
+			var result RepoSecurityResult
+			require.NoError(t, val.Get(&result))
+			require.Equal(t, "api", result.Repository)
+			require.Equal(t, ProviderGitLab, result.Provider)
+			require.Equal(t, tc.wantSecret, result.SecretScanning)
+			require.Equal(t, tc.wantDependency, result.DependabotAlerts)
+			require.Equal(t, tc.wantSAST, result.CodeScanning)
+			require.Equal(t, tc.wantCodeowners, result.HasCodeowners)
+			require.Equal(t, tc.wantPolicy, result.HasSecurityPolicy)
+			require.Equal(t, tc.wantCompliant, result.IsFullyCompliant())
+			require.Nil(t, result.Error)
+		})
+	}
+}
+
+func TestGitLabCheckRepoNotFound(t *testing.T) {
+	_, a := newFakeGitLab(t, map[string]fakeResponse{
+		gitlabProjectPath: {http.StatusNotFound, "project_not_found.json"},
+	})
+	env := newActivityEnv(a)
+
+	val, err := env.ExecuteActivity(a.CheckProviderRepo, RepoCheckInput{Provider: ProviderGitLab, Org: "acme", Repo: "api"})
+	require.NoError(t, err)
+
+	var result RepoSecurityResult
+	require.NoError(t, val.Get(&result))
+	require.Nil(t, result.Error)
+	require.Equal(t, &DisappearedRepo{Repository: "api", Reason: DisappearedDeleted, HTTPStatus: http.StatusNotFound}, result.Disappeared)
+}
+
+func TestValidateGitLabGroup(t *testing.T) {
+	for _, ok := range []string{"acme", "acme/platform", "acme_corp/team.infra", "a"} {
+		require.NoError(t, ValidateGitLabGroup(ok), ok)
+	}
+	for _, bad := range []string{"", "acme/", "/acme", "acme corp", "-acme", "acme/team.", "acme//platform"} {
+		require.Error(t, ValidateGitLabGroup(bad), bad)
+	}
+}
@@ -0,0 +1,155 @@
+package scanner
+
+// =============================================================================
+// The activities' HTTP client — timeouts, connection pool, proxy, User-Agent
+// =============================================================================
+//
+// A single client Timeout lets one slow GitHub response eat half of a 60s
+// activity, and the default Transport keeps only 2 idle connections per
+// host, so a batch of scanBatchSize concurrent repo checks keeps opening new
+// TLS connections. NewActivities builds a client with separate dial, TLS and
+// response-header timeouts, a pool sized to the batch, the proxy from
+// HTTPS_PROXY (or ActivitiesConfig.ProxyURL), and a User-Agent naming the
+// scanner, which GitHub asks API clients to send.
+// =============================================================================
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Defaults for ActivitiesConfig's zero values.
+const (
+	DefaultRequestTimeout        = 30 * time.Second
+	DefaultDialTimeout           = 5 * time.Second
+	DefaultTLSHandshakeTimeout   = 5 * time.Second
+	DefaultResponseHeaderTimeout = 15 * time.Second
+	DefaultMaxIdleConnsPerHost   = scanBatchSize
+)
+
+// DefaultUserAgent identifies this build of the scanner.
+func DefaultUserAgent() string {
+	return "temporal-security-scanner/" + Version + " (+https://github.com/salkimmich/temporal-security-scanner)"
+}
+
+// ActivitiesConfig configures NewActivities. Zero values take the defaults
+// above.
+type ActivitiesConfig struct {
+	// RequestTimeout bounds a whole request, body included.
+	RequestTimeout time.Duration
+	// DialTimeout, TLSHandshakeTimeout and ResponseHeaderTimeout bound
+	// connecting, the TLS handshake, and waiting for the response headers.
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	// MaxIdleConnsPerHost is how many connections to each API host are
+	// kept open between requests.
+	MaxIdleConnsPerHost int
+	// ProxyURL sends every request through this proxy. Empty honors
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY.
+	ProxyURL string
+	// UserAgent is sent on every request; empty means DefaultUserAgent.
+	UserAgent string
+
+	// The remaining fields are copied to the Activities.
+	BaseURL       string
+	GitLabURL     string
+	BlobStore     BlobStore
+	APIUsage      *APIUsageTracker
+	Secrets       SecretSource
+	Credentials   CredentialSource
+	HEC           *HECConfig
+	Datadog       *DatadogConfig
+	Jira          *JiraConfig
+	PagerDuty     *PagerDutyConfig
+	LatestReports *LatestReports
+	AuditLog      AuditLog
+	RateLimiter   *RateLimiter
+	OrgConfigs    *OrgConfigRegistry
+}
+
+// NewActivities builds Activities with an HTTP client configured by cfg.
+func NewActivities(cfg ActivitiesConfig) (*Activities, error) {
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Activities{
+		HTTPClient:    client,
+		BaseURL:       cfg.BaseURL,
+		BlobStore:     cfg.BlobStore,
+		GitLabURL:     cfg.GitLabURL,
+		APIUsage:      cfg.APIUsage,
+		Secrets:       cfg.Secrets,
+		Credentials:   cfg.Credentials,
+		HEC:           cfg.HEC,
+		Datadog:       cfg.Datadog,
+		Jira:          cfg.Jira,
+		PagerDuty:     cfg.PagerDuty,
+		LatestReports: cfg.LatestReports,
+		AuditLog:      cfg.AuditLog,
+		RateLimiter:   cfg.RateLimiter,
+		OrgConfigs:    cfg.OrgConfigs,
+	}, nil
+}
+
+func newHTTPClient(cfg ActivitiesConfig) (*http.Client, error) {
+	orDefault := func(d, def time.Duration) time.Duration {
+		if d > 0 {
+			return d
+		}
+		return def
+	}
+	proxy := http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		u, err := url.Parse(cfg.ProxyURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", cfg.ProxyURL)
+		}
+		proxy = http.ProxyURL(u)
+	}
+	idle := cfg.MaxIdleConnsPerHost
+	if idle <= 0 {
+		idle = DefaultMaxIdleConnsPerHost
+	}
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent()
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   orDefault(cfg.DialTimeout, DefaultDialTimeout),
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   orDefault(cfg.TLSHandshakeTimeout, DefaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: orDefault(cfg.ResponseHeaderTimeout, DefaultResponseHeaderTimeout),
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   idle,
+		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+	return &http.Client{
+		Timeout:   orDefault(cfg.RequestTimeout, DefaultRequestTimeout),
+		Transport: &userAgentTransport{base: transport, userAgent: userAgent},
+	}, nil
+}
+
+// userAgentTransport sets User-Agent on requests that have none.
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		// RoundTrippers must not modify the caller's request.
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
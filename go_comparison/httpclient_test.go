@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func transportOf(t *testing.T, a *Activities) *http.Transport {
+	t.Helper()
+	ua, ok := a.HTTPClient.Transport.(*userAgentTransport)
+	require.True(t, ok)
+	transport, ok := ua.base.(*http.Transport)
+	require.True(t, ok)
+	return transport
+}
+
+func TestNewActivitiesDefaults(t *testing.T) {
+	a, err := NewActivities(ActivitiesConfig{BaseURL: "https://ghe.example.com/api/v3"})
+	require.NoError(t, err)
+	require.Equal(t, "https://ghe.example.com/api/v3", a.BaseURL)
+	require.Equal(t, DefaultRequestTimeout, a.HTTPClient.Timeout)
+
+	transport := transportOf(t, a)
+	require.Equal(t, DefaultTLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	require.Equal(t, DefaultResponseHeaderTimeout, transport.ResponseHeaderTimeout)
+	require.Equal(t, scanBatchSize, transport.MaxIdleConnsPerHost, "one idle connection per concurrent repo check")
+	require.NotNil(t, transport.Proxy, "HTTPS_PROXY is honored")
+}
+
+func TestNewActivitiesConfig(t *testing.T) {
+	a, err := NewActivities(ActivitiesConfig{
+		RequestTimeout:        20 * time.Second,
+		TLSHandshakeTimeout:   3 * time.Second,
+		ResponseHeaderTimeout: 8 * time.Second,
+		MaxIdleConnsPerHost:   32,
+		ProxyURL:              "http://proxy.internal:3128",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 20*time.Second, a.HTTPClient.Timeout)
+
+	transport := transportOf(t, a)
+	require.Equal(t, 3*time.Second, transport.TLSHandshakeTimeout)
+	require.Equal(t, 8*time.Second, transport.ResponseHeaderTimeout)
+	require.Equal(t, 32, transport.MaxIdleConnsPerHost)
+	proxy, err := transport.Proxy(httptest.NewRequest(http.MethodGet, "https://api.github.com/rate_limit", nil))
+	require.NoError(t, err)
+	require.Equal(t, "http://proxy.internal:3128", proxy.String())
+
+	_, err = NewActivities(ActivitiesConfig{ProxyURL: "proxy.internal"})
+	require.ErrorContains(t, err, "invalid proxy URL")
+}
+
+func TestActivitiesSendUserAgent(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("User-Agent"))
+		_, _ = w.Write([]byte(`{"resources":{"core":{"limit":5000,"remaining":4999,"reset":1767225600}}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	for _, ua := range []string{"", "acme-security/2.1"} {
+		a, err := NewActivities(ActivitiesConfig{BaseURL: srv.URL, UserAgent: ua})
+		require.NoError(t, err)
+		_, err = newActivityEnv(a).ExecuteActivity(a.GetRateLimit, (*Credential)(nil))
+		require.NoError(t, err)
+	}
+	require.Equal(t, []string{DefaultUserAgent(), "acme-security/2.1"}, got)
+	require.Contains(t, DefaultUserAgent(), "temporal-security-scanner/"+Version)
+}
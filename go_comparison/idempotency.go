@@ -0,0 +1,150 @@
+package scanner
+
+// =============================================================================
+// Idempotent remediation writes
+// =============================================================================
+//
+// Temporal retries an activity that timed out, even when its write reached
+// the tracker and only the answer was lost. A remediation activity that
+// simply wrote again would file a second issue, or repeat an update.
+//
+// Each write therefore has a remediationKey — the workflow run, what it is
+// about (a team's issue label) and the action — and goes through three
+// steps:
+//
+//  1. Look the key up in the worker's blob store. A write this run already
+//     finished is not repeated; its recorded result is reused.
+//  2. Record the key as pending, then write. A created issue also carries
+//     the key's label, security-scan-op:<hash>, so the tracker itself can
+//     say whether the write happened.
+//  3. Record the key as done, with the result.
+//
+// A retry that finds the key pending, or finds no record of it at all,
+// first searches the tracker for the key's label before creating anything.
+//
+// This is at-least-once made as close to exactly-once as the tracker
+// allows, not exactly-once:
+//
+//   - Between the write and step 3 only the label stands in for the record.
+//     Jira's search index can lag a write by a few seconds, so a retry
+//     faster than that can still file a duplicate; the retry policy's
+//     backoff makes this rare.
+//   - An update has no label to search for, so a lost answer to one is
+//     repeated. Updates write the same fields each time, so this costs a
+//     request rather than changing anything.
+//   - Keys are per run: a new scan of the org writes again, as it should.
+//   - The store failing does not fail the write; it only loses step 1.
+// =============================================================================
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// Remediation actions with idempotency keys.
+const (
+	remediationJiraIssue   = "jira_issue"
+	remediationIssueDigest = "issue_digest"
+)
+
+// idempotencyLabelPrefix prefixes the label a created issue carries.
+const idempotencyLabelPrefix = "security-scan-op:"
+
+// remediationKey identifies one remediation write of one workflow run.
+type remediationKey struct {
+	RunID   string
+	Subject string
+	Action  string
+}
+
+// newRemediationKey is the key of action on subject in the activity's run.
+func newRemediationKey(ctx context.Context, subject, action string) remediationKey {
+	return remediationKey{RunID: activity.GetInfo(ctx).WorkflowExecution.RunID, Subject: subject, Action: action}
+}
+
+func (k remediationKey) String() string {
+	return k.RunID + "/" + k.Action + "/" + k.Subject
+}
+
+// hash is short enough for a label and stable across workers.
+func (k remediationKey) hash() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{k.RunID, k.Action, k.Subject}, "\x00")))
+	return hex.EncodeToString(sum[:12])
+}
+
+// label is the marker a created issue carries.
+func (k remediationKey) label() string {
+	return idempotencyLabelPrefix + k.hash()
+}
+
+func (k remediationKey) blobKey() string {
+	return "idempotency/" + k.hash() + ".json"
+}
+
+// idempotencyRecord is what the blob store holds for a key.
+type idempotencyRecord struct {
+	Key        string          `json:"key"`
+	Done       bool            `json:"done"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	RecordedAt time.Time       `json:"recorded_at"`
+}
+
+// loadIdempotency is k's record, or nil when there is none, the worker has
+// no blob store, or the store cannot be read.
+func (a *Activities) loadIdempotency(ctx context.Context, k remediationKey) *idempotencyRecord {
+	if a.BlobStore == nil {
+		return nil
+	}
+	data, err := a.BlobStore.Get(ctx, a.BlobStore.URI(k.blobKey()))
+	if err != nil {
+		if !errors.Is(err, ErrBlobNotFound) {
+			activity.GetLogger(ctx).Warn("Reading idempotency record failed", "key", k.String(), "error", err)
+		}
+		return nil
+	}
+	var rec idempotencyRecord
+	if err := json.Unmarshal(data, &rec); err != nil || rec.Key != k.String() {
+		return nil
+	}
+	return &rec
+}
+
+// saveIdempotency records k as pending, or as done with result. A failure
+// is logged, not returned: the write it guards has happened or will.
+func (a *Activities) saveIdempotency(ctx context.Context, k remediationKey, done bool, result interface{}) {
+	if a.BlobStore == nil {
+		return
+	}
+	rec := idempotencyRecord{Key: k.String(), Done: done, RecordedAt: time.Now().UTC()}
+	var err error
+	if result != nil {
+		rec.Result, err = json.Marshal(result)
+	}
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(rec)
+	}
+	if err == nil {
+		_, err = a.BlobStore.Put(ctx, k.blobKey(), data)
+	}
+	if err != nil {
+		activity.GetLogger(ctx).Warn("Recording idempotency key failed", "key", k.String(), "error", err)
+	}
+}
+
+// mayHaveWritten reports whether an earlier attempt may have made the
+// write rec is for without recording it: rec is pending, or this is a
+// retry with no record.
+func mayHaveWritten(ctx context.Context, rec *idempotencyRecord) bool {
+	if rec != nil {
+		return !rec.Done
+	}
+	return activity.GetInfo(ctx).Attempt > 1
+}
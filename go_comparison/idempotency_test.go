@@ -0,0 +1,99 @@
+package scanner
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// loseAnswer is a transport that delivers the first request with method
+// to the server and then drops the answer, as a timeout after a
+// successful write does.
+type loseAnswer struct {
+	base   http.RoundTripper
+	method string
+	mu     sync.Mutex
+	lost   bool
+}
+
+func (l *loseAnswer) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := l.base.RoundTrip(r)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil || r.Method != l.method || l.lost {
+		return resp, err
+	}
+	l.lost = true
+	resp.Body.Close()
+	return nil, errors.New("connection reset by peer")
+}
+
+func TestCreateJiraIssuesRetryAfterLostCreate(t *testing.T) {
+	f, cfg := newFakeJira(t)
+	cfg.HTTPClient = &http.Client{Transport: &loseAnswer{base: cfg.HTTPClient.Transport, method: http.MethodPost}}
+	a := &Activities{Jira: cfg, BlobStore: &FileBlobStore{Dir: t.TempDir()}}
+
+	// payments' issue is filed, but the attempt never hears so.
+	_, err := newActivityEnv(a).ExecuteActivity(a.CreateJiraIssues, JiraInput{Report: jiraReport(t)})
+	require.ErrorContains(t, err, "connection reset")
+	require.Len(t, f.created, 1)
+
+	// The retry finds it by its idempotency label instead of filing it
+	// again, or updating it as if a past scan had.
+	val, err := newActivityEnv(a).ExecuteActivity(a.CreateJiraIssues, JiraInput{Report: jiraReport(t)})
+	require.NoError(t, err)
+	var out RemediationResult
+	require.NoError(t, val.Get(&out))
+	require.Equal(t, []RemediationIssue{
+		{Team: "payments", Key: "SEC-1", Action: RemediationCreated, Repos: []string{"api", "billing"}},
+		{Team: "platform", Key: "SEC-2", Action: RemediationCreated, Repos: []string{"api", "web"}},
+	}, out.Issues)
+	require.Len(t, f.created, 2)
+	require.Empty(t, f.updated)
+
+	// Once recorded, a further attempt of the run writes nothing at all.
+	writes := f.writes
+	val, err = newActivityEnv(a).ExecuteActivity(a.CreateJiraIssues, JiraInput{Report: jiraReport(t)})
+	require.NoError(t, err)
+	var again RemediationResult
+	require.NoError(t, val.Get(&again))
+	require.Equal(t, out.Issues, again.Issues)
+	require.Equal(t, writes, f.writes)
+}
+
+func TestCreateJiraIssuesRetryAfterLostUpdate(t *testing.T) {
+	f, cfg := newFakeJira(t)
+	f.issues["security-scan:acme:payments"] = "SEC-100"
+	f.issues["security-scan:acme:platform"] = "SEC-101"
+	cfg.HTTPClient = &http.Client{Transport: &loseAnswer{base: cfg.HTTPClient.Transport, method: http.MethodPut}}
+	a := &Activities{Jira: cfg, BlobStore: &FileBlobStore{Dir: t.TempDir()}}
+
+	_, err := newActivityEnv(a).ExecuteActivity(a.CreateJiraIssues, JiraInput{Report: jiraReport(t)})
+	require.Error(t, err)
+	require.Equal(t, 1, f.writes)
+
+	// The lost update is repeated, which changes nothing; nothing is filed.
+	val, err := newActivityEnv(a).ExecuteActivity(a.CreateJiraIssues, JiraInput{Report: jiraReport(t)})
+	require.NoError(t, err)
+	var out RemediationResult
+	require.NoError(t, val.Get(&out))
+	_, updated := out.Keys()
+	require.Equal(t, []string{"SEC-100", "SEC-101"}, updated)
+	require.Equal(t, 3, f.writes)
+	require.Empty(t, f.created)
+}
+
+func TestRemediationKey(t *testing.T) {
+	k := remediationKey{RunID: "run-1", Subject: "security-scan:acme:platform", Action: remediationJiraIssue}
+	require.Equal(t, "run-1/jira_issue/security-scan:acme:platform", k.String())
+	require.Regexp(t, `^security-scan-op:[0-9a-f]{24}$`, k.label())
+	require.Equal(t, k.label(), remediationKey{RunID: "run-1", Subject: "security-scan:acme:platform", Action: remediationJiraIssue}.label(), "stable")
+
+	other := k
+	other.RunID = "run-2"
+	require.NotEqual(t, k.label(), other.label(), "each run writes again")
+	require.NotEqual(t, k.blobKey(), other.blobKey())
+}
@@ -0,0 +1,102 @@
+package scanner
+
+// =============================================================================
+// Scan initiator — who started a scan, and why
+// =============================================================================
+//
+// ScanInput.InitiatedBy and Reason record who started a scan and why, e.g.
+// "alice" and "incident INC-1234". The starter sets InitiatedBy to the
+// local user. A run a Temporal Schedule starts without one is stamped
+// "schedule:<schedule ID>", read from the TemporalScheduledById search
+// attribute the server sets on scheduled runs.
+//
+// Both are written to the report (initiated_by, reason), the org's scan
+// history, and the run's memo (memo.go). With ProgressIntervalSeconds the progress
+// loop also upserts InitiatedBy as the ScanInitiatedBy search attribute,
+// which must then be registered (Keyword) like ScanStatus.
+//
+// The values reach logs, Splunk, Jira and webhooks, so the workflow strips
+// control characters from them and rejects values longer than
+// MaxInitiatedByLength or MaxReasonLength characters.
+// =============================================================================
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// MaxInitiatedByLength caps ScanInput.InitiatedBy.
+	MaxInitiatedByLength = 128
+	// MaxReasonLength caps ScanInput.Reason.
+	MaxReasonLength = 512
+)
+
+// ScanInitiatedByKey is the search attribute the progress loop sets to
+// ScanInput.InitiatedBy.
+var ScanInitiatedByKey = temporal.NewSearchAttributeKeyKeyword("ScanInitiatedBy")
+
+// scheduledByKey is the search attribute the server sets on the runs a
+// Schedule starts, to the schedule's ID.
+var scheduledByKey = temporal.NewSearchAttributeKeyKeyword("TemporalScheduledById")
+
+// CleanScanLabel is s without control characters, and with each run of
+// whitespace, line breaks included, made one space.
+func CleanScanLabel(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// ValidateInitiator checks the lengths of a cleaned InitiatedBy and Reason.
+func ValidateInitiator(initiatedBy, reason string) error {
+	if n := utf8.RuneCountInString(initiatedBy); n > MaxInitiatedByLength {
+		return fmt.Errorf("initiated_by is %d characters, more than %d", n, MaxInitiatedByLength)
+	}
+	if n := utf8.RuneCountInString(reason); n > MaxReasonLength {
+		return fmt.Errorf("reason is %d characters, more than %d", n, MaxReasonLength)
+	}
+	return nil
+}
+
+// scanInitiator is initiatedBy, or "schedule:<id>" for a run a Schedule
+// started without one.
+func scanInitiator(ctx workflow.Context, initiatedBy string) string {
+	if initiatedBy != "" {
+		return initiatedBy
+	}
+	if id, ok := workflow.GetTypedSearchAttributes(ctx).GetKeyword(scheduledByKey); ok && id != "" {
+		return "schedule:" + id
+	}
+	return ""
+}
+
+// tagInitiator records in's InitiatedBy and Reason in the run's memo, as
+// runs before ScanMemo did.
+func tagInitiator(ctx workflow.Context, in ScanInput) {
+	memo := map[string]interface{}{}
+	if in.InitiatedBy != "" {
+		memo["initiated_by"] = in.InitiatedBy
+	}
+	if in.Reason != "" {
+		memo["reason"] = in.Reason
+	}
+	if len(memo) == 0 {
+		return
+	}
+	if err := workflow.UpsertMemo(ctx, memo); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to record the scan's initiator in its memo", "error", err)
+	}
+}
@@ -0,0 +1,71 @@
+package scanner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestCleanScanLabel(t *testing.T) {
+	require.Equal(t, "alice", CleanScanLabel("  alice\n"))
+	require.Equal(t, "incident INC-1234 sev1", CleanScanLabel("incident\tINC-1234\r\nsev1"))
+	require.Equal(t, "red[31mteam", CleanScanLabel("red\x1b[31mteam"), "escape sequences lose their ESC")
+	require.Equal(t, "evil.exe", CleanScanLabel("evil\u202e.exe\x00"))
+	require.Equal(t, "Jürgen 🚀", CleanScanLabel("Jürgen 🚀"))
+}
+
+func TestValidateInitiator(t *testing.T) {
+	require.NoError(t, ValidateInitiator("", ""))
+	require.NoError(t, ValidateInitiator(strings.Repeat("ü", MaxInitiatedByLength), strings.Repeat("x", MaxReasonLength)))
+	require.EqualError(t, ValidateInitiator(strings.Repeat("a", MaxInitiatedByLength+1), ""), "initiated_by is 129 characters, more than 128")
+	require.EqualError(t, ValidateInitiator("alice", strings.Repeat("x", MaxReasonLength+1)), "reason is 513 characters, more than 512")
+}
+
+func TestWorkflowRecordsInitiator(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	var memo map[string]interface{}
+	env.OnUpsertMemo(mock.Anything).Run(func(args mock.Arguments) {
+		memo = args.Get(0).(map[string]interface{})
+	}).Return(nil)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", InitiatedBy: " alice\n", Reason: "incident\x07 INC-1234"})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, "alice", report.InitiatedBy)
+	require.Equal(t, "incident INC-1234", report.Reason)
+	require.Equal(t, "alice", memo[MemoInitiatedBy])
+	require.Equal(t, "incident INC-1234", memo[MemoReason])
+}
+
+func TestWorkflowStampsScheduleAsInitiator(t *testing.T) {
+	env := newTestEnv(t)
+	require.NoError(t, env.SetTypedSearchAttributesOnStart(temporal.NewSearchAttributes(scheduledByKey.ValueSet("weekly-acme"))))
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Reason: "scheduled"})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, "schedule:weekly-acme", report.InitiatedBy)
+}
+
+func TestWorkflowRejectsLongReason(t *testing.T) {
+	env := newTestEnv(t)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Reason: strings.Repeat("x", MaxReasonLength+1)})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+	env.AssertNumberOfCalls(t, "FetchOrgReposPage", 0)
+}
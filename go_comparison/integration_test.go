@@ -0,0 +1,139 @@
+//go:build integration
+
+package scanner
+
+// =============================================================================
+// Integration test — a real Temporal server, worker and client
+// =============================================================================
+//
+// The testsuite runs the workflow in-process: it registers whatever the test
+// hands it and never encodes a payload through a server. This test starts
+// the Temporal CLI dev server, runs the worker's own registration
+// (RunWorker) against it with the mock GitHub API, and drives scans through
+// a real client the way the starter does (StartScan): queries, the
+// pause_scan and resume_scan signals, and cancel_scan. Registration names,
+// the data converter and the task queue are all exercised for real.
+//
+// It needs the Temporal CLI, downloaded on first run unless TEMPORAL_CLI
+// names one already installed:
+//
+//	go test -tags integration ./go_comparison -run TestIntegration -v
+//	TEMPORAL_CLI=$(which temporal) go test -tags integration ./go_comparison -run TestIntegration
+// =============================================================================
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/salkimmich/temporal-security-scanner/go_comparison/internal/githubmock"
+)
+
+// startIntegrationWorker starts a dev server and a worker on it, talking to
+// gh, and returns a client for the server.
+func startIntegrationWorker(t *testing.T, gh *githubmock.Server) client.Client {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	threshold, err := ParseCompressionThreshold("on")
+	require.NoError(t, err)
+	server, err := testsuite.StartDevServer(ctx, testsuite.DevServerOptions{
+		ExistingPath:  os.Getenv("TEMPORAL_CLI"),
+		ClientOptions: &client.Options{DataConverter: NewDataConverter(threshold), Logger: nopLogger{}},
+	})
+	require.NoError(t, err, "starting the Temporal dev server")
+	t.Cleanup(func() { _ = server.Stop() })
+
+	srv := httptest.NewServer(gh)
+	t.Cleanup(srv.Close)
+	blobStore, err := OpenBlobStore(t.TempDir())
+	require.NoError(t, err)
+	activities, err := NewActivities(ActivitiesConfig{BaseURL: srv.URL, BlobStore: blobStore})
+	require.NoError(t, err)
+
+	stop := make(chan interface{})
+	done := make(chan error, 1)
+	go func() {
+		done <- RunWorker(WorkerConfig{Client: server.Client(), Activities: activities}, stop)
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		require.NoError(t, <-done)
+	})
+	return server.Client()
+}
+
+// startHeldScan starts a scan of org, pauses it and waits until it is held
+// after its first batch.
+func startHeldScan(t *testing.T, c client.Client, id string, input ScanInput) client.WorkflowRun {
+	t.Helper()
+	ctx := context.Background()
+	run, err := StartScan(ctx, c, client.StartWorkflowOptions{ID: id, TaskQueue: TaskQueue}, input, false)
+	require.NoError(t, err)
+	require.NoError(t, c.SignalWorkflow(ctx, id, run.GetRunID(), PauseScanSignal, "integration test"))
+
+	var progress ScanProgress
+	require.Eventually(t, func() bool {
+		val, err := c.QueryWorkflow(ctx, id, run.GetRunID(), "progress")
+		return err == nil && val.Get(&progress) == nil && progress.Status == ScanHeld
+	}, time.Minute, 100*time.Millisecond, "the scan is held before its next batch")
+	require.True(t, progress.PauseRequested)
+	require.Positive(t, progress.ScannedRepos)
+	require.Less(t, progress.ScannedRepos, progress.TotalRepos)
+	return run
+}
+
+func TestIntegrationScanThroughTemporal(t *testing.T) {
+	gh := githubmock.New(githubmock.Config{Org: "acme-corp", Repos: 60, Seed: 7, Token: "demo-token"})
+	c := startIntegrationWorker(t, gh)
+	token := "demo-token"
+	input := ScanInput{Org: "acme-corp", Token: &token}
+
+	t.Run("query, pause and resume", func(t *testing.T) {
+		run := startHeldScan(t, c, "security-scan-acme-corp-integration", input)
+		ctx := context.Background()
+
+		val, err := c.QueryWorkflow(ctx, run.GetID(), run.GetRunID(), ResultsSoFarQuery)
+		require.NoError(t, err)
+		var results []RepoSecurityResult
+		require.NoError(t, val.Get(&results))
+		require.NotEmpty(t, results)
+
+		require.NoError(t, c.SignalWorkflow(ctx, run.GetID(), run.GetRunID(), ResumeScanSignal, "integration test"))
+		var report Report
+		require.NoError(t, run.Get(ctx, &report))
+		require.NoError(t, report.Validate())
+
+		archived := 0
+		for _, r := range gh.Repos() {
+			if r.Archived {
+				archived++
+			}
+		}
+		require.Equal(t, "acme-corp", report.Org)
+		require.Equal(t, 60-archived, report.TotalRepos)
+		require.Equal(t, archived, report.Archived)
+		require.Zero(t, report.Errors)
+		require.False(t, report.Cancelled)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		run := startHeldScan(t, c, "security-scan-acme-corp-integration-cancel", input)
+		ctx := context.Background()
+
+		require.NoError(t, c.SignalWorkflow(ctx, run.GetID(), run.GetRunID(), "cancel_scan", "integration test"))
+		var report Report
+		require.NoError(t, run.Get(ctx, &report))
+		require.NoError(t, report.Validate())
+		require.True(t, report.Cancelled)
+		require.Equal(t, "integration test", report.CancelReason)
+		require.Less(t, report.ReposScannedBeforeCancel, report.TotalRepos)
+	})
+}
@@ -0,0 +1,501 @@
+// Package githubmock serves a deterministic fake of the GitHub REST API
+// endpoints the scanner calls, so demos, development, and integration tests
+// run without a token or rate limit.
+//
+// Every repository's security settings are derived from Config.Seed and the
+// repo's index, so the same config always produces the same org and the same
+// scan report.
+package githubmock
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRateLimit matches GitHub's authenticated core limit.
+const DefaultRateLimit = 5000
+
+// Config describes the fake organization.
+type Config struct {
+	Org   string // organization login; requests for any other org 404
+	Repos int    // number of repositories in the org
+	Seed  int64  // seeds the distribution of security settings
+
+	// Token, when set, must be sent as "token <Token>" or "Bearer <Token>";
+	// anything else gets a 401. Empty accepts any or no credentials.
+	Token string
+
+	// RateLimit is the number of requests allowed per hour-long window.
+	// 0 means DefaultRateLimit; negative disables rate limiting.
+	RateLimit int
+
+	// Enterprise, when set, is the slug of an enterprise account that owns
+	// Org; the GraphQL API lists Org as its only organization. Queries for
+	// any other enterprise find none.
+	Enterprise string
+
+	// Now overrides the clock for rate-limit reset times and the repos'
+	// push times, which fall within two years before New is called.
+	Now func() time.Time
+}
+
+// Repo is one generated repository and the settings the scanner should find.
+type Repo struct {
+	Name           string
+	Private        bool
+	Archived       bool
+	SecretScanning string // "enabled", "disabled", or "" (no security_and_analysis block)
+	Dependabot     bool
+	CodeScanning   int    // HTTP status of the code-scanning alerts endpoint: 200, 403, or 404
+	Codeowners     string // directory holding CODEOWNERS ("", ".github/", "docs/"); see HasCodeowners
+	HasCodeowners  bool
+	SecurityPolicy bool // SECURITY.md at the repo root
+
+	ActionsEnabled      bool
+	AllowedActions      string // "all", "local_only", or "selected"
+	WorkflowPermissions string // default GITHUB_TOKEN permissions: "read" or "write"
+
+	PushedAt time.Time // zero for an empty repo that was never pushed to
+
+	Internal      bool // visibility "internal"; internal repos are also Private
+	Fork          bool
+	DefaultBranch string // "main" or "master"
+	Language      string // "" when GitHub detected none
+	Topics        []string
+}
+
+// Visibility is the repo's visibility as GitHub reports it.
+func (r Repo) Visibility() string {
+	switch {
+	case r.Internal:
+		return "internal"
+	case r.Private:
+		return "private"
+	}
+	return "public"
+}
+
+// Server is an http.Handler implementing the fake API.
+type Server struct {
+	cfg   Config
+	repos []Repo
+	index map[string]int
+
+	mu       sync.Mutex
+	used     int
+	resetAt  time.Time
+	requests int
+}
+
+// New generates the organization described by cfg.
+func New(cfg Config) *Server {
+	if cfg.Org == "" {
+		cfg.Org = "acme-corp"
+	}
+	if cfg.RateLimit == 0 {
+		cfg.RateLimit = DefaultRateLimit
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	now := cfg.Now().UTC().Truncate(time.Second)
+	s := &Server{cfg: cfg, index: make(map[string]int, cfg.Repos)}
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	for i := 0; i < cfg.Repos; i++ {
+		r := Repo{
+			Name:         fmt.Sprintf("service-%03d", i),
+			Private:      rng.Intn(3) > 0,
+			Archived:     rng.Intn(20) == 0,
+			Dependabot:   rng.Intn(4) > 0,
+			CodeScanning: http.StatusNotFound,
+		}
+		switch n := rng.Intn(10); {
+		case n < 6:
+			r.SecretScanning = "enabled"
+		case n < 9:
+			r.SecretScanning = "disabled"
+		}
+		switch n := rng.Intn(10); {
+		case n < 5:
+			r.CodeScanning = http.StatusOK
+		case n < 6:
+			r.CodeScanning = http.StatusForbidden
+		}
+		// Drawn after the GHAS settings so a seed keeps producing the same mix.
+		if n := rng.Intn(6); n < 3 {
+			r.HasCodeowners = true
+			r.Codeowners = []string{"", ".github/", "docs/"}[n]
+		}
+		r.SecurityPolicy = rng.Intn(2) == 0
+		r.ActionsEnabled = rng.Intn(10) > 0
+		r.AllowedActions = []string{"all", "all", "local_only", "selected"}[rng.Intn(4)]
+		r.WorkflowPermissions = []string{"read", "write"}[rng.Intn(2)]
+		if rng.Intn(25) > 0 {
+			r.PushedAt = now.AddDate(0, 0, -rng.Intn(730)).Add(-time.Duration(rng.Intn(86400)) * time.Second)
+		}
+		r.Internal = r.Private && rng.Intn(4) == 0
+		r.Fork = rng.Intn(15) == 0
+		r.DefaultBranch = []string{"main", "main", "main", "master"}[rng.Intn(4)]
+		r.Language = []string{"Go", "Go", "Python", "TypeScript", "Java", "HCL", ""}[rng.Intn(7)]
+		for _, topic := range []string{"payments", "platform", "internal-tools"} {
+			if rng.Intn(5) == 0 {
+				r.Topics = append(r.Topics, topic)
+			}
+		}
+		s.index[r.Name] = i
+		s.repos = append(s.repos, r)
+	}
+	return s
+}
+
+// Repos returns the generated repositories in listing order.
+func (s *Server) Repos() []Repo {
+	return append([]Repo(nil), s.repos...)
+}
+
+// Requests returns the number of requests served so far.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	graphql := r.URL.Path == "/graphql"
+	if r.Method != http.MethodGet && !(graphql && r.Method == http.MethodPost) {
+		writeMessage(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	if !s.authorized(r) {
+		writeMessage(w, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+	if !s.takeRateLimit(w) {
+		writeMessage(w, http.StatusForbidden, "API rate limit exceeded for mock user.")
+		return
+	}
+
+	if graphql {
+		s.serveGraphQL(w, r)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "rate_limit":
+		s.serveRateLimit(w)
+	case len(parts) == 2 && parts[0] == "orgs":
+		s.serveOrg(w, parts[1])
+	case len(parts) == 2 && parts[0] == "user" && parts[1] == "orgs":
+		writeJSON(w, http.StatusOK, []map[string]string{{"login": s.cfg.Org}})
+	case len(parts) == 3 && parts[0] == "orgs" && parts[2] == "repos":
+		s.serveOrgRepos(w, r, parts[1])
+	case len(parts) >= 3 && parts[0] == "repos":
+		s.serveRepo(w, parts[1], parts[2], strings.Join(parts[3:], "/"))
+	default:
+		writeMessage(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.cfg.Token == "" {
+		return true
+	}
+	auth := r.Header.Get("Authorization")
+	return auth == "token "+s.cfg.Token || auth == "Bearer "+s.cfg.Token
+}
+
+// takeRateLimit counts the request against the current window and sets the
+// X-RateLimit-* headers GitHub sends on every response.
+func (s *Server) takeRateLimit(w http.ResponseWriter) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if s.cfg.RateLimit < 0 {
+		return true
+	}
+
+	now := s.cfg.Now()
+	if s.resetAt.IsZero() || !now.Before(s.resetAt) {
+		s.used = 0
+		s.resetAt = now.Add(time.Hour).Truncate(time.Second)
+	}
+	ok := s.used < s.cfg.RateLimit
+	if ok {
+		s.used++
+	}
+
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(s.cfg.RateLimit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(s.cfg.RateLimit-s.used))
+	h.Set("X-RateLimit-Used", strconv.Itoa(s.used))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(s.resetAt.Unix(), 10))
+	h.Set("X-RateLimit-Resource", "core")
+	if !ok {
+		h.Set("Retry-After", strconv.Itoa(int(s.resetAt.Sub(now).Seconds())))
+	}
+	return ok
+}
+
+func (s *Server) serveRateLimit(w http.ResponseWriter) {
+	h := w.Header()
+	limit, _ := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	remaining, _ := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	used, _ := strconv.Atoi(h.Get("X-RateLimit-Used"))
+	reset, _ := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	core := map[string]interface{}{"limit": limit, "remaining": remaining, "used": used, "reset": reset}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"resources": map[string]interface{}{"core": core},
+		"rate":      core,
+	})
+}
+
+// serveGraphQL answers the enterprise organizations query, the only one
+// the scanner sends, with Org as the enterprise's one organization.
+func (s *Server) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Variables struct {
+			Slug string `json:"slug"`
+		} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	if s.cfg.Enterprise == "" || req.Variables.Slug != s.cfg.Enterprise {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"enterprise": nil},
+			"errors": []map[string]string{{
+				"type":    "NOT_FOUND",
+				"message": fmt.Sprintf("Could not resolve to a Enterprise with the slug of '%s'.", req.Variables.Slug),
+			}},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"enterprise": map[string]interface{}{
+				"organizations": map[string]interface{}{
+					"nodes":    []map[string]string{{"login": s.cfg.Org}},
+					"pageInfo": map[string]interface{}{"hasNextPage": false, "endCursor": nil},
+				},
+			},
+		},
+	})
+}
+
+func (s *Server) serveOrg(w http.ResponseWriter, org string) {
+	if org != s.cfg.Org {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	public, private := 0, 0
+	for _, r := range s.repos {
+		if r.Private {
+			private++
+		} else {
+			public++
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"login":               org,
+		"public_repos":        public,
+		"total_private_repos": private,
+	})
+}
+
+func (s *Server) serveOrgRepos(w http.ResponseWriter, r *http.Request, org string) {
+	if org != s.cfg.Org {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	perPage := queryInt(r, "per_page", 30)
+	if perPage < 1 || perPage > 100 {
+		perPage = 30
+	}
+	page := queryInt(r, "page", 1)
+	if page < 1 {
+		page = 1
+	}
+
+	start := (page - 1) * perPage
+	end := start + perPage
+	if start > len(s.repos) {
+		start = len(s.repos)
+	}
+	if end > len(s.repos) {
+		end = len(s.repos)
+	}
+
+	lastPage := (len(s.repos) + perPage - 1) / perPage
+	if links := s.pageLinks(r, page, lastPage, perPage); links != "" {
+		w.Header().Set("Link", links)
+	}
+
+	out := make([]map[string]interface{}, 0, end-start)
+	for _, repo := range s.repos[start:end] {
+		out = append(out, s.repoJSON(repo))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// pageLinks builds the RFC 8288 Link header GitHub uses for pagination.
+func (s *Server) pageLinks(r *http.Request, page, lastPage, perPage int) string {
+	if lastPage <= 1 {
+		return ""
+	}
+	link := func(p int, rel string) string {
+		return fmt.Sprintf(`<http://%s%s?per_page=%d&page=%d>; rel="%s"`, r.Host, r.URL.Path, perPage, p, rel)
+	}
+	var links []string
+	if page < lastPage {
+		links = append(links, link(page+1, "next"), link(lastPage, "last"))
+	}
+	if page > 1 {
+		links = append(links, link(1, "first"), link(page-1, "prev"))
+	}
+	return strings.Join(links, ", ")
+}
+
+func (s *Server) serveRepo(w http.ResponseWriter, org, name, sub string) {
+	i, ok := s.index[name]
+	if org != s.cfg.Org || !ok {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	repo := s.repos[i]
+
+	switch sub {
+	case "":
+		writeJSON(w, http.StatusOK, s.repoJSON(repo))
+	case "vulnerability-alerts":
+		if repo.Dependabot {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeMessage(w, http.StatusNotFound, "Vulnerability alerts are disabled.")
+	case "code-scanning/alerts":
+		switch repo.CodeScanning {
+		case http.StatusOK:
+			writeJSON(w, http.StatusOK, []interface{}{})
+		case http.StatusForbidden:
+			writeMessage(w, http.StatusForbidden, "Advanced Security must be enabled for this repository to use code scanning.")
+		default:
+			writeMessage(w, http.StatusNotFound, "no analysis found")
+		}
+	case "community/profile":
+		files := map[string]interface{}{"readme": map[string]string{"url": s.contentsURL(repo, "README.md")}}
+		if repo.SecurityPolicy {
+			files["security"] = map[string]string{"url": s.contentsURL(repo, "SECURITY.md")}
+		} else {
+			files["security"] = nil
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"health_percentage": 42, "files": files})
+	case "actions/permissions":
+		if !repo.ActionsEnabled {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"enabled": false})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"enabled": true, "allowed_actions": repo.AllowedActions})
+	case "actions/permissions/workflow":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"default_workflow_permissions":     repo.WorkflowPermissions,
+			"can_approve_pull_request_reviews": false,
+		})
+	case "contents/" + repo.Codeowners + "CODEOWNERS":
+		if !repo.HasCodeowners {
+			writeMessage(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.serveFile(w, repo, repo.Codeowners+"CODEOWNERS", "* @"+s.cfg.Org+"/platform-team\n")
+	case "contents/SECURITY.md":
+		if !repo.SecurityPolicy {
+			writeMessage(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.serveFile(w, repo, "SECURITY.md", "# Security Policy\n\nReport vulnerabilities to security@example.com.\n")
+	default:
+		writeMessage(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (s *Server) contentsURL(repo Repo, path string) string {
+	return "https://api.github.com/repos/" + s.cfg.Org + "/" + repo.Name + "/contents/" + path
+}
+
+// serveFile answers a contents API request for a file.
+func (s *Server) serveFile(w http.ResponseWriter, repo Repo, path, content string) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"type":     "file",
+		"encoding": "base64",
+		"name":     path[strings.LastIndex(path, "/")+1:],
+		"path":     path,
+		"size":     len(content),
+		"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+		"url":      s.contentsURL(repo, path),
+	})
+}
+
+func (s *Server) repoJSON(repo Repo) map[string]interface{} {
+	full := s.cfg.Org + "/" + repo.Name
+	var language interface{}
+	if repo.Language != "" {
+		language = repo.Language
+	}
+	out := map[string]interface{}{
+		"id":             512340000 + s.index[repo.Name],
+		"name":           repo.Name,
+		"full_name":      full,
+		"private":        repo.Private,
+		"archived":       repo.Archived,
+		"visibility":     repo.Visibility(),
+		"fork":           repo.Fork,
+		"language":       language,
+		"topics":         append([]string{}, repo.Topics...),
+		"default_branch": repo.DefaultBranch,
+		"html_url":       "https://github.com/" + full,
+		"owner":          map[string]interface{}{"login": s.cfg.Org, "type": "Organization"},
+		"pushed_at":      nil,
+		"updated_at":     nil,
+	}
+	if !repo.PushedAt.IsZero() {
+		out["pushed_at"] = repo.PushedAt.Format(time.RFC3339)
+		out["updated_at"] = repo.PushedAt.Format(time.RFC3339)
+	}
+	if repo.SecretScanning != "" {
+		out["security_and_analysis"] = map[string]interface{}{
+			"secret_scanning": map[string]string{"status": repo.SecretScanning},
+		}
+	}
+	return out
+}
+
+func queryInt(r *http.Request, key string, def int) int {
+	n, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{
+		"message":           msg,
+		"documentation_url": "https://docs.github.com/rest",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,193 @@
+package githubmock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestSeedIsDeterministic(t *testing.T) {
+	now := func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+	a := New(Config{Repos: 50, Seed: 3, Now: now}).Repos()
+	b := New(Config{Repos: 50, Seed: 3, Now: now}).Repos()
+	c := New(Config{Repos: 50, Seed: 4, Now: now}).Repos()
+	require.Equal(t, a, b)
+	require.NotEqual(t, a, c)
+}
+
+func TestOrgReposPagination(t *testing.T) {
+	s := New(Config{Org: "acme-corp", Repos: 150})
+
+	rec := get(t, s, "/orgs/acme-corp/repos?per_page=100&page=1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var page []map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page, 100)
+	require.Equal(t, "acme-corp/service-000", page[0]["full_name"])
+	require.Contains(t, rec.Header().Get("Link"), `page=2>; rel="next"`)
+
+	rec = get(t, s, "/orgs/acme-corp/repos?per_page=100&page=2")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page, 50)
+	require.NotContains(t, rec.Header().Get("Link"), `rel="next"`)
+
+	for i, r := range s.Repos()[100:] {
+		if r.PushedAt.IsZero() {
+			require.Nil(t, page[i]["pushed_at"], r.Name)
+		} else {
+			require.Equal(t, r.PushedAt.Format(time.RFC3339), page[i]["pushed_at"], r.Name)
+		}
+		require.Equal(t, r.Visibility(), page[i]["visibility"], r.Name)
+		require.Equal(t, r.Fork, page[i]["fork"], r.Name)
+		require.Equal(t, r.DefaultBranch, page[i]["default_branch"], r.Name)
+		require.Len(t, page[i]["topics"], len(r.Topics), r.Name)
+		if r.Language == "" {
+			require.Nil(t, page[i]["language"], r.Name)
+		} else {
+			require.Equal(t, r.Language, page[i]["language"], r.Name)
+		}
+	}
+
+	rec = get(t, s, "/orgs/acme-corp/repos?per_page=100&page=3")
+	require.Equal(t, "[]\n", rec.Body.String())
+
+	require.Equal(t, http.StatusNotFound, get(t, s, "/orgs/other/repos").Code)
+}
+
+func TestOrgAndUserOrgs(t *testing.T) {
+	s := New(Config{Org: "acme-corp", Repos: 20, Seed: 2})
+	private := 0
+	for _, r := range s.Repos() {
+		if r.Private {
+			private++
+		}
+	}
+
+	rec := get(t, s, "/orgs/acme-corp")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var org struct {
+		Login             string `json:"login"`
+		PublicRepos       int    `json:"public_repos"`
+		TotalPrivateRepos int    `json:"total_private_repos"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &org))
+	require.Equal(t, "acme-corp", org.Login)
+	require.Equal(t, 20-private, org.PublicRepos)
+	require.Equal(t, private, org.TotalPrivateRepos)
+	require.Equal(t, http.StatusNotFound, get(t, s, "/orgs/other").Code)
+
+	rec = get(t, s, "/user/orgs")
+	require.JSONEq(t, `[{"login":"acme-corp"}]`, rec.Body.String())
+}
+
+func TestEnterpriseOrgsQuery(t *testing.T) {
+	s := New(Config{Org: "acme-corp", Repos: 1, Enterprise: "acme"})
+	query := func(slug string) string {
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"query":"...","variables":{"slug":"` + slug + `"}}`)
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", body))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+	require.JSONEq(t, `{"data":{"enterprise":{"organizations":{
+		"nodes":[{"login":"acme-corp"}],"pageInfo":{"hasNextPage":false,"endCursor":null}}}}}`, query("acme"))
+	require.Contains(t, query("other"), `"NOT_FOUND"`)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orgs/acme-corp", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestRepoEndpointsMatchSettings(t *testing.T) {
+	s := New(Config{Org: "acme-corp", Repos: 40, Seed: 9})
+	for _, r := range s.Repos() {
+		rec := get(t, s, "/repos/acme-corp/"+r.Name)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var body struct {
+			SecurityAndAnalysis *struct {
+				SecretScanning struct {
+					Status string `json:"status"`
+				} `json:"secret_scanning"`
+			} `json:"security_and_analysis"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		if r.SecretScanning == "" {
+			require.Nil(t, body.SecurityAndAnalysis)
+		} else {
+			require.Equal(t, r.SecretScanning, body.SecurityAndAnalysis.SecretScanning.Status)
+		}
+
+		want := http.StatusNotFound
+		if r.Dependabot {
+			want = http.StatusNoContent
+		}
+		require.Equal(t, want, get(t, s, "/repos/acme-corp/"+r.Name+"/vulnerability-alerts").Code)
+		require.Equal(t, r.CodeScanning, get(t, s, "/repos/acme-corp/"+r.Name+"/code-scanning/alerts").Code)
+
+		want = http.StatusNotFound
+		if r.HasCodeowners {
+			want = http.StatusOK
+		}
+		require.Equal(t, want, get(t, s, "/repos/acme-corp/"+r.Name+"/contents/"+r.Codeowners+"CODEOWNERS").Code)
+		want = http.StatusNotFound
+		if r.SecurityPolicy {
+			want = http.StatusOK
+		}
+		require.Equal(t, want, get(t, s, "/repos/acme-corp/"+r.Name+"/contents/SECURITY.md").Code)
+
+		var perms struct {
+			Enabled        bool   `json:"enabled"`
+			AllowedActions string `json:"allowed_actions"`
+		}
+		rec = get(t, s, "/repos/acme-corp/"+r.Name+"/actions/permissions")
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &perms))
+		require.Equal(t, r.ActionsEnabled, perms.Enabled)
+		if r.ActionsEnabled {
+			require.Equal(t, r.AllowedActions, perms.AllowedActions)
+		}
+	}
+}
+
+func TestRateLimitHeadersAndExhaustion(t *testing.T) {
+	now := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	s := New(Config{Repos: 1, RateLimit: 2, Now: func() time.Time { return now }})
+
+	rec := get(t, s, "/repos/acme-corp/service-000")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("X-RateLimit-Limit"))
+	require.Equal(t, "1", rec.Header().Get("X-RateLimit-Remaining"))
+	require.Equal(t, "1772463600", rec.Header().Get("X-RateLimit-Reset"))
+
+	require.Equal(t, http.StatusOK, get(t, s, "/repos/acme-corp/service-000").Code)
+	rec = get(t, s, "/repos/acme-corp/service-000")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+	require.Equal(t, "3600", rec.Header().Get("Retry-After"))
+
+	// The window resets after an hour.
+	now = now.Add(time.Hour)
+	require.Equal(t, http.StatusOK, get(t, s, "/repos/acme-corp/service-000").Code)
+}
+
+func TestTokenRequired(t *testing.T) {
+	s := New(Config{Repos: 1, Token: "secret"})
+	require.Equal(t, http.StatusUnauthorized, get(t, s, "/repos/acme-corp/service-000").Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/repos/acme-corp/service-000", nil)
+	req.Header.Set("Authorization", "token secret")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
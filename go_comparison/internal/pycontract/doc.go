@@ -0,0 +1,20 @@
+// Package pycontract holds the JSON contract between the Go scanner and the
+// Python implementation in temporal/, so the two SDKs' workers and clients
+// stay interchangeable on the same task queue.
+//
+// testdata has payloads the Python implementation wrote (regenerate them
+// with testdata/generate.py) for ScanInput, RepoInfo, RepoSecurityResult,
+// ScanProgress and the report. The tests decode each into the Go type and
+// encode it again; the result must be the same bytes once the keys are
+// sorted, except that:
+//
+//   - a key Python writes as null may be left out, since both sides read a
+//     missing optional field as its default, and
+//   - Go may add keys Python does not have, as long as their values are
+//     empty: both SDKs ignore keys they do not know, and an empty value is
+//     what a Python payload decodes to.
+//
+// A failing test means one side renamed or dropped a field the other still
+// uses. Fix the Go tag or add a compatibility alias on the Go type rather
+// than editing the fixtures.
+package pycontract
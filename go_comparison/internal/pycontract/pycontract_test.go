@@ -0,0 +1,220 @@
+package pycontract
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// zeroTime is how Go writes a time.Time no Python payload set.
+const zeroTime = "0001-01-01T00:00:00Z"
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return b
+}
+
+// empty reports whether v is the decoded form of a value a Python payload
+// leaves at its Go zero value.
+func empty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case string:
+		return v == "" || v == zeroTime
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// conform shapes got, the decoded Go encoding, like want, the decoded
+// Python one, as the package doc allows: keys Python wrote as null may be
+// missing from got, and keys only got has are dropped when empty. What is
+// left must equal want.
+func conform(t *testing.T, path string, got, want interface{}) interface{} {
+	t.Helper()
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return got
+		}
+		out := map[string]interface{}{}
+		for k, v := range g {
+			if _, known := w[k]; !known {
+				require.True(t, empty(v), "%s.%s: Go writes %v where Python has no such field", path, k, v)
+				continue
+			}
+			out[k] = conform(t, path+"."+k, v, w[k])
+		}
+		for k, v := range w {
+			if _, ok := out[k]; !ok && v == nil {
+				out[k] = nil
+			}
+		}
+		return out
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return got
+		}
+		out := make([]interface{}, len(g))
+		for i := range g {
+			out[i] = conform(t, path, g[i], w[i])
+		}
+		return out
+	}
+	return got
+}
+
+// roundTrip decodes the fixture name into a new value of v's type, encodes
+// it again and requires the bytes to match the fixture's once conformed.
+func roundTrip(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	fixture := readFixture(t, name)
+	ptr := reflect.New(reflect.TypeOf(v))
+	require.NoError(t, json.Unmarshal(fixture, ptr.Interface()), "decoding %s", name)
+	encoded, err := json.Marshal(ptr.Elem().Interface())
+	require.NoError(t, err)
+
+	var got, want interface{}
+	require.NoError(t, json.Unmarshal(encoded, &got))
+	require.NoError(t, json.Unmarshal(fixture, &want))
+	// Both sides are written the way the Python SDK writes them: compact,
+	// keys sorted. Go sorts map keys when it encodes.
+	canonical, err := json.Marshal(conform(t, "$", got, want))
+	require.NoError(t, err)
+	require.Equal(t, string(bytes.TrimSpace(fixture)), string(canonical), "%s does not survive a Go round trip", name)
+}
+
+func TestRoundTrip(t *testing.T) {
+	for name, v := range map[string]interface{}{
+		"scan_input.json":                 scanner.ScanInput{},
+		"scan_input_no_token.json":        scanner.ScanInput{},
+		"repo_info.json":                  scanner.RepoInfo{},
+		"repo_info_archived.json":         scanner.RepoInfo{},
+		"repo_security_result.json":       scanner.RepoSecurityResult{},
+		"repo_security_result_error.json": scanner.RepoSecurityResult{},
+		"repo_security_results.json":      []scanner.RepoSecurityResult{},
+		"scan_progress.json":              scanner.ScanProgress{},
+		"report.json":                     scanner.Report{},
+		"report_empty.json":               scanner.Report{},
+		"report_cancelled.json":           scanner.Report{},
+	} {
+		t.Run(name, func(t *testing.T) { roundTrip(t, name, v) })
+	}
+}
+
+func TestFixturesDecodeToGoValues(t *testing.T) {
+	// A guard against a tag that decodes into the wrong field: the round
+	// trip alone would pass if a renamed field was dropped and re-added.
+	var in scanner.ScanInput
+	require.NoError(t, json.Unmarshal(readFixture(t, "scan_input.json"), &in))
+	require.Equal(t, "acme-corp", in.Org)
+	require.Equal(t, "ghp_contract", *in.Token)
+
+	var r scanner.RepoSecurityResult
+	require.NoError(t, json.Unmarshal(readFixture(t, "repo_security_result.json"), &r))
+	require.Equal(t, scanner.StatusEnabled, r.SecretScanning)
+	require.Equal(t, scanner.StatusDisabled, r.DependabotAlerts)
+	require.Equal(t, scanner.StatusNotConfigured, r.CodeScanning)
+	require.Nil(t, r.Error)
+
+	var p scanner.ScanProgress
+	require.NoError(t, json.Unmarshal(readFixture(t, "scan_progress.json"), &p))
+	require.Equal(t, scanner.ScanScanning, p.Status)
+	require.Equal(t, 20, p.ScannedRepos)
+
+	var report scanner.Report
+	require.NoError(t, json.Unmarshal(readFixture(t, "report.json"), &report))
+	require.Equal(t, "40.0%", report.ComplianceRate)
+	require.Equal(t, []string{"web", "docs"}, report.NonCompliantRepos)
+	require.Equal(t, 3, *report.DependabotEnabled)
+}
+
+// jsonKind is the JSON type of a decoded value.
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+// requireSameKeys requires report to have every key of the Python report
+// in fixture, with a value of the same JSON type.
+func requireSameKeys(t *testing.T, fixture string, report map[string]interface{}) {
+	t.Helper()
+	var python map[string]interface{}
+	require.NoError(t, json.Unmarshal(readFixture(t, fixture), &python))
+	for key, want := range python {
+		got, ok := report[key]
+		require.True(t, ok, "the Go report has no %q", key)
+		require.Equal(t, jsonKind(want), jsonKind(got), "%q", key)
+	}
+}
+
+func buildReport(t *testing.T, in scanner.ReportInput) map[string]interface{} {
+	t.Helper()
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestActivityEnvironment()
+	a := &scanner.Activities{}
+	env.RegisterActivity(a)
+	in.Policy = scanner.DefaultCompliancePolicy()
+	val, err := env.ExecuteActivity(a.BuildReport, in)
+	require.NoError(t, err)
+	var report map[string]interface{}
+	require.NoError(t, val.Get(&report))
+	return report
+}
+
+func TestGoReportHasPythonKeys(t *testing.T) {
+	// The workflow's report is a map built by BuildReport, not from
+	// Report's tags; a client written against the Python report reads it
+	// too. Python keeps an errored repo's result; Go counts it instead.
+	var results, scanned []scanner.RepoSecurityResult
+	require.NoError(t, json.Unmarshal(readFixture(t, "repo_security_results.json"), &results))
+	errors := 0
+	for _, r := range results {
+		if r.Error != nil {
+			errors++
+			continue
+		}
+		scanned = append(scanned, r)
+	}
+
+	report := buildReport(t, scanner.ReportInput{Org: "acme-corp", Results: scanned, Errors: errors})
+	requireSameKeys(t, "report.json", report)
+	require.Equal(t, "acme-corp", report["org"])
+	require.ElementsMatch(t, []interface{}{"web", "docs"}, report["non_compliant_repos"])
+
+	requireSameKeys(t, "report_empty.json", buildReport(t, scanner.ReportInput{Org: "acme-corp"}))
+	requireSameKeys(t, "report_cancelled.json", buildReport(t, scanner.ReportInput{
+		Org: "acme-corp", Cancelled: true, CancelReason: "Manual cancellation",
+	}))
+}
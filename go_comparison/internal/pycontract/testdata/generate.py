@@ -0,0 +1,118 @@
+"""
+Regenerate the pycontract fixtures from the Python implementation.
+
+    python go_comparison/internal/pycontract/testdata/generate.py
+
+Each fixture is the payload data the Python SDK's default JSON converter
+writes for a value built by temporal/models.py or temporal/activities.py:
+compact, with sorted keys. The Go tests in ../ decode them and encode them
+back; see doc.go.
+
+Only the dataclasses and generate_report run. When temporalio or requests
+is not installed, stand-ins that do nothing are put in their place so the
+fixtures can be regenerated without the worker's dependencies.
+"""
+
+import dataclasses
+import json
+import logging
+import pathlib
+import sys
+import types
+
+HERE = pathlib.Path(__file__).resolve().parent
+sys.path.insert(0, str(HERE.parents[3]))
+
+try:
+    import temporalio.converter
+except ImportError:
+    temporalio = types.ModuleType("temporalio")
+    temporalio.activity = types.SimpleNamespace(
+        defn=lambda fn=None, **_: fn if fn else (lambda f: f),
+        logger=logging.getLogger("activity"),
+    )
+    temporalio.converter = None
+    sys.modules["temporalio"] = temporalio
+try:
+    import requests  # noqa: F401
+except ImportError:
+    requests = types.ModuleType("requests")
+    requests.exceptions = types.SimpleNamespace(Timeout=TimeoutError, ConnectionError=ConnectionError)
+    sys.modules["requests"] = requests
+
+from temporal.activities import generate_report  # noqa: E402
+from temporal.models import (  # noqa: E402
+    RepoInfo,
+    RepoSecurityResult,
+    ScanInput,
+    ScanProgress,
+    SecurityStatus,
+)
+
+SCANNED_AT = "2026-03-02T14:00:00.123456+00:00"
+
+
+def encode(value) -> bytes:
+    """The payload data the Python SDK writes for value."""
+    if temporalio.converter is not None:
+        return temporalio.converter.default().payload_converter.to_payloads([value])[0].data
+    if dataclasses.is_dataclass(value):
+        value = dataclasses.asdict(value)
+    elif isinstance(value, list):
+        value = [dataclasses.asdict(v) if dataclasses.is_dataclass(v) else v for v in value]
+    return json.dumps(value, separators=(",", ":"), sort_keys=True).encode()
+
+
+def result(repo: str, secret: str, dependabot: str, code: str, error: str | None = None) -> RepoSecurityResult:
+    return RepoSecurityResult(
+        repository=repo,
+        secret_scanning=secret,
+        dependabot_alerts=dependabot,
+        code_scanning=code,
+        error=error,
+        scanned_at=SCANNED_AT,
+    )
+
+
+E, D, NC, NA, U = (
+    SecurityStatus.ENABLED,
+    SecurityStatus.DISABLED,
+    SecurityStatus.NOT_CONFIGURED,
+    SecurityStatus.NO_ACCESS,
+    SecurityStatus.UNKNOWN,
+)
+results = [
+    result("payments-api", E, E, E),
+    result("web", E, D, NC),
+    result("docs", D, E, NA),
+    result("ledger", E, E, E),
+    result("gone", U, U, U, error="Repository not found"),
+]
+
+# The keys the workflow adds to generate_report's dict when cancel_scan
+# stopped it before any repo was scanned (see workflows.py).
+cancelled = generate_report("acme-corp", [])
+cancelled["cancelled"] = True
+cancelled["cancel_reason"] = "Manual cancellation"
+cancelled["repos_scanned_before_cancel"] = 0
+
+fixtures = {
+    "scan_input.json": ScanInput(org="acme-corp", token="ghp_contract"),
+    "scan_input_no_token.json": ScanInput(org="acme-corp"),
+    "repo_info.json": RepoInfo(name="payments-api", full_name="acme-corp/payments-api", private=True),
+    "repo_info_archived.json": RepoInfo(name="legacy", full_name="acme-corp/legacy", archived=True),
+    "repo_security_result.json": results[1],
+    "repo_security_result_error.json": results[4],
+    "repo_security_results.json": results,
+    "scan_progress.json": ScanProgress(
+        org="acme-corp", total_repos=25, scanned_repos=20, compliant_repos=12,
+        non_compliant_repos=8, errors=1, status="scanning",
+    ),
+    "report.json": generate_report("acme-corp", results),
+    "report_empty.json": generate_report("acme-corp", []),
+    "report_cancelled.json": cancelled,
+}
+
+for name, value in fixtures.items():
+    (HERE / name).write_bytes(encode(value))
+    print("wrote", name)
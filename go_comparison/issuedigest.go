@@ -0,0 +1,428 @@
+package scanner
+
+// =============================================================================
+// Issue digest — one checklist issue instead of an issue per repo
+// =============================================================================
+//
+// The issue_digest sink keeps a single GitHub issue in a tracking repo (the
+// sink's repo option) whose body is a checklist of the org's non-compliant
+// repos and the checks each fails; with per_team=true it keeps one issue per
+// team of a team scan instead. Every scan updates the issue in place:
+//
+//   - a repo that fails gets an unticked item, appended in name order the
+//     first time it fails and rewritten when its failures change;
+//   - a repo the scan found compliant has its item ticked, and only the box
+//     changes, so the item still says what was fixed;
+//   - items of repos the scan cannot vouch for are left alone (digestCovers).
+//
+// Each item ends in an anchor, <!-- repo:NAME -->, that the next scan finds
+// it by, so an update rewrites only the items that changed and keeps the
+// order, the line endings and anything written around the checklist. A scan
+// that changes nothing writes nothing.
+//
+// The issue is found among the repo's open issues labelled
+// security-scan-digest by the marker on its first line, which names the org
+// and team. The issues are written with the worker's GitHub token (see
+// secrets.go), which needs to be able to write issues in the tracking repo.
+// A created issue has an idempotency key (see idempotency.go), so a retried
+// delivery reports it as created rather than updated.
+// =============================================================================
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// RemediationUnchanged is the action on a digest issue a scan had nothing
+// to change in.
+const RemediationUnchanged = "unchanged"
+
+// ErrTypeIssueDigestDenied is the ApplicationError type for a tracking repo
+// the worker's token cannot write issues in.
+const ErrTypeIssueDigestDenied = "ISSUE_DIGEST_DENIED"
+
+const (
+	digestLabel = "security-scan-digest"
+	digestBegin = "<!-- security-scan-digest:begin -->"
+	digestEnd   = "<!-- security-scan-digest:end -->"
+)
+
+// digestItem matches a checklist item: its box, its text and its repo.
+var digestItem = regexp.MustCompile(`^- \[([ xX])\] (.*?) ?<!-- repo:(\S+) -->$`)
+
+// digestGroup is what one digest issue covers.
+type digestGroup struct {
+	Team     string
+	Failures map[string][]string
+	// Covers reports whether the scan vouches for a repo (digestCovers).
+	Covers func(repo string) bool
+}
+
+// digestGroups splits r into the digest issues to keep: one for the org,
+// or with perTeam one per team of a team scan. Groups without failures are
+// kept too, since their issue may have items to tick.
+func digestGroups(r Report, perTeam bool) []digestGroup {
+	failures := map[string][]string{}
+	for repo, checks := range r.failures(r.RepoFailures == nil) {
+		if len(checks) > 0 {
+			failures[repo] = checks
+		}
+	}
+	if !perTeam || len(r.TeamRepos) == 0 {
+		var scope []string
+		for _, repos := range r.TeamRepos {
+			scope = append(scope, repos...)
+		}
+		return []digestGroup{{Failures: failures, Covers: digestCovers(r, scope)}}
+	}
+	var groups []digestGroup
+	for _, team := range sortedKeys(r.TeamRepos) {
+		g := digestGroup{Team: team, Failures: map[string][]string{}, Covers: digestCovers(r, r.TeamRepos[team])}
+		for _, repo := range r.TeamRepos[team] {
+			if checks, ok := failures[repo]; ok {
+				g.Failures[repo] = checks
+			}
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// digestCovers returns whether r found a repo compliant if it does not
+// fail: r checked the repo, among scope when that is not empty, and read
+// every check. A scan with errors vouches for nothing, as its errored repos
+// are not listed; neither does one of a repo list or a topic filter, which
+// does not say which repos it selected.
+func digestCovers(r Report, scope []string) func(string) bool {
+	if r.Errors > 0 || r.RepoList || len(r.IncludeTopics) > 0 || len(r.ExcludeTopics) > 0 {
+		return func(string) bool { return false }
+	}
+	unresolved := map[string]bool{}
+	for _, list := range [][]string{
+		r.IndeterminateRepos, r.RetryLaterRepos, r.UnscannedRepos,
+		r.CancelledInFlightRepos, r.TimedOutInBatchRepos, r.ArchivedRepos,
+	} {
+		for _, repo := range list {
+			unresolved[repo] = true
+		}
+	}
+	for _, d := range r.DisappearedRepos {
+		unresolved[d.Repository] = true
+	}
+	var inScope map[string]bool
+	if len(scope) > 0 {
+		inScope = make(map[string]bool, len(scope))
+		for _, repo := range scope {
+			inScope[repo] = true
+		}
+	}
+	return func(repo string) bool {
+		return !unresolved[repo] && (inScope == nil || inScope[repo])
+	}
+}
+
+// marker is the first line of g's issue, which finds it again.
+func (g digestGroup) marker(org string) string {
+	if g.Team != "" {
+		return fmt.Sprintf("<!-- security-scan-digest org=%s team=%s -->", org, g.Team)
+	}
+	return fmt.Sprintf("<!-- security-scan-digest org=%s -->", org)
+}
+
+// title is the issue's title when it is created; updates leave it alone.
+func (g digestGroup) title(org string) string {
+	if g.Team != "" {
+		return fmt.Sprintf("Security scan digest: %s team %s", org, g.Team)
+	}
+	return "Security scan digest: " + org
+}
+
+// digestItemLine is the checklist item of repo.
+func digestItemLine(repo string, failures []string) string {
+	return fmt.Sprintf("- [ ] `%s`: %s <!-- repo:%s -->", repo, strings.Join(failures, ", "), repo)
+}
+
+// newDigestBody is the body of g's issue when it is created.
+func (g digestGroup) newDigestBody(org string) string {
+	owner := org
+	if g.Team != "" {
+		owner = org + " team " + g.Team
+	}
+	lines := []string{
+		g.marker(org),
+		fmt.Sprintf("Repositories of %s that fail required security checks. Each scan updates this checklist: "+
+			"a repository is ticked once it passes them all and added when it starts failing. "+
+			"Text outside the checklist is left alone.", owner),
+		"",
+		digestBegin,
+		digestEnd,
+		"",
+	}
+	return g.mergeDigest(strings.Join(lines, "\n"))
+}
+
+// mergeDigest brings the checklist in body up to date with g; see the top
+// of this file. A body without a checklist gets one at the end.
+func (g digestGroup) mergeDigest(body string) string {
+	newline := "\n"
+	if strings.Contains(body, "\r\n") {
+		newline = "\r\n"
+	}
+	begin := strings.Index(body, digestBegin)
+	end := strings.Index(body, digestEnd)
+	if begin < 0 || end < begin {
+		if body != "" && !strings.HasSuffix(body, newline) {
+			body += newline
+		}
+		body += newline + digestBegin + newline + digestEnd + newline
+		begin, end = strings.LastIndex(body, digestBegin), strings.LastIndex(body, digestEnd)
+	}
+	start := begin + len(digestBegin)
+
+	var lines []string
+	if inner := strings.TrimPrefix(body[start:end], newline); inner != "" {
+		lines = strings.SplitAfter(inner, newline)
+		if last := len(lines) - 1; lines[last] == "" {
+			lines = lines[:last]
+		} else {
+			lines[last] += newline
+		}
+	}
+	seen := map[string]bool{}
+	for i, line := range lines {
+		text := strings.TrimSuffix(line, newline)
+		m := digestItem.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		repo := m[3]
+		seen[repo] = true
+		if failures, ok := g.Failures[repo]; ok {
+			lines[i] = digestItemLine(repo, failures) + newline
+		} else if m[1] == " " && g.Covers(repo) {
+			lines[i] = strings.Replace(text, "- [ ]", "- [x]", 1) + newline
+		}
+	}
+	for _, repo := range sortedKeys(g.Failures) {
+		if !seen[repo] {
+			lines = append(lines, digestItemLine(repo, g.Failures[repo])+newline)
+		}
+	}
+	return body[:start] + newline + strings.Join(lines, "") + body[end:]
+}
+
+// digestIssue is the part of a GitHub issue the digest reads.
+type digestIssue struct {
+	Number int    `json:"number"`
+	Body   string `json:"body"`
+	URL    string `json:"html_url"`
+}
+
+// digestProgress is the issue_digest sink's heartbeat: the issues already
+// written.
+type digestProgress struct {
+	Result RemediationResult `json:"result"`
+}
+
+// updateIssueDigests keeps r's digest issues in repo, the tracking repo's
+// full name.
+func (a *Activities) updateIssueDigests(ctx context.Context, repo string, perTeam bool, r Report) (*RemediationResult, error) {
+	headers, err := a.githubHeaders(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	progress := digestProgress{Result: RemediationResult{Tracker: "github", Project: repo, Issues: []RemediationIssue{}}}
+	if activity.HasHeartbeatDetails(ctx) {
+		var prev digestProgress
+		if err := activity.GetHeartbeatDetails(ctx, &prev); err == nil {
+			progress = prev
+		}
+	}
+	done := make(map[string]bool, len(progress.Result.Issues))
+	for _, i := range progress.Result.Issues {
+		done[i.Team] = true
+	}
+
+	var open []digestIssue
+	for page := 1; ; page++ {
+		var issues []digestIssue
+		path := fmt.Sprintf("/repos/%s/issues?state=open&labels=%s&sort=created&direction=asc&per_page=100&page=%d", repo, digestLabel, page)
+		if err := a.githubJSON(ctx, http.MethodGet, a.apiURL("%s", path), headers, nil, &issues); err != nil {
+			return nil, err
+		}
+		open = append(open, issues...)
+		if len(issues) < 100 {
+			break
+		}
+	}
+
+	for _, g := range digestGroups(r, perTeam) {
+		if done[g.Team] {
+			continue
+		}
+		issue := RemediationIssue{Team: g.Team, Repos: sortedKeys(g.Failures)}
+		marker := g.marker(r.Org)
+		var existing *digestIssue
+		for i := range open {
+			if strings.HasPrefix(strings.TrimSpace(open[i].Body), marker) {
+				existing = &open[i]
+				break
+			}
+		}
+		op := newRemediationKey(ctx, repo+" "+marker, remediationIssueDigest)
+		rec := a.loadIdempotency(ctx, op)
+		switch {
+		case rec != nil && rec.Done && json.Unmarshal(rec.Result, &issue) == nil:
+			// Written by an attempt whose answer was lost.
+		case existing != nil:
+			issue.Key = fmt.Sprintf("%s#%d", repo, existing.Number)
+			body := g.mergeDigest(existing.Body)
+			switch {
+			case rec != nil && !rec.Done:
+				// An earlier attempt of this run created it.
+				issue.Action = RemediationCreated
+			case body == existing.Body:
+				issue.Action = RemediationUnchanged
+			default:
+				issue.Action = RemediationUpdated
+			}
+			if body != existing.Body {
+				path := fmt.Sprintf("/repos/%s/issues/%d", repo, existing.Number)
+				if err := a.githubJSON(ctx, http.MethodPatch, a.apiURL("%s", path), headers, map[string]string{"body": body}, nil); err != nil {
+					return nil, digestError(err, progress.Result)
+				}
+			}
+		case len(g.Failures) == 0:
+			// Nothing to fix and no issue to tick items in.
+			continue
+		default:
+			a.saveIdempotency(ctx, op, false, nil)
+			var created digestIssue
+			req := map[string]interface{}{"title": g.title(r.Org), "body": g.newDigestBody(r.Org), "labels": []string{digestLabel}}
+			if err := a.githubJSON(ctx, http.MethodPost, a.apiURL("/repos/%s/issues", repo), headers, req, &created); err != nil {
+				return nil, digestError(err, progress.Result)
+			}
+			issue.Key, issue.Action = fmt.Sprintf("%s#%d", repo, created.Number), RemediationCreated
+		}
+		a.saveIdempotency(ctx, op, true, issue)
+		progress.Result.Issues = append(progress.Result.Issues, issue)
+		activity.RecordHeartbeat(ctx, progress)
+	}
+	return &progress.Result, nil
+}
+
+// digestError is err with the issues written before it as details when it
+// is not retryable, as every other group would fail the same way.
+func digestError(err error, written RemediationResult) error {
+	if appErr, ok := err.(*temporal.ApplicationError); ok && appErr.NonRetryable() {
+		written.Error = err.Error()
+		return temporal.NewNonRetryableApplicationError(appErr.Message(), appErr.Type(), nil, written)
+	}
+	return err
+}
+
+// githubJSON sends one GitHub API request with body, if not nil, as JSON
+// and decodes the answer into out, if not nil. A token that may not read
+// or write the issues is not retried.
+func (a *Activities) githubJSON(ctx context.Context, method, url string, headers map[string]string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidInput, nil)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidInput, nil)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := a.do(req)
+	if err != nil {
+		return requestError(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return requestError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusGone, http.StatusUnprocessableEntity:
+		// GitHub answers 404 for a repo the token cannot see and 410 when
+		// the repo has issues turned off.
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("GitHub refused %s %s: %s", method, req.URL.Path, sanitizeErrorMessage(responseSummary(resp.StatusCode, data))),
+			ErrTypeIssueDigestDenied, nil)
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("GitHub %s %s: %s", method, req.URL.Path, sanitizeErrorMessage(responseSummary(resp.StatusCode, data)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return parseError("issues of the tracking repo", err)
+	}
+	return nil
+}
+
+// validateIssueDigestOptions checks the issue_digest sink's options.
+func validateIssueDigestOptions(o map[string]string) error {
+	if err := ValidateRepoFullName(o["repo"]); err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+	if v, ok := o["per_team"]; ok {
+		if _, err := strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("per_team %q is not true or false", v)
+		}
+	}
+	return nil
+}
+
+func (a *Activities) deliverToIssueDigest(ctx context.Context, in DeliverReportInput) (SinkDelivery, error) {
+	b, err := json.Marshal(in.Report)
+	if err != nil {
+		return SinkDelivery{}, temporal.NewNonRetryableApplicationError("encoding report: "+err.Error(), ErrTypeInvalidInput, nil)
+	}
+	report, err := ParseReport(b)
+	if err != nil {
+		return SinkDelivery{}, temporal.NewNonRetryableApplicationError("decoding report: "+err.Error(), ErrTypeInvalidInput, nil)
+	}
+	repo := in.Sink.Options["repo"]
+	perTeam, _ := strconv.ParseBool(in.Sink.Options["per_team"])
+	result, err := a.updateIssueDigests(ctx, repo, perTeam, report)
+	if err != nil {
+		return SinkDelivery{}, err
+	}
+	counts := map[string]int{}
+	for _, i := range result.Issues {
+		counts[i.Action]++
+	}
+	var parts []string
+	for _, action := range []string{RemediationCreated, RemediationUpdated, RemediationUnchanged} {
+		if counts[action] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[action], action))
+		}
+	}
+	summary := "no issues"
+	if len(parts) > 0 {
+		summary = "digest issues: " + strings.Join(parts, ", ")
+	}
+	return SinkDelivery{Destination: repo, Summary: summary}, nil
+}
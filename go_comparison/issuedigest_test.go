@@ -0,0 +1,250 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+// digestReport is a full-org scan of acme whose failures are failing.
+func digestReport(failing map[string][]string) Report {
+	return Report{Org: "acme", RepoFailures: failing, IndeterminateRepos: []string{"legacy"}}
+}
+
+func requireGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "issuedigest", name)
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(want), got)
+}
+
+func TestIssueDigestBodyGolden(t *testing.T) {
+	existing, err := os.ReadFile(filepath.Join("testdata", "issuedigest", "existing.md"))
+	require.NoError(t, err)
+
+	report := digestReport(map[string][]string{
+		"api":     {"dependabot"},
+		"web":     {"dependabot"},
+		"billing": {"secret_scanning"},
+		"zeta":    {"code_scanning"},
+		"alpha":   {"codeowners"},
+	})
+	g := digestGroups(report, false)[0]
+
+	t.Run("new", func(t *testing.T) {
+		requireGolden(t, "new.md", g.newDigestBody("acme"))
+	})
+
+	t.Run("merged", func(t *testing.T) {
+		merged := g.mergeDigest(string(existing))
+		requireGolden(t, "merged.md", merged)
+		require.Equal(t, merged, g.mergeDigest(merged), "a second merge of the same scan changes nothing")
+	})
+
+	t.Run("line endings", func(t *testing.T) {
+		crlf := strings.ReplaceAll(string(existing), "\n", "\r\n")
+		merged := g.mergeDigest(crlf)
+		require.NotContains(t, strings.ReplaceAll(merged, "\r\n", ""), "\n")
+		want, err := os.ReadFile(filepath.Join("testdata", "issuedigest", "merged.md"))
+		require.NoError(t, err)
+		require.Equal(t, strings.ReplaceAll(string(want), "\n", "\r\n"), merged)
+	})
+
+	t.Run("checklist removed", func(t *testing.T) {
+		body := "<!-- security-scan-digest org=acme -->\nSomeone deleted the checklist."
+		requireGolden(t, "appended.md", g.mergeDigest(body))
+	})
+}
+
+func TestIssueDigestMergeIsMinimal(t *testing.T) {
+	existing, err := os.ReadFile(filepath.Join("testdata", "issuedigest", "existing.md"))
+	require.NoError(t, err)
+	g := digestGroups(digestReport(map[string][]string{
+		"api":    {"code_scanning", "dependabot"},
+		"docs":   {"secret_scanning"},
+		"legacy": {"code_scanning"},
+		"web":    {"dependabot"},
+	}), false)[0]
+	require.Equal(t, string(existing), g.mergeDigest(string(existing)), "the failures the issue lists already")
+
+	// Only docs' box changes once it is fixed.
+	g.Failures = map[string][]string{"api": {"code_scanning", "dependabot"}, "web": {"dependabot"}}
+	merged := g.mergeDigest(string(existing))
+	require.Equal(t, strings.Replace(string(existing), "- [ ] `docs`", "- [x] `docs`", 1), merged)
+}
+
+func TestDigestCovers(t *testing.T) {
+	full := Report{
+		Org: "acme", IndeterminateRepos: []string{"a"}, RetryLaterRepos: []string{"b"},
+		UnscannedRepos: []string{"c"}, ArchivedRepos: []string{"d"},
+		DisappearedRepos: []DisappearedRepo{{Repository: "e"}},
+	}
+	covers := digestCovers(full, nil)
+	for _, repo := range []string{"a", "b", "c", "d", "e"} {
+		require.False(t, covers(repo), repo)
+	}
+	require.True(t, covers("api"))
+	require.False(t, digestCovers(full, []string{"web"})("api"), "outside the team")
+	require.True(t, digestCovers(full, []string{"web"})("web"))
+
+	for name, r := range map[string]Report{
+		"errors":    {Org: "acme", Errors: 1},
+		"repo list": {Org: "acme", RepoList: true},
+		"topics":    {Org: "acme", IncludeTopics: []string{"pci"}},
+	} {
+		require.False(t, digestCovers(r, nil)("api"), name)
+	}
+}
+
+func TestDigestGroups(t *testing.T) {
+	r := Report{
+		Org:          "acme",
+		RepoFailures: map[string][]string{"api": {"dependabot"}, "web": {}, "billing": {"code_scanning"}},
+		TeamRepos:    map[string][]string{"platform": {"api", "web"}, "payments": {"billing"}, "docs": {"handbook"}},
+	}
+
+	org := digestGroups(r, false)
+	require.Len(t, org, 1)
+	require.Equal(t, map[string][]string{"api": {"dependabot"}, "billing": {"code_scanning"}}, org[0].Failures)
+	require.True(t, org[0].Covers("handbook"))
+	require.False(t, org[0].Covers("other"), "not in the selected teams")
+
+	teams := digestGroups(r, true)
+	var names []string
+	for _, g := range teams {
+		names = append(names, g.Team)
+	}
+	require.Equal(t, []string{"docs", "payments", "platform"}, names, "docs has nothing to fix but may have items to tick")
+	require.Empty(t, teams[0].Failures)
+	require.Equal(t, map[string][]string{"api": {"dependabot"}}, teams[2].Failures)
+	require.Equal(t, "<!-- security-scan-digest org=acme team=platform -->", teams[2].marker("acme"))
+}
+
+func TestValidateIssueDigestOptions(t *testing.T) {
+	require.NoError(t, ValidateReportSinks([]ReportSinkSpec{{Type: SinkIssueDigest, Options: map[string]string{"repo": "acme/security", "per_team": "true"}}}))
+	require.ErrorContains(t, ValidateReportSinks([]ReportSinkSpec{{Type: SinkIssueDigest}}), "repo")
+	require.ErrorContains(t, ValidateReportSinks([]ReportSinkSpec{{Type: SinkIssueDigest, Options: map[string]string{"repo": "security"}}}), "repo: ")
+	require.ErrorContains(t, ValidateReportSinks([]ReportSinkSpec{{Type: SinkIssueDigest, Options: map[string]string{"repo": "acme/security", "per_team": "yes please"}}}), "per_team")
+}
+
+// fakeIssues is a GitHub repo's issues.
+type fakeIssues struct {
+	t       *testing.T
+	mu      sync.Mutex
+	issues  []digestIssue
+	created int
+	patched int
+}
+
+func (f *fakeIssues) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	require.Equal(f.t, "token worker-token", r.Header.Get("Authorization"))
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/security/issues":
+		require.Equal(f.t, digestLabel, r.URL.Query().Get("labels"))
+		require.Equal(f.t, "open", r.URL.Query().Get("state"))
+		json.NewEncoder(w).Encode(f.issues)
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/security/issues":
+		var req struct {
+			Title  string   `json:"title"`
+			Body   string   `json:"body"`
+			Labels []string `json:"labels"`
+		}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(f.t, []string{digestLabel}, req.Labels)
+		issue := digestIssue{Number: 100 + len(f.issues), Body: req.Body}
+		f.issues = append(f.issues, issue)
+		f.created++
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(issue)
+	case r.Method == http.MethodPatch:
+		var req struct {
+			Body string `json:"body"`
+		}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+		for i := range f.issues {
+			if r.URL.Path == fmt.Sprintf("/repos/acme/security/issues/%d", f.issues[i].Number) {
+				f.issues[i].Body = req.Body
+				f.patched++
+				json.NewEncoder(w).Encode(f.issues[i])
+				return
+			}
+		}
+		http.NotFound(w, r)
+	default:
+		f.t.Fatalf("unexpected %s %s", r.Method, r.URL)
+	}
+}
+
+func TestDeliverReportToIssueDigest(t *testing.T) {
+	f := &fakeIssues{t: t, issues: []digestIssue{{Number: 7, Body: "Unrelated issue with the label"}}}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	t.Setenv("DIGEST_TEST_TOKEN", "worker-token")
+	env := newActivityEnv(&Activities{HTTPClient: srv.Client(), BaseURL: srv.URL, Secrets: EnvSecretSource{Var: "DIGEST_TEST_TOKEN"}})
+	deliver := func(r Report, options map[string]string) string {
+		b, err := json.Marshal(r)
+		require.NoError(t, err)
+		var report map[string]interface{}
+		require.NoError(t, json.Unmarshal(b, &report))
+		val, err := env.ExecuteActivity("DeliverReport", DeliverReportInput{
+			Sink:   ReportSinkSpec{Type: SinkIssueDigest, Options: options},
+			Report: report,
+		})
+		require.NoError(t, err)
+		var d *SinkDelivery
+		require.NoError(t, val.Get(&d))
+		require.Equal(t, "acme/security", d.Destination)
+		return d.Summary
+	}
+	org := map[string]string{"repo": "acme/security"}
+
+	require.Equal(t, "digest issues: 1 created", deliver(digestReport(map[string][]string{"api": {"dependabot"}}), org))
+	require.Equal(t, "digest issues: 1 unchanged", deliver(digestReport(map[string][]string{"api": {"dependabot"}}), org))
+	require.Equal(t, 0, f.patched, "nothing to change, nothing written")
+
+	require.Equal(t, "digest issues: 1 updated", deliver(digestReport(map[string][]string{"web": {"code_scanning"}}), org))
+	body := f.issues[1].Body
+	require.Contains(t, body, "- [x] `api`: dependabot <!-- repo:api -->")
+	require.Contains(t, body, "- [ ] `web`: code_scanning <!-- repo:web -->")
+	require.Equal(t, "Unrelated issue with the label", f.issues[0].Body)
+
+	// Per team, only teams with something to fix get a new issue.
+	teams := digestReport(map[string][]string{"api": {"dependabot"}})
+	teams.TeamRepos = map[string][]string{"platform": {"api"}, "docs": {"handbook"}}
+	require.Equal(t, "digest issues: 1 created", deliver(teams, map[string]string{"repo": "acme/security", "per_team": "true"}))
+	require.True(t, strings.HasPrefix(f.issues[2].Body, "<!-- security-scan-digest org=acme team=platform -->"))
+	require.Equal(t, 2, f.created)
+}
+
+func TestDeliverReportToIssueDigestDenied(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	}))
+	defer srv.Close()
+	env := newActivityEnv(&Activities{HTTPClient: srv.Client(), BaseURL: srv.URL})
+	_, err := env.ExecuteActivity("DeliverReport", DeliverReportInput{
+		Sink:   ReportSinkSpec{Type: SinkIssueDigest, Options: map[string]string{"repo": "acme/security"}},
+		Report: map[string]interface{}{"org": "acme", "non_compliant_repos": []string{"api"}},
+	})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, ErrTypeIssueDigestDenied, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+}
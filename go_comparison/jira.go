@@ -0,0 +1,440 @@
+package scanner
+
+// =============================================================================
+// Jira remediation — one issue per team
+// =============================================================================
+//
+// Teams that track work in Jira get one issue per team listing their
+// non-compliant repos and the checks each one fails. When the worker has
+// Jira configured (Activities.Jira), the workflow runs CreateJiraIssues
+// after the report, and the report's remediation section lists the issues
+// it created or updated.
+//
+// Teams come from the report's team_repos, so a team scan files one issue
+// per selected team (a repo in two teams is on both); any other scan files a
+// single issue for the org. An issue is found again by its label,
+// security-scan:<org> or security-scan:<org>:<team>, among the project's
+// open issues, so the next scan updates it instead of filing another. The
+// heartbeat records every issue as it is written, and each write has an
+// idempotency key (see idempotency.go), so a retried activity does not
+// file a team's issue twice either, even when only the answer to the write
+// was lost.
+//
+// JiraConfig.MaxCreate caps the issues one scan creates; updates are not
+// capped. With DryRun the issues are looked up but nothing is written.
+// Like forwarding, this never fails the scan: a rejected API token is
+// recorded in the remediation section.
+// =============================================================================
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Defaults for JiraConfig's zero values.
+const (
+	DefaultJiraIssueType = "Task"
+	DefaultJiraMaxCreate = 10
+	jiraLabel            = "security-scan"
+)
+
+// ErrTypeJiraUnauthorized is the ApplicationError type for a Jira API token
+// Jira rejects.
+const ErrTypeJiraUnauthorized = "JIRA_UNAUTHORIZED"
+
+// Remediation issue actions.
+const (
+	RemediationCreated     = "created"
+	RemediationUpdated     = "updated"
+	RemediationWouldCreate = "would_create"
+	RemediationWouldUpdate = "would_update"
+	RemediationSkipped     = "skipped"
+)
+
+// JiraConfig is a Jira Cloud project to file remediation issues in.
+type JiraConfig struct {
+	// URL is the site, e.g. https://acme.atlassian.net.
+	URL string
+	// Email and APIToken authenticate as the issues' reporter.
+	Email    string
+	APIToken string
+	Project  string
+	// IssueType defaults to DefaultJiraIssueType.
+	IssueType string
+	// MaxCreate caps the issues one scan creates; 0 means
+	// DefaultJiraMaxCreate.
+	MaxCreate int
+	DryRun    bool
+	// HTTPClient defaults to the Activities' client.
+	HTTPClient *http.Client
+}
+
+// JiraInput is the input to CreateJiraIssues.
+type JiraInput struct {
+	Report map[string]interface{} `json:"report"`
+}
+
+// RemediationResult is the report's remediation section.
+type RemediationResult struct {
+	Tracker string             `json:"tracker"`
+	Project string             `json:"project,omitempty"`
+	DryRun  bool               `json:"dry_run,omitempty"`
+	Issues  []RemediationIssue `json:"issues"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// RemediationIssue is one team's issue. Key is empty for an issue that
+// was not created, because of DryRun or MaxCreate.
+type RemediationIssue struct {
+	Team   string   `json:"team,omitempty"`
+	Key    string   `json:"key,omitempty"`
+	Action string   `json:"action"`
+	Repos  []string `json:"repos"`
+}
+
+// Keys are the keys of the issues created and updated.
+func (r *RemediationResult) Keys() (created, updated []string) {
+	for _, i := range r.Issues {
+		switch i.Action {
+		case RemediationCreated:
+			created = append(created, i.Key)
+		case RemediationUpdated:
+			updated = append(updated, i.Key)
+		}
+	}
+	return created, updated
+}
+
+// teamFindings is what one team's issue lists: its non-compliant repos and
+// the checks each fails.
+type teamFindings struct {
+	Team     string
+	Failures map[string][]string
+}
+
+// groupFailuresByTeam splits r's non-compliant repos by team_repos, in
+// team order, leaving out teams with nothing to fix. Without team_repos
+// the whole org is one group with no team.
+func groupFailuresByTeam(r Report) []teamFindings {
+	failures := map[string][]string{}
+	for repo, checks := range r.failures(r.RepoFailures == nil) {
+		if len(checks) > 0 {
+			failures[repo] = checks
+		}
+	}
+	if len(r.TeamRepos) == 0 {
+		if len(failures) == 0 {
+			return nil
+		}
+		return []teamFindings{{Failures: failures}}
+	}
+	var groups []teamFindings
+	for _, team := range sortedKeys(r.TeamRepos) {
+		g := teamFindings{Team: team, Failures: map[string][]string{}}
+		for _, repo := range r.TeamRepos[team] {
+			if checks, ok := failures[repo]; ok {
+				g.Failures[repo] = checks
+			}
+		}
+		if len(g.Failures) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// jiraIssueLabel is the label CreateJiraIssues finds a team's issue by.
+func jiraIssueLabel(org, team string) string {
+	label := jiraLabel + ":" + org
+	if team != "" {
+		label += ":" + team
+	}
+	// Jira labels cannot contain spaces.
+	return strings.ReplaceAll(label, " ", "_")
+}
+
+// summary is the issue's title.
+func (g teamFindings) summary(org string) string {
+	owner := org
+	if g.Team != "" {
+		owner = org + " team " + g.Team
+	}
+	n := len(g.Failures)
+	if n == 1 {
+		return fmt.Sprintf("Security scan: 1 non-compliant repo in %s", owner)
+	}
+	return fmt.Sprintf("Security scan: %d non-compliant repos in %s", n, owner)
+}
+
+// description is the issue body in Atlassian Document Format: a bullet per
+// repo naming its failed checks.
+func (g teamFindings) description(r Report) map[string]interface{} {
+	text := func(s string) map[string]interface{} {
+		return map[string]interface{}{"type": "text", "text": s}
+	}
+	paragraph := func(s string) map[string]interface{} {
+		return map[string]interface{}{"type": "paragraph", "content": []interface{}{text(s)}}
+	}
+	var items []interface{}
+	for _, repo := range sortedKeys(g.Failures) {
+		items = append(items, map[string]interface{}{
+			"type":    "listItem",
+			"content": []interface{}{paragraph(repo + ": " + strings.Join(g.Failures[repo], ", "))},
+		})
+	}
+	intro := fmt.Sprintf("These repositories in %s fail required security checks.", r.Org)
+	if r.CompletedAt != "" {
+		intro = fmt.Sprintf("As of the scan completed at %s, these repositories in %s fail required security checks.", r.CompletedAt, r.Org)
+	}
+	content := []interface{}{
+		paragraph(intro),
+		map[string]interface{}{"type": "bulletList", "content": items},
+	}
+	if r.RunID != "" {
+		content = append(content, paragraph("Scan run: "+r.RunID))
+	}
+	return map[string]interface{}{"type": "doc", "version": 1, "content": content}
+}
+
+// jiraProgress is CreateJiraIssues' heartbeat: the issues already written.
+type jiraProgress struct {
+	Result RemediationResult `json:"result"`
+}
+
+// CreateJiraIssues files or updates one issue per team with failures. It
+// returns nil when the worker has no Jira.
+func (a *Activities) CreateJiraIssues(ctx context.Context, in JiraInput) (*RemediationResult, error) {
+	cfg := a.Jira
+	if cfg == nil || cfg.URL == "" {
+		return nil, nil
+	}
+	b, err := json.Marshal(in.Report)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("encoding report: "+err.Error(), ErrTypeInvalidInput, nil)
+	}
+	report, err := ParseReport(b)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("decoding report: "+err.Error(), ErrTypeInvalidInput, nil)
+	}
+
+	progress := jiraProgress{Result: RemediationResult{Tracker: "jira", Project: cfg.Project, DryRun: cfg.DryRun, Issues: []RemediationIssue{}}}
+	if activity.HasHeartbeatDetails(ctx) {
+		var prev jiraProgress
+		if err := activity.GetHeartbeatDetails(ctx, &prev); err == nil {
+			progress = prev
+		}
+	}
+	done := make(map[string]bool, len(progress.Result.Issues))
+	created := 0
+	for _, i := range progress.Result.Issues {
+		done[i.Team] = true
+		if i.Action == RemediationCreated || i.Action == RemediationWouldCreate {
+			created++
+		}
+	}
+	maxCreate := cfg.MaxCreate
+	if maxCreate <= 0 {
+		maxCreate = DefaultJiraMaxCreate
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = a.HTTPClient
+	}
+
+	for _, g := range groupFailuresByTeam(report) {
+		if done[g.Team] {
+			continue
+		}
+		issue := RemediationIssue{Team: g.Team, Repos: sortedKeys(g.Failures)}
+		label := jiraIssueLabel(report.Org, g.Team)
+		op := newRemediationKey(ctx, label, remediationJiraIssue)
+		var rec *idempotencyRecord
+		if !cfg.DryRun {
+			rec = a.loadIdempotency(ctx, op)
+		}
+		if rec != nil && rec.Done && json.Unmarshal(rec.Result, &issue) == nil {
+			// Written by an attempt whose answer was lost.
+			if issue.Action == RemediationCreated {
+				created++
+			}
+			progress.Result.Issues = append(progress.Result.Issues, issue)
+			activity.RecordHeartbeat(ctx, progress)
+			continue
+		}
+		var key string
+		var err error
+		if !cfg.DryRun && mayHaveWritten(ctx, rec) {
+			// The issue this run filed carries op's label.
+			key, err = cfg.findIssue(ctx, client, op.label())
+			if key != "" {
+				issue.Key, issue.Action = key, RemediationCreated
+				created++
+			}
+		}
+		if err == nil && issue.Key == "" {
+			key, err = cfg.findIssue(ctx, client, label)
+		}
+		if err == nil && issue.Key == "" {
+			switch {
+			case key != "" && cfg.DryRun:
+				issue.Key, issue.Action = key, RemediationWouldUpdate
+			case key != "":
+				issue.Key, issue.Action = key, RemediationUpdated
+				err = cfg.updateIssue(ctx, client, key, g, report)
+			case created >= maxCreate:
+				issue.Action = RemediationSkipped
+			case cfg.DryRun:
+				issue.Action = RemediationWouldCreate
+				created++
+			default:
+				issue.Action = RemediationCreated
+				a.saveIdempotency(ctx, op, false, nil)
+				issue.Key, err = cfg.createIssue(ctx, client, []string{label, op.label()}, g, report)
+				created++
+			}
+		}
+		if err != nil {
+			var appErr *temporal.ApplicationError
+			if errors.As(err, &appErr) && appErr.NonRetryable() {
+				// Every other team would fail the same way; stop here and
+				// keep what was written.
+				progress.Result.Error = err.Error()
+				return nil, temporal.NewNonRetryableApplicationError(err.Error(), appErr.Type(), nil, progress.Result)
+			}
+			return nil, err
+		}
+		if !cfg.DryRun && issue.Key != "" {
+			a.saveIdempotency(ctx, op, true, issue)
+		}
+		progress.Result.Issues = append(progress.Result.Issues, issue)
+		activity.RecordHeartbeat(ctx, progress)
+	}
+	return &progress.Result, nil
+}
+
+// findIssue is the key of the oldest open issue in the project with label,
+// or "" if there is none.
+func (cfg *JiraConfig) findIssue(ctx context.Context, client *http.Client, label string) (string, error) {
+	jql := fmt.Sprintf(`project = %q AND labels = %q AND statusCategory != Done ORDER BY created ASC`, cfg.Project, label)
+	q := url.Values{"jql": {jql}, "fields": {"summary"}, "maxResults": {"1"}}
+	var out struct {
+		Issues []struct {
+			Key string `json:"key"`
+		} `json:"issues"`
+	}
+	if err := cfg.do(ctx, client, http.MethodGet, "/rest/api/3/search/jql?"+q.Encode(), nil, &out); err != nil {
+		return "", err
+	}
+	if len(out.Issues) == 0 {
+		return "", nil
+	}
+	return out.Issues[0].Key, nil
+}
+
+// createIssue files g's issue with labels besides jiraLabel.
+func (cfg *JiraConfig) createIssue(ctx context.Context, client *http.Client, labels []string, g teamFindings, r Report) (string, error) {
+	issueType := cfg.IssueType
+	if issueType == "" {
+		issueType = DefaultJiraIssueType
+	}
+	body := map[string]interface{}{"fields": map[string]interface{}{
+		"project":     map[string]string{"key": cfg.Project},
+		"issuetype":   map[string]string{"name": issueType},
+		"summary":     g.summary(r.Org),
+		"description": g.description(r),
+		"labels":      append([]string{jiraLabel}, labels...),
+	}}
+	var out struct {
+		Key string `json:"key"`
+	}
+	if err := cfg.do(ctx, client, http.MethodPost, "/rest/api/3/issue", body, &out); err != nil {
+		return "", err
+	}
+	return out.Key, nil
+}
+
+func (cfg *JiraConfig) updateIssue(ctx context.Context, client *http.Client, key string, g teamFindings, r Report) error {
+	body := map[string]interface{}{"fields": map[string]interface{}{
+		"summary":     g.summary(r.Org),
+		"description": g.description(r),
+	}}
+	return cfg.do(ctx, client, http.MethodPut, "/rest/api/3/issue/"+url.PathEscape(key), body, nil)
+}
+
+// do sends one API request with basic auth. 401 and 403 are non-retryable;
+// anything else that is not 2xx is left to the retry policy.
+func (cfg *JiraConfig) do(ctx context.Context, client *http.Client, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(cfg.URL, "/")+path, reader)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidInput, nil)
+	}
+	req.SetBasicAuth(cfg.Email, cfg.APIToken)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("jira %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("jira %s %s: %w", method, path, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("Jira rejected the API token (%d)", resp.StatusCode), ErrTypeJiraUnauthorized, nil)
+	case resp.StatusCode/100 != 2:
+		return fmt.Errorf("jira %s %s returned %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// createJiraIssues runs CreateJiraIssues. A failure is recorded in the
+// result, with the issues written before it, rather than failing the scan.
+func createJiraIssues(ctx workflow.Context, report map[string]interface{}) *RemediationResult {
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		HeartbeatTimeout:    time.Minute,
+		RetryPolicy:         githubRetryPolicy(),
+	})
+	var result *RemediationResult
+	err := workflow.ExecuteActivity(actCtx, "CreateJiraIssues", JiraInput{Report: report}).Get(ctx, &result)
+	if err == nil {
+		return result
+	}
+	workflow.GetLogger(ctx).Warn("Creating Jira issues failed", "error", err)
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) && appErr.HasDetails() {
+		var partial RemediationResult
+		if appErr.Details(&partial) == nil {
+			return &partial
+		}
+	}
+	return &RemediationResult{Tracker: "jira", Issues: []RemediationIssue{}, Error: err.Error()}
+}
@@ -0,0 +1,294 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+)
+
+// fakeJira is an httptest Jira Cloud project. issues maps label to the key
+// of an open issue with it; created issues are added. status, when set,
+// answers every request.
+type fakeJira struct {
+	t       *testing.T
+	mu      sync.Mutex
+	issues  map[string]string
+	next    int
+	status  int
+	created []map[string]interface{}
+	updated map[string]map[string]interface{}
+	writes  int
+}
+
+func newFakeJira(t *testing.T) (*fakeJira, *JiraConfig) {
+	t.Helper()
+	f := &fakeJira{t: t, issues: map[string]string{}, next: 1, updated: map[string]map[string]interface{}{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, &JiraConfig{URL: srv.URL, Email: "bot@acme.io", APIToken: "jira-token", Project: "SEC", HTTPClient: srv.Client()}
+}
+
+func (f *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if user, pass, ok := r.BasicAuth(); !ok || user != "bot@acme.io" || pass != "jira-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		_, _ = w.Write([]byte(`{"errorMessages":["nope"]}`))
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/rest/api/3/search/jql":
+		jql := r.URL.Query().Get("jql")
+		require.Contains(f.t, jql, `project = "SEC"`)
+		var issues []map[string]string
+		for label, key := range f.issues {
+			if strings.Contains(jql, fmt.Sprintf("labels = %q ", label)) {
+				issues = append(issues, map[string]string{"key": key})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"issues": issues})
+	case r.Method == http.MethodPost && r.URL.Path == "/rest/api/3/issue":
+		var body struct {
+			Fields map[string]interface{} `json:"fields"`
+		}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&body))
+		key := fmt.Sprintf("SEC-%d", f.next)
+		f.next++
+		for _, label := range body.Fields["labels"].([]interface{})[1:] {
+			f.issues[label.(string)] = key
+		}
+		f.created = append(f.created, body.Fields)
+		f.writes++
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{"key": key})
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/rest/api/3/issue/"):
+		var body struct {
+			Fields map[string]interface{} `json:"fields"`
+		}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&body))
+		f.updated[strings.TrimPrefix(r.URL.Path, "/rest/api/3/issue/")] = body.Fields
+		f.writes++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// jiraReport is a team scan of acme: platform has two failing repos,
+// payments one (shared with platform), and docs none.
+func jiraReport(t *testing.T) map[string]interface{} {
+	t.Helper()
+	r := Report{
+		Org: "acme", TotalRepos: 4, FullyCompliant: 1, ComplianceRate: "25.0%",
+		NonCompliantRepos: []string{"api", "billing", "web"},
+		RepoFailures: map[string][]string{
+			"api":     {CheckSecretScanning, ResultCodeowners},
+			"billing": {CheckDependabot},
+			"web":     {CheckDependabot},
+			"docs":    {},
+		},
+		Teams:       []string{"platform", "payments", "docs"},
+		TeamRepos:   map[string][]string{"platform": {"api", "web"}, "payments": {"billing", "api"}, "docs": {"docs"}},
+		RunID:       "run-1",
+		CompletedAt: "2026-03-02T14:03:20Z",
+	}
+	b, err := json.Marshal(r)
+	require.NoError(t, err)
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &m))
+	return m
+}
+
+func runJira(t *testing.T, cfg *JiraConfig, report map[string]interface{}) (*RemediationResult, error) {
+	t.Helper()
+	a := &Activities{Jira: cfg}
+	val, err := newActivityEnv(a).ExecuteActivity(a.CreateJiraIssues, JiraInput{Report: report})
+	if err != nil {
+		return nil, err
+	}
+	var out *RemediationResult
+	if val.HasValue() {
+		require.NoError(t, val.Get(&out))
+	}
+	return out, nil
+}
+
+func TestCreateJiraIssues(t *testing.T) {
+	f, cfg := newFakeJira(t)
+	// payments already has an open issue from last week's scan.
+	f.issues["security-scan:acme:payments"] = "SEC-100"
+
+	out, err := runJira(t, cfg, jiraReport(t))
+	require.NoError(t, err)
+	require.Equal(t, &RemediationResult{Tracker: "jira", Project: "SEC", Issues: []RemediationIssue{
+		{Team: "payments", Key: "SEC-100", Action: RemediationUpdated, Repos: []string{"api", "billing"}},
+		{Team: "platform", Key: "SEC-1", Action: RemediationCreated, Repos: []string{"api", "web"}},
+	}}, out, "docs has nothing to fix")
+	created, updated := out.Keys()
+	require.Equal(t, []string{"SEC-1"}, created)
+	require.Equal(t, []string{"SEC-100"}, updated)
+
+	issue := f.created[0]
+	require.Equal(t, "Security scan: 2 non-compliant repos in acme team platform", issue["summary"])
+	op := remediationKey{RunID: "default-test-run-id", Subject: "security-scan:acme:platform", Action: remediationJiraIssue}
+	require.Equal(t, []interface{}{"security-scan", "security-scan:acme:platform", op.label()}, issue["labels"])
+	require.Equal(t, map[string]interface{}{"name": DefaultJiraIssueType}, issue["issuetype"])
+	desc, err := json.Marshal(issue["description"])
+	require.NoError(t, err)
+	require.Contains(t, string(desc), `"text":"api: secret_scanning, codeowners"`)
+	require.Contains(t, string(desc), `"text":"Scan run: run-1"`)
+	require.Equal(t, "Security scan: 2 non-compliant repos in acme team payments", f.updated["SEC-100"]["summary"])
+
+	// The next scan updates both instead of filing new ones.
+	out, err = runJira(t, cfg, jiraReport(t))
+	require.NoError(t, err)
+	created, updated = out.Keys()
+	require.Empty(t, created)
+	require.Equal(t, []string{"SEC-100", "SEC-1"}, updated)
+	require.Len(t, f.created, 1)
+}
+
+func TestCreateJiraIssuesWholeOrg(t *testing.T) {
+	f, cfg := newFakeJira(t)
+	report := jiraReport(t)
+	delete(report, "team_repos")
+	out, err := runJira(t, cfg, report)
+	require.NoError(t, err)
+	require.Equal(t, []RemediationIssue{
+		{Key: "SEC-1", Action: RemediationCreated, Repos: []string{"api", "billing", "web"}},
+	}, out.Issues)
+	require.Equal(t, "Security scan: 3 non-compliant repos in acme", f.created[0]["summary"])
+}
+
+func TestCreateJiraIssuesCapAndDryRun(t *testing.T) {
+	f, cfg := newFakeJira(t)
+	cfg.MaxCreate = 1
+	out, err := runJira(t, cfg, jiraReport(t))
+	require.NoError(t, err)
+	require.Equal(t, []string{RemediationCreated, RemediationSkipped}, []string{out.Issues[0].Action, out.Issues[1].Action})
+	require.Empty(t, out.Issues[1].Key)
+	require.Len(t, f.created, 1)
+
+	f, cfg = newFakeJira(t)
+	f.issues["security-scan:acme:platform"] = "SEC-5"
+	cfg.DryRun = true
+	out, err = runJira(t, cfg, jiraReport(t))
+	require.NoError(t, err)
+	require.True(t, out.DryRun)
+	require.Equal(t, []RemediationIssue{
+		{Team: "payments", Action: RemediationWouldCreate, Repos: []string{"api", "billing"}},
+		{Team: "platform", Key: "SEC-5", Action: RemediationWouldUpdate, Repos: []string{"api", "web"}},
+	}, out.Issues)
+	require.Zero(t, f.writes, "a dry run writes nothing")
+}
+
+func TestCreateJiraIssuesResumesFromHeartbeat(t *testing.T) {
+	// The first attempt filed payments' issue and then failed; the search
+	// index may not show it yet, but the heartbeat does.
+	f, cfg := newFakeJira(t)
+	a := &Activities{Jira: cfg}
+	env := newActivityEnv(a)
+	env.SetHeartbeatDetails(jiraProgress{Result: RemediationResult{Tracker: "jira", Project: "SEC", Issues: []RemediationIssue{
+		{Team: "payments", Key: "SEC-40", Action: RemediationCreated, Repos: []string{"api", "billing"}},
+	}}})
+	var heartbeats int
+	env.SetOnActivityHeartbeatListener(func(*activity.Info, converter.EncodedValues) { heartbeats++ })
+	val, err := env.ExecuteActivity(a.CreateJiraIssues, JiraInput{Report: jiraReport(t)})
+	require.NoError(t, err)
+	var out RemediationResult
+	require.NoError(t, val.Get(&out))
+	require.Equal(t, []string{"SEC-40", "SEC-1"}, []string{out.Issues[0].Key, out.Issues[1].Key})
+	require.Len(t, f.created, 1, "only platform's issue is created")
+	require.Equal(t, 1, heartbeats)
+}
+
+func TestCreateJiraIssuesFailures(t *testing.T) {
+	// A rejected token stops without retries and keeps what was written.
+	_, cfg := newFakeJira(t)
+	cfg.APIToken = "revoked"
+	_, err := runJira(t, cfg, jiraReport(t))
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, ErrTypeJiraUnauthorized, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+	var partial RemediationResult
+	require.NoError(t, appErr.Details(&partial))
+	require.Contains(t, partial.Error, "Jira rejected the API token (401)")
+	require.Empty(t, partial.Issues)
+
+	// Jira being down is left to the retry policy.
+	f, cfg := newFakeJira(t)
+	f.status = http.StatusServiceUnavailable
+	_, err = runJira(t, cfg, jiraReport(t))
+	require.ErrorContains(t, err, "returned 503")
+	require.False(t, isNonRetryable(err))
+
+	out, err := runJira(t, nil, jiraReport(t))
+	require.NoError(t, err)
+	require.Nil(t, out, "not configured")
+}
+
+func TestWorkflowRecordsRemediation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		result *RemediationResult
+		err    error
+		want   interface{}
+	}{
+		"created": {
+			result: &RemediationResult{Tracker: "jira", Project: "SEC", Issues: []RemediationIssue{
+				{Key: "SEC-1", Action: RemediationCreated, Repos: []string{"repo-001"}},
+			}},
+			want: map[string]interface{}{"tracker": "jira", "project": "SEC", "issues": []interface{}{
+				map[string]interface{}{"key": "SEC-1", "action": "created", "repos": []interface{}{"repo-001"}},
+			}},
+		},
+		"token rejected": {
+			err: temporal.NewNonRetryableApplicationError("Jira rejected the API token (401)", ErrTypeJiraUnauthorized, nil,
+				RemediationResult{Tracker: "jira", Project: "SEC", Issues: []RemediationIssue{}, Error: "Jira rejected the API token (401)"}),
+			want: map[string]interface{}{"tracker": "jira", "project": "SEC", "issues": []interface{}{},
+				"error": "Jira rejected the API token (401)"},
+		},
+		"not configured": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnv(t)
+			onListOrgRepos(env, fakeRepos(3))
+			env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(compliantUnless("repo-001"))
+			env.OnActivity("CreateJiraIssues", mock.Anything, mock.Anything).Return(tc.result, tc.err)
+
+			env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+			require.NoError(t, env.GetWorkflowError(), "Jira never fails the scan")
+			var report map[string]interface{}
+			require.NoError(t, env.GetWorkflowResult(&report))
+			if tc.want == nil {
+				require.NotContains(t, report, "remediation")
+				return
+			}
+			require.Equal(t, tc.want, report["remediation"])
+		})
+	}
+}
+
+func TestJiraIssueLabel(t *testing.T) {
+	require.Equal(t, "security-scan:acme", jiraIssueLabel("acme", ""))
+	require.Equal(t, "security-scan:acme:platform", jiraIssueLabel("acme", "platform"))
+	require.Equal(t, "security-scan:acme/infra:sre_team", jiraIssueLabel("acme/infra", "sre team"))
+}
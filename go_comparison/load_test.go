@@ -0,0 +1,241 @@
+package scanner
+
+// =============================================================================
+// Load test and benchmark — large-organization scans
+// =============================================================================
+//
+// Temporal enforces hard limits on a single workflow run: 51,200 history
+// events and 2 MiB per payload (plus a 50 MiB total history). A 6,000-repo
+// org is comfortably "large" against those numbers, so this file drives
+// SecurityScanWorkflow over 1k/5k/10k synthetic repos with instant mocked
+// activities and checks two things against configurable budgets:
+//
+//   - estimated history event count for the run
+//   - the encoded size of the results still held inline, which is the
+//     largest payload (it is both the BuildReport input and the
+//     results_so_far reply); past ResultsOffloadBytes the rest are offloaded
+//     to StoreResults, so this stays bounded
+//
+// A budget failure is the signal to land continue-as-new (history) or
+// results offloading (payload size); it is not something to tune away.
+//
+// The 1k case runs with every go test; SCAN_LOAD_TEST=1 adds 5k and 10k.
+// The 10k case is over the history budget until the scan continues as new
+// (tracked in Salkimmich/temporal-security-scanner#synth-826): it is
+// reported as a known failure, and fails once it fits so the entry is
+// removed.
+//
+//	go test ./go_comparison -run TestLoad -v
+//	SCAN_LOAD_TEST=1 go test ./go_comparison -run TestLoad -v
+//	SCAN_LOAD_MAX_EVENTS=20000 SCAN_LOAD_TEST=1 go test ./go_comparison -run TestLoad
+//	go test ./go_comparison -run '^$' -bench BenchmarkSecurityScanWorkflow
+// =============================================================================
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// Defaults mirror the Temporal server's hard limits.
+const (
+	defaultMaxHistoryEvents = 51200
+	defaultMaxPayloadBytes  = 2 * 1024 * 1024
+)
+
+// loadCase is one synthetic org size.
+type loadCase struct {
+	repos int
+	// large cases run only with SCAN_LOAD_TEST=1.
+	large bool
+	// overHistoryBudget names the issue tracking a size known to exceed
+	// the history budget.
+	overHistoryBudget string
+}
+
+var loadCases = []loadCase{
+	{repos: 1000},
+	{repos: 5000, large: true},
+	{repos: 10000, large: true, overHistoryBudget: "Salkimmich/temporal-security-scanner#synth-826"},
+}
+
+// loadBudget holds the limits a synthetic scan must stay under.
+type loadBudget struct {
+	MaxHistoryEvents int
+	MaxPayloadBytes  int
+}
+
+func loadBudgetFromEnv(tb testing.TB) loadBudget {
+	return loadBudget{
+		MaxHistoryEvents: envInt(tb, "SCAN_LOAD_MAX_EVENTS", defaultMaxHistoryEvents),
+		MaxPayloadBytes:  envInt(tb, "SCAN_LOAD_MAX_PAYLOAD_BYTES", defaultMaxPayloadBytes),
+	}
+}
+
+func envInt(tb testing.TB, key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		tb.Fatalf("%s=%q: %v", key, v, err)
+	}
+	return n
+}
+
+// nopLogger silences the per-activity log lines of a 10k-repo run.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+// loadStats describes one synthetic scan.
+type loadStats struct {
+	Repos      int
+	Activities int
+	// HistoryEvents is an upper-bound estimate: the testsuite does not
+	// record history, so it is derived from the activity count assuming
+	// every activity completion triggers its own workflow task.
+	HistoryEvents int
+	PayloadBytes  int
+	Elapsed       time.Duration
+}
+
+// estimateHistoryEvents returns the worst-case event count for a run with
+// the given number of activities and signals:
+//
+//	2 (started + completed) + 3 per activity (scheduled/started/completed)
+//	+ 3 per workflow task (scheduled/started/completed) + 1 per signal
+//
+// with one workflow task for the first run plus one per activity completion
+// and per signal.
+func estimateHistoryEvents(activities, signals int) int {
+	workflowTasks := 1 + activities + signals
+	return 2 + 3*activities + 3*workflowTasks + signals
+}
+
+// runSyntheticScan executes the workflow over n fake repos with activities
+// that return immediately, and measures the resulting run.
+func runSyntheticScan(tb testing.TB, n int) loadStats {
+	tb.Helper()
+	var s testsuite.WorkflowTestSuite
+	s.SetLogger(nopLogger{})
+	env := s.NewTestWorkflowEnvironment()
+	// BuildReport runs as a local activity and reads offloaded chunks back,
+	// so StoreResults must really store them.
+	a := &Activities{BlobStore: &memBlobStore{blobs: map[string][]byte{}}}
+	env.RegisterActivity(a)
+
+	var activities int64
+	count := func(mock.Arguments) { atomic.AddInt64(&activities, 1) }
+
+	onListOrgRepos(env, fakeRepos(n)).Run(count)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(count).
+		Return(func(_ context.Context, _, repoName string, _ *Credential, _ []string) (*RepoSecurityResult, error) {
+			return &RepoSecurityResult{
+				Repository:       repoName,
+				SecretScanning:   StatusEnabled,
+				DependabotAlerts: StatusEnabled,
+				CodeScanning:     StatusNotConfigured,
+				ScannedAt:        "2026-03-02T14:00:00Z",
+			}, nil
+		})
+	env.OnActivity("CheckActionsSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(count).
+		Return(&ActionsSecurity{ActionsEnabled: true, AllowedActions: AllowedActionsSelected, DefaultWorkflowPermissions: WorkflowPermissionsRead}, nil)
+	env.OnActivity("StoreResults", mock.Anything, mock.Anything, mock.Anything).Run(count).
+		Return(a.StoreResults)
+
+	start := time.Now()
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+	elapsed := time.Since(start)
+
+	if !env.IsWorkflowCompleted() {
+		tb.Fatal("workflow did not complete")
+	}
+	if err := env.GetWorkflowError(); err != nil {
+		tb.Fatalf("workflow failed: %v", err)
+	}
+
+	val, err := env.QueryWorkflow("results_so_far")
+	if err != nil {
+		tb.Fatalf("results_so_far: %v", err)
+	}
+	var results []RepoSecurityResult
+	if err := val.Get(&results); err != nil {
+		tb.Fatal(err)
+	}
+	payload, err := converter.GetDefaultDataConverter().ToPayload(results)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	acts := int(atomic.LoadInt64(&activities))
+	return loadStats{
+		Repos:         n,
+		Activities:    acts,
+		HistoryEvents: estimateHistoryEvents(acts, 0),
+		PayloadBytes:  len(payload.GetData()),
+		Elapsed:       elapsed,
+	}
+}
+
+func TestLoadLargeOrgScan(t *testing.T) {
+	budget := loadBudgetFromEnv(t)
+	large := os.Getenv("SCAN_LOAD_TEST") != ""
+
+	for _, c := range loadCases {
+		c := c
+		t.Run(fmt.Sprintf("%d_repos", c.repos), func(t *testing.T) {
+			if c.large && !large {
+				t.Skip("set SCAN_LOAD_TEST=1 to run the large cases")
+			}
+			stats := runSyntheticScan(t, c.repos)
+			t.Logf("repos=%d activities=%d est_history_events=%d results_payload=%dB elapsed=%s",
+				stats.Repos, stats.Activities, stats.HistoryEvents, stats.PayloadBytes, stats.Elapsed)
+
+			if stats.PayloadBytes > budget.MaxPayloadBytes {
+				t.Errorf("results payload %dB exceeds budget %dB: lower ResultsOffloadBytes",
+					stats.PayloadBytes, budget.MaxPayloadBytes)
+			}
+			switch over := stats.HistoryEvents > budget.MaxHistoryEvents; {
+			case over && c.overHistoryBudget != "":
+				t.Skipf("known failure, %s: estimated history events %d exceed budget %d",
+					c.overHistoryBudget, stats.HistoryEvents, budget.MaxHistoryEvents)
+			case over:
+				t.Errorf("estimated history events %d exceed budget %d: the scan needs continue-as-new",
+					stats.HistoryEvents, budget.MaxHistoryEvents)
+			case c.overHistoryBudget != "":
+				t.Errorf("estimated history events %d are within budget %d: close %s and drop the known failure",
+					stats.HistoryEvents, budget.MaxHistoryEvents, c.overHistoryBudget)
+			}
+		})
+	}
+}
+
+// BenchmarkSecurityScanWorkflow reports wall-clock time for executing the
+// whole workflow in-process. It is a lower bound on replay time for a real
+// history of the same size, since replay runs the same workflow code.
+func BenchmarkSecurityScanWorkflow(b *testing.B) {
+	for _, c := range loadCases {
+		n := c.repos
+		b.Run(fmt.Sprintf("%d_repos", n), func(b *testing.B) {
+			var stats loadStats
+			for i := 0; i < b.N; i++ {
+				stats = runSyntheticScan(b, n)
+			}
+			b.ReportMetric(float64(stats.HistoryEvents), "events")
+			b.ReportMetric(float64(stats.PayloadBytes), "payload-bytes")
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package scanner
+
+// =============================================================================
+// Scan memo — the scan's parameters, readable in the Temporal UI
+// =============================================================================
+//
+// Search attributes are typed and limited in number; the memo is neither.
+// Every scan carries ScanMemo(input) in its memo: what it scans, with which
+// checks and filters, under which policy, who started it and why, and the
+// scanner version. The values are display strings, not settings: nothing
+// reads them back to run a scan. The token is never included.
+//
+// The starter sets the memo when it starts a scan (StartScan) or an
+// enterprise scan, and the enterprise workflow when it starts an org's
+// child (EnterpriseScanMemo). The workflow upserts it again once its input
+// is resolved (org config, the Schedule that started it), so a run started
+// by any path carries it.
+// =============================================================================
+
+import (
+	"fmt"
+	"strings"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// Memo keys. initiated_by and reason predate the others.
+const (
+	MemoOrg            = "org"
+	MemoProvider       = "provider"
+	MemoChecks         = "checks"
+	MemoFilters        = "filters"
+	MemoPolicy         = "policy"
+	MemoInitiatedBy    = "initiated_by"
+	MemoReason         = "reason"
+	MemoScannerVersion = "scanner_version"
+	MemoEnterprise     = "enterprise"
+)
+
+// ScanMemo is the memo of a scan of in.
+func ScanMemo(in ScanInput) map[string]interface{} {
+	provider := in.Provider
+	if provider == "" {
+		provider = ProviderGitHub
+	}
+	checks := "default (" + strings.Join(DefaultChecks(), ", ") + ")"
+	if len(in.Checks) > 0 {
+		checks = strings.Join(in.Checks, ", ")
+	}
+	if in.IncludeAccessAudit {
+		checks += ", plus access audit"
+	}
+	memo := map[string]interface{}{
+		MemoOrg:            in.Org,
+		MemoProvider:       provider,
+		MemoChecks:         checks,
+		MemoFilters:        scanFilters(in),
+		MemoPolicy:         in.CompliancePolicy.memoName(),
+		MemoScannerVersion: Version,
+	}
+	if in.InitiatedBy != "" {
+		memo[MemoInitiatedBy] = in.InitiatedBy
+	}
+	if in.Reason != "" {
+		memo[MemoReason] = in.Reason
+	}
+	return memo
+}
+
+// EnterpriseScanMemo is the memo of in's scan of org, or of the enterprise
+// scan itself when org is empty.
+func EnterpriseScanMemo(in EnterpriseScanInput, org string) map[string]interface{} {
+	scan := in.Scan
+	scan.Org = org
+	memo := ScanMemo(scan)
+	if org == "" {
+		delete(memo, MemoOrg)
+	}
+	memo[MemoEnterprise] = in.Enterprise
+	return memo
+}
+
+// scanFilters describes which of the org's repos in scans, e.g.
+// "topics tier-1, pci; active within 180 days".
+func scanFilters(in ScanInput) string {
+	var parts []string
+	if n := len(in.Repos); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d listed repos", n))
+	}
+	if len(in.Teams) > 0 {
+		parts = append(parts, "teams "+strings.Join(in.Teams, ", "))
+	}
+	if len(in.IncludeTopics) > 0 {
+		parts = append(parts, "topics "+strings.Join(in.IncludeTopics, ", "))
+	}
+	if len(in.ExcludeTopics) > 0 {
+		parts = append(parts, "excluding topics "+strings.Join(in.ExcludeTopics, ", "))
+	}
+	if in.ActiveWithinDays > 0 {
+		parts = append(parts, fmt.Sprintf("active within %d days", in.ActiveWithinDays))
+	}
+	if in.IncludeArchived {
+		parts = append(parts, "archived included")
+	}
+	if in.ResumeFrom != nil {
+		parts = append(parts, "resuming an earlier scan")
+	}
+	if len(parts) == 0 {
+		return "all repos"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// memoName is the policy's Name, "custom" for an unnamed one, or
+// "default" without one.
+func (p *CompliancePolicy) memoName() string {
+	switch {
+	case p == nil:
+		return "default"
+	case p.Name != "":
+		return p.Name
+	}
+	return "custom"
+}
+
+// tagScanMemo upserts ScanMemo(in) into the run's memo.
+func tagScanMemo(ctx workflow.Context, in ScanInput) {
+	if err := workflow.UpsertMemo(ctx, ScanMemo(in)); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to record the scan's parameters in its memo", "error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanMemo(t *testing.T) {
+	token := "ghp_secret"
+	require.Equal(t, map[string]interface{}{
+		MemoOrg:            "acme",
+		MemoProvider:       ProviderGitHub,
+		MemoChecks:         "default (secret_scanning, dependabot, code_scanning)",
+		MemoFilters:        "all repos",
+		MemoPolicy:         "default",
+		MemoScannerVersion: Version,
+	}, ScanMemo(ScanInput{Org: "acme", Token: &token}))
+
+	memo := ScanMemo(ScanInput{
+		Org:                "acme",
+		Token:              &token,
+		Checks:             []string{CheckSecretScanning, CheckWebhooks},
+		IncludeAccessAudit: true,
+		IncludeTopics:      []string{"tier-1", "pci"},
+		ExcludeTopics:      []string{"deprecated"},
+		ActiveWithinDays:   180,
+		IncludeArchived:    true,
+		CompliancePolicy:   &CompliancePolicy{Name: "pci-tier-1", RequireSecretScanning: true},
+		InitiatedBy:        "alice",
+		Reason:             "Q3 sweep",
+	})
+	require.Equal(t, "secret_scanning, webhooks, plus access audit", memo[MemoChecks])
+	require.Equal(t, "topics tier-1, pci; excluding topics deprecated; active within 180 days; archived included", memo[MemoFilters])
+	require.Equal(t, "pci-tier-1", memo[MemoPolicy])
+	require.Equal(t, "alice", memo[MemoInitiatedBy])
+	require.Equal(t, "Q3 sweep", memo[MemoReason])
+	for _, v := range memo {
+		require.NotContains(t, v, token)
+	}
+
+	require.Equal(t, "custom", ScanMemo(ScanInput{Org: "acme", CompliancePolicy: &CompliancePolicy{}})[MemoPolicy])
+	require.Equal(t, "2 listed repos; teams platform", ScanMemo(ScanInput{Org: "acme", Repos: []string{"acme/a", "acme/b"}, Teams: []string{"platform"}})[MemoFilters])
+}
+
+func TestEnterpriseScanMemo(t *testing.T) {
+	in := EnterpriseScanInput{Enterprise: "acme-ent", Scan: ScanInput{Reason: "quarterly"}}
+	parent := EnterpriseScanMemo(in, "")
+	require.NotContains(t, parent, MemoOrg)
+	require.Equal(t, "acme-ent", parent[MemoEnterprise])
+	require.Equal(t, "quarterly", parent[MemoReason])
+
+	child := EnterpriseScanMemo(in, "acme-payments")
+	require.Equal(t, "acme-payments", child[MemoOrg])
+	require.Equal(t, "acme-ent", child[MemoEnterprise])
+}
+
+func TestWorkflowRecordsScanMemo(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	var memo map[string]interface{}
+	env.OnUpsertMemo(mock.Anything).Run(func(args mock.Arguments) {
+		memo = args.Get(0).(map[string]interface{})
+	}).Return(nil)
+
+	// A run with no initiator, e.g. started by hand in the UI, still gets
+	// its parameters.
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", IncludeTopics: []string{"tier-1"}})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, ScanMemo(ScanInput{Org: "acme", IncludeTopics: []string{"tier-1"}}), memo)
+}
@@ -0,0 +1,291 @@
+package scanner
+
+// =============================================================================
+// Compliance metrics — trends in Datadog
+// =============================================================================
+//
+// When the worker has Datadog configured (Activities.Datadog), the
+// workflow's last step is EmitComplianceMetrics, which submits the report's
+// headline numbers as gauges, tagged with the org and the scan's run ID:
+//
+//	security_scan.compliance_rate     percent of repos fully compliant
+//	security_scan.check.adoption      repos passing a check, tagged check:<name>
+//	security_scan.errors              repos that could not be scanned
+//	security_scan.duration_seconds    started_at to completed_at
+//	security_scan.repos               repos scanned
+//
+// They go either to the metrics API (DatadogAPI, needs an API key) or to a
+// local DogStatsD agent over UDP (DatadogStatsd). API failures are retried
+// by the activity's retry policy; a scan never fails because its metrics
+// could not be sent.
+// =============================================================================
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Metric names.
+const (
+	MetricComplianceRate = "security_scan.compliance_rate"
+	MetricCheckAdoption  = "security_scan.check.adoption"
+	MetricErrors         = "security_scan.errors"
+	MetricDuration       = "security_scan.duration_seconds"
+	MetricRepos          = "security_scan.repos"
+)
+
+// Metric tag keys.
+const (
+	MetricTagOrg   = "org"
+	MetricTagRunID = "run_id"
+	MetricTagCheck = "check"
+)
+
+// DatadogTransport is how EmitComplianceMetrics reaches Datadog.
+type DatadogTransport string
+
+// Datadog transports.
+const (
+	DatadogAPI    DatadogTransport = "api"
+	DatadogStatsd DatadogTransport = "statsd"
+)
+
+// Defaults for DatadogConfig's zero values.
+const (
+	DefaultDatadogSite       = "datadoghq.com"
+	DefaultDogStatsdAddr     = "127.0.0.1:8125"
+	datadogSeriesPath        = "/api/v2/series"
+	datadogMetricTypeGauge   = 3
+	dogStatsdMaxPacketLength = 1432
+)
+
+// ErrTypeMetricsRejected is the ApplicationError type for metrics Datadog
+// refuses, e.g. for a bad API key.
+const ErrTypeMetricsRejected = "METRICS_REJECTED"
+
+// DatadogConfig is where to send compliance metrics.
+type DatadogConfig struct {
+	Transport DatadogTransport
+	// APIKey and Site are for DatadogAPI. APIURL overrides the URL built
+	// from Site, e.g. for a proxy.
+	APIKey string
+	Site   string
+	APIURL string
+	// StatsdAddr is the DogStatsD agent's host:port for DatadogStatsd.
+	StatsdAddr string
+	// Tags are added to every metric, e.g. env:prod.
+	Tags []string
+	// HTTPClient defaults to the Activities' client.
+	HTTPClient *http.Client
+}
+
+// MetricsInput is the input to EmitComplianceMetrics.
+type MetricsInput struct {
+	Report map[string]interface{} `json:"report"`
+}
+
+// Metric is one gauge sample.
+type Metric struct {
+	Name  string
+	Value float64
+	Tags  []string
+}
+
+// ComplianceMetrics are the gauges for r, each tagged with r's org and run
+// ID plus extra. Checks the scan did not run and a duration that is not
+// known are left out.
+func ComplianceMetrics(r Report, extra []string) []Metric {
+	tags := append([]string{MetricTagOrg + ":" + r.Org}, extra...)
+	if r.RunID != "" {
+		tags = append(tags, MetricTagRunID+":"+r.RunID)
+	}
+	gauge := func(name string, value float64, more ...string) Metric {
+		return Metric{Name: name, Value: value, Tags: append(append([]string{}, tags...), more...)}
+	}
+
+	metrics := []Metric{
+		gauge(MetricComplianceRate, r.Rate()),
+		gauge(MetricRepos, float64(r.TotalRepos)),
+		gauge(MetricErrors, float64(r.Errors)),
+	}
+	for _, c := range r.checkCounts() {
+		metrics = append(metrics, gauge(MetricCheckAdoption, float64(c.count), MetricTagCheck+":"+c.check))
+	}
+	if d, ok := r.Duration(); ok {
+		metrics = append(metrics, gauge(MetricDuration, d.Seconds()))
+	}
+	return metrics
+}
+
+// EmitComplianceMetrics sends the report's ComplianceMetrics to the
+// worker's Datadog. It does nothing when the worker has none.
+func (a *Activities) EmitComplianceMetrics(ctx context.Context, in MetricsInput) error {
+	cfg := a.Datadog
+	if cfg == nil {
+		return nil
+	}
+	b, err := json.Marshal(in.Report)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("encoding report: "+err.Error(), ErrTypeInvalidInput, nil)
+	}
+	report, err := ParseReport(b)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("decoding report: "+err.Error(), ErrTypeInvalidInput, nil)
+	}
+	metrics := ComplianceMetrics(report, cfg.Tags)
+	switch cfg.Transport {
+	case DatadogAPI, "":
+		client := cfg.HTTPClient
+		if client == nil {
+			client = a.HTTPClient
+		}
+		ts := time.Now()
+		if t, err := time.Parse(time.RFC3339, report.CompletedAt); err == nil {
+			ts = t
+		}
+		return cfg.submitSeries(ctx, client, metrics, ts)
+	case DatadogStatsd:
+		return cfg.sendStatsd(ctx, metrics)
+	}
+	return temporal.NewNonRetryableApplicationError(
+		fmt.Sprintf("unknown Datadog transport %q: want api or statsd", cfg.Transport), ErrTypeInvalidInput, nil)
+}
+
+// seriesURL is the metrics API endpoint.
+func (cfg *DatadogConfig) seriesURL() string {
+	if cfg.APIURL != "" {
+		return strings.TrimRight(cfg.APIURL, "/") + datadogSeriesPath
+	}
+	site := cfg.Site
+	if site == "" {
+		site = DefaultDatadogSite
+	}
+	return "https://api." + site + datadogSeriesPath
+}
+
+// datadogSeries is one series of the metrics API's v2 payload.
+type datadogSeries struct {
+	Metric string         `json:"metric"`
+	Type   int            `json:"type"`
+	Points []datadogPoint `json:"points"`
+	Tags   []string       `json:"tags"`
+}
+
+type datadogPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// submitSeries POSTs metrics as gauges at ts. 429 and 5xx are left to the
+// retry policy; any other 4xx means the key or payload is wrong, which a
+// retry will not fix.
+func (cfg *DatadogConfig) submitSeries(ctx context.Context, client *http.Client, metrics []Metric, ts time.Time) error {
+	payload := struct {
+		Series []datadogSeries `json:"series"`
+	}{}
+	for _, m := range metrics {
+		payload.Series = append(payload.Series, datadogSeries{
+			Metric: m.Name,
+			Type:   datadogMetricTypeGauge,
+			Points: []datadogPoint{{Timestamp: ts.Unix(), Value: m.Value}},
+			Tags:   m.Tags,
+		})
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.seriesURL(), bytes.NewReader(body))
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidInput, nil)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", cfg.APIKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("Datadog returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		return temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeMetricsRejected, nil)
+	}
+	return err
+}
+
+// statsdLine is m in the DogStatsD datagram format.
+func statsdLine(m Metric) string {
+	line := m.Name + ":" + strconv.FormatFloat(m.Value, 'f', -1, 64) + "|g"
+	if len(m.Tags) > 0 {
+		line += "|#" + strings.Join(m.Tags, ",")
+	}
+	return line
+}
+
+// sendStatsd writes metrics to the agent, as many lines per datagram as
+// fit. UDP gives no delivery report, so only local errors surface.
+func (cfg *DatadogConfig) sendStatsd(ctx context.Context, metrics []Metric) error {
+	addr := cfg.StatsdAddr
+	if addr == "" {
+		addr = DefaultDogStatsdAddr
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return fmt.Errorf("dialing DogStatsD: %w", err)
+	}
+	defer conn.Close()
+
+	var packet []byte
+	flush := func() error {
+		if len(packet) == 0 {
+			return nil
+		}
+		_, err := conn.Write(packet)
+		packet = packet[:0]
+		return err
+	}
+	for _, m := range metrics {
+		line := statsdLine(m)
+		if len(packet) > 0 && len(packet)+1+len(line) > dogStatsdMaxPacketLength {
+			if err := flush(); err != nil {
+				return fmt.Errorf("writing to DogStatsD: %w", err)
+			}
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if err := flush(); err != nil {
+		return fmt.Errorf("writing to DogStatsD: %w", err)
+	}
+	return nil
+}
+
+// emitComplianceMetrics runs EmitComplianceMetrics. A failure is only
+// logged: the scan's result does not depend on its metrics.
+func emitComplianceMetrics(ctx workflow.Context, report map[string]interface{}) {
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy:         githubRetryPolicy(),
+	})
+	if err := workflow.ExecuteActivity(actCtx, "EmitComplianceMetrics", MetricsInput{Report: report}).Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Warn("Emitting compliance metrics failed", "error", err)
+	}
+}
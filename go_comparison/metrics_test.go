@@ -0,0 +1,195 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+// metricsReport is a finished two-check scan of acme.
+func metricsReport() Report {
+	return Report{
+		Org: "acme", TotalRepos: 4, FullyCompliant: 3, ComplianceRate: "75.0%", Errors: 1,
+		NonCompliantRepos:     []string{"api"},
+		SecretScanningEnabled: count(4),
+		DependabotEnabled:     count(3),
+		RunID:                 "run-1",
+		StartedAt:             "2026-03-02T14:00:00Z",
+		CompletedAt:           "2026-03-02T14:03:20Z",
+	}
+}
+
+func metricsInput(t *testing.T, r Report) MetricsInput {
+	t.Helper()
+	b, err := json.Marshal(r)
+	require.NoError(t, err)
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &m))
+	return MetricsInput{Report: m}
+}
+
+func TestMetricNames(t *testing.T) {
+	// Dashboards and monitors query these; renaming one breaks them.
+	require.Equal(t, "security_scan.compliance_rate", MetricComplianceRate)
+	require.Equal(t, "security_scan.check.adoption", MetricCheckAdoption)
+	require.Equal(t, "security_scan.errors", MetricErrors)
+	require.Equal(t, "security_scan.duration_seconds", MetricDuration)
+	require.Equal(t, "security_scan.repos", MetricRepos)
+	require.Equal(t, []string{"org", "run_id", "check"}, []string{MetricTagOrg, MetricTagRunID, MetricTagCheck})
+}
+
+func TestComplianceMetrics(t *testing.T) {
+	metrics := ComplianceMetrics(metricsReport(), []string{"env:prod"})
+	base := []string{"org:acme", "env:prod", "run_id:run-1"}
+	require.Equal(t, []Metric{
+		{MetricComplianceRate, 75, base},
+		{MetricRepos, 4, base},
+		{MetricErrors, 1, base},
+		{MetricCheckAdoption, 4, append(append([]string{}, base...), "check:secret_scanning")},
+		{MetricCheckAdoption, 3, append(append([]string{}, base...), "check:dependabot")},
+		{MetricDuration, 200, base},
+	}, metrics)
+
+	// Unknown duration and run ID are left out.
+	r := metricsReport()
+	r.StartedAt, r.RunID = "", ""
+	for _, m := range ComplianceMetrics(r, nil) {
+		require.NotEqual(t, MetricDuration, m.Name)
+		require.Equal(t, "org:acme", m.Tags[0])
+		require.NotContains(t, strings.Join(m.Tags, ","), "run_id")
+	}
+}
+
+func TestEmitComplianceMetricsAPI(t *testing.T) {
+	var got struct {
+		Series []datadogSeries `json:"series"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, datadogSeriesPath, r.URL.Path)
+		require.Equal(t, "dd-key", r.Header.Get("DD-API-KEY"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	a := &Activities{Datadog: &DatadogConfig{Transport: DatadogAPI, APIKey: "dd-key", APIURL: srv.URL, HTTPClient: srv.Client()}}
+	_, err := newActivityEnv(a).ExecuteActivity(a.EmitComplianceMetrics, metricsInput(t, metricsReport()))
+	require.NoError(t, err)
+	require.Len(t, got.Series, 6)
+	rate := got.Series[0]
+	require.Equal(t, MetricComplianceRate, rate.Metric)
+	require.Equal(t, datadogMetricTypeGauge, rate.Type)
+	require.Equal(t, []datadogPoint{{Timestamp: 1772460200, Value: 75}}, rate.Points, "stamped with completed_at")
+}
+
+func TestEmitComplianceMetricsAPIErrors(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"errors":["nope"]}`))
+	}))
+	defer srv.Close()
+	a := &Activities{Datadog: &DatadogConfig{APIKey: "dd-key", APIURL: srv.URL, HTTPClient: srv.Client()}}
+
+	// 5xx is left to the retry policy.
+	_, err := newActivityEnv(a).ExecuteActivity(a.EmitComplianceMetrics, metricsInput(t, metricsReport()))
+	require.ErrorContains(t, err, "Datadog returned 503")
+	require.False(t, isNonRetryable(err))
+
+	// A rejected key is not retried.
+	status = http.StatusForbidden
+	_, err = newActivityEnv(a).ExecuteActivity(a.EmitComplianceMetrics, metricsInput(t, metricsReport()))
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, ErrTypeMetricsRejected, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+}
+
+func TestEmitComplianceMetricsStatsd(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	a := &Activities{Datadog: &DatadogConfig{Transport: DatadogStatsd, StatsdAddr: conn.LocalAddr().String()}}
+	_, err = newActivityEnv(a).ExecuteActivity(a.EmitComplianceMetrics, metricsInput(t, metricsReport()))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 65536)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	lines := strings.Split(string(buf[:n]), "\n")
+	require.Equal(t, []string{
+		"security_scan.compliance_rate:75|g|#org:acme,run_id:run-1",
+		"security_scan.repos:4|g|#org:acme,run_id:run-1",
+		"security_scan.errors:1|g|#org:acme,run_id:run-1",
+		"security_scan.check.adoption:4|g|#org:acme,run_id:run-1,check:secret_scanning",
+		"security_scan.check.adoption:3|g|#org:acme,run_id:run-1,check:dependabot",
+		"security_scan.duration_seconds:200|g|#org:acme,run_id:run-1",
+	}, lines, "all six fit one datagram")
+}
+
+func TestSendStatsdSplitsDatagrams(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var metrics []Metric
+	for i := 0; i < 40; i++ {
+		metrics = append(metrics, Metric{Name: MetricRepos, Value: float64(i), Tags: []string{"org:" + strings.Repeat("x", 40)}})
+	}
+	cfg := &DatadogConfig{StatsdAddr: conn.LocalAddr().String()}
+	require.NoError(t, cfg.sendStatsd(context.Background(), metrics))
+
+	var lines []string
+	buf := make([]byte, 65536)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for len(lines) < len(metrics) {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		require.LessOrEqual(t, n, dogStatsdMaxPacketLength)
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+	require.Len(t, lines, 40)
+	sort.Strings(lines)
+	require.True(t, strings.HasPrefix(lines[0], MetricRepos+":0|g|#org:x"))
+}
+
+func TestEmitComplianceMetricsNotConfigured(t *testing.T) {
+	a := &Activities{}
+	_, err := newActivityEnv(a).ExecuteActivity(a.EmitComplianceMetrics, metricsInput(t, metricsReport()))
+	require.NoError(t, err)
+}
+
+func TestWorkflowIgnoresMetricsFailure(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(2))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	var emitted MetricsInput
+	env.OnActivity("EmitComplianceMetrics", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { emitted = args.Get(1).(MetricsInput) }).
+		Return(errors.New("Datadog returned 503"))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.NoError(t, env.GetWorkflowError(), "metrics never fail the scan")
+	require.Equal(t, "acme", emitted.Report["org"])
+}
+
+func TestDatadogSeriesURL(t *testing.T) {
+	require.Equal(t, "https://api.datadoghq.com/api/v2/series", (&DatadogConfig{}).seriesURL())
+	require.Equal(t, "https://api.datadoghq.eu/api/v2/series", (&DatadogConfig{Site: "datadoghq.eu"}).seriesURL())
+	require.Equal(t, "http://proxy:8080/api/v2/series", (&DatadogConfig{Site: "datadoghq.eu", APIURL: "http://proxy:8080/"}).seriesURL())
+}
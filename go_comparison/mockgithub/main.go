@@ -0,0 +1,40 @@
+// Mockgithub serves a deterministic fake GitHub API for demos and offline
+// development. Point the worker at it with GITHUB_API_URL:
+//
+//	go run ./go_comparison/mockgithub --org acme-corp --repos 250 --seed 7
+//	GITHUB_API_URL=http://localhost:8090 go run ./go_comparison/worker
+//	go run ./go_comparison/starter --org acme-corp
+//
+// The same --seed always produces the same repos and the same report.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/salkimmich/temporal-security-scanner/go_comparison/internal/githubmock"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8090", "Address to listen on")
+	org := flag.String("org", "acme-corp", "Organization to serve")
+	repos := flag.Int("repos", 250, "Number of repositories in the organization")
+	seed := flag.Int64("seed", 1, "Seed for the distribution of security settings")
+	token := flag.String("token", "", "Require this token (default: accept any credentials)")
+	rateLimit := flag.Int("rate-limit", githubmock.DefaultRateLimit, "Requests per hour; negative disables rate limiting")
+	enterprise := flag.String("enterprise", "acme", "Enterprise slug that owns the organization (for --enterprise scans)")
+	flag.Parse()
+
+	srv := githubmock.New(githubmock.Config{
+		Org:        *org,
+		Repos:      *repos,
+		Seed:       *seed,
+		Token:      *token,
+		RateLimit:  *rateLimit,
+		Enterprise: *enterprise,
+	})
+
+	log.Printf("Mock GitHub serving org '%s' (%d repos, seed %d) on http://%s", *org, *repos, *seed, *addr)
+	log.Fatalln(http.ListenAndServe(*addr, srv))
+}
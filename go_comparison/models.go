@@ -23,6 +23,17 @@ package scanner
 // args. This makes it safe to add fields later without breaking compatibility.
 // =============================================================================
 
+import (
+	"strings"
+	"time"
+)
+
+// Version identifies the worker build that produced a report. It is stamped
+// at build time:
+//
+//	go build -ldflags "-X github.com/salkimmich/temporal-security-scanner/go_comparison.Version=v1.2.3" ./go_comparison/worker
+var Version = "dev"
+
 // ScanInput is the input to the SecurityScanWorkflow.
 //
 // Python equivalent:
@@ -34,6 +45,493 @@ package scanner
 type ScanInput struct {
 	Org   string  `json:"org"`
 	Token *string `json:"token,omitempty"` // Pointer = optional (nil when absent)
+
+	// Credential says what kind of token the scan authenticates with and
+	// where the worker finds it (see credential.go). Token is a pat
+	// credential; set one or the other.
+	Credential *Credential `json:"credential,omitempty"`
+
+	// FailurePolicy overrides DefaultFailurePolicy when set.
+	FailurePolicy *FailurePolicy `json:"failure_policy,omitempty"`
+
+	// CompliancePolicy overrides DefaultCompliancePolicy when set.
+	CompliancePolicy *CompliancePolicy `json:"compliance_policy,omitempty"`
+
+	// Checks selects which checks run, by name from CheckRegistry. Empty
+	// means DefaultChecks.
+	Checks []string `json:"checks,omitempty"`
+
+	// IncludeAccessAudit adds CheckAccessAudit (deploy keys and outside
+	// collaborators) to Checks. It needs admin scope.
+	IncludeAccessAudit bool `json:"include_access_audit,omitempty"`
+
+	// DeployKeyMaxAgeDays flags deploy keys older than this many days.
+	// 0 means DefaultDeployKeyMaxAgeDays.
+	DeployKeyMaxAgeDays int `json:"deploy_key_max_age_days,omitempty"`
+
+	// ResultsOffloadBytes is the serialized size above which accumulated
+	// results are moved to blob storage (claim-check). 0 means
+	// DefaultResultsOffloadBytes; negative disables offloading.
+	ResultsOffloadBytes int `json:"results_offload_bytes,omitempty"`
+
+	// Suppressions are accepted risks excluded from compliance. They are
+	// combined with any loaded from SuppressionsSource.
+	Suppressions []Suppression `json:"suppressions,omitempty"`
+
+	// SuppressionsSource is a file path on the worker or an http(s) URL
+	// that LoadSuppressions reads more suppressions from.
+	SuppressionsSource string `json:"suppressions_source,omitempty"`
+
+	// ChildPerBatch scans each batch in a ScanBatchWorkflow child instead
+	// of in this workflow, keeping the parent's history small for very large
+	// orgs and making each batch's retries visible on its own.
+	ChildPerBatch bool `json:"child_per_batch,omitempty"`
+
+	// ActivityBatching checks up to 50 repos per CheckRepoSecurityBatch
+	// activity instead of running three activities per repo, for orgs
+	// large enough that the per-activity history dominates.
+	ActivityBatching bool `json:"activity_batching,omitempty"`
+
+	// Concurrency, when positive, scans with up to this many repos in
+	// flight at all times instead of in batches of 10, starting the next
+	// repo as soon as one finishes (see window.go). It does not combine
+	// with the batching options.
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// ActiveWithinDays, when positive, scans only repos pushed to within
+	// this many days of the scan's start. 0 scans every repo.
+	ActiveWithinDays int `json:"active_within_days,omitempty"`
+
+	// IncludeArchived scans archived repos too, flagging their results
+	// Archived. By default they are left out of the scan and its
+	// compliance rate, and only listed in the report's archived_repos.
+	IncludeArchived bool `json:"include_archived,omitempty"`
+
+	// IncludeTopics, when set, scans only repos with at least one of these
+	// topics; ExcludeTopics leaves out repos with any of its (see
+	// topics.go). Neither combines with Repos.
+	IncludeTopics []string `json:"include_topics,omitempty"`
+	ExcludeTopics []string `json:"exclude_topics,omitempty"`
+
+	// Deadline and MaxDurationSeconds time-box the scan: once the earlier
+	// of them passes, it stops with a partial report that lists the repos
+	// it did not scan (see deadline.go).
+	Deadline           *time.Time `json:"deadline,omitempty"`
+	MaxDurationSeconds int        `json:"max_duration_seconds,omitempty"`
+
+	// IncludeEvidence records, per check, the requests it made and what
+	// they returned, for audit trails (see evidence.go).
+	IncludeEvidence bool `json:"include_evidence,omitempty"`
+
+	// StragglerTimeout, when set, releases repos that take far longer than
+	// the rest of their batch and rescans them once every batch has run
+	// (see straggler.go). It does not apply with ActivityBatching.
+	StragglerTimeout *StragglerTimeout `json:"straggler_timeout,omitempty"`
+
+	// InitiatedBy and Reason say who started the scan and why, e.g. a user
+	// name or "schedule:<id>", and "incident INC-1234" (see initiator.go).
+	InitiatedBy string `json:"initiated_by,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+
+	// WorkerAffinity runs every repo check of the scan on one worker, in a
+	// session the workflow replaces if that worker goes away (see
+	// session.go). It does not combine with ChildPerBatch.
+	WorkerAffinity bool `json:"worker_affinity,omitempty"`
+
+	// PriorityRepos (names or globs) and PriorityTopics pick the repos to
+	// scan before the others, so a scan cut short has covered them (see
+	// priority.go).
+	PriorityRepos  []string `json:"priority_repos,omitempty"`
+	PriorityTopics []string `json:"priority_topics,omitempty"`
+
+	// ResumeFrom, when set, continues a cancelled or deadline-stopped
+	// scan: it scans only the repos that scan did not get to, and reports
+	// on both scans' results (see resume.go).
+	ResumeFrom *ResumeState `json:"resume_from,omitempty"`
+
+	// Repos, when set, scans exactly these "owner/name" repos instead of
+	// fetching the org's list. Every owner must be Org.
+	Repos []string `json:"repos,omitempty"`
+
+	// Teams, when set, scans the repos of these GitHub team slugs instead
+	// of the whole org. Cannot be combined with Repos.
+	Teams []string `json:"teams,omitempty"`
+
+	// ProgressIntervalSeconds, when positive, logs progress and upserts the
+	// ScanStatus search attribute, and ScanInitiatedBy when InitiatedBy is
+	// set, this often while repos are being scanned. The attributes must be
+	// registered on the namespace (Keyword).
+	ProgressIntervalSeconds int `json:"progress_interval_seconds,omitempty"`
+
+	// Timeouts, when set, overrides the timeouts of the fetch, scan and
+	// report activities (see timeouts.go).
+	Timeouts *Timeouts `json:"timeouts,omitempty"`
+
+	// RetryPolicies, when set, overrides the retry policies of the fetch,
+	// scan and report activities (see retrypolicy.go).
+	RetryPolicies *RetryPolicies `json:"retry_policies,omitempty"`
+
+	// ProgressWebhook, when set, POSTs the scan's progress to a URL as it
+	// goes (see progresswebhook.go).
+	ProgressWebhook *ProgressWebhook `json:"progress_webhook,omitempty"`
+
+	// Sinks, when set, are where the report is delivered once it is built
+	// (see sinks.go).
+	Sinks []ReportSinkSpec `json:"sinks,omitempty"`
+
+	// Provider is where Org lives: ProviderGitHub (the default when empty)
+	// or ProviderGitLab, in which case Org is a group path.
+	Provider string `json:"provider,omitempty"`
+
+	// GitLab holds options that only apply when Provider is ProviderGitLab.
+	GitLab *GitLabOptions `json:"gitlab,omitempty"`
+
+	// MaxAPIRequests, when positive, stops the scan after this many API
+	// requests: further requests fail with ErrTypeAPIBudgetExceeded and the
+	// report covers the repos scanned so far. Needs the worker to track API
+	// usage (see APIUsageTracker).
+	MaxAPIRequests int `json:"max_api_requests,omitempty"`
+
+	// BatchDelay, when set, pauses between batches of concurrent repo
+	// checks. Cancelling the scan cuts a pause short.
+	BatchDelay *BatchDelay `json:"batch_delay,omitempty"`
+}
+
+// ScanBatchInput is one batch of repos for scanBatch or ScanBatchWorkflow.
+type ScanBatchInput struct {
+	Org                 string      `json:"org"`
+	Credential          *Credential `json:"token,omitempty"`
+	Repos               []string    `json:"repos"`
+	Checks              []string    `json:"checks"`
+	DeployKeyMaxAgeDays int         `json:"deploy_key_max_age_days,omitempty"`
+
+	// Provider is ScanInput.Provider; empty means GitHub.
+	Provider string `json:"provider,omitempty"`
+
+	// NoAccess are selected checks the token cannot evaluate. They are not
+	// run; their results are recorded as StatusNoAccess.
+	NoAccess []string `json:"no_access,omitempty"`
+
+	// BatchDelay is ScanInput.BatchDelay, applied by ScanBatchWorkflow
+	// between its groups.
+	BatchDelay *BatchDelay `json:"batch_delay,omitempty"`
+
+	// ActivityBatching is ScanInput.ActivityBatching.
+	ActivityBatching bool `json:"activity_batching,omitempty"`
+
+	// ScanRetry is ScanInput.RetryPolicies.Scan.
+	ScanRetry *RetryOverride `json:"scan_retry,omitempty"`
+
+	// ScanTimeouts is ScanInput.Timeouts.Scan.
+	ScanTimeouts *ActivityTimeouts `json:"scan_timeouts,omitempty"`
+
+	// Stragglers is ScanInput.StragglerTimeout.
+	Stragglers *StragglerTimeout `json:"stragglers,omitempty"`
+
+	// IncludeEvidence is ScanInput.IncludeEvidence.
+	IncludeEvidence bool `json:"include_evidence,omitempty"`
+}
+
+// ScanBatchResult is what ScanBatchWorkflow returns. Results include repos
+// that errored, with Error set.
+type ScanBatchResult struct {
+	Results   []RepoSecurityResult `json:"results"`
+	Cancelled bool                 `json:"cancelled,omitempty"`
+
+	// BudgetExceeded means the scan's API budget ran out; the repos it cut
+	// short are not in Results.
+	BudgetExceeded bool `json:"budget_exceeded,omitempty"`
+
+	// CancelledInFlight are the repos whose scan was cancelled while it
+	// ran; they are not in Results.
+	CancelledInFlight []string `json:"cancelled_in_flight,omitempty"`
+
+	// TimedOutInBatch are the stragglers released from their group (see
+	// straggler.go); they are not in Results.
+	TimedOutInBatch []string `json:"timed_out_in_batch,omitempty"`
+}
+
+// ReportInput is everything BuildReport needs: the results to aggregate and
+// the run metadata the workflow used to add to the report itself.
+type ReportInput struct {
+	Org          string               `json:"org"`
+	Provider     string               `json:"provider,omitempty"`
+	Results      []RepoSecurityResult `json:"results"`
+	Refs         []BlobRef            `json:"refs,omitempty"`
+	Policy       CompliancePolicy     `json:"policy"`
+	Checks       []string             `json:"checks"`
+	Suppressions []Suppression        `json:"suppressions,omitempty"`
+
+	// Errors is the number of repos whose scan failed; their results are
+	// not in Results.
+	Errors int `json:"errors"`
+	// ErrorsByCategory splits Errors by ErrorCategory, and RetryLater
+	// lists the errored repos whose category is retryable.
+	ErrorsByCategory    map[ErrorCategory]int `json:"errors_by_category,omitempty"`
+	RetryLater          []string              `json:"retry_later,omitempty"`
+	EstimatedAPICalls   int                   `json:"estimated_api_calls"`
+	ExpiredSuppressions []Suppression         `json:"expired_suppressions,omitempty"`
+	ActiveWithinDays    int                   `json:"active_within_days,omitempty"`
+	SkippedInactive     []string              `json:"skipped_inactive,omitempty"`
+	// ArchivedRepos are the archived repos left out of the scan.
+	ArchivedRepos []string `json:"archived_repos,omitempty"`
+	// IncludeTopics and ExcludeTopics are ScanInput's; SkippedByTopic are
+	// the repos they left out of the scan.
+	IncludeTopics  []string `json:"include_topics,omitempty"`
+	ExcludeTopics  []string `json:"exclude_topics,omitempty"`
+	SkippedByTopic []string `json:"skipped_by_topic,omitempty"`
+	// Deadline is the scan's deadline, if it had one. UnscannedRepos are
+	// the repos a cancelled scan, one DeadlineReached, or one stopped by
+	// its API budget, stopped before scanning.
+	Deadline        *time.Time `json:"deadline,omitempty"`
+	DeadlineReached bool       `json:"deadline_reached,omitempty"`
+	UnscannedRepos  []string   `json:"unscanned_repos,omitempty"`
+	// TimedOutInBatch are the stragglers released from their batch and
+	// rescanned in a second pass.
+	TimedOutInBatch []string `json:"timed_out_in_batch,omitempty"`
+	// PriorityRepos is how many of the repos to scan were priority repos,
+	// and PriorityReposScanned how many of those were scanned.
+	PriorityRepos        int `json:"priority_repos,omitempty"`
+	PriorityReposScanned int `json:"priority_repos_scanned,omitempty"`
+	// ResumedFromRunIDs are the runs a resumed scan continues.
+	ResumedFromRunIDs []string `json:"resumed_from_run_ids,omitempty"`
+	// OrgRenamedTo is the login Org was renamed to, which the scan ran
+	// under (see orgrename.go).
+	OrgRenamedTo string `json:"org_renamed_to,omitempty"`
+	// RepoList is set for a scan of ScanInput.Repos rather than the org's
+	// listing.
+	RepoList bool     `json:"repo_list,omitempty"`
+	Teams    []string `json:"teams,omitempty"`
+	// TeamRepos maps each selected team to its scanned repos.
+	TeamRepos map[string][]string `json:"team_repos,omitempty"`
+	// DuplicateRepos is how many repeated repos were dropped from the list
+	// before scanning.
+	DuplicateRepos int `json:"duplicate_repos,omitempty"`
+	// WorkerSessions is how many worker sessions a WorkerAffinity scan
+	// ran its checks in.
+	WorkerSessions int `json:"worker_sessions,omitempty"`
+	// InitiatedBy and Reason are ScanInput's.
+	InitiatedBy string `json:"initiated_by,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	// Disappeared are the listed repos gone by the time they were
+	// checked; they are not in Results or Errors.
+	Disappeared []DisappearedRepo `json:"disappeared,omitempty"`
+
+	WorkflowID  string    `json:"workflow_id"`
+	RunID       string    `json:"run_id"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+
+	Cancelled                bool   `json:"cancelled,omitempty"`
+	CancelReason             string `json:"cancel_reason,omitempty"`
+	ReposScannedBeforeCancel int    `json:"repos_scanned_before_cancel,omitempty"`
+	// CancelledInFlight are the repos being scanned when the scan was
+	// cancelled.
+	CancelledInFlight []string `json:"cancelled_in_flight,omitempty"`
+
+	TokenCapabilities *TokenCapabilities `json:"token_capabilities,omitempty"`
+
+	// APIUsage is the run's API requests, when the worker tracks them.
+	APIUsage *APIUsage `json:"api_usage,omitempty"`
+}
+
+// DefaultDeployKeyMaxAgeDays is the age past which a deploy key is stale.
+const DefaultDeployKeyMaxAgeDays = 365
+
+// APICallsPerRepo is the worst-case number of GitHub requests one repo costs
+// with this input: the repo lookup plus each selected check's APICalls,
+// ignoring pagination of the access audit listings.
+func (in ScanInput) APICallsPerRepo() int {
+	calls := 1
+	for _, name := range in.checks().names() {
+		calls += lookupCheck(name).APICalls
+	}
+	return calls
+}
+
+// DefaultResultsOffloadBytes keeps every payload well under Temporal's
+// 2 MiB limit; 512 KiB is also where the server starts warning.
+const DefaultResultsOffloadBytes = 512 * 1024
+
+// BlobRef is the claim check for a chunk of results stored by StoreResults.
+// Only the reference travels through workflow history; LoadResults (or
+// GenerateReport) resolves it on the worker.
+type BlobRef struct {
+	URI    string `json:"uri"`
+	Count  int    `json:"count"`
+	Bytes  int    `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// ErrTypeScanDegraded is the ApplicationError type returned when too many
+// repositories errored for the report to be trusted. The partial report is
+// attached as the error's details.
+const ErrTypeScanDegraded = "SCAN_DEGRADED"
+
+// FailurePolicy decides when a scan is broken rather than merely non-compliant.
+//
+// A revoked token makes every CheckRepoSecurity fail. Without a policy the
+// workflow would "succeed" with 0 scanned repos and report 0% compliance,
+// which is indistinguishable from a genuinely non-compliant org.
+type FailurePolicy struct {
+	// MaxErrorRatio is the fraction of errored repos above which the scan
+	// is considered degraded (0.5 = more than half).
+	MaxErrorRatio float64 `json:"max_error_ratio"`
+	// MinRepos is the number of attempted repos required before
+	// MaxErrorRatio applies. Below it, only a 100% error rate fails the scan.
+	MinRepos int `json:"min_repos"`
+}
+
+// DefaultFailurePolicy fails the scan when every repo errored, or when more
+// than half errored with at least 10 repos attempted.
+func DefaultFailurePolicy() FailurePolicy {
+	return FailurePolicy{MaxErrorRatio: 0.5, MinRepos: 10}
+}
+
+// IsDegraded reports whether errors out of attempted repos breaches the policy.
+func (p FailurePolicy) IsDegraded(attempted, errors int) bool {
+	if attempted == 0 || errors == 0 {
+		return false
+	}
+	if errors >= attempted {
+		return true
+	}
+	return attempted >= p.MinRepos && float64(errors)/float64(attempted) > p.MaxErrorRatio
+}
+
+// CompliancePolicy selects which checks a repository must pass to count as
+// compliant. Checks that are not required are still reported.
+type CompliancePolicy struct {
+	// Name labels the policy in the scan's memo, e.g. "pci-tier-1"; it
+	// changes nothing else.
+	Name string `json:"name,omitempty"`
+
+	RequireSecretScanning bool `json:"require_secret_scanning"`
+	RequireDependabot     bool `json:"require_dependabot"`
+	RequireCodeScanning   bool `json:"require_code_scanning"`
+	RequireCodeowners     bool `json:"require_codeowners"`
+	RequireSecurityPolicy bool `json:"require_security_policy"`
+
+	// RequireReadOnlyWorkflowToken requires the default GITHUB_TOKEN to be
+	// read-only. Repos with Actions disabled pass.
+	RequireReadOnlyWorkflowToken bool `json:"require_read_only_workflow_token"`
+
+	// RequireSecurityUpdates and RequireDependabotConfig require Dependabot
+	// to open fix pull requests and a valid dependabot.yml (dependabot.go).
+	RequireSecurityUpdates  bool `json:"require_security_updates,omitempty"`
+	RequireDependabotConfig bool `json:"require_dependabot_config,omitempty"`
+
+	// RequireSecureWebhooks requires every webhook to use HTTPS and a
+	// secret, and to point within WebhookAllowedDomains when it is set
+	// (webhooks.go). The allowlist applies whenever the webhooks check
+	// runs, required or not.
+	RequireSecureWebhooks bool     `json:"require_secure_webhooks,omitempty"`
+	WebhookAllowedDomains []string `json:"webhook_allowed_domains,omitempty"`
+
+	// Scoring weights the checks into the report's compliance score; nil
+	// means DefaultScoringPolicy.
+	Scoring *ScoringPolicy `json:"scoring,omitempty"`
+
+	// IndeterminateFails counts a repo whose only failed checks could not
+	// be determined (StatusNoAccess, StatusUnknown, StatusError) as
+	// non-compliant, as before such repos were counted apart as
+	// indeterminate.
+	IndeterminateFails bool `json:"indeterminate_fails,omitempty"`
+}
+
+// DefaultCompliancePolicy requires the three GHAS features, matching the
+// Python version's is_fully_compliant.
+func DefaultCompliancePolicy() CompliancePolicy {
+	return CompliancePolicy{
+		RequireSecretScanning: true,
+		RequireDependabot:     true,
+		RequireCodeScanning:   true,
+	}
+}
+
+// IsCompliant reports whether r passes every check the policy requires:
+// each required result in r.Checks must be StatusEnabled. A check that could
+// not be determined does not pass.
+func (p CompliancePolicy) IsCompliant(r *RepoSecurityResult) bool {
+	return len(p.failedResults(r)) == 0
+}
+
+// ComplianceOutcome is how a repo counts towards an org's compliance.
+type ComplianceOutcome int
+
+const (
+	OutcomeCompliant ComplianceOutcome = iota
+	OutcomeNonCompliant
+	// OutcomeIndeterminate is a repo that failed no check but could not
+	// read every required one, usually for want of token scope. It counts
+	// neither way in the compliance rate.
+	OutcomeIndeterminate
+)
+
+// outcome is r's ComplianceOutcome given the required results it fails, after
+// suppressions (see evaluate).
+func (p CompliancePolicy) outcome(r *RepoSecurityResult, failed []string) ComplianceOutcome {
+	if len(failed) == 0 {
+		return OutcomeCompliant
+	}
+	if p.IndeterminateFails {
+		return OutcomeNonCompliant
+	}
+	for _, name := range failed {
+		if !r.Check(name).Status.indeterminate() {
+			return OutcomeNonCompliant
+		}
+	}
+	return OutcomeIndeterminate
+}
+
+// Outcome is r's ComplianceOutcome under the policy, without suppressions.
+func (p CompliancePolicy) Outcome(r *RepoSecurityResult) ComplianceOutcome {
+	return p.outcome(r, p.failedResults(r))
+}
+
+// failedResults lists the required results of r that are not StatusEnabled.
+func (p CompliancePolicy) failedResults(r *RepoSecurityResult) []string {
+	var failed []string
+	for _, name := range p.requiredResults() {
+		if r.Check(name).Status != StatusEnabled {
+			failed = append(failed, name)
+		}
+	}
+	return failed
+}
+
+// requiredResults lists the RepoSecurityResult.Checks keys the policy requires.
+func (p CompliancePolicy) requiredResults() []string {
+	var out []string
+	if p.RequireSecretScanning {
+		out = append(out, CheckSecretScanning)
+	}
+	if p.RequireDependabot {
+		out = append(out, CheckDependabot)
+	}
+	if p.RequireCodeScanning {
+		out = append(out, CheckCodeScanning)
+	}
+	if p.RequireCodeowners {
+		out = append(out, ResultCodeowners)
+	}
+	if p.RequireSecurityPolicy {
+		out = append(out, ResultSecurityPolicy)
+	}
+	if p.RequireReadOnlyWorkflowToken {
+		out = append(out, ResultReadOnlyWorkflowToken)
+	}
+	if p.RequireSecurityUpdates {
+		out = append(out, CheckSecurityUpdates)
+	}
+	if p.RequireDependabotConfig {
+		out = append(out, CheckDependabotConfig)
+	}
+	if p.RequireSecureWebhooks {
+		out = append(out, CheckWebhooks)
+	}
+	return out
 }
 
 // RepoInfo contains minimal repository data needed for scanning.
@@ -51,6 +549,52 @@ type RepoInfo struct {
 	FullName string `json:"full_name"`
 	Private  bool   `json:"private"`
 	Archived bool   `json:"archived"`
+
+	// PushedAt is nil for an empty repository that was never pushed to.
+	PushedAt  *time.Time `json:"pushed_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+
+	// Teams are the selected teams the repo belongs to in a team scan.
+	Teams []string `json:"teams,omitempty"`
+
+	RepoMetadata
+}
+
+// RepoMetadata is what the repo listing says about a repo besides its name.
+// The workflow copies it into the repo's RepoSecurityResult. Repos named
+// in ScanInput.Repos have none.
+type RepoMetadata struct {
+	DefaultBranch string `json:"default_branch,omitempty"`
+	Fork          bool   `json:"fork,omitempty"`
+	// Visibility is "public", "private" or "internal".
+	Visibility string `json:"visibility,omitempty"`
+	// Language is the repo's primary language; empty when the provider
+	// does not know it.
+	Language string   `json:"language,omitempty"`
+	Topics   []string `json:"topics,omitempty"`
+}
+
+// isZero reports whether m holds no metadata.
+func (m RepoMetadata) isZero() bool {
+	return m.DefaultBranch == "" && !m.Fork && m.Visibility == "" && m.Language == "" && len(m.Topics) == 0
+}
+
+// visibility is GitHub's visibility field, or what private implies when a
+// server too old to send it left it out.
+func visibility(v string, private bool) string {
+	switch {
+	case v != "":
+		return v
+	case private:
+		return "private"
+	}
+	return "public"
+}
+
+// ActiveSince reports whether the repo was pushed to at or after t. A repo
+// that was never pushed to is inactive.
+func (r RepoInfo) ActiveSince(t time.Time) bool {
+	return r.PushedAt != nil && !r.PushedAt.Before(t)
 }
 
 // SecurityStatus represents the state of a security feature.
@@ -63,6 +607,11 @@ type RepoInfo struct {
 //	class SecurityStatus(StrEnum):
 //	    ENABLED = "enabled"
 //	    DISABLED = "disabled"
+//
+// The values are provider-neutral: each Provider maps its own responses onto
+// them (a GitLab project without SAST is StatusNotConfigured, as a GitHub
+// repo without code scanning is), and nothing downstream knows which one
+// produced a result.
 type SecurityStatus string
 
 const (
@@ -72,8 +621,20 @@ const (
 	StatusNoAccess      SecurityStatus = "no access"
 	StatusUnknown       SecurityStatus = "unknown"
 	StatusError         SecurityStatus = "error"
+	// StatusMisconfigured is a configuration file that exists but does not
+	// parse or is invalid, e.g. a malformed dependabot.yml.
+	StatusMisconfigured SecurityStatus = "misconfigured"
+	// StatusGone is every check of a repo that disappeared between the
+	// listing and its check (see disappeared.go).
+	StatusGone SecurityStatus = "gone"
 )
 
+// indeterminate reports whether s says the check could not be read rather
+// than how it is set.
+func (s SecurityStatus) indeterminate() bool {
+	return s == StatusNoAccess || s == StatusUnknown || s == StatusError
+}
+
 // RepoSecurityResult holds the scan result for one repository.
 //
 // Python equivalent uses a @property for is_fully_compliant.
@@ -87,21 +648,390 @@ const (
 //	    @property
 //	    def is_fully_compliant(self) -> bool:
 //	        return (self.secret_scanning == SecurityStatus.ENABLED and ...)
+//
+// Checks holds one CheckResult per check outcome and is what compliance and
+// the report are computed from. New checks add keys there instead of fields
+// here. The named status fields below are kept, computed from Checks, for
+// consumers of the original JSON shape.
 type RepoSecurityResult struct {
-	Repository      string         `json:"repository"`
-	SecretScanning  SecurityStatus `json:"secret_scanning"`
+	Repository string                 `json:"repository"`
+	Checks     map[string]CheckResult `json:"checks,omitempty"`
+
+	// Provider is the SCM the repo was read from. Results from workers that
+	// predate providers leave it empty, which means GitHub.
+	Provider string `json:"provider,omitempty"`
+
+	SecretScanning   SecurityStatus `json:"secret_scanning"`
 	DependabotAlerts SecurityStatus `json:"dependabot_alerts"`
-	CodeScanning    SecurityStatus `json:"code_scanning"`
-	Error           *string        `json:"error,omitempty"`
-	ScannedAt       string         `json:"scanned_at"`
+	CodeScanning     SecurityStatus `json:"code_scanning"`
+
+	// File checks: nil means unknown (the token could not read the repo's
+	// contents); an empty file counts as absent.
+	HasCodeowners     *bool `json:"has_codeowners"`
+	HasSecurityPolicy *bool `json:"has_security_policy"`
+
+	// Actions is nil when the Actions settings could not be read.
+	Actions *ActionsSecurity `json:"actions,omitempty"`
+
+	// SecurityUpdates is the security_updates result's status; empty when
+	// the check did not run. DependabotConfig is the dependabot.yml found,
+	// if any (see dependabot.go).
+	SecurityUpdates  SecurityStatus    `json:"security_updates,omitempty"`
+	DependabotConfig *DependabotConfig `json:"dependabot_config,omitempty"`
+
+	// WebhookFindings is every webhook the webhooks check read, with its
+	// issues (see webhooks.go); nil when the check did not run.
+	WebhookFindings []WebhookFinding `json:"webhook_findings,omitempty"`
+
+	// Access is nil unless ScanInput.IncludeAccessAudit is set.
+	Access *AccessAudit `json:"access,omitempty"`
+
+	// Metadata is the repo's RepoMetadata from the listing; nil for repos
+	// named explicitly and in results of scans that predate it.
+	Metadata *RepoMetadata `json:"metadata,omitempty"`
+
+	// Archived is set on the results of archived repos, which are only
+	// scanned when ScanInput.IncludeArchived asks for them.
+	Archived bool `json:"archived,omitempty"`
+
+	// TokenLacksRepoAccess is set when a token granted repo by repo was
+	// not granted this one; its checks are StatusNoAccess (see
+	// repoaccess.go).
+	TokenLacksRepoAccess bool `json:"token_lacks_repo_access,omitempty"`
+
+	// MatchedTopics are the ScanInput.IncludeTopics the repo has,
+	// lowercased.
+	MatchedTopics []string `json:"matched_topics,omitempty"`
+
+	// Error is set when the repo could not be scanned, and ScanError
+	// says why (see scanerror.go). Error is kept for readers of the
+	// original JSON shape.
+	Error     *string    `json:"error,omitempty"`
+	ScanError *ScanError `json:"scan_error,omitempty"`
+	// Disappeared is set when the repo was gone by the time it was
+	// checked; it is neither an error nor a result (see disappeared.go).
+	Disappeared *DisappearedRepo `json:"disappeared,omitempty"`
+	ScannedAt   string           `json:"scanned_at"`
+}
+
+// AccessAudit lists who besides org members can change a repository, as
+// reported by AuditRepoAccess. Both listings need admin scope; a listing the
+// token could not read is named in NoAccess and its counts stay zero.
+type AccessAudit struct {
+	DeployKeys        int                `json:"deploy_keys"`
+	FlaggedDeployKeys []FlaggedDeployKey `json:"flagged_deploy_keys,omitempty"`
+
+	// OutsideCollaborators counts outside collaborators with write or admin
+	// permission; all of them are listed.
+	OutsideCollaborators []OutsideCollaborator `json:"outside_collaborators,omitempty"`
+
+	NoAccess []string `json:"no_access,omitempty"` // "deploy_keys", "collaborators"
+}
+
+// Values of AccessAudit.NoAccess.
+const (
+	AccessDeployKeys    = "deploy_keys"
+	AccessCollaborators = "collaborators"
+)
+
+// FlaggedDeployKey is a deploy key that can push, is stale, or both.
+type FlaggedDeployKey struct {
+	ID        int64  `json:"id"`
+	Title     string `json:"title"`
+	ReadOnly  bool   `json:"read_only"`
+	CreatedAt string `json:"created_at"`
+	Stale     bool   `json:"stale"`
+}
+
+// OutsideCollaborator is a non-member with write or admin access.
+type OutsideCollaborator struct {
+	Login      string `json:"login"`
+	Permission string `json:"permission"` // "admin", "maintain", or "write"
+}
+
+// Findings is the number of flagged deploy keys plus privileged outside
+// collaborators, used to rank repos in the report.
+func (a *AccessAudit) Findings() int {
+	if a == nil {
+		return 0
+	}
+	return len(a.FlaggedDeployKeys) + len(a.OutsideCollaborators)
+}
+
+// Values of ActionsSecurity.AllowedActions.
+const (
+	AllowedActionsAll       = "all"
+	AllowedActionsLocalOnly = "local_only"
+	AllowedActionsSelected  = "selected"
+)
+
+// Values of ActionsSecurity.DefaultWorkflowPermissions.
+const (
+	WorkflowPermissionsRead  = "read"
+	WorkflowPermissionsWrite = "write"
+)
+
+// ActionsSecurity holds a repository's GitHub Actions hardening settings,
+// as reported by CheckActionsSecurity. Empty strings mean the setting could
+// not be read (or does not apply because Actions is disabled).
+type ActionsSecurity struct {
+	ActionsEnabled             bool   `json:"actions_enabled"`
+	AllowedActions             string `json:"allowed_actions,omitempty"`
+	DefaultWorkflowPermissions string `json:"default_workflow_permissions,omitempty"`
+}
+
+// IsReadOnlyToken reports whether workflows in the repo get a read-only
+// GITHUB_TOKEN by default. Disabled Actions means no token at all.
+func (a *ActionsSecurity) IsReadOnlyToken() bool {
+	if a == nil {
+		return false
+	}
+	return !a.ActionsEnabled || a.DefaultWorkflowPermissions == WorkflowPermissionsRead
 }
 
 // IsFullyCompliant checks whether all security features are enabled.
 // In Python this is a @property; in Go it's an explicit method.
+// Scans with a custom CompliancePolicy use CompliancePolicy.IsCompliant.
 func (r *RepoSecurityResult) IsFullyCompliant() bool {
-	return r.SecretScanning == StatusEnabled &&
-		r.DependabotAlerts == StatusEnabled &&
-		r.CodeScanning == StatusEnabled
+	return DefaultCompliancePolicy().IsCompliant(r)
+}
+
+// CheckResult is the outcome of one check on one repository.
+//
+// Status is StatusEnabled when the control is in place (a file is present,
+// the GITHUB_TOKEN is read-only, ...), so a compliance policy only ever
+// compares against StatusEnabled.
+type CheckResult struct {
+	Status SecurityStatus `json:"status"`
+	// Details holds check-specific counts, e.g. flagged deploy keys.
+	Details map[string]int `json:"details,omitempty"`
+	// Message explains the status when one word is not enough.
+	Message string `json:"message,omitempty"`
+	// Evidence is set with ScanInput.IncludeEvidence (see evidence.go).
+	Evidence []Evidence `json:"evidence,omitempty"`
+}
+
+// Check returns the result recorded under name, or StatusUnknown.
+//
+// Results from workers that predate Checks carry only the named fields; the
+// result is then derived from those, so old histories and stored blobs are
+// reported the same way as new ones.
+func (r *RepoSecurityResult) Check(name string) CheckResult {
+	if c, ok := r.Checks[name]; ok {
+		return c
+	}
+	switch name {
+	case CheckSecretScanning:
+		return statusResult(r.SecretScanning)
+	case CheckDependabot:
+		return statusResult(r.DependabotAlerts)
+	case CheckCodeScanning:
+		return statusResult(r.CodeScanning)
+	case ResultCodeowners:
+		return presenceResult(r.HasCodeowners)
+	case ResultSecurityPolicy:
+		return presenceResult(r.HasSecurityPolicy)
+	case CheckActions:
+		c, _ := actionsResults(r.Actions)
+		return c
+	case ResultReadOnlyWorkflowToken:
+		_, c := actionsResults(r.Actions)
+		return c
+	case CheckAccessAudit:
+		return accessResult(r.Access)
+	}
+	return CheckResult{Status: StatusUnknown}
+}
+
+// setCheck records c under name and updates the named field derived from it.
+func (r *RepoSecurityResult) setCheck(name string, c CheckResult) {
+	if r.Checks == nil {
+		r.Checks = make(map[string]CheckResult)
+	}
+	r.Checks[name] = c
+	switch name {
+	case CheckSecretScanning:
+		r.SecretScanning = c.Status
+	case CheckDependabot:
+		r.DependabotAlerts = c.Status
+	case CheckCodeScanning:
+		r.CodeScanning = c.Status
+	case ResultCodeowners:
+		r.HasCodeowners = presence(c.Status)
+	case ResultSecurityPolicy:
+		r.HasSecurityPolicy = presence(c.Status)
+	case CheckSecurityUpdates:
+		r.SecurityUpdates = c.Status
+	}
+}
+
+// checkResultKeys are the keys of r.Checks that check records.
+func checkResultKeys(check string) []string {
+	switch check {
+	case CheckFiles:
+		return []string{ResultCodeowners, ResultSecurityPolicy}
+	case CheckActions:
+		return []string{CheckActions, ResultReadOnlyWorkflowToken}
+	}
+	return []string{check}
+}
+
+// setNoAccess records every result of check as StatusNoAccess.
+func (r *RepoSecurityResult) setNoAccess(check, message string) {
+	for _, key := range checkResultKeys(check) {
+		r.setCheck(key, CheckResult{Status: StatusNoAccess, Message: message})
+	}
+}
+
+// setCheckError records every result of check as StatusError, for a check
+// whose requests failed.
+func (r *RepoSecurityResult) setCheckError(check, message string) {
+	for _, key := range checkResultKeys(check) {
+		r.setCheck(key, CheckResult{Status: StatusError, Message: message})
+	}
+}
+
+// setActions stores the CheckActionsSecurity result; nil means unknown.
+func (r *RepoSecurityResult) setActions(a *ActionsSecurity) {
+	r.Actions = a
+	actions, readOnly := actionsResults(a)
+	r.setCheck(CheckActions, actions)
+	r.setCheck(ResultReadOnlyWorkflowToken, readOnly)
+}
+
+// setAccess stores the AuditRepoAccess result; nil means unknown.
+func (r *RepoSecurityResult) setAccess(a *AccessAudit) {
+	r.Access = a
+	r.setCheck(CheckAccessAudit, accessResult(a))
+}
+
+func statusResult(s SecurityStatus) CheckResult {
+	if s == "" {
+		s = StatusUnknown
+	}
+	return CheckResult{Status: s}
+}
+
+// presenceResult maps a file check's *bool to a CheckResult; presence is
+// the inverse.
+func presenceResult(b *bool) CheckResult {
+	switch {
+	case b == nil:
+		return CheckResult{Status: StatusUnknown}
+	case *b:
+		return CheckResult{Status: StatusEnabled}
+	}
+	return CheckResult{Status: StatusDisabled}
+}
+
+func presence(s SecurityStatus) *bool {
+	if s != StatusEnabled && s != StatusDisabled {
+		return nil
+	}
+	b := s == StatusEnabled
+	return &b
+}
+
+// actionsResults splits ActionsSecurity into the "actions" result (is
+// Actions enabled, and is it restricted to local or selected actions) and
+// the read-only GITHUB_TOKEN result.
+func actionsResults(a *ActionsSecurity) (actions, readOnly CheckResult) {
+	if a == nil {
+		return CheckResult{Status: StatusUnknown}, CheckResult{Status: StatusUnknown}
+	}
+	actions = CheckResult{Status: StatusDisabled}
+	if a.ActionsEnabled {
+		restricted := 0
+		if a.AllowedActions == AllowedActionsLocalOnly || a.AllowedActions == AllowedActionsSelected {
+			restricted = 1
+		}
+		actions = CheckResult{
+			Status:  StatusEnabled,
+			Details: map[string]int{"restricted": restricted},
+			Message: "allowed_actions: " + a.AllowedActions,
+		}
+	}
+	readOnly = CheckResult{Status: StatusDisabled, Message: "default_workflow_permissions: " + a.DefaultWorkflowPermissions}
+	if a.IsReadOnlyToken() {
+		readOnly = CheckResult{Status: StatusEnabled}
+	}
+	return actions, readOnly
+}
+
+// accessResult summarizes an AccessAudit: StatusEnabled when nothing was
+// flagged, StatusDisabled when something was, and StatusNoAccess when a
+// listing could not be read and the other flagged nothing. The counts are
+// in Details.
+func accessResult(a *AccessAudit) CheckResult {
+	if a == nil {
+		return CheckResult{Status: StatusUnknown}
+	}
+	c := CheckResult{
+		Status: StatusEnabled,
+		Details: map[string]int{
+			"deploy_keys":           a.DeployKeys,
+			"flagged_deploy_keys":   len(a.FlaggedDeployKeys),
+			"outside_collaborators": len(a.OutsideCollaborators),
+		},
+	}
+	if len(a.NoAccess) > 0 {
+		c.Status = StatusNoAccess
+		c.Message = "cannot list " + strings.Join(a.NoAccess, ", ")
+	}
+	if a.Findings() > 0 {
+		c.Status = StatusDisabled
+	}
+	return c
+}
+
+// ScanStatus is the phase a scan is in, ScanProgress.Status. Queries and the
+// ScanStatus search attribute carry the values, so they never change once
+// released; a new phase adds a value.
+type ScanStatus string
+
+const (
+	ScanStarting      ScanStatus = "starting"
+	ScanFetchingRepos ScanStatus = "fetching_repos"
+	ScanScanning      ScanStatus = "scanning"
+	// ScanPaused is the pause between batches (ScanInput.BatchDelay). Its
+	// value is from before the name.
+	ScanPaused ScanStatus = "sleeping"
+	// ScanHeld is a scan held before its next batch by pause_scan until
+	// resume_scan (see pause.go).
+	ScanHeld ScanStatus = "held"
+	// ScanRetryingFailures is the second pass that rescans the stragglers
+	// released from their batches (ScanInput.StragglerTimeout).
+	ScanRetryingFailures ScanStatus = "retrying_failures"
+
+	ScanCancelled      ScanStatus = "cancelled"
+	ScanBudgetExceeded ScanStatus = "budget_exceeded"
+	// ScanDeadlineReached is a scan stopped at ScanInput.Deadline.
+	ScanDeadlineReached ScanStatus = "deadline_reached"
+	ScanCompleted       ScanStatus = "completed"
+	// ScanEmpty is a completed scan that found no repos to scan.
+	ScanEmpty    ScanStatus = "empty"
+	ScanDegraded ScanStatus = "degraded"
+)
+
+// Valid reports whether s is one of the ScanStatus constants.
+func (s ScanStatus) Valid() bool {
+	switch s {
+	case ScanStarting, ScanFetchingRepos, ScanScanning, ScanPaused, ScanHeld, ScanRetryingFailures,
+		ScanCancelled, ScanBudgetExceeded, ScanDeadlineReached, ScanCompleted, ScanEmpty, ScanDegraded:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether the scan is done scanning: it has, or is
+// writing, its report. Scans of older workers may report statuses this
+// build does not know; those are not terminal.
+func (s ScanStatus) IsTerminal() bool {
+	switch s {
+	case ScanCancelled, ScanBudgetExceeded, ScanDeadlineReached, ScanCompleted, ScanEmpty, ScanDegraded:
+		return true
+	}
+	return false
 }
 
 // ScanProgress represents the queryable state of an in-flight scan.
@@ -111,13 +1041,49 @@ func (r *RepoSecurityResult) IsFullyCompliant() bool {
 // an instance of this struct as internal state, and a query handler
 // returns it on demand.
 type ScanProgress struct {
-	Org              string `json:"org"`
-	TotalRepos       int    `json:"total_repos"`
-	ScannedRepos     int    `json:"scanned_repos"`
-	CompliantRepos   int    `json:"compliant_repos"`
-	NonCompliantRepos int   `json:"non_compliant_repos"`
-	Errors           int    `json:"errors"`
-	Status           string `json:"status"`
+	Org               string `json:"org"`
+	TotalRepos        int    `json:"total_repos"`
+	ScannedRepos      int    `json:"scanned_repos"`
+	CompliantRepos    int    `json:"compliant_repos"`
+	NonCompliantRepos int    `json:"non_compliant_repos"`
+	// IndeterminateRepos is the repos counted as OutcomeIndeterminate.
+	IndeterminateRepos int `json:"indeterminate_repos"`
+	// TimedOutInBatch is the repos released from their batch as
+	// stragglers, to be rescanned in a second pass.
+	TimedOutInBatch int `json:"timed_out_in_batch,omitempty"`
+	// CancelledInFlight is the repos being scanned when the scan was
+	// cancelled, which have no result.
+	CancelledInFlight int `json:"cancelled_in_flight,omitempty"`
+	// DisappearedRepos is the repos gone by the time they were checked,
+	// which count neither as scanned nor as errors.
+	DisappearedRepos int `json:"disappeared_repos,omitempty"`
+	// ReposListed is the repos listed so far while Status is
+	// ScanFetchingRepos a page at a time.
+	ReposListed int        `json:"repos_listed,omitempty"`
+	Errors      int        `json:"errors"`
+	Status      ScanStatus `json:"status"`
+
+	// NextBatchAt is when the next batch starts while Status is
+	// ScanPaused (see ScanInput.BatchDelay).
+	NextBatchAt *time.Time `json:"next_batch_at,omitempty"`
+	// Batch is the batch being scanned, from 1, of Batches. Scans with
+	// ScanInput.Concurrency have no batches and leave both zero.
+	Batch   int `json:"batch,omitempty"`
+	Batches int `json:"batches,omitempty"`
+	// PauseRequested is whether pause_scan holds the scan; it takes
+	// effect before the next batch, when Status becomes ScanHeld.
+	PauseRequested bool `json:"pause_requested,omitempty"`
+	// Deadline is when the scan stops if it has not finished (see
+	// ScanInput.Deadline).
+	Deadline *time.Time `json:"deadline,omitempty"`
+
+	// Run metadata for auditors. Times come from workflow.Now, so they are
+	// deterministic across replays. CompletedAt stays zero while running.
+	WorkflowID  string    `json:"workflow_id"`
+	RunID       string    `json:"run_id"`
+	StartedAt   time.Time `json:"started_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	CompletedAt time.Time `json:"completed_at"`
 }
 
 // PercentComplete calculates completion percentage.
@@ -0,0 +1,269 @@
+package scanner
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// legacyResultJSON is a RepoSecurityResult as written before Checks existed,
+// e.g. in an old workflow history or an offloaded results blob.
+const legacyResultJSON = `{
+	"repository": "payments-api",
+	"secret_scanning": "enabled",
+	"dependabot_alerts": "enabled",
+	"code_scanning": "not configured",
+	"has_codeowners": true,
+	"has_security_policy": null,
+	"actions": {"actions_enabled": true, "allowed_actions": "all", "default_workflow_permissions": "read"},
+	"scanned_at": "2026-01-02T03:04:05Z"
+}`
+
+func TestRepoSecurityResultLegacyJSON(t *testing.T) {
+	var r RepoSecurityResult
+	require.NoError(t, json.Unmarshal([]byte(legacyResultJSON), &r))
+	require.Nil(t, r.Checks)
+
+	require.Equal(t, StatusEnabled, r.Check(CheckSecretScanning).Status)
+	require.Equal(t, StatusEnabled, r.Check(CheckDependabot).Status)
+	require.Equal(t, StatusNotConfigured, r.Check(CheckCodeScanning).Status)
+	require.Equal(t, StatusEnabled, r.Check(ResultCodeowners).Status)
+	require.Equal(t, StatusUnknown, r.Check(ResultSecurityPolicy).Status)
+	require.Equal(t, 0, r.Check(CheckActions).Details["restricted"])
+	require.Equal(t, StatusEnabled, r.Check(ResultReadOnlyWorkflowToken).Status)
+	require.Equal(t, StatusUnknown, r.Check(CheckAccessAudit).Status)
+	require.False(t, r.IsFullyCompliant())
+
+	policy := CompliancePolicy{RequireSecretScanning: true, RequireCodeowners: true, RequireReadOnlyWorkflowToken: true}
+	require.True(t, policy.IsCompliant(&r))
+}
+
+func TestRepoSecurityResultJSONRoundTrip(t *testing.T) {
+	var r RepoSecurityResult
+	r.Repository = "payments-api"
+	r.setCheck(CheckSecretScanning, CheckResult{Status: StatusEnabled})
+	r.setCheck(CheckDependabot, CheckResult{Status: StatusDisabled})
+	r.setCheck(CheckCodeScanning, CheckResult{Status: StatusNoAccess})
+	r.setCheck(ResultCodeowners, CheckResult{Status: StatusDisabled})
+	r.setActions(&ActionsSecurity{ActionsEnabled: true, AllowedActions: AllowedActionsSelected, DefaultWorkflowPermissions: WorkflowPermissionsWrite})
+	r.setAccess(&AccessAudit{DeployKeys: 2, NoAccess: []string{AccessCollaborators}})
+
+	// The named fields stay populated for consumers of the original shape.
+	b, err := json.Marshal(r)
+	require.NoError(t, err)
+	var legacy map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &legacy))
+	require.Equal(t, "enabled", legacy["secret_scanning"])
+	require.Equal(t, "disabled", legacy["dependabot_alerts"])
+	require.Equal(t, "no access", legacy["code_scanning"])
+	require.Equal(t, false, legacy["has_codeowners"])
+	require.Nil(t, legacy["has_security_policy"])
+
+	var got RepoSecurityResult
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.Equal(t, r, got)
+	require.Equal(t, CheckResult{
+		Status:  StatusEnabled,
+		Details: map[string]int{"restricted": 1},
+		Message: "allowed_actions: selected",
+	}, got.Check(CheckActions))
+	require.Equal(t, StatusDisabled, got.Check(ResultReadOnlyWorkflowToken).Status)
+	require.Equal(t, CheckResult{
+		Status:  StatusNoAccess,
+		Details: map[string]int{"deploy_keys": 2, "flagged_deploy_keys": 0, "outside_collaborators": 0},
+		Message: "cannot list collaborators",
+	}, got.Check(CheckAccessAudit))
+}
+
+func TestGenerateReportFromLegacyResults(t *testing.T) {
+	var legacy RepoSecurityResult
+	require.NoError(t, json.Unmarshal([]byte(legacyResultJSON), &legacy))
+	var current RepoSecurityResult
+	current.Repository = "billing"
+	current.setCheck(CheckSecretScanning, CheckResult{Status: StatusEnabled})
+	current.setCheck(CheckDependabot, CheckResult{Status: StatusEnabled})
+	current.setCheck(CheckCodeScanning, CheckResult{Status: StatusEnabled})
+
+	env := newActivityEnv(&Activities{})
+	val, err := env.ExecuteActivity("GenerateReport", "acme", []RepoSecurityResult{legacy, current},
+		[]BlobRef(nil), DefaultCompliancePolicy(), []string(nil), []Suppression(nil))
+	require.NoError(t, err)
+
+	var report map[string]interface{}
+	require.NoError(t, val.Get(&report))
+	require.EqualValues(t, 2, report["secret_scanning_enabled"])
+	require.EqualValues(t, 2, report["dependabot_enabled"])
+	require.EqualValues(t, 1, report["code_scanning_enabled"])
+	require.EqualValues(t, 1, report["fully_compliant"])
+	require.Equal(t, []interface{}{"payments-api"}, report["non_compliant_repos"])
+}
+
+func TestCompliancePolicyOutcome(t *testing.T) {
+	result := func(code, dependabot SecurityStatus) *RepoSecurityResult {
+		var r RepoSecurityResult
+		r.setCheck(CheckSecretScanning, CheckResult{Status: StatusEnabled})
+		r.setCheck(CheckDependabot, CheckResult{Status: dependabot})
+		r.setCheck(CheckCodeScanning, CheckResult{Status: code})
+		return &r
+	}
+	policy := DefaultCompliancePolicy()
+	require.Equal(t, OutcomeCompliant, policy.Outcome(result(StatusEnabled, StatusEnabled)))
+	require.Equal(t, OutcomeNonCompliant, policy.Outcome(result(StatusNotConfigured, StatusEnabled)))
+	require.Equal(t, OutcomeIndeterminate, policy.Outcome(result(StatusNoAccess, StatusEnabled)))
+	require.Equal(t, OutcomeIndeterminate, policy.Outcome(result(StatusNoAccess, StatusUnknown)))
+	require.Equal(t, OutcomeNonCompliant, policy.Outcome(result(StatusNoAccess, StatusDisabled)),
+		"a determinate failure decides")
+
+	policy.IndeterminateFails = true
+	require.Equal(t, OutcomeNonCompliant, policy.Outcome(result(StatusNoAccess, StatusEnabled)))
+}
+
+func TestGenerateReportIndeterminate(t *testing.T) {
+	repo := func(name string, code SecurityStatus) RepoSecurityResult {
+		r := RepoSecurityResult{Repository: name}
+		r.setCheck(CheckSecretScanning, CheckResult{Status: StatusEnabled})
+		r.setCheck(CheckDependabot, CheckResult{Status: StatusEnabled})
+		r.setCheck(CheckCodeScanning, CheckResult{Status: code})
+		return r
+	}
+	results := []RepoSecurityResult{
+		repo("api", StatusEnabled),
+		repo("web", StatusNotConfigured),
+		repo("billing", StatusNoAccess),
+		repo("docs", StatusUnknown),
+	}
+	generate := func(policy CompliancePolicy) map[string]interface{} {
+		env := newActivityEnv(&Activities{})
+		val, err := env.ExecuteActivity("GenerateReport", "acme", results,
+			[]BlobRef(nil), policy, []string(nil), []Suppression(nil))
+		require.NoError(t, err)
+		var report map[string]interface{}
+		require.NoError(t, val.Get(&report))
+		return report
+	}
+
+	report := generate(DefaultCompliancePolicy())
+	require.EqualValues(t, 1, report["fully_compliant"])
+	require.Equal(t, []interface{}{"web"}, report["non_compliant_repos"])
+	require.EqualValues(t, 2, report["indeterminate"])
+	require.Equal(t, []interface{}{"billing", "docs"}, report["indeterminate_repos"])
+	require.Equal(t, "50.0%", report["compliance_rate"], "over the determinate repos only")
+
+	strict := DefaultCompliancePolicy()
+	strict.IndeterminateFails = true
+	report = generate(strict)
+	require.Equal(t, []interface{}{"web", "billing", "docs"}, report["non_compliant_repos"])
+	require.NotContains(t, report, "indeterminate")
+	require.Equal(t, "25.0%", report["compliance_rate"])
+}
+
+// scanStatusConsts parses the ScanStatus constants out of models.go, name
+// to value.
+func scanStatusConsts(t *testing.T) map[string]ScanStatus {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "models.go", nil, 0)
+	require.NoError(t, err)
+	consts := make(map[string]ScanStatus)
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if typ, ok := vs.Type.(*ast.Ident); !ok || typ.Name != "ScanStatus" {
+				continue
+			}
+			for i, name := range vs.Names {
+				v, err := strconv.Unquote(vs.Values[i].(*ast.BasicLit).Value)
+				require.NoError(t, err)
+				consts[name.Name] = ScanStatus(v)
+			}
+		}
+	}
+	return consts
+}
+
+// TestScanStatusJSONIsStable pins the values queries and the ScanStatus
+// search attribute have carried; changing one breaks their readers.
+func TestScanStatusJSONIsStable(t *testing.T) {
+	want := map[string]ScanStatus{
+		"ScanStarting": "starting", "ScanFetchingRepos": "fetching_repos", "ScanScanning": "scanning",
+		"ScanPaused": "sleeping", "ScanHeld": "held", "ScanRetryingFailures": "retrying_failures", "ScanCancelled": "cancelled",
+		"ScanBudgetExceeded": "budget_exceeded", "ScanDeadlineReached": "deadline_reached",
+		"ScanCompleted": "completed", "ScanEmpty": "empty", "ScanDegraded": "degraded",
+	}
+	consts := scanStatusConsts(t)
+	require.Equal(t, want, consts)
+	for name, s := range consts {
+		require.True(t, s.Valid(), "%s is missing from ScanStatus.Valid", name)
+	}
+	require.False(t, ScanStatus("sleepin").Valid())
+
+	b, err := json.Marshal(ScanProgress{Status: ScanPaused})
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"status":"sleeping"`)
+}
+
+func TestScanStatusIsTerminal(t *testing.T) {
+	for _, s := range []ScanStatus{ScanCancelled, ScanBudgetExceeded, ScanCompleted, ScanEmpty, ScanDegraded} {
+		require.True(t, s.IsTerminal(), s)
+	}
+	for _, s := range []ScanStatus{ScanStarting, ScanFetchingRepos, ScanScanning, ScanPaused, ScanHeld, ScanRetryingFailures, "paused_by_operator"} {
+		require.False(t, s.IsTerminal(), s)
+	}
+}
+
+// TestScanStatusAssignmentsUseConstants checks that every ScanProgress
+// status the package sets is a ScanStatus constant, not a string literal
+// or a computed value.
+func TestScanStatusAssignmentsUseConstants(t *testing.T) {
+	consts := scanStatusConsts(t)
+	files, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+	checked := 0
+	check := func(fset *token.FileSet, expr ast.Expr) {
+		ident, ok := expr.(*ast.Ident)
+		require.True(t, ok && consts[ident.Name] != "", "%s: ScanProgress.Status set to something other than a ScanStatus constant", fset.Position(expr.Pos()))
+		checked++
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		require.NoError(t, err)
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				for i, lhs := range n.Lhs {
+					sel, ok := lhs.(*ast.SelectorExpr)
+					if !ok || sel.Sel.Name != "Status" {
+						continue
+					}
+					if x, ok := sel.X.(*ast.Ident); ok && x.Name == "progress" {
+						check(fset, n.Rhs[i])
+					}
+				}
+			case *ast.CompositeLit:
+				if typ, ok := n.Type.(*ast.Ident); ok && typ.Name == "ScanProgress" {
+					for _, elt := range n.Elts {
+						if kv, ok := elt.(*ast.KeyValueExpr); ok && kv.Key.(*ast.Ident).Name == "Status" {
+							check(fset, kv.Value)
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	require.GreaterOrEqual(t, checked, 9, "the workflow's status assignments were not found")
+}
@@ -0,0 +1,291 @@
+package scanner
+
+// =============================================================================
+// OCSF export — findings in the SIEM's schema
+// =============================================================================
+//
+// The SIEM ingests security findings as OCSF (https://schema.ocsf.io).
+// OCSFFindings turns a report into one Compliance Finding event (class 2003)
+// per failed check per repo, plus one per suppressed finding with status
+// Suppressed, so accepted risks stay visible there too. FormatReport with
+// FormatOCSF writes them as NDJSON, one event per line, ready for direct
+// ingestion by the SIEM or anything that ships reports to it.
+//
+// finding_info.uid is the same for a repo's check in every scan, so the
+// SIEM can group a finding's events across weeks; metadata.correlation_uid
+// is the scan's run ID. Reports with inline evidence (ScanInput.
+// IncludeEvidence) add each failed check's to unmapped.evidence.
+// =============================================================================
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// OCSFVersion is the OCSF schema version the events follow.
+const OCSFVersion = "1.1.0"
+
+// OCSF enum values used by the Compliance Finding events.
+const (
+	ocsfCategoryFindings         = 2
+	ocsfClassComplianceFinding   = 2003
+	ocsfActivityCreate           = 1
+	ocsfStatusNew                = 1
+	ocsfStatusSuppressed         = 3
+	ocsfComplianceStatusFail     = 3
+	ocsfSeverityUnknown          = 0
+	ocsfSeverityLow              = 2
+	ocsfSeverityMedium           = 3
+	ocsfSeverityHigh             = 4
+	ocsfComplianceFindingTypeUID = ocsfClassComplianceFinding*100 + ocsfActivityCreate
+)
+
+var ocsfSeverityNames = map[int]string{
+	ocsfSeverityUnknown: "Unknown",
+	ocsfSeverityLow:     "Low",
+	ocsfSeverityMedium:  "Medium",
+	ocsfSeverityHigh:    "High",
+}
+
+// ocsfCheckSeverity is how bad a failed check is. Leaked secrets are the
+// most direct risk; missing files are hygiene.
+var ocsfCheckSeverity = map[string]int{
+	CheckSecretScanning:         ocsfSeverityHigh,
+	CheckDependabot:             ocsfSeverityMedium,
+	CheckCodeScanning:           ocsfSeverityMedium,
+	CheckActions:                ocsfSeverityMedium,
+	ResultReadOnlyWorkflowToken: ocsfSeverityMedium,
+	ResultCodeowners:            ocsfSeverityLow,
+	ResultSecurityPolicy:        ocsfSeverityLow,
+	CheckSecurityUpdates:        ocsfSeverityMedium,
+	CheckDependabotConfig:       ocsfSeverityLow,
+	CheckWebhooks:               ocsfSeverityMedium,
+}
+
+// OCSFFinding is an OCSF Compliance Finding event.
+type OCSFFinding struct {
+	ActivityID   int    `json:"activity_id"`
+	ActivityName string `json:"activity_name"`
+	CategoryUID  int    `json:"category_uid"`
+	CategoryName string `json:"category_name"`
+	ClassUID     int    `json:"class_uid"`
+	ClassName    string `json:"class_name"`
+	TypeUID      int    `json:"type_uid"`
+	TypeName     string `json:"type_name"`
+	SeverityID   int    `json:"severity_id"`
+	Severity     string `json:"severity"`
+	StatusID     int    `json:"status_id"`
+	Status       string `json:"status"`
+	// Time is when the scan completed, in milliseconds since the epoch.
+	Time    int64  `json:"time"`
+	Message string `json:"message"`
+
+	Metadata    OCSFMetadata      `json:"metadata"`
+	FindingInfo OCSFFindingInfo   `json:"finding_info"`
+	Compliance  OCSFCompliance    `json:"compliance"`
+	Resources   []OCSFResource    `json:"resources"`
+	Unmapped    map[string]string `json:"unmapped,omitempty"`
+}
+
+// OCSFMetadata is an event's metadata object.
+type OCSFMetadata struct {
+	Version        string      `json:"version"`
+	Product        OCSFProduct `json:"product"`
+	CorrelationUID string      `json:"correlation_uid,omitempty"`
+}
+
+// OCSFProduct names the scanner.
+type OCSFProduct struct {
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name"`
+	Version    string `json:"version,omitempty"`
+}
+
+// OCSFFindingInfo identifies the finding.
+type OCSFFindingInfo struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+	Desc  string `json:"desc,omitempty"`
+}
+
+// OCSFCompliance is the failed control.
+type OCSFCompliance struct {
+	Control   string   `json:"control"`
+	Standards []string `json:"standards"`
+	Status    string   `json:"status"`
+	StatusID  int      `json:"status_id"`
+}
+
+// OCSFResource is the repo a finding is about.
+type OCSFResource struct {
+	UID   string    `json:"uid"`
+	Name  string    `json:"name"`
+	Type  string    `json:"type"`
+	Group OCSFGroup `json:"group"`
+}
+
+// OCSFGroup is the org or group that owns the repo.
+type OCSFGroup struct {
+	Name string `json:"name"`
+}
+
+// OCSFFindings maps every failed and suppressed check in r to an event,
+// ordered by repo and check. Reports saved before repo_failures existed
+// give one "compliance" finding per non-compliant repo.
+func OCSFFindings(r Report) []OCSFFinding {
+	var events []OCSFFinding
+	for repo, checks := range r.failures(r.RepoFailures == nil) {
+		for _, check := range checks {
+			events = append(events, ocsfFinding(r, repo, check, nil))
+		}
+	}
+	for i := range r.Suppressed {
+		s := &r.Suppressed[i]
+		events = append(events, ocsfFinding(r, s.Repository, s.Check, s))
+	}
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if a.Resources[0].Name != b.Resources[0].Name {
+			return a.Resources[0].Name < b.Resources[0].Name
+		}
+		return a.Compliance.Control < b.Compliance.Control
+	})
+	return events
+}
+
+// ocsfFinding is the field mapping: one failed check of one repo, excused
+// by suppressed when it is non-nil.
+func ocsfFinding(r Report, repo, check string, suppressed *SuppressedFinding) OCSFFinding {
+	// Repos are named relative to the org, GitLab subgroups included.
+	fullName := r.Org + "/" + repo
+	severity, ok := ocsfCheckSeverity[check]
+	if !ok {
+		severity = ocsfSeverityUnknown
+	}
+	provider := r.Provider
+	if provider == "" {
+		provider = ProviderGitHub
+	}
+	version := r.WorkerVersion
+	if version == "" {
+		version = Version
+	}
+	var millis int64
+	if t, err := time.Parse(time.RFC3339, r.CompletedAt); err == nil {
+		millis = t.UnixMilli()
+	}
+
+	title := fmt.Sprintf("%s fails %s", fullName, check)
+	e := OCSFFinding{
+		ActivityID:   ocsfActivityCreate,
+		ActivityName: "Create",
+		CategoryUID:  ocsfCategoryFindings,
+		CategoryName: "Findings",
+		ClassUID:     ocsfClassComplianceFinding,
+		ClassName:    "Compliance Finding",
+		TypeUID:      ocsfComplianceFindingTypeUID,
+		TypeName:     "Compliance Finding: Create",
+		SeverityID:   severity,
+		Severity:     ocsfSeverityNames[severity],
+		StatusID:     ocsfStatusNew,
+		Status:       "New",
+		Time:         millis,
+		Message:      title,
+		Metadata: OCSFMetadata{
+			Version:        OCSFVersion,
+			Product:        OCSFProduct{Name: "temporal-security-scanner", VendorName: "salkimmich", Version: version},
+			CorrelationUID: r.RunID,
+		},
+		FindingInfo: OCSFFindingInfo{
+			UID:   provider + ":" + fullName + ":" + check,
+			Title: title,
+		},
+		Compliance: OCSFCompliance{
+			Control:   check,
+			Standards: []string{"temporal-security-scanner"},
+			Status:    "Fail",
+			StatusID:  ocsfComplianceStatusFail,
+		},
+		Resources: []OCSFResource{{
+			UID:   fullName,
+			Name:  fullName,
+			Type:  "Repository",
+			Group: OCSFGroup{Name: r.Org},
+		}},
+		Unmapped: map[string]string{"provider": provider},
+	}
+	if r.WorkflowID != "" {
+		e.Unmapped["workflow_id"] = r.WorkflowID
+	}
+	// The check's evidence, if the report has it inline, as a JSON string:
+	// unmapped values are strings.
+	if evidence := r.Evidence[repo][check]; len(evidence) > 0 {
+		if b, err := json.Marshal(evidence); err == nil {
+			e.Unmapped["evidence"] = string(b)
+		}
+	}
+	if suppressed != nil {
+		e.StatusID = ocsfStatusSuppressed
+		e.Status = "Suppressed"
+		e.FindingInfo.Desc = "Suppressed: " + suppressed.Justification
+		if suppressed.Expires != "" {
+			e.FindingInfo.Desc += " (until " + suppressed.Expires + ")"
+		}
+	}
+	return e
+}
+
+// ReportFormat is an output format of FormatReport.
+type ReportFormat string
+
+// Report formats.
+const (
+	FormatText ReportFormat = "text"
+	FormatJSON ReportFormat = "json"
+	FormatOCSF ReportFormat = "ocsf"
+	FormatProm ReportFormat = "prom"
+)
+
+// ParseReportFormat checks that s names a ReportFormat.
+func ParseReportFormat(s string) (ReportFormat, error) {
+	switch f := ReportFormat(s); f {
+	case FormatText, FormatJSON, FormatOCSF, FormatProm:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown report format %q: want text, json, ocsf or prom", s)
+}
+
+// FormatReport writes r in format: text as RenderReport prints it without
+// color, indented JSON, OCSF findings as NDJSON, or Prometheus gauges (see
+// prometheus.go).
+func FormatReport(r Report, format ReportFormat) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case FormatText:
+		RenderReport(&buf, r, RenderOptions{})
+	case FormatJSON:
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	case FormatOCSF:
+		enc := json.NewEncoder(&buf)
+		for _, e := range OCSFFindings(r) {
+			if err := enc.Encode(e); err != nil {
+				return nil, err
+			}
+		}
+	case FormatProm:
+		if err := WritePrometheus(&buf, r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+	return buf.Bytes(), nil
+}
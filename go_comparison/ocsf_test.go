@@ -0,0 +1,142 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ocsfFixture is a four-repo scan: two repos fail checks of each severity,
+// one passes, and one has its code scanning finding suppressed.
+func ocsfFixture() Report {
+	return Report{
+		SchemaVersion: ReportSchemaVersion,
+		Org:           "acme", TotalRepos: 4, FullyCompliant: 2, ComplianceRate: "50.0%",
+		NonCompliantRepos: []string{"web", "api"},
+		RepoFailures: map[string][]string{
+			"api":    {CheckSecretScanning, ResultCodeowners},
+			"web":    {CheckDependabot},
+			"docs":   {},
+			"mirror": {},
+		},
+		Suppressed: []SuppressedFinding{
+			{Repository: "mirror", Check: CheckCodeScanning, Justification: "read-only mirror", Expires: "2026-12-31"},
+		},
+		WorkflowID:    "security-scan-acme",
+		RunID:         "run-1",
+		StartedAt:     "2026-03-02T14:00:00Z",
+		CompletedAt:   "2026-03-02T14:03:20Z",
+		WorkerVersion: "v1.4.0",
+	}
+}
+
+func TestOCSFGolden(t *testing.T) {
+	got, err := FormatReport(ocsfFixture(), FormatOCSF)
+	require.NoError(t, err)
+	path := filepath.Join("testdata", "ocsf", "report.ndjson")
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got))
+
+	// NDJSON: every line is one complete event.
+	lines := 0
+	sc := bufio.NewScanner(bytes.NewReader(got))
+	for sc.Scan() {
+		var e map[string]interface{}
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &e))
+		lines++
+	}
+	require.Equal(t, 4, lines)
+}
+
+func TestOCSFFinding(t *testing.T) {
+	events := OCSFFindings(ocsfFixture())
+	require.Len(t, events, 4)
+
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Resources[0].Name+" "+e.Compliance.Control)
+	}
+	require.Equal(t, []string{
+		"acme/api codeowners", "acme/api secret_scanning", "acme/mirror code_scanning", "acme/web dependabot",
+	}, names, "ordered by repo, then check")
+
+	secret := events[1]
+	require.Equal(t, 2003, secret.ClassUID)
+	require.Equal(t, 200301, secret.TypeUID)
+	require.Equal(t, 4, secret.SeverityID)
+	require.Equal(t, "High", secret.Severity)
+	require.Equal(t, "New", secret.Status)
+	require.Equal(t, int64(1772460200000), secret.Time)
+	require.Equal(t, "run-1", secret.Metadata.CorrelationUID)
+	require.Equal(t, "v1.4.0", secret.Metadata.Product.Version)
+	require.Equal(t, "github:acme/api:secret_scanning", secret.FindingInfo.UID)
+	require.Equal(t, OCSFResource{UID: "acme/api", Name: "acme/api", Type: "Repository", Group: OCSFGroup{Name: "acme"}}, secret.Resources[0])
+	require.Equal(t, "security-scan-acme", secret.Unmapped["workflow_id"])
+
+	require.Equal(t, "Low", events[0].Severity)
+
+	suppressed := events[2]
+	require.Equal(t, 3, suppressed.StatusID)
+	require.Equal(t, "Suppressed", suppressed.Status)
+	require.Equal(t, "Suppressed: read-only mirror (until 2026-12-31)", suppressed.FindingInfo.Desc)
+	require.Equal(t, "Fail", suppressed.Compliance.Status)
+}
+
+func TestOCSFFindingsLegacyAndGitLab(t *testing.T) {
+	// Reports saved before repo_failures existed only name the repos.
+	legacy := Report{Org: "acme", TotalRepos: 2, NonCompliantRepos: []string{"api"}}
+	events := OCSFFindings(legacy)
+	require.Len(t, events, 1)
+	require.Equal(t, legacyCheck, events[0].Compliance.Control)
+	require.Equal(t, "Unknown", events[0].Severity)
+	require.Zero(t, events[0].Time, "no completed_at")
+
+	// GitLab repos in subgroups are named relative to the group.
+	gitlab := Report{Org: "acme/platform", Provider: ProviderGitLab, TotalRepos: 1,
+		NonCompliantRepos: []string{"infra/terraform"},
+		RepoFailures:      map[string][]string{"infra/terraform": {CheckDependabot}}}
+	events = OCSFFindings(gitlab)
+	require.Equal(t, "acme/platform/infra/terraform", events[0].Resources[0].Name)
+	require.Equal(t, "acme/platform", events[0].Resources[0].Group.Name)
+	require.Equal(t, "gitlab:acme/platform/infra/terraform:dependabot", events[0].FindingInfo.UID)
+
+	require.Empty(t, OCSFFindings(Report{Org: "acme", TotalRepos: 3, FullyCompliant: 3, RepoFailures: map[string][]string{}}))
+}
+
+func TestFormatReport(t *testing.T) {
+	r := ocsfFixture()
+
+	text, err := FormatReport(r, FormatText)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Equal(t, buf.String(), string(text))
+
+	js, err := FormatReport(r, FormatJSON)
+	require.NoError(t, err)
+	parsed, err := ParseReport(js)
+	require.NoError(t, err)
+	require.Equal(t, r, parsed)
+
+	_, err = FormatReport(r, "xml")
+	require.Error(t, err)
+
+	for _, s := range []string{"text", "json", "ocsf", "prom"} {
+		f, err := ParseReportFormat(s)
+		require.NoError(t, err)
+		require.Equal(t, s, string(f))
+	}
+	_, err = ParseReportFormat("OCSF ")
+	require.True(t, strings.Contains(err.Error(), "want text, json, ocsf or prom"))
+}
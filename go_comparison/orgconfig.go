@@ -0,0 +1,339 @@
+package scanner
+
+// =============================================================================
+// Org config registry — per-organization scan defaults on the worker
+// =============================================================================
+//
+// Teams that scan many orgs repeat the same policy, checks and sinks in
+// every ScanInput. An OrgConfigRegistry holds them once per org on the
+// worker, in a YAML (or JSON) file mapping org names to OrgScanDefaults:
+//
+//	acme:
+//	  checks: [secret_scanning, dependabot, files]
+//	  compliance_policy: {require_secret_scanning: true, require_codeowners: true}
+//	  concurrency: 20
+//	  sinks: [{type: file, options: {dir: /var/lib/scanner/reports}}]
+//
+// or in a directory of such files. Org names are case-insensitive, and an
+// org may appear in only one file.
+//
+// ResolveScanConfig, a local activity at the start of the workflow, fills
+// each field the input leaves empty from its org's defaults: the explicit
+// input always wins. A default that would conflict with the input is not
+// applied — a policy requiring a check the input does not select, or a
+// concurrency for an input that batches — so defaults never make a valid
+// input invalid. Orgs without an entry, and workers without a registry,
+// get the package defaults. The report's scan_config section records what
+// was resolved and where it came from.
+//
+// The worker reloads the registry on SIGHUP. A reload that fails keeps the
+// previous entries, so a typo does not drop every org to package defaults.
+// =============================================================================
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScanConfigPackageDefaults is ScanConfig.Source for an org with no entry.
+const ScanConfigPackageDefaults = "package defaults"
+
+// OrgScanDefaults are the ScanInput fields an org's entry may set. They
+// share ScanInput's names.
+type OrgScanDefaults struct {
+	CompliancePolicy   *CompliancePolicy `json:"compliance_policy,omitempty"`
+	Checks             []string          `json:"checks,omitempty"`
+	ActiveWithinDays   int               `json:"active_within_days,omitempty"`
+	PriorityRepos      []string          `json:"priority_repos,omitempty"`
+	PriorityTopics     []string          `json:"priority_topics,omitempty"`
+	SuppressionsSource string            `json:"suppressions_source,omitempty"`
+	Concurrency        int               `json:"concurrency,omitempty"`
+	BatchDelay         *BatchDelay       `json:"batch_delay,omitempty"`
+	Sinks              []ReportSinkSpec  `json:"sinks,omitempty"`
+}
+
+// validate checks the defaults as a ScanInput holding only them would be.
+func (d OrgScanDefaults) validate() error {
+	in := d.apply(ScanInput{})
+	if err := ValidateChecks(in.Checks); err != nil {
+		return err
+	}
+	if in.CompliancePolicy != nil {
+		checks := in.checks()
+		for _, c := range in.CompliancePolicy.requiredChecks() {
+			if !checks[c] {
+				return fmt.Errorf("compliance policy requires check %q, which is not selected", c)
+			}
+		}
+		if in.CompliancePolicy.Scoring != nil {
+			if err := in.CompliancePolicy.Scoring.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	if in.ActiveWithinDays < 0 {
+		return fmt.Errorf("active_within_days must not be negative, got %d", in.ActiveWithinDays)
+	}
+	if err := in.validatePriority(); err != nil {
+		return err
+	}
+	if in.BatchDelay != nil {
+		if err := in.BatchDelay.validate(); err != nil {
+			return err
+		}
+	}
+	if err := in.validateConcurrency(); err != nil {
+		return err
+	}
+	return ValidateReportSinks(in.Sinks)
+}
+
+// apply copies the defaults into in, ignoring what in already sets.
+func (d OrgScanDefaults) apply(in ScanInput) ScanInput {
+	in.CompliancePolicy = d.CompliancePolicy
+	in.Checks = d.Checks
+	in.ActiveWithinDays = d.ActiveWithinDays
+	in.PriorityRepos = d.PriorityRepos
+	in.PriorityTopics = d.PriorityTopics
+	in.SuppressionsSource = d.SuppressionsSource
+	in.Concurrency = d.Concurrency
+	in.BatchDelay = d.BatchDelay
+	in.Sinks = d.Sinks
+	return in
+}
+
+// scanDefaultsOf is the part of in that OrgScanDefaults covers.
+func scanDefaultsOf(in ScanInput) OrgScanDefaults {
+	return OrgScanDefaults{
+		CompliancePolicy:   in.CompliancePolicy,
+		Checks:             in.Checks,
+		ActiveWithinDays:   in.ActiveWithinDays,
+		PriorityRepos:      in.PriorityRepos,
+		PriorityTopics:     in.PriorityTopics,
+		SuppressionsSource: in.SuppressionsSource,
+		Concurrency:        in.Concurrency,
+		BatchDelay:         in.BatchDelay,
+		Sinks:              in.Sinks,
+	}
+}
+
+// mergeScanDefaults fills the fields in leaves empty from d, skipping
+// defaults that would conflict with in. It returns the merged input and
+// the JSON names of the fields taken from d, in OrgScanDefaults order.
+func mergeScanDefaults(in ScanInput, d OrgScanDefaults) (ScanInput, []string) {
+	var applied []string
+	if len(in.Checks) == 0 && !in.IncludeAccessAudit && len(d.Checks) > 0 {
+		in.Checks = d.Checks
+		applied = append(applied, "checks")
+	}
+	if in.CompliancePolicy == nil && d.CompliancePolicy != nil {
+		checks, fits := in.checks(), true
+		for _, c := range d.CompliancePolicy.requiredChecks() {
+			fits = fits && checks[c]
+		}
+		if fits {
+			in.CompliancePolicy = d.CompliancePolicy
+			applied = append(applied, "compliance_policy")
+		}
+	}
+	if in.ActiveWithinDays == 0 && d.ActiveWithinDays > 0 {
+		in.ActiveWithinDays = d.ActiveWithinDays
+		applied = append(applied, "active_within_days")
+	}
+	if len(in.PriorityRepos) == 0 && len(d.PriorityRepos) > 0 {
+		in.PriorityRepos = d.PriorityRepos
+		applied = append(applied, "priority_repos")
+	}
+	if len(in.PriorityTopics) == 0 && len(d.PriorityTopics) > 0 {
+		in.PriorityTopics = d.PriorityTopics
+		applied = append(applied, "priority_topics")
+	}
+	if len(in.Suppressions) == 0 && in.SuppressionsSource == "" && d.SuppressionsSource != "" {
+		in.SuppressionsSource = d.SuppressionsSource
+		applied = append(applied, "suppressions_source")
+	}
+	// Concurrency and batching are alternatives: a default of either
+	// applies only when the input has chosen neither.
+	batching := in.ChildPerBatch || in.ActivityBatching || in.BatchDelay != nil || in.StragglerTimeout != nil
+	if in.Concurrency == 0 && !batching && d.Concurrency > 0 {
+		in.Concurrency = d.Concurrency
+		applied = append(applied, "concurrency")
+	}
+	if in.BatchDelay == nil && in.Concurrency == 0 && d.BatchDelay != nil {
+		in.BatchDelay = d.BatchDelay
+		applied = append(applied, "batch_delay")
+	}
+	if len(in.Sinks) == 0 && len(d.Sinks) > 0 {
+		in.Sinks = d.Sinks
+		applied = append(applied, "sinks")
+	}
+	return in, applied
+}
+
+// ParseOrgScanDefaults reads a YAML (or JSON) mapping of org names to
+// their defaults and validates each entry. Unknown fields are errors.
+func ParseOrgScanDefaults(data []byte) (map[string]OrgScanDefaults, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing org config: %w", err)
+	}
+	orgs := make(map[string]OrgScanDefaults, len(doc))
+	for org, v := range doc {
+		key := strings.ToLower(strings.TrimSpace(org))
+		if key == "" {
+			return nil, fmt.Errorf("org config has an entry with no org name")
+		}
+		if _, dup := orgs[key]; dup {
+			return nil, fmt.Errorf("org %q is configured twice", org)
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("org %q: %w", org, err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		var d OrgScanDefaults
+		if v != nil {
+			if err := dec.Decode(&d); err != nil {
+				return nil, fmt.Errorf("org %q: %w", org, err)
+			}
+		}
+		if err := d.validate(); err != nil {
+			return nil, fmt.Errorf("org %q: %w", org, err)
+		}
+		orgs[key] = d
+	}
+	return orgs, nil
+}
+
+// orgConfigEntry is an org's defaults and the file they came from.
+type orgConfigEntry struct {
+	defaults OrgScanDefaults
+	source   string
+}
+
+// OrgConfigRegistry holds the org defaults read from Path, a file or a
+// directory of *.yaml, *.yml and *.json files. It is safe for concurrent
+// use.
+type OrgConfigRegistry struct {
+	Path string
+
+	mu      sync.RWMutex
+	entries map[string]orgConfigEntry
+}
+
+// LoadOrgConfigRegistry reads the registry at path.
+func LoadOrgConfigRegistry(path string) (*OrgConfigRegistry, error) {
+	r := &OrgConfigRegistry{Path: path}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads Path again. On error the registry keeps its entries.
+func (r *OrgConfigRegistry) Reload() error {
+	files := []string{r.Path}
+	info, err := os.Stat(r.Path)
+	if err != nil {
+		return fmt.Errorf("reading org config: %w", err)
+	}
+	if info.IsDir() {
+		files = nil
+		for _, pattern := range []string{"*.yaml", "*.yml", "*.json"} {
+			matches, _ := filepath.Glob(filepath.Join(r.Path, pattern))
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+	}
+
+	entries := map[string]orgConfigEntry{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("reading org config: %w", err)
+		}
+		orgs, err := ParseOrgScanDefaults(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for org, d := range orgs {
+			if prev, dup := entries[org]; dup {
+				return fmt.Errorf("org %q is configured in both %s and %s", org, prev.source, file)
+			}
+			entries[org] = orgConfigEntry{defaults: d, source: file}
+		}
+	}
+
+	r.mu.Lock()
+	r.entries = entries
+	r.mu.Unlock()
+	return nil
+}
+
+// Orgs returns the configured org names, sorted.
+func (r *OrgConfigRegistry) Orgs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	orgs := make([]string, 0, len(r.entries))
+	for org := range r.entries {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+	return orgs
+}
+
+// Lookup returns org's defaults and the file they came from, or false
+// when org has no entry.
+func (r *OrgConfigRegistry) Lookup(org string) (OrgScanDefaults, string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.entries[strings.ToLower(org)]
+	return e.defaults, e.source, ok
+}
+
+// ScanConfig is the report's scan_config section: the settings the scan
+// resolved and where their defaults came from.
+type ScanConfig struct {
+	// Source is the registry file of the org's entry, or
+	// ScanConfigPackageDefaults.
+	Source string `json:"source"`
+	// FromRegistry are the fields the registry supplied; the others are
+	// the input's own or the package defaults.
+	FromRegistry []string        `json:"from_registry,omitempty"`
+	Resolved     OrgScanDefaults `json:"resolved"`
+}
+
+// ResolvedScanInput is ResolveScanConfig's result.
+type ResolvedScanInput struct {
+	Input  ScanInput  `json:"input"`
+	Config ScanConfig `json:"config"`
+	// AuditLog reports whether the worker keeps an audit log, so the
+	// workflow schedules AppendAuditLog only when it does (see audit.go).
+	AuditLog bool `json:"audit_log,omitempty"`
+}
+
+// ResolveScanConfig merges input's org defaults under it. The result's
+// input has no token or credential: the workflow keeps its own, so the
+// token is not recorded in the activity's result.
+func (a *Activities) ResolveScanConfig(_ context.Context, input ScanInput) (ResolvedScanInput, error) {
+	input.Token, input.Credential = nil, nil
+	config := ScanConfig{Source: ScanConfigPackageDefaults}
+	if a.OrgConfigs != nil {
+		if d, source, ok := a.OrgConfigs.Lookup(input.Org); ok {
+			config.Source = source
+			input, config.FromRegistry = mergeScanDefaults(input, d)
+		}
+	}
+	config.Resolved = scanDefaultsOf(input)
+	return ResolvedScanInput{Input: input, Config: config, AuditLog: a.AuditLog != nil}, nil
+}
@@ -0,0 +1,251 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func writeOrgConfig(t *testing.T, path, data string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+}
+
+func TestParseOrgScanDefaults(t *testing.T) {
+	orgs, err := ParseOrgScanDefaults([]byte(`
+Acme:
+  checks: [secret_scanning, files]
+  compliance_policy: {require_secret_scanning: true, require_codeowners: true}
+  concurrency: 20
+  sinks: [{type: file, options: {dir: /var/reports}}]
+empty-org:
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]OrgScanDefaults{
+		"acme": {
+			Checks:           []string{CheckSecretScanning, CheckFiles},
+			CompliancePolicy: &CompliancePolicy{RequireSecretScanning: true, RequireCodeowners: true},
+			Concurrency:      20,
+			Sinks:            []ReportSinkSpec{{Type: SinkFile, Options: map[string]string{"dir": "/var/reports"}}},
+		},
+		"empty-org": {},
+	}, orgs)
+
+	for data, want := range map[string]string{
+		"acme: {checks: [secret_scaning]}":                      `org "acme": unknown check(s) secret_scaning`,
+		"acme: {batch_size: 10}":                                `unknown field "batch_size"`,
+		"acme: {concurrency: 5, batch_delay: {seconds: 1}}":     "concurrency replaces batching",
+		"acme: {compliance_policy: {require_codeowners: true}}": `requires check "files", which is not selected`,
+		"acme: {sinks: [{type: file}]}":                         "file sink needs the dir option",
+		"acme: {active_within_days: -1}":                        "active_within_days must not be negative",
+		"acme: {}\nACME: {}":                                    "configured twice",
+		"- acme":                                                "parsing org config",
+		"acme: {priority_repos: ['[']}":                         "not a valid glob",
+		"acme: {batch_delay: {seconds: 1, jitter: 2}}":          "jitter must be between 0 and 1",
+	} {
+		_, err := ParseOrgScanDefaults([]byte(data))
+		require.ErrorContains(t, err, want, data)
+	}
+}
+
+func TestMergeScanDefaultsExplicitInputWins(t *testing.T) {
+	defaults := OrgScanDefaults{
+		Checks:             []string{CheckSecretScanning, CheckFiles},
+		CompliancePolicy:   &CompliancePolicy{RequireCodeowners: true},
+		ActiveWithinDays:   90,
+		PriorityRepos:      []string{"api-*"},
+		SuppressionsSource: "/etc/scanner/suppressions.yaml",
+		Concurrency:        20,
+		Sinks:              []ReportSinkSpec{{Type: SinkBlob}},
+	}
+
+	// An empty input takes every default.
+	in, applied := mergeScanDefaults(ScanInput{Org: "acme"}, defaults)
+	require.Equal(t, []string{"checks", "compliance_policy", "active_within_days", "priority_repos", "suppressions_source", "concurrency", "sinks"}, applied)
+	require.Equal(t, defaults, scanDefaultsOf(in))
+
+	// Explicit fields are kept, and so are the defaults of the others.
+	explicit := ScanInput{
+		Org:              "acme",
+		Checks:           []string{CheckSecretScanning},
+		ActiveWithinDays: 30,
+		Sinks:            []ReportSinkSpec{{Type: SinkFile, Options: map[string]string{"dir": "/var/reports"}}},
+		Suppressions:     []Suppression{{Repo: "legacy", Justification: "archived soon"}},
+	}
+	in, applied = mergeScanDefaults(explicit, defaults)
+	require.Equal(t, []string{"priority_repos", "concurrency"}, applied)
+	require.Equal(t, []string{CheckSecretScanning}, in.Checks)
+	require.Equal(t, 30, in.ActiveWithinDays)
+	require.Equal(t, explicit.Sinks, in.Sinks)
+	require.Empty(t, in.SuppressionsSource, "inline suppressions replace the default source")
+	require.Nil(t, in.CompliancePolicy, "the default policy needs the files check, which the input does not select")
+
+	// Defaults never make an input invalid: a batching input takes no
+	// default concurrency, and a concurrent one no batch delay.
+	in, applied = mergeScanDefaults(ScanInput{Org: "acme", ChildPerBatch: true}, OrgScanDefaults{Concurrency: 20})
+	require.Empty(t, applied)
+	require.NoError(t, in.validateConcurrency())
+	in, applied = mergeScanDefaults(ScanInput{Org: "acme", Concurrency: 5}, OrgScanDefaults{BatchDelay: &BatchDelay{Seconds: 2}})
+	require.Empty(t, applied)
+	require.NoError(t, in.validateConcurrency())
+	in, _ = mergeScanDefaults(ScanInput{Org: "acme"}, OrgScanDefaults{BatchDelay: &BatchDelay{Seconds: 2}})
+	require.Equal(t, &BatchDelay{Seconds: 2}, in.BatchDelay)
+
+	// IncludeAccessAudit alone selects the default checks plus the audit,
+	// so default checks do not replace it.
+	_, applied = mergeScanDefaults(ScanInput{Org: "acme", IncludeAccessAudit: true}, OrgScanDefaults{Checks: []string{CheckFiles}})
+	require.Empty(t, applied)
+}
+
+func TestOrgConfigRegistryLoadsADirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeOrgConfig(t, filepath.Join(dir, "platform.yaml"), "acme: {checks: [secret_scanning]}\nglobex: {concurrency: 4}\n")
+	writeOrgConfig(t, filepath.Join(dir, "legacy.json"), `{"initech": {"active_within_days": 365}}`)
+	writeOrgConfig(t, filepath.Join(dir, "README.md"), "not config")
+
+	r, err := LoadOrgConfigRegistry(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"acme", "globex", "initech"}, r.Orgs())
+	d, source, ok := r.Lookup("ACME")
+	require.True(t, ok)
+	require.Equal(t, filepath.Join(dir, "platform.yaml"), source)
+	require.Equal(t, []string{CheckSecretScanning}, d.Checks)
+	_, _, ok = r.Lookup("umbrella")
+	require.False(t, ok)
+
+	writeOrgConfig(t, filepath.Join(dir, "more.yml"), "Globex: {concurrency: 8}\n")
+	_, err = LoadOrgConfigRegistry(dir)
+	require.ErrorContains(t, err, `org "globex" is configured in both`)
+
+	_, err = LoadOrgConfigRegistry(filepath.Join(dir, "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestOrgConfigRegistryReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orgs.yaml")
+	writeOrgConfig(t, path, "acme: {concurrency: 4}\n")
+	r, err := LoadOrgConfigRegistry(path)
+	require.NoError(t, err)
+	a := &Activities{OrgConfigs: r}
+
+	resolve := func() ResolvedScanInput {
+		t.Helper()
+		resolved, err := a.ResolveScanConfig(context.Background(), ScanInput{Org: "acme"})
+		require.NoError(t, err)
+		return resolved
+	}
+	require.Equal(t, 4, resolve().Input.Concurrency)
+
+	// A reload picks up changed, added and removed entries.
+	writeOrgConfig(t, path, "acme: {concurrency: 8}\nglobex: {}\n")
+	require.NoError(t, r.Reload())
+	require.Equal(t, 8, resolve().Input.Concurrency)
+	require.Equal(t, []string{"acme", "globex"}, r.Orgs())
+
+	// A reload that fails keeps what was loaded before.
+	writeOrgConfig(t, path, "acme: {concurrency: -1}\n")
+	require.ErrorContains(t, r.Reload(), "concurrency must not be negative")
+	require.Equal(t, 8, resolve().Input.Concurrency)
+	require.NoError(t, os.Remove(path))
+	require.Error(t, r.Reload())
+	require.Equal(t, []string{"acme", "globex"}, r.Orgs())
+}
+
+func TestResolveScanConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orgs.yaml")
+	writeOrgConfig(t, path, "acme: {checks: [secret_scanning], concurrency: 4}\n")
+	r, err := LoadOrgConfigRegistry(path)
+	require.NoError(t, err)
+	token := "ghp_resolve"
+
+	resolved, err := (&Activities{OrgConfigs: r}).ResolveScanConfig(context.Background(), ScanInput{Org: "acme", Token: &token, Concurrency: 2})
+	require.NoError(t, err)
+	require.Nil(t, resolved.Input.Token, "the token stays out of the result")
+	require.Equal(t, ScanConfig{
+		Source:       path,
+		FromRegistry: []string{"checks"},
+		Resolved:     OrgScanDefaults{Checks: []string{CheckSecretScanning}, Concurrency: 2},
+	}, resolved.Config)
+	require.False(t, resolved.AuditLog)
+
+	resolved, err = (&Activities{AuditLog: &FileAuditLog{Path: filepath.Join(t.TempDir(), "audit.ndjson")}}).
+		ResolveScanConfig(context.Background(), ScanInput{Org: "acme"})
+	require.NoError(t, err)
+	require.True(t, resolved.AuditLog, "the workflow learns the worker keeps an audit log")
+
+	// Unknown orgs, and workers without a registry, get package defaults.
+	for _, a := range []*Activities{{OrgConfigs: r}, {}} {
+		in := ScanInput{Org: "umbrella", Token: &token, ActiveWithinDays: 30}
+		resolved, err = a.ResolveScanConfig(context.Background(), in)
+		require.NoError(t, err)
+		in.Token = nil
+		require.Equal(t, in, resolved.Input)
+		require.Equal(t, ScanConfig{Source: ScanConfigPackageDefaults, Resolved: OrgScanDefaults{ActiveWithinDays: 30}}, resolved.Config)
+	}
+}
+
+func newOrgConfigTestEnv(t *testing.T, config string) (*testsuite.TestWorkflowEnvironment, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "orgs.yaml")
+	writeOrgConfig(t, path, config)
+	r, err := LoadOrgConfigRegistry(path)
+	require.NoError(t, err)
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{OrgConfigs: r})
+	mockActionsSecurity(env)
+	return env, path
+}
+
+func TestWorkflowAppliesOrgDefaults(t *testing.T) {
+	reports := t.TempDir()
+	env, path := newOrgConfigTestEnv(t, `
+acme:
+  checks: [secret_scanning]
+  sinks: [{type: file, options: {dir: `+reports+`}}]
+`)
+	onListOrgRepos(env, fakeRepos(3))
+	token := "ghp_org-defaults"
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "ACME", mock.Anything, PATCredential(&token), []string{CheckSecretScanning}).
+		Return(compliantUnless("repo-001"))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "ACME", Token: &token, PriorityRepos: []string{"repo-002"}})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, []string{CheckSecretScanning}, report.Checks)
+	require.Equal(t, &ScanConfig{
+		Source:       path,
+		FromRegistry: []string{"checks", "sinks"},
+		Resolved: OrgScanDefaults{
+			Checks:        []string{CheckSecretScanning},
+			PriorityRepos: []string{"repo-002"},
+			Sinks:         []ReportSinkSpec{{Type: SinkFile, Options: map[string]string{"dir": reports}}},
+		},
+	}, report.ScanConfig)
+	require.Len(t, report.Delivery, 1)
+	require.True(t, report.Delivery[0].Delivered)
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 3)
+}
+
+func TestWorkflowUsesPackageDefaultsForUnknownOrgs(t *testing.T) {
+	env, _ := newOrgConfigTestEnv(t, "globex: {checks: [secret_scanning]}\n")
+	onListOrgRepos(env, fakeRepos(2))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "acme", mock.Anything, mock.Anything, DefaultChecks()).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, &ScanConfig{Source: ScanConfigPackageDefaults}, report.ScanConfig)
+	require.Empty(t, report.Delivery)
+}
@@ -0,0 +1,81 @@
+package scanner
+
+// =============================================================================
+// Organization names — fail on a typo before any request is made
+// =============================================================================
+//
+// ValidateOrgName applies GitHub's login rules, so "acme corp" or "-acme" is
+// rejected by the starter before a workflow exists and by the workflow before
+// FetchOrgRepos retries against a 404. A syntactically valid name that does
+// not exist is caught by the starter's pre-flight, which uses SuggestOrg to
+// offer the closest org the token can see.
+// =============================================================================
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxOrgNameLength is GitHub's limit on account logins.
+const maxOrgNameLength = 39
+
+// orgNamePattern is alphanumerics and hyphens, not at either end. GitHub
+// now also forbids consecutive hyphens, but some older accounts have them,
+// so they are allowed.
+var orgNamePattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?$`)
+
+// ValidateOrgName checks that org is a possible GitHub organization login.
+func ValidateOrgName(org string) error {
+	switch {
+	case org == "":
+		return fmt.Errorf("organization name is empty")
+	case len(org) > maxOrgNameLength:
+		return fmt.Errorf("organization %q is longer than %d characters", org, maxOrgNameLength)
+	case !orgNamePattern.MatchString(org):
+		return fmt.Errorf("organization %q may only contain letters, digits, and hyphens, and cannot start or end with a hyphen", org)
+	}
+	return nil
+}
+
+// maxSuggestionDistance is the most edits SuggestOrg will accept; anything
+// further is more likely a different org than a typo.
+const maxSuggestionDistance = 3
+
+// SuggestOrg returns the candidate closest to org by edit distance,
+// ignoring case, or "" if none is within a few edits. Ties go to the
+// earlier candidate.
+func SuggestOrg(org string, candidates []string) string {
+	best, bestDist := "", maxSuggestionDistance+1
+	target := strings.ToLower(org)
+	for _, c := range candidates {
+		d := levenshtein(target, strings.ToLower(c))
+		// A distance of len(org) or more means nothing was shared.
+		if d < bestDist && d < len(target) {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// levenshtein is the edit distance between a and b, by bytes; logins are
+// ASCII.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,39 @@
+package scanner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestValidateOrgName(t *testing.T) {
+	for _, org := range []string{"acme", "temporalio", "acme-corp", "a", "A1", "old--style", strings.Repeat("x", 39)} {
+		require.NoError(t, ValidateOrgName(org), org)
+	}
+	for _, org := range []string{"", "-acme", "acme-", "acme corp", "acme/web", "acme_corp", "acme.io", strings.Repeat("x", 40)} {
+		require.Error(t, ValidateOrgName(org), org)
+	}
+}
+
+func TestSuggestOrg(t *testing.T) {
+	orgs := []string{"temporalio", "temporal-community", "acme-corp"}
+	require.Equal(t, "temporalio", SuggestOrg("temporalo", orgs))
+	require.Equal(t, "temporalio", SuggestOrg("TemporalIO", orgs), "case is ignored")
+	require.Equal(t, "acme-corp", SuggestOrg("acmecorp", orgs))
+	require.Empty(t, SuggestOrg("kubernetes", orgs))
+	require.Empty(t, SuggestOrg("ab", []string{"xy"}), "nothing in common")
+	require.Empty(t, SuggestOrg("temporalo", nil))
+}
+
+func TestWorkflowRejectsInvalidOrg(t *testing.T) {
+	env := newTestEnv(t)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme corp"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr), "got %v", env.GetWorkflowError())
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+}
@@ -0,0 +1,101 @@
+package scanner
+
+// =============================================================================
+// Org renames — following an org to its new login
+// =============================================================================
+//
+// GitHub keeps a renamed org's old login as a redirect: /orgs/{old}/repos
+// answers 301 with the listing's new URL, e.g. /organizations/123/repos,
+// in its Location header and its body's "url". The HTTP client follows
+// it; listGitHubRepos then lists the later pages from where the first was
+// served, and follows a redirect the client hands back itself (a client
+// configured not to follow them, say). Some 404s name where the org went
+// in "url" too, e.g. when the old login no longer redirects; the listing
+// follows those the same way, and only if the token can see the org under
+// its new login does it list anything.
+//
+// The listing names repos by their full names under the new login, which
+// is how SecurityScanWorkflow learns it (orgRenamedTo): it scans under the
+// new login, and the report keeps the org it was asked for in "org" and
+// adds "org_renamed_to". The workflow ID stays the one the caller chose.
+// A renamed org with no repos has nothing to scan and is not reported as
+// renamed.
+// =============================================================================
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxListingRedirects bounds how many times a repo listing follows a
+// redirect itself, so two logins pointing at each other cannot loop.
+const maxListingRedirects = 5
+
+// isRedirect reports whether status is a redirect with a new location.
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// movedTo is the repo listing path a redirect, or a 404 naming where the
+// org went, points to: its Location header or its body's "url". It is ""
+// when they point nowhere under the API.
+func (a *Activities) movedTo(resp *http.Response, body []byte) string {
+	loc := resp.Header.Get("Location")
+	if loc == "" {
+		var moved struct {
+			URL string `json:"url"`
+		}
+		if json.Unmarshal(body, &moved) == nil {
+			loc = moved.URL
+		}
+	}
+	if loc == "" || resp.Request == nil {
+		return ""
+	}
+	u, err := resp.Request.URL.Parse(loc)
+	if err != nil {
+		return ""
+	}
+	path := a.apiPath(u)
+	// A 404 names the org; its repos are listed under it.
+	if path != "" && !strings.HasSuffix(path, "/repos") {
+		path += "/repos"
+	}
+	return path
+}
+
+// apiPath is u's path under the GitHub API root, without its query, or ""
+// when u is not under the root.
+func (a *Activities) apiPath(u *url.URL) string {
+	base, err := url.Parse(a.apiURL(""))
+	if err != nil || u == nil || u.Host != base.Host {
+		return ""
+	}
+	root := strings.TrimRight(base.Path, "/")
+	if !strings.HasPrefix(u.Path, root+"/") {
+		return ""
+	}
+	return strings.TrimPrefix(u.Path, root)
+}
+
+// orgRenamedTo is the login the repos' full names put them under, when it
+// is not org's: GitHub lists a renamed org's repos under its new login.
+func orgRenamedTo(org string, repos []RepoInfo) string {
+	for _, r := range repos {
+		owner, _, ok := strings.Cut(r.FullName, "/")
+		if !ok || owner == "" {
+			continue
+		}
+		if strings.EqualFold(owner, org) {
+			return ""
+		}
+		return owner
+	}
+	return ""
+}
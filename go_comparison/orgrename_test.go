@@ -0,0 +1,128 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+// renamedOrg serves old-corp's listing as a 301 to organizations/123,
+// which lists 101 repos under new-corp, and counts the requests per path.
+type renamedOrg struct {
+	mu       sync.Mutex
+	requests map[string]int
+	// notFoundHint answers old-corp with a 404 naming new-corp instead.
+	notFoundHint bool
+}
+
+func (o *renamedOrg) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	o.requests[r.URL.Path]++
+	o.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Path == "/orgs/old-corp/repos" && o.notFoundHint:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"message":"Not Found","url":"http://%s/orgs/new-corp"}`, r.Host)
+	case r.URL.Path == "/orgs/old-corp/repos":
+		w.Header().Set("Location", "/organizations/123/repos?"+r.URL.RawQuery)
+		w.WriteHeader(http.StatusMovedPermanently)
+		fmt.Fprintf(w, `{"message":"Moved Permanently","url":"http://%s/organizations/123/repos?%s"}`, r.Host, r.URL.RawQuery)
+	case r.URL.Path == "/organizations/123/repos", r.URL.Path == "/orgs/new-corp/repos":
+		n := 100
+		if r.URL.Query().Get("page") == "2" {
+			n = 1
+		}
+		var repos []string
+		for i := 0; i < n; i++ {
+			repos = append(repos, fmt.Sprintf(`{"name":"repo-%s-%03d","full_name":"new-corp/repo-%[1]s-%03[2]d"}`, r.URL.Query().Get("page"), i))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(repos, ","))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Not Found"}`)
+	}
+}
+
+func TestFetchOrgReposFollowsRename(t *testing.T) {
+	for name, tc := range map[string]struct {
+		notFoundHint   bool
+		followRedirect bool
+	}{
+		"client follows the 301":    {followRedirect: true},
+		"client hands back the 301": {},
+		"404 naming the new login":  {notFoundHint: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			org := &renamedOrg{requests: map[string]int{}, notFoundHint: tc.notFoundHint}
+			srv := httptest.NewServer(org)
+			t.Cleanup(srv.Close)
+			client := srv.Client()
+			if !tc.followRedirect {
+				client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+			}
+			a := &Activities{HTTPClient: client, BaseURL: srv.URL}
+			env := newActivityEnv(a)
+
+			val, err := env.ExecuteActivity(a.FetchOrgRepos, ScanInput{Org: "old-corp"})
+			require.NoError(t, err)
+			var repos []RepoInfo
+			require.NoError(t, val.Get(&repos))
+			require.Len(t, repos, 101)
+			require.Equal(t, "new-corp", orgRenamedTo("old-corp", repos))
+			require.Equal(t, 1, org.requests["/orgs/old-corp/repos"], "later pages go straight to the new listing")
+		})
+	}
+}
+
+func TestFetchOrgReposRenameNotVisible(t *testing.T) {
+	// The 404 names an org the token cannot see either.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		if r.URL.Path == "/orgs/old-corp/repos" {
+			fmt.Fprintf(w, `{"message":"Not Found","url":"http://%s/orgs/new-corp"}`, r.Host)
+			return
+		}
+		fmt.Fprint(w, `{"message":"Not Found"}`)
+	}))
+	t.Cleanup(srv.Close)
+	a := &Activities{HTTPClient: srv.Client(), BaseURL: srv.URL}
+	env := newActivityEnv(a)
+
+	_, err := env.ExecuteActivity(a.FetchOrgRepos, ScanInput{Org: "old-corp"})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, "NOT_FOUND", appErr.Type())
+}
+
+func TestOrgRenamedTo(t *testing.T) {
+	require.Equal(t, "", orgRenamedTo("Acme", []RepoInfo{{Name: "api", FullName: "acme/api"}}), "logins are case-insensitive")
+	require.Equal(t, "acme-inc", orgRenamedTo("acme", []RepoInfo{{Name: "x"}, {Name: "api", FullName: "acme-inc/api"}}))
+	require.Equal(t, "", orgRenamedTo("acme", nil))
+}
+
+func TestWorkflowScansRenamedOrgUnderNewLogin(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, []RepoInfo{{Name: "api", FullName: "new-corp/api"}, {Name: "web", FullName: "new-corp/web"}})
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "new-corp", mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "old-corp"})
+
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 2)
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, "old-corp", report["org"])
+	require.Equal(t, "new-corp", report["org_renamed_to"])
+	require.Equal(t, "default-test-workflow-id", report["workflow_id"])
+}
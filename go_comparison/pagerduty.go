@@ -0,0 +1,259 @@
+package scanner
+
+// =============================================================================
+// PagerDuty — paging on-call when compliance gets worse
+// =============================================================================
+//
+// On-call is paged only when things get worse, not on every scan. When the
+// worker has PagerDuty configured (Activities.PagerDuty), TriggerPagerDuty
+// sends an Events API v2 trigger when both:
+//
+//	the compliance rate is below PagerDutyConfig.Threshold, and
+//	the diff against the org's last report (see scanhistory.go) shows a
+//	regression: a lower rate or a repo newly failing a check
+//
+// Every event for an org uses the dedup key security-scan/<provider>/<org>,
+// so a repeat trigger updates the open incident instead of paging again.
+// The report's paging section records whether the alert is open, and the
+// next scan reads it from the last report: once the rate is back at or
+// above the threshold, it sends the resolve event.
+//
+// A first scan has no baseline, so it never pages, and neither a partial
+// report (cancelled, deadline reached or API budget exceeded) nor a scan
+// that narrows the repo set (see scanhistory.go) pages or resolves: its
+// rate covers only some of the org's repos. Failing to reach
+// PagerDuty is recorded in the paging section, not a scan failure; the
+// alert keeps its previous state so the next scan tries again.
+// =============================================================================
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Defaults for PagerDutyConfig's zero values.
+const (
+	DefaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	DefaultPagerDutySeverity  = "critical"
+)
+
+// Event actions of the Events API v2.
+const (
+	PagerDutyTrigger = "trigger"
+	PagerDutyResolve = "resolve"
+)
+
+// ErrTypePagerDutyRejected is the ApplicationError type for an event
+// PagerDuty refuses, e.g. for an unknown routing key.
+const ErrTypePagerDutyRejected = "PAGERDUTY_REJECTED"
+
+// PagerDutyConfig is the PagerDuty service to page.
+type PagerDutyConfig struct {
+	// RoutingKey is the service's Events API v2 integration key.
+	RoutingKey string
+	// Threshold is the compliance rate, in percent, below which a
+	// regression pages.
+	Threshold float64
+	// Severity of triggered alerts; DefaultPagerDutySeverity if empty.
+	Severity string
+	// EventsURL defaults to DefaultPagerDutyEventsURL.
+	EventsURL string
+	// HTTPClient defaults to the Activities' client.
+	HTTPClient *http.Client
+}
+
+// PagerInput is the input to TriggerPagerDuty.
+type PagerInput struct {
+	Report map[string]interface{} `json:"report"`
+	// Diff is against the org's last report; nil when there is none.
+	Diff *ReportDiff `json:"diff,omitempty"`
+	// Previous is the last report's paging section.
+	Previous *PagingResult `json:"previous,omitempty"`
+}
+
+// PagingResult is the report's paging section.
+type PagingResult struct {
+	DedupKey string `json:"dedup_key"`
+	// Open is whether the alert is open after this scan.
+	Open bool `json:"open"`
+	// Action is the event sent by this scan, if any.
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// pagerDutyDedupKey is the dedup key of every event for org.
+func pagerDutyDedupKey(provider, org string) string {
+	return "security-scan/" + providerName(provider) + "/" + org
+}
+
+// regressed reports whether d shows things getting worse.
+func regressed(d *ReportDiff) bool {
+	return d != nil && (d.RateDelta < 0 || len(d.Regressions) > 0)
+}
+
+// pagerDutyAction decides the event to send, if any, and whether the alert
+// is open afterwards.
+func pagerDutyAction(rate, threshold float64, d *ReportDiff, wasOpen bool) (action string, open bool) {
+	below := rate < threshold
+	switch {
+	case below && regressed(d):
+		return PagerDutyTrigger, true
+	case below:
+		return "", wasOpen
+	case wasOpen:
+		return PagerDutyResolve, false
+	}
+	return "", false
+}
+
+// TriggerPagerDuty sends the trigger or resolve event the report calls for.
+// It returns nil when the worker has no PagerDuty.
+func (a *Activities) TriggerPagerDuty(ctx context.Context, in PagerInput) (*PagingResult, error) {
+	cfg := a.PagerDuty
+	if cfg == nil || cfg.RoutingKey == "" {
+		return nil, nil
+	}
+	report, err := reportFromMap(in.Report)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("decoding report: "+err.Error(), ErrTypeInvalidInput, nil)
+	}
+	wasOpen := in.Previous != nil && in.Previous.Open
+	action, open := pagerDutyAction(report.Rate(), cfg.Threshold, in.Diff, wasOpen)
+	result := &PagingResult{DedupKey: pagerDutyDedupKey(report.Provider, report.Org), Open: open, Action: action}
+	if action == "" {
+		return result, nil
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = a.HTTPClient
+	}
+	if err := cfg.send(ctx, client, cfg.event(action, result.DedupKey, report, in.Diff)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// pagerDutyEvent is an Events API v2 event.
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// event builds the event for action. Resolve events carry no payload.
+func (cfg *PagerDutyConfig) event(action, dedupKey string, r Report, d *ReportDiff) pagerDutyEvent {
+	e := pagerDutyEvent{RoutingKey: cfg.RoutingKey, EventAction: action, DedupKey: dedupKey}
+	if action != PagerDutyTrigger {
+		return e
+	}
+	severity := cfg.Severity
+	if severity == "" {
+		severity = DefaultPagerDutySeverity
+	}
+	details := map[string]interface{}{
+		"compliance_rate":     r.ComplianceRate,
+		"threshold":           fmt.Sprintf("%.1f%%", cfg.Threshold),
+		"non_compliant_repos": len(r.NonCompliantRepos),
+		"run_id":              r.RunID,
+		"workflow_id":         r.WorkflowID,
+	}
+	if d != nil {
+		details["compliance_rate_delta"] = d.RateDelta
+		details["regressions"] = d.Regressions
+	}
+	e.Payload = &pagerDutyPayload{
+		Summary:       fmt.Sprintf("Security compliance of %s regressed to %s (threshold %.1f%%)", r.Org, r.ComplianceRate, cfg.Threshold),
+		Source:        "temporal-security-scanner",
+		Severity:      severity,
+		Component:     r.Org,
+		Group:         providerName(r.Provider),
+		Class:         "compliance-regression",
+		CustomDetails: details,
+	}
+	return e
+}
+
+// send enqueues e. 429 and 5xx are left to the retry policy; any other 4xx
+// means the routing key or event is wrong, which a retry will not fix.
+func (cfg *PagerDutyConfig) send(ctx context.Context, client *http.Client, e pagerDutyEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	eventsURL := cfg.EventsURL
+	if eventsURL == "" {
+		eventsURL = DefaultPagerDutyEventsURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, eventsURL, bytes.NewReader(body))
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidInput, nil)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending PagerDuty %s: %w", e.EventAction, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("PagerDuty returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		return temporal.NewNonRetryableApplicationError(err.Error(), ErrTypePagerDutyRejected, nil)
+	}
+	return err
+}
+
+// triggerPagerDuty runs TriggerPagerDuty for report against baseline. A
+// failure keeps the previous alert state and records the error.
+func triggerPagerDuty(ctx workflow.Context, report map[string]interface{}, baseline *Report) *PagingResult {
+	cur, err := reportFromMap(report)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Cannot read the report for paging", "error", err)
+		return &PagingResult{Error: err.Error()}
+	}
+	in := PagerInput{Report: report}
+	if baseline != nil {
+		in.Previous = baseline.Paging
+		if CheckComparable(*baseline, cur) == nil {
+			d := CompareReports(*baseline, cur)
+			in.Diff = &d
+		}
+	}
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy:         githubRetryPolicy(),
+	})
+	var result *PagingResult
+	err = workflow.ExecuteActivity(actCtx, "TriggerPagerDuty", in).Get(ctx, &result)
+	if err == nil {
+		return result
+	}
+	workflow.GetLogger(ctx).Warn("Paging failed", "error", err)
+	return &PagingResult{
+		DedupKey: pagerDutyDedupKey(cur.Provider, cur.Org),
+		Open:     in.Previous != nil && in.Previous.Open,
+		Error:    err.Error(),
+	}
+}
@@ -0,0 +1,364 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+// fakePagerDuty is an httptest Events API v2 endpoint that records events
+// and answers each with status (202 when zero).
+type fakePagerDuty struct {
+	mu     sync.Mutex
+	status int
+	events []pagerDutyEvent
+}
+
+func newFakePagerDuty(t *testing.T) (*fakePagerDuty, *PagerDutyConfig) {
+	t.Helper()
+	f := &fakePagerDuty{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.status != 0 {
+			w.WriteHeader(f.status)
+			_, _ = w.Write([]byte(`{"status":"invalid event","message":"Event object is invalid"}`))
+			return
+		}
+		var e pagerDutyEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		f.events = append(f.events, e)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status":"success","dedup_key":"` + e.DedupKey + `"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return f, &PagerDutyConfig{RoutingKey: "pd-key", Threshold: 80, EventsURL: srv.URL, HTTPClient: srv.Client()}
+}
+
+func (f *fakePagerDuty) Events() []pagerDutyEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]pagerDutyEvent{}, f.events...)
+}
+
+func TestPagerDutyAction(t *testing.T) {
+	worse := &ReportDiff{RateDelta: -5}
+	newFailure := &ReportDiff{Regressions: map[string][]string{CheckDependabot: {"api"}}}
+	better := &ReportDiff{RateDelta: 5}
+	for _, tc := range []struct {
+		name       string
+		rate       float64
+		diff       *ReportDiff
+		wasOpen    bool
+		wantAction string
+		wantOpen   bool
+	}{
+		{"below and worse", 70, worse, false, PagerDutyTrigger, true},
+		{"below with a new failure", 79.9, newFailure, false, PagerDutyTrigger, true},
+		{"worse again updates the alert", 60, worse, true, PagerDutyTrigger, true},
+		{"below but improving", 70, better, false, "", false},
+		{"below but improving keeps the alert open", 75, better, true, "", true},
+		{"no baseline", 10, nil, false, "", false},
+		{"above but worse", 90, worse, false, "", false},
+		{"recovered", 80, better, true, PagerDutyResolve, false},
+	} {
+		action, open := pagerDutyAction(tc.rate, 80, tc.diff, tc.wasOpen)
+		require.Equal(t, tc.wantAction, action, tc.name)
+		require.Equal(t, tc.wantOpen, open, tc.name)
+	}
+}
+
+func pagerInput(t *testing.T, rate string, diff *ReportDiff, previous *PagingResult) PagerInput {
+	t.Helper()
+	m, err := json.Marshal(Report{Org: "acme", TotalRepos: 10, ComplianceRate: rate, NonCompliantRepos: []string{"api"}, RunID: "run-2"})
+	require.NoError(t, err)
+	var report map[string]interface{}
+	require.NoError(t, json.Unmarshal(m, &report))
+	return PagerInput{Report: report, Diff: diff, Previous: previous}
+}
+
+func runPager(t *testing.T, cfg *PagerDutyConfig, in PagerInput) (*PagingResult, error) {
+	t.Helper()
+	a := &Activities{PagerDuty: cfg}
+	val, err := newActivityEnv(a).ExecuteActivity(a.TriggerPagerDuty, in)
+	if err != nil {
+		return nil, err
+	}
+	var out *PagingResult
+	if val.HasValue() {
+		require.NoError(t, val.Get(&out))
+	}
+	return out, nil
+}
+
+func TestTriggerPagerDuty(t *testing.T) {
+	f, cfg := newFakePagerDuty(t)
+	diff := &ReportDiff{RateDelta: -20, Regressions: map[string][]string{CheckSecretScanning: {"api"}}}
+	out, err := runPager(t, cfg, pagerInput(t, "60.0%", diff, nil))
+	require.NoError(t, err)
+	require.Equal(t, &PagingResult{DedupKey: "security-scan/github/acme", Open: true, Action: PagerDutyTrigger}, out)
+
+	e := f.Events()[0]
+	require.Equal(t, "pd-key", e.RoutingKey)
+	require.Equal(t, PagerDutyTrigger, e.EventAction)
+	require.Equal(t, "security-scan/github/acme", e.DedupKey)
+	require.Equal(t, "Security compliance of acme regressed to 60.0% (threshold 80.0%)", e.Payload.Summary)
+	require.Equal(t, DefaultPagerDutySeverity, e.Payload.Severity)
+	require.Equal(t, "run-2", e.Payload.CustomDetails["run_id"])
+	require.Equal(t, map[string]interface{}{CheckSecretScanning: []interface{}{"api"}}, e.Payload.CustomDetails["regressions"])
+
+	// Back above the threshold: the open alert is resolved, without a payload.
+	out, err = runPager(t, cfg, pagerInput(t, "85.0%", &ReportDiff{RateDelta: 25}, out))
+	require.NoError(t, err)
+	require.Equal(t, &PagingResult{DedupKey: "security-scan/github/acme", Action: PagerDutyResolve}, out)
+	resolve := f.Events()[1]
+	require.Equal(t, PagerDutyResolve, resolve.EventAction)
+	require.Equal(t, "security-scan/github/acme", resolve.DedupKey)
+	require.Nil(t, resolve.Payload)
+
+	// Nothing to say: no event.
+	out, err = runPager(t, cfg, pagerInput(t, "90.0%", &ReportDiff{}, out))
+	require.NoError(t, err)
+	require.False(t, out.Open)
+	require.Empty(t, out.Action)
+	require.Len(t, f.Events(), 2)
+}
+
+func TestTriggerPagerDutyFailures(t *testing.T) {
+	worse := &ReportDiff{RateDelta: -20}
+
+	f, cfg := newFakePagerDuty(t)
+	f.status = http.StatusBadRequest
+	_, err := runPager(t, cfg, pagerInput(t, "60.0%", worse, nil))
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, ErrTypePagerDutyRejected, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+
+	f.status = http.StatusTooManyRequests
+	_, err = runPager(t, cfg, pagerInput(t, "60.0%", worse, nil))
+	require.ErrorContains(t, err, "PagerDuty returned 429")
+	require.False(t, isNonRetryable(err))
+
+	out, err := runPager(t, nil, pagerInput(t, "60.0%", worse, nil))
+	require.NoError(t, err)
+	require.Nil(t, out, "not configured")
+}
+
+// pagedScan runs a full scan of acme's three repos on a worker with store
+// and cfg, with the given repos failing dependabot, and returns its report.
+func pagedScan(t *testing.T, store BlobStore, cfg *PagerDutyConfig, nonCompliant ...string) Report {
+	t.Helper()
+	return pagedScanOf(t, store, cfg, workflowChanges[changeScanHistory], ScanInput{Org: "acme"}, nonCompliant...)
+}
+
+// pagedScanOf is pagedScan with input, recorded at scanHistory. Only
+// repo-000 was pushed to in the last 30 days, or has the tier-1 topic; the
+// others have legacy.
+func pagedScanOf(t *testing.T, store BlobStore, cfg *PagerDutyConfig, scanHistory workflow.Version, input ScanInput, nonCompliant ...string) Report {
+	t.Helper()
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	start := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	env.SetStartTime(start)
+	env.OnGetVersion(changeScanHistory, workflow.DefaultVersion, workflowChanges[changeScanHistory]).Return(scanHistory)
+	env.RegisterActivity(&Activities{BlobStore: store, PagerDuty: cfg})
+	mockActionsSecurity(env)
+	repos := fakeRepos(3)
+	for i := range repos {
+		pushed := start.AddDate(0, 0, -90)
+		repos[i].Topics = []string{"legacy"}
+		if i == 0 {
+			pushed = start.AddDate(0, 0, -1)
+			repos[i].Topics = []string{"tier-1"}
+		}
+		repos[i].PushedAt = &pushed
+	}
+	onListOrgRepos(env, repos)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless(nonCompliant...))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, input)
+
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	return report
+}
+
+func TestWorkflowPagesOnRegression(t *testing.T) {
+	f, cfg := newFakePagerDuty(t)
+	store := &FileBlobStore{Dir: t.TempDir()}
+
+	// The first scan has no baseline to regress from.
+	first := pagedScan(t, store, cfg, "repo-000")
+	require.Equal(t, &PagingResult{DedupKey: "security-scan/github/acme"}, first.Paging)
+
+	// 66.7% -> 33.3%, below the 80% threshold: page.
+	second := pagedScan(t, store, cfg, "repo-000", "repo-001")
+	require.Equal(t, &PagingResult{DedupKey: "security-scan/github/acme", Open: true, Action: PagerDutyTrigger}, second.Paging)
+
+	// Still below but no worse: the alert stays open, nobody is paged.
+	third := pagedScan(t, store, cfg, "repo-000", "repo-001")
+	require.Equal(t, &PagingResult{DedupKey: "security-scan/github/acme", Open: true}, third.Paging)
+
+	// Recovered: resolve.
+	fourth := pagedScan(t, store, cfg)
+	require.Equal(t, &PagingResult{DedupKey: "security-scan/github/acme", Action: PagerDutyResolve}, fourth.Paging)
+
+	var actions []string
+	for _, e := range f.Events() {
+		actions = append(actions, e.EventAction)
+	}
+	require.Equal(t, []string{PagerDutyTrigger, PagerDutyResolve}, actions)
+}
+
+func TestWorkflowRecordsPagingFailure(t *testing.T) {
+	f, cfg := newFakePagerDuty(t)
+	store := &FileBlobStore{Dir: t.TempDir()}
+	pagedScan(t, store, cfg)
+
+	f.status = http.StatusBadRequest
+	report := pagedScan(t, store, cfg, "repo-000", "repo-001")
+	require.False(t, report.Paging.Open, "the alert was never opened")
+	require.Contains(t, report.Paging.Error, "PagerDuty returned 400")
+
+	// The next scan tries again.
+	f.status = 0
+	report = pagedScan(t, store, cfg, "repo-000", "repo-001", "repo-002")
+	require.True(t, report.Paging.Open)
+	require.Len(t, f.Events(), 1)
+}
+
+func TestWorkflowDoesNotPageOnNarrowedScan(t *testing.T) {
+	narrowed := map[string]ScanInput{
+		"active within days": {Org: "acme", ActiveWithinDays: 30},
+		"include topics":     {Org: "acme", IncludeTopics: []string{"tier-1"}},
+		"exclude topics":     {Org: "acme", ExcludeTopics: []string{"legacy"}},
+	}
+	for name, input := range narrowed {
+		t.Run(name, func(t *testing.T) {
+			f, cfg := newFakePagerDuty(t)
+			store := &FileBlobStore{Dir: t.TempDir()}
+			pagedScan(t, store, cfg)
+
+			// repo-000 is all the scan covers: 0%, below the threshold
+			// and a regression from 100%, but not the org's rate.
+			report := pagedScanOf(t, store, cfg, workflowChanges[changeScanHistory], input, "repo-000")
+			require.Equal(t, 1, report.TotalRepos)
+			require.Equal(t, "0.0%", report.ComplianceRate)
+			require.Nil(t, report.Paging)
+			require.Empty(t, f.Events(), "a narrowed scan does not page")
+
+			baseline, err := (&Activities{BlobStore: store}).LoadLastReport(context.Background(), "", "acme")
+			require.NoError(t, err)
+			require.Equal(t, "100.0%", baseline["compliance_rate"], "a narrowed scan does not replace the baseline")
+		})
+	}
+
+	t.Run("recorded before version 3", func(t *testing.T) {
+		f, cfg := newFakePagerDuty(t)
+		store := &FileBlobStore{Dir: t.TempDir()}
+		pagedScan(t, store, cfg)
+
+		report := pagedScanOf(t, store, cfg, 2, ScanInput{Org: "acme", ActiveWithinDays: 30}, "repo-000")
+		require.NotNil(t, report.Paging)
+		require.Equal(t, PagerDutyTrigger, report.Paging.Action, "old runs replay paging on a narrowed scan")
+		require.Len(t, f.Events(), 1)
+	})
+}
+
+// partialScan runs a scan of acme's 25 repos, all failing dependabot, on a
+// worker with store and cfg. stop makes the report partial: it changes the
+// input and mocks CheckRepoSecurity.
+func partialScan(t *testing.T, store BlobStore, cfg *PagerDutyConfig, scanHistory workflow.Version, stop func(*testsuite.TestWorkflowEnvironment, *ScanInput)) Report {
+	t.Helper()
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{BlobStore: store, PagerDuty: cfg})
+	mockActionsSecurity(env)
+	env.OnGetVersion(changeScanHistory, workflow.DefaultVersion, workflowChanges[changeScanHistory]).Return(scanHistory)
+	onListOrgRepos(env, fakeRepos(25))
+	input := ScanInput{Org: "acme"}
+	stop(env, &input)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, input)
+
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	return report
+}
+
+func TestWorkflowDoesNotPageOnPartialReport(t *testing.T) {
+	var all []string
+	for _, r := range fakeRepos(25) {
+		all = append(all, r.Name)
+	}
+	failing := compliantUnless(all...)
+
+	stops := map[string]func(*testsuite.TestWorkflowEnvironment, *ScanInput){
+		"cancelled": func(env *testsuite.TestWorkflowEnvironment, _ *ScanInput) {
+			env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				After(time.Minute).Return(failing)
+			env.RegisterDelayedCallback(func() {
+				env.SignalWorkflow("cancel_scan", "change freeze")
+			}, 90*time.Second)
+		},
+		"deadline reached": func(env *testsuite.TestWorkflowEnvironment, in *ScanInput) {
+			env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				After(30 * time.Second).Return(failing)
+			in.MaxDurationSeconds = 45
+		},
+		"budget exceeded": func(env *testsuite.TestWorkflowEnvironment, in *ScanInput) {
+			env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(func(ctx context.Context, org, repo string, cred *Credential, checks []string) (*RepoSecurityResult, error) {
+					if repo >= "repo-012" {
+						return nil, temporal.NewNonRetryableApplicationError("API budget of 36 requests for this scan is spent", ErrTypeAPIBudgetExceeded, nil)
+					}
+					return failing(ctx, org, repo, cred, checks)
+				})
+			in.MaxAPIRequests = 36
+		},
+	}
+	for name, stop := range stops {
+		t.Run(name, func(t *testing.T) {
+			f, cfg := newFakePagerDuty(t)
+			store := &FileBlobStore{Dir: t.TempDir()}
+			pagedScan(t, store, cfg)
+
+			// 0% is below the threshold and a regression from 100%, but
+			// only for the repos the scan got to.
+			report := partialScan(t, store, cfg, workflowChanges[changeScanHistory], stop)
+			require.NotEqual(t, "100.0%", report.ComplianceRate)
+			require.Nil(t, report.Paging)
+			require.Empty(t, f.Events(), "a partial report does not page")
+
+			baseline, err := (&Activities{BlobStore: store}).LoadLastReport(context.Background(), "", "acme")
+			require.NoError(t, err)
+			require.Equal(t, "100.0%", baseline["compliance_rate"], "a partial report does not replace the baseline")
+		})
+	}
+
+	t.Run("recorded before version 2", func(t *testing.T) {
+		f, cfg := newFakePagerDuty(t)
+		store := &FileBlobStore{Dir: t.TempDir()}
+		pagedScan(t, store, cfg)
+
+		report := partialScan(t, store, cfg, 1, stops["deadline reached"])
+		require.NotNil(t, report.Paging)
+		require.Equal(t, PagerDutyTrigger, report.Paging.Action, "old runs replay paging on a partial report")
+		require.Len(t, f.Events(), 1)
+	})
+}
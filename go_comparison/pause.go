@@ -0,0 +1,66 @@
+package scanner
+
+// =============================================================================
+// Pausing a scan — holding it before its next batch
+// =============================================================================
+//
+// cancel_scan ends a scan; pause_scan only holds it. A held scan finishes the
+// batch it is in, then waits with status ScanHeld until resume_scan lets the
+// next batch start. Cancelling a held scan, or reaching its deadline, ends
+// the hold the way it ends a batch delay. The last of pause_scan and
+// resume_scan received wins, and ScanProgress.PauseRequested says which
+// that was.
+//
+// The hold is a workflow.Await: it issues no commands, so a scan that is
+// never paused records the same history as before and needs no version
+// gate. Scans with ScanInput.Concurrency keep a window of repos in flight
+// rather than running batches, and the stragglers' second pass runs once
+// every batch has; neither has a place to hold, so pause_scan is recorded
+// but does not hold them.
+// =============================================================================
+
+import (
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Signals that hold and release a scan. Both take an optional payload,
+// such as who sent them, which is only logged.
+const (
+	PauseScanSignal  = "pause_scan"
+	ResumeScanSignal = "resume_scan"
+)
+
+// watchPause calls onChange with true on each pause_scan and false on each
+// resume_scan, for the life of ctx.
+func watchPause(ctx workflow.Context, logger log.Logger, onChange func(paused bool)) {
+	pauseCh := workflow.GetSignalChannel(ctx, PauseScanSignal)
+	resumeCh := workflow.GetSignalChannel(ctx, ResumeScanSignal)
+	workflow.Go(ctx, func(gCtx workflow.Context) {
+		sel := workflow.NewSelector(gCtx)
+		receive := func(paused bool) func(workflow.ReceiveChannel, bool) {
+			return func(c workflow.ReceiveChannel, _ bool) {
+				var by interface{}
+				c.Receive(gCtx, &by)
+				logger.Info("Pause changed", "paused", paused, "by", by)
+				onChange(paused)
+			}
+		}
+		sel.AddReceive(pauseCh, receive(true))
+		sel.AddReceive(resumeCh, receive(false))
+		for {
+			sel.Select(gCtx)
+		}
+	})
+}
+
+// holdWhilePaused holds the scan with status ScanHeld until released
+// returns true, then puts it back to ScanScanning.
+func holdWhilePaused(ctx workflow.Context, progress *ScanProgress, released func() bool) error {
+	progress.Status, progress.UpdatedAt = ScanHeld, workflow.Now(ctx)
+	if err := workflow.Await(ctx, released); err != nil {
+		return err
+	}
+	progress.Status, progress.UpdatedAt = ScanScanning, workflow.Now(ctx)
+	return nil
+}
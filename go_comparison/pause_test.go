@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// onSlowRepoChecks mocks CheckRepoSecurity to take a minute per repo, so
+// signals can land mid-batch.
+func onSlowRepoChecks(env *testsuite.TestWorkflowEnvironment) {
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(time.Minute).Return(compliantUnless())
+}
+
+func TestWorkflowPauseHoldsBeforeNextBatch(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(25))
+	onSlowRepoChecks(env)
+
+	start := env.Now()
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(PauseScanSignal, "alice")
+	}, 30*time.Second)
+	// An hour on, the first batch has finished and nothing has started.
+	env.RegisterDelayedCallback(func() {
+		val, err := env.QueryWorkflow("progress")
+		require.NoError(t, err)
+		var p ScanProgress
+		require.NoError(t, val.Get(&p))
+		require.Equal(t, ScanHeld, p.Status)
+		require.True(t, p.PauseRequested)
+		require.Equal(t, 10, p.ScannedRepos)
+		require.Equal(t, 1, p.Batch)
+		require.Equal(t, 3, p.Batches)
+		env.SignalWorkflow(ResumeScanSignal, nil)
+	}, time.Hour)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Greater(t, env.Now().Sub(start), time.Hour, "the scan waited for resume_scan")
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 25, report.TotalRepos)
+	require.False(t, report.Cancelled)
+}
+
+func TestWorkflowCancelEndsHold(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(25))
+	onSlowRepoChecks(env)
+
+	start := env.Now()
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(PauseScanSignal, nil)
+	}, 30*time.Second)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("cancel_scan", "change freeze")
+	}, time.Hour)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Less(t, env.Now().Sub(start), 2*time.Hour)
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, true, report["cancelled"])
+	require.EqualValues(t, 10, report["repos_scanned_before_cancel"])
+}
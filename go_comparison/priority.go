@@ -0,0 +1,73 @@
+package scanner
+
+// =============================================================================
+// Priority repositories — scanning the critical repos first
+// =============================================================================
+//
+// A scan that is cancelled or stops at its deadline covers the repos it
+// got to first. ScanInput.PriorityRepos and PriorityTopics move the repos
+// that matter most to the front of the list: a repo is a priority repo
+// when its name matches one of PriorityRepos (exact names or path.Match
+// globs, "owner/name" when the pattern has a slash) or it has one of
+// PriorityTopics. Both compare case-insensitively, as GitHub does. The
+// reordering is a stable partition of the listing, so replays scan in the
+// same order. The report counts the priority repos and how many of them
+// were scanned.
+// =============================================================================
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// validatePriority checks that every PriorityRepos pattern is a valid glob.
+func (in ScanInput) validatePriority() error {
+	for _, p := range in.PriorityRepos {
+		if _, err := path.Match(p, ""); err != nil || p == "" {
+			return fmt.Errorf("priority repo pattern %q is not a valid glob", p)
+		}
+	}
+	return nil
+}
+
+// isPriority reports whether r matches PriorityRepos or PriorityTopics.
+func (in ScanInput) isPriority(r RepoInfo) bool {
+	for _, p := range in.PriorityRepos {
+		name := r.Name
+		if strings.Contains(p, "/") {
+			name = r.FullName
+		}
+		if ok, _ := path.Match(strings.ToLower(p), strings.ToLower(name)); ok {
+			return true
+		}
+	}
+	for _, want := range in.PriorityTopics {
+		for _, topic := range r.Topics {
+			if strings.EqualFold(topic, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// prioritize moves the priority repos to the front of repos, keeping the
+// order within both groups, and returns the priority repos' names.
+func (in ScanInput) prioritize(repos []RepoInfo) (ordered []RepoInfo, priority map[string]bool) {
+	if len(in.PriorityRepos) == 0 && len(in.PriorityTopics) == 0 {
+		return repos, nil
+	}
+	priority = make(map[string]bool)
+	for _, r := range repos {
+		if in.isPriority(r) {
+			priority[r.Name] = true
+		}
+	}
+	ordered = append([]RepoInfo(nil), repos...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return priority[ordered[i].Name] && !priority[ordered[j].Name]
+	})
+	return ordered, priority
+}
@@ -0,0 +1,92 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestPrioritizeGlobsAndTopics(t *testing.T) {
+	repos := []RepoInfo{
+		{Name: "docs", FullName: "acme/docs"},
+		{Name: "payments-api", FullName: "acme/payments-api"},
+		{Name: "web", FullName: "acme/web", RepoMetadata: RepoMetadata{Topics: []string{"frontend", "PCI"}}},
+		{Name: "Auth", FullName: "acme/Auth"},
+		{Name: "payments-ui", FullName: "acme/payments-ui"},
+		{Name: "cli", FullName: "acme/cli"},
+	}
+	in := ScanInput{PriorityRepos: []string{"payments-*", "acme/auth"}, PriorityTopics: []string{"pci"}}
+
+	ordered, priority := in.prioritize(repos)
+	var names []string
+	for _, r := range ordered {
+		names = append(names, r.Name)
+	}
+	require.Equal(t, []string{"payments-api", "web", "Auth", "payments-ui", "docs", "cli"}, names,
+		"priority repos first, each group in listing order")
+	require.Len(t, priority, 4)
+	require.Equal(t, "docs", repos[0].Name, "the listing itself is left alone")
+
+	ordered, priority = ScanInput{}.prioritize(repos)
+	require.Equal(t, repos, ordered)
+	require.Nil(t, priority)
+}
+
+func TestValidatePriority(t *testing.T) {
+	require.NoError(t, ScanInput{PriorityRepos: []string{"api", "svc-[a-c]*"}}.validatePriority())
+	require.ErrorContains(t, ScanInput{PriorityRepos: []string{"svc-[a"}}.validatePriority(), "not a valid glob")
+
+	env := newTestEnv(t)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", PriorityRepos: []string{""}})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+}
+
+func TestWorkflowScansPriorityReposFirst(t *testing.T) {
+	repos := fakeRepos(25)
+	for i := 18; i < 25; i++ {
+		repos[i].Topics = []string{"tier-0"}
+	}
+	env := newTestEnv(t)
+	env.OnGetVersion(changeBatchCollection, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+	onListOrgRepos(env, repos)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(time.Minute).Return(compliantUnless())
+
+	// Cancelled during the first batch, which finishes: the seven tier-0
+	// repos and the two matching the glob are in it.
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("cancel_scan", "change freeze")
+	}, 30*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org:            "acme",
+		PriorityRepos:  []string{"repo-00[12]"},
+		PriorityTopics: []string{"tier-0"},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, true, report["cancelled"])
+	require.EqualValues(t, 9, report["priority_repos"])
+	require.EqualValues(t, 9, report["priority_repos_scanned"])
+
+	val, err := env.QueryWorkflow("results_so_far")
+	require.NoError(t, err)
+	var results []RepoSecurityResult
+	require.NoError(t, val.Get(&results))
+	scanned := map[string]bool{}
+	for _, r := range results {
+		scanned[r.Repository] = true
+	}
+	require.True(t, scanned["repo-024"] && scanned["repo-001"] && scanned["repo-000"], "priority repos, then the first of the rest")
+	require.False(t, scanned["repo-003"])
+}
@@ -0,0 +1,298 @@
+package scanner
+
+// =============================================================================
+// Progress cache — a worker-side fallback for the progress query
+// =============================================================================
+//
+// The progress query needs a worker to replay the workflow, so it fails
+// during worker outages and while the workflow task is backing off, which
+// is when operators most want to know how a scan is doing. ProgressCache
+// keeps a best-effort copy of each scan's ScanProgress in the worker,
+// built from what its interceptor sees the scan's activities do:
+//
+//	FetchOrgRepos, FetchTeamRepos    org, status, and the repos listed
+//	FetchOrgReposPage                the same, a page at a time
+//	CheckRepoSecurity                one repo scanned, or one error
+//	CheckRepoSecurityBatch           its repos, as its heartbeats report them
+//	PublishProgress                  the workflow's own ScanProgress, whole
+//
+// The listed repos include ones the workflow then filters out, and only
+// PublishProgress (see progresswebhook.go) brings the compliance counts,
+// so the copy is an approximation between webhook updates. ServeHTTP
+// serves it at /progress/{workflowID} with the time this worker last saw
+// the scan do something, so readers can tell how stale it is.
+//
+// The cache is off unless the worker registers its Interceptor. It lives
+// in the worker process: with several workers, each knows only the
+// activities it ran. Batch children's activities count toward their
+// parent scan when APIUsageTracker's interceptor is registered too, as it
+// carries the parent's run in their headers.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+)
+
+// progressCacheRetention is how long a scan's progress is kept after this
+// worker last saw it.
+const progressCacheRetention = 24 * time.Hour
+
+// ProgressCachePath is where ServeHTTP serves a scan's progress, followed
+// by its workflow ID.
+const ProgressCachePath = "/progress/"
+
+// ProgressCache holds the latest progress of every scan whose activities
+// ran on this worker, by workflow ID. Register Interceptor on the worker.
+type ProgressCache struct {
+	mu    sync.Mutex
+	scans map[string]*cachedScan
+	now   func() time.Time
+}
+
+type cachedScan struct {
+	progress ScanProgress
+	updated  time.Time
+	// batches is what each CheckRepoSecurityBatch activity has counted so
+	// far, so its heartbeats and result are each counted once.
+	batches map[string]batchCount
+}
+
+type batchCount struct{ scanned, errors, disappeared int }
+
+// CachedProgress is a scan's progress as ServeHTTP serves it.
+type CachedProgress struct {
+	Progress ScanProgress `json:"progress"`
+	// UpdatedAt is when this worker last saw the scan do something, and
+	// AgeSeconds how long before the response that was.
+	UpdatedAt  time.Time `json:"updated_at"`
+	AgeSeconds float64   `json:"age_seconds"`
+}
+
+// NewProgressCache returns an empty cache.
+func NewProgressCache() *ProgressCache {
+	return &ProgressCache{scans: make(map[string]*cachedScan), now: time.Now}
+}
+
+// update applies f to the progress of scope's scan. A run other than the
+// cached one replaces it, and scans idle for progressCacheRetention are
+// dropped.
+func (c *ProgressCache) update(scope apiScope, f func(s *cachedScan)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	s, ok := c.scans[scope.WorkflowID]
+	if !ok || s.progress.RunID != scope.RunID {
+		for id, old := range c.scans {
+			if now.Sub(old.updated) > progressCacheRetention {
+				delete(c.scans, id)
+			}
+		}
+		s = &cachedScan{
+			progress: ScanProgress{WorkflowID: scope.WorkflowID, RunID: scope.RunID, StartedAt: now},
+			batches:  make(map[string]batchCount),
+		}
+		c.scans[scope.WorkflowID] = s
+	}
+	f(s)
+	s.updated = now
+	s.progress.UpdatedAt = now
+}
+
+// Progress returns the cached progress of the scan with workflowID.
+func (c *ProgressCache) Progress(workflowID string) (CachedProgress, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.scans[workflowID]
+	if !ok {
+		return CachedProgress{}, false
+	}
+	return CachedProgress{
+		Progress:   s.progress,
+		UpdatedAt:  s.updated,
+		AgeSeconds: c.now().Sub(s.updated).Seconds(),
+	}, true
+}
+
+// ServeHTTP serves the progress of the scan whose workflow ID follows
+// ProgressCachePath as JSON, with Last-Modified set to its UpdatedAt, or
+// 404 when this worker has not seen the scan.
+func (c *ProgressCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	workflowID := strings.TrimPrefix(r.URL.Path, ProgressCachePath)
+	p, ok := c.Progress(workflowID)
+	if workflowID == "" || !ok {
+		http.Error(w, "no progress cached for this workflow ID", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Last-Modified", p.UpdatedAt.UTC().Format(http.TimeFormat))
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// started records what an activity's input says about its scan.
+func (c *ProgressCache) started(scope apiScope, activityType string, args []interface{}) {
+	if len(args) == 0 {
+		return
+	}
+	switch in := args[0].(type) {
+	case ScanInput:
+		if activityType == "FetchOrgRepos" || activityType == "FetchTeamRepos" {
+			c.update(scope, func(s *cachedScan) {
+				s.progress.Org, s.progress.Status = in.Org, ScanFetchingRepos
+			})
+		}
+	case RepoPageInput:
+		if in.Page == 1 {
+			c.update(scope, func(s *cachedScan) {
+				s.progress.Org, s.progress.Status, s.progress.ReposListed = in.Org, ScanFetchingRepos, 0
+			})
+		}
+	case PublishProgressInput:
+		c.update(scope, func(s *cachedScan) {
+			p := in.Update.Progress
+			p.WorkflowID, p.RunID = s.progress.WorkflowID, s.progress.RunID
+			s.progress = p
+		})
+	}
+}
+
+// finished records what an activity's result says about its scan.
+// batchKey identifies a CheckRepoSecurityBatch activity.
+func (c *ProgressCache) finished(scope apiScope, batchKey string, result interface{}) {
+	switch r := result.(type) {
+	case []RepoInfo:
+		c.update(scope, func(s *cachedScan) {
+			s.progress.TotalRepos, s.progress.Status = len(r), ScanScanning
+		})
+	case *RepoPage:
+		if r == nil {
+			return
+		}
+		c.update(scope, func(s *cachedScan) {
+			s.progress.ReposListed += len(r.Repos)
+			if !r.HasMore {
+				s.progress.TotalRepos, s.progress.Status = s.progress.ReposListed, ScanScanning
+			}
+		})
+	case *RepoSecurityResult:
+		if r == nil {
+			return
+		}
+		c.update(scope, func(s *cachedScan) {
+			switch {
+			case r.Disappeared != nil:
+				s.progress.DisappearedRepos++
+			case r.Error != nil:
+				s.progress.Errors++
+			default:
+				s.progress.ScannedRepos++
+			}
+		})
+	case *RepoBatchResult:
+		if r == nil {
+			return
+		}
+		results := make([]*RepoSecurityResult, len(r.Results))
+		for i := range r.Results {
+			results[i] = &r.Results[i]
+		}
+		c.batchProgress(scope, batchKey, results)
+	}
+}
+
+// batchProgress counts the results a batch has that it had not reported
+// before.
+func (c *ProgressCache) batchProgress(scope apiScope, batchKey string, results []*RepoSecurityResult) {
+	var n batchCount
+	for _, r := range results {
+		switch {
+		case r == nil:
+		case r.Disappeared != nil:
+			n.disappeared++
+		case r.Error != nil:
+			n.errors++
+		default:
+			n.scanned++
+		}
+	}
+	c.update(scope, func(s *cachedScan) {
+		seen := s.batches[batchKey]
+		s.progress.ScannedRepos += n.scanned - seen.scanned
+		s.progress.Errors += n.errors - seen.errors
+		s.progress.DisappearedRepos += n.disappeared - seen.disappeared
+		s.batches[batchKey] = n
+	})
+}
+
+// Interceptor returns the worker interceptor that feeds the cache.
+func (c *ProgressCache) Interceptor() interceptor.WorkerInterceptor {
+	return &progressCacheInterceptor{cache: c}
+}
+
+type progressCacheInterceptor struct {
+	interceptor.WorkerInterceptorBase
+	cache *ProgressCache
+}
+
+func (i *progressCacheInterceptor) InterceptActivity(ctx context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	a := &progressCacheActivityInbound{cache: i.cache}
+	a.Next = next
+	return a
+}
+
+type progressCacheActivityInbound struct {
+	interceptor.ActivityInboundInterceptorBase
+	cache *ProgressCache
+	// scope and batchKey are set when the activity starts, for its
+	// heartbeats.
+	scope    apiScope
+	batchKey string
+}
+
+func (a *progressCacheActivityInbound) Init(outbound interceptor.ActivityOutboundInterceptor) error {
+	o := &progressCacheActivityOutbound{inbound: a}
+	o.Next = outbound
+	return a.Next.Init(o)
+}
+
+// ExecuteActivity records the activity's input and, when it succeeds, its
+// result. Failed attempts may be retried, so they are not counted.
+func (a *progressCacheActivityInbound) ExecuteActivity(ctx context.Context, in *interceptor.ExecuteActivityInput) (interface{}, error) {
+	info := activity.GetInfo(ctx)
+	a.scope = activityScope(ctx)
+	a.batchKey = info.WorkflowExecution.ID + "/" + info.ActivityID
+	a.cache.started(a.scope, info.ActivityType.Name, in.Args)
+	result, err := a.Next.ExecuteActivity(ctx, in)
+	if err == nil {
+		a.cache.finished(a.scope, a.batchKey, result)
+	}
+	return result, err
+}
+
+type progressCacheActivityOutbound struct {
+	interceptor.ActivityOutboundInterceptorBase
+	inbound *progressCacheActivityInbound
+}
+
+// RecordHeartbeat counts the repos a CheckRepoSecurityBatch heartbeat
+// reports done.
+func (o *progressCacheActivityOutbound) RecordHeartbeat(ctx context.Context, details ...interface{}) {
+	if len(details) == 1 {
+		if p, ok := details[0].(repoBatchProgress); ok {
+			o.inbound.cache.batchProgress(o.inbound.scope, o.inbound.batchKey, p.Results)
+		}
+	}
+	o.Next.RecordHeartbeat(ctx, details...)
+}
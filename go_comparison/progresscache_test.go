@@ -0,0 +1,188 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/worker"
+)
+
+func TestWorkflowFillsProgressCache(t *testing.T) {
+	cache := NewProgressCache()
+	env := newTestEnv(t)
+	env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{
+		NewAPIUsageTracker().Interceptor(), cache.Interceptor(),
+	}})
+	// Function mocks run through the interceptors; value mocks do not.
+	onListOrgRepos(env, fakeRepos(25))
+	compliant := compliantUnless()
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, org, repo string, cred *Credential, checks []string) (*RepoSecurityResult, error) {
+			if repo == "repo-013" {
+				return scanErrorResult(repo, errors.New("archived mid-scan")), nil
+			}
+			return compliant(ctx, org, repo, cred, checks)
+		})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+	require.NoError(t, env.GetWorkflowError())
+
+	p, ok := cache.Progress("default-test-workflow-id")
+	require.True(t, ok)
+	require.Equal(t, "acme", p.Progress.Org)
+	require.Equal(t, ScanScanning, p.Progress.Status, "the cache cannot tell the scan ended")
+	require.Equal(t, 25, p.Progress.TotalRepos)
+	require.Equal(t, 24, p.Progress.ScannedRepos)
+	require.Equal(t, 1, p.Progress.Errors)
+	require.NotEmpty(t, p.Progress.RunID)
+}
+
+func TestProgressCacheConcurrentUpdates(t *testing.T) {
+	cache := NewProgressCache()
+	scope := apiScope{WorkflowID: "security-scan-acme", RunID: "run-1"}
+	cache.finished(scope, "", fakeRepos(200))
+
+	// Parallel CheckRepoSecurity activities, and batches whose heartbeats
+	// report their results one at a time before they complete.
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := &RepoSecurityResult{Repository: fmt.Sprintf("repo-%03d", i)}
+			if i%10 == 0 {
+				r = scanErrorResult(r.Repository, errors.New("not found"))
+			}
+			cache.finished(scope, "", r)
+		}(i)
+	}
+	for b := 0; b < 5; b++ {
+		wg.Add(1)
+		go func(b int) {
+			defer wg.Done()
+			key := fmt.Sprintf("security-scan-acme/%d", b)
+			results := make([]*RepoSecurityResult, 20)
+			for i := range results {
+				results[i] = &RepoSecurityResult{Repository: fmt.Sprintf("batch-%d-%d", b, i)}
+				cache.batchProgress(scope, key, results)
+			}
+			final := &RepoBatchResult{}
+			for _, r := range results {
+				final.Results = append(final.Results, *r)
+			}
+			cache.finished(scope, key, final)
+		}(b)
+	}
+	wg.Wait()
+
+	p, ok := cache.Progress("security-scan-acme")
+	require.True(t, ok)
+	require.Equal(t, 200, p.Progress.TotalRepos)
+	require.Equal(t, 90+100, p.Progress.ScannedRepos, "each batch's repos counted once")
+	require.Equal(t, 10, p.Progress.Errors)
+}
+
+func TestProgressCacheTakesPublishedProgress(t *testing.T) {
+	cache := NewProgressCache()
+	scope := apiScope{WorkflowID: "security-scan-acme", RunID: "run-1"}
+	cache.started(scope, "PublishProgress", []interface{}{PublishProgressInput{Update: ProgressUpdate{
+		Sequence: 2,
+		Progress: ScanProgress{Org: "acme", TotalRepos: 25, ScannedRepos: 20, CompliantRepos: 18, Status: ScanScanning},
+	}}})
+	cache.finished(scope, "", &RepoSecurityResult{Repository: "repo-020"})
+
+	p, _ := cache.Progress("security-scan-acme")
+	require.Equal(t, 21, p.Progress.ScannedRepos)
+	require.Equal(t, 18, p.Progress.CompliantRepos)
+	require.Equal(t, "run-1", p.Progress.RunID)
+
+	// A new run of the workflow ID starts over.
+	cache.finished(apiScope{WorkflowID: "security-scan-acme", RunID: "run-2"}, "", &RepoSecurityResult{Repository: "repo-000"})
+	p, _ = cache.Progress("security-scan-acme")
+	require.Equal(t, "run-2", p.Progress.RunID)
+	require.Equal(t, 1, p.Progress.ScannedRepos)
+	require.Zero(t, p.Progress.TotalRepos)
+}
+
+func TestProgressCacheServeHTTP(t *testing.T) {
+	now := time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC)
+	cache := NewProgressCache()
+	cache.now = func() time.Time { return now }
+	cache.started(apiScope{WorkflowID: "security-scan-acme", RunID: "run-1"}, "FetchOrgRepos", []interface{}{ScanInput{Org: "acme"}})
+	now = now.Add(90 * time.Second)
+
+	rec := httptest.NewRecorder()
+	cache.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/progress/security-scan-acme", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "Mon, 02 Mar 2026 02:00:00 GMT", rec.Header().Get("Last-Modified"))
+	var got CachedProgress
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, ScanFetchingRepos, got.Progress.Status)
+	require.Equal(t, "acme", got.Progress.Org)
+	require.Equal(t, 90.0, got.AgeSeconds)
+	require.True(t, got.UpdatedAt.Equal(time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC)))
+
+	for _, path := range []string{"/progress/security-scan-other", "/progress/"} {
+		rec = httptest.NewRecorder()
+		cache.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusNotFound, rec.Code, path)
+	}
+	rec = httptest.NewRecorder()
+	cache.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/progress/security-scan-acme", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestProgressCacheDropsIdleScans(t *testing.T) {
+	now := time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC)
+	cache := NewProgressCache()
+	cache.now = func() time.Time { return now }
+	cache.finished(apiScope{WorkflowID: "security-scan-old", RunID: "run-1"}, "", fakeRepos(3))
+	now = now.Add(progressCacheRetention + time.Minute)
+	cache.finished(apiScope{WorkflowID: "security-scan-new", RunID: "run-1"}, "", fakeRepos(3))
+
+	_, ok := cache.Progress("security-scan-old")
+	require.False(t, ok)
+	_, ok = cache.Progress("security-scan-new")
+	require.True(t, ok)
+}
+
+func TestWorkflowFillsProgressCacheFromBatchHeartbeats(t *testing.T) {
+	cache := NewProgressCache()
+	env := newTestEnv(t)
+	env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{cache.Interceptor()}})
+	onListOrgRepos(env, fakeRepos(30))
+	heartbeats := 0
+	env.OnActivity("CheckRepoSecurityBatch", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, in RepoBatchInput) (*RepoBatchResult, error) {
+			progress := repoBatchProgress{Results: make([]*RepoSecurityResult, len(in.Repos))}
+			out := &RepoBatchResult{}
+			for i, repo := range in.Repos {
+				progress.Results[i] = &RepoSecurityResult{Repository: repo}
+				activity.RecordHeartbeat(ctx, progress)
+				heartbeats++
+				out.Results = append(out.Results, *progress.Results[i])
+			}
+			p, _ := cache.Progress("default-test-workflow-id")
+			require.Equal(t, len(in.Repos), p.Progress.ScannedRepos, "counted as the heartbeats arrive")
+			return out, nil
+		})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ActivityBatching: true})
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, 30, heartbeats)
+
+	p, ok := cache.Progress("default-test-workflow-id")
+	require.True(t, ok)
+	require.Equal(t, 30, p.Progress.ScannedRepos, "heartbeats and results counted once")
+}
@@ -0,0 +1,170 @@
+package scanner
+
+// =============================================================================
+// Progress webhook — pushing live progress to a dashboard
+// =============================================================================
+//
+// A dashboard that wants live progress without polling the progress query
+// sets ScanInput.ProgressWebhook. The workflow then runs PublishProgress
+// after every batch (or every EveryRepos repos) and once more when the scan
+// ends, and PublishProgress POSTs a ProgressUpdate to the webhook's URL.
+//
+// Publishing is fire-and-forget: the workflow does not wait for the POST,
+// and the activity has a single attempt, so a slow or dead dashboard never
+// slows the scan; a failure is only logged. Updates can therefore arrive
+// out of order. Each carries the scan's workflow and run IDs and a
+// Sequence that increases by one per update of the run, so receivers keep
+// the update with the highest Sequence and drop the rest.
+//
+// The URL is recorded in the workflow's history, so it should not embed a
+// secret.
+// =============================================================================
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// progressWebhookTimeout bounds one PublishProgress attempt.
+const progressWebhookTimeout = 10 * time.Second
+
+// ProgressWebhook is where and how often a scan publishes its progress.
+type ProgressWebhook struct {
+	// URL receives each ProgressUpdate as a JSON POST.
+	URL string `json:"url"`
+	// EveryRepos, when positive, publishes each time this many more repos
+	// have been checked instead of after every batch.
+	EveryRepos int `json:"every_repos,omitempty"`
+}
+
+// validate checks that URL is an absolute http(s) URL and EveryRepos is
+// not negative.
+func (h *ProgressWebhook) validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("progress webhook URL %q is not an http(s) URL", h.URL)
+	}
+	if h.EveryRepos < 0 {
+		return fmt.Errorf("progress webhook interval must not be negative, got %d repos", h.EveryRepos)
+	}
+	return nil
+}
+
+// ProgressUpdate is the body PublishProgress POSTs.
+type ProgressUpdate struct {
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+	// Sequence starts at 1 and increases by one per update of the run.
+	Sequence int          `json:"sequence"`
+	Progress ScanProgress `json:"progress"`
+}
+
+// PublishProgressInput is the input to PublishProgress.
+type PublishProgressInput struct {
+	URL    string         `json:"url"`
+	Update ProgressUpdate `json:"update"`
+}
+
+// PublishProgress POSTs in.Update to in.URL. Any response but a 2xx is an
+// error; the workflow gives it no retry.
+func (a *Activities) PublishProgress(ctx context.Context, in PublishProgressInput) error {
+	body, err := json.Marshal(in.Update)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("encoding progress: "+err.Error(), ErrTypeInvalidInput, nil)
+	}
+	return a.postJSON(ctx, in.URL, "progress", body)
+}
+
+// postJSON POSTs body to url. Any response but a 2xx is an error naming
+// what was posted.
+func (a *Activities) postJSON(ctx context.Context, url, what string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidInput, nil)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting %s: %w", what, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s webhook: %s", what, sanitizeErrorMessage(responseSummary(resp.StatusCode, msg)))
+}
+
+// progressPublisher schedules the PublishProgress calls of one run. A nil
+// publisher, for scans without a webhook, publishes nothing.
+type progressPublisher struct {
+	hook     ProgressWebhook
+	sequence int
+	// pending is the repos checked since the last update.
+	pending int
+}
+
+// newProgressPublisher returns the publisher for hook, or nil without one.
+func newProgressPublisher(hook *ProgressWebhook) *progressPublisher {
+	if hook == nil {
+		return nil
+	}
+	return &progressPublisher{hook: *hook}
+}
+
+// repoChecked publishes when EveryRepos repos have been checked since the
+// last update.
+func (p *progressPublisher) repoChecked(ctx workflow.Context, progress ScanProgress) {
+	if p == nil || p.hook.EveryRepos <= 0 {
+		return
+	}
+	if p.pending++; p.pending >= p.hook.EveryRepos {
+		p.publish(ctx, progress)
+	}
+}
+
+// batchDone publishes after a batch, unless updates go by EveryRepos.
+func (p *progressPublisher) batchDone(ctx workflow.Context, progress ScanProgress) {
+	if p == nil || p.hook.EveryRepos > 0 {
+		return
+	}
+	p.publish(ctx, progress)
+}
+
+// publish starts PublishProgress with the next Sequence and does not wait
+// for it. progress is passed by value, so later changes are not sent.
+func (p *progressPublisher) publish(ctx workflow.Context, progress ScanProgress) {
+	if p == nil {
+		return
+	}
+	p.sequence++
+	p.pending = 0
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: progressWebhookTimeout,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+	})
+	f := workflow.ExecuteActivity(actCtx, "PublishProgress", PublishProgressInput{
+		URL: p.hook.URL,
+		Update: ProgressUpdate{
+			WorkflowID: progress.WorkflowID,
+			RunID:      progress.RunID,
+			Sequence:   p.sequence,
+			Progress:   progress,
+		},
+	})
+	sequence := p.sequence
+	workflow.Go(ctx, func(gCtx workflow.Context) {
+		if err := f.Get(gCtx, nil); err != nil {
+			workflow.GetLogger(gCtx).Warn("Publishing progress failed", "sequence", sequence, "error", err)
+		}
+	})
+}
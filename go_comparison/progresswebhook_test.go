@@ -0,0 +1,148 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestWorkflowPublishesProgressAfterEachBatch(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(25))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	var mu sync.Mutex
+	var updates []ProgressUpdate
+	env.OnActivity("PublishProgress", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			in := args.Get(1).(PublishProgressInput)
+			require.Equal(t, "https://dash.example.com/scans", in.URL)
+			mu.Lock()
+			defer mu.Unlock()
+			updates = append(updates, in.Update)
+		}).
+		Return(nil)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org:             "acme",
+		ProgressWebhook: &ProgressWebhook{URL: "https://dash.example.com/scans"},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Len(t, updates, 4, "three batches and the end of the scan")
+	// The publishes run concurrently, so they arrive in any order.
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Sequence < updates[j].Sequence })
+	for i, u := range updates {
+		require.Equal(t, i+1, u.Sequence)
+		require.Equal(t, "default-test-workflow-id", u.WorkflowID)
+		require.NotEmpty(t, u.RunID)
+		require.Equal(t, 25, u.Progress.TotalRepos)
+	}
+	require.Equal(t, []int{10, 20, 25, 25}, []int{
+		updates[0].Progress.ScannedRepos, updates[1].Progress.ScannedRepos,
+		updates[2].Progress.ScannedRepos, updates[3].Progress.ScannedRepos,
+	})
+	require.Equal(t, ScanScanning, updates[2].Progress.Status)
+	require.Equal(t, ScanCompleted, updates[3].Progress.Status)
+}
+
+func TestWorkflowPublishesProgressEveryNRepos(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(12))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	// The publishes run concurrently, so they are sorted by sequence.
+	var mu sync.Mutex
+	scannedBySequence := map[int]int{}
+	env.OnActivity("PublishProgress", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			u := args.Get(1).(PublishProgressInput).Update
+			mu.Lock()
+			defer mu.Unlock()
+			scannedBySequence[u.Sequence] = u.Progress.ScannedRepos
+		}).
+		Return(nil)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org:             "acme",
+		ProgressWebhook: &ProgressWebhook{URL: "http://dash.internal/progress", EveryRepos: 5},
+	})
+
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, map[int]int{1: 5, 2: 10, 3: 12}, scannedBySequence, "not after each batch; the last is the end of the scan")
+}
+
+func TestWorkflowIgnoresProgressWebhookFailure(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(25))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	env.OnActivity("PublishProgress", mock.Anything, mock.Anything).
+		After(time.Hour).Return(errors.New("connection refused"))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org:             "acme",
+		ProgressWebhook: &ProgressWebhook{URL: "https://dash.example.com/scans"},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError(), "the webhook never fails the scan")
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 25, report["total_repos"])
+	env.AssertNumberOfCalls(t, "PublishProgress", 4)
+}
+
+func TestWorkflowRejectsBadProgressWebhook(t *testing.T) {
+	for name, hook := range map[string]*ProgressWebhook{
+		"no scheme":      {URL: "dash.example.com/scans"},
+		"other scheme":   {URL: "ftp://dash.example.com/scans"},
+		"negative every": {URL: "https://dash.example.com/scans", EveryRepos: -1},
+	} {
+		env := newTestEnv(t)
+		env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ProgressWebhook: hook})
+		var appErr *temporal.ApplicationError
+		require.True(t, errors.As(env.GetWorkflowError(), &appErr), name)
+		require.Equal(t, ErrTypeInvalidInput, appErr.Type(), name)
+	}
+}
+
+func TestPublishProgress(t *testing.T) {
+	var got ProgressUpdate
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"message":"dashboard is down"}`))
+	}))
+	defer srv.Close()
+
+	a := &Activities{HTTPClient: srv.Client()}
+	in := PublishProgressInput{URL: srv.URL, Update: ProgressUpdate{
+		WorkflowID: "security-scan-acme",
+		RunID:      "run-1",
+		Sequence:   3,
+		Progress:   ScanProgress{Org: "acme", TotalRepos: 25, ScannedRepos: 10, Status: ScanScanning},
+	}}
+	_, err := newActivityEnv(a).ExecuteActivity(a.PublishProgress, in)
+	require.NoError(t, err)
+	require.Equal(t, in.Update.WorkflowID, got.WorkflowID)
+	require.Equal(t, 3, got.Sequence)
+	require.Equal(t, 10, got.Progress.ScannedRepos)
+
+	status = http.StatusBadGateway
+	_, err = newActivityEnv(a).ExecuteActivity(a.PublishProgress, in)
+	require.ErrorContains(t, err, "progress webhook: unexpected status 502: dashboard is down")
+}
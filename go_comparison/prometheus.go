@@ -0,0 +1,255 @@
+package scanner
+
+// =============================================================================
+// Prometheus — a report's numbers as exposition text
+// =============================================================================
+//
+// Some teams only want to scrape the latest compliance numbers. FormatReport
+// with FormatProm writes a report's headline numbers as Prometheus gauges in
+// the text exposition format, labelled with the org and its provider; the
+// starter's --format prom --output writes them where node_exporter's
+// textfile collector picks them up:
+//
+//	scanner_compliance_rate            fully compliant repos over determinate repos, 0 to 1
+//	scanner_check_adoption_ratio       repos passing a check over repos scanned, 0 to 1, labelled check
+//	scanner_check_passing_repos        repos passing a check, labelled check
+//	scanner_repos                      repos scanned
+//	scanner_fully_compliant_repos      repos passing every check
+//	scanner_scan_errors                repos that could not be scanned
+//	scanner_scan_duration_seconds      started_at to completed_at
+//	scanner_scan_completed_timestamp_seconds
+//
+// They are the numbers EmitComplianceMetrics sends to Datadog (metrics.go),
+// without the run ID: a scrape wants one series per org, not one per scan.
+// The worker's /metrics serves them too, for the last completed full-org
+// scan of each org it saved (LatestReports). Like ProgressCache, the copy
+// lives in the worker process: each worker knows the scans it finished
+// since it started. Everything on /metrics, from the API usage counts to
+// the SDK's metrics, is written by writePromGauges (WritePromSources), so
+// it is escaped one way and each metric has a single HELP and TYPE.
+// =============================================================================
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prometheus metric names.
+const (
+	PromComplianceRate = "scanner_compliance_rate"
+	PromCheckAdoption  = "scanner_check_adoption_ratio"
+	PromCheckPassing   = "scanner_check_passing_repos"
+	PromRepos          = "scanner_repos"
+	PromFullyCompliant = "scanner_fully_compliant_repos"
+	PromErrors         = "scanner_scan_errors"
+	PromDuration       = "scanner_scan_duration_seconds"
+	PromCompletedAt    = "scanner_scan_completed_timestamp_seconds"
+)
+
+var (
+	promMetricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	promLabelName  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// promGauge is one gauge and its samples. kind is the metric's TYPE,
+// gauge when empty.
+type promGauge struct {
+	name, help, kind string
+	samples          []promSample
+}
+
+// promSample is one sample of a gauge; labels are name, value pairs. suffix
+// follows the metric's name, e.g. a summary's _sum.
+type promSample struct {
+	labels []string
+	value  float64
+	suffix string
+}
+
+// promGauges are the gauges of reports, in the order above.
+func promGauges(reports []Report) []promGauge {
+	gauges := []promGauge{
+		{name: PromComplianceRate, help: "Fully compliant repos over the repos whose compliance could be determined, 0 to 1."},
+		{name: PromCheckAdoption, help: "Repos passing a check over the repos scanned, 0 to 1."},
+		{name: PromCheckPassing, help: "Repos passing a check."},
+		{name: PromRepos, help: "Repos scanned."},
+		{name: PromFullyCompliant, help: "Repos passing every check."},
+		{name: PromErrors, help: "Repos that could not be scanned."},
+		{name: PromDuration, help: "How long the scan took."},
+		{name: PromCompletedAt, help: "When the scan completed, in seconds since the epoch."},
+	}
+	add := func(i int, value float64, labels ...string) {
+		gauges[i].samples = append(gauges[i].samples, promSample{labels: labels, value: value})
+	}
+	for _, r := range reports {
+		org := []string{"org", r.Org, "provider", providerName(r.Provider)}
+		add(0, r.Rate()/100, org...)
+		for _, c := range r.checkCounts() {
+			check := append(append([]string{}, org...), "check", c.check)
+			if r.TotalRepos > 0 {
+				add(1, float64(c.count)/float64(r.TotalRepos), check...)
+			}
+			add(2, float64(c.count), check...)
+		}
+		add(3, float64(r.TotalRepos), org...)
+		add(4, float64(r.FullyCompliant), org...)
+		add(5, float64(r.Errors), org...)
+		if d, ok := r.Duration(); ok {
+			add(6, d.Seconds(), org...)
+		}
+		if t, err := time.Parse(time.RFC3339, r.CompletedAt); err == nil {
+			add(7, float64(t.Unix()), org...)
+		}
+	}
+	return gauges
+}
+
+// WritePrometheus writes the gauges of reports to w in the Prometheus text
+// exposition format. Gauges without samples are left out.
+func WritePrometheus(w io.Writer, reports ...Report) error {
+	return writePromGauges(w, promGauges(reports))
+}
+
+// PromSource is one part of the worker's /metrics: LatestReports,
+// APIUsageTracker, RateLimiter or PromMetricsHandler.
+type PromSource interface {
+	promGauges() []promGauge
+}
+
+// WritePromSources writes the gauges of every source to w as one
+// exposition, so a metric more than one of them has gets a single HELP
+// and TYPE.
+func WritePromSources(w io.Writer, sources ...PromSource) error {
+	var gauges []promGauge
+	for _, src := range sources {
+		gauges = append(gauges, src.promGauges()...)
+	}
+	return writePromGauges(w, gauges)
+}
+
+// writePromGauges writes gauges, refusing metric and label names the
+// exposition format does not allow. The samples of gauges of the same name
+// are written together under the first one's HELP and TYPE; a later one of
+// another TYPE is refused.
+func writePromGauges(w io.Writer, gauges []promGauge) error {
+	var merged []promGauge
+	byName := map[string]int{}
+	for _, g := range gauges {
+		if !promMetricName.MatchString(g.name) {
+			return fmt.Errorf("invalid Prometheus metric name %q", g.name)
+		}
+		if g.kind == "" {
+			g.kind = "gauge"
+		}
+		i, ok := byName[g.name]
+		if !ok {
+			byName[g.name] = len(merged)
+			merged = append(merged, g)
+			continue
+		}
+		if merged[i].kind != g.kind {
+			return fmt.Errorf("conflicting Prometheus metric %s: both a %s and a %s", g.name, merged[i].kind, g.kind)
+		}
+		merged[i].samples = append(append([]promSample(nil), merged[i].samples...), g.samples...)
+	}
+
+	var b strings.Builder
+	for _, g := range merged {
+		if len(g.samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", g.name, promEscape(g.help, false), g.name, g.kind)
+		for _, s := range g.samples {
+			b.WriteString(g.name + s.suffix)
+			for i := 0; i+1 < len(s.labels); i += 2 {
+				name := s.labels[i]
+				if !promLabelName.MatchString(name) || strings.HasPrefix(name, "__") {
+					return fmt.Errorf("invalid Prometheus label name %q", name)
+				}
+				sep := ","
+				if i == 0 {
+					sep = "{"
+				}
+				fmt.Fprintf(&b, `%s%s="%s"`, sep, name, promEscape(s.labels[i+1], true))
+			}
+			if len(s.labels) > 0 {
+				b.WriteByte('}')
+			}
+			fmt.Fprintf(&b, " %s\n", strconv.FormatFloat(s.value, 'f', -1, 64))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// promEscape escapes a label value (label) or a HELP text: backslashes and
+// line feeds, and in label values double quotes too. Invalid UTF-8 is
+// replaced, as the format requires UTF-8.
+func promEscape(s string, label bool) string {
+	var b strings.Builder
+	for _, r := range strings.ToValidUTF8(s, "\uFFFD") {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '"' && label:
+			b.WriteString(`\"`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// LatestReports keeps the last completed full-org report of each org, so
+// the worker's /metrics can serve their gauges. SaveLastReport records
+// into it when Activities.LatestReports is set.
+type LatestReports struct {
+	mu      sync.Mutex
+	reports map[string]Report
+}
+
+// NewLatestReports returns an empty LatestReports.
+func NewLatestReports() *LatestReports {
+	return &LatestReports{reports: map[string]Report{}}
+}
+
+// Record makes r its org's latest report.
+func (l *LatestReports) Record(r Report) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reports[providerName(r.Provider)+"/"+r.Org] = r
+}
+
+// Reports returns the latest report of each org, by provider and org.
+func (l *LatestReports) Reports() []Report {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	reports := make([]Report, 0, len(l.reports))
+	for _, k := range sortedKeys(l.reports) {
+		reports = append(reports, l.reports[k])
+	}
+	return reports
+}
+
+// WritePrometheus writes the gauges of the latest reports.
+func (l *LatestReports) WritePrometheus(w io.Writer) error {
+	return WritePrometheus(w, l.Reports()...)
+}
+
+func (l *LatestReports) promGauges() []promGauge {
+	return promGauges(l.Reports())
+}
+
+// ServeHTTP serves the gauges of the latest reports.
+func (l *LatestReports) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = l.WritePrometheus(w)
+}
@@ -0,0 +1,112 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WritePrometheus(&out, metricsReport()))
+	require.Equal(t, `# HELP scanner_compliance_rate Fully compliant repos over the repos whose compliance could be determined, 0 to 1.
+# TYPE scanner_compliance_rate gauge
+scanner_compliance_rate{org="acme",provider="github"} 0.75
+# HELP scanner_check_adoption_ratio Repos passing a check over the repos scanned, 0 to 1.
+# TYPE scanner_check_adoption_ratio gauge
+scanner_check_adoption_ratio{org="acme",provider="github",check="secret_scanning"} 1
+scanner_check_adoption_ratio{org="acme",provider="github",check="dependabot"} 0.75
+# HELP scanner_check_passing_repos Repos passing a check.
+# TYPE scanner_check_passing_repos gauge
+scanner_check_passing_repos{org="acme",provider="github",check="secret_scanning"} 4
+scanner_check_passing_repos{org="acme",provider="github",check="dependabot"} 3
+# HELP scanner_repos Repos scanned.
+# TYPE scanner_repos gauge
+scanner_repos{org="acme",provider="github"} 4
+# HELP scanner_fully_compliant_repos Repos passing every check.
+# TYPE scanner_fully_compliant_repos gauge
+scanner_fully_compliant_repos{org="acme",provider="github"} 3
+# HELP scanner_scan_errors Repos that could not be scanned.
+# TYPE scanner_scan_errors gauge
+scanner_scan_errors{org="acme",provider="github"} 1
+# HELP scanner_scan_duration_seconds How long the scan took.
+# TYPE scanner_scan_duration_seconds gauge
+scanner_scan_duration_seconds{org="acme",provider="github"} 200
+# HELP scanner_scan_completed_timestamp_seconds When the scan completed, in seconds since the epoch.
+# TYPE scanner_scan_completed_timestamp_seconds gauge
+scanner_scan_completed_timestamp_seconds{org="acme",provider="github"} 1772460200
+`, out.String())
+
+	b, err := FormatReport(metricsReport(), FormatProm)
+	require.NoError(t, err)
+	require.Equal(t, out.String(), string(b))
+}
+
+func TestWritePrometheusEscapesLabelValues(t *testing.T) {
+	r := Report{Org: "a\\b\"c\nd\xff", Provider: ProviderGitLab, TotalRepos: 1}
+	var out bytes.Buffer
+	require.NoError(t, WritePrometheus(&out, r))
+	require.Contains(t, out.String(), `scanner_repos{org="a\\b\"c\nd`+"\uFFFD"+`",provider="gitlab"} 1`+"\n")
+	require.NotContains(t, out.String(), "scanner_scan_duration_seconds", "gauges without samples are left out")
+}
+
+func TestWritePrometheusRejectsInvalidNames(t *testing.T) {
+	for name, g := range map[string]promGauge{
+		"metric name":    {name: "scanner.repos", samples: []promSample{{value: 1}}},
+		"label name":     {name: "scanner_repos", samples: []promSample{{labels: []string{"org-name", "acme"}, value: 1}}},
+		"reserved label": {name: "scanner_repos", samples: []promSample{{labels: []string{"__name__", "x"}, value: 1}}},
+	} {
+		var out bytes.Buffer
+		require.ErrorContains(t, writePromGauges(&out, []promGauge{g}), "invalid Prometheus", name)
+		require.Empty(t, out.String(), name)
+	}
+	for _, g := range promGauges([]Report{metricsReport()}) {
+		require.Regexp(t, promMetricName, g.name)
+	}
+}
+
+func TestWritePromSourcesWritesEachMetricOnce(t *testing.T) {
+	acme, globex := NewLatestReports(), NewLatestReports()
+	acme.Record(metricsReport())
+	globex.Record(Report{Org: "globex", TotalRepos: 2, FullyCompliant: 2, ComplianceRate: "100.0%"})
+	tracker := NewAPIUsageTracker()
+
+	var out bytes.Buffer
+	require.NoError(t, WritePromSources(&out, acme, tracker, globex))
+	body := out.String()
+	require.Equal(t, 1, strings.Count(body, "# HELP scanner_repos "))
+	require.Equal(t, 1, strings.Count(body, "# TYPE scanner_repos gauge\n"))
+	require.Contains(t, body, "scanner_repos{org=\"acme\",provider=\"github\"} 4\nscanner_repos{org=\"globex\",provider=\"github\"} 2\n")
+	require.Contains(t, body, "# TYPE scanner_api_budget_rejections_total counter\nscanner_api_budget_rejections_total 0\n")
+
+	out.Reset()
+	err := writePromGauges(&out, []promGauge{
+		{name: "scanner_repos", samples: []promSample{{value: 1}}},
+		{name: "scanner_repos", kind: "counter", samples: []promSample{{value: 2}}},
+	})
+	require.ErrorContains(t, err, "conflicting Prometheus metric scanner_repos")
+	require.Empty(t, out.String())
+}
+
+func TestLatestReportsServesLastReportPerOrg(t *testing.T) {
+	latest := NewLatestReports()
+	a := &Activities{LatestReports: latest}
+	older := metricsReport()
+	older.FullyCompliant, older.ComplianceRate = 1, "25.0%"
+	for _, r := range []Report{older, metricsReport(), {Org: "globex", TotalRepos: 2, FullyCompliant: 2, ComplianceRate: "100.0%"}} {
+		require.NoError(t, a.SaveLastReport(context.Background(), "", r.Org, metricsInput(t, r).Report))
+	}
+
+	rec := httptest.NewRecorder()
+	latest.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/plain; version=0.0.4", rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	require.Contains(t, body, "scanner_compliance_rate{org=\"acme\",provider=\"github\"} 0.75\nscanner_compliance_rate{org=\"globex\",provider=\"github\"} 1\n")
+	require.Equal(t, 1, strings.Count(body, `scanner_repos{org="acme"`))
+}
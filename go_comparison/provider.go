@@ -0,0 +1,136 @@
+package scanner
+
+// =============================================================================
+// SCM providers — where the repositories live
+// =============================================================================
+//
+// A Provider lists an org's repositories and runs the per-repo checks against
+// one SCM. GitHub is the original and default implementation; GitLab maps
+// the same checks onto its closest equivalents (see gitlab.go). Both record
+// results with the same SecurityStatus values, so compliance, scoring, and
+// the report do not care where a repo came from.
+//
+// The activities delegate to the provider named by the scan. GitHub scans
+// keep calling CheckRepoSecurity with its original arguments, so existing
+// histories replay and mixed-version workers agree; other providers go
+// through CheckProviderRepo, which takes a RepoCheckInput.
+// =============================================================================
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// Provider names accepted in ScanInput.Provider.
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+// Provider is one SCM the scanner can read repositories from.
+type Provider interface {
+	// ListRepos lists the repositories of input.Org.
+	ListRepos(ctx context.Context, input ScanInput) ([]RepoInfo, error)
+	// CheckRepo runs the selected checks on one repository.
+	CheckRepo(ctx context.Context, in RepoCheckInput) (*RepoSecurityResult, error)
+}
+
+// RepoCheckInput is the input to CheckProviderRepo.
+type RepoCheckInput struct {
+	Provider string `json:"provider"`
+	Org      string `json:"org"`
+	Repo     string `json:"repo"`
+	// Credential was the token, as the JSON name still says.
+	Credential *Credential `json:"token,omitempty"`
+	Checks     []string    `json:"checks"`
+
+	// IncludeEvidence is ScanInput.IncludeEvidence.
+	IncludeEvidence bool `json:"include_evidence,omitempty"`
+}
+
+// providerChecks lists the checks each provider other than GitHub
+// implements; GitHub implements them all.
+var providerChecks = map[string][]string{
+	ProviderGitLab: {CheckSecretScanning, CheckDependabot, CheckCodeScanning, CheckFiles},
+}
+
+// providerName returns name, or ProviderGitHub when it is empty.
+func providerName(name string) string {
+	if name == "" {
+		return ProviderGitHub
+	}
+	return name
+}
+
+// ValidateProvider checks that provider is known and implements every
+// check in checks.
+func ValidateProvider(provider string, checks []string) error {
+	provider = providerName(provider)
+	if provider == ProviderGitHub {
+		return nil
+	}
+	supported, ok := providerChecks[provider]
+	if !ok {
+		return fmt.Errorf("unknown provider %q; known providers: %s, %s", provider, ProviderGitHub, ProviderGitLab)
+	}
+	if missing := subtract(checks, supported); len(missing) > 0 {
+		return fmt.Errorf("provider %s does not support check(s) %s", provider, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// validateOrg checks Org against the naming rules of the scan's provider.
+func (in ScanInput) validateOrg() error {
+	if providerName(in.Provider) == ProviderGitLab {
+		return ValidateGitLabGroup(in.Org)
+	}
+	return ValidateOrgName(in.Org)
+}
+
+// provider returns the implementation for name.
+func (a *Activities) provider(name string) (Provider, error) {
+	switch providerName(name) {
+	case ProviderGitHub:
+		return githubProvider{a}, nil
+	case ProviderGitLab:
+		return gitlabProvider{a}, nil
+	}
+	return nil, temporal.NewNonRetryableApplicationError(
+		fmt.Sprintf("unknown provider %q", name), ErrTypeInvalidInput, nil)
+}
+
+// CheckProviderRepo runs the selected checks on one repository of any
+// provider. The workflow uses it for providers other than GitHub, and for
+// GitHub when it wants evidence.
+func (a *Activities) CheckProviderRepo(ctx context.Context, in RepoCheckInput) (*RepoSecurityResult, error) {
+	p, err := a.provider(in.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if !in.IncludeEvidence {
+		return p.CheckRepo(ctx, in)
+	}
+	ctx, evidence := withEvidence(ctx)
+	result, err := p.CheckRepo(ctx, in)
+	evidence.attach(result)
+	return result, err
+}
+
+// githubProvider is the GitHub implementation, backed by the original
+// activity code.
+type githubProvider struct{ a *Activities }
+
+func (p githubProvider) ListRepos(ctx context.Context, input ScanInput) ([]RepoInfo, error) {
+	return p.a.fetchGitHubRepos(ctx, input)
+}
+
+func (p githubProvider) CheckRepo(ctx context.Context, in RepoCheckInput) (*RepoSecurityResult, error) {
+	result, err := p.a.checkGitHubRepo(ctx, in.Org, in.Repo, in.Credential, in.Checks)
+	if result != nil {
+		result.Provider = ProviderGitHub
+	}
+	return result, err
+}
@@ -0,0 +1,134 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+// TestProviderStatusesAgree runs the same situations through both providers
+// and expects the same provider-neutral statuses, so a report over a mix of
+// GitHub and GitLab repos means one thing.
+func TestProviderStatusesAgree(t *testing.T) {
+	const githubRepo = "/repos/acme-corp/payments-api"
+	const ciPath = gitlabProjectPath + "/repository/files/.gitlab-ci.yml/raw?ref=main"
+
+	tests := []struct {
+		name   string
+		github map[string]fakeResponse
+		gitlab map[string]fakeResponse
+		want   [3]SecurityStatus // secret scanning, dependabot, code scanning
+	}{
+		{
+			name: "every scanner on",
+			github: map[string]fakeResponse{
+				githubRepo:                           {http.StatusOK, "repo_secret_scanning_enabled.json"},
+				githubRepo + "/vulnerability-alerts": {http.StatusNoContent, ""},
+				githubRepo + "/code-scanning/alerts": {http.StatusOK, "code_scanning_alerts.json"},
+			},
+			gitlab: map[string]fakeResponse{
+				gitlabProjectPath: {http.StatusOK, "project.json"},
+				ciPath:            {http.StatusOK, "ci_security.yml"},
+			},
+			want: [3]SecurityStatus{StatusEnabled, StatusEnabled, StatusEnabled},
+		},
+		{
+			name: "no scanner configured",
+			github: map[string]fakeResponse{
+				githubRepo:                           {http.StatusOK, "repo_no_security_and_analysis.json"},
+				githubRepo + "/vulnerability-alerts": {http.StatusNotFound, "not_found.json"},
+				githubRepo + "/code-scanning/alerts": {http.StatusNotFound, "code_scanning_no_analysis.json"},
+			},
+			gitlab: map[string]fakeResponse{
+				gitlabProjectPath: {http.StatusOK, "project.json"},
+				ciPath:            {http.StatusOK, "ci_build_only.yml"},
+			},
+			want: [3]SecurityStatus{StatusDisabled, StatusDisabled, StatusNotConfigured},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, gh := newFakeGitHub(t, tc.github)
+			_, gl := newFakeGitLab(t, tc.gitlab)
+			for _, run := range []struct {
+				a  *Activities
+				in RepoCheckInput
+			}{
+				{gh, RepoCheckInput{Provider: ProviderGitHub, Org: "acme-corp", Repo: "payments-api", Checks: DefaultChecks()}},
+				{gl, RepoCheckInput{Provider: ProviderGitLab, Org: "acme", Repo: "api", Checks: DefaultChecks()}},
+			} {
+				env := newActivityEnv(run.a)
+				val, err := env.ExecuteActivity(run.a.CheckProviderRepo, run.in)
+				require.NoError(t, err)
+
+				var result RepoSecurityResult
+				require.NoError(t, val.Get(&result))
+				require.Equal(t, run.in.Provider, result.Provider)
+				got := [3]SecurityStatus{result.SecretScanning, result.DependabotAlerts, result.CodeScanning}
+				require.Equal(t, tc.want, got, run.in.Provider)
+			}
+		})
+	}
+}
+
+func TestValidateProvider(t *testing.T) {
+	require.NoError(t, ValidateProvider("", []string{CheckActions, CheckAccessAudit}))
+	require.NoError(t, ValidateProvider(ProviderGitLab, append(DefaultChecks(), CheckFiles)))
+	require.ErrorContains(t, ValidateProvider(ProviderGitLab, []string{CheckSecretScanning, CheckActions}), "does not support check(s) actions")
+	require.ErrorContains(t, ValidateProvider("bitbucket", nil), "unknown provider")
+}
+
+func TestWorkflowGitLabProvider(t *testing.T) {
+	env := newTestEnv(t)
+	env.OnActivity("FetchOrgRepos", mock.Anything, mock.MatchedBy(func(in ScanInput) bool {
+		return in.Provider == ProviderGitLab
+	})).Return([]RepoInfo{{Name: "api"}, {Name: "platform/billing"}}, nil)
+	env.OnActivity("CheckProviderRepo", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, in RepoCheckInput) (*RepoSecurityResult, error) {
+			r, _ := compliantUnless("api")(ctx, in.Org, in.Repo, in.Credential, in.Checks)
+			r.Provider = in.Provider
+			return r, nil
+		})
+
+	token := "glpat-test"
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme/platform", Token: &token, Provider: ProviderGitLab})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNotCalled(t, "CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	env.AssertNotCalled(t, "ValidateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	env.AssertNotCalled(t, "CheckActionsSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, ProviderGitLab, report["provider"])
+	require.EqualValues(t, 2, report["total_repos"])
+	require.Equal(t, []interface{}{"api"}, report["non_compliant_repos"])
+}
+
+func TestWorkflowRejectsUnsupportedProviderInput(t *testing.T) {
+	for name, input := range map[string]ScanInput{
+		"unknown provider":       {Org: "acme", Provider: "bitbucket"},
+		"check gitlab lacks":     {Org: "acme", Provider: ProviderGitLab, Checks: []string{CheckActions}},
+		"access audit on gitlab": {Org: "acme", Provider: ProviderGitLab, IncludeAccessAudit: true},
+		"bad group path":         {Org: "acme//platform", Provider: ProviderGitLab},
+	} {
+		input := input
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.ExecuteWorkflow(SecurityScanWorkflow, input)
+
+			require.True(t, env.IsWorkflowCompleted())
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(env.GetWorkflowError(), &appErr), "got %v", env.GetWorkflowError())
+			require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+		})
+	}
+}
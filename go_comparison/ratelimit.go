@@ -0,0 +1,94 @@
+package scanner
+
+// =============================================================================
+// GitHub rate limit status
+// =============================================================================
+//
+// GET /rate_limit reports the token's remaining budget per API and does not
+// count against it. GetRateLimit exposes it as an activity so a workflow can
+// check the budget before or during a scan; the starter calls it directly
+// for --rate-limit.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// RateLimit is the budget of one GitHub API. Limit is 0 when GitHub did not
+// report that API.
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Used      int       `json:"used"`
+	Reset     time.Time `json:"reset"`
+}
+
+// RateLimitStatus is the budget of the REST (core), search, and GraphQL APIs.
+type RateLimitStatus struct {
+	Core    RateLimit `json:"core"`
+	Search  RateLimit `json:"search"`
+	GraphQL RateLimit `json:"graphql"`
+}
+
+// UnmarshalJSON decodes GitHub's reset field, which is Unix seconds.
+func (r *RateLimit) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Limit     int    `json:"limit"`
+		Remaining int    `json:"remaining"`
+		Used      int    `json:"used"`
+		Reset     *int64 `json:"reset"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*r = RateLimit{Limit: raw.Limit, Remaining: raw.Remaining, Used: raw.Used}
+	if raw.Reset != nil {
+		r.Reset = time.Unix(*raw.Reset, 0).UTC()
+	}
+	return nil
+}
+
+// MarshalJSON writes Reset as Unix seconds, the same shape GitHub sends, so
+// a RateLimit survives a round trip through an activity result.
+func (r RateLimit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Limit     int   `json:"limit"`
+		Remaining int   `json:"remaining"`
+		Used      int   `json:"used"`
+		Reset     int64 `json:"reset"`
+	}{r.Limit, r.Remaining, r.Used, r.Reset.Unix()})
+}
+
+// GetRateLimit returns the token's current GitHub rate limit status. A nil
+// token reports the unauthenticated (per-IP) budget.
+func (a *Activities) GetRateLimit(ctx context.Context, cred *Credential) (*RateLimitStatus, error) {
+	headers, err := a.githubHeaders(ctx, cred)
+	if err != nil {
+		return nil, err
+	}
+	status, body, err := a.checkEndpoint(ctx, a.apiURL("/rate_limit"), headers)
+	if err != nil {
+		return nil, fmt.Errorf("fetching rate limit: %w", err)
+	}
+	switch status {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, temporal.NewNonRetryableApplicationError("invalid GitHub API token", "UNAUTHORIZED", nil)
+	default:
+		return nil, statusError("fetching rate limit", status, body)
+	}
+
+	var resp struct {
+		Resources RateLimitStatus `json:"resources"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("parsing rate limit: %w", err)
+	}
+	return &resp.Resources, nil
+}
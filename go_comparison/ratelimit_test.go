@@ -0,0 +1,44 @@
+package scanner
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestGetRateLimit(t *testing.T) {
+	f, a := newFakeGitHub(t, map[string]fakeResponse{
+		"/rate_limit": {http.StatusOK, "rate_limit.json"},
+	})
+	env := newActivityEnv(a)
+	token := "ghp_test"
+
+	val, err := env.ExecuteActivity(a.GetRateLimit, PATCredential(&token))
+	require.NoError(t, err)
+
+	var status RateLimitStatus
+	require.NoError(t, val.Get(&status))
+	reset := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	require.Equal(t, RateLimit{Limit: 5000, Remaining: 3800, Used: 1200, Reset: reset}, status.Core)
+	require.Equal(t, 28, status.Search.Remaining)
+	require.Equal(t, 5000, status.GraphQL.Remaining)
+	require.Equal(t, "token ghp_test", f.Requests()[0].Header.Get("Authorization"))
+}
+
+func TestGetRateLimitBadToken(t *testing.T) {
+	_, a := newFakeGitHub(t, map[string]fakeResponse{
+		"/rate_limit": {http.StatusUnauthorized, "bad_credentials.json"},
+	})
+	env := newActivityEnv(a)
+	token := "ghp_revoked"
+
+	_, err := env.ExecuteActivity(a.GetRateLimit, PATCredential(&token))
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr), "got %v", err)
+	require.Equal(t, "UNAUTHORIZED", appErr.Type())
+	require.True(t, appErr.NonRetryable())
+}
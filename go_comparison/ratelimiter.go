@@ -0,0 +1,286 @@
+package scanner
+
+// =============================================================================
+// Rate limiter — pacing requests per token across worker replicas
+// =============================================================================
+//
+// GitHub's budget belongs to the token, not the worker: three replicas
+// scanning with one token spend it three times as fast. A RateLimiter,
+// consulted by Activities.do before every request, spreads each token's
+// remaining budget evenly over the time left until it resets:
+//
+//	interval = (X-RateLimit-Reset - now) / X-RateLimit-Remaining
+//
+// The limits come from the X-RateLimit headers of the token's last
+// response; until a token has had one, its requests are not paced. Each
+// token (a hash of its Authorization header) has a bucket per API host and
+// resource — core, search and graphql have separate budgets — that lets
+// Burst requests through at once and one more per interval after that.
+//
+// The buckets are a RateBuckets. With RedisRateBuckets every replica takes
+// from the same bucket; LocalRateBuckets keeps them in the process. A
+// request waits for its turn up to MaxWait, and beyond that fails as
+// RATE_LIMIT for Temporal to retry later. When Redis cannot be reached the
+// limiter fails open to its local buckets, logs a warning and counts it in
+// scanner_rate_limiter_fallbacks_total; pacing is then per replica until
+// Redis is back.
+// =============================================================================
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// Defaults for RateLimiter's zero values.
+const (
+	DefaultRateLimitBurst   = 10
+	DefaultRateLimitMaxWait = 30 * time.Second
+)
+
+// RateBuckets are token buckets shared by whoever takes from them.
+type RateBuckets interface {
+	// Take takes a token from key's bucket, which holds burst tokens and
+	// gains one every interval. When it is empty, Take reserves the next
+	// token and returns how long until it is due, or, if that is longer
+	// than maxWait, reserves nothing and returns false.
+	Take(ctx context.Context, key string, interval time.Duration, burst int, maxWait time.Duration) (wait time.Duration, ok bool, err error)
+}
+
+// takeGCRA is a token bucket kept as the time its next token is due (tat,
+// the generic cell rate algorithm's theoretical arrival time). It returns
+// the bucket's new tat and Take's results.
+func takeGCRA(tat, now time.Time, interval time.Duration, burst int, maxWait time.Duration) (time.Time, time.Duration, bool) {
+	if tat.Before(now) {
+		tat = now
+	}
+	wait := tat.Add(interval - time.Duration(burst)*interval).Sub(now)
+	if wait > maxWait {
+		return tat, wait, false
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return tat.Add(interval), wait, true
+}
+
+// LocalRateBuckets keeps buckets in the process.
+type LocalRateBuckets struct {
+	mu   sync.Mutex
+	tats map[string]time.Time
+	now  func() time.Time
+}
+
+// NewLocalRateBuckets returns empty buckets.
+func NewLocalRateBuckets() *LocalRateBuckets {
+	return &LocalRateBuckets{tats: map[string]time.Time{}, now: time.Now}
+}
+
+func (b *LocalRateBuckets) Take(_ context.Context, key string, interval time.Duration, burst int, maxWait time.Duration) (time.Duration, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	tat, wait, ok := takeGCRA(b.tats[key], now, interval, burst, maxWait)
+	b.tats[key] = tat
+	// Buckets that have refilled hold nothing worth keeping.
+	for k, t := range b.tats {
+		if t.Before(now) {
+			delete(b.tats, k)
+		}
+	}
+	return wait, ok, nil
+}
+
+// rateBudget is what a bucket's last response said of its budget.
+type rateBudget struct {
+	remaining int
+	reset     time.Time
+}
+
+// RateLimiter paces requests by the budget GitHub reports for their token.
+type RateLimiter struct {
+	// Buckets are shared with other workers, e.g. RedisRateBuckets; nil
+	// uses Local only.
+	Buckets RateBuckets
+	// Local are this worker's buckets, used when Buckets is nil or fails.
+	// NewRateLimiter sets them.
+	Local RateBuckets
+	// Burst is how many requests a bucket lets through at once; 0 means
+	// DefaultRateLimitBurst.
+	Burst int
+	// MaxWait is the longest a request waits for its turn; 0 means
+	// DefaultRateLimitMaxWait.
+	MaxWait time.Duration
+
+	mu        sync.Mutex
+	budgets   map[string]rateBudget
+	waits     int
+	waited    time.Duration
+	rejected  int
+	fallbacks int
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRateLimiter returns a limiter taking from buckets, or from its own
+// when buckets is nil.
+func NewRateLimiter(buckets RateBuckets) *RateLimiter {
+	return &RateLimiter{Buckets: buckets, Local: NewLocalRateBuckets()}
+}
+
+// rateLimitKey is the bucket of req: its host, token and resource.
+func rateLimitKey(req *http.Request) string {
+	token := "anonymous"
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		token = hex.EncodeToString(sum[:8])
+	}
+	resource := "core"
+	switch path := req.URL.Path; {
+	case strings.HasPrefix(path, "/search/") || strings.Contains(path, "/api/v3/search/"):
+		resource = "search"
+	case strings.HasSuffix(path, "/graphql"):
+		resource = "graphql"
+	}
+	return "scanner:ratelimit:" + req.URL.Host + ":" + token + ":" + resource
+}
+
+// Wait blocks until req may be sent. It fails with a RATE_LIMIT error when
+// that would take longer than MaxWait, and with ctx's error if ctx ends
+// first.
+func (l *RateLimiter) Wait(ctx context.Context, req *http.Request) error {
+	key := rateLimitKey(req)
+	now := l.clock()
+	l.mu.Lock()
+	budget, known := l.budgets[key]
+	l.mu.Unlock()
+	if !known || !budget.reset.After(now) {
+		return nil
+	}
+	maxWait := l.MaxWait
+	if maxWait <= 0 {
+		maxWait = DefaultRateLimitMaxWait
+	}
+
+	var wait time.Duration
+	ok := true
+	if budget.remaining <= 0 {
+		wait = budget.reset.Sub(now)
+		ok = wait <= maxWait
+	} else {
+		interval := budget.reset.Sub(now) / time.Duration(budget.remaining)
+		burst := l.Burst
+		if burst <= 0 {
+			burst = DefaultRateLimitBurst
+		}
+		burst = min(burst, budget.remaining)
+		var err error
+		if l.Buckets != nil {
+			wait, ok, err = l.Buckets.Take(ctx, key, interval, burst, maxWait)
+			if err != nil {
+				l.mu.Lock()
+				l.fallbacks++
+				l.mu.Unlock()
+				if activity.IsActivity(ctx) {
+					activity.GetLogger(ctx).Warn("Shared rate limiter unavailable; pacing requests locally", "error", err)
+				}
+			}
+		}
+		if l.Buckets == nil || err != nil {
+			wait, ok, _ = l.Local.Take(ctx, key, interval, burst, maxWait)
+		}
+	}
+
+	l.mu.Lock()
+	switch {
+	case !ok:
+		l.rejected++
+	case wait > 0:
+		l.waits++
+		l.waited += wait
+	}
+	l.mu.Unlock()
+	if !ok {
+		return newCheckError(ErrorRateLimit, 0, nil,
+			"rate limiter: the token's next request is not due for %s, beyond the %s wait allowed", wait.Round(time.Second), maxWait)
+	}
+	if wait <= 0 {
+		return nil
+	}
+	return l.pause(ctx, wait)
+}
+
+// Observe records the budget the response headers h report for req's
+// bucket. Responses without them leave it as it was.
+func (l *RateLimiter) Observe(req *http.Request, h http.Header) {
+	remaining, err1 := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	reset, err2 := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err1 != nil || err2 != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.budgets == nil {
+		l.budgets = map[string]rateBudget{}
+	}
+	l.budgets[rateLimitKey(req)] = rateBudget{remaining: remaining, reset: time.Unix(reset, 0)}
+}
+
+// Fallbacks is how many requests were paced locally because Buckets
+// failed.
+func (l *RateLimiter) Fallbacks() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fallbacks
+}
+
+// WritePrometheus writes the limiter's counters in the Prometheus text
+// exposition format.
+func (l *RateLimiter) WritePrometheus(w io.Writer) error {
+	return writePromGauges(w, l.promGauges())
+}
+
+func (l *RateLimiter) promGauges() []promGauge {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	counter := func(name, help string, value float64) promGauge {
+		return promGauge{name: name, help: help, kind: "counter", samples: []promSample{{value: value}}}
+	}
+	return []promGauge{
+		counter("scanner_rate_limiter_waits_total", "Requests the rate limiter held back.", float64(l.waits)),
+		counter("scanner_rate_limiter_wait_seconds_total", "Time requests were held back for.", l.waited.Seconds()),
+		counter("scanner_rate_limiter_rejections_total", "Requests failed because their turn was further off than the wait allowed.", float64(l.rejected)),
+		counter("scanner_rate_limiter_fallbacks_total", "Requests paced by this worker alone because the shared buckets failed.", float64(l.fallbacks)),
+	}
+}
+
+func (l *RateLimiter) clock() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
+
+// pause waits d or until ctx ends.
+func (l *RateLimiter) pause(ctx context.Context, d time.Duration) error {
+	if l.sleep != nil {
+		return l.sleep(ctx, d)
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
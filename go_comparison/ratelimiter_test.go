@@ -0,0 +1,227 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeGCRA(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tat := time.Time{}
+	var waits []time.Duration
+	for i := 0; i < 5; i++ {
+		var wait time.Duration
+		var ok bool
+		tat, wait, ok = takeGCRA(tat, now, time.Second, 3, 10*time.Second)
+		require.True(t, ok)
+		waits = append(waits, wait)
+	}
+	require.Equal(t, []time.Duration{0, 0, 0, time.Second, 2 * time.Second}, waits)
+
+	// Past maxWait nothing is reserved.
+	next, wait, ok := takeGCRA(tat, now, time.Second, 3, 2*time.Second)
+	require.False(t, ok)
+	require.Equal(t, 3*time.Second, wait)
+	require.Equal(t, tat, next)
+
+	// A bucket refills while it is not used.
+	_, wait, ok = takeGCRA(tat, now.Add(time.Minute), time.Second, 3, 0)
+	require.True(t, ok)
+	require.Zero(t, wait)
+}
+
+// testLimiter is a limiter on a frozen clock that records its waits
+// instead of sleeping.
+type testLimiter struct {
+	*RateLimiter
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+func newTestLimiter(buckets RateBuckets, now time.Time) *testLimiter {
+	l := &testLimiter{RateLimiter: NewRateLimiter(buckets)}
+	l.now = func() time.Time { return now }
+	l.Local.(*LocalRateBuckets).now = l.now
+	l.sleep = func(_ context.Context, d time.Duration) error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.waits = append(l.waits, d)
+		return nil
+	}
+	return l
+}
+
+// budgetHeaders are the rate limit headers of a response with remaining
+// requests until reset.
+func budgetHeaders(remaining int, reset time.Time) http.Header {
+	h := http.Header{}
+	h.Set("X-RateLimit-Limit", "5000")
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	return h
+}
+
+func githubRequest(t *testing.T, path, token string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest("GET", "https://api.github.com"+path, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	return req
+}
+
+func TestRateLimiterPacesByTheReportedBudget(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	l := newTestLimiter(nil, now)
+	l.Burst = 2
+	ctx := context.Background()
+	req := githubRequest(t, "/repos/acme/api", "ghp_one")
+
+	// Nothing is known of the token before its first response.
+	for i := 0; i < 5; i++ {
+		require.NoError(t, l.Wait(ctx, req))
+	}
+	require.Empty(t, l.waits)
+
+	// 100 requests left for 100s: one a second, after a burst of two.
+	l.Observe(req, budgetHeaders(100, now.Add(100*time.Second)))
+	for i := 0; i < 4; i++ {
+		require.NoError(t, l.Wait(ctx, req))
+	}
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second}, l.waits)
+
+	// Other tokens and other resources have buckets of their own.
+	l.waits = nil
+	require.NoError(t, l.Wait(ctx, githubRequest(t, "/repos/acme/api", "ghp_two")))
+	search := githubRequest(t, "/search/code", "ghp_one")
+	l.Observe(search, budgetHeaders(30, now.Add(60*time.Second)))
+	require.NoError(t, l.Wait(ctx, search))
+	require.Empty(t, l.waits)
+
+	// A spent budget waits for the reset, up to MaxWait.
+	l.Observe(req, budgetHeaders(0, now.Add(10*time.Second)))
+	require.NoError(t, l.Wait(ctx, req))
+	require.Equal(t, []time.Duration{10 * time.Second}, l.waits)
+	l.Observe(req, budgetHeaders(0, now.Add(time.Hour)))
+	err := l.Wait(ctx, req)
+	require.Equal(t, ErrorRateLimit, NewScanError(err).Category)
+	require.Contains(t, err.Error(), "not due for 1h0m0s")
+
+	// Once the reset has passed, the old budget no longer applies.
+	l.Observe(req, budgetHeaders(0, now.Add(-time.Second)))
+	require.NoError(t, l.Wait(ctx, req))
+
+	var metrics strings.Builder
+	require.NoError(t, l.WritePrometheus(&metrics))
+	require.Contains(t, metrics.String(), "scanner_rate_limiter_waits_total 3\n")
+	require.Contains(t, metrics.String(), "scanner_rate_limiter_wait_seconds_total 13\n")
+	require.Contains(t, metrics.String(), "scanner_rate_limiter_rejections_total 1\n")
+}
+
+func TestRateLimiterIsSharedAcrossReplicas(t *testing.T) {
+	srv := newMiniredis(t, "")
+	now := time.Unix(1_700_000_000, 0)
+
+	// Three replicas, each with its own connection pool, see the same
+	// budget: 600 requests in the next 60s, a request every 100ms.
+	var replicas []*testLimiter
+	for i := 0; i < 3; i++ {
+		l := newTestLimiter(newRedisBuckets(t, srv, "", 0), now)
+		l.Burst = 5
+		l.MaxWait = 2 * time.Second
+		l.Observe(githubRequest(t, "/", "ghp_shared"), budgetHeaders(600, now.Add(time.Minute)))
+		replicas = append(replicas, l)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	rejected := 0
+	for _, l := range replicas {
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(l *testLimiter) {
+				defer wg.Done()
+				if err := l.Wait(context.Background(), githubRequest(t, "/repos/acme/api", "ghp_shared")); err != nil {
+					mu.Lock()
+					rejected++
+					mu.Unlock()
+				}
+			}(l)
+		}
+	}
+	wg.Wait()
+
+	// Every admitted request has a turn of its own: five at once, then
+	// one per 100ms up to the 2s wait allowed. The rest are refused.
+	var waits []time.Duration
+	for _, l := range replicas {
+		require.Zero(t, l.Fallbacks())
+		waits = append(waits, l.waits...)
+	}
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	var want []time.Duration
+	for i := 1; i <= 20; i++ {
+		want = append(want, time.Duration(i)*100*time.Millisecond)
+	}
+	require.Equal(t, want, waits)
+	require.Equal(t, 60-5-20, rejected)
+}
+
+func TestRateLimiterFallsBackToLocalBuckets(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	now := time.Unix(1_700_000_000, 0)
+	l := newTestLimiter(&RedisRateBuckets{Addr: addr}, now)
+	l.Burst = 1
+	req := githubRequest(t, "/repos/acme/api", "ghp_one")
+	l.Observe(req, budgetHeaders(10, now.Add(10*time.Second)))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.Wait(context.Background(), req))
+	}
+
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second}, l.waits, "paced by the local buckets")
+	require.Equal(t, 3, l.Fallbacks())
+	var metrics strings.Builder
+	require.NoError(t, l.WritePrometheus(&metrics))
+	require.Contains(t, metrics.String(), "scanner_rate_limiter_fallbacks_total 3\n")
+}
+
+func TestActivitiesWaitForTheRateLimiter(t *testing.T) {
+	requests := 0
+	reset := time.Now().Add(time.Hour)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		for k, v := range budgetHeaders(0, reset) {
+			w.Header()[k] = v
+		}
+		fmt.Fprint(w, `{"resources":{"core":{"limit":5000,"remaining":0,"used":5000,"reset":`+strconv.FormatInt(reset.Unix(), 10)+`}}}`)
+	}))
+	defer srv.Close()
+
+	a := &Activities{HTTPClient: srv.Client(), BaseURL: srv.URL, RateLimiter: NewRateLimiter(nil)}
+	token := "ghp_spent"
+	_, err := a.GetRateLimit(context.Background(), PATCredential(&token))
+	require.NoError(t, err)
+
+	// The budget is spent until the reset, an hour away: the next request
+	// fails without being sent.
+	_, err = a.GetRateLimit(context.Background(), PATCredential(&token))
+	require.Error(t, err)
+	require.Equal(t, ErrorRateLimit, NewScanError(err).Category)
+	require.Equal(t, 1, requests)
+}
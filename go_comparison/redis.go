@@ -0,0 +1,154 @@
+package scanner
+
+// =============================================================================
+// Redis rate buckets
+// =============================================================================
+//
+// RedisRateBuckets keeps RateLimiter's buckets in Redis, so every worker
+// pointed at the same server paces one token together. Each Take is one
+// script run, which Redis executes atomically: the bucket is a single key
+// holding the millisecond its next token is due (see takeGCRA), read and
+// advanced by the script on Redis's own clock, so the workers' clocks do not
+// need to agree. Needs Redis 5 or later, whose scripts may read TIME before
+// they write.
+//
+// The client is go-redis, which pools connections and sends the script by
+// its SHA-1, loading it on a server that does not have it yet. Calls are
+// short and bounded by Timeout: a slow Redis makes the limiter fall back to
+// its local buckets rather than hold up the scan.
+// =============================================================================
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisTimeout bounds each Redis call, connecting included.
+const DefaultRedisTimeout = 500 * time.Millisecond
+
+// redisMaxIdle is how many connections are kept open between calls.
+const redisMaxIdle = 16
+
+// takeScript is takeGCRA in Lua. KEYS[1] is the bucket; ARGV are the
+// interval, burst and max wait, in milliseconds and tokens.
+const takeScript = `
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+local wait = tat + interval - burst * interval - now
+if wait > tonumber(ARGV[3]) then return {0, wait} end
+tat = tat + interval
+redis.call('SET', KEYS[1], tat, 'PX', tat - now + 1000)
+if wait < 0 then wait = 0 end
+return {1, wait}
+`
+
+// takeGCRAScript runs takeScript by its SHA-1, loading it when needed.
+var takeGCRAScript = redis.NewScript(takeScript)
+
+// RedisRateBuckets are RateBuckets in a Redis server.
+type RedisRateBuckets struct {
+	Addr     string
+	Password string
+	DB       int
+	// TLS connects with TLS, verifying the server's certificate.
+	TLS bool
+	// Timeout bounds each call; 0 means DefaultRedisTimeout.
+	Timeout time.Duration
+
+	once   sync.Once
+	client *redis.Client
+}
+
+// NewRedisRateBuckets builds RedisRateBuckets from a URL such as
+// redis://:password@host:6379/0, or rediss:// for TLS. Without a password
+// in the URL, REDIS_PASSWORD is used.
+func NewRedisRateBuckets(rawURL string) (*RedisRateBuckets, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("redis URL %q is not redis:// or rediss://", rawURL)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("redis URL %q has no host", rawURL)
+	}
+	b := &RedisRateBuckets{Addr: u.Host, TLS: u.Scheme == "rediss", Password: os.Getenv("REDIS_PASSWORD")}
+	if u.Port() == "" {
+		b.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if p, ok := u.User.Password(); ok {
+		b.Password = p
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if b.DB, err = strconv.Atoi(db); err != nil || b.DB < 0 {
+			return nil, fmt.Errorf("redis URL %q: database %q is not a number", rawURL, db)
+		}
+	}
+	return b, nil
+}
+
+func (b *RedisRateBuckets) Take(ctx context.Context, key string, interval time.Duration, burst int, maxWait time.Duration) (time.Duration, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout())
+	defer cancel()
+	reply, err := takeGCRAScript.Run(ctx, b.redisClient(), []string{key},
+		max(interval.Milliseconds(), 1), burst, maxWait.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, false, fmt.Errorf("redis: %w", err)
+	}
+	if len(reply) != 2 {
+		return 0, false, fmt.Errorf("redis: unexpected script reply %v", reply)
+	}
+	return time.Duration(reply[1]) * time.Millisecond, reply[0] == 1, nil
+}
+
+// Close closes the connections to the server.
+func (b *RedisRateBuckets) Close() error {
+	return b.redisClient().Close()
+}
+
+func (b *RedisRateBuckets) timeout() time.Duration {
+	if b.Timeout <= 0 {
+		return DefaultRedisTimeout
+	}
+	return b.Timeout
+}
+
+// redisClient returns the client, made on first use from b's settings.
+func (b *RedisRateBuckets) redisClient() *redis.Client {
+	b.once.Do(func() {
+		opts := &redis.Options{
+			Addr:                  b.Addr,
+			Password:              b.Password,
+			DB:                    b.DB,
+			DialTimeout:           b.timeout(),
+			ReadTimeout:           b.timeout(),
+			WriteTimeout:          b.timeout(),
+			ContextTimeoutEnabled: true,
+			MaxIdleConns:          redisMaxIdle,
+			// A retry would only run into the call's timeout; the limiter
+			// falls back to its local buckets instead.
+			MaxRetries: -1,
+		}
+		if b.TLS {
+			host, _, _ := net.SplitHostPort(b.Addr)
+			opts.TLSConfig = &tls.Config{ServerName: host}
+		}
+		b.client = redis.NewClient(opts)
+	})
+	return b.client
+}
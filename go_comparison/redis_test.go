@@ -0,0 +1,164 @@
+package scanner
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// newMiniredis starts a miniredis, which runs takeScript's Lua, with its
+// clock stopped at a fixed time.
+func newMiniredis(t *testing.T, password string) *miniredis.Miniredis {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	srv.SetTime(time.Unix(1_700_000_000, 0))
+	if password != "" {
+		srv.RequireAuth(password)
+	}
+	return srv
+}
+
+// newRedisBuckets is RedisRateBuckets for srv, closed with the test.
+func newRedisBuckets(t *testing.T, srv *miniredis.Miniredis, password string, db int) *RedisRateBuckets {
+	t.Helper()
+	b := &RedisRateBuckets{Addr: srv.Addr(), Password: password, DB: db}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestNewRedisRateBuckets(t *testing.T) {
+	t.Setenv("REDIS_PASSWORD", "from-env")
+	type settings struct {
+		addr, password string
+		db             int
+		tls            bool
+	}
+	for url, want := range map[string]settings{
+		"redis://redis.internal":                {"redis.internal:6379", "from-env", 0, false},
+		"redis://:s3cret@redis.internal:7000/2": {"redis.internal:7000", "s3cret", 2, false},
+		"rediss://redis.internal:6380":          {"redis.internal:6380", "from-env", 0, true},
+	} {
+		got, err := NewRedisRateBuckets(url)
+		require.NoError(t, err, url)
+		require.Equal(t, want, settings{got.Addr, got.Password, got.DB, got.TLS}, url)
+	}
+	for _, url := range []string{"http://redis.internal", "redis://", "redis://redis.internal/db1"} {
+		_, err := NewRedisRateBuckets(url)
+		require.Error(t, err, url)
+	}
+}
+
+func TestRedisRateBucketsTake(t *testing.T) {
+	srv := newMiniredis(t, "s3cret")
+	b := newRedisBuckets(t, srv, "s3cret", 3)
+	ctx := context.Background()
+
+	var waits []time.Duration
+	for i := 0; i < 4; i++ {
+		wait, ok, err := b.Take(ctx, "bucket", 100*time.Millisecond, 2, time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+		waits = append(waits, wait)
+	}
+	require.Equal(t, []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond}, waits)
+
+	// A wait past maxWait reserves nothing.
+	wait, ok, err := b.Take(ctx, "bucket", 100*time.Millisecond, 2, 250*time.Millisecond)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, 300*time.Millisecond, wait)
+	wait, _, _ = b.Take(ctx, "bucket", 100*time.Millisecond, 2, time.Second)
+	require.Equal(t, 300*time.Millisecond, wait)
+
+	// Buckets are per key and per database.
+	wait, _, _ = b.Take(ctx, "other", 100*time.Millisecond, 2, time.Second)
+	require.Zero(t, wait)
+	wait, _, _ = newRedisBuckets(t, srv, "s3cret", 0).Take(ctx, "bucket", 100*time.Millisecond, 2, time.Second)
+	require.Zero(t, wait)
+
+	// Once Redis's clock passes the bucket's due time, it is full again.
+	srv.SetTime(time.Unix(1_700_000_010, 0))
+	wait, ok, _ = b.Take(ctx, "bucket", 100*time.Millisecond, 2, time.Second)
+	require.True(t, ok)
+	require.Zero(t, wait)
+}
+
+// TestRedisRateBucketsConcurrentTake has three workers, each with its own
+// connections, take from one bucket at once: the script hands every
+// admitted caller a turn of its own.
+func TestRedisRateBucketsConcurrentTake(t *testing.T) {
+	srv := newMiniredis(t, "")
+	var workers []*RedisRateBuckets
+	for i := 0; i < 3; i++ {
+		workers = append(workers, newRedisBuckets(t, srv, "", 0))
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		admitted []time.Duration
+		refused  []time.Duration
+		errs     []error
+	)
+	for _, b := range workers {
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(b *RedisRateBuckets) {
+				defer wg.Done()
+				wait, ok, err := b.Take(context.Background(), "github/ghp_shared", 100*time.Millisecond, 5, 2*time.Second)
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err != nil:
+					errs = append(errs, err)
+				case ok:
+					admitted = append(admitted, wait)
+				default:
+					refused = append(refused, wait)
+				}
+			}(b)
+		}
+	}
+	wg.Wait()
+	require.Empty(t, errs)
+
+	// Five at once, then one per 100ms up to the 2s allowed.
+	sort.Slice(admitted, func(i, j int) bool { return admitted[i] < admitted[j] })
+	want := make([]time.Duration, 5)
+	for i := 1; i <= 20; i++ {
+		want = append(want, time.Duration(i)*100*time.Millisecond)
+	}
+	require.Equal(t, want, admitted)
+	require.Len(t, refused, 60-25)
+	for _, wait := range refused {
+		require.Equal(t, 2100*time.Millisecond, wait, "a refused take reserves nothing")
+	}
+}
+
+func TestRedisRateBucketsErrors(t *testing.T) {
+	srv := newMiniredis(t, "s3cret")
+	_, _, err := newRedisBuckets(t, srv, "wrong", 0).Take(context.Background(), "bucket", time.Second, 1, time.Second)
+	require.ErrorContains(t, err, "WRONGPASS")
+	_, _, err = newRedisBuckets(t, srv, "", 0).Take(context.Background(), "bucket", time.Second, 1, time.Second)
+	require.ErrorContains(t, err, "NOAUTH")
+
+	// A server that goes away fails the call, and the next one too.
+	b := newRedisBuckets(t, srv, "s3cret", 0)
+	_, _, err = b.Take(context.Background(), "bucket", time.Second, 1, time.Second)
+	require.NoError(t, err)
+	srv.Close()
+	_, _, err = b.Take(context.Background(), "bucket", time.Second, 1, time.Second)
+	require.Error(t, err)
+	_, _, err = b.Take(context.Background(), "bucket", time.Second, 1, time.Second)
+	require.Error(t, err)
+
+	// A server that comes back is dialled again.
+	require.NoError(t, srv.Restart())
+	_, _, err = b.Take(context.Background(), "bucket", time.Second, 1, time.Second)
+	require.NoError(t, err)
+}
@@ -0,0 +1,175 @@
+package scanner
+
+// =============================================================================
+// Remediation plan — which org-level changes fix the most repos
+// =============================================================================
+//
+// Fixing non-compliant repos one settings page at a time does not scale.
+// Several checks have an org-level switch that turns the control on for
+// every repo at once, so AnalyzeRemediation ranks those switches by how
+// many non-compliant repos they would make compliant:
+//
+//	1. Enable secret scanning for new and existing repositories   fixes 112
+//	2. Enable Dependabot alerts for new and existing repositories fixes  87 more
+//
+// The ranking is greedy: each step is the switch that makes the most repos
+// compliant on top of the steps before it, so a repo failing two checks is
+// counted where its second switch is flipped; ties, and steps that fix
+// nothing by themselves, go to the switch that touches the most repos
+// (Improves).
+//
+// A repo's failures are the ones GenerateReport counts: required checks
+// it fails, less suppressed ones. Errored, disappeared and indeterminate
+// repos are left out, and so are archived repos scanned with
+// IncludeArchived, which no setting reaches until they are unarchived.
+// CODEOWNERS, SECURITY.md and dependabot.yml are files each repo needs;
+// repos that fail one stay in PerRepo whatever the org turns on.
+//
+// The scanner does not read org settings, so a step may name a switch that
+// is already on but does not cover the repos it lists (for example one
+// enabled for new repositories only).
+// =============================================================================
+
+import "context"
+
+// orgRemediations are the org-level changes that fix a failed result, in
+// the order ties are broken.
+var orgRemediations = []struct {
+	result, change string
+}{
+	{CheckSecretScanning, "Enable secret scanning for new and existing repositories at the org level"},
+	{CheckDependabot, "Enable Dependabot alerts for new and existing repositories at the org level"},
+	{CheckSecurityUpdates, "Enable Dependabot security updates for new and existing repositories at the org level"},
+	{CheckCodeScanning, "Enable code scanning default setup for eligible repositories at the org level"},
+	{ResultReadOnlyWorkflowToken, "Set the org's default GITHUB_TOKEN permissions to read-only"},
+}
+
+// RemediationPlan is the report's remediation_plan section.
+type RemediationPlan struct {
+	// NonCompliant is the non-compliant repos the plan is over.
+	NonCompliant int `json:"non_compliant"`
+	// Steps are the org-level changes, best first.
+	Steps []RemediationStep `json:"steps"`
+	// Fixed is the repos all the steps together make compliant.
+	Fixed int `json:"fixed"`
+	// PerRepo is the repos still failing a check no org setting fixes.
+	PerRepo int `json:"per_repo"`
+	// ArchivedSkipped is the non-compliant archived repos left out.
+	ArchivedSkipped int `json:"archived_skipped,omitempty"`
+}
+
+// RemediationStep is one org-level change of a RemediationPlan.
+type RemediationStep struct {
+	Check  string `json:"check"`
+	Change string `json:"change"`
+	// Fixes is the repos this change makes compliant after the steps
+	// before it.
+	Fixes int `json:"fixes"`
+	// Improves is the repos failing Check, compliant afterwards or not.
+	Improves int `json:"improves"`
+}
+
+// AnalyzeRemediation ranks the org-level changes that would fix the scan's
+// non-compliant repos. It takes the BuildReport input and reads the
+// offloaded results the same way, so the workflow runs it as a local
+// activity. GitLab groups have no such switches and get no plan.
+func (a *Activities) AnalyzeRemediation(ctx context.Context, in ReportInput) (*RemediationPlan, error) {
+	if in.Provider == ProviderGitLab {
+		return nil, nil
+	}
+	results := in.Results
+	for _, ref := range in.Refs {
+		chunk, err := a.LoadResults(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunk...)
+	}
+	results = newestResults(results)
+
+	var failures [][]string
+	archived := 0
+	for i := range results {
+		r := &results[i]
+		if r.Error != nil || r.Disappeared != nil {
+			continue
+		}
+		failed, _ := in.Policy.evaluate(r, in.Suppressions)
+		if in.Policy.outcome(r, failed) != OutcomeNonCompliant {
+			continue
+		}
+		if r.Archived {
+			archived++
+			continue
+		}
+		failures = append(failures, failed)
+	}
+	plan := planRemediation(failures)
+	plan.ArchivedSkipped = archived
+	return &plan, nil
+}
+
+// planRemediation ranks orgRemediations over the failed results of each
+// non-compliant repo.
+func planRemediation(failures [][]string) RemediationPlan {
+	plan := RemediationPlan{NonCompliant: len(failures), Steps: []RemediationStep{}}
+	fixable := map[string]bool{}
+	for _, o := range orgRemediations {
+		fixable[o.result] = true
+	}
+
+	// left holds each repo's failures no step has fixed yet; repos failing
+	// a per-repo check can never be fixed and are set aside.
+	var left []map[string]bool
+	improves := map[string]int{}
+	for _, failed := range failures {
+		set := map[string]bool{}
+		perRepo := false
+		for _, f := range failed {
+			set[f] = true
+			improves[f]++
+			perRepo = perRepo || !fixable[f]
+		}
+		if perRepo {
+			plan.PerRepo++
+			continue
+		}
+		left = append(left, set)
+	}
+
+	done := map[string]bool{}
+	for {
+		best, bestFixes := -1, 0
+		for i, o := range orgRemediations {
+			if done[o.result] || improves[o.result] == 0 {
+				continue
+			}
+			fixes := 0
+			for _, set := range left {
+				if len(set) == 1 && set[o.result] {
+					fixes++
+				}
+			}
+			if best < 0 || fixes > bestFixes || (fixes == bestFixes && improves[o.result] > improves[orgRemediations[best].result]) {
+				best, bestFixes = i, fixes
+			}
+		}
+		if best < 0 {
+			break
+		}
+		o := orgRemediations[best]
+		done[o.result] = true
+		plan.Steps = append(plan.Steps, RemediationStep{Check: o.result, Change: o.change, Fixes: bestFixes, Improves: improves[o.result]})
+		plan.Fixed += bestFixes
+
+		kept := left[:0]
+		for _, set := range left {
+			delete(set, o.result)
+			if len(set) > 0 {
+				kept = append(kept, set)
+			}
+		}
+		left = kept
+	}
+	return plan
+}
@@ -0,0 +1,193 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// failing returns n repos' failures, each the given failed results.
+func failing(n int, failed ...string) [][]string {
+	out := make([][]string, n)
+	for i := range out {
+		out[i] = failed
+	}
+	return out
+}
+
+func planSteps(plan RemediationPlan) []string {
+	var steps []string
+	for _, s := range plan.Steps {
+		steps = append(steps, fmt.Sprintf("%s fixes %d improves %d", s.Check, s.Fixes, s.Improves))
+	}
+	return steps
+}
+
+func TestPlanRemediation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		failures [][]string
+		steps    []string
+		fixed    int
+		perRepo  int
+	}{
+		"one check each": {
+			failures: append(failing(112, CheckSecretScanning), failing(87, CheckDependabot)...),
+			steps:    []string{"secret_scanning fixes 112 improves 112", "dependabot fixes 87 improves 87"},
+			fixed:    199,
+		},
+		// Code scanning alone fixes 8 and secret scanning 5; once code
+		// scanning is on, secret scanning also fixes the 10 failing both.
+		"overlap counted once": {
+			failures: append(append(failing(10, CheckSecretScanning, CheckCodeScanning), failing(5, CheckSecretScanning)...), failing(8, CheckCodeScanning)...),
+			steps:    []string{"code_scanning fixes 8 improves 18", "secret_scanning fixes 15 improves 15"},
+			fixed:    23,
+		},
+		// Neither fixes a repo alone: the tie goes to the most improved,
+		// then to the table's order.
+		"nothing fixed alone": {
+			failures: append(failing(6, CheckDependabot, CheckSecurityUpdates), failing(1, CheckSecurityUpdates, ResultReadOnlyWorkflowToken)...),
+			steps: []string{
+				"security_updates fixes 0 improves 7",
+				"dependabot fixes 6 improves 6",
+				"read_only_workflow_token fixes 1 improves 1",
+			},
+			fixed: 7,
+		},
+		// Files are per repo: those repos are never fixed, though the
+		// switch still improves them.
+		"per-repo checks": {
+			failures: append(failing(4, ResultCodeowners, CheckSecretScanning), failing(3, CheckSecretScanning)...),
+			steps:    []string{"secret_scanning fixes 3 improves 7"},
+			fixed:    3,
+			perRepo:  4,
+		},
+		"only per-repo checks": {
+			failures: failing(2, ResultSecurityPolicy),
+			perRepo:  2,
+		},
+		"nothing to fix": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			plan := planRemediation(tc.failures)
+			require.Equal(t, tc.steps, planSteps(plan))
+			require.NotNil(t, plan.Steps)
+			require.Equal(t, len(tc.failures), plan.NonCompliant)
+			require.Equal(t, tc.fixed, plan.Fixed)
+			require.Equal(t, tc.perRepo, plan.PerRepo)
+		})
+	}
+}
+
+// TestPlanRemediationSyntheticOrgs checks the plan's arithmetic over random
+// orgs: every repo is fixed by exactly one step or needs per-repo work,
+// and the first step fixes as many repos as any single switch could.
+func TestPlanRemediationSyntheticOrgs(t *testing.T) {
+	results := []string{CheckSecretScanning, CheckDependabot, CheckSecurityUpdates, CheckCodeScanning, ResultReadOnlyWorkflowToken, ResultCodeowners}
+	rng := rand.New(rand.NewSource(1))
+	for org := 0; org < 200; org++ {
+		// Each result fails at its own rate, as adoption differs by check.
+		rates := make([]float64, len(results))
+		for i := range rates {
+			rates[i] = rng.Float64() * 0.6
+		}
+		var failures [][]string
+		improves := map[string]int{}
+		alone := map[string]int{}
+		for repo := rng.Intn(500); repo > 0; repo-- {
+			var failed []string
+			for i, r := range results {
+				if rng.Float64() < rates[i] {
+					failed = append(failed, r)
+					improves[r]++
+				}
+			}
+			if len(failed) == 0 {
+				continue
+			}
+			if len(failed) == 1 {
+				alone[failed[0]]++
+			}
+			failures = append(failures, failed)
+		}
+
+		plan := planRemediation(failures)
+		sum := 0
+		for _, s := range plan.Steps {
+			sum += s.Fixes
+			require.Equal(t, improves[s.Check], s.Improves, "org %d %s", org, s.Check)
+		}
+		require.Equal(t, plan.Fixed, sum, "org %d", org)
+		require.Equal(t, plan.NonCompliant, plan.Fixed+plan.PerRepo, "org %d", org)
+		if len(plan.Steps) > 0 {
+			best := 0
+			for _, r := range results[:len(results)-1] {
+				best = max(best, alone[r])
+			}
+			require.Equal(t, best, plan.Steps[0].Fixes, "org %d", org)
+		}
+	}
+}
+
+func TestAnalyzeRemediation(t *testing.T) {
+	msg := "404 Not Found"
+	result := func(repo string, secret, dependabot SecurityStatus) RepoSecurityResult {
+		return RepoSecurityResult{Repository: repo, Checks: map[string]CheckResult{
+			CheckSecretScanning: {Status: secret}, CheckDependabot: {Status: dependabot},
+		}}
+	}
+	archived := result("old", StatusDisabled, StatusEnabled)
+	archived.Archived = true
+	errored := result("broken", StatusDisabled, StatusDisabled)
+	errored.Error = &msg
+	in := ReportInput{
+		Policy: CompliancePolicy{RequireSecretScanning: true, RequireDependabot: true},
+		Results: []RepoSecurityResult{
+			result("api", StatusDisabled, StatusEnabled),
+			result("web", StatusDisabled, StatusEnabled),
+			result("cli", StatusEnabled, StatusDisabled),
+			result("mirror", StatusEnabled, StatusDisabled),
+			result("ok", StatusEnabled, StatusEnabled),
+			result("unreadable", StatusNoAccess, StatusEnabled),
+			archived,
+			errored,
+		},
+		Suppressions: []Suppression{{Repo: "mirror", Checks: []string{CheckDependabot}, Justification: "read-only mirror"}},
+	}
+
+	plan, err := (&Activities{}).AnalyzeRemediation(context.Background(), in)
+	require.NoError(t, err)
+	require.Equal(t, []string{"secret_scanning fixes 2 improves 2", "dependabot fixes 1 improves 1"}, planSteps(*plan))
+	require.Equal(t, 3, plan.NonCompliant, "suppressed, indeterminate, archived and errored repos are left out")
+	require.Equal(t, 1, plan.ArchivedSkipped)
+
+	in.Provider = ProviderGitLab
+	plan, err = (&Activities{}).AnalyzeRemediation(context.Background(), in)
+	require.NoError(t, err)
+	require.Nil(t, plan)
+}
+
+func TestWorkflowReportHasRemediationPlan(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(5))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "acme", mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("repo-001", "repo-003"))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.NotNil(t, report.RemediationPlan)
+	require.Equal(t, []string{"code_scanning fixes 2 improves 2"}, planSteps(*report.RemediationPlan))
+
+	var out bytes.Buffer
+	RenderReport(&out, report, RenderOptions{})
+	require.Contains(t, out.String(), "Remediation plan: org settings fix 2 of 2 non-compliant repos\n"+
+		"    1. Enable code scanning default setup for eligible repositories at the org level: fixes 2 (improves 2)\n")
+}
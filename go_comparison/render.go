@@ -0,0 +1,615 @@
+package scanner
+
+// =============================================================================
+// Report rendering — the human-readable view of a Report
+// =============================================================================
+//
+// RenderReport prints the summary first and keeps it on screen: the
+// non-compliant list is cut to DefaultRenderLimit repos unless Verbose is
+// set, in which case every non-compliant repo is listed with the checks it
+// failed. Color is opt-in; callers decide whether the writer is a terminal
+// and whether NO_COLOR or --no-color apply.
+// =============================================================================
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRenderLimit is how many non-compliant repos RenderReport lists
+// when not verbose.
+const DefaultRenderLimit = 20
+
+// RenderOptions controls RenderReport.
+type RenderOptions struct {
+	// Color adds ANSI colors to the counts and headings.
+	Color bool
+	// Verbose lists every non-compliant repo with its failed checks, and
+	// adds the visibility and language breakdowns.
+	Verbose bool
+	// Limit caps the non-compliant repos listed when not verbose; 0 means
+	// DefaultRenderLimit.
+	Limit int
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// paint wraps s in an ANSI color when colors are on.
+func (o RenderOptions) paint(color, s string) string {
+	if !o.Color {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+const reportRule = "============================================================"
+
+// RenderReport writes r as text to w.
+func RenderReport(w io.Writer, r Report, opts RenderOptions) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, reportRule)
+	if r.Cancelled {
+		fmt.Fprintf(w, "  %s: %s\n", opts.paint(ansiYellow, "Security Scan CANCELLED"), r.Org)
+		fmt.Fprintf(w, "  Reason: %s\n", r.CancelReason)
+		fmt.Fprintf(w, "  Partial results (%d of %d repos scanned)\n", r.ReposScannedBeforeCancel, r.TotalRepos)
+		if n := len(r.CancelledInFlightRepos); n > 0 {
+			fmt.Fprintf(w, "  Cancelled in flight: %d repos (not scanned)\n", n)
+		}
+	} else if u := r.APIUsage; u != nil && u.BudgetExceeded {
+		fmt.Fprintf(w, "  %s: %s\n", opts.paint(ansiYellow, "Security Scan STOPPED"), r.Org)
+		fmt.Fprintf(w, "  Reason: API budget of %d requests spent\n", u.MaxRequests)
+		fmt.Fprintf(w, "  Partial results (%d repos scanned, %d not scanned)\n", r.TotalRepos, len(r.UnscannedRepos))
+	} else if r.DeadlineReached {
+		fmt.Fprintf(w, "  %s: %s\n", opts.paint(ansiYellow, "Security Scan STOPPED"), r.Org)
+		fmt.Fprintf(w, "  Reason: deadline %s reached\n", r.Deadline)
+		fmt.Fprintf(w, "  Partial results (%d repos scanned, %d not scanned)\n", r.TotalRepos, len(r.UnscannedRepos))
+	} else {
+		fmt.Fprintf(w, "  %s: %s\n", opts.paint(ansiBold, "Security Scan Complete"), r.Org)
+	}
+	if r.RepoAccessWarning != "" {
+		fmt.Fprintf(w, "  %s: %s\n", opts.paint(ansiYellow, "WARNING"), r.RepoAccessWarning)
+	}
+	if r.Provider != "" && r.Provider != ProviderGitHub {
+		fmt.Fprintf(w, "  Provider: %s\n", r.Provider)
+	}
+	if r.OrgRenamedTo != "" {
+		fmt.Fprintf(w, "  Renamed:  scanned as %s\n", r.OrgRenamedTo)
+	}
+	if len(r.Teams) > 0 {
+		fmt.Fprintf(w, "  Teams:    %s\n", strings.Join(r.Teams, ", "))
+	}
+	if r.RunID != "" {
+		fmt.Fprintf(w, "  Run ID:   %s\n", r.RunID)
+	}
+	if r.InitiatedBy != "" {
+		fmt.Fprintf(w, "  By:       %s\n", r.InitiatedBy)
+	}
+	if r.Reason != "" {
+		fmt.Fprintf(w, "  Reason:   %s\n", r.Reason)
+	}
+	if c := r.ScanConfig; c != nil && len(c.FromRegistry) > 0 {
+		fmt.Fprintf(w, "  Defaults: %s from %s\n", strings.Join(c.FromRegistry, ", "), c.Source)
+	}
+	if len(r.ResumedFromRunIDs) > 0 {
+		fmt.Fprintf(w, "  Resumes:  %s\n", strings.Join(r.ResumedFromRunIDs, ", "))
+	}
+	if d, ok := r.Duration(); ok {
+		fmt.Fprintf(w, "  Duration: %s\n", d)
+	}
+	if r.WorkerVersion != "" {
+		fmt.Fprintf(w, "  Worker:   %s\n", r.WorkerVersion)
+	}
+	fmt.Fprintln(w, reportRule)
+	fmt.Fprintf(w, "  Total repositories:   %d\n", r.TotalRepos)
+	if r.SkippedInactive > 0 {
+		fmt.Fprintf(w, "  Skipped (inactive):   %d\n", r.SkippedInactive)
+	}
+	if r.SkippedByTopic > 0 {
+		fmt.Fprintf(w, "  Skipped (topic):      %d\n", r.SkippedByTopic)
+	}
+	if n := len(r.TimedOutInBatchRepos); n > 0 {
+		fmt.Fprintf(w, "  Timed out in batch:   %d (rescanned in a second pass)\n", n)
+	}
+	if r.PriorityRepos > 0 {
+		fmt.Fprintf(w, "  Priority repos:       %d of %d scanned\n", r.PriorityReposScanned, r.PriorityRepos)
+	}
+	if r.Archived > 0 {
+		fmt.Fprintf(w, "  Archived (skipped):   %d\n", r.Archived)
+	}
+	if r.Disappeared > 0 {
+		fmt.Fprintf(w, "  Disappeared:          %d%s\n", r.Disappeared, disappearedBreakdown(r.DisappearedRepos))
+	}
+	if r.DuplicateRepos > 0 {
+		fmt.Fprintf(w, "  Duplicates dropped:   %d\n", r.DuplicateRepos)
+	}
+	fmt.Fprintf(w, "  Fully compliant:      %s\n", opts.paint(ansiGreen, fmt.Sprint(r.FullyCompliant)))
+	if n := len(r.NonCompliantRepos); n > 0 {
+		fmt.Fprintf(w, "  Non-compliant:        %s\n", opts.paint(ansiRed, fmt.Sprint(n)))
+	}
+	if r.Indeterminate > 0 {
+		fmt.Fprintf(w, "  Indeterminate:        %s (checks not readable; not in the rate)\n", opts.paint(ansiYellow, fmt.Sprint(r.Indeterminate)))
+	}
+	if r.TokenLacksRepoAccess > 0 {
+		fmt.Fprintf(w, "  No repo access:       %d (token not granted the repo)\n", r.TokenLacksRepoAccess)
+	}
+	fmt.Fprintf(w, "  Compliance rate:      %s\n", opts.paint(rateColor(r), r.ComplianceRate))
+	if r.ComplianceScore != nil {
+		fmt.Fprintf(w, "  Compliance score:     %v/100\n", *r.ComplianceScore)
+	}
+	// Only the checks the scan ran appear in the report.
+	for _, line := range []struct {
+		label string
+		count *int
+	}{
+		{"Secret scanning:     ", r.SecretScanningEnabled},
+		{"Dependabot alerts:   ", r.DependabotEnabled},
+		{"Code scanning (GHAS):", r.CodeScanningEnabled},
+		{"CODEOWNERS:          ", r.CodeownersPresent},
+		{"SECURITY.md:         ", r.SecurityPolicyPresent},
+		{"Read-only GH token:  ", r.ReadOnlyWorkflowToken},
+		{"Security updates:    ", r.SecurityUpdatesEnabled},
+		{"dependabot.yml:      ", r.DependabotConfigPresent},
+		{"Secure webhooks:     ", r.WebhooksSecure},
+	} {
+		if line.count != nil {
+			fmt.Fprintf(w, "  %s %d/%d\n", line.label, *line.count, r.TotalRepos)
+		}
+	}
+	if r.ActionsRestricted != nil {
+		enabled := 0
+		if r.ActionsEnabled != nil {
+			enabled = *r.ActionsEnabled
+		}
+		fmt.Fprintf(w, "  Actions restricted:   %d/%d enabled\n", *r.ActionsRestricted, enabled)
+	}
+	if s := webhookIssueCounts(r.WebhookFindings).String(); s != "" {
+		fmt.Fprintf(w, "  Insecure webhooks:    %s\n", s)
+	}
+	if r.Errors > 0 {
+		fmt.Fprintf(w, "  Errors:               %s%s\n", opts.paint(ansiYellow, fmt.Sprint(r.Errors)), errorBreakdown(r.ErrorsByCategory))
+		if n := len(r.RetryLaterRepos); n > 0 {
+			fmt.Fprintf(w, "  Retry later:          %d (rate limits, timeouts, server errors)\n", n)
+		}
+	}
+	if u := r.APIUsage; u != nil {
+		budget := ""
+		if u.MaxRequests > 0 {
+			budget = fmt.Sprintf(" of %d", u.MaxRequests)
+		}
+		fmt.Fprintf(w, "  API requests:         %d%s\n", u.Requests, budget)
+	}
+	if a := r.AccessAudit; a != nil {
+		fmt.Fprintf(w, "  Access audit:         %d repos (%d without admin access)\n", a.ReposAudited, a.ReposNoAccess)
+		fmt.Fprintf(w, "    Deploy keys:        %d (%d flagged)\n", a.DeployKeys, a.FlaggedDeployKeys)
+		fmt.Fprintf(w, "    Outside collaborators with write/admin: %d\n", a.OutsideCollaborators)
+		if len(a.WorstOffenders) > 0 {
+			fmt.Fprintln(w, "    Worst offenders:")
+			for _, o := range a.WorstOffenders {
+				fmt.Fprintf(w, "      - %s (%d flagged keys, %d collaborators)\n",
+					o.Repository, o.FlaggedDeployKeys, o.OutsideCollaborators)
+			}
+		}
+	}
+	if len(r.ResultsBlobRefs) > 0 {
+		fmt.Fprintf(w, "  Full results:         offloaded to %d blob(s)\n", len(r.ResultsBlobRefs))
+		for _, ref := range r.ResultsBlobRefs {
+			fmt.Fprintf(w, "    - %s (%d repos)\n", ref.URI, ref.Count)
+		}
+	}
+	if f := r.Forwarding; f != nil {
+		failed := fmt.Sprint(f.EventsFailed)
+		if f.EventsFailed > 0 {
+			failed = opts.paint(ansiYellow, failed)
+		}
+		fmt.Fprintf(w, "  Forwarded to SIEM:    %d events (%s failed)\n", f.EventsSent, failed)
+	}
+	if p := r.Paging; p != nil {
+		status := "no alert"
+		if p.Open {
+			status = "alert open"
+		}
+		if p.Action != "" {
+			status += " (" + p.Action + " sent)"
+		}
+		if p.Error != "" {
+			status += " " + opts.paint(ansiYellow, "(paging failed)")
+		}
+		fmt.Fprintf(w, "  PagerDuty:            %s\n", status)
+	}
+	if rem := r.Remediation; rem != nil {
+		created, updated := rem.Keys()
+		dryRun := ""
+		if rem.DryRun {
+			dryRun = " (dry run)"
+		}
+		fmt.Fprintf(w, "  Jira issues:          %d created, %d updated%s\n", len(created), len(updated), dryRun)
+		for _, i := range rem.Issues {
+			if i.Key != "" {
+				fmt.Fprintf(w, "    - %s %s (%d repos)\n", i.Key, i.Action, len(i.Repos))
+			}
+		}
+	}
+	if len(r.Delivery) > 0 {
+		delivered := 0
+		for _, d := range r.Delivery {
+			if d.Delivered {
+				delivered++
+			}
+		}
+		fmt.Fprintf(w, "  Delivered:            %d of %d sinks\n", delivered, len(r.Delivery))
+		for _, d := range r.Delivery {
+			switch {
+			case !d.Delivered:
+				fmt.Fprintf(w, "    - %s %s\n", d.Type, opts.paint(ansiYellow, "failed: "+d.Error))
+			case d.Summary != "":
+				fmt.Fprintf(w, "    - %s %s (%s)\n", d.Type, d.Destination, d.Summary)
+			default:
+				fmt.Fprintf(w, "    - %s %s\n", d.Type, d.Destination)
+			}
+		}
+	}
+	if plan := r.RemediationPlan; plan != nil && len(plan.Steps) > 0 {
+		fmt.Fprintf(w, "\n  %s: org settings fix %d of %d non-compliant repos\n",
+			opts.paint(ansiBold, "Remediation plan"), plan.Fixed, plan.NonCompliant)
+		for i, s := range plan.Steps {
+			fmt.Fprintf(w, "    %d. %s: fixes %d (improves %d)\n", i+1, s.Change, s.Fixes, s.Improves)
+		}
+		if plan.PerRepo > 0 {
+			fmt.Fprintf(w, "    %d repos stay non-compliant until they add CODEOWNERS, SECURITY.md or dependabot.yml\n", plan.PerRepo)
+		}
+		if plan.ArchivedSkipped > 0 {
+			fmt.Fprintf(w, "    %d archived repos left out (no setting reaches them)\n", plan.ArchivedSkipped)
+		}
+	}
+	if len(r.WorstScoringRepos) > 0 {
+		fmt.Fprintf(w, "\n  %s:\n", opts.paint(ansiBold, "Lowest scores"))
+		for _, s := range r.WorstScoringRepos {
+			fmt.Fprintf(w, "    - %s (%v)\n", s.Repository, s.Score)
+		}
+	}
+	if len(r.Suppressed) > 0 {
+		fmt.Fprintf(w, "\n  %s:\n", opts.paint(ansiBold, "Suppressed findings"))
+		for _, f := range r.Suppressed {
+			fmt.Fprintf(w, "    - %s %s: %s\n", f.Repository, f.Check, f.Justification)
+		}
+	}
+	if len(r.ExpiredSuppressions) > 0 {
+		fmt.Fprintf(w, "\n  %s:\n", opts.paint(ansiYellow, "Expired suppressions (no longer applied)"))
+		for _, s := range r.ExpiredSuppressions {
+			fmt.Fprintf(w, "    - %s (expired %s): %s\n", s.Repo, s.Expires, s.Justification)
+		}
+	}
+	if caps := r.TokenCapabilities; caps != nil {
+		var limited []CheckCapability
+		for _, c := range caps.Checks {
+			if c.Access == AccessNone || c.Access == AccessPartial {
+				limited = append(limited, c)
+			}
+		}
+		if len(limited) > 0 {
+			fmt.Fprintf(w, "\n  %s:\n", opts.paint(ansiYellow, fmt.Sprintf("Token limits (%s token)", caps.Kind)))
+			for _, c := range limited {
+				access := "partial access"
+				if c.Access == AccessNone {
+					access = "no access"
+				}
+				fmt.Fprintf(w, "    - %s: %s (%s)\n", c.Check, access, c.Reason)
+			}
+		}
+	}
+	if opts.Verbose {
+		renderBreakdowns(w, r, opts)
+	}
+	renderNonCompliant(w, r, opts)
+	renderEvidence(w, r, opts)
+	fmt.Fprintln(w, reportRule)
+}
+
+// renderEvidence prints the evidence appendix after everything else, or
+// says where it is kept.
+func renderEvidence(w io.Writer, r Report, opts RenderOptions) {
+	switch {
+	case r.EvidenceRef != nil:
+		fmt.Fprintf(w, "\n  %s: %d repos, stored at %s\n", opts.paint(ansiBold, "Evidence"), r.EvidenceRef.Count, r.EvidenceRef.URI)
+	case r.EvidenceOmitted > 0:
+		fmt.Fprintf(w, "\n  %s: left out for %d repos (too large for the report, and no blob store)\n",
+			opts.paint(ansiYellow, "Evidence"), r.EvidenceOmitted)
+	case len(r.Evidence) > 0:
+		fmt.Fprintf(w, "\n  %s:\n", opts.paint(ansiBold, "Appendix: evidence"))
+		for _, repo := range sortedKeys(r.Evidence) {
+			fmt.Fprintf(w, "    %s\n", repo)
+			for _, check := range sortedKeys(r.Evidence[repo]) {
+				for _, e := range r.Evidence[repo][check] {
+					fmt.Fprintf(w, "      %-22s %d %s (%s)\n", check, e.HTTPStatus, e.URL, e.RequestedAt.Format(time.RFC3339))
+					if len(e.Fragment) > 0 {
+						fmt.Fprintf(w, "        %s\n", e.Fragment)
+					}
+				}
+			}
+		}
+	}
+}
+
+// renderNonCompliant lists the non-compliant repos, cut to the limit unless
+// verbose. Verbose adds each repo's failed checks when the report has them.
+func renderNonCompliant(w io.Writer, r Report, opts RenderOptions) {
+	repos := append([]string(nil), r.NonCompliantRepos...)
+	if len(repos) == 0 {
+		return
+	}
+	sort.Strings(repos)
+	fmt.Fprintf(w, "\n  %s:\n", opts.paint(ansiRed, "Non-compliant repos"))
+
+	shown := repos[:opts.shown(len(repos))]
+	for _, repo := range shown {
+		failed := r.RepoFailures[repo]
+		if opts.Verbose && len(failed) > 0 {
+			fmt.Fprintf(w, "    - %s: %s\n", repo, strings.Join(failed, ", "))
+			continue
+		}
+		fmt.Fprintf(w, "    - %s\n", repo)
+	}
+	renderMore(w, len(repos)-len(shown))
+}
+
+// shown is how many of a list of n repos to show: all when verbose, else
+// up to the limit.
+func (o RenderOptions) shown(n int) int {
+	limit := o.Limit
+	if limit <= 0 {
+		limit = DefaultRenderLimit
+	}
+	if o.Verbose {
+		return n
+	}
+	return min(n, limit)
+}
+
+// renderMore notes how many repos a list left out.
+func renderMore(w io.Writer, more int) {
+	if more > 0 {
+		fmt.Fprintf(w, "    ... and %d more (use --verbose)\n", more)
+	}
+}
+
+// resultColumns is the order of RenderResults' check columns.
+var resultColumns = []string{
+	CheckSecretScanning, CheckDependabot, CheckCodeScanning,
+	ResultCodeowners, ResultSecurityPolicy,
+	CheckActions, ResultReadOnlyWorkflowToken, CheckAccessAudit,
+	CheckSecurityUpdates, CheckDependabotConfig, CheckWebhooks,
+}
+
+// RenderResults writes per-repo results as text to w: a table of each
+// repo's check statuses and error, non-compliant repos first. The checks
+// are the ones the results have; results from workers that predate
+// per-check results show the default checks. Like the report's
+// non-compliant list, the table is cut to the limit unless verbose.
+func RenderResults(w io.Writer, results []RepoSecurityResult, opts RenderOptions) {
+	fmt.Fprintf(w, "\n  %s: %d repos\n", opts.paint(ansiBold, "Results so far"), len(results))
+	if len(results) == 0 {
+		return
+	}
+	sorted := append([]RepoSecurityResult(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ci, cj := sorted[i].IsFullyCompliant(), sorted[j].IsFullyCompliant()
+		if ci != cj {
+			return !ci
+		}
+		return sorted[i].Repository < sorted[j].Repository
+	})
+
+	present := map[string]bool{}
+	for _, r := range sorted {
+		for key := range r.Checks {
+			present[key] = true
+		}
+	}
+	if len(present) == 0 {
+		for _, key := range DefaultChecks() {
+			present[key] = true
+		}
+	}
+	columns := []string{"repo"}
+	for _, key := range resultColumns {
+		if present[key] {
+			columns = append(columns, key)
+		}
+	}
+	columns = append(columns, "error")
+
+	// Widths are measured before painting so colors don't skew them.
+	shown := sorted[:opts.shown(len(sorted))]
+	rows := make([][]string, len(shown))
+	widths := make([]int, len(columns))
+	for i, c := range columns {
+		widths[i] = len(c)
+	}
+	for i, r := range shown {
+		row := []string{r.Repository}
+		for _, key := range columns[1 : len(columns)-1] {
+			status := string(r.Check(key).Status)
+			if _, ok := r.Checks[key]; !ok && (len(r.Checks) > 0 || r.Error != nil) {
+				status = "-" // not run on this repo
+			}
+			row = append(row, status)
+		}
+		row = append(row, resultError(r))
+		for j, cell := range row {
+			widths[j] = max(widths[j], len(cell))
+		}
+		rows[i] = row
+	}
+
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = opts.paint(ansiBold, strings.ToUpper(c)) + strings.Repeat(" ", widths[i]-len(c))
+	}
+	fmt.Fprintf(w, "    %s\n", strings.TrimRight(strings.Join(header, "  "), " "))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			color := statusColor(SecurityStatus(cell))
+			if j == len(row)-1 {
+				color = ansiYellow
+			}
+			cells[j] = cell
+			if j > 0 && color != "" && cell != "" {
+				cells[j] = opts.paint(color, cell)
+			}
+			cells[j] += strings.Repeat(" ", widths[j]-len(cell))
+		}
+		fmt.Fprintf(w, "    %s\n", strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+	renderMore(w, len(sorted)-len(shown))
+}
+
+// statusColor is the color of a check status in RenderResults.
+func statusColor(s SecurityStatus) string {
+	switch s {
+	case StatusEnabled:
+		return ansiGreen
+	case StatusDisabled, StatusNotConfigured, StatusMisconfigured:
+		return ansiRed
+	case StatusNoAccess, StatusUnknown, StatusError:
+		return ansiYellow
+	}
+	return ""
+}
+
+// resultError is r's error for RenderResults, with its category when known.
+func resultError(r RepoSecurityResult) string {
+	switch {
+	case r.ScanError != nil:
+		return fmt.Sprintf("%s: %s", r.ScanError.Category, r.ScanError.Message)
+	case r.Error != nil:
+		return *r.Error
+	}
+	return ""
+}
+
+// renderBreakdowns writes the report's breakdowns, largest bucket first.
+func renderBreakdowns(w io.Writer, r Report, opts RenderOptions) {
+	for _, section := range []struct {
+		title   string
+		buckets map[string]ComplianceBreakdown
+	}{
+		{"By visibility", r.ByVisibility},
+		{"By language", r.ByLanguage},
+		{"By topic", r.ByTopic},
+	} {
+		if len(section.buckets) == 0 {
+			continue
+		}
+		keys := sortedKeys(section.buckets)
+		sort.SliceStable(keys, func(i, j int) bool { return section.buckets[keys[i]].Repos > section.buckets[keys[j]].Repos })
+		fmt.Fprintf(w, "\n  %s:\n", opts.paint(ansiBold, section.title))
+		for _, key := range keys {
+			b := section.buckets[key]
+			fmt.Fprintf(w, "    %-12s %4d repos  %6s", key, b.Repos, b.ComplianceRate)
+			if len(b.TopNonCompliantRepos) > 0 {
+				fmt.Fprintf(w, "  behind: %s", strings.Join(b.TopNonCompliantRepos, ", "))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// RenderEnterpriseReport writes e as text to w: the enterprise totals, a
+// line per org, and when verbose every org's report in full.
+func RenderEnterpriseReport(w io.Writer, e EnterpriseReport, opts RenderOptions) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, reportRule)
+	if e.Cancelled {
+		fmt.Fprintf(w, "  %s: %s\n", opts.paint(ansiYellow, "Enterprise Scan CANCELLED"), e.Enterprise)
+		fmt.Fprintf(w, "  Reason: %s\n", e.CancelReason)
+	} else {
+		fmt.Fprintf(w, "  %s: %s\n", opts.paint(ansiBold, "Enterprise Scan Complete"), e.Enterprise)
+	}
+	if e.RunID != "" {
+		fmt.Fprintf(w, "  Run ID:   %s\n", e.RunID)
+	}
+	fmt.Fprintln(w, reportRule)
+	fmt.Fprintf(w, "  Organizations:        %d\n", len(e.Orgs))
+	fmt.Fprintf(w, "  Total repositories:   %d\n", e.TotalRepos)
+	fmt.Fprintf(w, "  Fully compliant:      %s\n", opts.paint(ansiGreen, fmt.Sprint(e.FullyCompliant)))
+	if e.NonCompliant > 0 {
+		fmt.Fprintf(w, "  Non-compliant:        %s\n", opts.paint(ansiRed, fmt.Sprint(e.NonCompliant)))
+	}
+	if e.Indeterminate > 0 {
+		fmt.Fprintf(w, "  Indeterminate:        %s (checks not readable; not in the rate)\n", opts.paint(ansiYellow, fmt.Sprint(e.Indeterminate)))
+	}
+	fmt.Fprintf(w, "  Compliance rate:      %s\n", opts.paint(rateColor(e.summary()), e.ComplianceRate))
+	if e.Errors > 0 {
+		fmt.Fprintf(w, "  Errors:               %d\n", e.Errors)
+	}
+
+	fmt.Fprintf(w, "\n  %s:\n", opts.paint(ansiBold, "By organization"))
+	for _, org := range sortedKeys(e.OrgReports) {
+		r := e.OrgReports[org]
+		fmt.Fprintf(w, "    %-20s %4d repos  %6s", org, r.TotalRepos, opts.paint(rateColor(r), r.ComplianceRate))
+		if r.Cancelled {
+			fmt.Fprint(w, "  (cancelled)")
+		}
+		fmt.Fprintln(w)
+	}
+	for _, org := range sortedKeys(e.FailedOrgs) {
+		fmt.Fprintf(w, "    %-20s %s\n", org, opts.paint(ansiRed, "failed: "+e.FailedOrgs[org]))
+	}
+	if len(e.SkippedOrgs) > 0 {
+		fmt.Fprintf(w, "\n  Not scanned (cancelled): %s\n", strings.Join(e.SkippedOrgs, ", "))
+	}
+	fmt.Fprintln(w, reportRule)
+	if opts.Verbose {
+		for _, org := range sortedKeys(e.OrgReports) {
+			RenderReport(w, e.OrgReports[org], opts)
+		}
+	}
+}
+
+// rateColor is green for a fully compliant org, red for one under half
+// compliant, and yellow in between.
+func rateColor(r Report) string {
+	switch rate := r.Rate(); {
+	case r.TotalRepos > 0 && rate >= 100:
+		return ansiGreen
+	case rate < 50:
+		return ansiRed
+	default:
+		return ansiYellow
+	}
+}
+
+// disappearedBreakdown is how many disappeared repos each reason
+// accounts for, e.g. " (deleted 2, transferred 1)".
+func disappearedBreakdown(repos []DisappearedRepo) string {
+	byReason := map[string]int{}
+	for _, d := range repos {
+		byReason[string(d.Reason)]++
+	}
+	return errorBreakdown(byReason)
+}
+
+// errorBreakdown is " (RATE_LIMIT 3, NOT_FOUND 1)", most common first, or
+// empty for reports without errors_by_category.
+func errorBreakdown(byCategory map[string]int) string {
+	if len(byCategory) == 0 {
+		return ""
+	}
+	categories := sortedKeys(byCategory)
+	sort.SliceStable(categories, func(i, j int) bool { return byCategory[categories[i]] > byCategory[categories[j]] })
+	parts := make([]string, len(categories))
+	for i, c := range categories {
+		parts[i] = fmt.Sprintf("%s %d", c, byCategory[c])
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
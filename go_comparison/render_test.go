@@ -0,0 +1,276 @@
+package scanner
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the testdata/render and testdata/issuedigest golden files and report.schema.json")
+
+func count(n int) *int { return &n }
+
+// renderFixture is a 25-repo scan with 22 non-compliant repos, enough to
+// be truncated.
+func renderFixture() Report {
+	r := Report{
+		Org: "acme", TotalRepos: 25, FullyCompliant: 3, ComplianceRate: "12.0%",
+		ComplianceScore: score(61.5), Errors: 1,
+		RunID: "run-1", StartedAt: "2026-03-02T14:00:00Z", CompletedAt: "2026-03-02T14:03:20Z",
+		WorkerVersion:         "v1.4.0",
+		SecretScanningEnabled: count(20), DependabotEnabled: count(12), CodeScanningEnabled: count(5),
+		RepoFailures:      map[string][]string{"api": {}, "web": {}, "docs": {}},
+		WorstScoringRepos: []RepoScore{{Repository: "svc-07", Score: 20}, {Repository: "svc-12", Score: 35.5}},
+		Suppressed:        []SuppressedFinding{{Repository: "mirror", Check: CheckCodeScanning, Justification: "read-only mirror"}},
+	}
+	for i := 1; i <= 22; i++ {
+		repo := fmt.Sprintf("svc-%02d", i)
+		r.NonCompliantRepos = append(r.NonCompliantRepos, repo)
+		r.RepoFailures[repo] = []string{CheckDependabot, CheckCodeScanning}
+	}
+	return r
+}
+
+func TestRenderReportGolden(t *testing.T) {
+	cancelled := renderFixture()
+	cancelled.Cancelled, cancelled.CancelReason, cancelled.ReposScannedBeforeCancel = true, "change freeze", 25
+
+	for name, tc := range map[string]struct {
+		report Report
+		opts   RenderOptions
+	}{
+		"default":   {renderFixture(), RenderOptions{}},
+		"verbose":   {renderFixture(), RenderOptions{Verbose: true}},
+		"color":     {renderFixture(), RenderOptions{Color: true, Limit: 3}},
+		"cancelled": {cancelled, RenderOptions{Limit: 5}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			RenderReport(&buf, tc.report, tc.opts)
+			path := filepath.Join("testdata", "render", name+".golden")
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+			}
+			want, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, string(want), buf.String())
+		})
+	}
+}
+
+func TestRenderReportTruncation(t *testing.T) {
+	var buf bytes.Buffer
+	RenderReport(&buf, renderFixture(), RenderOptions{})
+	require.Contains(t, buf.String(), "    - svc-20\n")
+	require.NotContains(t, buf.String(), "svc-21")
+	require.Contains(t, buf.String(), "... and 2 more (use --verbose)")
+	require.NotContains(t, buf.String(), "\x1b[", "no color unless asked")
+
+	buf.Reset()
+	RenderReport(&buf, renderFixture(), RenderOptions{Verbose: true})
+	require.Contains(t, buf.String(), "    - svc-22: dependabot, code_scanning\n")
+	require.NotContains(t, buf.String(), "more (use --verbose)")
+}
+
+func TestRenderReportTokenLimits(t *testing.T) {
+	r := renderFixture()
+	r.TokenCapabilities = &TokenCapabilities{Kind: TokenClassic, Checks: []CheckCapability{
+		{Check: CheckSecretScanning, Access: AccessFull},
+		{Check: CheckDependabot, Access: AccessNone, Reason: "needs repo scope"},
+	}}
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "Token limits (classic token):\n    - dependabot: no access (needs repo scope)\n")
+	require.NotContains(t, buf.String(), "secret_scanning: full")
+}
+
+func TestRenderReportAPIBudget(t *testing.T) {
+	r := renderFixture()
+	r.APIUsage = &APIUsage{Requests: 75, MaxRequests: 75, BudgetExceeded: true}
+	r.UnscannedRepos = []string{"svc-21", "svc-22"}
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "Security Scan STOPPED: acme\n  Reason: API budget of 75 requests spent\n"+
+		"  Partial results (25 repos scanned, 2 not scanned)\n")
+	require.Contains(t, buf.String(), "  API requests:         75 of 75\n")
+}
+
+func TestRenderReportErrorCategories(t *testing.T) {
+	r := renderFixture()
+	r.Errors = 4
+	r.ErrorsByCategory = map[string]int{"NOT_FOUND": 1, "RATE_LIMIT": 3}
+	r.RetryLaterRepos = []string{"api", "billing", "web"}
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "  Errors:               4 (RATE_LIMIT 3, NOT_FOUND 1)\n"+
+		"  Retry later:          3 (rate limits, timeouts, server errors)\n")
+}
+
+func TestRenderReportCancelledInFlight(t *testing.T) {
+	r := renderFixture()
+	r.Cancelled, r.CancelReason, r.ReposScannedBeforeCancel = true, "change freeze", 20
+	r.CancelledInFlightRepos = []string{"svc-21", "svc-22"}
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "  Partial results (20 of 25 repos scanned)\n  Cancelled in flight: 2 repos (not scanned)\n")
+}
+
+func TestRenderReportIndeterminate(t *testing.T) {
+	r := renderFixture()
+	r.Indeterminate = 2
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "  Indeterminate:        2 (checks not readable; not in the rate)\n")
+}
+
+func TestRenderReportArchived(t *testing.T) {
+	r := renderFixture()
+	r.Archived, r.ArchivedRepos = 2, []string{"legacy", "old-site"}
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "  Archived (skipped):   2\n")
+}
+
+func TestRenderReportDeadlineReached(t *testing.T) {
+	r := renderFixture()
+	r.Deadline, r.DeadlineReached = "2026-03-02T04:00:00Z", true
+	r.UnscannedRepos = []string{"svc-21", "svc-22"}
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "Security Scan STOPPED: acme\n  Reason: deadline 2026-03-02T04:00:00Z reached\n"+
+		"  Partial results (25 repos scanned, 2 not scanned)\n")
+}
+
+func TestRenderReportResumed(t *testing.T) {
+	r := renderFixture()
+	r.RunID, r.ResumedFromRunIDs = "run-3", []string{"run-1", "run-2"}
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "  Run ID:   run-3\n  Resumes:  run-1, run-2\n")
+}
+
+func TestRenderReportForwarding(t *testing.T) {
+	r := renderFixture()
+	r.Forwarding = &ForwardResult{Destination: "https://splunk:8088", Mode: ForwardPerFinding, EventsSent: 9, EventsFailed: 3}
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "  Forwarded to SIEM:    9 events (3 failed)\n")
+}
+
+func TestRenderReportRemediation(t *testing.T) {
+	r := renderFixture()
+	r.Remediation = &RemediationResult{Tracker: "jira", Issues: []RemediationIssue{
+		{Team: "payments", Key: "SEC-7", Action: RemediationUpdated, Repos: []string{"billing"}},
+		{Team: "platform", Key: "SEC-12", Action: RemediationCreated, Repos: []string{"api", "web"}},
+		{Team: "web", Action: RemediationSkipped, Repos: []string{"site"}},
+	}}
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "  Jira issues:          1 created, 1 updated\n"+
+		"    - SEC-7 updated (1 repos)\n    - SEC-12 created (2 repos)\n")
+}
+
+func TestRenderReportPaging(t *testing.T) {
+	r := renderFixture()
+	r.Paging = &PagingResult{DedupKey: "security-scan/github/acme", Open: true, Action: PagerDutyTrigger}
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "  PagerDuty:            alert open (trigger sent)\n")
+
+	r.Paging = &PagingResult{Error: "PagerDuty returned 400"}
+	buf.Reset()
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "  PagerDuty:            no alert (paging failed)\n")
+}
+
+func TestRenderReportDelivery(t *testing.T) {
+	r := renderFixture()
+	r.Delivery = []SinkDelivery{
+		{Type: SinkFile, Destination: "/var/reports/a.json", Delivered: true},
+		{Type: SinkWebhook, Error: "report webhook: 503 Service Unavailable"},
+		{Type: SinkSplunk, Destination: "https://splunk:8088", Delivered: true, Summary: "9 events sent"},
+	}
+	var buf bytes.Buffer
+	RenderReport(&buf, r, RenderOptions{})
+	require.Contains(t, buf.String(), "  Delivered:            2 of 3 sinks\n"+
+		"    - file /var/reports/a.json\n"+
+		"    - webhook failed: report webhook: 503 Service Unavailable\n"+
+		"    - splunk https://splunk:8088 (9 events sent)\n")
+}
+
+func TestRenderEnterpriseReport(t *testing.T) {
+	e := EnterpriseReport{
+		Enterprise: "acme", Orgs: []string{"alpha", "beta", "gone", "late"},
+		TotalRepos: 25, FullyCompliant: 3, NonCompliant: 22, ComplianceRate: "12.0%",
+		OrgReports:  map[string]Report{"alpha": renderFixture()},
+		FailedOrgs:  map[string]string{"gone": "organization 'gone' not found"},
+		SkippedOrgs: []string{"late"},
+		Cancelled:   true, CancelReason: "change freeze",
+	}
+	var buf bytes.Buffer
+	RenderEnterpriseReport(&buf, e, RenderOptions{})
+	out := buf.String()
+	require.Contains(t, out, "  Enterprise Scan CANCELLED: acme\n  Reason: change freeze\n")
+	require.Contains(t, out, "  Organizations:        4\n")
+	require.Contains(t, out, "    alpha                  25 repos   12.0%\n")
+	require.Contains(t, out, "    gone                 failed: organization 'gone' not found\n")
+	require.Contains(t, out, "  Not scanned (cancelled): late\n")
+	require.NotContains(t, out, "Security Scan Complete", "org reports only when verbose")
+
+	buf.Reset()
+	RenderEnterpriseReport(&buf, e, RenderOptions{Verbose: true})
+	require.Contains(t, buf.String(), "Security Scan Complete: acme")
+}
+
+// resultsFixture has a compliant repo, a non-compliant one, one that failed
+// to scan, and one from a worker that predates per-check results.
+func resultsFixture() []RepoSecurityResult {
+	errMsg := "GitHub API returned 404"
+	enabled := CheckResult{Status: StatusEnabled}
+	return []RepoSecurityResult{
+		{Repository: "web", Checks: map[string]CheckResult{
+			CheckSecretScanning: enabled, CheckDependabot: enabled, CheckCodeScanning: enabled,
+		}},
+		{Repository: "api", Checks: map[string]CheckResult{
+			CheckSecretScanning: enabled, CheckDependabot: {Status: StatusDisabled}, CheckCodeScanning: {Status: StatusNotConfigured},
+			ResultCodeowners: {Status: StatusNoAccess},
+		}},
+		{Repository: "gone", Error: &errMsg, ScanError: &ScanError{Category: ErrorNotFound, Message: errMsg}},
+		{Repository: "legacy", SecretScanning: StatusEnabled, DependabotAlerts: StatusEnabled, CodeScanning: StatusDisabled},
+	}
+}
+
+func TestRenderResultsGolden(t *testing.T) {
+	for name, opts := range map[string]RenderOptions{
+		"results":       {Verbose: true},
+		"results-color": {Color: true, Limit: 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			RenderResults(&buf, resultsFixture(), opts)
+			path := filepath.Join("testdata", "render", name+".golden")
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+			}
+			want, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, string(want), buf.String())
+		})
+	}
+}
+
+func TestRenderResultsLegacy(t *testing.T) {
+	var buf bytes.Buffer
+	RenderResults(&buf, resultsFixture()[3:], RenderOptions{})
+	require.Contains(t, buf.String(), "REPO    SECRET_SCANNING  DEPENDABOT  CODE_SCANNING  ERROR\n")
+	require.Contains(t, buf.String(), "legacy  enabled          enabled     disabled\n")
+
+	buf.Reset()
+	RenderResults(&buf, nil, RenderOptions{})
+	require.Equal(t, "\n  Results so far: 0 repos\n", buf.String())
+}
@@ -0,0 +1,90 @@
+package scanner
+
+// =============================================================================
+// Replay tests — the determinism safety net
+// =============================================================================
+//
+// Every JSON file in testdata/histories is the full event history of a
+// SecurityScanWorkflow run. The replayer re-executes the workflow against
+// that history and fails if the current code would issue different commands
+// (a different activity, a different order, a new timer...). That is exactly
+// what would happen to an in-flight scan when a worker is redeployed.
+//
+// THE RULE: if a change makes this test fail, do not regenerate the
+// goldens. Guard the new behaviour with a change ID (see versions.go) so
+// that histories recorded before the change keep taking the old branch:
+//
+//	v := changeVersion(ctx, changeActivityArgs)
+//	if v == workflow.DefaultVersion {
+//	    // old code path, unchanged
+//	} else {
+//	    // new code path
+//	}
+//
+// Then export a history from the new code path and add it alongside the old
+// ones:
+//
+//	go run ./go_comparison/starter --org <org> --export-history go_comparison/testdata/histories/<name>.json
+//
+// Python equivalent: temporalio.worker.Replayer(workflows=[...]).replay_workflow(history)
+// =============================================================================
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.temporal.io/sdk/worker"
+)
+
+func TestReplayGoldenHistories(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "histories", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no golden histories found in testdata/histories")
+	}
+
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			replayer := worker.NewWorkflowReplayer()
+			replayer.RegisterWorkflow(SecurityScanWorkflow)
+			if err := replayer.ReplayWorkflowHistoryFromJSONFile(nil, file); err != nil {
+				t.Fatalf("replay of %s failed; guard the change with workflow.GetVersion: %v", file, err)
+			}
+		})
+	}
+}
+
+// TestGoldenHistoriesAreVersionZero makes sure the goldens were recorded
+// before every change in workflowChanges: without version markers, their
+// replay takes each change's DefaultVersion branch, so the test above
+// covers the old branch of every guarded change.
+func TestGoldenHistoriesAreVersionZero(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "histories", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var history struct {
+			Events []struct {
+				EventType string `json:"eventType"`
+			} `json:"events"`
+		}
+		if err := json.Unmarshal(data, &history); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		for _, e := range history.Events {
+			if e.EventType == "EVENT_TYPE_MARKER_RECORDED" {
+				t.Errorf("%s has a marker; add it as a golden of a newer version instead", file)
+			}
+		}
+	}
+}
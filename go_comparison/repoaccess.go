@@ -0,0 +1,140 @@
+package scanner
+
+// =============================================================================
+// Repo access — fine-grained tokens that can read only some repos
+// =============================================================================
+//
+// A classic token reads every repo its user can, so a listed repo whose GET
+// answers 404 has been deleted since the listing (see disappeared.go).
+// Fine-grained personal access tokens and GitHub App tokens are granted
+// repo by repo: the org's listing can name repos the token was never given,
+// and GitHub answers 404 for each of them. Reading those as deleted, or
+// their checks as disabled, misreports the org.
+//
+// For such tokens — github_pat_, ghs_ and ghu_ — checkGitHubRepo marks a
+// 404 result TokenLacksRepoAccess, with every selected check
+// StatusNoAccess, and the workflow runs no further checks on it. The
+// repos are indeterminate, so they stay out of the compliance rate, and the
+// report counts them in token_lacks_repo_access. When more than
+// RepoAccessWarnFraction of the scanned repos are inaccessible, the report
+// carries a repo_access_warning, shown at the top of the rendered report:
+// the token was most likely given the wrong repositories.
+//
+// A repo deleted during the scan reads the same way to such a token; the
+// token cannot tell the two apart.
+//
+// ValidateToken predicts this before the scan: it GETs a sample of the
+// org's repos with a fine-grained token and records how many it could not
+// read in token_capabilities.repo_access.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RepoAccessWarnFraction is the share of repos a token may be unable to
+// read before the report warns that its repository selection is wrong.
+const RepoAccessWarnFraction = 0.2
+
+// repoAccessSampleSize is how many repos ValidateToken tries to read.
+const repoAccessSampleSize = 10
+
+// repoAccessMessage is the Message of the checks of a repo the token
+// cannot read.
+const repoAccessMessage = "token lacks access to this repository"
+
+// perRepoTokenPrefixes are the prefixes of tokens granted repo by repo.
+var perRepoTokenPrefixes = []string{"github_pat_", "ghs_", "ghu_"}
+
+// perRepoToken reports whether headers authenticate with a token that may
+// be granted only some of the org's repos.
+func perRepoToken(headers map[string]string) bool {
+	token := strings.TrimPrefix(strings.TrimPrefix(headers["Authorization"], "token "), "Bearer ")
+	for _, p := range perRepoTokenPrefixes {
+		if strings.HasPrefix(token, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// setTokenLacksRepoAccess marks r as a repo the token cannot read: every
+// one of checks is StatusNoAccess.
+func (r *RepoSecurityResult) setTokenLacksRepoAccess(checks []string) {
+	r.TokenLacksRepoAccess = true
+	for _, c := range newCheckSet(checks).names() {
+		r.setNoAccess(c, repoAccessMessage)
+	}
+}
+
+// repoAccessWarning is the warning for inaccessible of total repos, or ""
+// when there are not enough of them to warn about.
+func repoAccessWarning(inaccessible, total int) string {
+	if total == 0 || float64(inaccessible) <= RepoAccessWarnFraction*float64(total) {
+		return ""
+	}
+	return fmt.Sprintf("the token cannot read %d of %d repos (%.0f%%); check the repositories a fine-grained token or GitHub App installation was granted",
+		inaccessible, total, float64(inaccessible)/float64(total)*100)
+}
+
+// RepoAccessSample is what ValidateToken found reading a sample of the
+// org's repos with a fine-grained token.
+type RepoAccessSample struct {
+	Sampled      int      `json:"sampled"`
+	Inaccessible []string `json:"inaccessible,omitempty"`
+	// Warning predicts the report's repo_access_warning.
+	Warning string `json:"warning,omitempty"`
+}
+
+// RepoAccessWarning is the sample's warning, or "" when there is none.
+func (c *TokenCapabilities) RepoAccessWarning() string {
+	if c == nil || c.RepoAccess == nil {
+		return ""
+	}
+	return c.RepoAccess.Warning
+}
+
+// sampleRepoAccess GETs the first repoAccessSampleSize of org's repos. It
+// returns the sample, nil when org lists no repos, and the first repo the
+// token could read, "" when there was none.
+func (a *Activities) sampleRepoAccess(ctx context.Context, org string, headers map[string]string) (*RepoAccessSample, string, error) {
+	status, body, err := a.checkEndpoint(ctx, a.apiURL("/orgs/%s/repos?per_page=%d", org, repoAccessSampleSize), headers)
+	if err != nil {
+		return nil, "", fmt.Errorf("validating token: %w", err)
+	}
+	var repos []struct {
+		Name string `json:"name"`
+	}
+	if status == http.StatusOK {
+		if err := json.Unmarshal(body, &repos); err != nil {
+			return nil, "", fmt.Errorf("parsing repos of %s: %w", org, err)
+		}
+	}
+	if len(repos) == 0 {
+		return nil, "", nil
+	}
+	if len(repos) > repoAccessSampleSize {
+		repos = repos[:repoAccessSampleSize]
+	}
+
+	sample := &RepoAccessSample{Sampled: len(repos)}
+	readable := ""
+	for _, r := range repos {
+		status, _, err := a.checkEndpoint(ctx, a.apiURL("/repos/%s/%s", org, r.Name), headers)
+		if err != nil {
+			return nil, "", fmt.Errorf("validating token: %w", err)
+		}
+		switch {
+		case status == http.StatusNotFound:
+			sample.Inaccessible = append(sample.Inaccessible, r.Name)
+		case readable == "":
+			readable = r.Name
+		}
+	}
+	sample.Warning = repoAccessWarning(len(sample.Inaccessible), sample.Sampled)
+	return sample, readable, nil
+}
@@ -0,0 +1,143 @@
+package scanner
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRepoSecurityTokenLacksRepoAccess(t *testing.T) {
+	f, a := newFakeGitHub(t, map[string]fakeResponse{
+		"/repos/acme-corp/payments-api": {http.StatusNotFound, "not_found.json"},
+	})
+	token := "github_pat_test"
+	val, err := newActivityEnv(a).ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", PATCredential(&token), DefaultChecks())
+	require.NoError(t, err)
+
+	var result RepoSecurityResult
+	require.NoError(t, val.Get(&result))
+	require.Nil(t, result.Error)
+	require.Nil(t, result.Disappeared, "a fine-grained token's 404 is no deletion")
+	require.True(t, result.TokenLacksRepoAccess)
+	for _, c := range DefaultChecks() {
+		require.Equal(t, CheckResult{Status: StatusNoAccess, Message: repoAccessMessage}, result.Check(c), c)
+	}
+	require.Len(t, f.Requests(), 1, "nothing else is checked")
+
+	// A classic token still reads the 404 as a deleted repo.
+	token = "ghp_test"
+	val, err = newActivityEnv(a).ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", PATCredential(&token), DefaultChecks())
+	require.NoError(t, err)
+	result = RepoSecurityResult{}
+	require.NoError(t, val.Get(&result))
+	require.False(t, result.TokenLacksRepoAccess)
+	require.Equal(t, DisappearedDeleted, result.Disappeared.Reason)
+}
+
+func TestPerRepoToken(t *testing.T) {
+	for token, want := range map[string]bool{
+		"github_pat_11ABC": true,
+		"ghs_installation": true,
+		"ghu_usertoserver": true,
+		"ghp_classic":      false,
+		"gho_oauth":        false,
+	} {
+		require.Equal(t, want, perRepoToken(map[string]string{"Authorization": "token " + token}), token)
+	}
+	require.False(t, perRepoToken(map[string]string{}))
+}
+
+func TestRepoAccessWarning(t *testing.T) {
+	require.Empty(t, repoAccessWarning(0, 0))
+	require.Empty(t, repoAccessWarning(2, 10), "at the threshold")
+	require.Equal(t,
+		"the token cannot read 3 of 10 repos (30%); check the repositories a fine-grained token or GitHub App installation was granted",
+		repoAccessWarning(3, 10))
+}
+
+func TestValidateTokenSamplesRepoAccess(t *testing.T) {
+	f, a := newFakeGitHub(t, map[string]fakeResponse{
+		"/orgs/acme-corp":                                              {http.StatusOK, "org.json"},
+		"/orgs/acme-corp/repos?per_page=10":                            {http.StatusOK, "org_repos_sample.json"},
+		"/repos/acme-corp/service-000":                                 {http.StatusNotFound, "not_found.json"},
+		"/repos/acme-corp/service-001":                                 {http.StatusNotFound, "not_found.json"},
+		"/repos/acme-corp/service-002":                                 {http.StatusOK, "repo_secret_scanning_enabled.json"},
+		"/repos/acme-corp/service-003":                                 {http.StatusOK, "repo_secret_scanning_enabled.json"},
+		"/repos/acme-corp/service-004":                                 {http.StatusOK, "repo_secret_scanning_enabled.json"},
+		"/repos/acme-corp/service-002/vulnerability-alerts":            {http.StatusNoContent, ""},
+		"/repos/acme-corp/service-002/code-scanning/alerts?per_page=1": {http.StatusOK, "code_scanning_alerts_empty.json"},
+	})
+	token := "github_pat_test"
+	val, err := newActivityEnv(a).ExecuteActivity(a.ValidateToken, "acme-corp", PATCredential(&token), []string(nil))
+	require.NoError(t, err)
+	var caps TokenCapabilities
+	require.NoError(t, val.Get(&caps))
+
+	require.Equal(t, "acme-corp/service-002", caps.ProbedRepo, "probed on the first repo it can read")
+	require.Equal(t, 5, caps.RepoAccess.Sampled)
+	require.Equal(t, []string{"service-000", "service-001"}, caps.RepoAccess.Inaccessible)
+	require.Contains(t, caps.RepoAccessWarning(), "cannot read 2 of 5 repos (40%)")
+	require.Empty(t, caps.Unavailable())
+	require.NotEmpty(t, f.Requests())
+
+	// When it can read none of them, the checks cannot be judged.
+	_, a = newFakeGitHub(t, map[string]fakeResponse{
+		"/orgs/acme-corp":                   {http.StatusOK, "org.json"},
+		"/orgs/acme-corp/repos?per_page=10": {http.StatusOK, "org_repos_sample.json"},
+		"/repos/acme-corp/service-000":      {http.StatusNotFound, "not_found.json"},
+		"/repos/acme-corp/service-001":      {http.StatusNotFound, "not_found.json"},
+		"/repos/acme-corp/service-002":      {http.StatusNotFound, "not_found.json"},
+		"/repos/acme-corp/service-003":      {http.StatusNotFound, "not_found.json"},
+		"/repos/acme-corp/service-004":      {http.StatusNotFound, "not_found.json"},
+	})
+	val, err = newActivityEnv(a).ExecuteActivity(a.ValidateToken, "acme-corp", PATCredential(&token), []string(nil))
+	require.NoError(t, err)
+	caps = TokenCapabilities{}
+	require.NoError(t, val.Get(&caps))
+	require.Empty(t, caps.ProbedRepo)
+	require.Len(t, caps.RepoAccess.Inaccessible, 5)
+	for _, c := range caps.Checks {
+		require.Equal(t, CheckCapability{Check: c.Check, Access: AccessUnknown, Reason: "the token could not read any sampled repo"}, c)
+	}
+}
+
+func TestWorkflowReportsReposTheTokenCannotRead(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(4))
+	lacking := func(repo string) *RepoSecurityResult {
+		r := &RepoSecurityResult{Repository: repo}
+		r.setTokenLacksRepoAccess([]string{CheckSecretScanning, CheckCodeScanning, CheckActions})
+		return r
+	}
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-001", mock.Anything, mock.Anything).
+		Return(lacking("repo-001"), nil)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-002", mock.Anything, mock.Anything).
+		Return(lacking("repo-002"), nil)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Checks: []string{CheckSecretScanning, CheckCodeScanning, CheckActions}})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.NoError(t, report.Validate())
+	require.Equal(t, 4, report.TotalRepos)
+	require.Equal(t, 2, report.FullyCompliant)
+	require.Equal(t, []string{"repo-001", "repo-002"}, report.IndeterminateRepos)
+	require.Equal(t, "100.0%", report.ComplianceRate, "unreadable repos are not in the rate")
+	require.Equal(t, 2, report.TokenLacksRepoAccess)
+	require.Equal(t, []string{"repo-001", "repo-002"}, report.TokenLacksRepoAccessRepos)
+	require.Contains(t, report.RepoAccessWarning, "cannot read 2 of 4 repos (50%)")
+	// Unreadable repos have no Actions settings to read either.
+	env.AssertNumberOfCalls(t, "CheckActionsSecurity", 2)
+
+	var out bytes.Buffer
+	RenderReport(&out, report, RenderOptions{})
+	require.Contains(t, out.String(), "  WARNING: the token cannot read 2 of 4 repos (50%)")
+	require.Contains(t, out.String(), "  No repo access:       2 (token not granted the repo)\n")
+}
@@ -0,0 +1,318 @@
+package scanner
+
+// =============================================================================
+// Activity batching — many repos per activity
+// =============================================================================
+//
+// One activity per repo costs three history events (scheduled, started,
+// completed) and a round trip through the server for every repo, plus two
+// more activities per repo for the Actions and access checks. For an org of
+// thousands of repos that overhead dominates the history. With
+// ScanInput.ActivityBatching the workflow instead sends up to
+// activityBatchSize repos to one CheckRepoSecurityBatch, which checks them
+// with scanBatchSize goroutines and returns every result at once.
+//
+// The activity heartbeats the results so far after each repo. A retried
+// attempt picks them up from the heartbeat details and only checks the repos
+// that were still pending or failed retryably, so a rate limit near the end
+// of a batch does not redo its first repos. On the last attempt, retryable
+// failures are reported on their repos instead of failing the batch.
+//
+// The workflow cancels a running batch when the scan is cancelled or
+// reaches its deadline, and waits for it: the activity returns the repos it
+// checked and the ones it skipped, as it does when the API budget runs out,
+// and the skipped repos are unscanned like the per-repo path's in flight.
+// =============================================================================
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// activityBatchSize is the most repos one CheckRepoSecurityBatch checks.
+const activityBatchSize = 50
+
+// RepoBatchInput is the input to CheckRepoSecurityBatch.
+type RepoBatchInput struct {
+	Provider            string      `json:"provider,omitempty"`
+	Org                 string      `json:"org"`
+	Repos               []string    `json:"repos"`
+	Credential          *Credential `json:"token,omitempty"`
+	Checks              []string    `json:"checks"`
+	DeployKeyMaxAgeDays int         `json:"deploy_key_max_age_days,omitempty"`
+	IncludeEvidence     bool        `json:"include_evidence,omitempty"`
+
+	// MaxAttempts is the activity's retry policy's; on that attempt
+	// retryable failures are returned per repo.
+	MaxAttempts int32 `json:"max_attempts,omitempty"`
+}
+
+// RepoBatchResult is what CheckRepoSecurityBatch returns. Results are in
+// input order; a repo that failed has Error set.
+type RepoBatchResult struct {
+	Results []RepoSecurityResult `json:"results"`
+
+	// BudgetExceeded means the scan's API budget ran out; the repos it cut
+	// short are in Skipped.
+	BudgetExceeded bool `json:"budget_exceeded,omitempty"`
+
+	// Skipped are the repos not checked, in input order, because the API
+	// budget ran out or the activity was cancelled.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// repoBatchProgress is CheckRepoSecurityBatch's heartbeat: the result for
+// each input index, nil while pending or after a retryable failure.
+type repoBatchProgress struct {
+	Results []*RepoSecurityResult `json:"results"`
+}
+
+// CheckRepoSecurityBatch runs the selected checks, including the Actions and
+// access checks, on every repo in in.Repos. Cancelled, it returns the repos
+// checked so far and skips the rest; a worker shutting down fails it
+// instead, so the next attempt picks up from the heartbeat.
+func (a *Activities) CheckRepoSecurityBatch(ctx context.Context, in RepoBatchInput) (*RepoBatchResult, error) {
+	progress := repoBatchProgress{Results: make([]*RepoSecurityResult, len(in.Repos))}
+	if activity.HasHeartbeatDetails(ctx) {
+		var prev repoBatchProgress
+		if err := activity.GetHeartbeatDetails(ctx, &prev); err == nil && len(prev.Results) == len(in.Repos) {
+			progress = prev
+		}
+	}
+	lastAttempt := in.MaxAttempts > 0 && activity.GetInfo(ctx).Attempt >= in.MaxAttempts
+
+	var (
+		mu             sync.Mutex
+		retry          error
+		budgetExceeded bool
+		wg             sync.WaitGroup
+	)
+	work := make(chan int)
+	for w := 0; w < scanBatchSize; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				result, err := a.checkRepoInBatch(ctx, in, in.Repos[i])
+				mu.Lock()
+				switch {
+				case ctx.Err() != nil:
+					// Cut short: skipped, not failed.
+				case isBudgetExceeded(err):
+					budgetExceeded = true
+				case err != nil && !lastAttempt && !isNonRetryable(err):
+					retry = err
+				case err != nil:
+					progress.Results[i] = scanErrorResult(in.Repos[i], err)
+				default:
+					progress.Results[i] = result
+				}
+				activity.RecordHeartbeat(ctx, progress)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i, r := range progress.Results {
+		mu.Lock()
+		stop := budgetExceeded || ctx.Err() != nil
+		mu.Unlock()
+		if stop {
+			break
+		}
+		if r == nil {
+			work <- i
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil && (!errors.Is(err, context.Canceled) || workerStopping(ctx)) {
+		return nil, err
+	}
+	if retry != nil && !budgetExceeded && ctx.Err() == nil {
+		// The heartbeat keeps the finished repos for the next attempt.
+		return nil, retry
+	}
+	out := &RepoBatchResult{BudgetExceeded: budgetExceeded}
+	for i, r := range progress.Results {
+		if r != nil {
+			out.Results = append(out.Results, *r)
+		} else {
+			out.Skipped = append(out.Skipped, in.Repos[i])
+		}
+	}
+	return out, nil
+}
+
+// workerStopping reports whether the worker running the activity is
+// shutting down, which cancels ctx too.
+func workerStopping(ctx context.Context) bool {
+	select {
+	case <-activity.GetWorkerStopChannel(ctx):
+		return true
+	default:
+		return false
+	}
+}
+
+// checkRepoInBatch is what scanBatch does for one repo with separate
+// activities: the repo checks, then Actions and the access audit, whose
+// failures leave them unknown.
+func (a *Activities) checkRepoInBatch(ctx context.Context, in RepoBatchInput, repo string) (*RepoSecurityResult, error) {
+	result, err := a.CheckProviderRepo(ctx, RepoCheckInput{
+		Provider: in.Provider, Org: in.Org, Repo: repo, Credential: in.Credential, Checks: in.Checks,
+		IncludeEvidence: in.IncludeEvidence,
+	})
+	if err != nil || result.Error != nil || result.Disappeared != nil || result.TokenLacksRepoAccess {
+		return result, err
+	}
+	logger := activity.GetLogger(ctx)
+	checks := newCheckSet(in.Checks)
+	if checks[CheckActions] {
+		actions, err := a.CheckActionsSecurity(ctx, in.Org, repo, in.Credential)
+		if isBudgetExceeded(err) {
+			return nil, err
+		}
+		if err != nil {
+			logger.Warn("Actions check failed", "repo", repo, "error", err)
+		}
+		result.setActions(actions)
+	}
+	if checks[CheckAccessAudit] {
+		access, err := a.AuditRepoAccess(ctx, in.Org, repo, in.Credential, in.DeployKeyMaxAgeDays)
+		if isBudgetExceeded(err) {
+			return nil, err
+		}
+		if err != nil {
+			logger.Warn("Access audit failed", "repo", repo, "error", err)
+		}
+		result.setAccess(access)
+	}
+	return result, nil
+}
+
+// isNonRetryable reports whether err is an ApplicationError that retrying
+// cannot fix.
+func isNonRetryable(err error) bool {
+	var appErr *temporal.ApplicationError
+	return errors.As(err, &appErr) && appErr.NonRetryable()
+}
+
+// repoBatchActivityOptions give a batch the time its repos would have had
+// one after another, scanBatchSize at a time, and expect a heartbeat at
+// least as often as one repo's check may take, unless perRepo sets a
+// heartbeat timeout.
+func repoBatchActivityOptions(perRepo ActivityTimeouts, retryPolicy *temporal.RetryPolicy, repos int) workflow.ActivityOptions {
+	waves := time.Duration((repos + scanBatchSize - 1) / scanBatchSize)
+	heartbeat := perRepo.heartbeat()
+	if heartbeat == 0 {
+		heartbeat = perRepo.startToClose()
+	}
+	return workflow.ActivityOptions{
+		StartToCloseTimeout:    waves * perRepo.startToClose(),
+		ScheduleToCloseTimeout: waves * perRepo.scheduleToClose(),
+		HeartbeatTimeout:       heartbeat,
+		RetryPolicy:            retryPolicy,
+	}
+}
+
+// scanBatchWithActivities is scanBatch for ScanBatchInput.ActivityBatching:
+// in.Repos go to CheckRepoSecurityBatch activityBatchSize at a time, one
+// activity after another. Each takes scanCtx's context values, such as a
+// worker session, with its own options.
+//
+// Once stop reports true, the running activity is cancelled and its
+// checked repos passed to onResult; the repos it skipped and those of the
+// batches not started are returned as inFlight. Repos skipped because the
+// API budget ran out are not passed to onResult, as in scanBatch. Runs from
+// before batch-stop wait for each activity to finish.
+func scanBatchWithActivities(ctx, scanCtx workflow.Context, in ScanBatchInput, runChecks []string, stop func() bool, onResult func(*RepoSecurityResult)) (budgetExceeded bool, inFlight []string) {
+	retryPolicy := in.ScanRetry.apply(ScanRetryPolicy())
+	timeouts := defaultScanTimeouts.merge(in.ScanTimeouts)
+	stopVersion := changeVersion(ctx, changeBatchStop)
+	for start := 0; start < len(in.Repos); start += activityBatchSize {
+		end := start + activityBatchSize
+		if end > len(in.Repos) {
+			end = len(in.Repos)
+		}
+		if stopVersion >= 1 && stop() {
+			return false, append(inFlight, in.Repos[start:]...)
+		}
+		repos := in.Repos[start:end]
+		opts := repoBatchActivityOptions(timeouts, retryPolicy, len(repos))
+		opts.WaitForCancellation = stopVersion >= 1
+		actCtx, cancel := workflow.WithCancel(workflow.WithActivityOptions(scanCtx, opts))
+		future := workflow.ExecuteActivity(actCtx, "CheckRepoSecurityBatch", RepoBatchInput{
+			Provider:            in.Provider,
+			Org:                 in.Org,
+			Repos:               repos,
+			Credential:          in.Credential,
+			Checks:              runChecks,
+			DeployKeyMaxAgeDays: in.DeployKeyMaxAgeDays,
+			IncludeEvidence:     in.IncludeEvidence,
+			MaxAttempts:         retryPolicy.MaximumAttempts,
+		})
+		halted := false
+		if stopVersion >= 1 {
+			halted = awaitBatchOrStop(ctx, future, stop)
+			if halted {
+				cancel()
+			}
+		}
+		var out RepoBatchResult
+		err := future.Get(ctx, &out)
+		cancel()
+		if err != nil {
+			if halted {
+				// Cancelled before it could report: nothing was checked.
+				return false, append(inFlight, in.Repos[start:]...)
+			}
+			// Only a failure of the batch as a whole gets here, e.g. a
+			// timeout; every repo in it is reported with that error.
+			for _, repo := range repos {
+				onResult(scanErrorResult(repo, err))
+			}
+			continue
+		}
+		for i := range out.Results {
+			r := &out.Results[i]
+			if r.Error == nil && r.Disappeared == nil {
+				for _, c := range in.NoAccess {
+					r.setNoAccess(c, "token lacks the scope or permission for this check")
+				}
+			}
+			onResult(r)
+		}
+		if out.BudgetExceeded {
+			return true, inFlight
+		}
+		if halted {
+			return false, append(append(inFlight, out.Skipped...), in.Repos[end:]...)
+		}
+	}
+	return false, inFlight
+}
+
+// awaitBatchOrStop waits until future is ready or stop reports true, and
+// reports whether it was stop.
+func awaitBatchOrStop(ctx workflow.Context, future workflow.Future, stop func() bool) bool {
+	done := false
+	stopped, settle := workflow.NewFuture(ctx)
+	workflow.Go(ctx, func(gCtx workflow.Context) {
+		_ = workflow.Await(gCtx, func() bool { return done || stop() })
+		settle.Set(nil, nil)
+	})
+	halted := false
+	sel := workflow.NewSelector(ctx)
+	sel.AddFuture(future, func(workflow.Future) {})
+	sel.AddFuture(stopped, func(workflow.Future) { halted = !future.IsReady() })
+	sel.Select(ctx)
+	done = true
+	return halted
+}
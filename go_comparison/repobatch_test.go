@@ -0,0 +1,253 @@
+package scanner
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestCheckRepoSecurityBatch(t *testing.T) {
+	routes := compliantRepoRoutes()
+	routes["/repos/acme-corp/gone"] = fakeResponse{http.StatusNotFound, "not_found.json"}
+	_, a := newFakeGitHub(t, routes)
+
+	val, err := newActivityEnv(a).ExecuteActivity(a.CheckRepoSecurityBatch, RepoBatchInput{
+		Org: "acme-corp", Repos: []string{"payments-api", "gone"}, Checks: DefaultChecks(),
+	})
+	require.NoError(t, err)
+	var out RepoBatchResult
+	require.NoError(t, val.Get(&out))
+	require.Len(t, out.Results, 2)
+	require.Equal(t, "payments-api", out.Results[0].Repository, "results are in input order")
+	require.Nil(t, out.Results[0].Error)
+	require.Equal(t, StatusEnabled, out.Results[0].SecretScanning)
+	require.Equal(t, "gone", out.Results[1].Repository)
+	require.Nil(t, out.Results[1].Error)
+	require.Equal(t, DisappearedDeleted, out.Results[1].Disappeared.Reason)
+}
+
+func TestCheckRepoSecurityBatchResumesFromHeartbeat(t *testing.T) {
+	f, a := newFakeGitHub(t, compliantRepoRoutes())
+	env := newActivityEnv(a)
+	// The previous attempt finished web before failing.
+	env.SetHeartbeatDetails(repoBatchProgress{Results: []*RepoSecurityResult{
+		{Repository: "web", SecretScanning: StatusDisabled},
+		nil,
+	}})
+
+	val, err := env.ExecuteActivity(a.CheckRepoSecurityBatch, RepoBatchInput{
+		Org: "acme-corp", Repos: []string{"web", "payments-api"}, Checks: DefaultChecks(),
+	})
+	require.NoError(t, err)
+	var out RepoBatchResult
+	require.NoError(t, val.Get(&out))
+	require.Equal(t, StatusDisabled, out.Results[0].SecretScanning, "web comes from the heartbeat")
+	require.Equal(t, StatusEnabled, out.Results[1].SecretScanning)
+	for _, r := range f.Requests() {
+		require.NotContains(t, r.URL.Path, "/web", "web must not be checked again")
+	}
+}
+
+func TestCheckRepoSecurityBatchRetryableFailure(t *testing.T) {
+	routes := compliantRepoRoutes()
+	// An empty body fails to parse, which is retryable.
+	routes["/repos/acme-corp/flaky"] = fakeResponse{http.StatusOK, ""}
+
+	// Before the last attempt the batch fails so Temporal retries it,
+	// keeping payments-api in the heartbeat.
+	_, a := newFakeGitHub(t, routes)
+	env := newActivityEnv(a)
+	var heartbeat repoBatchProgress
+	env.SetOnActivityHeartbeatListener(func(_ *activity.Info, details converter.EncodedValues) {
+		require.NoError(t, details.Get(&heartbeat))
+	})
+	_, err := env.ExecuteActivity(a.CheckRepoSecurityBatch, RepoBatchInput{
+		Org: "acme-corp", Repos: []string{"payments-api", "flaky"}, Checks: DefaultChecks(), MaxAttempts: 5,
+	})
+	require.Error(t, err)
+	require.NotNil(t, heartbeat.Results[0])
+	require.Nil(t, heartbeat.Results[1])
+
+	// On the last attempt the failure is reported on the repo.
+	_, a = newFakeGitHub(t, routes)
+	val, err := newActivityEnv(a).ExecuteActivity(a.CheckRepoSecurityBatch, RepoBatchInput{
+		Org: "acme-corp", Repos: []string{"payments-api", "flaky"}, Checks: DefaultChecks(), MaxAttempts: 1,
+	})
+	require.NoError(t, err)
+	var out RepoBatchResult
+	require.NoError(t, val.Get(&out))
+	require.Nil(t, out.Results[0].Error)
+	require.NotNil(t, out.Results[1].Error)
+}
+
+func TestCheckRepoSecurityBatchBudgetExceeded(t *testing.T) {
+	routes := compliantRepoRoutes()
+	routes["/repos/acme-corp/web"] = fakeResponse{http.StatusOK, "repo_secret_scanning_enabled.json"}
+	_, a := newFakeGitHub(t, routes)
+	env := trackedActivityEnv(t, a, apiScope{WorkflowID: "security-scan-acme-corp", RunID: "run-1", MaxRequests: 1})
+
+	val, err := env.ExecuteActivity(a.CheckRepoSecurityBatch, RepoBatchInput{
+		Org: "acme-corp", Repos: []string{"payments-api", "web"}, Checks: DefaultChecks(),
+	})
+	require.NoError(t, err)
+	var out RepoBatchResult
+	require.NoError(t, val.Get(&out))
+	require.True(t, out.BudgetExceeded)
+	require.Empty(t, out.Results)
+	require.Equal(t, []string{"payments-api", "web"}, out.Skipped, "skipped repos are in input order")
+}
+
+// countActivities runs a 120-repo scan and returns how many activities it
+// scheduled; each one is three history events.
+func countActivities(t *testing.T, input ScanInput) int {
+	t.Helper()
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(120))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("repo-007"))
+	compliant := compliantUnless("repo-007")
+	env.OnActivity("CheckRepoSecurityBatch", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, in RepoBatchInput) (*RepoBatchResult, error) {
+			out := &RepoBatchResult{}
+			for _, repo := range in.Repos {
+				r, _ := compliant(ctx, in.Org, repo, in.Credential, in.Checks)
+				r.setActions(hardenedActions)
+				out.Results = append(out.Results, *r)
+			}
+			return out, nil
+		})
+	activities := 0
+	env.SetOnActivityStartedListener(func(*activity.Info, context.Context, converter.EncodedValues) {
+		activities++
+	})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, input)
+
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 120, report.TotalRepos)
+	require.Equal(t, 119, report.FullyCompliant)
+	require.Equal(t, []string{"repo-007"}, report.NonCompliantRepos)
+	return activities
+}
+
+func TestActivityBatchingShrinksHistory(t *testing.T) {
+	checks := []string{CheckSecretScanning, CheckDependabot, CheckCodeScanning, CheckActions}
+	perRepo := countActivities(t, ScanInput{Org: "acme", Checks: checks})
+	batched := countActivities(t, ScanInput{Org: "acme", Checks: checks, ActivityBatching: true})
+
+	// Per repo: FetchOrgReposPage for each of the two pages, then
+	// CheckRepoSecurity and CheckActionsSecurity for each of the 120 repos.
+	// Batched: the two pages and one CheckRepoSecurityBatch per 50 repos.
+	// Both end with the four post-report steps.
+	require.Equal(t, 2+2*120+4, perRepo)
+	require.Equal(t, 2+3+4, batched)
+}
+
+func TestChildBatchUsesActivityBatching(t *testing.T) {
+	env := newTestEnv(t)
+	env.RegisterWorkflow(ScanBatchWorkflow)
+	onListOrgRepos(env, fakeRepos(120))
+	env.OnActivity("CheckRepoSecurityBatch", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, in RepoBatchInput) (*RepoBatchResult, error) {
+			out := &RepoBatchResult{}
+			for _, repo := range in.Repos {
+				out.Results = append(out.Results, RepoSecurityResult{
+					Repository: repo, SecretScanning: StatusEnabled, DependabotAlerts: StatusEnabled, CodeScanning: StatusEnabled,
+				})
+			}
+			return out, nil
+		})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ChildPerBatch: true, ActivityBatching: true})
+
+	require.NoError(t, env.GetWorkflowError())
+	// Children of 100 and 20 repos; the first runs two batches of 50.
+	env.AssertNumberOfCalls(t, "CheckRepoSecurityBatch", 3)
+	env.AssertNotCalled(t, "CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 120, report.FullyCompliant)
+}
+
+// onBatchBudget has CheckRepoSecurityBatch check the first five repos of
+// its batch and skip the rest, the budget spent.
+func onBatchBudget(env *testsuite.TestWorkflowEnvironment) {
+	env.OnActivity("CheckRepoSecurityBatch", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, in RepoBatchInput) (*RepoBatchResult, error) {
+			out := &RepoBatchResult{BudgetExceeded: true, Skipped: in.Repos[5:]}
+			for _, repo := range in.Repos[:5] {
+				out.Results = append(out.Results, RepoSecurityResult{
+					Repository: repo, SecretScanning: StatusEnabled, DependabotAlerts: StatusEnabled, CodeScanning: StatusEnabled,
+				})
+			}
+			return out, nil
+		})
+}
+
+func TestActivityBatchingReportsReposSkippedForBudget(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(120))
+	onBatchBudget(env)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ActivityBatching: true, MaxAPIRequests: 100})
+
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurityBatch", 1)
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 5, report.TotalRepos)
+	require.Zero(t, report.Errors)
+	// The 45 the batch skipped and the 70 of the batches never started.
+	require.Len(t, report.UnscannedRepos, 115)
+	require.Equal(t, "repo-005", report.UnscannedRepos[0])
+}
+
+func TestActivityBatchingStopsWhenCancelled(t *testing.T) {
+	env := newTestEnv(t)
+	env.SetStartTime(time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC))
+	onListOrgRepos(env, fakeRepos(120))
+	env.OnActivity("CheckRepoSecurityBatch", mock.Anything, mock.Anything).
+		After(time.Hour).Return(&RepoBatchResult{}, nil)
+	cancelled := 0
+	env.SetOnActivityCanceledListener(func(*activity.Info) { cancelled++ })
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("cancel_scan", "change freeze")
+	}, 30*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ActivityBatching: true})
+
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, 1, cancelled, "the running batch is cancelled, not waited out")
+	env.AssertNumberOfCalls(t, "CheckRepoSecurityBatch", 1)
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, true, report["cancelled"])
+	require.Equal(t, "2026-03-02T14:00:30Z", report["completed_at"])
+	require.Len(t, report["cancelled_in_flight_repos"], 50, "the running batch")
+	require.Len(t, report["unscanned_repos"], 120)
+}
+
+func TestActivityBatchingBeforeBatchStopWaitsOutTheBatch(t *testing.T) {
+	env := newTestEnv(t)
+	env.SetStartTime(time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC))
+	env.OnGetVersion(changeBatchStop, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+	onListOrgRepos(env, fakeRepos(120))
+	onBatchBudget(env)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ActivityBatching: true, MaxAPIRequests: 100})
+
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 5, report.TotalRepos)
+}
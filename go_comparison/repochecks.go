@@ -0,0 +1,84 @@
+package scanner
+
+// =============================================================================
+// Repo checks — a repo's checks run concurrently
+// =============================================================================
+//
+// checkGitHubRepo looks the repo up first: the lookup reads secret scanning
+// and tells whether the repo is still there (see disappeared.go). The other
+// checks are independent GETs, so they then run concurrently, at most
+// repoCheckConcurrency at a time. A repo with the default checks takes two
+// round trips instead of three; one with every check, about as long as its
+// slowest check.
+//
+// A check whose requests fail after the lookup — a timeout, a server error,
+// a response that does not parse — is recorded as StatusError with the
+// error as its message, and the other checks keep their results. Like a
+// check the token cannot read, StatusError counts as indeterminate. The
+// repo itself only fails when the lookup does, or on what no check can work
+// around: a spent API budget or a cancelled activity.
+//
+// Each check records into its own RepoSecurityResult, merged into the
+// repo's once the check is done, and its requests are evidence for it
+// alone (evidenceFor).
+// =============================================================================
+
+import (
+	"context"
+	"sync"
+
+	"go.temporal.io/sdk/activity"
+	"golang.org/x/sync/errgroup"
+)
+
+// repoCheckConcurrency bounds how many of a repo's checks run at once.
+const repoCheckConcurrency = 3
+
+// repoCheck is one of the checks checkGitHubRepo runs after the repo
+// lookup. run records check's results in result.
+type repoCheck struct {
+	check string
+	run   func(ctx context.Context, org, repoName string, headers map[string]string, result *RepoSecurityResult) error
+}
+
+// runRepoChecks runs checks concurrently and merges their results into
+// result.
+func (a *Activities) runRepoChecks(ctx context.Context, org, repoName string, headers map[string]string, checks []repoCheck, result *RepoSecurityResult) error {
+	var mu sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(repoCheckConcurrency)
+	for _, c := range checks {
+		c := c
+		g.Go(func() error {
+			part := &RepoSecurityResult{Repository: repoName}
+			err := c.run(gctx, org, repoName, headers, part)
+			if isBudgetExceeded(err) || err != nil && gctx.Err() != nil {
+				// The other checks are cancelled too.
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				activity.GetLogger(ctx).Warn("Check failed", "repo", repoName, "check", c.check, "error", err)
+				result.setCheckError(c.check, err.Error())
+				return nil
+			}
+			result.merge(part)
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// merge copies what a check recorded in part into r.
+func (r *RepoSecurityResult) merge(part *RepoSecurityResult) {
+	for name, c := range part.Checks {
+		r.setCheck(name, c)
+	}
+	if part.DependabotConfig != nil {
+		r.DependabotConfig = part.DependabotConfig
+	}
+	if part.WebhookFindings != nil {
+		r.WebhookFindings = part.WebhookFindings
+	}
+}
@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slowGitHub serves payments-api's checks from fixtures, each after its
+// delay. A route with a nil fixture drops the connection.
+type slowGitHub map[string]struct {
+	delay   time.Duration
+	status  int
+	fixture string
+}
+
+func (s slowGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, ok := s[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	time.Sleep(route.delay)
+	if route.status == 0 {
+		// A connection the server gave up on.
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+		return
+	}
+	w.WriteHeader(route.status)
+	if route.fixture != "" {
+		b, _ := os.ReadFile(filepath.Join("testdata", "github", route.fixture))
+		_, _ = w.Write(b)
+	}
+}
+
+func TestCheckRepoSecurityRunsChecksConcurrently(t *testing.T) {
+	const repoPath = "/repos/acme-corp/payments-api"
+	const delay = 200 * time.Millisecond
+	srv := httptest.NewServer(slowGitHub{
+		repoPath:                               {0, http.StatusOK, "repo_secret_scanning_enabled.json"},
+		repoPath + "/vulnerability-alerts":     {delay, http.StatusNoContent, ""},
+		repoPath + "/code-scanning/alerts":     {delay, http.StatusOK, "code_scanning_alerts.json"},
+		repoPath + "/automated-security-fixes": {delay, http.StatusOK, "automated_security_fixes_enabled.json"},
+	})
+	t.Cleanup(srv.Close)
+	a := &Activities{HTTPClient: srv.Client(), BaseURL: srv.URL}
+	env := newActivityEnv(a)
+
+	checks := []string{CheckSecretScanning, CheckDependabot, CheckCodeScanning, CheckSecurityUpdates}
+	start := time.Now()
+	val, err := env.ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), checks)
+	elapsed := time.Since(start)
+	require.NoError(t, err)
+
+	var result RepoSecurityResult
+	require.NoError(t, val.Get(&result))
+	require.Equal(t, StatusEnabled, result.SecretScanning)
+	require.Equal(t, StatusEnabled, result.DependabotAlerts)
+	require.Equal(t, StatusEnabled, result.CodeScanning)
+	require.Equal(t, StatusEnabled, result.SecurityUpdates)
+	// One after the other the three slow checks take 3×delay.
+	require.Less(t, elapsed, 2*delay, "checks ran one after the other")
+}
+
+func TestCheckRepoSecurityKeepsOtherChecksWhenOneFails(t *testing.T) {
+	const repoPath = "/repos/acme-corp/payments-api"
+	srv := httptest.NewServer(slowGitHub{
+		repoPath:                           {0, http.StatusOK, "repo_secret_scanning_enabled.json"},
+		repoPath + "/vulnerability-alerts": {0, http.StatusNoContent, ""},
+		repoPath + "/code-scanning/alerts": {0, 0, ""},
+	})
+	t.Cleanup(srv.Close)
+	a := &Activities{HTTPClient: srv.Client(), BaseURL: srv.URL}
+	env := newActivityEnv(a)
+
+	val, err := env.ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), DefaultChecks())
+	require.NoError(t, err, "one failed check does not fail the repo")
+
+	var result RepoSecurityResult
+	require.NoError(t, val.Get(&result))
+	require.Nil(t, result.Error)
+	require.Equal(t, StatusEnabled, result.SecretScanning)
+	require.Equal(t, StatusEnabled, result.DependabotAlerts)
+	require.Equal(t, StatusError, result.CodeScanning)
+	require.Contains(t, result.Check(CheckCodeScanning).Message, "code-scanning/alerts")
+
+	// The failed check could not be read, so the repo is indeterminate.
+	require.Equal(t, OutcomeIndeterminate, DefaultCompliancePolicy().Outcome(&result))
+}
+
+func TestCheckRepoSecurityFailsWhenLookupFails(t *testing.T) {
+	srv := httptest.NewServer(slowGitHub{
+		"/repos/acme-corp/payments-api": {0, 0, ""},
+	})
+	t.Cleanup(srv.Close)
+	a := &Activities{HTTPClient: srv.Client(), BaseURL: srv.URL}
+	env := newActivityEnv(a)
+
+	_, err := env.ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), DefaultChecks())
+	require.Error(t, err)
+	require.Equal(t, ErrorServerError, NewScanError(err).Category)
+}
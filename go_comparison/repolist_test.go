@@ -0,0 +1,137 @@
+package scanner
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestParseRepoList(t *testing.T) {
+	repos, dups, err := ParseRepoList([]byte(`# critical repos
+acme/api
+acme/web   # customer facing
+
+acme/API
+acme/billing
+`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"acme/api", "acme/web", "acme/billing"}, repos)
+	require.Equal(t, []string{"acme/API"}, dups)
+
+	for _, bad := range []string{"acme", "acme/", "/api", "acme/api/extra", "acme/a pi"} {
+		_, _, err := ParseRepoList([]byte("acme/ok\n" + bad + "\n"))
+		require.ErrorContains(t, err, "line 2", bad)
+	}
+}
+
+func TestRepoListHash(t *testing.T) {
+	a := RepoListHash([]string{"acme/api", "acme/web"})
+	require.Len(t, a, 8)
+	require.Equal(t, a, RepoListHash([]string{"acme/web", "ACME/api"}), "order and case do not matter")
+	require.NotEqual(t, a, RepoListHash([]string{"acme/api"}))
+}
+
+func TestWorkflowScansExplicitRepos(t *testing.T) {
+	env := newTestEnv(t)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "acme", mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("web"))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Repos: []string{"acme/api", "Acme/web"}})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 2, report["total_repos"])
+	require.Equal(t, []interface{}{"web"}, report["non_compliant_repos"])
+	env.AssertNotCalled(t, "FetchOrgReposPage", mock.Anything, mock.Anything)
+}
+
+func TestWorkflowRejectsInvalidRepos(t *testing.T) {
+	for name, input := range map[string]ScanInput{
+		"other org":        {Org: "acme", Repos: []string{"acme/api", "globex/api"}},
+		"malformed":        {Org: "acme", Repos: []string{"api"}},
+		"with active days": {Org: "acme", Repos: []string{"acme/api"}, ActiveWithinDays: 90},
+		"teams and repos":  {Org: "acme", Repos: []string{"acme/api"}, Teams: []string{"platform"}},
+		"bad team slug":    {Org: "acme", Teams: []string{"platform/api"}},
+		"teams on gitlab":  {Org: "acme", Provider: ProviderGitLab, Teams: []string{"platform"}},
+	} {
+		input := input
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.ExecuteWorkflow(SecurityScanWorkflow, input)
+
+			require.True(t, env.IsWorkflowCompleted())
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(env.GetWorkflowError(), &appErr), "got %v", env.GetWorkflowError())
+			require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+		})
+	}
+}
+
+func TestNormalizeRepos(t *testing.T) {
+	repos, dups := normalizeRepos([]RepoInfo{
+		{Name: "MyRepo", FullName: "acme/MyRepo"},
+		{Name: " web ", FullName: " acme/web "},
+		{Name: "myrepo", FullName: "acme/myrepo"},
+		{Name: "web", FullName: "ACME/web"},
+		{Name: "api", FullName: "acme/api"},
+	})
+	require.Equal(t, []RepoInfo{
+		{Name: "MyRepo", FullName: "acme/MyRepo"},
+		{Name: "web", FullName: "acme/web"},
+		{Name: "api", FullName: "acme/api"},
+	}, repos)
+	require.Equal(t, []string{"acme/myrepo", "ACME/web"}, dups)
+}
+
+func TestWorkflowDedupesExplicitRepos(t *testing.T) {
+	env := newTestEnv(t)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "acme", mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Repos: []string{"acme/MyRepo", " acme/myrepo", "acme/web"}})
+
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 2)
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 2, report["total_repos"])
+	require.EqualValues(t, 1, report["duplicate_repos"])
+}
+
+// TestWorkflowDedupesShiftedPage lists an org while a repo is created: the
+// last repo of page 1 is pushed onto page 2 and returned twice. Two of the
+// listed repos are archived, and left out.
+func TestWorkflowDedupesShiftedPage(t *testing.T) {
+	_, a := newFakeGitHub(t, map[string]fakeResponse{
+		reposPage("1"): {http.StatusOK, "org_repos_page1.json"},
+		reposPage("2"): {http.StatusOK, "org_repos_page2_shifted.json"},
+	})
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(a)
+	mockActionsSecurity(env)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "acme-corp", mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme-corp"})
+
+	require.NoError(t, env.GetWorkflowError())
+	val, err := env.QueryWorkflow("progress")
+	require.NoError(t, err)
+	var progress ScanProgress
+	require.NoError(t, val.Get(&progress))
+	require.Equal(t, 101, progress.TotalRepos)
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 101)
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 101, report["total_repos"])
+	require.EqualValues(t, 1, report["duplicate_repos"])
+	require.EqualValues(t, 2, report["archived"])
+}
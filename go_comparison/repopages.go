@@ -0,0 +1,97 @@
+package scanner
+
+// =============================================================================
+// Repo listing pages — one activity per page of a GitHub org's repos
+// =============================================================================
+//
+// FetchOrgRepos lists an org's repos in one activity. Heartbeats keep it
+// from being declared dead, but they do not extend its StartToClose, and an
+// org with 8,000 repos takes 80 pages: longer than the fetch timeout allows,
+// so every retry starts again from page 1 and fails the same way.
+//
+// SecurityScanWorkflow therefore lists a GitHub org one FetchOrgReposPage
+// activity per page. Each takes one request, a failed page is retried on
+// its own, and ScanProgress.ReposListed grows page by page. A page says
+// where the listing continues (RepoPage.Path), so a renamed org's later
+// pages are asked for where its first was served from, as FetchOrgRepos
+// does (see orgrename.go).
+//
+// Runs recorded before the change call FetchOrgRepos, which stays
+// registered so they replay; team and GitLab listings still use a single
+// activity.
+// =============================================================================
+
+import (
+	"context"
+	"fmt"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// RepoPageInput is the input to FetchOrgReposPage.
+type RepoPageInput struct {
+	Org        string      `json:"org"`
+	Credential *Credential `json:"token,omitempty"`
+	// Page is the page to fetch, from 1.
+	Page int `json:"page"`
+	// Path is the previous page's RepoPage.Path; empty for the first.
+	Path string `json:"path,omitempty"`
+}
+
+// RepoPage is one page of an org's repo listing.
+type RepoPage struct {
+	Repos []RepoInfo `json:"repos"`
+	// HasMore is whether the listing may go on past this page.
+	HasMore bool `json:"has_more"`
+	// Path is the API path the page was served from, where the next page
+	// is; it is not the org's own once the org was renamed.
+	Path string `json:"path"`
+}
+
+// FetchOrgReposPage fetches one page of a GitHub org's repos.
+func (a *Activities) FetchOrgReposPage(ctx context.Context, in RepoPageInput) (*RepoPage, error) {
+	if in.Page < 1 {
+		return nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("invalid page %d", in.Page), ErrTypeInvalidInput, nil)
+	}
+	notFound := temporal.NewNonRetryableApplicationError(
+		fmt.Sprintf("organization '%s' not found", in.Org),
+		"NOT_FOUND",
+		nil,
+	)
+	cred, err := a.githubToken(ctx, in.Credential)
+	if err != nil {
+		return nil, err
+	}
+	path := in.Path
+	if path == "" {
+		path = "/orgs/" + in.Org + "/repos"
+	}
+	repos, served, more, err := a.listGitHubRepoPage(ctx, path, in.Page, cred, notFound, nil)
+	if err != nil {
+		return nil, err
+	}
+	activity.GetLogger(ctx).Info("Fetched repositories page", "org", in.Org, "page", in.Page, "count", len(repos))
+	return &RepoPage{Repos: repos, HasMore: more, Path: served}, nil
+}
+
+// listOrgRepoPages lists input.Org's repos one FetchOrgReposPage per page,
+// counting them in progress as the pages come in.
+func listOrgRepoPages(ctx workflow.Context, input ScanInput, progress *ScanProgress) ([]RepoInfo, error) {
+	var repos []RepoInfo
+	in := RepoPageInput{Org: input.Org, Credential: input.credential()}
+	for in.Page = 1; ; in.Page++ {
+		var page RepoPage
+		if err := workflow.ExecuteActivity(ctx, "FetchOrgReposPage", in).Get(ctx, &page); err != nil {
+			return nil, err
+		}
+		repos = append(repos, page.Repos...)
+		progress.ReposListed, progress.UpdatedAt = len(repos), workflow.Now(ctx)
+		if !page.HasMore {
+			return repos, nil
+		}
+		in.Path = page.Path
+	}
+}
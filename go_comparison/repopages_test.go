@@ -0,0 +1,143 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestFetchOrgReposPage(t *testing.T) {
+	_, a := newFakeGitHub(t, map[string]fakeResponse{
+		reposPage("1"): {http.StatusOK, "org_repos_page1.json"},
+		reposPage("2"): {http.StatusOK, "org_repos_page2.json"},
+		reposPage("3"): {http.StatusNotFound, "not_found.json"},
+	})
+	env := newActivityEnv(a)
+
+	val, err := env.ExecuteActivity(a.FetchOrgReposPage, RepoPageInput{Org: "acme-corp", Page: 1})
+	require.NoError(t, err)
+	var page RepoPage
+	require.NoError(t, val.Get(&page))
+	require.Len(t, page.Repos, 100)
+	require.True(t, page.HasMore, "a full page may not be the last")
+	require.Equal(t, "/orgs/acme-corp/repos", page.Path)
+
+	val, err = env.ExecuteActivity(a.FetchOrgReposPage, RepoPageInput{Org: "acme-corp", Page: 2, Path: page.Path})
+	require.NoError(t, err)
+	page = RepoPage{}
+	require.NoError(t, val.Get(&page))
+	require.Len(t, page.Repos, 3)
+	require.False(t, page.HasMore)
+
+	_, err = env.ExecuteActivity(a.FetchOrgReposPage, RepoPageInput{Org: "acme-corp", Page: 3})
+	require.ErrorContains(t, err, "organization 'acme-corp' not found")
+	_, err = env.ExecuteActivity(a.FetchOrgReposPage, RepoPageInput{Org: "acme-corp"})
+	require.ErrorContains(t, err, "invalid page 0")
+}
+
+// pagedGitHub serves an org of pages×100 repos, less 58 on the last page,
+// and fails each page in failOnce with a 502 the first time it is asked
+// for.
+type pagedGitHub struct {
+	pages    int
+	failOnce map[int]bool
+
+	mu       sync.Mutex
+	requests map[int]int
+}
+
+func (g *pagedGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/orgs/acme-corp/repos" {
+		http.NotFound(w, r)
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	g.mu.Lock()
+	g.requests[page]++
+	first := g.requests[page] == 1
+	g.mu.Unlock()
+	if first && g.failOnce[page] {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	n := 100
+	switch {
+	case page > g.pages:
+		n = 0
+	case page == g.pages:
+		n = 42
+	}
+	repos := make([]map[string]interface{}, n)
+	for i := range repos {
+		name := fmt.Sprintf("repo-%05d", (page-1)*100+i)
+		repos[i] = map[string]interface{}{"name": name, "full_name": "acme-corp/" + name, "default_branch": "main"}
+	}
+	_ = json.NewEncoder(w).Encode(repos)
+}
+
+func TestWorkflowListsHugeOrgPageByPage(t *testing.T) {
+	gh := &pagedGitHub{pages: 150, failOnce: map[int]bool{77: true}, requests: map[int]int{}}
+	srv := httptest.NewServer(gh)
+	t.Cleanup(srv.Close)
+
+	var s testsuite.WorkflowTestSuite
+	s.SetLogger(nopLogger{})
+	env := s.NewTestWorkflowEnvironment()
+	// 15,000 results are more than the history should hold.
+	env.RegisterActivity(&Activities{HTTPClient: srv.Client(), BaseURL: srv.URL, BlobStore: &FileBlobStore{Dir: t.TempDir()}})
+	env.OnActivity("CheckRepoSecurityBatch", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, in RepoBatchInput) (*RepoBatchResult, error) {
+			out := &RepoBatchResult{}
+			for _, repo := range in.Repos {
+				out.Results = append(out.Results, RepoSecurityResult{
+					Repository: repo, SecretScanning: StatusEnabled, DependabotAlerts: StatusEnabled, CodeScanning: StatusEnabled,
+				})
+			}
+			return out, nil
+		})
+
+	started := map[string]int{}
+	env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, _ converter.EncodedValues) {
+		started[info.ActivityType.Name]++
+	})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme-corp", ActivityBatching: true})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 149*100+42, report.TotalRepos)
+	require.Equal(t, report.TotalRepos, report.FullyCompliant)
+
+	// One activity per page, each asked for once; the failed page alone
+	// was retried, not the 76 before it.
+	require.Equal(t, 150+1, started["FetchOrgReposPage"])
+	require.Zero(t, started["FetchOrgRepos"])
+	require.Len(t, gh.requests, 150)
+	for page, n := range gh.requests {
+		want := 1
+		if page == 77 {
+			want = 2
+		}
+		require.Equal(t, want, n, "page %d", page)
+	}
+
+	val, err := env.QueryWorkflow("progress")
+	require.NoError(t, err)
+	var progress ScanProgress
+	require.NoError(t, val.Get(&progress))
+	require.Equal(t, report.TotalRepos, progress.ReposListed)
+}
@@ -0,0 +1,434 @@
+package scanner
+
+// =============================================================================
+// Report diff — what changed between two saved scans
+// =============================================================================
+//
+// Reports are saved weekly as security_scan_<org>.json. CompareReports turns
+// two of them into a ReportDiff: rate and error deltas, repos that started or
+// stopped failing each check, repos that appeared or disappeared, and
+// findings that were newly suppressed or lost their suppression. Suppression
+// changes count as changes even when compliance is otherwise unchanged.
+// =============================================================================
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// legacyCheck stands in for the unknown failed checks of a report saved
+// before repo_failures existed, which only lists non-compliant repos.
+const legacyCheck = "compliance"
+
+// Report is the typed view of a saved report. ReportJSONSchema is generated
+// from it, so every field the scanner writes belongs here. Unknown keys are
+// ignored.
+type Report struct {
+	SchemaVersion     string         `json:"schema_version,omitempty"`
+	Org               string         `json:"org"`
+	OrgRenamedTo      string         `json:"org_renamed_to,omitempty"`
+	InitiatedBy       string         `json:"initiated_by,omitempty"`
+	Reason            string         `json:"reason,omitempty"`
+	Provider          string         `json:"provider,omitempty"`
+	TotalRepos        int            `json:"total_repos"`
+	FullyCompliant    int            `json:"fully_compliant"`
+	ComplianceRate    string         `json:"compliance_rate"`
+	ComplianceScore   *float64       `json:"compliance_score,omitempty"`
+	Errors            int            `json:"errors"`
+	ErrorsByCategory  map[string]int `json:"errors_by_category,omitempty"`
+	RetryLaterRepos   []string       `json:"retry_later_repos,omitempty"`
+	NonCompliantRepos []string       `json:"non_compliant_repos"`
+	// Indeterminate repos failed only checks that could not be read; they
+	// are left out of the compliance rate.
+	Indeterminate      int      `json:"indeterminate,omitempty"`
+	IndeterminateRepos []string `json:"indeterminate_repos,omitempty"`
+	// TokenLacksRepoAccess counts the repos the token could not read; they
+	// are among the indeterminate ones. RepoAccessWarning is set when they
+	// are too many; see repoaccess.go.
+	TokenLacksRepoAccess      int      `json:"token_lacks_repo_access,omitempty"`
+	TokenLacksRepoAccessRepos []string `json:"token_lacks_repo_access_repos,omitempty"`
+	RepoAccessWarning         string   `json:"repo_access_warning,omitempty"`
+	// ByVisibility and ByLanguage break compliance down per bucket; see
+	// breakdown.go. ByTopic does per topic, and its buckets overlap; see
+	// topics.go.
+	ByVisibility map[string]ComplianceBreakdown `json:"by_visibility,omitempty"`
+	ByLanguage   map[string]ComplianceBreakdown `json:"by_language,omitempty"`
+	ByTopic      map[string]ComplianceBreakdown `json:"by_topic,omitempty"`
+	RepoFailures map[string][]string            `json:"repo_failures,omitempty"`
+	Suppressed   []SuppressedFinding            `json:"suppressed,omitempty"`
+	Cancelled    bool                           `json:"cancelled,omitempty"`
+	CompletedAt  string                         `json:"completed_at,omitempty"`
+
+	CancelReason             string              `json:"cancel_reason,omitempty"`
+	ReposScannedBeforeCancel int                 `json:"repos_scanned_before_cancel,omitempty"`
+	CancelledInFlightRepos   []string            `json:"cancelled_in_flight_repos,omitempty"`
+	RunID                    string              `json:"run_id,omitempty"`
+	StartedAt                string              `json:"started_at,omitempty"`
+	WorkerVersion            string              `json:"worker_version,omitempty"`
+	SkippedInactive          int                 `json:"skipped_inactive,omitempty"`
+	DuplicateRepos           int                 `json:"duplicate_repos,omitempty"`
+	WorkerSessions           int                 `json:"worker_sessions,omitempty"`
+	RepoList                 bool                `json:"repo_list,omitempty"`
+	Teams                    []string            `json:"teams,omitempty"`
+	TeamRepos                map[string][]string `json:"team_repos,omitempty"`
+	SkippedInactiveSample    []string            `json:"skipped_inactive_sample,omitempty"`
+	Archived                 int                 `json:"archived,omitempty"`
+	ArchivedRepos            []string            `json:"archived_repos,omitempty"`
+	IncludeTopics            []string            `json:"include_topics,omitempty"`
+	ExcludeTopics            []string            `json:"exclude_topics,omitempty"`
+	SkippedByTopic           int                 `json:"skipped_by_topic,omitempty"`
+	Disappeared              int                 `json:"disappeared,omitempty"`
+	DisappearedRepos         []DisappearedRepo   `json:"disappeared_repos,omitempty"`
+	Deadline                 string              `json:"deadline,omitempty"`
+	DeadlineReached          bool                `json:"deadline_reached,omitempty"`
+	UnscannedRepos           []string            `json:"unscanned_repos,omitempty"`
+	ResumedFromRunIDs        []string            `json:"resumed_from_run_ids,omitempty"`
+	TimedOutInBatchRepos     []string            `json:"timed_out_in_batch_repos,omitempty"`
+	Evidence                 EvidenceAppendix    `json:"evidence,omitempty"`
+	EvidenceRef              *BlobRef            `json:"evidence_ref,omitempty"`
+	EvidenceOmitted          int                 `json:"evidence_omitted,omitempty"`
+	PriorityRepos            int                 `json:"priority_repos,omitempty"`
+	PriorityReposScanned     int                 `json:"priority_repos_scanned,omitempty"`
+	WorkflowID               string              `json:"workflow_id,omitempty"`
+	EstimatedAPICalls        int                 `json:"estimated_api_calls,omitempty"`
+	Checks                   []string            `json:"checks,omitempty"`
+
+	// The per-check counts are nil when the scan did not run the check.
+	SecretScanningEnabled *int `json:"secret_scanning_enabled,omitempty"`
+	DependabotEnabled     *int `json:"dependabot_enabled,omitempty"`
+	CodeScanningEnabled   *int `json:"code_scanning_enabled,omitempty"`
+	CodeownersPresent     *int `json:"codeowners_present,omitempty"`
+	SecurityPolicyPresent *int `json:"security_policy_present,omitempty"`
+	ActionsEnabled        *int `json:"actions_enabled,omitempty"`
+	ReadOnlyWorkflowToken *int `json:"read_only_workflow_token,omitempty"`
+	ActionsRestricted     *int `json:"actions_restricted,omitempty"`
+
+	SecurityUpdatesEnabled        *int `json:"security_updates_enabled,omitempty"`
+	DependabotConfigPresent       *int `json:"dependabot_config_present,omitempty"`
+	DependabotConfigMisconfigured *int `json:"dependabot_config_misconfigured,omitempty"`
+
+	// WebhooksSecure counts the repos none of whose webhooks has an issue,
+	// and WebhookFindings the webhooks with each issue (see webhooks.go).
+	WebhooksSecure  *int           `json:"webhooks_secure,omitempty"`
+	WebhookFindings map[string]int `json:"webhook_findings,omitempty"`
+
+	Scoring             *ScoringPolicy      `json:"scoring,omitempty"`
+	AccessAudit         *AccessAuditSummary `json:"access_audit,omitempty"`
+	ResultsBlobRefs     []BlobRef           `json:"results_blob_refs,omitempty"`
+	WorstScoringRepos   []RepoScore         `json:"worst_scoring_repos,omitempty"`
+	ExpiredSuppressions []Suppression       `json:"expired_suppressions,omitempty"`
+	TokenCapabilities   *TokenCapabilities  `json:"token_capabilities,omitempty"`
+	APIUsage            *APIUsage           `json:"api_usage,omitempty"`
+	Forwarding          *ForwardResult      `json:"forwarding,omitempty"`
+	Remediation         *RemediationResult  `json:"remediation,omitempty"`
+	RemediationPlan     *RemediationPlan    `json:"remediation_plan,omitempty"`
+	Paging              *PagingResult       `json:"paging,omitempty"`
+	Delivery            []SinkDelivery      `json:"delivery,omitempty"`
+	ScanConfig          *ScanConfig         `json:"scan_config,omitempty"`
+}
+
+// AccessAuditSummary is the report's access_audit section.
+type AccessAuditSummary struct {
+	ReposAudited         int              `json:"repos_audited"`
+	ReposNoAccess        int              `json:"repos_no_access"`
+	DeployKeys           int              `json:"deploy_keys"`
+	FlaggedDeployKeys    int              `json:"flagged_deploy_keys"`
+	OutsideCollaborators int              `json:"outside_collaborators"`
+	WorstOffenders       []AccessOffender `json:"worst_offenders"`
+}
+
+// reportFields is Report without its methods, which encoding/json writes
+// field by field.
+type reportFields Report
+
+// MarshalJSON writes a cancelled report's cancel_reason and
+// repos_scanned_before_cancel even when they are empty, as the workflow
+// does: clients of the Python report read both once cancelled is set, and
+// a scan cancelled before its first batch scanned none.
+func (r Report) MarshalJSON() ([]byte, error) {
+	if !r.Cancelled {
+		return json.Marshal(reportFields(r))
+	}
+	return json.Marshal(struct {
+		reportFields
+		CancelReason             string `json:"cancel_reason"`
+		ReposScannedBeforeCancel int    `json:"repos_scanned_before_cancel"`
+	}{reportFields(r), r.CancelReason, r.ReposScannedBeforeCancel})
+}
+
+// Duration is the scan's wall-clock time from started_at to completed_at,
+// or false if either is missing.
+func (r Report) Duration() (time.Duration, bool) {
+	start, err := time.Parse(time.RFC3339, r.StartedAt)
+	if err != nil {
+		return 0, false
+	}
+	end, err := time.Parse(time.RFC3339, r.CompletedAt)
+	if err != nil {
+		return 0, false
+	}
+	return end.Sub(start), true
+}
+
+// checkCount is the repos passing one check.
+type checkCount struct {
+	check string
+	count int
+}
+
+// checkCounts are the per-check counts of the checks the scan ran, in
+// report order.
+func (r Report) checkCounts() []checkCount {
+	var counts []checkCount
+	for _, c := range []struct {
+		check string
+		count *int
+	}{
+		{CheckSecretScanning, r.SecretScanningEnabled},
+		{CheckDependabot, r.DependabotEnabled},
+		{CheckCodeScanning, r.CodeScanningEnabled},
+		{ResultCodeowners, r.CodeownersPresent},
+		{ResultSecurityPolicy, r.SecurityPolicyPresent},
+		{ResultReadOnlyWorkflowToken, r.ReadOnlyWorkflowToken},
+		{CheckActions, r.ActionsRestricted},
+		{CheckSecurityUpdates, r.SecurityUpdatesEnabled},
+		{CheckDependabotConfig, r.DependabotConfigPresent},
+		{CheckWebhooks, r.WebhooksSecure},
+	} {
+		if c.count != nil {
+			counts = append(counts, checkCount{c.check, *c.count})
+		}
+	}
+	return counts
+}
+
+// Adoption is the repos passing each check the scan ran.
+func (r Report) Adoption() map[string]int {
+	counts := r.checkCounts()
+	if len(counts) == 0 {
+		return nil
+	}
+	adoption := make(map[string]int, len(counts))
+	for _, c := range counts {
+		adoption[c.check] = c.count
+	}
+	return adoption
+}
+
+// ParseReport decodes a saved report.
+func ParseReport(data []byte) (Report, error) {
+	var r Report
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// Rate is the compliance rate in percent, or 0 for an empty scan.
+func (r Report) Rate() float64 {
+	if v, err := strconv.ParseFloat(strings.TrimSuffix(r.ComplianceRate, "%"), 64); err == nil {
+		return v
+	}
+	if r.determinate() <= 0 {
+		return 0
+	}
+	return float64(r.FullyCompliant) / float64(r.determinate()) * 100
+}
+
+// determinate is the number of repos the compliance rate is over.
+func (r Report) determinate() int {
+	return r.TotalRepos - r.Indeterminate
+}
+
+// failures returns repo -> failed checks. The legacy view, built from
+// non_compliant_repos, is used when either report lacks repo_failures so
+// the two sides are comparable.
+func (r Report) failures(legacy bool) map[string][]string {
+	if !legacy {
+		return r.RepoFailures
+	}
+	out := make(map[string][]string, len(r.NonCompliantRepos))
+	for _, repo := range r.NonCompliantRepos {
+		out[repo] = []string{legacyCheck}
+	}
+	return out
+}
+
+// ReportDiff is the difference between two reports of the same org.
+type ReportDiff struct {
+	Org string `json:"org"`
+
+	OldRate   float64 `json:"old_compliance_rate"`
+	NewRate   float64 `json:"new_compliance_rate"`
+	RateDelta float64 `json:"compliance_rate_delta"`
+
+	// ScoreDelta is nil unless both reports have a compliance score.
+	ScoreDelta *float64 `json:"compliance_score_delta,omitempty"`
+
+	OldErrors   int `json:"old_errors"`
+	NewErrors   int `json:"new_errors"`
+	ErrorsDelta int `json:"errors_delta"`
+
+	// Regressions and Improvements map a check to the repos that started
+	// or stopped failing it. Repos only in one report are not included.
+	Regressions  map[string][]string `json:"regressions,omitempty"`
+	Improvements map[string][]string `json:"improvements,omitempty"`
+
+	// Added and Removed are repos scanned in only one of the reports. They
+	// are only known when both reports have repo_failures; a repo whose
+	// scan errored is missing from its report.
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+
+	// NewlySuppressed findings were failing and are now excused.
+	// Unsuppressed findings were excused and are not any more, because the
+	// suppression expired or was removed, or the check now passes.
+	NewlySuppressed []SuppressedFinding `json:"newly_suppressed,omitempty"`
+	Unsuppressed    []SuppressedFinding `json:"unsuppressed,omitempty"`
+
+	// SuppressionChanged findings are excused in both reports but with a
+	// different justification or expiry; the new one is listed.
+	SuppressionChanged []SuppressedFinding `json:"suppression_changed,omitempty"`
+}
+
+// HasRegressions reports whether any repo started failing a check. It is
+// what CI gates on.
+func (d ReportDiff) HasRegressions() bool {
+	return len(d.Regressions) > 0
+}
+
+// HasChanges reports whether anything besides timing differs, including
+// suppression changes that leave compliance untouched.
+func (d ReportDiff) HasChanges() bool {
+	return d.RateDelta != 0 || d.ErrorsDelta != 0 ||
+		(d.ScoreDelta != nil && *d.ScoreDelta != 0) ||
+		len(d.Regressions) > 0 || len(d.Improvements) > 0 ||
+		len(d.Added) > 0 || len(d.Removed) > 0 ||
+		len(d.NewlySuppressed) > 0 || len(d.Unsuppressed) > 0 ||
+		len(d.SuppressionChanged) > 0
+}
+
+// CompareReports diffs old against cur, the newer report. All lists are sorted.
+func CompareReports(old, cur Report) ReportDiff {
+	d := ReportDiff{
+		Org:         cur.Org,
+		OldRate:     old.Rate(),
+		NewRate:     cur.Rate(),
+		OldErrors:   old.Errors,
+		NewErrors:   cur.Errors,
+		ErrorsDelta: cur.Errors - old.Errors,
+	}
+	d.RateDelta = roundScore(d.NewRate - d.OldRate)
+	if old.ComplianceScore != nil && cur.ComplianceScore != nil {
+		delta := roundScore(*cur.ComplianceScore - *old.ComplianceScore)
+		d.ScoreDelta = &delta
+	}
+
+	// Repo sets are only complete when both reports list every repo.
+	complete := old.RepoFailures != nil && cur.RepoFailures != nil
+	oldFail, newFail := old.failures(!complete), cur.failures(!complete)
+	oldSup, newSup := findingSet(old.Suppressed), findingSet(cur.Suppressed)
+
+	repos := make(map[string]bool, len(newFail))
+	for repo := range oldFail {
+		repos[repo] = true
+	}
+	for repo := range newFail {
+		repos[repo] = true
+	}
+	for repo := range repos {
+		before, inOld := oldFail[repo]
+		after, inNew := newFail[repo]
+		if complete && !inOld {
+			d.Added = append(d.Added, repo)
+			continue
+		}
+		if complete && !inNew {
+			d.Removed = append(d.Removed, repo)
+			continue
+		}
+		for _, check := range subtract(after, before) {
+			d.Regressions = addRepo(d.Regressions, check, repo)
+		}
+		for _, check := range subtract(before, after) {
+			// A newly suppressed finding is reported as such, not as a fix.
+			if _, ok := newSup[findingKey{repo, check}]; ok {
+				continue
+			}
+			d.Improvements = addRepo(d.Improvements, check, repo)
+		}
+	}
+
+	for _, f := range cur.Suppressed {
+		prev, ok := oldSup[findingKey{f.Repository, f.Check}]
+		switch {
+		case !ok:
+			d.NewlySuppressed = append(d.NewlySuppressed, f)
+		case prev != f:
+			d.SuppressionChanged = append(d.SuppressionChanged, f)
+		}
+	}
+	for _, f := range old.Suppressed {
+		if _, ok := newSup[findingKey{f.Repository, f.Check}]; !ok {
+			d.Unsuppressed = append(d.Unsuppressed, f)
+		}
+	}
+
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	for _, m := range []map[string][]string{d.Regressions, d.Improvements} {
+		for _, repos := range m {
+			sort.Strings(repos)
+		}
+	}
+	sortFindings(d.NewlySuppressed)
+	sortFindings(d.Unsuppressed)
+	sortFindings(d.SuppressionChanged)
+	return d
+}
+
+type findingKey struct{ repo, check string }
+
+func findingSet(findings []SuppressedFinding) map[findingKey]SuppressedFinding {
+	set := make(map[findingKey]SuppressedFinding, len(findings))
+	for _, f := range findings {
+		set[findingKey{f.Repository, f.Check}] = f
+	}
+	return set
+}
+
+func sortFindings(findings []SuppressedFinding) {
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Repository != findings[j].Repository {
+			return findings[i].Repository < findings[j].Repository
+		}
+		return findings[i].Check < findings[j].Check
+	})
+}
+
+// subtract returns the elements of a not in b.
+func subtract(a, b []string) []string {
+	var out []string
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func addRepo(m map[string][]string, check, repo string) map[string][]string {
+	if m == nil {
+		m = make(map[string][]string)
+	}
+	m[check] = append(m[check], repo)
+	return m
+}
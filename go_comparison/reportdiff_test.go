@@ -0,0 +1,142 @@
+package scanner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func score(v float64) *float64 { return &v }
+
+func TestCompareReports(t *testing.T) {
+	old := Report{
+		Org: "acme", TotalRepos: 4, FullyCompliant: 2, ComplianceRate: "50.0%",
+		ComplianceScore: score(80), Errors: 1,
+		RepoFailures: map[string][]string{
+			"api":     {},
+			"web":     {CheckCodeScanning},
+			"billing": {CheckDependabot, CheckCodeScanning},
+			"legacy":  {},
+		},
+	}
+	cur := Report{
+		Org: "acme", TotalRepos: 4, FullyCompliant: 2, ComplianceRate: "50.0%",
+		ComplianceScore: score(77.5), Errors: 0,
+		RepoFailures: map[string][]string{
+			"api":     {CheckSecretScanning, CheckCodeScanning},
+			"web":     {},
+			"billing": {CheckCodeScanning},
+			"search":  {},
+		},
+	}
+
+	d := CompareReports(old, cur)
+	require.Equal(t, "acme", d.Org)
+	require.Equal(t, 0.0, d.RateDelta)
+	require.Equal(t, -2.5, *d.ScoreDelta)
+	require.Equal(t, -1, d.ErrorsDelta)
+	require.Equal(t, map[string][]string{
+		CheckSecretScanning: {"api"},
+		CheckCodeScanning:   {"api"},
+	}, d.Regressions)
+	require.Equal(t, map[string][]string{
+		CheckCodeScanning: {"web"},
+		CheckDependabot:   {"billing"},
+	}, d.Improvements)
+	require.Equal(t, []string{"search"}, d.Added)
+	require.Equal(t, []string{"legacy"}, d.Removed)
+	require.True(t, d.HasRegressions())
+	require.True(t, d.HasChanges())
+}
+
+func TestCompareReportsUnchanged(t *testing.T) {
+	r := Report{
+		Org: "acme", TotalRepos: 2, FullyCompliant: 1, ComplianceRate: "50.0%",
+		RepoFailures: map[string][]string{"api": {}, "web": {CheckDependabot}},
+		Suppressed:   []SuppressedFinding{{Repository: "web", Check: CheckCodeScanning, Justification: "no CI"}},
+	}
+	d := CompareReports(r, r)
+	require.False(t, d.HasRegressions())
+	require.False(t, d.HasChanges())
+}
+
+func TestCompareReportsSuppressionChanges(t *testing.T) {
+	waiver := SuppressedFinding{Repository: "web", Check: CheckCodeScanning, Justification: "no CI", Expires: "2026-06-30"}
+	failing := Report{
+		Org: "acme", TotalRepos: 1, ComplianceRate: "0.0%",
+		RepoFailures: map[string][]string{"web": {CheckCodeScanning}},
+	}
+	suppressed := Report{
+		Org: "acme", TotalRepos: 1, FullyCompliant: 1, ComplianceRate: "100.0%",
+		RepoFailures: map[string][]string{"web": {}},
+		Suppressed:   []SuppressedFinding{waiver},
+	}
+
+	// A new suppression is reported as such, not as a fix.
+	d := CompareReports(failing, suppressed)
+	require.Empty(t, d.Improvements)
+	require.Equal(t, []SuppressedFinding{waiver}, d.NewlySuppressed)
+	require.False(t, d.HasRegressions())
+	require.True(t, d.HasChanges())
+
+	// When it expires, the finding is a regression again.
+	d = CompareReports(suppressed, failing)
+	require.Equal(t, map[string][]string{CheckCodeScanning: {"web"}}, d.Regressions)
+	require.Equal(t, []SuppressedFinding{waiver}, d.Unsuppressed)
+
+	// A changed suppression with identical compliance is still a change.
+	extended := suppressed
+	extended.Suppressed = []SuppressedFinding{{Repository: "web", Check: CheckCodeScanning, Justification: "no CI", Expires: "2026-12-31"}}
+	d = CompareReports(suppressed, extended)
+	require.Equal(t, extended.Suppressed, d.SuppressionChanged)
+	require.Empty(t, d.NewlySuppressed)
+	require.True(t, d.HasChanges())
+	require.False(t, d.HasRegressions())
+	moved := suppressed
+	moved.Suppressed = []SuppressedFinding{{Repository: "web", Check: CheckDependabot, Justification: "vendored"}}
+	d = CompareReports(suppressed, moved)
+	require.True(t, d.HasChanges())
+	require.False(t, d.HasRegressions())
+}
+
+func TestCompareReportsLegacy(t *testing.T) {
+	// Reports saved before repo_failures only list non-compliant repos.
+	old := Report{Org: "acme", TotalRepos: 3, FullyCompliant: 2, ComplianceRate: "66.7%", NonCompliantRepos: []string{"web"}}
+	cur := Report{
+		Org: "acme", TotalRepos: 3, FullyCompliant: 1, ComplianceRate: "33.3%",
+		NonCompliantRepos: []string{"api", "billing"},
+		RepoFailures:      map[string][]string{"api": {CheckDependabot}, "billing": {CheckCodeScanning}, "web": {}},
+	}
+
+	d := CompareReports(old, cur)
+	require.Equal(t, -33.4, d.RateDelta)
+	require.Nil(t, d.ScoreDelta)
+	require.Equal(t, map[string][]string{legacyCheck: {"api", "billing"}}, d.Regressions)
+	require.Equal(t, map[string][]string{legacyCheck: {"web"}}, d.Improvements)
+	require.Empty(t, d.Added, "repo sets are unknown for legacy reports")
+	require.Empty(t, d.Removed)
+}
+
+func TestParseReportFromGenerateReport(t *testing.T) {
+	results := []RepoSecurityResult{
+		scoredResult("alpha", map[string]SecurityStatus{CheckSecretScanning: StatusEnabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusEnabled}),
+		scoredResult("bravo", map[string]SecurityStatus{CheckSecretScanning: StatusDisabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusNotConfigured}),
+	}
+	env := newActivityEnv(&Activities{})
+	val, err := env.ExecuteActivity("GenerateReport", "acme", results, []BlobRef(nil), DefaultCompliancePolicy(), []string(nil), []Suppression(nil))
+	require.NoError(t, err)
+	var raw map[string]interface{}
+	require.NoError(t, val.Get(&raw))
+	data, err := json.Marshal(raw)
+	require.NoError(t, err)
+
+	r, err := ParseReport(data)
+	require.NoError(t, err)
+	require.Equal(t, 2, r.TotalRepos)
+	require.Equal(t, 50.0, r.Rate())
+	require.Equal(t, map[string][]string{
+		"alpha": {},
+		"bravo": {CheckSecretScanning, CheckCodeScanning},
+	}, r.RepoFailures)
+}
@@ -0,0 +1,283 @@
+package scanner
+
+// =============================================================================
+// Report schema — a versioned contract for report consumers
+// =============================================================================
+//
+// Dashboards and ticketing jobs read the saved reports, and broke whenever a
+// field changed under them. Every report now carries schema_version, and
+// ReportJSONSchema describes it, generated from the Report struct's json
+// tags. The published copy is report.schema.json next to this file;
+// TestReportSchema fails when the struct and that file disagree, and
+// enforces the bump rules below before -update may rewrite it.
+//
+// ReportSchemaVersion is MAJOR or MAJOR.MINOR:
+//
+//	minor  fields were added; readers of the old schema are unaffected
+//	major  fields were removed, renamed or retyped, or stopped being required
+//
+// Reports saved before schema_version existed have the version 1 shape.
+// Reports of different major versions cannot be compared.
+//
+// Report.Validate checks that a report's numbers agree with each other.
+// GenerateReport runs it on every report it builds, so an aggregation bug
+// fails the scan instead of publishing a report that contradicts itself.
+// =============================================================================
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportSchemaVersion is the schema_version of the reports this build writes.
+const ReportSchemaVersion = "1.27"
+
+// ErrTypeInvalidReport is the ApplicationError type for a report that fails
+// Report.Validate.
+const ErrTypeInvalidReport = "INVALID_REPORT"
+
+// ReportSchemaID is the $id of ReportJSONSchema.
+const ReportSchemaID = "https://github.com/salkimmich/temporal-security-scanner/blob/main/go_comparison/report.schema.json"
+
+// SchemaMajor is the major version of the report's schema_version; reports
+// without one are version 1.
+func (r Report) SchemaMajor() (int, error) {
+	if r.SchemaVersion == "" {
+		return 1, nil
+	}
+	major, _, _ := strings.Cut(r.SchemaVersion, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid schema_version %q", r.SchemaVersion)
+	}
+	return n, nil
+}
+
+// CheckComparable returns an error unless old and cur have the same major
+// schema version, which CompareReports needs.
+func CheckComparable(old, cur Report) error {
+	oldMajor, err := old.SchemaMajor()
+	if err != nil {
+		return err
+	}
+	curMajor, err := cur.SchemaMajor()
+	if err != nil {
+		return err
+	}
+	if oldMajor != curMajor {
+		return fmt.Errorf("report schema versions %d and %d are incompatible", oldMajor, curMajor)
+	}
+	return nil
+}
+
+// Validate checks that the report's counts agree: compliant, non-compliant
+// and indeterminate repos fit in the total, repo_failures matches
+// non_compliant_repos and indeterminate_repos, per-check counts are within
+// the total, the compliance rate is the one the counts give, and the
+// visibility and language breakdowns add up.
+func (r Report) Validate() error {
+	if r.TotalRepos < 0 || r.FullyCompliant < 0 || r.Errors < 0 || r.Indeterminate < 0 {
+		return fmt.Errorf("report has a negative count")
+	}
+	if r.Indeterminate != len(r.IndeterminateRepos) {
+		return fmt.Errorf("indeterminate is %d but indeterminate_repos has %d", r.Indeterminate, len(r.IndeterminateRepos))
+	}
+	if r.TokenLacksRepoAccess != len(r.TokenLacksRepoAccessRepos) {
+		return fmt.Errorf("token_lacks_repo_access is %d but token_lacks_repo_access_repos has %d", r.TokenLacksRepoAccess, len(r.TokenLacksRepoAccessRepos))
+	}
+	if r.Archived != len(r.ArchivedRepos) {
+		return fmt.Errorf("archived is %d but archived_repos has %d", r.Archived, len(r.ArchivedRepos))
+	}
+	if r.Disappeared != len(r.DisappearedRepos) {
+		return fmt.Errorf("disappeared is %d but disappeared_repos has %d", r.Disappeared, len(r.DisappearedRepos))
+	}
+	if len(r.Evidence) > 0 && r.EvidenceRef != nil {
+		return fmt.Errorf("evidence is both inline and in evidence_ref")
+	}
+	if r.PriorityReposScanned > r.PriorityRepos {
+		return fmt.Errorf("priority_repos_scanned %d is more than priority_repos %d", r.PriorityReposScanned, r.PriorityRepos)
+	}
+	if r.DeadlineReached && len(r.UnscannedRepos) == 0 {
+		return fmt.Errorf("deadline_reached is set but unscanned_repos is empty")
+	}
+	if len(r.UnscannedRepos) > 0 && !r.DeadlineReached && !r.Cancelled && (r.APIUsage == nil || !r.APIUsage.BudgetExceeded) {
+		return fmt.Errorf("unscanned_repos lists %d repos but the scan was neither cancelled nor stopped at its deadline or API budget", len(r.UnscannedRepos))
+	}
+	if n := r.FullyCompliant + len(r.NonCompliantRepos) + r.Indeterminate; n > r.TotalRepos {
+		return fmt.Errorf("%d compliant, %d non-compliant and %d indeterminate repos exceed total_repos %d",
+			r.FullyCompliant, len(r.NonCompliantRepos), r.Indeterminate, r.TotalRepos)
+	}
+	if r.RepoFailures != nil {
+		if len(r.RepoFailures) > r.TotalRepos {
+			return fmt.Errorf("repo_failures has %d repos, more than total_repos %d", len(r.RepoFailures), r.TotalRepos)
+		}
+		failing := 0
+		for _, failed := range r.RepoFailures {
+			if len(failed) > 0 {
+				failing++
+			}
+		}
+		for _, repo := range append(r.NonCompliantRepos, r.IndeterminateRepos...) {
+			if len(r.RepoFailures[repo]) == 0 {
+				return fmt.Errorf("non-compliant repo %s has no failures in repo_failures", repo)
+			}
+		}
+		if want := len(r.NonCompliantRepos) + r.Indeterminate; failing != want {
+			return fmt.Errorf("repo_failures has %d failing repos but non_compliant_repos and indeterminate_repos have %d", failing, want)
+		}
+		if passing := len(r.RepoFailures) - failing; passing > r.FullyCompliant {
+			return fmt.Errorf("repo_failures has %d passing repos but fully_compliant is %d", passing, r.FullyCompliant)
+		}
+	}
+	if r.ErrorsByCategory != nil {
+		sum := 0
+		for _, n := range r.ErrorsByCategory {
+			sum += n
+		}
+		if sum != r.Errors {
+			return fmt.Errorf("errors_by_category adds up to %d, not errors %d", sum, r.Errors)
+		}
+	}
+	if len(r.RetryLaterRepos) > r.Errors {
+		return fmt.Errorf("retry_later_repos has %d repos, more than errors %d", len(r.RetryLaterRepos), r.Errors)
+	}
+	if r.ComplianceRate != "" {
+		want := complianceRate(r.FullyCompliant, r.TotalRepos, r.Indeterminate)
+		if r.ComplianceRate != want {
+			return fmt.Errorf("compliance_rate %s does not match %d of %d determinate repos (%s)", r.ComplianceRate, r.FullyCompliant, r.determinate(), want)
+		}
+	}
+	for name, buckets := range map[string]map[string]ComplianceBreakdown{"by_visibility": r.ByVisibility, "by_language": r.ByLanguage} {
+		if buckets != nil {
+			if err := validateBreakdown(name, buckets, r); err != nil {
+				return err
+			}
+		}
+	}
+	if err := validateTopicBreakdown(r.ByTopic, r); err != nil {
+		return err
+	}
+	if s := r.ComplianceScore; s != nil && (*s < 0 || *s > 100) {
+		return fmt.Errorf("compliance_score %g is outside 0-100", *s)
+	}
+	for field, n := range map[string]*int{
+		"secret_scanning_enabled":         r.SecretScanningEnabled,
+		"dependabot_enabled":              r.DependabotEnabled,
+		"code_scanning_enabled":           r.CodeScanningEnabled,
+		"codeowners_present":              r.CodeownersPresent,
+		"security_policy_present":         r.SecurityPolicyPresent,
+		"actions_enabled":                 r.ActionsEnabled,
+		"read_only_workflow_token":        r.ReadOnlyWorkflowToken,
+		"security_updates_enabled":        r.SecurityUpdatesEnabled,
+		"dependabot_config_present":       r.DependabotConfigPresent,
+		"dependabot_config_misconfigured": r.DependabotConfigMisconfigured,
+		"webhooks_secure":                 r.WebhooksSecure,
+	} {
+		if n != nil && (*n < 0 || *n > r.TotalRepos) {
+			return fmt.Errorf("%s is %d, outside 0-%d", field, *n, r.TotalRepos)
+		}
+	}
+	for issue, n := range r.WebhookFindings {
+		if n < 0 {
+			return fmt.Errorf("webhook_findings[%s] is negative", issue)
+		}
+	}
+	if a := r.AccessAudit; a != nil && a.FlaggedDeployKeys > a.DeployKeys {
+		return fmt.Errorf("access_audit flags %d of only %d deploy keys", a.FlaggedDeployKeys, a.DeployKeys)
+	}
+	return nil
+}
+
+// validateReport decodes a report map as built by GenerateReport and
+// validates it.
+func validateReport(report map[string]interface{}) error {
+	b, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	r, err := ParseReport(b)
+	if err != nil {
+		return err
+	}
+	return r.Validate()
+}
+
+// ReportJSONSchema is the JSON Schema (draft 2020-12) of the reports this
+// build writes.
+func ReportJSONSchema() ([]byte, error) {
+	// Report's MarshalJSON only adds keys its fields already describe.
+	schema := jsonSchemaOf(reflect.TypeOf(reportFields{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = ReportSchemaID
+	schema["title"] = "Security scan report"
+	schema["x-schema-version"] = ReportSchemaVersion
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// jsonSchemaOf describes how encoding/json writes a value of type t.
+// Pointers, slices and maps may also be null: reports are assembled as maps,
+// which write a nil list as null rather than leaving it out.
+func jsonSchemaOf(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t.Implements(marshalerType) {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(jsonSchemaOf(t.Elem()))
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return nullable(map[string]interface{}{"type": "array", "items": jsonSchemaOf(t.Elem())})
+	case reflect.Map:
+		return nullable(map[string]interface{}{"type": "object", "additionalProperties": jsonSchemaOf(t.Elem())})
+	case reflect.Struct:
+		props := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = jsonSchemaOf(f.Type)
+			if !strings.Contains(","+opts+",", ",omitempty,") {
+				required = append(required, name)
+			}
+		}
+		return map[string]interface{}{"type": "object", "properties": props, "required": required}
+	}
+	return map[string]interface{}{}
+}
+
+// nullable lets schema also match null.
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []string{typ, "null"}
+	}
+	return schema
+}
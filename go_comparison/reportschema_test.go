@@ -0,0 +1,325 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const publishedSchema = "report.schema.json"
+
+// TestReportSchema keeps report.schema.json in step with the Report struct
+// and enforces the bump rules in reportschema.go: any change needs a new
+// ReportSchemaVersion, and a change that breaks readers needs a new major.
+func TestReportSchema(t *testing.T) {
+	got, err := ReportJSONSchema()
+	require.NoError(t, err)
+	got = append(got, '\n')
+
+	published, err := os.ReadFile(publishedSchema)
+	if err == nil && bytes.Equal(got, published) {
+		return
+	}
+	if err == nil {
+		var old, cur map[string]interface{}
+		require.NoError(t, json.Unmarshal(published, &old))
+		require.NoError(t, json.Unmarshal(got, &cur))
+		oldVersion, _ := old["x-schema-version"].(string)
+		if oldVersion == ReportSchemaVersion {
+			t.Fatalf("the report schema changed but ReportSchemaVersion is still %s; bump it (see reportschema.go) and rerun with -update", oldVersion)
+		}
+		oldMajor, err := Report{SchemaVersion: oldVersion}.SchemaMajor()
+		require.NoError(t, err)
+		curMajor, err := Report{SchemaVersion: ReportSchemaVersion}.SchemaMajor()
+		require.NoError(t, err)
+		if breaks := schemaBreaks(old, cur, ""); len(breaks) > 0 && curMajor <= oldMajor {
+			t.Fatalf("these changes need a major ReportSchemaVersion bump: %v", breaks)
+		}
+	}
+	if !*updateGolden {
+		t.Fatalf("%s is out of date; rerun with -update", publishedSchema)
+	}
+	require.NoError(t, os.WriteFile(publishedSchema, got, 0o644))
+}
+
+// schemaBreaks lists the differences from old to cur that can break a
+// reader of old: removed properties, changed types, and properties that are
+// no longer required.
+func schemaBreaks(old, cur map[string]interface{}, path string) []string {
+	var breaks []string
+	if !reflect.DeepEqual(old["type"], cur["type"]) {
+		breaks = append(breaks, fmt.Sprintf("%s: type %v is now %v", path, old["type"], cur["type"]))
+	}
+	oldProps, _ := old["properties"].(map[string]interface{})
+	curProps, _ := cur["properties"].(map[string]interface{})
+	for name, o := range oldProps {
+		c, ok := curProps[name]
+		if !ok {
+			breaks = append(breaks, fmt.Sprintf("%s.%s: removed", path, name))
+			continue
+		}
+		breaks = append(breaks, schemaBreaks(o.(map[string]interface{}), c.(map[string]interface{}), path+"."+name)...)
+	}
+	required := map[string]bool{}
+	for _, name := range asSlice(cur["required"]) {
+		required[name.(string)] = true
+	}
+	for _, name := range asSlice(old["required"]) {
+		if !required[name.(string)] {
+			breaks = append(breaks, fmt.Sprintf("%s.%s: no longer required", path, name))
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		o, ok1 := old[key].(map[string]interface{})
+		c, ok2 := cur[key].(map[string]interface{})
+		if ok1 && ok2 {
+			breaks = append(breaks, schemaBreaks(o, c, path+"[]")...)
+		}
+	}
+	sort.Strings(breaks)
+	return breaks
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
+
+func TestSchemaBreaks(t *testing.T) {
+	schema := func() map[string]interface{} {
+		b, err := ReportJSONSchema()
+		require.NoError(t, err)
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal(b, &m))
+		return m
+	}
+	props := func(m map[string]interface{}) map[string]interface{} {
+		return m["properties"].(map[string]interface{})
+	}
+	old := schema()
+	require.Empty(t, schemaBreaks(old, schema(), ""))
+
+	added := schema()
+	props(added)["new_field"] = map[string]interface{}{"type": "string"}
+	require.Empty(t, schemaBreaks(old, added, ""), "adding a field is a minor change")
+
+	removed := schema()
+	delete(props(removed), "teams")
+	require.Equal(t, []string{".teams: removed"}, schemaBreaks(old, removed, ""))
+
+	retyped := schema()
+	props(retyped)["errors"] = map[string]interface{}{"type": "string"}
+	require.Equal(t, []string{".errors: type integer is now string"}, schemaBreaks(old, retyped, ""))
+
+	optional := schema()
+	optional["required"] = []interface{}{"org"}
+	require.Contains(t, schemaBreaks(old, optional, ""), ".total_repos: no longer required")
+}
+
+// matchSchema checks v, decoded from JSON, against the subset of JSON Schema
+// that ReportJSONSchema uses. Unlike the schema itself it rejects unknown
+// object keys, so a field the scanner writes but Report lacks is caught.
+func matchSchema(schema map[string]interface{}, v interface{}, path string) error {
+	if typ, ok := schema["type"]; ok {
+		var types []interface{}
+		if s, ok := typ.(string); ok {
+			types = []interface{}{s}
+		} else {
+			types = typ.([]interface{})
+		}
+		matched := false
+		for _, want := range types {
+			if jsonTypeIs(v, want.(string)) {
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: %v is not %v", path, v, typ)
+		}
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, name := range asSlice(schema["required"]) {
+			if _, ok := v[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %s", path, name)
+			}
+		}
+		for key, val := range v {
+			sub, ok := props[key].(map[string]interface{})
+			if !ok {
+				sub, ok = schema["additionalProperties"].(map[string]interface{})
+			}
+			if !ok {
+				return fmt.Errorf("%s: unknown key %s", path, key)
+			}
+			if err := matchSchema(sub, val, path+"."+key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		for i, val := range v {
+			if err := matchSchema(items, val, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func jsonTypeIs(v interface{}, typ string) bool {
+	switch v := v.(type) {
+	case nil:
+		return typ == "null"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case float64:
+		return typ == "number" || typ == "integer" && v == math.Trunc(v)
+	case []interface{}:
+		return typ == "array"
+	case map[string]interface{}:
+		return typ == "object"
+	}
+	return false
+}
+
+func TestGeneratedReportMatchesSchema(t *testing.T) {
+	schemaJSON, err := ReportJSONSchema()
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(schemaJSON, &schema))
+
+	alpha := scoredResult("alpha", map[string]SecurityStatus{CheckSecretScanning: StatusEnabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusEnabled})
+	alpha.setActions(hardenedActions)
+	alpha.setAccess(&AccessAudit{DeployKeys: 1})
+	bravo := scoredResult("bravo", map[string]SecurityStatus{CheckSecretScanning: StatusDisabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusEnabled})
+	bravo.setAccess(&AccessAudit{DeployKeys: 1, NoAccess: []string{AccessCollaborators}})
+	mirror := scoredResult("mirror", map[string]SecurityStatus{CheckSecretScanning: StatusEnabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusDisabled})
+	start := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+
+	// Every optional section the scanner writes.
+	report, err := (&Activities{}).BuildReport(context.Background(), ReportInput{
+		Org:     "acme",
+		Results: []RepoSecurityResult{alpha, bravo, mirror},
+		Policy:  DefaultCompliancePolicy(),
+		Checks:  []string{CheckSecretScanning, CheckDependabot, CheckCodeScanning, CheckFiles, CheckActions, CheckAccessAudit},
+		Suppressions: []Suppression{
+			{Repo: "mirror", Checks: []string{CheckCodeScanning}, Justification: "read-only mirror"},
+		},
+		Errors:              1,
+		EstimatedAPICalls:   40,
+		ExpiredSuppressions: []Suppression{{Repo: "old", Justification: "gone", Expires: "2026-01-01"}},
+		ActiveWithinDays:    90,
+		SkippedInactive:     []string{"dusty"},
+		Teams:               []string{"platform"},
+		DuplicateRepos:      2,
+		Provider:            ProviderGitHub,
+		WorkflowID:          "security-scan-acme",
+		RunID:               "run-1",
+		StartedAt:           start,
+		CompletedAt:         start.Add(time.Minute),
+		Cancelled:           true,
+		CancelReason:        "stopped",
+		TokenCapabilities: &TokenCapabilities{Kind: TokenClassic, Scopes: []string{"repo"},
+			Checks: []CheckCapability{{Check: CheckSecretScanning, Access: AccessFull}}},
+		APIUsage: &APIUsage{Requests: 12, ByCategory: map[string]int{"repo": 12}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, ReportSchemaVersion, report["schema_version"])
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.NoError(t, matchSchema(schema, decoded, "report"))
+
+	r, err := ParseReport(data)
+	require.NoError(t, err)
+	require.NoError(t, r.Validate())
+
+	// An empty scan writes nulls for its lists.
+	empty, err := (&Activities{}).BuildReport(context.Background(), ReportInput{Org: "acme", Policy: DefaultCompliancePolicy()})
+	require.NoError(t, err)
+	data, err = json.Marshal(empty)
+	require.NoError(t, err)
+	decoded = nil
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.NoError(t, matchSchema(schema, decoded, "report"))
+}
+
+func TestReportValidate(t *testing.T) {
+	valid := func() Report {
+		return Report{
+			TotalRepos: 4, FullyCompliant: 2, ComplianceRate: "50.0%",
+			NonCompliantRepos:     []string{"api", "web"},
+			RepoFailures:          map[string][]string{"api": {CheckDependabot}, "web": {CheckCodeScanning}, "docs": {}, "cli": {}},
+			SecretScanningEnabled: count(4),
+			AccessAudit:           &AccessAuditSummary{DeployKeys: 3, FlaggedDeployKeys: 1},
+		}
+	}
+	require.NoError(t, valid().Validate())
+	require.NoError(t, Report{TotalRepos: 0, ComplianceRate: "N/A"}.Validate())
+	require.NoError(t, Report{TotalRepos: 3, FullyCompliant: 1, NonCompliantRepos: []string{"a"}}.Validate(),
+		"legacy reports have no repo_failures")
+	withIndeterminate := valid()
+	withIndeterminate.RepoFailures["cli"] = []string{CheckCodeScanning}
+	withIndeterminate.FullyCompliant = 1
+	withIndeterminate.Indeterminate = 1
+	withIndeterminate.IndeterminateRepos = []string{"cli"}
+	withIndeterminate.ComplianceRate = "33.3%"
+	require.NoError(t, withIndeterminate.Validate(), "indeterminate repos are out of the rate")
+	withIndeterminate.Indeterminate = 2
+	require.ErrorContains(t, withIndeterminate.Validate(), "indeterminate is 2 but indeterminate_repos has 1")
+
+	tests := []struct {
+		name   string
+		break_ func(*Report)
+		want   string
+	}{
+		{"too many repos", func(r *Report) { r.FullyCompliant = 3; r.ComplianceRate = "75.0%" }, "exceed total_repos"},
+		{"rate", func(r *Report) { r.ComplianceRate = "40.0%" }, "does not match 2 of 4 determinate repos (50.0%)"},
+		{"empty rate", func(r *Report) {
+			r.TotalRepos = 0
+			r.FullyCompliant = 0
+			r.NonCompliantRepos = nil
+			r.RepoFailures = nil
+		}, "N/A"},
+		{"unlisted failure", func(r *Report) { r.RepoFailures["docs"] = []string{CheckDependabot} }, "3 failing repos"},
+		{"listed without failures", func(r *Report) { r.RepoFailures["web"] = nil }, "web has no failures"},
+		{"passing count", func(r *Report) { r.FullyCompliant = 1; r.ComplianceRate = "25.0%" }, "2 passing repos but fully_compliant is 1"},
+		{"check count", func(r *Report) { r.SecretScanningEnabled = count(5) }, "secret_scanning_enabled is 5"},
+		{"archived count", func(r *Report) { r.Archived = 1 }, "archived is 1 but archived_repos has 0"},
+		{"deadline without unscanned", func(r *Report) { r.DeadlineReached = true }, "unscanned_repos is empty"},
+		{"unscanned of a finished scan", func(r *Report) { r.UnscannedRepos = []string{"cli"} }, "neither cancelled nor stopped at its deadline"},
+		{"score", func(r *Report) { r.ComplianceScore = score(101) }, "compliance_score 101"},
+		{"negative", func(r *Report) { r.Errors = -1 }, "negative"},
+		{"deploy keys", func(r *Report) { r.AccessAudit.FlaggedDeployKeys = 4 }, "flags 4 of only 3"},
+	}
+	for _, tc := range tests {
+		r := valid()
+		tc.break_(&r)
+		err := r.Validate()
+		require.Error(t, err, tc.name)
+		require.Contains(t, err.Error(), tc.want, tc.name)
+	}
+}
+
+func TestCheckComparable(t *testing.T) {
+	require.NoError(t, CheckComparable(Report{}, Report{SchemaVersion: ReportSchemaVersion}), "legacy reports are version 1")
+	require.NoError(t, CheckComparable(Report{SchemaVersion: "1"}, Report{SchemaVersion: "1.3"}))
+	require.EqualError(t, CheckComparable(Report{SchemaVersion: "1.3"}, Report{SchemaVersion: "2"}),
+		"report schema versions 1 and 2 are incompatible")
+	require.EqualError(t, CheckComparable(Report{SchemaVersion: "v2"}, Report{}), `invalid schema_version "v2"`)
+}
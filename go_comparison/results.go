@@ -0,0 +1,53 @@
+package scanner
+
+// =============================================================================
+// Results queries — the per-repo results of a running or finished scan
+// =============================================================================
+//
+// results_so_far returns every result the workflow holds inline in one
+// query response, which the server caps in size (a few MB). results_page
+// returns them a page at a time, so a scan with thousands of repos can
+// still be read while it runs:
+//
+//	results_page(offset, limit) -> ResultsPage
+//
+// Both answer for closed workflows too: a worker replays the run. Results
+// offloaded to the blob store (see blobstore.go) are in neither; the report
+// lists their refs.
+// =============================================================================
+
+// Query names of the per-repo results.
+const (
+	ResultsSoFarQuery = "results_so_far"
+	ResultsPageQuery  = "results_page"
+)
+
+// DefaultResultsPageSize is the page size of results_page when the caller
+// passes no limit.
+const DefaultResultsPageSize = 200
+
+// ResultsPage is one page of the results a workflow holds inline.
+type ResultsPage struct {
+	Results []RepoSecurityResult `json:"results"`
+	Offset  int                  `json:"offset"`
+	// Total is how many results the workflow holds inline.
+	Total int `json:"total"`
+	// NextOffset is the offset of the next page; 0 after the last page.
+	NextOffset int `json:"next_offset,omitempty"`
+}
+
+// PageResults returns the page of results starting at offset. A limit of 0
+// or less means DefaultResultsPageSize; an offset past the end returns an
+// empty page.
+func PageResults(results []RepoSecurityResult, offset, limit int) ResultsPage {
+	if limit <= 0 {
+		limit = DefaultResultsPageSize
+	}
+	offset = min(max(offset, 0), len(results))
+	end := min(offset+limit, len(results))
+	page := ResultsPage{Results: results[offset:end:end], Offset: offset, Total: len(results)}
+	if end < len(results) {
+		page.NextOffset = end
+	}
+	return page
+}
@@ -0,0 +1,62 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageResults(t *testing.T) {
+	results := make([]RepoSecurityResult, 5)
+	for i, repo := range fakeRepos(5) {
+		results[i].Repository = repo.Name
+	}
+	names := func(p ResultsPage) []string {
+		var out []string
+		for _, r := range p.Results {
+			out = append(out, r.Repository)
+		}
+		return out
+	}
+
+	p := PageResults(results, 0, 2)
+	require.Equal(t, []string{"repo-000", "repo-001"}, names(p))
+	require.Equal(t, ResultsPage{Results: p.Results, Offset: 0, Total: 5, NextOffset: 2}, p)
+
+	p = PageResults(results, 4, 2)
+	require.Equal(t, []string{"repo-004"}, names(p))
+	require.Zero(t, p.NextOffset, "the last page")
+
+	p = PageResults(results, 9, 2)
+	require.Empty(t, p.Results)
+	require.Equal(t, 5, p.Offset)
+
+	p = PageResults(results, -1, 0)
+	require.Len(t, p.Results, 5, "no limit is the default page size")
+	require.Zero(t, p.Offset)
+
+	p = PageResults(results, 0, 2)
+	p.Results = append(p.Results, RepoSecurityResult{Repository: "appended"})
+	require.Equal(t, "repo-002", results[2].Repository, "a page does not alias the rest")
+}
+
+func TestWorkflowResultsPageQuery(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	val, err := env.QueryWorkflow(ResultsPageQuery, 1, 1)
+	require.NoError(t, err)
+	var page ResultsPage
+	require.NoError(t, val.Get(&page))
+	require.Len(t, page.Results, 1)
+	require.Equal(t, 1, page.Offset)
+	require.Equal(t, 3, page.Total)
+	require.Equal(t, 2, page.NextOffset)
+}
@@ -0,0 +1,119 @@
+package scanner
+
+// =============================================================================
+// Resumed scans — continuing a cancelled or deadline-stopped scan
+// =============================================================================
+//
+// A scan that was cancelled or stopped at its deadline lists the repos it
+// did not get to in its report's unscanned_repos. ScanInput.ResumeFrom
+// hands a later scan of the org those repos together with the stopped
+// scan's results: the ones held inline, as its results_so_far query
+// returns them, and the claim checks in its report's results_blob_refs.
+// The later scan scans only the unscanned repos and reports on the stopped
+// scan's results and its own together, naming the runs it continues in
+// resumed_from_run_ids. Its results_so_far and results_blob_refs include
+// the carried-over results, so a resumed scan can be resumed in turn.
+//
+// A repo with results from more than one run is counted once, by its
+// newest result (RepoSecurityResult.ScannedAt). Repos the stopped scan
+// failed on are not carried over; its errors stay in its own report.
+// =============================================================================
+
+import (
+	"fmt"
+	"time"
+)
+
+// ResumeState is where a cancelled or deadline-stopped scan continues. Its
+// unscanned_repos and results_blob_refs decode from the stopped scan's
+// report.
+type ResumeState struct {
+	// UnscannedRepos are the repos to scan, out of those the org lists;
+	// the report's unscanned_repos. Without them, every listed repo
+	// without a result in Results is scanned.
+	UnscannedRepos []string `json:"unscanned_repos"`
+	// Results are the stopped scan's results held inline, and Refs the
+	// claim checks of those it offloaded.
+	Results []RepoSecurityResult `json:"results,omitempty"`
+	Refs    []BlobRef            `json:"results_blob_refs,omitempty"`
+	// RunIDs are the runs of the stopped scan and of the scans it resumed
+	// in turn, oldest first.
+	RunIDs []string `json:"run_ids,omitempty"`
+}
+
+func (s *ResumeState) validate() error {
+	if len(s.UnscannedRepos) == 0 && len(s.Results) == 0 && len(s.Refs) == 0 {
+		return fmt.Errorf("resume_from has nothing to resume: no unscanned repos and no results")
+	}
+	return nil
+}
+
+// filter keeps the repos of repos that are left to scan, and returns the
+// unscanned repos of s no longer listed.
+func (s *ResumeState) filter(repos []RepoInfo) (kept []RepoInfo, gone []string) {
+	if len(s.UnscannedRepos) == 0 {
+		scanned := make(map[string]bool, len(s.Results))
+		for _, r := range s.Results {
+			scanned[r.Repository] = true
+		}
+		for _, r := range repos {
+			if !scanned[r.Name] {
+				kept = append(kept, r)
+			}
+		}
+		return kept, nil
+	}
+	want := make(map[string]bool, len(s.UnscannedRepos))
+	for _, name := range s.UnscannedRepos {
+		want[name] = true
+	}
+	for _, r := range repos {
+		if want[r.Name] {
+			kept = append(kept, r)
+			delete(want, r.Name)
+		}
+	}
+	for _, name := range s.UnscannedRepos {
+		if want[name] {
+			gone = append(gone, name)
+		}
+	}
+	return kept, gone
+}
+
+// resumedFromRunIDs are the runs a scan resuming s continues; nil when it
+// resumes nothing.
+func resumedFromRunIDs(s *ResumeState) []string {
+	if s == nil {
+		return nil
+	}
+	return s.RunIDs
+}
+
+// scannedAt is when the repo was scanned; zero when ScannedAt does not
+// parse, which makes the result the oldest.
+func (r *RepoSecurityResult) scannedAt() time.Time {
+	t, _ := time.Parse(time.RFC3339, r.ScannedAt)
+	return t
+}
+
+// newestResults keeps one result per repo: the one scanned last, or the
+// later one in results when they tie. The kept results stay in the order
+// their repos first appear; results without a repo name are all kept.
+func newestResults(results []RepoSecurityResult) []RepoSecurityResult {
+	index := make(map[string]int, len(results))
+	out := make([]RepoSecurityResult, 0, len(results))
+	for _, r := range results {
+		i, seen := index[r.Repository]
+		switch {
+		case r.Repository == "":
+			out = append(out, r)
+		case !seen:
+			index[r.Repository] = len(out)
+			out = append(out, r)
+		case !r.scannedAt().Before(out[i].scannedAt()):
+			out[i] = r
+		}
+	}
+	return out
+}
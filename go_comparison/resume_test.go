@@ -0,0 +1,114 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewestResults(t *testing.T) {
+	results := []RepoSecurityResult{
+		{Repository: "api", ScannedAt: "2026-03-01T02:00:00Z", CodeScanning: StatusNotConfigured},
+		{Repository: "web", ScannedAt: "2026-03-01T02:00:00Z"},
+		{Repository: "api", ScannedAt: "2026-03-02T02:00:00Z", CodeScanning: StatusEnabled},
+		{Repository: "web", ScannedAt: ""},
+	}
+	got := newestResults(results)
+	require.Len(t, got, 2)
+	require.Equal(t, "api", got[0].Repository)
+	require.Equal(t, StatusEnabled, got[0].CodeScanning, "the newer result wins")
+	require.Equal(t, "2026-03-01T02:00:00Z", got[1].ScannedAt, "a result without a time is the oldest")
+}
+
+func TestResumeStateFilter(t *testing.T) {
+	repos := fakeRepos(4)
+	unscanned := &ResumeState{UnscannedRepos: []string{"repo-002", "repo-009"}}
+	kept, gone := unscanned.filter(repos)
+	require.Equal(t, []RepoInfo{repos[2]}, kept)
+	require.Equal(t, []string{"repo-009"}, gone)
+
+	// Without an unscanned list, repos with a result are skipped.
+	scanned := &ResumeState{Results: []RepoSecurityResult{{Repository: "repo-000"}, {Repository: "repo-003"}}}
+	kept, gone = scanned.filter(repos)
+	require.Equal(t, []RepoInfo{repos[1], repos[2]}, kept)
+	require.Empty(t, gone)
+}
+
+func TestWorkflowCancelledScanListsUnscannedRepos(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(15))
+	for i := 0; i < 5; i++ {
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, fmt.Sprintf("repo-%03d", i), mock.Anything, mock.Anything).
+			Return(compliantUnless())
+	}
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(time.Hour).Return(compliantUnless())
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("cancel_scan", "change freeze")
+	}, 30*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, true, report["cancelled"])
+	require.NotContains(t, report, "deadline_reached")
+	require.Len(t, report["cancelled_in_flight_repos"], 5)
+	require.Len(t, report["unscanned_repos"], 10, "the repos cancelled in flight and the batch never started")
+}
+
+func TestWorkflowResumeMergesPriorResults(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(5))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, org, repo string, cred *Credential, checks []string) (*RepoSecurityResult, error) {
+			r, err := compliantUnless()(ctx, org, repo, cred, checks)
+			r.ScannedAt = "2026-03-02T02:00:00Z"
+			return r, err
+		})
+
+	prior := func(repo string, codeScanning SecurityStatus) RepoSecurityResult {
+		return RepoSecurityResult{Repository: repo, SecretScanning: StatusEnabled, DependabotAlerts: StatusEnabled,
+			CodeScanning: codeScanning, ScannedAt: "2026-03-01T02:00:00Z"}
+	}
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ResumeFrom: &ResumeState{
+		UnscannedRepos: []string{"repo-003", "repo-004"},
+		Results: []RepoSecurityResult{
+			prior("repo-000", StatusEnabled),
+			prior("repo-001", StatusNotConfigured),
+			prior("repo-002", StatusEnabled),
+			// Scanned again below; the fresh result replaces it.
+			prior("repo-003", StatusNotConfigured),
+		},
+		RunIDs: []string{"run-1", "run-2"},
+	}})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 2)
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 5, report["total_repos"])
+	require.EqualValues(t, 4, report["fully_compliant"])
+	require.Equal(t, []interface{}{"repo-001"}, report["non_compliant_repos"])
+	require.Equal(t, []interface{}{"run-1", "run-2"}, report["resumed_from_run_ids"])
+
+	val, err := env.QueryWorkflow("progress")
+	require.NoError(t, err)
+	var progress ScanProgress
+	require.NoError(t, val.Get(&progress))
+	require.Equal(t, 2, progress.TotalRepos, "progress covers this run's repos")
+
+	val, err = env.QueryWorkflow("results_so_far")
+	require.NoError(t, err)
+	var results []RepoSecurityResult
+	require.NoError(t, val.Get(&results))
+	require.Len(t, results, 6, "the carried-over results, so this scan can be resumed in turn")
+}
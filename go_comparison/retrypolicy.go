@@ -0,0 +1,158 @@
+package scanner
+
+// =============================================================================
+// Retry policies — one per kind of activity
+// =============================================================================
+//
+// The scan's activities fail in different ways, so they retry differently:
+//
+//	FetchRetryPolicy    listing repos; rides out GitHub outages of several
+//	                    minutes, since the scan cannot start without it
+//	ScanRetryPolicy     per-repo checks; backs off steeply, since their
+//	                    failures are mostly rate limits
+//	ReportRetryPolicy   building the report from results already held;
+//	                    a failure there is a bug, so it is tried twice
+//
+// Errors a retry cannot fix, such as an invalid input or a repo the token
+// cannot see, are non-retryable in each. ScanInput.RetryPolicies overrides
+// a policy's intervals and attempts per scan. Retry policies are not part
+// of the workflow's commands, so changing them is safe for running scans.
+// =============================================================================
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// FetchRetryPolicy is the retry policy for listing the repos to scan.
+func FetchRetryPolicy() *temporal.RetryPolicy {
+	return &temporal.RetryPolicy{
+		InitialInterval:    5 * time.Second,
+		BackoffCoefficient: 2.0,
+		MaximumInterval:    5 * time.Minute,
+		MaximumAttempts:    8,
+		NonRetryableErrorTypes: []string{
+			ErrTypeInvalidInput, ErrTypeAPIBudgetExceeded,
+			string(ErrorNotFound), string(ErrorNoAccess),
+		},
+	}
+}
+
+// ScanRetryPolicy is the retry policy for the per-repo check activities.
+func ScanRetryPolicy() *temporal.RetryPolicy {
+	return &temporal.RetryPolicy{
+		InitialInterval:    5 * time.Second,
+		BackoffCoefficient: 3.0,
+		MaximumInterval:    5 * time.Minute,
+		MaximumAttempts:    5,
+		NonRetryableErrorTypes: []string{
+			ErrTypeInvalidInput, ErrTypeAPIBudgetExceeded,
+			string(ErrorNotFound), string(ErrorNoAccess), string(ErrorParseError),
+		},
+	}
+}
+
+// ReportRetryPolicy is the retry policy for building and storing the
+// report.
+func ReportRetryPolicy() *temporal.RetryPolicy {
+	return &temporal.RetryPolicy{
+		InitialInterval:        time.Second,
+		BackoffCoefficient:     2.0,
+		MaximumInterval:        10 * time.Second,
+		MaximumAttempts:        2,
+		NonRetryableErrorTypes: []string{ErrTypeInvalidInput, ErrTypeInvalidReport},
+	}
+}
+
+// RetryPolicies overrides the scan's retry policies; nil keeps a
+// policy's defaults.
+type RetryPolicies struct {
+	Fetch  *RetryOverride `json:"fetch,omitempty"`
+	Scan   *RetryOverride `json:"scan,omitempty"`
+	Report *RetryOverride `json:"report,omitempty"`
+}
+
+// RetryOverride replaces the fields of a retry policy that are set.
+// The policy's non-retryable error types are kept.
+type RetryOverride struct {
+	InitialIntervalSeconds float64 `json:"initial_interval_seconds,omitempty"`
+	BackoffCoefficient     float64 `json:"backoff_coefficient,omitempty"`
+	MaximumIntervalSeconds float64 `json:"maximum_interval_seconds,omitempty"`
+	// MaximumAttempts of 1 turns retries off.
+	MaximumAttempts int32 `json:"maximum_attempts,omitempty"`
+}
+
+// validate checks that every override is usable.
+func (p *RetryPolicies) validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, o := range []struct {
+		name string
+		*RetryOverride
+	}{{"fetch", p.Fetch}, {"scan", p.Scan}, {"report", p.Report}} {
+		if err := o.validate(o.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks that o is usable; name says whose policy it overrides.
+func (o *RetryOverride) validate(name string) error {
+	if o == nil {
+		return nil
+	}
+	switch {
+	case o.InitialIntervalSeconds < 0, o.MaximumIntervalSeconds < 0, o.MaximumAttempts < 0:
+		return fmt.Errorf("%s retry policy: intervals and attempts must not be negative", name)
+	case o.BackoffCoefficient != 0 && o.BackoffCoefficient < 1:
+		return fmt.Errorf("%s retry policy: backoff coefficient must be at least 1, got %g", name, o.BackoffCoefficient)
+	}
+	return nil
+}
+
+// apply returns policy with o's fields set; policy itself when o is nil.
+func (o *RetryOverride) apply(policy *temporal.RetryPolicy) *temporal.RetryPolicy {
+	if o == nil {
+		return policy
+	}
+	if o.InitialIntervalSeconds > 0 {
+		policy.InitialInterval = time.Duration(o.InitialIntervalSeconds * float64(time.Second))
+	}
+	if o.BackoffCoefficient > 0 {
+		policy.BackoffCoefficient = o.BackoffCoefficient
+	}
+	if o.MaximumIntervalSeconds > 0 {
+		policy.MaximumInterval = time.Duration(o.MaximumIntervalSeconds * float64(time.Second))
+	}
+	if o.MaximumAttempts > 0 {
+		policy.MaximumAttempts = o.MaximumAttempts
+	}
+	return policy
+}
+
+// fetchRetryPolicy, scanRetryPolicy and reportRetryPolicy are the scan's
+// policies with its overrides applied. p may be nil.
+func (p *RetryPolicies) fetchRetryPolicy() *temporal.RetryPolicy {
+	if p == nil {
+		return FetchRetryPolicy()
+	}
+	return p.Fetch.apply(FetchRetryPolicy())
+}
+
+func (p *RetryPolicies) scanRetryPolicy() *temporal.RetryPolicy {
+	if p == nil {
+		return ScanRetryPolicy()
+	}
+	return p.Scan.apply(ScanRetryPolicy())
+}
+
+func (p *RetryPolicies) reportRetryPolicy() *temporal.RetryPolicy {
+	if p == nil {
+		return ReportRetryPolicy()
+	}
+	return p.Report.apply(ReportRetryPolicy())
+}
@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestRetryPolicyOverrides(t *testing.T) {
+	var none *RetryPolicies
+	require.Equal(t, FetchRetryPolicy(), none.fetchRetryPolicy())
+	require.Equal(t, ScanRetryPolicy(), none.scanRetryPolicy())
+	require.Equal(t, ReportRetryPolicy(), none.reportRetryPolicy())
+	require.Equal(t, ReportRetryPolicy(), (&RetryPolicies{Scan: &RetryOverride{MaximumAttempts: 9}}).reportRetryPolicy())
+
+	p := (&RetryPolicies{Scan: &RetryOverride{InitialIntervalSeconds: 0.5, MaximumAttempts: 9}}).scanRetryPolicy()
+	require.Equal(t, 500*time.Millisecond, p.InitialInterval)
+	require.EqualValues(t, 9, p.MaximumAttempts)
+	require.Equal(t, ScanRetryPolicy().BackoffCoefficient, p.BackoffCoefficient, "unset fields keep the default")
+	require.Equal(t, ScanRetryPolicy().NonRetryableErrorTypes, p.NonRetryableErrorTypes)
+
+	require.NoError(t, (&RetryPolicies{Fetch: &RetryOverride{BackoffCoefficient: 1.5}}).validate())
+	require.ErrorContains(t, (&RetryPolicies{Report: &RetryOverride{MaximumAttempts: -1}}).validate(), "report retry policy")
+	require.ErrorContains(t, (&RetryPolicies{Scan: &RetryOverride{BackoffCoefficient: 0.5}}).validate(), "at least 1")
+}
+
+// serverError is a retryable check error.
+var serverError = newCheckError(ErrorServerError, 502, nil, "unexpected status 502")
+
+func TestWorkflowHonorsFetchRetryAttempts(t *testing.T) {
+	for name, tc := range map[string]struct {
+		policies *RetryPolicies
+		err      error
+		calls    int
+	}{
+		"default":       {nil, serverError, int(FetchRetryPolicy().MaximumAttempts)},
+		"override":      {&RetryPolicies{Fetch: &RetryOverride{MaximumAttempts: 3}}, serverError, 3},
+		"non-retryable": {nil, newCheckError(ErrorNotFound, 404, nil, "no such org"), 1},
+	} {
+		env := newTestEnv(t)
+		env.OnActivity("FetchOrgReposPage", mock.Anything, mock.Anything).Return(nil, tc.err)
+
+		env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", RetryPolicies: tc.policies})
+
+		require.Error(t, env.GetWorkflowError(), name)
+		env.AssertNumberOfCalls(t, "FetchOrgReposPage", tc.calls)
+	}
+}
+
+func TestWorkflowHonorsScanRetryAttempts(t *testing.T) {
+	for _, activityBatching := range []bool{false, true} {
+		env := newTestEnv(t)
+		onListOrgRepos(env, fakeRepos(3))
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-001", mock.Anything, mock.Anything).
+			Return(nil, serverError)
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(compliantUnless())
+		batchAttempts := 0
+		env.OnActivity("CheckRepoSecurityBatch", mock.Anything, mock.Anything).
+			Run(func(mock.Arguments) { batchAttempts++ }).
+			Return(nil, serverError)
+
+		env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+			Org:              "acme",
+			ActivityBatching: activityBatching,
+			RetryPolicies:    &RetryPolicies{Scan: &RetryOverride{MaximumAttempts: 2}},
+		})
+
+		if activityBatching {
+			// The whole batch failed, so every repo errored.
+			require.ErrorContains(t, env.GetWorkflowError(), "scan degraded")
+			require.Equal(t, 2, batchAttempts)
+		} else {
+			require.NoError(t, env.GetWorkflowError())
+			env.AssertNumberOfCalls(t, "CheckRepoSecurity", 2+2)
+		}
+	}
+}
+
+func TestWorkflowHonorsReportRetryAttempts(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	// BuildReport is a local activity, which is only mocked by its method.
+	attempts := 0
+	env.OnActivity((&Activities{}).BuildReport, mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { attempts++ }).
+		Return(nil, errors.New("out of memory"))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.ErrorContains(t, env.GetWorkflowError(), "generating report")
+	require.EqualValues(t, ReportRetryPolicy().MaximumAttempts, attempts)
+}
+
+func TestWorkflowRejectsBadRetryPolicies(t *testing.T) {
+	env := newTestEnv(t)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org:           "acme",
+		RetryPolicies: &RetryPolicies{Fetch: &RetryOverride{InitialIntervalSeconds: -1}},
+	})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+}
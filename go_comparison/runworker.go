@@ -0,0 +1,55 @@
+package scanner
+
+// =============================================================================
+// Running a worker — the registration the worker binary and tests share
+// =============================================================================
+//
+// A workflow or activity registered under the wrong name, or a worker
+// polling the wrong task queue, fails only against a real server: the
+// testsuite registers whatever the test hands it. The worker binary and the
+// integration test (integration_test.go) both register through NewWorker,
+// so the test covers the registration that ships.
+// =============================================================================
+
+import (
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+)
+
+// TaskQueue is the task queue scans run on. It is separate from the Python
+// worker's so both can run against the same server.
+const TaskQueue = "security-scanner-go"
+
+// WorkerConfig is what a worker needs besides its settings.
+type WorkerConfig struct {
+	Client client.Client
+	// TaskQueue defaults to TaskQueue.
+	TaskQueue string
+	Options   worker.Options
+	// Activities are registered as they are, dependencies and all.
+	Activities *Activities
+}
+
+// NewWorker creates a worker on cfg.TaskQueue with every workflow and
+// activity of the scanner registered.
+func NewWorker(cfg WorkerConfig) worker.Worker {
+	queue := cfg.TaskQueue
+	if queue == "" {
+		queue = TaskQueue
+	}
+	w := worker.New(cfg.Client, queue, cfg.Options)
+	// ScanBatchWorkflow runs batches for ScanInput.ChildPerBatch;
+	// EnterpriseScanWorkflow runs a SecurityScanWorkflow per org of an
+	// enterprise.
+	w.RegisterWorkflow(SecurityScanWorkflow)
+	w.RegisterWorkflow(ScanBatchWorkflow)
+	w.RegisterWorkflow(EnterpriseScanWorkflow)
+	w.RegisterActivity(cfg.Activities)
+	return w
+}
+
+// RunWorker runs NewWorker(cfg) until interruptCh receives or closes, as
+// worker.InterruptCh does on SIGINT or SIGTERM.
+func RunWorker(cfg WorkerConfig, interruptCh <-chan interface{}) error {
+	return NewWorker(cfg).Run(interruptCh)
+}
@@ -0,0 +1,142 @@
+package scanner
+
+// =============================================================================
+// Scan errors — why a repo could not be scanned
+// =============================================================================
+//
+// A repo whose check failed is reported with a ScanError next to the
+// original error string. Its Category comes from the activity: the repo
+// checks fail with an ApplicationError whose type is the category and whose
+// details are the HTTP status, if there was one. Activity timeouts are
+// TIMEOUT; anything else is UNKNOWN.
+//
+// The category decides what the workflow makes of the repo. RATE_LIMIT,
+// TIMEOUT and SERVER_ERROR say nothing about the repo and are listed in the
+// report's retry_later_repos for the next scan; the others are errors of
+// the repo itself. Either way the repo counts in the report's errors, and
+// the report's errors_by_category adds them up per category.
+// =============================================================================
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// ErrorCategory is why a repo's scan failed.
+type ErrorCategory string
+
+const (
+	ErrorNotFound    ErrorCategory = "NOT_FOUND"
+	ErrorNoAccess    ErrorCategory = "NO_ACCESS"
+	ErrorRateLimit   ErrorCategory = "RATE_LIMIT"
+	ErrorTimeout     ErrorCategory = "TIMEOUT"
+	ErrorServerError ErrorCategory = "SERVER_ERROR"
+	ErrorParseError  ErrorCategory = "PARSE_ERROR"
+	// ErrorUnknown is any other failure, and every failure reported by
+	// workers that predate categories.
+	ErrorUnknown ErrorCategory = "UNKNOWN"
+)
+
+// Retryable reports whether a later scan of the repo may well succeed:
+// the failure was the provider's or the network's, not the repo's.
+func (c ErrorCategory) Retryable() bool {
+	switch c {
+	case ErrorRateLimit, ErrorTimeout, ErrorServerError:
+		return true
+	}
+	return false
+}
+
+// ScanError is why a repo could not be scanned.
+type ScanError struct {
+	Category ErrorCategory `json:"category"`
+	Message  string        `json:"message"`
+	// HTTPStatus is the provider's response status; 0 when there was no
+	// response.
+	HTTPStatus int  `json:"http_status,omitempty"`
+	Retryable  bool `json:"retryable"`
+}
+
+// NewScanError classifies err, an error of a repo check as the activity
+// returned it or as the workflow received it.
+func NewScanError(err error) ScanError {
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) {
+		se := ScanError{Category: ErrorCategory(appErr.Type()), Message: appErr.Message()}
+		switch se.Category {
+		case ErrorNotFound, ErrorNoAccess, ErrorRateLimit, ErrorTimeout, ErrorServerError, ErrorParseError, ErrorUnknown:
+			// Only newCheckError's details are a status.
+			if appErr.HasDetails() {
+				_ = appErr.Details(&se.HTTPStatus)
+			}
+		case "UNAUTHORIZED":
+			se.Category = ErrorNoAccess
+		default:
+			se.Category = ErrorUnknown
+		}
+		se.Retryable = se.Category.Retryable()
+		return se
+	}
+	var timeoutErr *temporal.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return ScanError{Category: ErrorTimeout, Message: err.Error(), Retryable: true}
+	}
+	return ScanError{Category: ErrorUnknown, Message: err.Error()}
+}
+
+// scanErrorResult is the result of a repo whose check failed with err.
+func scanErrorResult(repo string, err error) *RepoSecurityResult {
+	msg := err.Error()
+	se := NewScanError(err)
+	return &RepoSecurityResult{Repository: repo, Error: &msg, ScanError: &se}
+}
+
+// setScanError marks r as failed with se; Error is se's message.
+func (r *RepoSecurityResult) setScanError(se ScanError) {
+	r.Error, r.ScanError = &se.Message, &se
+}
+
+// FailureCategory is the category of r's error. Results from workers that
+// predate ScanError are ErrorUnknown.
+func (r *RepoSecurityResult) FailureCategory() ErrorCategory {
+	if r.ScanError != nil {
+		return r.ScanError.Category
+	}
+	return ErrorUnknown
+}
+
+// newCheckError is the error a repo check fails with: an ApplicationError
+// of category's type with status as its details. Temporal retries it like
+// any other error; the category only decides what happens once the
+// retries are spent.
+func newCheckError(category ErrorCategory, status int, cause error, format string, args ...interface{}) error {
+	opts := temporal.ApplicationErrorOptions{Cause: cause}
+	if status != 0 {
+		opts.Details = []interface{}{status}
+	}
+	return temporal.NewApplicationErrorWithOptions(fmt.Sprintf(format, args...), string(category), opts)
+}
+
+// requestError classifies a failed request: a timeout, or no usable
+// response, which is the server's. ApplicationErrors, such as a spent API
+// budget, are returned as they are.
+func requestError(err error) error {
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) {
+		return err
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return newCheckError(ErrorTimeout, 0, err, "%v", err)
+	}
+	return newCheckError(ErrorServerError, 0, err, "%v", err)
+}
+
+// parseError is the error for a response about what that did not decode.
+func parseError(what string, err error) error {
+	return newCheckError(ErrorParseError, 0, err, "parsing %s: %v", what, err)
+}
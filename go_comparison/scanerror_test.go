@@ -0,0 +1,81 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestNewScanError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err  error
+		want ScanError
+	}{
+		"rate limit with status": {
+			statusError("reading repo web", http.StatusForbidden, []byte(`{"message":"API rate limit exceeded"}`)),
+			ScanError{Category: ErrorRateLimit, Message: "reading repo web: unexpected status 403: API rate limit exceeded", HTTPStatus: 403, Retryable: true},
+		},
+		"permission 403": {
+			statusError("reading repo web", http.StatusForbidden, []byte(`{"message":"Resource not accessible by integration"}`)),
+			ScanError{Category: ErrorNoAccess, Message: "reading repo web: unexpected status 403: Resource not accessible by integration", HTTPStatus: 403},
+		},
+		"server error": {
+			statusError("reading project web", http.StatusBadGateway, nil),
+			ScanError{Category: ErrorServerError, Message: "reading project web: unexpected status 502", HTTPStatus: 502, Retryable: true},
+		},
+		"other status": {
+			statusError("reading project web", http.StatusTeapot, nil),
+			ScanError{Category: ErrorUnknown, Message: "reading project web: unexpected status 418", HTTPStatus: 418},
+		},
+		"parse": {
+			parseError("repo web", errors.New("unexpected end of JSON input")),
+			ScanError{Category: ErrorParseError, Message: "parsing repo web: unexpected end of JSON input"},
+		},
+		"request timeout": {
+			requestError(fmt.Errorf("GET: %w", context.DeadlineExceeded)),
+			ScanError{Category: ErrorTimeout, Message: "GET: context deadline exceeded", Retryable: true},
+		},
+		"no response": {
+			requestError(errors.New("connection refused")),
+			ScanError{Category: ErrorServerError, Message: "connection refused", Retryable: true},
+		},
+		"unauthorized": {
+			temporal.NewNonRetryableApplicationError("invalid GitLab API token", "UNAUTHORIZED", nil),
+			ScanError{Category: ErrorNoAccess, Message: "invalid GitLab API token"},
+		},
+		"activity timeout": {
+			temporal.NewTimeoutError(0, nil),
+			ScanError{Category: ErrorTimeout, Message: temporal.NewTimeoutError(0, nil).Error(), Retryable: true},
+		},
+		"other type": {
+			temporal.NewNonRetryableApplicationError("boom", "TEST", nil, "not a status"),
+			ScanError{Category: ErrorUnknown, Message: "boom"},
+		},
+		"plain": {
+			errors.New("boom"),
+			ScanError{Category: ErrorUnknown, Message: "boom"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.want, NewScanError(tc.err))
+		})
+	}
+}
+
+func TestRequestErrorKeepsApplicationErrors(t *testing.T) {
+	budget := temporal.NewNonRetryableApplicationError("spent", ErrTypeAPIBudgetExceeded, nil)
+	require.Same(t, budget, requestError(budget))
+}
+
+func TestScanErrorResultKeepsErrorString(t *testing.T) {
+	err := statusError("reading repo web", http.StatusServiceUnavailable, nil)
+	r := scanErrorResult("web", err)
+	require.Equal(t, err.Error(), *r.Error)
+	require.Equal(t, ErrorServerError, r.FailureCategory())
+	require.Equal(t, ErrorUnknown, (&RepoSecurityResult{Error: r.Error}).FailureCategory(), "results of older workers")
+}
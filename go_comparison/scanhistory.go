@@ -0,0 +1,133 @@
+package scanner
+
+// =============================================================================
+// Scan history — the last report of each org
+// =============================================================================
+//
+// A full-org scan saves its report to the worker's blob store under
+// history/<provider>/<org>/latest.json, and the next scan of that org reads
+// it back as its baseline: the workflow diffs the two with CompareReports,
+// and carries state such as an open PagerDuty alert from one scan to the
+// next. Each saved report is also a point of the org's compliance trend
+// (see trend.go). A scan that narrows the repo set (teams, a repo list,
+// ActiveWithinDays or a topic filter) covers only part of the org, and a
+// cancelled scan, or
+// one stopped at its deadline or by its API budget, only part of its
+// repos, so they neither read nor replace the baseline.
+// Workers without a blob store have no history.
+//
+// Both are local activities: one small blob each, read or written on the
+// worker that already holds the report.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// narrowsRepos reports whether the scan leaves out repos of the org that a
+// plain scan would check, so its report is no baseline for the org.
+func (in ScanInput) narrowsRepos() bool {
+	return len(in.Teams) > 0 || len(in.Repos) > 0 || in.ActiveWithinDays > 0 ||
+		len(in.IncludeTopics) > 0 || len(in.ExcludeTopics) > 0
+}
+
+// scanHistoryKey is the blob store key of org's last report.
+func scanHistoryKey(provider, org string) string {
+	return "history/" + providerName(provider) + "/" + org + "/latest.json"
+}
+
+// LoadLastReport returns the last saved report of org, or nil if there is
+// none or the worker has no blob store.
+func (a *Activities) LoadLastReport(ctx context.Context, provider, org string) (map[string]interface{}, error) {
+	if a.BlobStore == nil {
+		return nil, nil
+	}
+	data, err := a.BlobStore.Get(ctx, a.BlobStore.URI(scanHistoryKey(provider, org)))
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading last report: %w", err)
+	}
+	var report map[string]interface{}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("decoding last report: %w", err)
+	}
+	return report, nil
+}
+
+// SaveLastReport makes report the baseline of org's next scan and adds it
+// to org's compliance trend (see trend.go), and records it in
+// LatestReports if the worker keeps them. It saves nothing when the worker
+// has no blob store.
+func (a *Activities) SaveLastReport(ctx context.Context, provider, org string, report map[string]interface{}) error {
+	if a.LatestReports != nil {
+		if r, err := reportFromMap(report); err == nil {
+			a.LatestReports.Record(r)
+		}
+	}
+	if a.BlobStore == nil {
+		return nil
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+	if _, err := a.BlobStore.Put(ctx, scanHistoryKey(provider, org), data); err != nil {
+		return fmt.Errorf("saving report: %w", err)
+	}
+	r, err := reportFromMap(report)
+	if err != nil {
+		return fmt.Errorf("decoding report: %w", err)
+	}
+	return appendTrendPoint(ctx, a.BlobStore, provider, org, NewTrendPoint(r))
+}
+
+// historyOptions are the local activity options of the history lookups.
+func historyOptions(ctx workflow.Context) workflow.Context {
+	return workflow.WithLocalActivityOptions(ctx, workflow.LocalActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy:         githubRetryPolicy(),
+	})
+}
+
+// loadBaseline is org's last report, or nil when there is none or it
+// cannot be read; a scan without a baseline simply has nothing to diff.
+func loadBaseline(ctx workflow.Context, provider, org string) *Report {
+	var raw map[string]interface{}
+	if err := workflow.ExecuteLocalActivity(historyOptions(ctx), "LoadLastReport", provider, org).Get(ctx, &raw); err != nil {
+		workflow.GetLogger(ctx).Warn("Could not load the last report", "error", err)
+		return nil
+	}
+	if raw == nil {
+		return nil
+	}
+	report, err := reportFromMap(raw)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Ignoring unreadable last report", "error", err)
+		return nil
+	}
+	return &report
+}
+
+// saveBaseline stores report for org's next scan. A failure is only logged.
+func saveBaseline(ctx workflow.Context, provider, org string, report map[string]interface{}) {
+	if err := workflow.ExecuteLocalActivity(historyOptions(ctx), "SaveLastReport", provider, org, report).Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Warn("Could not save the report as the next baseline", "error", err)
+	}
+}
+
+// reportFromMap is the typed view of a report the workflow built as a map.
+func reportFromMap(m map[string]interface{}) (Report, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return Report{}, err
+	}
+	return ParseReport(b)
+}
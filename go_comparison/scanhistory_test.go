@@ -0,0 +1,69 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestLastReportRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	a := &Activities{BlobStore: &FileBlobStore{Dir: dir}}
+	env := newActivityEnv(a)
+
+	load := func(provider string) map[string]interface{} {
+		t.Helper()
+		val, err := env.ExecuteActivity(a.LoadLastReport, provider, "acme")
+		require.NoError(t, err)
+		var got map[string]interface{}
+		require.NoError(t, val.Get(&got))
+		return got
+	}
+	require.Nil(t, load(ProviderGitHub), "no report saved yet")
+
+	report := map[string]interface{}{"org": "acme", "compliance_rate": "50.0%"}
+	_, err := env.ExecuteActivity(a.SaveLastReport, ProviderGitHub, "acme", report)
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(dir, "history", "github", "acme", "latest.json"))
+
+	require.Equal(t, report, load(""), "an empty provider is GitHub")
+	require.Nil(t, load(ProviderGitLab), "each provider has its own history")
+
+	// Without a blob store there is no history.
+	a = &Activities{}
+	env = newActivityEnv(a)
+	_, err = env.ExecuteActivity(a.SaveLastReport, ProviderGitHub, "acme", report)
+	require.NoError(t, err)
+	require.Nil(t, load(ProviderGitHub))
+}
+
+func TestWorkflowSavesBaselineForFullScansOnly(t *testing.T) {
+	dir := t.TempDir()
+	scan := func(input ScanInput) {
+		var s testsuite.WorkflowTestSuite
+		env := s.NewTestWorkflowEnvironment()
+		env.RegisterActivity(&Activities{BlobStore: &FileBlobStore{Dir: dir}})
+		mockActionsSecurity(env)
+		onListOrgRepos(env, fakeRepos(2))
+		env.OnActivity("FetchTeamRepos", mock.Anything, mock.Anything).Return(fakeRepos(1), nil)
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(compliantUnless())
+		env.ExecuteWorkflow(SecurityScanWorkflow, input)
+		require.NoError(t, env.GetWorkflowError())
+	}
+	path := filepath.Join(dir, "history", "github", "acme", "latest.json")
+
+	scan(ScanInput{Org: "acme", Teams: []string{"platform"}})
+	require.NoFileExists(t, path, "a team scan is not a baseline")
+
+	scan(ScanInput{Org: "acme"})
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	saved, err := ParseReport(data)
+	require.NoError(t, err)
+	require.Equal(t, 2, saved.TotalRepos)
+}
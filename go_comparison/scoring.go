@@ -0,0 +1,171 @@
+package scanner
+
+// =============================================================================
+// Compliance score — a weighted 0–100 view next to the binary compliance rate
+// =============================================================================
+//
+// IsCompliant says whether a repo passes every required check; it cannot say
+// that a repo missing code scanning is better off than one missing
+// everything. The score weights each check result and discounts it by the
+// severity of its status. The weights live in the CompliancePolicy, and the
+// report records the ones it used so a score can be reproduced.
+// =============================================================================
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ScoringPolicy weights check results into a 0–100 score per repository.
+type ScoringPolicy struct {
+	// Weights maps RepoSecurityResult.Checks keys to how much they count.
+	// Results whose check did not run are left out of the score.
+	Weights map[string]float64 `json:"weights"`
+
+	// Severity maps a result status to the fraction of its weight lost, from
+	// 0 (control in place) to 1 (control missing). Unlisted statuses lose
+	// the full weight, except StatusEnabled, which loses none.
+	Severity map[SecurityStatus]float64 `json:"severity"`
+}
+
+// DefaultScoringPolicy weights the GHAS features most, and counts a result
+// that could not be read as half missing.
+func DefaultScoringPolicy() ScoringPolicy {
+	return ScoringPolicy{
+		Weights: map[string]float64{
+			CheckSecretScanning:         30,
+			CheckDependabot:             20,
+			CheckCodeScanning:           20,
+			ResultCodeowners:            5,
+			ResultSecurityPolicy:        5,
+			ResultReadOnlyWorkflowToken: 10,
+			CheckAccessAudit:            10,
+			CheckSecurityUpdates:        10,
+			CheckDependabotConfig:       5,
+			CheckWebhooks:               5,
+		},
+		Severity: map[SecurityStatus]float64{
+			StatusEnabled:  0,
+			StatusNoAccess: 0.5,
+			StatusUnknown:  0.5,
+			StatusError:    0.5,
+		},
+	}
+}
+
+// Validate rejects unknown result keys, negative weights, and severities
+// outside [0, 1].
+func (s ScoringPolicy) Validate() error {
+	for key, w := range s.Weights {
+		if _, ok := resultChecks[key]; !ok {
+			return fmt.Errorf("scoring weight for unknown check result %q", key)
+		}
+		if w < 0 {
+			return fmt.Errorf("scoring weight for %q is negative", key)
+		}
+	}
+	for status, sev := range s.Severity {
+		if sev < 0 || sev > 1 {
+			return fmt.Errorf("scoring severity for %q must be between 0 and 1", status)
+		}
+	}
+	return nil
+}
+
+func (s ScoringPolicy) severity(status SecurityStatus) float64 {
+	if sev, ok := s.Severity[status]; ok {
+		return sev
+	}
+	if status == StatusEnabled {
+		return 0
+	}
+	return 1
+}
+
+// score returns r's 0–100 score over the results of the selected checks,
+// rounded to one decimal. A repo with nothing weighted scores 100.
+func (s ScoringPolicy) score(r *RepoSecurityResult, selected checkSet) float64 {
+	// Sum in key order so float rounding cannot differ between runs.
+	keys := make([]string, 0, len(s.Weights))
+	for key := range s.Weights {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var total, lost float64
+	for _, key := range keys {
+		if !selected[resultChecks[key]] {
+			continue
+		}
+		w := s.Weights[key]
+		total += w
+		lost += w * s.severity(r.Check(key).Status)
+	}
+	if total == 0 {
+		return 100
+	}
+	return roundScore(100 * (1 - lost/total))
+}
+
+func roundScore(x float64) float64 {
+	return math.Round(x*10) / 10
+}
+
+// worstScoringLimit caps the repos listed under worst_scoring_repos.
+const worstScoringLimit = 10
+
+// RepoScore is one entry of the report's worst_scoring_repos.
+type RepoScore struct {
+	Repository string  `json:"repository"`
+	Score      float64 `json:"score"`
+}
+
+// scoreTotals aggregates repo scores into the org score and the worst list.
+type scoreTotals struct {
+	sum    float64
+	scores []RepoScore
+}
+
+func (t *scoreTotals) add(repo string, score float64) {
+	t.sum += score
+	t.scores = append(t.scores, RepoScore{Repository: repo, Score: score})
+}
+
+// orgScore is the mean repo score.
+func (t *scoreTotals) orgScore() float64 {
+	if len(t.scores) == 0 {
+		return 100
+	}
+	return roundScore(t.sum / float64(len(t.scores)))
+}
+
+// worst returns the lowest-scoring repos below 100, lowest first (ties by
+// name, so the report is stable).
+func (t *scoreTotals) worst() []RepoScore {
+	var out []RepoScore
+	for _, s := range t.ranked(len(t.scores)) {
+		if s.Score < 100 {
+			out = append(out, s)
+		}
+	}
+	if len(out) > worstScoringLimit {
+		out = out[:worstScoringLimit]
+	}
+	return out
+}
+
+// ranked returns at most limit repos, lowest score first (ties by name).
+func (t *scoreTotals) ranked(limit int) []RepoScore {
+	out := append([]RepoScore(nil), t.scores...)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score < out[j].Score
+		}
+		return out[i].Repository < out[j].Repository
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// scoredResult builds a result with the given statuses recorded in Checks.
+func scoredResult(repo string, statuses map[string]SecurityStatus) RepoSecurityResult {
+	r := RepoSecurityResult{Repository: repo}
+	for key, status := range statuses {
+		r.setCheck(key, CheckResult{Status: status})
+	}
+	return r
+}
+
+func TestScoringPolicyScore(t *testing.T) {
+	all := newCheckSet([]string{CheckSecretScanning, CheckDependabot, CheckCodeScanning, CheckFiles, CheckActions, CheckAccessAudit})
+	ghas := newCheckSet(nil)
+
+	tests := []struct {
+		name     string
+		selected checkSet
+		statuses map[string]SecurityStatus
+		want     float64
+	}{
+		{
+			name:     "all enabled",
+			selected: ghas,
+			statuses: map[string]SecurityStatus{CheckSecretScanning: StatusEnabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusEnabled},
+			want:     100,
+		},
+		{
+			// 20 of 70 lost.
+			name:     "code scanning not configured",
+			selected: ghas,
+			statuses: map[string]SecurityStatus{CheckSecretScanning: StatusEnabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusNotConfigured},
+			want:     71.4,
+		},
+		{
+			// 30 of 70 lost.
+			name:     "secret scanning disabled",
+			selected: ghas,
+			statuses: map[string]SecurityStatus{CheckSecretScanning: StatusDisabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusEnabled},
+			want:     57.1,
+		},
+		{
+			// Unread results lose half: 20*0.5 + 20*0.5 of 70.
+			name:     "no access and unknown",
+			selected: ghas,
+			statuses: map[string]SecurityStatus{CheckSecretScanning: StatusEnabled, CheckDependabot: StatusUnknown, CheckCodeScanning: StatusNoAccess},
+			want:     71.4,
+		},
+		{
+			name:     "nothing enabled",
+			selected: ghas,
+			statuses: map[string]SecurityStatus{CheckSecretScanning: StatusDisabled, CheckDependabot: StatusDisabled, CheckCodeScanning: StatusNotConfigured},
+			want:     0,
+		},
+		{
+			// 5 + 10 of 100 lost.
+			name:     "all checks, missing SECURITY.md and flagged access",
+			selected: all,
+			statuses: map[string]SecurityStatus{
+				CheckSecretScanning: StatusEnabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusEnabled,
+				ResultCodeowners: StatusEnabled, ResultSecurityPolicy: StatusDisabled,
+				ResultReadOnlyWorkflowToken: StatusEnabled, CheckAccessAudit: StatusDisabled,
+			},
+			want: 85,
+		},
+		{
+			name:     "only unselected checks fail",
+			selected: newCheckSet([]string{CheckSecretScanning}),
+			statuses: map[string]SecurityStatus{CheckSecretScanning: StatusEnabled, CheckDependabot: StatusDisabled},
+			want:     100,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := scoredResult("repo", tc.statuses)
+			require.Equal(t, tc.want, DefaultScoringPolicy().score(&r, tc.selected))
+		})
+	}
+}
+
+func TestScoringPolicyCustomSeverity(t *testing.T) {
+	policy := ScoringPolicy{
+		Weights:  map[string]float64{CheckSecretScanning: 3, CheckCodeScanning: 1},
+		Severity: map[SecurityStatus]float64{StatusNotConfigured: 0.25},
+	}
+	r := scoredResult("repo", map[string]SecurityStatus{CheckSecretScanning: StatusEnabled, CheckCodeScanning: StatusNotConfigured})
+	require.Equal(t, 93.8, policy.score(&r, newCheckSet(nil)))
+}
+
+func TestScoringPolicyValidate(t *testing.T) {
+	require.NoError(t, DefaultScoringPolicy().Validate())
+	require.ErrorContains(t, ScoringPolicy{Weights: map[string]float64{"branch_protection": 1}}.Validate(), "branch_protection")
+	require.ErrorContains(t, ScoringPolicy{Weights: map[string]float64{CheckDependabot: -1}}.Validate(), "negative")
+	require.ErrorContains(t, ScoringPolicy{Severity: map[SecurityStatus]float64{StatusDisabled: 2}}.Validate(), "between 0 and 1")
+}
+
+func TestGenerateReportScores(t *testing.T) {
+	results := []RepoSecurityResult{
+		scoredResult("alpha", map[string]SecurityStatus{CheckSecretScanning: StatusEnabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusEnabled}),
+		scoredResult("bravo", map[string]SecurityStatus{CheckSecretScanning: StatusDisabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusEnabled}),
+		scoredResult("charlie", map[string]SecurityStatus{CheckSecretScanning: StatusEnabled, CheckDependabot: StatusEnabled, CheckCodeScanning: StatusNotConfigured}),
+		scoredResult("delta", map[string]SecurityStatus{CheckSecretScanning: StatusEnabled, CheckDependabot: StatusDisabled, CheckCodeScanning: StatusEnabled}),
+	}
+
+	env := newActivityEnv(&Activities{})
+	val, err := env.ExecuteActivity("GenerateReport", "acme", results, []BlobRef(nil), DefaultCompliancePolicy(), []string(nil), []Suppression(nil))
+	require.NoError(t, err)
+
+	var report map[string]interface{}
+	require.NoError(t, val.Get(&report))
+	// (100 + 57.1 + 71.4 + 71.4) / 4
+	require.EqualValues(t, 75, report["compliance_score"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"repository": "bravo", "score": 57.1},
+		map[string]interface{}{"repository": "charlie", "score": 71.4},
+		map[string]interface{}{"repository": "delta", "score": 71.4},
+	}, report["worst_scoring_repos"])
+	require.Contains(t, report["scoring"], "weights")
+	require.EqualValues(t, "25.0%", report["compliance_rate"])
+}
+
+func TestWorkflowRejectsInvalidScoring(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(1)).Maybe()
+
+	policy := DefaultCompliancePolicy()
+	policy.Scoring = &ScoringPolicy{Weights: map[string]float64{CheckDependabot: -5}}
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", CompliancePolicy: &policy})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.ErrorContains(t, env.GetWorkflowError(), "negative")
+}
@@ -0,0 +1,291 @@
+package scanner
+
+// =============================================================================
+// Secret sources — where the worker gets its GitHub token
+// =============================================================================
+//
+// A token in ScanInput is recorded in the workflow's history, and a token in
+// the worker's environment is readable by anything that can inspect the
+// process. Activities.Secrets lets the worker fetch the token from a secrets
+// manager instead; activities use it whenever the scan brought no token of
+// its own. Like the blob store it is a worker-side dependency and never
+// touches workflow code.
+//
+// EnvSecretSource is the default. VaultSecretSource reads a HashiCorp Vault
+// KV v2 secret and AWSSecretSource an AWS Secrets Manager secret, both over
+// plain HTTP. Wrap either in a CachedSecretSource so every activity does not
+// call the secrets manager.
+// =============================================================================
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// ErrTypeSecretSource marks an activity that could not fetch the GitHub
+// token from Activities.Secrets. It is retryable and distinct from
+// "UNAUTHORIZED", which means GitHub rejected the token.
+const ErrTypeSecretSource = "SECRET_SOURCE"
+
+// SecretSource supplies the GitHub token. An empty token means none is
+// configured and the scan runs unauthenticated.
+type SecretSource interface {
+	GetToken(ctx context.Context) (string, error)
+}
+
+// githubToken returns cred with its secret resolved (see credential.go),
+// or if it brings none the token from a.Secrets, of cred's type. It is nil
+// when the requests go unauthenticated.
+func (a *Activities) githubToken(ctx context.Context, cred *Credential) (*Credential, error) {
+	resolved, err := a.resolveCredential(ctx, cred)
+	if resolved != nil || err != nil || cred.kind() == CredentialNone || a.Secrets == nil {
+		return resolved, err
+	}
+	t, err := a.Secrets.GetToken(ctx)
+	if err != nil {
+		return nil, temporal.NewApplicationErrorWithCause(
+			fmt.Sprintf("fetching GitHub token from secret source: %v", err), ErrTypeSecretSource, err)
+	}
+	if t == "" {
+		return nil, nil
+	}
+	return &Credential{Type: cred.kind(), Value: t}, nil
+}
+
+// githubHeaders returns the headers of a GitHub API request made with
+// cred (see githubToken).
+func (a *Activities) githubHeaders(ctx context.Context, cred *Credential) (map[string]string, error) {
+	cred, err := a.githubToken(ctx, cred)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{"Accept": "application/vnd.github+json"}
+	if auth := cred.Authorization(); auth != "" {
+		headers["Authorization"] = auth
+	}
+	return headers, nil
+}
+
+// EnvSecretSource reads the token from an environment variable.
+type EnvSecretSource struct {
+	// Var is the variable's name; empty means GITHUB_TOKEN.
+	Var string
+}
+
+func (s EnvSecretSource) GetToken(context.Context) (string, error) {
+	name := s.Var
+	if name == "" {
+		name = "GITHUB_TOKEN"
+	}
+	return os.Getenv(name), nil
+}
+
+// VaultSecretSource reads the token from a Vault KV v2 secret.
+type VaultSecretSource struct {
+	// Addr is the Vault server, e.g. https://vault.example.com:8200.
+	Addr string
+	// Token authenticates to Vault; Namespace is the Vault Enterprise
+	// namespace, if any.
+	Token     string
+	Namespace string
+	// Mount is the KV v2 engine's mount (default "secret"), Path the
+	// secret's path within it and Key the field holding the GitHub token
+	// (default "token").
+	Mount      string
+	Path       string
+	Key        string
+	HTTPClient *http.Client
+}
+
+func (s *VaultSecretSource) GetToken(ctx context.Context) (string, error) {
+	mount, key := s.Mount, s.Key
+	if mount == "" {
+		mount = "secret"
+	}
+	if key == "" {
+		key = "token"
+	}
+	u := strings.TrimRight(s.Addr, "/") + "/v1/" + strings.Trim(mount, "/") + "/data/" + strings.Trim(s.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", s.Token)
+	if s.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.Namespace)
+	}
+	body, err := secretRequest(s.HTTPClient, req, "vault")
+	if err != nil {
+		return "", err
+	}
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("vault: parsing secret %s: %w", s.Path, err)
+	}
+	v, ok := secret.Data.Data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("vault: secret %s has no string field %q", s.Path, key)
+	}
+	return v, nil
+}
+
+// AWSSecretSource reads the token from AWS Secrets Manager.
+type AWSSecretSource struct {
+	// SecretID is the secret's name or ARN. If Key is set the secret is a
+	// JSON object and the token is its Key field; otherwise the whole
+	// secret string is the token.
+	SecretID string
+	Key      string
+	Region   string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Endpoint overrides the regional endpoint, e.g. for LocalStack.
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+// NewAWSSecretSourceFromEnv reads credentials the same way as the S3 blob
+// store: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN,
+// AWS_REGION (default us-east-1) and AWS_ENDPOINT_URL.
+func NewAWSSecretSourceFromEnv(secretID, key string) (*AWSSecretSource, error) {
+	creds := awsCredentialsFromEnv()
+	s := &AWSSecretSource{
+		SecretID:        secretID,
+		Key:             key,
+		Region:          creds.Region,
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Endpoint:        os.Getenv("AWS_ENDPOINT_URL"),
+	}
+	if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return nil, errors.New("aws secret source needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return s, nil
+}
+
+func (s *AWSSecretSource) GetToken(ctx context.Context) (string, error) {
+	u := s.Endpoint
+	if u == "" {
+		u = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com/", s.Region)
+	}
+	body, err := json.Marshal(map[string]string{"SecretId": s.SecretID})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	awsCredentials{
+		Region:          s.Region,
+		AccessKeyID:     s.AccessKeyID,
+		SecretAccessKey: s.SecretAccessKey,
+		SessionToken:    s.SessionToken,
+	}.sign(req, body, time.Now().UTC(), "secretsmanager")
+	resp, err := secretRequest(s.HTTPClient, req, "aws secrets manager")
+	if err != nil {
+		return "", err
+	}
+	var secret struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(resp, &secret); err != nil {
+		return "", fmt.Errorf("aws secrets manager: parsing secret %s: %w", s.SecretID, err)
+	}
+	if s.Key == "" {
+		return strings.TrimSpace(secret.SecretString), nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("aws secrets manager: secret %s is not a JSON object: %w", s.SecretID, err)
+	}
+	v, ok := fields[s.Key].(string)
+	if !ok {
+		return "", fmt.Errorf("aws secrets manager: secret %s has no string field %q", s.SecretID, s.Key)
+	}
+	return v, nil
+}
+
+// secretRequest sends req and returns the body of a 200 response.
+func secretRequest(client *http.Client, req *http.Request, name string) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading response: %w", name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s %s: status %d", name, req.Method, req.URL.Path, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// DefaultSecretTTL is how long a CachedSecretSource keeps a token.
+const DefaultSecretTTL = 5 * time.Minute
+
+// CachedSecretSource remembers Source's token for TTL, so rotating the
+// secret takes effect within TTL without a request per activity.
+type CachedSecretSource struct {
+	Source SecretSource
+	TTL    time.Duration
+
+	mu        sync.Mutex
+	token     string
+	fetchedAt time.Time
+	// now is time.Now; tests replace it.
+	now func() time.Time
+}
+
+// NewCachedSecretSource caches source's token for ttl (DefaultSecretTTL
+// if not positive).
+func NewCachedSecretSource(source SecretSource, ttl time.Duration) *CachedSecretSource {
+	if ttl <= 0 {
+		ttl = DefaultSecretTTL
+	}
+	return &CachedSecretSource{Source: source, TTL: ttl, now: time.Now}
+}
+
+// GetToken returns the cached token, fetching it again once it is older
+// than TTL. A failed refresh is returned, not papered over with the old
+// token, which may be the one that was revoked.
+func (c *CachedSecretSource) GetToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if !c.fetchedAt.IsZero() && now.Sub(c.fetchedAt) < c.TTL {
+		return c.token, nil
+	}
+	token, err := c.Source.GetToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.fetchedAt = token, now
+	return token, nil
+}
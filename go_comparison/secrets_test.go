@@ -0,0 +1,146 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+// fakeSecretSource hands out token-1, token-2, ... or err.
+type fakeSecretSource struct {
+	calls int
+	err   error
+}
+
+func (f *fakeSecretSource) GetToken(context.Context) (string, error) {
+	f.calls++
+	if f.err != nil {
+		return "", f.err
+	}
+	return fmt.Sprintf("token-%d", f.calls), nil
+}
+
+func TestCachedSecretSourceRefreshesAfterTTL(t *testing.T) {
+	source := &fakeSecretSource{}
+	cached := NewCachedSecretSource(source, time.Minute)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	cached.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		token, err := cached.GetToken(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "token-1", token)
+		now = now.Add(20 * time.Second)
+	}
+	require.Equal(t, 1, source.calls)
+
+	now = now.Add(time.Minute)
+	token, err := cached.GetToken(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-2", token, "an expired token is fetched again")
+
+	// A failed refresh is an error, not the old token.
+	now = now.Add(2 * time.Minute)
+	source.err = errors.New("vault sealed")
+	_, err = cached.GetToken(context.Background())
+	require.ErrorContains(t, err, "vault sealed")
+}
+
+func TestActivitiesUseSecretSource(t *testing.T) {
+	f, a := newFakeGitHub(t, map[string]fakeResponse{
+		"/repos/acme-corp/payments-api/actions/permissions": {http.StatusOK, "actions_permissions_disabled.json"},
+	})
+	a.Secrets = &fakeSecretSource{}
+
+	_, err := newActivityEnv(a).ExecuteActivity(a.CheckActionsSecurity, "acme-corp", "payments-api", (*Credential)(nil))
+	require.NoError(t, err)
+	require.Equal(t, "token token-1", f.Requests()[0].Header.Get("Authorization"))
+
+	// A token in the scan input wins over the worker's.
+	scanToken := "ghp_scan"
+	_, err = newActivityEnv(a).ExecuteActivity(a.CheckActionsSecurity, "acme-corp", "payments-api", PATCredential(&scanToken))
+	require.NoError(t, err)
+	require.Equal(t, "token ghp_scan", f.Requests()[1].Header.Get("Authorization"))
+}
+
+func TestActivitiesReportSecretSourceFailure(t *testing.T) {
+	_, a := newFakeGitHub(t, nil)
+	a.Secrets = &fakeSecretSource{err: errors.New("permission denied on secret/data/scanner/github")}
+
+	_, err := newActivityEnv(a).ExecuteActivity(a.FetchOrgRepos, ScanInput{Org: "acme-corp"})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr), "got %v", err)
+	require.Equal(t, ErrTypeSecretSource, appErr.Type())
+	require.False(t, appErr.NonRetryable(), "the secrets manager may recover")
+	require.Contains(t, appErr.Error(), "permission denied on secret/data/scanner/github")
+}
+
+func TestVaultSecretSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/data/scanner/github" || r.Header.Get("X-Vault-Token") != "hvs.test" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"pat":"ghp_from_vault"},"metadata":{"version":3}}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	s := &VaultSecretSource{Addr: srv.URL, Token: "hvs.test", Mount: "kv", Path: "scanner/github", Key: "pat"}
+	token, err := s.GetToken(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "ghp_from_vault", token)
+
+	s.Key = "token"
+	_, err = s.GetToken(context.Background())
+	require.ErrorContains(t, err, `no string field "token"`)
+
+	s.Token = "hvs.revoked"
+	_, err = s.GetToken(context.Background())
+	require.ErrorContains(t, err, "status 403")
+}
+
+func TestAWSSecretSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var in struct{ SecretId string }
+		_ = json.Unmarshal(body, &in)
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/secretsmanager/aws4_request") ||
+			in.SecretId != "scanner/github" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"Name":"scanner/github","SecretString":"{\"token\":\"ghp_from_aws\"}"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	s := &AWSSecretSource{
+		SecretID: "scanner/github", Key: "token", Region: "eu-west-1",
+		AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", Endpoint: srv.URL,
+	}
+	token, err := s.GetToken(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "ghp_from_aws", token)
+
+	s.Key = ""
+	token, err = s.GetToken(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, `{"token":"ghp_from_aws"}`, token, "without a key the whole secret string is the token")
+}
+
+func TestEnvSecretSource(t *testing.T) {
+	t.Setenv("SCANNER_TEST_TOKEN", "ghp_env")
+	token, err := EnvSecretSource{Var: "SCANNER_TEST_TOKEN"}.GetToken(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "ghp_env", token)
+}
@@ -0,0 +1,143 @@
+package scanner
+
+// =============================================================================
+// Worker affinity — one worker for a scan's repo checks
+// =============================================================================
+//
+// Some of a scan's state lives in the worker that runs its activities: the
+// API requests APIUsageTracker counts against ScanInput.MaxAPIRequests,
+// and the token a cached SecretSource holds. With several worker replicas
+// the server spreads a scan's activities over all of them, so each budgets
+// only its share and fetches the token again.
+//
+// With ScanInput.WorkerAffinity the workflow opens a Temporal session
+// (workflow.CreateSession) and runs every repo check in it, so they all go
+// to one worker. Workers accept sessions by default (see the worker's
+// --max-concurrent-sessions).
+//
+// When that worker goes away, the session fails and the SDK cancels the
+// checks running in it. scanSession opens a new session on another worker
+// and rescans the repos whose checks failed with it; results already
+// recorded are kept. After maxWorkerSessions sessions, or if no worker
+// takes one within sessionCreationTimeout, the rest of the scan runs on
+// any worker, as without affinity. The report's worker_sessions says how
+// many sessions the scan used.
+//
+// Sessions trade scheduling flexibility for locality: one worker's slots
+// bound the scan, so affinity is opt-in. It does not combine with
+// ChildPerBatch, whose children schedule their own activities.
+// =============================================================================
+
+import (
+	"errors"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// maxWorkerSessions is the most sessions one scan opens.
+	maxWorkerSessions = 5
+	// sessionCreationTimeout bounds the wait for a worker to take a session.
+	sessionCreationTimeout = time.Minute
+	// sessionExecutionTimeout bounds one session; a scan that runs longer
+	// continues in a new one.
+	sessionExecutionTimeout = 24 * time.Hour
+)
+
+// validateWorkerAffinity checks that WorkerAffinity is not set with
+// ChildPerBatch.
+func (in ScanInput) validateWorkerAffinity() error {
+	if in.WorkerAffinity && in.ChildPerBatch {
+		return errors.New("worker_affinity does not combine with child_per_batch")
+	}
+	return nil
+}
+
+// scanSession runs a scan's repo checks in a session, replacing the
+// session when its worker goes away.
+type scanSession struct {
+	// base is the scan's activity context, without a session.
+	base workflow.Context
+	// ctx is the current session's context, or base once the scan has
+	// given up on sessions.
+	ctx    workflow.Context
+	pinned bool
+	// created is how many sessions the scan has opened.
+	created int
+}
+
+// openScanSession opens the scan's first session on scanCtx.
+func openScanSession(ctx, scanCtx workflow.Context) *scanSession {
+	s := &scanSession{base: scanCtx, ctx: scanCtx}
+	s.renew(ctx)
+	return s
+}
+
+// failed reports whether the current session's worker went away.
+func (s *scanSession) failed() bool {
+	return s.pinned && workflow.GetSessionInfo(s.ctx).SessionState == workflow.SessionStateFailed
+}
+
+// renew opens a new session, or unpins the scan once it has opened
+// maxWorkerSessions or none could be opened.
+func (s *scanSession) renew(ctx workflow.Context) {
+	logger := workflow.GetLogger(ctx)
+	s.close()
+	s.ctx, s.pinned = s.base, false
+	if s.created >= maxWorkerSessions {
+		logger.Warn("Worker sessions keep failing; scanning the rest on any worker", "sessions", s.created)
+		return
+	}
+	sessionCtx, err := workflow.CreateSession(s.base, &workflow.SessionOptions{
+		CreationTimeout:  sessionCreationTimeout,
+		ExecutionTimeout: sessionExecutionTimeout,
+	})
+	if err != nil {
+		logger.Warn("No worker took the scan's session; scanning on any worker", "error", err)
+		return
+	}
+	s.ctx, s.pinned = sessionCtx, true
+	s.created++
+	logger.Info("Pinned the scan's checks to a worker",
+		"host", workflow.GetSessionInfo(sessionCtx).HostName, "session", s.created)
+}
+
+// close completes the current session, releasing its worker's slot.
+func (s *scanSession) close() {
+	if s.pinned {
+		workflow.CompleteSession(s.ctx)
+	}
+}
+
+// scan calls run with the session's context on repos, passing its
+// results on to onResult. Repos whose checks failed because the session's
+// worker went away are not passed on; run is called again with those in a
+// new session until none are left.
+func (s *scanSession) scan(ctx workflow.Context, repos []string, onResult func(*RepoSecurityResult) error,
+	run func(scanCtx workflow.Context, repos []string, onResult func(*RepoSecurityResult) error) error) error {
+	if s.failed() {
+		// The worker went away between scan calls.
+		s.renew(ctx)
+	}
+	for len(repos) > 0 {
+		var lost []string
+		pinned := s.pinned
+		err := run(s.ctx, repos, func(r *RepoSecurityResult) error {
+			if pinned && r.Error != nil && s.failed() {
+				lost = append(lost, r.Repository)
+				return nil
+			}
+			return onResult(r)
+		})
+		if err != nil {
+			return err
+		}
+		if len(lost) > 0 {
+			workflow.GetLogger(ctx).Warn("The scan's worker went away; rescanning its repos in a new session", "repos", len(lost))
+			s.renew(ctx)
+		}
+		repos = lost
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+)
+
+// The SDK's session activities, which the tests stand in for.
+const (
+	sessionCreationActivity   = "internalSessionCreationActivity"
+	sessionCompletionActivity = "internalSessionCompletionActivity"
+)
+
+// sessionWorkers answers each session the workflow creates as a new
+// session worker would, naming the workers worker-1, worker-2, ... It
+// records the worker each repo's CheckRepoSecurity ran on.
+type sessionWorkers struct {
+	mu      sync.Mutex
+	created int
+	ranOn   map[string][]string
+}
+
+func newSessionTestEnv(t *testing.T) (*testsuite.TestWorkflowEnvironment, *sessionWorkers) {
+	env := newTestEnv(t)
+	env.SetWorkerOptions(worker.Options{EnableSessionWorker: true})
+	workers := &sessionWorkers{ranOn: map[string][]string{}}
+	env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, args converter.EncodedValues) {
+		workers.mu.Lock()
+		defer workers.mu.Unlock()
+		switch info.ActivityType.Name {
+		case sessionCreationActivity:
+			var sessionID string
+			require.NoError(t, args.Get(&sessionID))
+			workers.created++
+			host := fmt.Sprintf("worker-%d", workers.created)
+			env.SignalWorkflow(sessionID, map[string]string{"Taskqueue": host, "HostName": host, "ResourceID": host})
+		case "CheckRepoSecurity":
+			var org, repo string
+			require.NoError(t, args.Get(&org, &repo))
+			workers.ranOn[repo] = append(workers.ranOn[repo], info.TaskQueue)
+		}
+	})
+	env.OnActivity(sessionCompletionActivity, mock.Anything, mock.Anything).Return(nil)
+	return env, workers
+}
+
+func TestWorkflowWorkerAffinity(t *testing.T) {
+	env, workers := newSessionTestEnv(t)
+	env.OnActivity(sessionCreationActivity, mock.Anything, mock.Anything).Return(nil)
+	onListOrgRepos(env, fakeRepos(15))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", WorkerAffinity: true})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 15, report.TotalRepos)
+	require.Equal(t, 1, report.WorkerSessions)
+	require.Len(t, workers.ranOn, 15)
+	for repo, ranOn := range workers.ranOn {
+		require.Equal(t, []string{"worker-1"}, ranOn, repo)
+	}
+	env.AssertNumberOfCalls(t, sessionCompletionActivity, 1)
+}
+
+func TestWorkflowWorkerAffinityRecreatesSession(t *testing.T) {
+	for name, input := range map[string]ScanInput{
+		"batches": {Org: "acme", WorkerAffinity: true},
+		"window":  {Org: "acme", WorkerAffinity: true, Concurrency: 10},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env, workers := newSessionTestEnv(t)
+			// The first session's worker dies 45s in, halfway through
+			// the second batch of ten; the next session's lives on. A
+			// server would time out the session's heartbeat instead.
+			env.OnActivity(sessionCreationActivity, mock.Anything, mock.Anything).
+				After(45 * time.Second).
+				Return(temporal.NewNonRetryableApplicationError("session worker stopped heartbeating", "WorkerLost", nil)).Once()
+			env.OnActivity(sessionCreationActivity, mock.Anything, mock.Anything).Return(nil)
+			onListOrgRepos(env, fakeRepos(25))
+			env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				After(30 * time.Second).Return(compliantUnless())
+
+			env.ExecuteWorkflow(SecurityScanWorkflow, input)
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+			var report Report
+			require.NoError(t, env.GetWorkflowResult(&report))
+			require.Equal(t, 25, report.TotalRepos)
+			require.Zero(t, report.Errors, "the checks the dead worker dropped are rescanned, not reported")
+			require.Equal(t, 2, report.WorkerSessions)
+
+			for _, repo := range fakeRepos(10) {
+				require.Equal(t, []string{"worker-1"}, workers.ranOn[repo.Name], "finished before the worker died; not redone")
+			}
+			for _, repo := range fakeRepos(20)[10:] {
+				require.Equal(t, []string{"worker-1", "worker-2"}, workers.ranOn[repo.Name], "cut short and rescanned")
+			}
+			for _, repo := range fakeRepos(25)[20:] {
+				require.Equal(t, []string{"worker-2"}, workers.ranOn[repo.Name])
+			}
+		})
+	}
+}
+
+func TestWorkflowWorkerAffinityWithoutSessionWorker(t *testing.T) {
+	env := newTestEnv(t)
+	env.SetWorkerOptions(worker.Options{EnableSessionWorker: true})
+	env.OnActivity(sessionCreationActivity, mock.Anything, mock.Anything).
+		Return(temporal.NewTimeoutError(enums.TIMEOUT_TYPE_SCHEDULE_TO_START, nil))
+	onListOrgRepos(env, fakeRepos(5))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", WorkerAffinity: true})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError(), "the scan runs on any worker instead")
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 5, report.TotalRepos)
+	require.Zero(t, report.WorkerSessions)
+}
+
+func TestWorkflowRejectsWorkerAffinityWithChildPerBatch(t *testing.T) {
+	env := newTestEnv(t)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", WorkerAffinity: true, ChildPerBatch: true})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+}
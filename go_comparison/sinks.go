@@ -0,0 +1,399 @@
+package scanner
+
+// =============================================================================
+// Report sinks — where a scan delivers its report
+// =============================================================================
+//
+// ScanInput.Sinks lists where the report goes once it is built, each as a
+// type and its options:
+//
+//	file          writes the report to the worker's disk        dir (required)
+//	blob          stores it in the worker's blob store          prefix
+//	webhook       POSTs it as JSON                              url (required)
+//	splunk        forwards its findings to the worker's HEC     -
+//	jira          files remediation issues in the worker's Jira -
+//	datadog       sends its metrics to the worker's Datadog     -
+//	issue_digest  keeps a checklist issue in a GitHub repo      repo (required), per_team
+//
+// The workflow checks every spec against reportSinkTypes before scanning,
+// and after the report runs a DeliverReport activity per sink, all at once,
+// each with DeliveryRetryPolicy or the spec's own overrides. One sink
+// failing does not affect the others or the scan: the report's delivery
+// section lists every sink's outcome in the order the scan listed them.
+//
+// The worker's side of each type is registered in Activities.reportSinks,
+// so a new integration is a sink there and an entry in reportSinkTypes,
+// not another step in the workflow. A scan that lists sinks is delivered
+// to those only; one that lists none gets the worker's configured Splunk,
+// Jira and Datadog steps as before. Sink options are recorded in the
+// workflow's history, so they should not embed a secret.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Report sink types.
+const (
+	SinkFile    = "file"
+	SinkBlob    = "blob"
+	SinkWebhook = "webhook"
+	SinkSplunk  = "splunk"
+	SinkJira    = "jira"
+	SinkDatadog = "datadog"
+	// SinkIssueDigest keeps one checklist issue of non-compliant repos in a
+	// tracking repo; see issuedigest.go.
+	SinkIssueDigest = "issue_digest"
+)
+
+// ErrTypeSinkNotConfigured is the ApplicationError type of a delivery to a
+// sink the worker has no destination for, e.g. splunk without
+// --splunk-hec-url.
+const ErrTypeSinkNotConfigured = "SINK_NOT_CONFIGURED"
+
+// deliveryTimeout bounds one DeliverReport attempt.
+const deliveryTimeout = 5 * time.Minute
+
+// ReportSinkSpec is one place a scan delivers its report to.
+type ReportSinkSpec struct {
+	Type    string            `json:"type"`
+	Options map[string]string `json:"options,omitempty"`
+	// Retry, when set, overrides DeliveryRetryPolicy for this sink.
+	Retry *RetryOverride `json:"retry,omitempty"`
+}
+
+// reportSinkType is what the workflow knows of a sink type.
+type reportSinkType struct {
+	required, optional []string
+	// validate checks the options' values; nil accepts any.
+	validate func(options map[string]string) error
+	// heartbeat is the heartbeat timeout of sinks that heartbeat.
+	heartbeat time.Duration
+}
+
+// reportSinkTypes is every sink type a scan may list.
+var reportSinkTypes = map[string]reportSinkType{
+	SinkFile: {required: []string{"dir"}, validate: func(o map[string]string) error {
+		if !filepath.IsAbs(o["dir"]) {
+			return fmt.Errorf("dir %q is not an absolute path", o["dir"])
+		}
+		return nil
+	}},
+	SinkBlob: {optional: []string{"prefix"}},
+	SinkWebhook: {required: []string{"url"}, validate: func(o map[string]string) error {
+		u, err := url.Parse(o["url"])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url %q is not an http(s) URL", o["url"])
+		}
+		return nil
+	}},
+	SinkSplunk:  {heartbeat: DefaultHECAckTimeout + time.Minute},
+	SinkJira:    {heartbeat: time.Minute},
+	SinkDatadog: {},
+	SinkIssueDigest: {required: []string{"repo"}, optional: []string{"per_team"},
+		validate: validateIssueDigestOptions, heartbeat: time.Minute},
+}
+
+// ReportSinkTypes lists the sink types a scan may list, sorted.
+func ReportSinkTypes() []string {
+	return sortedKeys(reportSinkTypes)
+}
+
+// ParseReportSinkSpec parses a sink as the starter's --sink takes it:
+// TYPE or TYPE:KEY=VALUE,KEY=VALUE. It does not validate the result.
+func ParseReportSinkSpec(s string) (ReportSinkSpec, error) {
+	typ, opts, hasOpts := strings.Cut(s, ":")
+	spec := ReportSinkSpec{Type: strings.TrimSpace(typ)}
+	if !hasOpts {
+		return spec, nil
+	}
+	spec.Options = map[string]string{}
+	for _, kv := range strings.Split(opts, ",") {
+		key, value, ok := strings.Cut(kv, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return ReportSinkSpec{}, fmt.Errorf("sink %q: option %q is not KEY=VALUE", s, kv)
+		}
+		if _, dup := spec.Options[key]; dup {
+			return ReportSinkSpec{}, fmt.Errorf("sink %q: option %s is set twice", s, key)
+		}
+		spec.Options[key] = strings.TrimSpace(value)
+	}
+	return spec, nil
+}
+
+// ValidateReportSinks checks every spec against the registered sink types.
+func ValidateReportSinks(specs []ReportSinkSpec) error {
+	for i, spec := range specs {
+		if err := spec.validate(); err != nil {
+			return fmt.Errorf("sink %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (s ReportSinkSpec) validate() error {
+	t, ok := reportSinkTypes[s.Type]
+	if !ok {
+		return fmt.Errorf("unknown sink type %q: want one of %s", s.Type, strings.Join(ReportSinkTypes(), ", "))
+	}
+	for _, key := range t.required {
+		if s.Options[key] == "" {
+			return fmt.Errorf("%s sink needs the %s option", s.Type, key)
+		}
+	}
+	for _, key := range sortedKeys(s.Options) {
+		if !slices.Contains(t.required, key) && !slices.Contains(t.optional, key) {
+			return fmt.Errorf("%s sink has no option %q", s.Type, key)
+		}
+	}
+	if t.validate != nil {
+		if err := t.validate(s.Options); err != nil {
+			return fmt.Errorf("%s sink: %w", s.Type, err)
+		}
+	}
+	return s.Retry.validate(s.Type + " sink")
+}
+
+// DeliveryRetryPolicy is the retry policy for delivering the report to a
+// sink.
+func DeliveryRetryPolicy() *temporal.RetryPolicy {
+	return &temporal.RetryPolicy{
+		InitialInterval:        5 * time.Second,
+		BackoffCoefficient:     2.0,
+		MaximumInterval:        2 * time.Minute,
+		MaximumAttempts:        5,
+		NonRetryableErrorTypes: []string{ErrTypeInvalidInput, ErrTypeSinkNotConfigured},
+	}
+}
+
+// SinkDelivery is one sink's entry in the report's delivery section.
+type SinkDelivery struct {
+	Type string `json:"type"`
+	// Destination is where the report went: a path, URI or host.
+	Destination string `json:"destination,omitempty"`
+	Delivered   bool   `json:"delivered"`
+	// Summary says what was delivered when the sink sends more than the
+	// report itself, e.g. how many events.
+	Summary string `json:"summary,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DeliverReportInput is the input to DeliverReport.
+type DeliverReportInput struct {
+	Sink   ReportSinkSpec         `json:"sink"`
+	Report map[string]interface{} `json:"report"`
+	// MaxAttempts is the sink's retry policy's, for sinks that give up
+	// on parts of a delivery on the last attempt.
+	MaxAttempts int32 `json:"max_attempts,omitempty"`
+}
+
+// reportSink delivers a report to one type of sink.
+type reportSink func(ctx context.Context, in DeliverReportInput) (SinkDelivery, error)
+
+// reportSinks is every sink type this worker delivers to.
+func (a *Activities) reportSinks() map[string]reportSink {
+	return map[string]reportSink{
+		SinkFile:        a.deliverToFile,
+		SinkBlob:        a.deliverToBlobStore,
+		SinkWebhook:     a.deliverToWebhook,
+		SinkSplunk:      a.deliverToSplunk,
+		SinkJira:        a.deliverToJira,
+		SinkDatadog:     a.deliverToDatadog,
+		SinkIssueDigest: a.deliverToIssueDigest,
+	}
+}
+
+// DeliverReport delivers in.Report to in.Sink.
+func (a *Activities) DeliverReport(ctx context.Context, in DeliverReportInput) (*SinkDelivery, error) {
+	deliver, ok := a.reportSinks()[in.Sink.Type]
+	if !ok {
+		return nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("this worker has no %q sink", in.Sink.Type), ErrTypeInvalidInput, nil)
+	}
+	d, err := deliver(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	d.Type, d.Delivered = in.Sink.Type, true
+	activity.GetLogger(ctx).Info("Delivered the report", "sink", d.Type, "destination", d.Destination)
+	return &d, nil
+}
+
+// reportFileName is the report's name in a file sink or blob store,
+// following the starter's security_scan_<org>.json so --history reads it.
+func reportFileName(ctx context.Context, report map[string]interface{}) string {
+	org, _ := report["org"].(string)
+	org = strings.NewReplacer("/", "_", `\`, "_").Replace(org)
+	return fmt.Sprintf("security_scan_%s_%s.json", org, activity.GetInfo(ctx).WorkflowExecution.RunID)
+}
+
+func (a *Activities) deliverToFile(ctx context.Context, in DeliverReportInput) (SinkDelivery, error) {
+	data, err := encodeDeliveredReport(in.Report)
+	if err != nil {
+		return SinkDelivery{}, err
+	}
+	dir := in.Sink.Options["dir"]
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return SinkDelivery{}, fmt.Errorf("creating %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, reportFileName(ctx, in.Report))
+	// Written to a temporary file first so readers never see half a report.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return SinkDelivery{}, fmt.Errorf("writing the report: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return SinkDelivery{}, fmt.Errorf("writing the report: %w", err)
+	}
+	return SinkDelivery{Destination: path}, nil
+}
+
+func (a *Activities) deliverToBlobStore(ctx context.Context, in DeliverReportInput) (SinkDelivery, error) {
+	if a.BlobStore == nil {
+		return SinkDelivery{}, sinkNotConfigured("blob store", "SCAN_BLOB_STORE")
+	}
+	data, err := encodeDeliveredReport(in.Report)
+	if err != nil {
+		return SinkDelivery{}, err
+	}
+	key := reportFileName(ctx, in.Report)
+	if prefix := strings.Trim(in.Sink.Options["prefix"], "/"); prefix != "" {
+		key = prefix + "/" + key
+	}
+	uri, err := a.BlobStore.Put(ctx, key, data)
+	if err != nil {
+		return SinkDelivery{}, fmt.Errorf("storing the report: %w", err)
+	}
+	return SinkDelivery{Destination: uri}, nil
+}
+
+func (a *Activities) deliverToWebhook(ctx context.Context, in DeliverReportInput) (SinkDelivery, error) {
+	data, err := encodeDeliveredReport(in.Report)
+	if err != nil {
+		return SinkDelivery{}, err
+	}
+	if err := a.postJSON(ctx, in.Sink.Options["url"], "report", data); err != nil {
+		return SinkDelivery{}, err
+	}
+	// Only the host: webhook paths often carry a token.
+	u, _ := url.Parse(in.Sink.Options["url"])
+	return SinkDelivery{Destination: u.Host}, nil
+}
+
+func (a *Activities) deliverToSplunk(ctx context.Context, in DeliverReportInput) (SinkDelivery, error) {
+	if a.HEC == nil || a.HEC.URL == "" {
+		return SinkDelivery{}, sinkNotConfigured("Splunk HEC", "--splunk-hec-url")
+	}
+	result, err := a.ForwardFindings(ctx, ForwardInput{Report: in.Report, MaxAttempts: in.MaxAttempts})
+	if err != nil {
+		return SinkDelivery{}, err
+	}
+	d := SinkDelivery{Destination: result.Destination, Summary: fmt.Sprintf("%d events sent", result.EventsSent)}
+	if result.EventsFailed > 0 {
+		d.Summary += fmt.Sprintf(", %d failed", result.EventsFailed)
+	}
+	return d, nil
+}
+
+func (a *Activities) deliverToJira(ctx context.Context, in DeliverReportInput) (SinkDelivery, error) {
+	if a.Jira == nil || a.Jira.URL == "" {
+		return SinkDelivery{}, sinkNotConfigured("Jira", "--jira-url")
+	}
+	result, err := a.CreateJiraIssues(ctx, JiraInput{Report: in.Report})
+	if err != nil {
+		return SinkDelivery{}, err
+	}
+	created, updated := result.Keys()
+	summary := fmt.Sprintf("%d issues created, %d updated", len(created), len(updated))
+	if result.DryRun {
+		summary += " (dry run)"
+	}
+	return SinkDelivery{Destination: a.Jira.URL, Summary: summary}, nil
+}
+
+func (a *Activities) deliverToDatadog(ctx context.Context, in DeliverReportInput) (SinkDelivery, error) {
+	if a.Datadog == nil {
+		return SinkDelivery{}, sinkNotConfigured("Datadog", "--datadog-metrics")
+	}
+	if err := a.EmitComplianceMetrics(ctx, MetricsInput{Report: in.Report}); err != nil {
+		return SinkDelivery{}, err
+	}
+	transport := a.Datadog.Transport
+	if transport == "" {
+		transport = DatadogAPI
+	}
+	return SinkDelivery{Destination: "datadog " + string(transport)}, nil
+}
+
+func sinkNotConfigured(what, setting string) error {
+	return temporal.NewNonRetryableApplicationError(
+		fmt.Sprintf("this worker has no %s configured (%s)", what, setting), ErrTypeSinkNotConfigured, nil)
+}
+
+func encodeDeliveredReport(report map[string]interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("encoding report: "+err.Error(), ErrTypeInvalidInput, nil)
+	}
+	return data, nil
+}
+
+// deliverReport delivers report to every sink at once and returns their
+// outcomes in the order of sinks. A sink that still fails after its
+// retries is recorded as not delivered.
+func deliverReport(ctx workflow.Context, sinks []ReportSinkSpec, report map[string]interface{}) []SinkDelivery {
+	futures := make([]workflow.Future, len(sinks))
+	for i, sink := range sinks {
+		retryPolicy := sink.Retry.apply(DeliveryRetryPolicy())
+		actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			StartToCloseTimeout: deliveryTimeout,
+			HeartbeatTimeout:    reportSinkTypes[sink.Type].heartbeat,
+			RetryPolicy:         retryPolicy,
+		})
+		futures[i] = workflow.ExecuteActivity(actCtx, "DeliverReport", DeliverReportInput{
+			Sink: sink, Report: report, MaxAttempts: retryPolicy.MaximumAttempts,
+		})
+	}
+	deliveries := make([]SinkDelivery, len(sinks))
+	for i, f := range futures {
+		var d *SinkDelivery
+		err := f.Get(ctx, &d)
+		if err == nil && d != nil {
+			deliveries[i] = *d
+			continue
+		}
+		if err == nil {
+			err = errors.New("the sink returned no result")
+		}
+		workflow.GetLogger(ctx).Warn("Delivering the report failed", "sink", sinks[i].Type, "error", err)
+		deliveries[i] = SinkDelivery{Type: sinks[i].Type, Error: deliveryError(err)}
+	}
+	return deliveries
+}
+
+// deliveryError is err's message without the activity error's wrapping.
+func deliveryError(err error) string {
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) {
+		return appErr.Message()
+	}
+	var timeoutErr *temporal.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return "timed out (" + timeoutErr.TimeoutType().String() + ")"
+	}
+	return err.Error()
+}
@@ -0,0 +1,196 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestParseReportSinkSpec(t *testing.T) {
+	spec, err := ParseReportSinkSpec("datadog")
+	require.NoError(t, err)
+	require.Equal(t, ReportSinkSpec{Type: SinkDatadog}, spec)
+
+	spec, err = ParseReportSinkSpec("webhook:url=https://hooks.example.com/scan?a=b")
+	require.NoError(t, err)
+	require.Equal(t, ReportSinkSpec{Type: SinkWebhook, Options: map[string]string{"url": "https://hooks.example.com/scan?a=b"}}, spec)
+
+	spec, err = ParseReportSinkSpec("blob: prefix = reports/weekly ")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"prefix": "reports/weekly"}, spec.Options)
+
+	for _, bad := range []string{"file:dir", "file:=/tmp", "file:dir=/a,dir=/b"} {
+		_, err := ParseReportSinkSpec(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestValidateReportSinks(t *testing.T) {
+	require.NoError(t, ValidateReportSinks(nil))
+	require.NoError(t, ValidateReportSinks([]ReportSinkSpec{
+		{Type: SinkFile, Options: map[string]string{"dir": "/var/reports"}},
+		{Type: SinkBlob},
+		{Type: SinkWebhook, Options: map[string]string{"url": "https://hooks.example.com/scan"}},
+		{Type: SinkSplunk, Retry: &RetryOverride{MaximumAttempts: 2}},
+	}))
+
+	for want, spec := range map[string]ReportSinkSpec{
+		`unknown sink type "s3": want one of blob, datadog, file, issue_digest, jira, splunk, webhook`: {Type: "s3"},
+		"file sink needs the dir option":                              {Type: SinkFile},
+		`file sink: dir "reports" is not an absolute path`:            {Type: SinkFile, Options: map[string]string{"dir": "reports"}},
+		`webhook sink: url "hooks.example.com" is not an http(s) URL`: {Type: SinkWebhook, Options: map[string]string{"url": "hooks.example.com"}},
+		`datadog sink has no option "url"`:                            {Type: SinkDatadog, Options: map[string]string{"url": "https://x"}},
+		"splunk sink":                                                 {Type: SinkSplunk, Retry: &RetryOverride{MaximumAttempts: -1}},
+	} {
+		err := ValidateReportSinks([]ReportSinkSpec{{Type: SinkBlob}, spec})
+		require.Error(t, err, want)
+		require.Contains(t, err.Error(), "sink 2: "+want)
+	}
+}
+
+func TestDeliverReportToFileAndBlobStore(t *testing.T) {
+	dir, blobDir := t.TempDir(), t.TempDir()
+	env := newActivityEnv(&Activities{BlobStore: &FileBlobStore{Dir: blobDir}})
+	report := map[string]interface{}{"org": "acme", "total_repos": 3}
+
+	val, err := env.ExecuteActivity("DeliverReport", DeliverReportInput{
+		Sink:   ReportSinkSpec{Type: SinkFile, Options: map[string]string{"dir": filepath.Join(dir, "reports")}},
+		Report: report,
+	})
+	require.NoError(t, err)
+	var d *SinkDelivery
+	require.NoError(t, val.Get(&d))
+	require.True(t, d.Delivered)
+	require.Equal(t, SinkFile, d.Type)
+	require.True(t, strings.HasPrefix(filepath.Base(d.Destination), "security_scan_acme_"), d.Destination)
+	data, err := os.ReadFile(d.Destination)
+	require.NoError(t, err)
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.EqualValues(t, 3, got["total_repos"])
+	_, err = os.Stat(d.Destination + ".tmp")
+	require.True(t, os.IsNotExist(err), "no temporary file is left behind")
+
+	val, err = env.ExecuteActivity("DeliverReport", DeliverReportInput{
+		Sink:   ReportSinkSpec{Type: SinkBlob, Options: map[string]string{"prefix": "/weekly/"}},
+		Report: report,
+	})
+	require.NoError(t, err)
+	require.NoError(t, val.Get(&d))
+	require.True(t, d.Delivered)
+	require.Contains(t, d.Destination, "weekly/security_scan_acme_")
+}
+
+func TestDeliverReportToWebhook(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/hooks/secret-token", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	env := newActivityEnv(&Activities{HTTPClient: srv.Client()})
+	val, err := env.ExecuteActivity("DeliverReport", DeliverReportInput{
+		Sink:   ReportSinkSpec{Type: SinkWebhook, Options: map[string]string{"url": srv.URL + "/hooks/secret-token"}},
+		Report: map[string]interface{}{"org": "acme"},
+	})
+	require.NoError(t, err)
+	var d *SinkDelivery
+	require.NoError(t, val.Get(&d))
+	require.Equal(t, "acme", got["org"])
+	require.Equal(t, strings.TrimPrefix(srv.URL, "http://"), d.Destination, "only the host, not the path's token")
+}
+
+func TestDeliverReportToUnconfiguredSink(t *testing.T) {
+	env := newActivityEnv(&Activities{})
+	for _, typ := range []string{SinkBlob, SinkSplunk, SinkJira, SinkDatadog} {
+		_, err := env.ExecuteActivity("DeliverReport", DeliverReportInput{
+			Sink:   ReportSinkSpec{Type: typ},
+			Report: map[string]interface{}{"org": "acme"},
+		})
+		var appErr *temporal.ApplicationError
+		require.True(t, errors.As(err, &appErr), typ)
+		require.Equal(t, ErrTypeSinkNotConfigured, appErr.Type(), typ)
+	}
+
+	_, err := env.ExecuteActivity("DeliverReport", DeliverReportInput{Sink: ReportSinkSpec{Type: "s3"}})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+}
+
+// sinkOfType matches a DeliverReport input for the given sink type.
+func sinkOfType(typ string) interface{} {
+	return mock.MatchedBy(func(in DeliverReportInput) bool { return in.Sink.Type == typ })
+}
+
+func TestWorkflowDeliversToSinks(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	env.OnActivity("ForwardFindings", mock.Anything, mock.Anything).Return(&ForwardResult{}, nil)
+	env.OnActivity("CreateJiraIssues", mock.Anything, mock.Anything).Return(&RemediationResult{}, nil)
+	env.OnActivity("EmitComplianceMetrics", mock.Anything, mock.Anything).Return(nil)
+
+	// The first sink finishes last and the second fails, so the delivery
+	// section's order comes from the scan's list, not from completion.
+	var delivered []string
+	env.OnActivity("DeliverReport", mock.Anything, sinkOfType(SinkFile)).After(time.Minute).
+		Return(func(_ context.Context, in DeliverReportInput) (*SinkDelivery, error) {
+			require.EqualValues(t, 3, in.Report["total_repos"], "the sink gets the finished report")
+			delivered = append(delivered, in.Sink.Type)
+			return &SinkDelivery{Type: SinkFile, Destination: "/var/reports/a.json", Delivered: true}, nil
+		})
+	env.OnActivity("DeliverReport", mock.Anything, sinkOfType(SinkWebhook)).
+		Return(nil, temporal.NewNonRetryableApplicationError("report webhook: 503 Service Unavailable", "WEBHOOK", nil))
+	env.OnActivity("DeliverReport", mock.Anything, sinkOfType(SinkDatadog)).
+		Return(func(_ context.Context, in DeliverReportInput) (*SinkDelivery, error) {
+			delivered = append(delivered, in.Sink.Type)
+			return &SinkDelivery{Type: SinkDatadog, Destination: "datadog api", Delivered: true}, nil
+		})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Sinks: []ReportSinkSpec{
+		{Type: SinkFile, Options: map[string]string{"dir": "/var/reports"}},
+		{Type: SinkWebhook, Options: map[string]string{"url": "https://hooks.example.com/scan"}},
+		{Type: SinkDatadog},
+	}})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError(), "a failed sink does not fail the scan")
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, []SinkDelivery{
+		{Type: SinkFile, Destination: "/var/reports/a.json", Delivered: true},
+		{Type: SinkWebhook, Error: "report webhook: 503 Service Unavailable"},
+		{Type: SinkDatadog, Destination: "datadog api", Delivered: true},
+	}, report.Delivery)
+	require.Equal(t, []string{SinkDatadog, SinkFile}, delivered, "the sinks ran at once")
+
+	// Sinks replace the worker's integrations.
+	env.AssertNumberOfCalls(t, "ForwardFindings", 0)
+	env.AssertNumberOfCalls(t, "CreateJiraIssues", 0)
+	env.AssertNumberOfCalls(t, "EmitComplianceMetrics", 0)
+}
+
+func TestWorkflowRejectsUnknownSink(t *testing.T) {
+	env := newTestEnv(t)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Sinks: []ReportSinkSpec{{Type: "s3"}}})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+	require.Contains(t, appErr.Message(), `sinks[0]: unknown sink type "s3"`)
+	env.AssertNumberOfCalls(t, "FetchOrgReposPage", 0)
+}
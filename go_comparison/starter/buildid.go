@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"go.temporal.io/sdk/client"
+)
+
+// buildIDAdmin is the part of client.Client that promoteBuildID uses.
+type buildIDAdmin interface {
+	GetWorkerBuildIdCompatibility(ctx context.Context, options *client.GetWorkerBuildIdCompatibilityOptions) (*client.WorkerBuildIDVersionSets, error)
+	UpdateWorkerBuildIdCompatibility(ctx context.Context, options *client.UpdateWorkerBuildIdCompatibilityOptions) error
+}
+
+// promotionAck is the result of --promote-build-id.
+type promotionAck struct {
+	TaskQueue       string `json:"task_queue"`
+	BuildID         string `json:"build_id"`
+	PreviousDefault string `json:"previous_default,omitempty"`
+	// Operation is "add_new_default_set" for a new Build ID,
+	// "promote_set" for one already known, or empty when it was already
+	// the default.
+	Operation string `json:"operation,omitempty"`
+}
+
+// promoteBuildID makes buildID the default for new scans on taskQueue.
+// A new Build ID gets a set of its own, incompatible with the current one,
+// so running scans finish on the workers of the build they started on; a
+// known one (a rollback) has its set promoted again. See the worker's
+// versioning.go for the rollout.
+func promoteBuildID(ctx context.Context, c buildIDAdmin, taskQueue, buildID string) (promotionAck, error) {
+	ack := promotionAck{TaskQueue: taskQueue, BuildID: buildID}
+	sets, err := c.GetWorkerBuildIdCompatibility(ctx, &client.GetWorkerBuildIdCompatibilityOptions{TaskQueue: taskQueue})
+	if err != nil {
+		return ack, fmt.Errorf("reading the Build IDs of %s: %w", taskQueue, err)
+	}
+	ack.PreviousDefault = sets.Default()
+	if ack.PreviousDefault == buildID {
+		return ack, nil
+	}
+
+	var op client.UpdateWorkerBuildIdCompatibilityOptions
+	op.TaskQueue = taskQueue
+	ack.Operation = "add_new_default_set"
+	op.Operation = &client.BuildIDOpAddNewIDInNewDefaultSet{BuildID: buildID}
+	for _, set := range sets.Sets {
+		if slices.Contains(set.BuildIDs, buildID) {
+			ack.Operation = "promote_set"
+			op.Operation = &client.BuildIDOpPromoteSet{BuildID: buildID}
+		}
+	}
+	if err := c.UpdateWorkerBuildIdCompatibility(ctx, &op); err != nil {
+		return ack, fmt.Errorf("promoting %s on %s: %w", buildID, taskQueue, err)
+	}
+	return ack, nil
+}
+
+func (o output) promoted(ack promotionAck) {
+	if o.json {
+		o.writeJSON(ack)
+		return
+	}
+	if ack.Operation == "" {
+		fmt.Fprintf(o.out, "Build ID %s is already the default on %s.\n", ack.BuildID, ack.TaskQueue)
+		return
+	}
+	fmt.Fprintf(o.out, "New scans on %s now run on Build ID %s.\n", ack.TaskQueue, ack.BuildID)
+	if ack.PreviousDefault != "" {
+		fmt.Fprintf(o.out, "  Running scans finish on %s; stop its workers once they have.\n", ack.PreviousDefault)
+	}
+}
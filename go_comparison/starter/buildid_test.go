@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+)
+
+// versionSets builds a GetWorkerBuildIdCompatibility result, oldest set
+// first. The SDK does not export the set type, so it is decoded.
+func versionSets(t *testing.T, sets ...[]string) *client.WorkerBuildIDVersionSets {
+	t.Helper()
+	var raw struct {
+		Sets []map[string][]string
+	}
+	for _, ids := range sets {
+		raw.Sets = append(raw.Sets, map[string][]string{"BuildIDs": ids})
+	}
+	b, err := json.Marshal(raw)
+	require.NoError(t, err)
+	out := &client.WorkerBuildIDVersionSets{}
+	require.NoError(t, json.Unmarshal(b, out))
+	return out
+}
+
+func expectGet(c *mocks.Client, sets *client.WorkerBuildIDVersionSets) {
+	c.On("GetWorkerBuildIdCompatibility", mock.Anything, &client.GetWorkerBuildIdCompatibilityOptions{TaskQueue: taskQueue}).
+		Return(sets, nil).Once()
+}
+
+func TestPromoteBuildID(t *testing.T) {
+	ctx := context.Background()
+
+	// A new build gets its own default set; v1's scans stay on v1.
+	c := mocks.NewClient(t)
+	expectGet(c, versionSets(t, []string{"v1"}))
+	c.On("UpdateWorkerBuildIdCompatibility", mock.Anything, &client.UpdateWorkerBuildIdCompatibilityOptions{
+		TaskQueue: taskQueue, Operation: &client.BuildIDOpAddNewIDInNewDefaultSet{BuildID: "v2"},
+	}).Return(nil).Once()
+	ack, err := promoteBuildID(ctx, c, taskQueue, "v2")
+	require.NoError(t, err)
+	require.Equal(t, promotionAck{TaskQueue: taskQueue, BuildID: "v2", PreviousDefault: "v1", Operation: "add_new_default_set"}, ack)
+
+	// Rolling back promotes the old set again.
+	c = mocks.NewClient(t)
+	expectGet(c, versionSets(t, []string{"v1"}, []string{"v2"}))
+	c.On("UpdateWorkerBuildIdCompatibility", mock.Anything, &client.UpdateWorkerBuildIdCompatibilityOptions{
+		TaskQueue: taskQueue, Operation: &client.BuildIDOpPromoteSet{BuildID: "v1"},
+	}).Return(nil).Once()
+	ack, err = promoteBuildID(ctx, c, taskQueue, "v1")
+	require.NoError(t, err)
+	require.Equal(t, "promote_set", ack.Operation)
+
+	// Already the default: nothing to update.
+	c = mocks.NewClient(t)
+	expectGet(c, versionSets(t, []string{"v1"}, []string{"v2"}))
+	ack, err = promoteBuildID(ctx, c, taskQueue, "v2")
+	require.NoError(t, err)
+	require.Empty(t, ack.Operation)
+
+	// The first versioned deploy starts from an empty task queue.
+	c = mocks.NewClient(t)
+	expectGet(c, versionSets(t))
+	c.On("UpdateWorkerBuildIdCompatibility", mock.Anything, mock.Anything).Return(nil).Once()
+	ack, err = promoteBuildID(ctx, c, taskQueue, "v1")
+	require.NoError(t, err)
+	require.Equal(t, promotionAck{TaskQueue: taskQueue, BuildID: "v1", Operation: "add_new_default_set"}, ack)
+}
+
+func TestPromoteBuildIDFailure(t *testing.T) {
+	c := mocks.NewClient(t)
+	expectGet(c, versionSets(t, []string{"v1"}))
+	c.On("UpdateWorkerBuildIdCompatibility", mock.Anything, mock.Anything).
+		Return(errors.New("worker versioning is disabled on this namespace")).Once()
+	_, err := promoteBuildID(context.Background(), c, taskQueue, "v2")
+	require.ErrorContains(t, err, "promoting v2 on security-scanner-go: worker versioning is disabled")
+}
+
+func TestPromotedOutput(t *testing.T) {
+	ack := promotionAck{TaskQueue: taskQueue, BuildID: "v2", PreviousDefault: "v1", Operation: "add_new_default_set"}
+	o, out, _ := testOutput(false)
+	o.promoted(ack)
+	require.Contains(t, out.String(), "New scans on security-scanner-go now run on Build ID v2.")
+	require.Contains(t, out.String(), "Running scans finish on v1")
+
+	o, out, _ = testOutput(true)
+	o.promoted(ack)
+	var got promotionAck
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	require.Equal(t, ack, got)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"go.temporal.io/sdk/converter"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// compressionThreshold reads SCAN_PAYLOAD_COMPRESSION, which must match the
+// worker's.
+func compressionThreshold() int {
+	threshold, err := scanner.ParseCompressionThreshold(os.Getenv("SCAN_PAYLOAD_COMPRESSION"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: SCAN_PAYLOAD_COMPRESSION: %v\n", err)
+		os.Exit(exitError)
+	}
+	return threshold
+}
+
+// dataConverter is the converter the worker uses, so the starter can read
+// compressed results and history payloads.
+func dataConverter() converter.DataConverter {
+	return scanner.NewDataConverter(compressionThreshold())
+}
+
+// serveCodec runs a codec server on addr until it fails. Point the Web UI's
+// codec endpoint (or temporal --codec-endpoint) at it.
+func serveCodec(addr, allowOrigin string) int {
+	log.Printf("Codec server listening on %s (endpoints /encode and /decode)", addr)
+	if err := http.ListenAndServe(addr, scanner.NewCodecServer(compressionThreshold(), allowOrigin)); err != nil {
+		fmt.Fprintf(os.Stderr, "Codec server failed: %v\n", err)
+	}
+	return exitError
+}
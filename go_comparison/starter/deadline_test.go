@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDeadline(t *testing.T) {
+	now := time.Date(2026, 3, 2, 2, 5, 0, 0, time.UTC)
+	for in, want := range map[string]time.Time{
+		"04:00":                time.Date(2026, 3, 2, 4, 0, 0, 0, time.UTC),
+		"01:30":                time.Date(2026, 3, 3, 1, 30, 0, 0, time.UTC),
+		"2026-03-02T04:00:00Z": time.Date(2026, 3, 2, 4, 0, 0, 0, time.UTC),
+	} {
+		got, err := parseDeadline(in, now)
+		require.NoError(t, err, in)
+		require.True(t, want.Equal(got), "%s: got %s", in, got)
+	}
+	_, err := parseDeadline("4am", now)
+	require.ErrorContains(t, err, "clock time like 04:00")
+}
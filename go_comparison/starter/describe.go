@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/converter"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// describeClient is the part of client.Client that --describe uses.
+type describeClient interface {
+	scanDescriber
+	resultsQuerier
+}
+
+// scanDescription is what --describe prints: the run's status, its memo
+// (see the scanner's memo.go) and its search attributes.
+type scanDescription struct {
+	WorkflowID string     `json:"workflow_id"`
+	RunID      string     `json:"run_id"`
+	Status     string     `json:"status"`
+	StartTime  time.Time  `json:"start_time"`
+	CloseTime  *time.Time `json:"close_time,omitempty"`
+	// Progress is the running scan's answer to the progress query; nil
+	// when it is closed or no worker answered.
+	Progress         *scanner.ScanProgress  `json:"progress,omitempty"`
+	Memo             map[string]interface{} `json:"memo"`
+	SearchAttributes map[string]interface{} `json:"search_attributes"`
+}
+
+// describeScan reads the latest run of workflowID.
+func describeScan(ctx context.Context, c describeClient, workflowID string) (scanDescription, error) {
+	resp, err := c.DescribeWorkflowExecution(ctx, workflowID, "")
+	if err != nil {
+		return scanDescription{}, err
+	}
+	info := resp.GetWorkflowExecutionInfo()
+	d := scanDescription{
+		WorkflowID: info.GetExecution().GetWorkflowId(),
+		RunID:      info.GetExecution().GetRunId(),
+		Status:     info.GetStatus().String(),
+		StartTime:  info.GetStartTime().AsTime(),
+		Memo:       decodePayloads(dataConverter(), info.GetMemo().GetFields()),
+		// Search attributes are never encoded by the payload codec.
+		SearchAttributes: decodePayloads(converter.GetDefaultDataConverter(), info.GetSearchAttributes().GetIndexedFields()),
+	}
+	if info.GetCloseTime() != nil {
+		t := info.GetCloseTime().AsTime()
+		d.CloseTime = &t
+	}
+	if info.GetStatus() == enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		// Best effort: a scan with no worker polling can't answer.
+		if val, err := c.QueryWorkflow(ctx, d.WorkflowID, d.RunID, "progress"); err == nil {
+			var p scanner.ScanProgress
+			if val.Get(&p) == nil {
+				d.Progress = &p
+			}
+		}
+	}
+	return d, nil
+}
+
+// decodePayloads decodes each payload with dc; one that does not decode is
+// shown as such rather than failing the rest.
+func decodePayloads(dc converter.DataConverter, fields map[string]*commonpb.Payload) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields))
+	for k, p := range fields {
+		var v interface{}
+		if err := dc.FromPayload(p, &v); err != nil {
+			v = fmt.Sprintf("(undecodable: %v)", err)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// printDescription writes d as --describe's text view.
+func printDescription(w io.Writer, d scanDescription) {
+	fmt.Fprintf(w, "Scan %s\n", d.WorkflowID)
+	fmt.Fprintf(w, "  Run ID:   %s\n", d.RunID)
+	status := d.Status
+	if p := d.Progress; p != nil {
+		status += " — " + listProgress(*p)
+	}
+	fmt.Fprintf(w, "  Status:   %s\n", status)
+	fmt.Fprintf(w, "  Started:  %s\n", d.StartTime.UTC().Format(time.RFC3339))
+	if d.CloseTime != nil {
+		fmt.Fprintf(w, "  Closed:   %s\n", d.CloseTime.UTC().Format(time.RFC3339))
+	}
+	for _, section := range []struct {
+		title  string
+		fields map[string]interface{}
+	}{
+		{"Memo", d.Memo},
+		{"Search attributes", d.SearchAttributes},
+	} {
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		if len(section.fields) == 0 {
+			fmt.Fprintln(w, "  (none)")
+			continue
+		}
+		keys := make([]string, 0, len(section.fields))
+		for k := range section.fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "  %-24s %v\n", k+":", section.fields[k])
+		}
+	}
+}
+
+func (o output) described(d scanDescription) {
+	if o.json {
+		o.writeJSON(d)
+		return
+	}
+	printDescription(o.out, d)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/enums/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// fakeDescribed is a running scan that describes itself with info and
+// answers the progress query.
+type fakeDescribed struct {
+	t        *testing.T
+	info     *workflowpb.WorkflowExecutionInfo
+	progress scanner.ScanProgress
+}
+
+func (f *fakeDescribed) DescribeWorkflowExecution(_ context.Context, workflowID, runID string) (*workflowservice.DescribeWorkflowExecutionResponse, error) {
+	require.Equal(f.t, "security-scan-acme", workflowID)
+	require.Empty(f.t, runID, "the latest run")
+	return &workflowservice.DescribeWorkflowExecutionResponse{WorkflowExecutionInfo: f.info}, nil
+}
+
+func (f *fakeDescribed) QueryWorkflow(_ context.Context, workflowID, runID, queryType string, args ...interface{}) (converter.EncodedValue, error) {
+	require.Equal(f.t, "run-1", runID)
+	b, err := json.Marshal(f.progress)
+	return encodedJSON(b), err
+}
+
+func TestDescribeScan(t *testing.T) {
+	fields := map[string]*commonpb.Payload{}
+	in := scanner.ScanInput{Org: "acme", IncludeTopics: []string{"tier-1"}, InitiatedBy: "alice", Reason: "Q3 sweep"}
+	for k, v := range scanner.ScanMemo(in) {
+		p, err := dataConverter().ToPayload(v)
+		require.NoError(t, err)
+		fields[k] = p
+	}
+	status, err := converter.GetDefaultDataConverter().ToPayload("scanning")
+	require.NoError(t, err)
+
+	f := &fakeDescribed{
+		t: t,
+		info: &workflowpb.WorkflowExecutionInfo{
+			Execution:        &commonpb.WorkflowExecution{WorkflowId: "security-scan-acme", RunId: "run-1"},
+			Status:           enums.WORKFLOW_EXECUTION_STATUS_RUNNING,
+			StartTime:        timestamppb.New(watchStart),
+			Memo:             &commonpb.Memo{Fields: fields},
+			SearchAttributes: &commonpb.SearchAttributes{IndexedFields: map[string]*commonpb.Payload{"ScanStatus": status}},
+		},
+		progress: scanner.ScanProgress{Status: scanner.ScanScanning, TotalRepos: 200, ScannedRepos: 50},
+	}
+	d, err := describeScan(context.Background(), f, "security-scan-acme")
+	require.NoError(t, err)
+	require.Equal(t, "Running", d.Status)
+	require.Equal(t, "acme", d.Memo[scanner.MemoOrg])
+	require.Equal(t, "topics tier-1", d.Memo[scanner.MemoFilters])
+	require.Equal(t, map[string]interface{}{"ScanStatus": "scanning"}, d.SearchAttributes)
+	require.Equal(t, 50, d.Progress.ScannedRepos)
+
+	var buf bytes.Buffer
+	printDescription(&buf, d)
+	require.Equal(t, strings.Join([]string{
+		"Scan security-scan-acme",
+		"  Run ID:   run-1",
+		"  Status:   Running — 50/200 (25.0%) scanning",
+		"  Started:  2026-03-02T14:00:00Z",
+		"",
+		"Memo:",
+		"  checks:                  default (secret_scanning, dependabot, code_scanning)",
+		"  filters:                 topics tier-1",
+		"  initiated_by:            alice",
+		"  org:                     acme",
+		"  policy:                  default",
+		"  provider:                github",
+		"  reason:                  Q3 sweep",
+		"  scanner_version:         dev",
+		"",
+		"Search attributes:",
+		"  ScanStatus:              scanning",
+		"",
+	}, "\n"), buf.String())
+}
+
+func TestDescribeScanClosedWithoutMemo(t *testing.T) {
+	f := &fakeDescribed{t: t, info: &workflowpb.WorkflowExecutionInfo{
+		Execution: &commonpb.WorkflowExecution{WorkflowId: "security-scan-acme", RunId: "run-1"},
+		Status:    enums.WORKFLOW_EXECUTION_STATUS_COMPLETED,
+		StartTime: timestamppb.New(watchStart),
+		CloseTime: timestamppb.New(watchStart.Add(90 * time.Second)),
+	}}
+	d, err := describeScan(context.Background(), f, "security-scan-acme")
+	require.NoError(t, err)
+	require.Nil(t, d.Progress, "a closed scan is not queried")
+
+	var buf bytes.Buffer
+	printDescription(&buf, d)
+	require.Contains(t, buf.String(), "  Closed:   2026-03-02T14:01:30Z\n\nMemo:\n  (none)\n")
+}
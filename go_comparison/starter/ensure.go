@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// ensureAttempts bounds how often ensureScan goes round when the running
+// scan finishes between a failed start and the update.
+const ensureAttempts = 3
+
+// scanEnsurer is the part of client.Client that ensureScan uses.
+type scanEnsurer interface {
+	ExecuteWorkflow(ctx context.Context, options client.StartWorkflowOptions, workflow interface{}, args ...interface{}) (client.WorkflowRun, error)
+	UpdateWorkflow(ctx context.Context, workflowID, workflowRunID, updateName string, args ...interface{}) (client.WorkflowUpdateHandle, error)
+}
+
+// ensureAck is the result of --ensure. Progress is set when the scan was
+// already running.
+type ensureAck struct {
+	Started    bool                  `json:"started"`
+	WorkflowID string                `json:"workflow_id"`
+	RunID      string                `json:"run_id"`
+	Progress   *scanner.ScanProgress `json:"progress,omitempty"`
+}
+
+// ensureScan starts the scan of input under options.ID unless one is
+// already running there, in which case it attaches with the
+// update_scan_config update and returns the scan's progress. The server
+// starts at most one run per ID, so of two invocations racing for the same
+// ID one starts the scan and the other attaches to it.
+//
+// The SDK this module is pinned to has no update-with-start, so this is a
+// start followed by an update; a scan that finishes in between is started
+// again.
+func ensureScan(ctx context.Context, c scanEnsurer, options client.StartWorkflowOptions, input scanner.ScanInput) (ensureAck, error) {
+	// A finished scan's ID may be reused; a running one is never replaced.
+	options.WorkflowIDReusePolicy = enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE
+	options.WorkflowExecutionErrorWhenAlreadyStarted = true
+
+	for attempt := 1; ; attempt++ {
+		run, err := c.ExecuteWorkflow(ctx, options, scanner.SecurityScanWorkflow, input)
+		if err == nil {
+			return ensureAck{Started: true, WorkflowID: run.GetID(), RunID: run.GetRunID()}, nil
+		}
+		var running *serviceerror.WorkflowExecutionAlreadyStarted
+		if !errors.As(err, &running) {
+			return ensureAck{}, fmt.Errorf("starting the scan: %w", err)
+		}
+
+		var progress scanner.ScanProgress
+		handle, err := c.UpdateWorkflow(ctx, options.ID, running.RunId, scanner.UpdateScanConfig, input)
+		if err == nil {
+			err = handle.Get(ctx, &progress)
+		}
+		var gone *serviceerror.NotFound
+		switch {
+		case err == nil:
+			return ensureAck{WorkflowID: options.ID, RunID: running.RunId, Progress: &progress}, nil
+		case errors.As(err, &gone) && attempt < ensureAttempts:
+			continue // finished since the start failed
+		default:
+			return ensureAck{}, fmt.Errorf("attaching to the running scan: %w", err)
+		}
+	}
+}
+
+func (o output) ensured(ack ensureAck, now time.Time) {
+	if o.json {
+		o.writeJSON(ack)
+		return
+	}
+	if ack.Started {
+		fmt.Fprintf(o.out, "Started new scan %s (run %s).\n", ack.WorkflowID, ack.RunID)
+		fmt.Fprintf(o.out, "  Query:  go run ./go_comparison/starter --workflow-id %s --query\n", ack.WorkflowID)
+		return
+	}
+	p := *ack.Progress
+	fmt.Fprintf(o.out, "Attached to existing scan %s started %d minutes ago.\n\n", ack.WorkflowID, int(now.Sub(p.StartedAt).Minutes()))
+	o.progress(p.Org, p)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// fakeServer starts at most one run per workflow ID, like the Temporal
+// server, and answers update_scan_config for running ones.
+type fakeServer struct {
+	mu      sync.Mutex
+	running map[string]string // workflow ID -> run ID
+	starts  int
+	updates int
+	// finishBeforeUpdate completes the running scan when an update arrives,
+	// as if it finished between the start and the update.
+	finishBeforeUpdate bool
+	options            []client.StartWorkflowOptions
+}
+
+type fakeRun struct {
+	client.WorkflowRun
+	id, runID string
+}
+
+func (r fakeRun) GetID() string    { return r.id }
+func (r fakeRun) GetRunID() string { return r.runID }
+
+type fakeUpdate struct {
+	client.WorkflowUpdateHandle
+	progress scanner.ScanProgress
+}
+
+func (u fakeUpdate) Get(_ context.Context, valuePtr interface{}) error {
+	*valuePtr.(*scanner.ScanProgress) = u.progress
+	return nil
+}
+
+func (s *fakeServer) ExecuteWorkflow(_ context.Context, options client.StartWorkflowOptions, _ interface{}, _ ...interface{}) (client.WorkflowRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options = append(s.options, options)
+	if runID, ok := s.running[options.ID]; ok {
+		return nil, serviceerror.NewWorkflowExecutionAlreadyStarted("Workflow execution is already running", "", runID)
+	}
+	s.starts++
+	runID := fmt.Sprintf("run-%d", s.starts)
+	s.running[options.ID] = runID
+	return fakeRun{id: options.ID, runID: runID}, nil
+}
+
+func (s *fakeServer) UpdateWorkflow(_ context.Context, workflowID, runID, updateName string, _ ...interface{}) (client.WorkflowUpdateHandle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finishBeforeUpdate {
+		s.finishBeforeUpdate = false
+		delete(s.running, workflowID)
+	}
+	if updateName != scanner.UpdateScanConfig || s.running[workflowID] != runID {
+		return nil, serviceerror.NewNotFound("workflow execution already completed")
+	}
+	s.updates++
+	return fakeUpdate{progress: scanner.ScanProgress{Org: "acme", Status: scanner.ScanScanning, RunID: runID, ScannedRepos: 3, TotalRepos: 10}}, nil
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{running: make(map[string]string)}
+}
+
+func TestEnsureScanStartsOrAttaches(t *testing.T) {
+	s := newFakeServer()
+	options := client.StartWorkflowOptions{ID: "security-scan-acme", TaskQueue: taskQueue}
+	input := scanner.ScanInput{Org: "acme"}
+
+	ack, err := ensureScan(context.Background(), s, options, input)
+	require.NoError(t, err)
+	require.Equal(t, ensureAck{Started: true, WorkflowID: "security-scan-acme", RunID: "run-1"}, ack)
+	require.True(t, s.options[0].WorkflowExecutionErrorWhenAlreadyStarted)
+	require.Equal(t, enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE, s.options[0].WorkflowIDReusePolicy, "a running scan is never replaced")
+
+	ack, err = ensureScan(context.Background(), s, options, input)
+	require.NoError(t, err)
+	require.False(t, ack.Started)
+	require.Equal(t, "run-1", ack.RunID)
+	require.Equal(t, 3, ack.Progress.ScannedRepos)
+	require.Equal(t, 1, s.starts)
+}
+
+func TestEnsureScanConcurrent(t *testing.T) {
+	s := newFakeServer()
+	options := client.StartWorkflowOptions{ID: "security-scan-acme", TaskQueue: taskQueue}
+
+	const callers = 8
+	acks := make([]ensureAck, callers)
+	var wg sync.WaitGroup
+	for i := range acks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			acks[i], err = ensureScan(context.Background(), s, options, scanner.ScanInput{Org: "acme"})
+			require.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	started := 0
+	for _, ack := range acks {
+		require.Equal(t, "run-1", ack.RunID, "every caller ends up on the same run")
+		if ack.Started {
+			started++
+		}
+	}
+	require.Equal(t, 1, started)
+	require.Equal(t, 1, s.starts)
+	require.Equal(t, callers-1, s.updates)
+}
+
+func TestEnsureScanRestartsFinishedScan(t *testing.T) {
+	s := newFakeServer()
+	options := client.StartWorkflowOptions{ID: "security-scan-acme", TaskQueue: taskQueue}
+	_, err := ensureScan(context.Background(), s, options, scanner.ScanInput{Org: "acme"})
+	require.NoError(t, err)
+
+	s.finishBeforeUpdate = true
+	ack, err := ensureScan(context.Background(), s, options, scanner.ScanInput{Org: "acme"})
+	require.NoError(t, err)
+	require.Equal(t, ensureAck{Started: true, WorkflowID: "security-scan-acme", RunID: "run-2"}, ack)
+}
+
+func TestEnsuredOutput(t *testing.T) {
+	o, out, _ := testOutput(false)
+	o.ensured(ensureAck{Started: true, WorkflowID: "security-scan-acme", RunID: "run-1"}, time.Now())
+	require.Contains(t, out.String(), "Started new scan security-scan-acme (run run-1).")
+
+	now := time.Date(2026, 3, 2, 14, 12, 30, 0, time.UTC)
+	p := scanner.ScanProgress{Org: "acme", Status: scanner.ScanScanning, ScannedRepos: 3, TotalRepos: 10, StartedAt: now.Add(-12 * time.Minute)}
+	attached := ensureAck{WorkflowID: "security-scan-acme", RunID: "run-1", Progress: &p}
+	o, out, _ = testOutput(false)
+	o.ensured(attached, now)
+	require.Contains(t, out.String(), "Attached to existing scan security-scan-acme started 12 minutes ago.")
+	require.Contains(t, out.String(), "3/10 repos (30.0%)")
+
+	o, out, _ = testOutput(true)
+	o.ensured(attached, now)
+	var got ensureAck
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	require.Equal(t, attached, got)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// enterpriseCommand is what --enterprise does besides starting a scan.
+type enterpriseCommand struct {
+	query         bool
+	cancelReason  string
+	noPreflight   bool
+	noWait        bool
+	waitTimeout   time.Duration
+	minCompliance float64
+	file          reportFile
+}
+
+// runEnterprise starts, queries or cancels the EnterpriseScanWorkflow of
+// in.Enterprise and returns the exit code. Its org scans are queried and
+// cancelled through it.
+func runEnterprise(o output, in scanner.EnterpriseScanInput, cmd enterpriseCommand) int {
+	workflowID := scanner.EnterpriseWorkflowID(in.Enterprise)
+	if cmd.query || cmd.cancelReason != "" {
+		c := dial()
+		defer c.Close()
+		if cmd.cancelReason != "" {
+			doCancel(c, o, workflowID, cmd.cancelReason)
+			return exitOK
+		}
+		return queryEnterprise(c, o, workflowID)
+	}
+
+	if err := in.Validate(); err != nil {
+		printError(os.Stderr, "Error", err)
+		return exitError
+	}
+	if in.Token == nil {
+		fmt.Fprintln(os.Stderr, "Error: --enterprise needs a token with the read:enterprise scope (--token or GITHUB_TOKEN)")
+		return exitError
+	}
+	// The workflow lists the orgs with the same activity, so a token that
+	// cannot read the enterprise fails here instead of after its retries.
+	if !cmd.noPreflight {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		a := &scanner.Activities{HTTPClient: &http.Client{}, BaseURL: githubAPIURL()}
+		orgs, err := a.FetchEnterpriseOrgs(ctx, in.Enterprise, scanner.PATCredential(in.Token))
+		cancel()
+		var appErr *temporal.ApplicationError
+		switch {
+		case errors.As(err, &appErr) && appErr.NonRetryable():
+			fmt.Fprintf(os.Stderr, "Error: %s\n", appErr.Message())
+			return exitError
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: could not list the orgs of '%s' (%v); starting anyway\n", in.Enterprise, err)
+		default:
+			fmt.Fprintf(o.info, "Enterprise '%s': %d orgs the token can see\n", in.Enterprise, len(orgs))
+		}
+	}
+
+	c := dial()
+	defer c.Close()
+
+	fmt.Fprintf(o.info, "Starting enterprise security scan for '%s'...\n", in.Enterprise)
+	fmt.Fprintf(o.info, "  Workflow ID: %s\n", workflowID)
+	fmt.Fprintf(o.info, "  Task Queue:  %s\n\n", taskQueue)
+	// Each org scan gets its own 30 minutes; the enterprise scan has no
+	// timeout of its own.
+	run, err := c.ExecuteWorkflow(context.Background(), client.StartWorkflowOptions{
+		ID:                    workflowID,
+		TaskQueue:             taskQueue,
+		WorkflowIDReusePolicy: enums.WORKFLOW_ID_REUSE_POLICY_TERMINATE_IF_RUNNING,
+		Memo:                  scanner.EnterpriseScanMemo(in, ""),
+	}, scanner.EnterpriseScanWorkflow, in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start workflow: %v\n", err)
+		return exitError
+	}
+	if cmd.noWait {
+		o.started(startedAck{WorkflowID: workflowID, RunID: run.GetRunID(), TaskQueue: taskQueue})
+		return exitOK
+	}
+
+	fmt.Fprint(o.info, "Scanning... (use --enterprise --query in another terminal to check progress)\n\n")
+	var report scanner.EnterpriseReport
+	err = waitForScan(context.Background(), c, run, cmd.waitTimeout, waitCheckInterval, &report)
+	if code, ok := waitStopped(os.Stderr, run, err, cmd.waitTimeout); ok {
+		return code
+	}
+	if err != nil {
+		printError(os.Stderr, "Workflow failed", err)
+		return exitError
+	}
+
+	o.enterpriseReport(report)
+	if !cmd.file.save(o.info, enterpriseReportPath(in.Enterprise), report) {
+		return exitError
+	}
+	return enterpriseExitCode(report, cmd.minCompliance)
+}
+
+func queryEnterprise(c client.Client, o output, workflowID string) int {
+	resp, err := c.QueryWorkflow(context.Background(), workflowID, "", "progress")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Query failed: %v\n", err)
+		return exitError
+	}
+	var progress scanner.EnterpriseProgress
+	if err := resp.Get(&progress); err != nil {
+		fmt.Fprintf(os.Stderr, "Decoding progress failed: %v\n", err)
+		return exitError
+	}
+	o.enterpriseProgress(progress)
+	return exitOK
+}
+
+// enterpriseExitCode is reportExitCode for an enterprise report. An org
+// that could not be scanned makes the report incomplete, so it is an
+// error.
+func enterpriseExitCode(r scanner.EnterpriseReport, minCompliance float64) int {
+	switch {
+	case r.Cancelled:
+		return exitCancelled
+	case len(r.FailedOrgs) > 0:
+		return exitError
+	case minCompliance > 0 && r.Rate() < minCompliance:
+		return exitComplianceFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/api/temporalproto"
+	"go.temporal.io/sdk/client"
+)
+
+// eventIterator is the part of client.HistoryEventIterator that
+// exportHistory uses.
+type eventIterator interface {
+	HasNext() bool
+	Next() (*historypb.HistoryEvent, error)
+}
+
+// doExportHistory writes the scan's full event history in the same JSON
+// format as `temporal workflow show --output json`, which is what
+// worker.WorkflowReplayer and the UI import read. Exported histories of
+// completed scans are checked into go_comparison/testdata/histories as
+// replay goldens. With timeline set, a per-activity timeline is printed
+// as the command's result.
+func doExportHistory(c client.Client, o output, workflowID, path string, timeline bool) {
+	info := describeTarget(c, o, workflowID)
+	if info.GetStatus() == enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		fmt.Fprintln(os.Stderr, "Warning: the scan is still running; the history ends at its latest event")
+	}
+	runID := info.GetExecution().GetRunId()
+
+	// Write next to path and rename, so a failed export never leaves a
+	// truncated file where a golden is expected.
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Writing history failed: %v\n", err)
+		os.Exit(exitError)
+	}
+	defer os.Remove(f.Name())
+
+	var tl *scanTimeline
+	if timeline {
+		tl = &scanTimeline{WorkflowID: workflowID, RunID: runID}
+	}
+	iter := c.GetWorkflowHistory(context.Background(), workflowID, runID, false, enums.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	n, err := exportHistory(f, iter, tl)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Exporting history failed: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Fprintf(o.info, "Exported %d events from '%s' to %s\n", n, workflowID, path)
+	if tl != nil {
+		o.timeline(tl)
+	}
+}
+
+// exportHistory streams events from iter to w as {"events": [...]}, one
+// event at a time, so a long scan's history is never held in memory. Each
+// event is also fed to tl when it is not nil. It returns the event count.
+func exportHistory(w io.Writer, iter eventIterator, tl *scanTimeline) (int, error) {
+	marshal := temporalproto.CustomJSONMarshalOptions{Indent: "  "}
+	if _, err := io.WriteString(w, "{\n  \"events\": ["); err != nil {
+		return 0, err
+	}
+	n := 0
+	for iter.HasNext() {
+		event, err := iter.Next()
+		if err != nil {
+			return n, fmt.Errorf("reading history: %w", err)
+		}
+		b, err := marshal.Marshal(event)
+		if err != nil {
+			return n, fmt.Errorf("encoding event %d: %w", event.GetEventId(), err)
+		}
+		sep := ",\n    "
+		if n == 0 {
+			sep = "\n    "
+		}
+		// Re-indent so each event nests under "events".
+		b = indentJSON(b, "    ")
+		if _, err := io.WriteString(w, sep); err != nil {
+			return n, err
+		}
+		if _, err := w.Write(b); err != nil {
+			return n, err
+		}
+		if tl != nil {
+			tl.add(event)
+		}
+		n++
+	}
+	if n > 0 {
+		_, err := io.WriteString(w, "\n  ]\n}\n")
+		return n, err
+	}
+	_, err := io.WriteString(w, "]\n}\n")
+	return n, err
+}
+
+// indentJSON prefixes every line after the first of an indented JSON
+// document with prefix.
+func indentJSON(b []byte, prefix string) []byte {
+	out := make([]byte, 0, len(b)+len(b)/8)
+	for _, c := range b {
+		out = append(out, c)
+		if c == '\n' {
+			out = append(out, prefix...)
+		}
+	}
+	return out
+}
+
+// repoActivities take (org, repoName, ...) arguments, so their timeline
+// entries are labelled with the repo.
+var repoActivities = map[string]bool{
+	"CheckRepoSecurity":    true,
+	"CheckActionsSecurity": true,
+	"AuditRepoAccess":      true,
+}
+
+// scanTimeline condenses a history into one entry per activity or child
+// workflow, so it grows with the number of activities rather than events.
+// Local activities (BuildReport) are recorded as markers and are not
+// listed.
+type scanTimeline struct {
+	WorkflowID string          `json:"workflow_id"`
+	RunID      string          `json:"run_id"`
+	StartedAt  time.Time       `json:"started_at"`
+	ClosedAt   *time.Time      `json:"closed_at,omitempty"`
+	Status     string          `json:"status"`
+	Entries    []timelineEntry `json:"entries"`
+
+	open map[int64]int // scheduled/initiated event ID -> index in Entries
+}
+
+type timelineEntry struct {
+	Type            string     `json:"type"`
+	Repo            string     `json:"repo,omitempty"`
+	ChildWorkflowID string     `json:"child_workflow_id,omitempty"`
+	ScheduledAt     time.Time  `json:"scheduled_at"`
+	ClosedAt        *time.Time `json:"closed_at,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds,omitempty"`
+	Status          string     `json:"status"`
+	Attempt         int32      `json:"attempt,omitempty"`
+}
+
+func (tl *scanTimeline) add(event *historypb.HistoryEvent) {
+	if tl.open == nil {
+		tl.open = make(map[int64]int)
+	}
+	at := event.GetEventTime().AsTime()
+
+	switch event.GetEventType() {
+	case enums.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED:
+		tl.StartedAt = at
+		tl.Status = "running"
+	case enums.EVENT_TYPE_WORKFLOW_EXECUTION_COMPLETED:
+		tl.closeWorkflow(at, "completed")
+	case enums.EVENT_TYPE_WORKFLOW_EXECUTION_FAILED:
+		tl.closeWorkflow(at, "failed")
+	case enums.EVENT_TYPE_WORKFLOW_EXECUTION_TIMED_OUT:
+		tl.closeWorkflow(at, "timed out")
+	case enums.EVENT_TYPE_WORKFLOW_EXECUTION_CANCELED:
+		tl.closeWorkflow(at, "cancelled")
+	case enums.EVENT_TYPE_WORKFLOW_EXECUTION_TERMINATED:
+		tl.closeWorkflow(at, "terminated")
+	case enums.EVENT_TYPE_WORKFLOW_EXECUTION_CONTINUED_AS_NEW:
+		tl.closeWorkflow(at, "continued as new")
+
+	case enums.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED:
+		attrs := event.GetActivityTaskScheduledEventAttributes()
+		entry := timelineEntry{Type: attrs.GetActivityType().GetName(), ScheduledAt: at, Status: "scheduled"}
+		if repoActivities[entry.Type] {
+			entry.Repo = repoArg(attrs.GetInput().GetPayloads())
+		}
+		tl.open[event.GetEventId()] = len(tl.Entries)
+		tl.Entries = append(tl.Entries, entry)
+	case enums.EVENT_TYPE_ACTIVITY_TASK_STARTED:
+		attrs := event.GetActivityTaskStartedEventAttributes()
+		if i, ok := tl.open[attrs.GetScheduledEventId()]; ok {
+			tl.Entries[i].Status = "started"
+			tl.Entries[i].Attempt = attrs.GetAttempt()
+		}
+	case enums.EVENT_TYPE_ACTIVITY_TASK_COMPLETED:
+		tl.close(event.GetActivityTaskCompletedEventAttributes().GetScheduledEventId(), at, "completed")
+	case enums.EVENT_TYPE_ACTIVITY_TASK_FAILED:
+		tl.close(event.GetActivityTaskFailedEventAttributes().GetScheduledEventId(), at, "failed")
+	case enums.EVENT_TYPE_ACTIVITY_TASK_TIMED_OUT:
+		tl.close(event.GetActivityTaskTimedOutEventAttributes().GetScheduledEventId(), at, "timed out")
+	case enums.EVENT_TYPE_ACTIVITY_TASK_CANCELED:
+		tl.close(event.GetActivityTaskCanceledEventAttributes().GetScheduledEventId(), at, "cancelled")
+
+	case enums.EVENT_TYPE_START_CHILD_WORKFLOW_EXECUTION_INITIATED:
+		attrs := event.GetStartChildWorkflowExecutionInitiatedEventAttributes()
+		tl.open[event.GetEventId()] = len(tl.Entries)
+		tl.Entries = append(tl.Entries, timelineEntry{
+			Type: attrs.GetWorkflowType().GetName(), ChildWorkflowID: attrs.GetWorkflowId(),
+			ScheduledAt: at, Status: "scheduled",
+		})
+	case enums.EVENT_TYPE_CHILD_WORKFLOW_EXECUTION_STARTED:
+		if i, ok := tl.open[event.GetChildWorkflowExecutionStartedEventAttributes().GetInitiatedEventId()]; ok {
+			tl.Entries[i].Status = "started"
+		}
+	case enums.EVENT_TYPE_START_CHILD_WORKFLOW_EXECUTION_FAILED:
+		tl.close(event.GetStartChildWorkflowExecutionFailedEventAttributes().GetInitiatedEventId(), at, "failed to start")
+	case enums.EVENT_TYPE_CHILD_WORKFLOW_EXECUTION_COMPLETED:
+		tl.close(event.GetChildWorkflowExecutionCompletedEventAttributes().GetInitiatedEventId(), at, "completed")
+	case enums.EVENT_TYPE_CHILD_WORKFLOW_EXECUTION_FAILED:
+		tl.close(event.GetChildWorkflowExecutionFailedEventAttributes().GetInitiatedEventId(), at, "failed")
+	case enums.EVENT_TYPE_CHILD_WORKFLOW_EXECUTION_TIMED_OUT:
+		tl.close(event.GetChildWorkflowExecutionTimedOutEventAttributes().GetInitiatedEventId(), at, "timed out")
+	case enums.EVENT_TYPE_CHILD_WORKFLOW_EXECUTION_CANCELED:
+		tl.close(event.GetChildWorkflowExecutionCanceledEventAttributes().GetInitiatedEventId(), at, "cancelled")
+	case enums.EVENT_TYPE_CHILD_WORKFLOW_EXECUTION_TERMINATED:
+		tl.close(event.GetChildWorkflowExecutionTerminatedEventAttributes().GetInitiatedEventId(), at, "terminated")
+	}
+}
+
+func (tl *scanTimeline) close(id int64, at time.Time, status string) {
+	i, ok := tl.open[id]
+	if !ok {
+		return
+	}
+	delete(tl.open, id)
+	e := &tl.Entries[i]
+	e.ClosedAt = &at
+	e.DurationSeconds = at.Sub(e.ScheduledAt).Seconds()
+	e.Status = status
+}
+
+func (tl *scanTimeline) closeWorkflow(at time.Time, status string) {
+	tl.ClosedAt = &at
+	tl.Status = status
+}
+
+// repoArg returns "org/name" from an activity's first two arguments, or ""
+// if they can't be decoded.
+func repoArg(payloads []*commonpb.Payload) string {
+	if len(payloads) < 2 {
+		return ""
+	}
+	dc := dataConverter()
+	var org, name string
+	if dc.FromPayload(payloads[0], &org) != nil || dc.FromPayload(payloads[1], &name) != nil {
+		return ""
+	}
+	return org + "/" + name
+}
+
+// timeline prints a scanTimeline; --json prints it verbatim.
+func (o output) timeline(tl *scanTimeline) {
+	if o.json {
+		o.writeJSON(tl)
+		return
+	}
+	fmt.Fprintf(o.out, "\nTimeline of '%s' (run %s)\n", tl.WorkflowID, tl.RunID)
+	fmt.Fprintf(o.out, "  %s  started\n", tl.StartedAt.Local().Format(time.RFC3339))
+	for _, e := range tl.Entries {
+		label := e.Type
+		switch {
+		case e.Repo != "":
+			label += "  " + e.Repo
+		case e.ChildWorkflowID != "":
+			label += "  " + e.ChildWorkflowID
+		}
+		took := ""
+		if e.ClosedAt != nil {
+			took = formatSeconds(e.DurationSeconds)
+		}
+		status := e.Status
+		if e.Attempt > 1 {
+			status += fmt.Sprintf(" (attempt %d)", e.Attempt)
+		}
+		fmt.Fprintf(o.out, "  %9s %8s  %-50s %s\n",
+			"+"+formatSeconds(e.ScheduledAt.Sub(tl.StartedAt).Seconds()), took, label, status)
+	}
+	if tl.ClosedAt != nil {
+		fmt.Fprintf(o.out, "  %9s %8s  %s\n", "+"+formatSeconds(tl.ClosedAt.Sub(tl.StartedAt).Seconds()), "", tl.Status)
+	} else {
+		fmt.Fprintf(o.out, "  %9s %8s  %s\n", "", "", tl.Status)
+	}
+}
+
+func formatSeconds(s float64) string {
+	return fmt.Sprintf("%.1fs", s)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/sdk/client"
+	"google.golang.org/protobuf/proto"
+)
+
+// sliceIterator replays a loaded history as if from GetWorkflowHistory.
+type sliceIterator struct{ events []*historypb.HistoryEvent }
+
+func (it *sliceIterator) HasNext() bool { return len(it.events) > 0 }
+
+func (it *sliceIterator) Next() (*historypb.HistoryEvent, error) {
+	e := it.events[0]
+	it.events = it.events[1:]
+	return e, nil
+}
+
+func loadGolden(t *testing.T, name string) *historypb.History {
+	f, err := os.Open(filepath.Join("..", "testdata", "histories", name))
+	require.NoError(t, err)
+	defer f.Close()
+	hist, err := client.HistoryFromJSON(f, client.HistoryJSONOptions{})
+	require.NoError(t, err)
+	return hist
+}
+
+func TestExportHistoryRoundTrip(t *testing.T) {
+	hist := loadGolden(t, "completed.json")
+
+	var buf bytes.Buffer
+	n, err := exportHistory(&buf, &sliceIterator{hist.Events}, nil)
+	require.NoError(t, err)
+	require.Equal(t, len(hist.Events), n)
+
+	got, err := client.HistoryFromJSON(&buf, client.HistoryJSONOptions{})
+	require.NoError(t, err)
+	require.True(t, proto.Equal(hist, got), "the export reads back as the same history")
+
+	buf.Reset()
+	_, err = exportHistory(&buf, &sliceIterator{}, nil)
+	require.NoError(t, err)
+	got, err = client.HistoryFromJSON(&buf, client.HistoryJSONOptions{})
+	require.NoError(t, err)
+	require.Empty(t, got.Events)
+}
+
+func TestExportHistoryTimeline(t *testing.T) {
+	hist := loadGolden(t, "error_heavy_degraded.json")
+	tl := &scanTimeline{WorkflowID: "security-scan-acme-corp"}
+	var buf bytes.Buffer
+	_, err := exportHistory(&buf, &sliceIterator{hist.Events}, tl)
+	require.NoError(t, err)
+
+	require.Equal(t, "failed", tl.Status)
+	require.NotNil(t, tl.ClosedAt)
+	require.Len(t, tl.Entries, 14)
+	require.Equal(t, "FetchOrgRepos", tl.Entries[0].Type)
+	require.Empty(t, tl.Entries[0].Repo)
+
+	failed := 0
+	for _, e := range tl.Entries {
+		require.NotNil(t, e.ClosedAt, e.Type)
+		if e.Type == "CheckRepoSecurity" {
+			require.Regexp(t, `^acme-corp/service-\d\d$`, e.Repo)
+		}
+		if e.Status == "failed" {
+			failed++
+		}
+	}
+	require.Equal(t, 9, failed)
+
+	o, out, _ := testOutput(false)
+	o.timeline(tl)
+	require.Contains(t, out.String(), "CheckRepoSecurity  acme-corp/service-01")
+	require.Contains(t, out.String(), "failed")
+}
@@ -6,176 +6,1161 @@
 //	go run ./go_comparison/starter --org temporalio
 //	Set GITHUB_TOKEN to avoid rate limits. Then:
 //	go run ./go_comparison/starter --org temporalio --no-wait
+//	go run ./go_comparison/starter --org temporalio --no-preflight
 //	go run ./go_comparison/starter --org temporalio --query
+//	go run ./go_comparison/starter --org temporalio --describe [--json]
+//	go run ./go_comparison/starter --org temporalio --results [--verbose] [--json]
+//	go run ./go_comparison/starter --org temporalio --results --results-limit 200 --results-offset 400
 //	go run ./go_comparison/starter --org temporalio --cancel "reason"
+//	go run ./go_comparison/starter --org temporalio --export-history history.json [--timeline]
+//	go run ./go_comparison/starter --org temporalio --checks secret_scanning,files,actions
+//	go run ./go_comparison/starter --provider gitlab --org acme/platform --gitlab-subgroups
+//	go run ./go_comparison/starter --list-checks
+//	go run ./go_comparison/starter --report-schema > report.schema.json
+//	go run ./go_comparison/starter --org temporalio --active-within 180d
+//	go run ./go_comparison/starter --org temporalio --suppressions suppressions.yaml
+//	go run ./go_comparison/starter --org temporalio --sink file:dir=/var/reports --sink webhook:url=https://hooks.example.com/scans
+//	go run ./go_comparison/starter --list [--org temporalio] [--json]
+//	go run ./go_comparison/starter --watch-all [--watch-interval 30s] [--json-stream]
+//	go run ./go_comparison/starter --repos-file critical.txt
+//	go run ./go_comparison/starter --org temporalio --team platform --team payments
+//	go run ./go_comparison/starter --diff last_week.json security_scan_temporalio.json [--json]
+//	go run ./go_comparison/starter --history --org temporalio --last 12 [--reports-dir reports] [--json]
+//	grep -v archived repos.txt | go run ./go_comparison/starter --repos -
+//	go run ./go_comparison/starter --org temporalio --json --min-compliance 90 > report.json
+//	go run ./go_comparison/starter --org temporalio --format ocsf > findings.ndjson
+//	go run ./go_comparison/starter --org temporalio --format prom --output /var/lib/node_exporter/textfile/scanner_temporalio.prom --overwrite
+//	go run ./go_comparison/starter --org temporalio --verbose --no-color
+//	go run ./go_comparison/starter --org temporalio --unique --no-wait
+//	go run ./go_comparison/starter --org temporalio --ensure [--json]
+//	go run ./go_comparison/starter --org temporalio --max-api-requests 2000
+//	go run ./go_comparison/starter --org temporalio --batch-delay 5s --batch-jitter 0.3
+//	go run ./go_comparison/starter --org temporalio --concurrency 20
+//	go run ./go_comparison/starter --org temporalio --worker-affinity
+//	go run ./go_comparison/starter --rate-limit [--org temporalio]
+//	go run ./go_comparison/starter --org temporalio --terminate "bad deploy" --yes
+//	go run ./go_comparison/starter --org temporalio --force --reason "incident INC-1234" [--initiated-by oncall]
+//	go run ./go_comparison/starter --org temporalio --reset-to-first-workflow-task --yes
+//	go run ./go_comparison/starter --workflow-id security-scan-temporalio/20260302T140000Z --query
+//	go run ./go_comparison/starter --org temporalio --wait-timeout 45m
+//	go run ./go_comparison/starter --org temporalio --output reports/temporalio.json [--overwrite] | --no-save
+//	go run ./go_comparison/starter --org temporalio --attach [--run-id ID]
+//	go run ./go_comparison/starter --codec-server :8081
+//	go run ./go_comparison/starter --promote-build-id v1.3.0
+//	go run ./go_comparison/starter --config scan.yaml [--print-config]
+//
+// Exit codes: 0 success, 1 infrastructure or input error, 2 compliance
+// failure (--min-compliance or --diff regressions), 3 scan cancelled or
+// stopped by --max-api-requests, 4 scan still running when --wait-timeout
+// passed, 5 scan degraded (too many repos errored).
 package main
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"go.temporal.io/api/enums"
+	"github.com/google/uuid"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/enums/v1"
+	filterpb "go.temporal.io/api/filter/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
 	"go.temporal.io/sdk/client"
 
 	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
 )
 
 const (
-	taskQueue        = "security-scanner-go"
+	taskQueue        = scanner.TaskQueue
 	executionTimeout = 30 * time.Minute
+
+	// listLimit caps how many scans --list shows, most recent first.
+	listLimit = 50
 )
 
+// configOptions are the environment variables the starter reads, which a
+// --config file may set.
+var configOptions = scanner.ConfigOptions{
+	Env:     []string{"GITHUB_TOKEN", "GITLAB_TOKEN", "GITHUB_API_URL", "SCAN_BLOB_STORE", "SCAN_PAYLOAD_COMPRESSION", "NO_COLOR"},
+	FlagEnv: map[string][]string{"token": {"GITHUB_TOKEN", "GITLAB_TOKEN"}},
+	Secrets: []string{"token", "GITHUB_TOKEN", "GITLAB_TOKEN"},
+}
+
 func main() {
-	org := flag.String("org", "", "GitHub organization to scan (required)")
-	token := flag.String("token", "", "GitHub PAT (or set GITHUB_TOKEN)")
+	org := flag.String("org", "", "GitHub organization or GitLab group to scan (required)")
+	token := flag.String("token", "", "GitHub PAT (or set GITHUB_TOKEN; GITLAB_TOKEN with --provider gitlab)")
+	credentialType := flag.String("credential-type", "", "What --token is: pat, fine-grained or app (a GitHub App installation token, sent as Bearer); default pat")
+	credentialRef := flag.String("credential-ref", "", "Have the worker look up the scan's token under this name (SCANNER_CREDENTIAL_<NAME> on the worker) instead of sending --token")
+	provider := flag.String("provider", scanner.ProviderGitHub, "Where --org lives: github or gitlab")
+	gitlabSubgroups := flag.Bool("gitlab-subgroups", false, "With --provider gitlab, also scan the group's subgroups")
 	noWait := flag.Bool("no-wait", false, "Start workflow and exit without waiting")
+	outputPath := flag.String("output", "", "Save the finished scan's report as JSON to this path, creating its directory (default: security_scan_<org>.json)")
+	noSave := flag.Bool("no-save", false, "Don't save the finished scan's report to a file")
+	overwrite := flag.Bool("overwrite", false, "Replace the report file if it already exists")
+	waitTimeout := flag.Duration("wait-timeout", 0, "Stop waiting for the report after this long, e.g. 45m, and exit 4; the scan keeps running (0: wait until it ends)")
+	attach := flag.Bool("attach", false, "Wait for the report of a scan that is already running instead of starting one")
+	runIDFlag := flag.String("run-id", "", "With --attach, the run to wait for (default: the latest run of the workflow ID)")
 	query := flag.Bool("query", false, "Query progress of a running scan")
+	describe := flag.Bool("describe", false, "Show a scan's status, memo (org, checks, filters, policy, initiator, reason, scanner version) and search attributes")
+	tui := flag.Bool("tui", false, "Watch a running scan full-screen: progress, recent repos and errors, with keys to pause (p), resume (r), cancel (c) or quit leaving it running (q)")
+	results := flag.Bool("results", false, "Print the per-repo results of a running or recently closed scan: each repo's check statuses and error")
+	resultsOffset := flag.Int("results-offset", 0, "With --results-limit, the first result to print")
+	resultsLimit := flag.Int("results-limit", 0, "With --results, print this many results from --results-offset, for scans whose results are too large for one query (0: all)")
 	cancelReason := flag.String("cancel", "", "Cancel a running scan with this reason")
-	flag.Parse()
+	exportPath := flag.String("export-history", "", "Export the scan's workflow history as JSON to this file")
+	timeline := flag.Bool("timeline", false, "With --export-history, also print each activity's schedule, duration, and outcome")
+	accessAudit := flag.Bool("access-audit", false, "Also audit deploy keys and outside collaborators (needs admin scope)")
+	evidence := flag.Bool("evidence", false, "Record what the API returned for each check, and when, in the results and an appendix of the report, for audit trails")
+	keyMaxAge := flag.Int("deploy-key-max-age", scanner.DefaultDeployKeyMaxAgeDays, "Flag deploy keys older than this many days")
+	checkList := flag.String("checks", "", "Comma-separated checks to run (default: "+strings.Join(scanner.DefaultChecks(), ",")+")")
+	listChecks := flag.Bool("list-checks", false, "List the available checks and exit")
+	reportSchema := flag.Bool("report-schema", false, "Print the JSON Schema of the reports this build writes and exit")
+	suppressionsPath := flag.String("suppressions", "", "YAML file (or http(s) URL read by the worker) of accepted risks")
+	childPerBatch := flag.Bool("child-per-batch", false, "Scan each batch of 100 repos in its own child workflow")
+	activityBatching := flag.Bool("activity-batching", false, "Check 50 repos per activity instead of one, for a much shorter history")
+	concurrency := flag.Int("concurrency", 0, "Keep this many repos in flight, starting the next as soon as one finishes, instead of scanning in batches of 10")
+	workerAffinity := flag.Bool("worker-affinity", false, "Run every repo check on one worker, moving to another if it goes away, so its API budget and token cache cover the whole scan")
+	activeWithin := flag.String("active-within", "", "Only scan repos pushed to within this many days, e.g. 180d")
+	priorityRepos := flag.String("priority-repos", "", "Scan these comma-separated repo names or globs first, e.g. payments-*,acme/auth")
+	priorityTopics := flag.String("priority-topics", "", "Scan repos with any of these comma-separated topics first, e.g. pci,tier-0")
+	includeArchived := flag.Bool("include-archived", false, "Scan archived repos too instead of only listing them in the report")
+	reposFile := flag.String("repos-file", "", "Scan only the owner/name repos listed in this file, one per line (# comments)")
+	reposArg := flag.String("repos", "", "Scan only these comma-separated owner/name repos, or - to read them from stdin")
+	enterprise := flag.String("enterprise", "", "Scan every org of this GitHub Enterprise slug, one child scan per org (token needs read:enterprise); --query and --cancel act on that scan")
+	maxOrgs := flag.Int("max-concurrent-orgs", scanner.DefaultEnterpriseOrgConcurrency, "With --enterprise, how many org scans run at once")
+	var teams stringList
+	flag.Var(&teams, "team", "Scan only the repos of this GitHub team slug (repeatable; token needs read:org)")
+	var topics, excludeTopics stringList
+	flag.Var(&topics, "topic", "Scan only repos with this topic, e.g. tier-1 (repeatable: repos with any of them)")
+	flag.Var(&excludeTopics, "exclude-topic", "Leave out repos with this topic, e.g. deprecated (repeatable)")
+	workflowIDFlag := flag.String("workflow-id", "", "Use this workflow ID instead of deriving it from --org (needed to query or cancel a --unique scan)")
+	idSuffix := flag.String("id-suffix", "", "Append this to the workflow ID, e.g. nightly, so the scan doesn't replace the org's ad-hoc scan")
+	ensure := flag.Bool("ensure", false, "Start the scan unless one is already running under its workflow ID; then attach to it and print its progress instead")
+	unique := flag.Bool("unique", false, "Append the start time to the workflow ID so the scan never replaces another")
+	force := flag.Bool("force", false, "Terminate a scan already running under the workflow ID and start this one instead (needs --reason)")
+	initiatedBy := flag.String("initiated-by", "", "Who is starting the scan, recorded in its report, memo and history (default: the local user)")
+	reason := flag.String("reason", "", "Why the scan is being started, e.g. scheduled, ad-hoc or \"incident INC-1234\"; recorded with --initiated-by")
+	terminateReason := flag.String("terminate", "", "Terminate a running scan with this reason (destructive; needs --yes)")
+	resetFirst := flag.Bool("reset-to-first-workflow-task", false, "Reset a scan to its first workflow task, rerunning it on the current worker code (needs --yes)")
+	resetEvent := flag.Int64("reset-to-event", 0, "Reset a scan to this WorkflowTaskCompleted event ID (needs --yes)")
+	yes := flag.Bool("yes", false, "Confirm --terminate or a reset")
+	rateLimit := flag.Bool("rate-limit", false, "Show the token's GitHub rate limit and whether it covers a scan of --org (no server needed)")
+	promoteBuildIDFlag := flag.String("promote-build-id", "", "Make this worker Build ID the default for new scans; running scans finish on their own build (see the worker's --worker-versioning)")
+	list := flag.Bool("list", false, "List running and recent scans (all orgs unless --org is set)")
+	watchAllFlag := flag.Bool("watch-all", false, "Watch every running scan in one table, refreshed every --watch-interval, until all have ended; exits 1 if any failed or was degraded, 3 if any was cancelled or stopped early")
+	watchInterval := flag.Duration("watch-interval", 10*time.Second, "With --watch-all, how often to refresh")
+	jsonStream := flag.Bool("json-stream", false, "With --watch-all, print one JSON line per scan on each refresh instead of the table")
+	jsonOut := flag.Bool("json", false, "Print the result as JSON on stdout; everything else goes to stderr")
+	format := flag.String("format", "text", "Report format: text, json (same as --json), ocsf (OCSF Compliance Finding events as NDJSON, for a SIEM) or prom (Prometheus gauges; --output saves them instead of the JSON report)")
+	verbose := flag.Bool("verbose", false, "List every non-compliant repo with its failed checks")
+	noColor := flag.Bool("no-color", false, "Never color the report (also off when NO_COLOR is set or stdout is not a terminal)")
+	minCompliance := flag.Float64("min-compliance", 0, "Exit 2 if the scan's compliance rate is below this percentage")
+	diffOld := flag.String("diff", "", "Compare two saved reports, old then new: --diff old.json new.json (no server needed)")
+	history := flag.Bool("history", false, "Show --org's compliance over its last scans from the workers' blob store (SCAN_BLOB_STORE) or --reports-dir (no server needed)")
+	lastN := flag.Int("last", 12, "With --history, how many scans to show (0: all)")
+	reportsDir := flag.String("reports-dir", ".", "With --history and no SCAN_BLOB_STORE, read the security_scan_<org>*.json reports saved here")
+	noPreflight := flag.Bool("no-preflight", false, "Don't check with GitHub that --org exists before starting (for air-gapped setups)")
+	progressEvery := flag.Duration("progress-interval", 0, "Log progress and upsert the ScanStatus search attribute this often, e.g. 1m (needs ScanStatus registered)")
+	progressWebhookURL := flag.String("progress-webhook", "", "POST the scan's progress as JSON to this URL after every batch and when it ends, e.g. for a dashboard")
+	progressWebhookEvery := flag.Int("progress-webhook-every", 0, "With --progress-webhook, post every this many repos instead of every batch")
+	var sinkFlags stringList
+	flag.Var(&sinkFlags, "sink", "Deliver the report to this sink, TYPE[:KEY=VALUE,...], e.g. webhook:url=https://... (repeatable; replaces the worker's Splunk, Jira and Datadog steps; types: "+strings.Join(scanner.ReportSinkTypes(), ", ")+")")
+	maxAPIRequests := flag.Int("max-api-requests", 0, "Stop the scan after this many GitHub/GitLab API requests and report what it scanned (0: no limit)")
+	deadlineFlag := flag.String("deadline", "", "Stop the scan at this time, e.g. 04:00 or 2026-03-01T04:00:00Z, and report what it scanned and what it did not")
+	maxDuration := flag.Duration("max-duration", 0, "Stop the scan after it has run this long, e.g. 2h, and report what it scanned and what it did not")
+	resumePath := flag.String("resume", "", "Continue the scan of this saved report, if it was cancelled or stopped at its deadline: scan only the repos it did not get to and report on both (needs a worker to read its results)")
+	resumeWorkflowID := flag.String("resume-workflow-id", "", "Like --resume, for the latest run of this workflow ID")
+	batchDelay := flag.Duration("batch-delay", 0, "Pause this long between batches of concurrent repo checks, e.g. 5s, to avoid secondary rate limits")
+	stragglerMultiple := flag.Float64("straggler-multiple", 0, "Release repos still running at this multiple (more than 1) of their batch's median scan time, and rescan them once every batch has run")
+	batchJitter := flag.Float64("batch-jitter", 0.2, "With --batch-delay, vary each pause by up to this fraction either way (0-1)")
+	codecServer := flag.String("codec-server", "", "Serve the payload codec on this address, e.g. :8081, so the Web UI can show compressed payloads (no server needed)")
+	codecOrigin := flag.String("codec-cors-origin", "http://localhost:8233", "Web UI origin allowed to call --codec-server")
+	configPath := flag.String(scanner.ConfigFlag, "", "Read unset flags, and environment variables under env:, from this YAML file of flag names to values")
+	printConfig := flag.Bool(scanner.PrintConfigFlag, false, "Print the settings merged from the command line, environment, --config and defaults, with secrets redacted, and exit")
+	// Parse errors exit with exitError; the default would be 2, which here
+	// means a compliance failure.
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(exitError)
+	}
+	// Flags beat the environment, which beats --config (see config.go in
+	// the scanner package).
+	cfg, err := scanner.LoadConfig(flag.CommandLine, *configPath, configOptions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitError)
+	}
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+	reportFormat, err := scanner.ParseReportFormat(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitError)
+	}
+	if *jsonOut {
+		if reportFormat == scanner.FormatOCSF || reportFormat == scanner.FormatProm {
+			fmt.Fprintf(os.Stderr, "Error: --json and --format %s are mutually exclusive\n", reportFormat)
+			os.Exit(exitError)
+		}
+		reportFormat = scanner.FormatJSON
+	}
+	if (*watchAllFlag || *jsonStream) && reportFormat != scanner.FormatText {
+		fmt.Fprintln(os.Stderr, "Error: --watch-all prints a table; use --json-stream for machine-readable output")
+		os.Exit(exitError)
+	}
+	if *tui && reportFormat != scanner.FormatText {
+		fmt.Fprintln(os.Stderr, "Error: --tui is a terminal view; use --query or --results for machine-readable output")
+		os.Exit(exitError)
+	}
+	o := newOutput(reportFormat == scanner.FormatJSON, renderOptions(os.Stdout, *verbose, *noColor))
+	if reportFormat == scanner.FormatOCSF || reportFormat == scanner.FormatProm {
+		o = o.withFormat(reportFormat)
+	}
+
+	if *listChecks {
+		printChecks()
+		return
+	}
+	if *reportSchema {
+		schema, err := scanner.ReportJSONSchema()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: building the report schema:", err)
+			os.Exit(exitError)
+		}
+		fmt.Println(string(schema))
+		return
+	}
+
+	if *codecServer != "" {
+		os.Exit(serveCodec(*codecServer, *codecOrigin))
+	}
+
+	if *diffOld != "" {
+		args := flag.Args()
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: --diff needs two reports: --diff old.json new.json")
+			os.Exit(exitError)
+		}
+		// The new report is positional, so flags after it (--json) are
+		// parsed here.
+		if err := flag.CommandLine.Parse(args[1:]); err != nil || flag.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, "Error: --diff needs exactly two reports: --diff old.json new.json")
+			os.Exit(exitError)
+		}
+		os.Exit(diffReports(newOutput(*jsonOut, o.render), *diffOld, args[0]))
+	}
+
+	if *history {
+		if *org == "" || *lastN < 0 {
+			fmt.Fprintln(os.Stderr, "Error: --history needs --org, and --last must not be negative")
+			os.Exit(exitError)
+		}
+		h, err := trendHistory(*provider, *reportsDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: opening the scan history: %v\n", err)
+			os.Exit(exitError)
+		}
+		os.Exit(showTrend(o, h, *org, *lastN))
+	}
+
+	var checks []string
+	if *checkList != "" {
+		for _, name := range strings.Split(*checkList, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				checks = append(checks, name)
+			}
+		}
+		if err := scanner.ValidateChecks(checks); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	activeDays := 0
+	if *activeWithin != "" {
+		var err error
+		if activeDays, err = parseDays(*activeWithin); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --active-within: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	if *batchDelay < 0 || *batchJitter < 0 || *batchJitter > 1 {
+		fmt.Fprintln(os.Stderr, "Error: --batch-delay must not be negative and --batch-jitter must be between 0 and 1")
+		os.Exit(exitError)
+	}
+	if *maxAPIRequests < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --max-api-requests must not be negative")
+		os.Exit(exitError)
+	}
+	if *resultsOffset < 0 || *resultsLimit < 0 || (*resultsOffset > 0 && *resultsLimit == 0) {
+		fmt.Fprintln(os.Stderr, "Error: --results-offset and --results-limit must not be negative, and --results-offset needs --results-limit")
+		os.Exit(exitError)
+	}
+	var deadline *time.Time
+	if *deadlineFlag != "" {
+		d, err := parseDeadline(*deadlineFlag, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --deadline: %v\n", err)
+			os.Exit(exitError)
+		}
+		deadline = &d
+	}
+	if *maxDuration < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --max-duration must not be negative")
+		os.Exit(exitError)
+	}
+	// Rounded up so a sub-second duration still sets a deadline.
+	maxDurationSeconds := int((*maxDuration + time.Second - 1) / time.Second)
+	var stragglers *scanner.StragglerTimeout
+	if *stragglerMultiple != 0 {
+		if *stragglerMultiple <= 1 || *activityBatching {
+			fmt.Fprintln(os.Stderr, "Error: --straggler-multiple must be more than 1, and does not apply with --activity-batching")
+			os.Exit(exitError)
+		}
+		stragglers = &scanner.StragglerTimeout{Multiple: *stragglerMultiple}
+	}
+	if *concurrency < 0 || (*concurrency > 0 && (*childPerBatch || *activityBatching || *batchDelay > 0 || stragglers != nil)) {
+		fmt.Fprintln(os.Stderr, "Error: --concurrency must not be negative, and replaces --child-per-batch, --activity-batching, --batch-delay and --straggler-multiple")
+		os.Exit(exitError)
+	}
+	if *workerAffinity && *childPerBatch {
+		fmt.Fprintln(os.Stderr, "Error: --worker-affinity does not combine with --child-per-batch")
+		os.Exit(exitError)
+	}
+	if *initiatedBy == "" {
+		*initiatedBy = localUser()
+	}
+	*initiatedBy, *reason = scanner.CleanScanLabel(*initiatedBy), scanner.CleanScanLabel(*reason)
+	if err := scanner.ValidateInitiator(*initiatedBy, *reason); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if *force && (*reason == "" || *ensure) {
+		fmt.Fprintln(os.Stderr, "Error: --force needs --reason, and does not combine with --ensure")
+		os.Exit(exitError)
+	}
+	if *noSave && (*outputPath != "" || *overwrite) {
+		fmt.Fprintln(os.Stderr, "Error: --no-save does not combine with --output or --overwrite")
+		os.Exit(exitError)
+	}
+	file := reportFile{path: *outputPath, noSave: *noSave, overwrite: *overwrite}
+	if reportFormat == scanner.FormatProm {
+		file.format = scanner.FormatProm
+	}
+	var progressWebhook *scanner.ProgressWebhook
+	if *progressWebhookURL != "" {
+		progressWebhook = &scanner.ProgressWebhook{URL: *progressWebhookURL, EveryRepos: *progressWebhookEvery}
+	} else if *progressWebhookEvery != 0 {
+		fmt.Fprintln(os.Stderr, "Error: --progress-webhook-every needs --progress-webhook")
+		os.Exit(exitError)
+	}
+	var sinks []scanner.ReportSinkSpec
+	for _, s := range sinkFlags {
+		spec, err := scanner.ParseReportSinkSpec(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --sink: %v\n", err)
+			os.Exit(exitError)
+		}
+		sinks = append(sinks, spec)
+	}
+	if err := scanner.ValidateReportSinks(sinks); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --sink: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	repos, err := readRepos(*reposFile, *reposArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if len(repos) > 0 {
+		owner, _, _ := strings.Cut(repos[0], "/")
+		if *org == "" {
+			*org = owner
+		} else if !strings.EqualFold(owner, *org) {
+			fmt.Fprintf(os.Stderr, "Error: the repo list is for %s, not --org %s\n", owner, *org)
+			os.Exit(exitError)
+		}
+	}
+
+	for _, slug := range teams {
+		if err := scanner.ValidateTeamSlug(slug); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --team: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if len(teams) > 0 && len(repos) > 0 {
+		fmt.Fprintln(os.Stderr, "Error: use only one of --team and --repos/--repos-file")
+		os.Exit(exitError)
+	}
+	if (len(topics) > 0 || len(excludeTopics) > 0) && len(repos) > 0 {
+		fmt.Fprintln(os.Stderr, "Error: --topic and --exclude-topic filter the org's repo list; they do not apply to --repos/--repos-file")
+		os.Exit(exitError)
+	}
+
+	if *list {
+		c := dial()
+		defer c.Close()
+		doList(c, o, *org)
+		return
+	}
+
+	if *watchAllFlag || *jsonStream {
+		if !*watchAllFlag || *watchInterval <= 0 {
+			fmt.Fprintln(os.Stderr, "Error: --json-stream needs --watch-all, and --watch-interval must be positive")
+			os.Exit(exitError)
+		}
+		c := dial()
+		defer c.Close()
+		os.Exit(doWatchAll(c, *watchInterval, *jsonStream))
+	}
+
+	if *promoteBuildIDFlag != "" {
+		c := dial()
+		defer c.Close()
+		ack, err := promoteBuildID(context.Background(), c, taskQueue, *promoteBuildIDFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		o.promoted(ack)
+		return
+	}
+
+	if *rateLimit {
+		if *token == "" {
+			*token = os.Getenv("GITHUB_TOKEN")
+		}
+		estimate := scanner.ScanInput{Checks: checks, IncludeAccessAudit: *accessAudit, Concurrency: *concurrency}
+		if *batchDelay > 0 {
+			estimate.BatchDelay = &scanner.BatchDelay{Seconds: batchDelay.Seconds(), Jitter: *batchJitter}
+		}
+		os.Exit(doRateLimit(o, *org, *token, repos, estimate))
+	}
+
+	if *enterprise != "" {
+		if err := scanner.ValidateEnterpriseSlug(*enterprise); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --enterprise: %v\n", err)
+			os.Exit(exitError)
+		}
+		if *org != "" || len(repos) > 0 || len(teams) > 0 || *provider != scanner.ProviderGitHub || *resumePath != "" || *resumeWorkflowID != "" {
+			fmt.Fprintln(os.Stderr, "Error: --enterprise scans every org of the enterprise; it cannot be combined with --org, --repos, --team, --provider or --resume")
+			os.Exit(exitError)
+		}
+		if *maxOrgs < 1 {
+			fmt.Fprintln(os.Stderr, "Error: --max-concurrent-orgs must be at least 1")
+			os.Exit(exitError)
+		}
+		if *tui {
+			fmt.Fprintln(os.Stderr, "Error: --tui watches one org's scan; pass its --workflow-id instead of --enterprise")
+			os.Exit(exitError)
+		}
+		if reportFormat == scanner.FormatProm {
+			fmt.Fprintln(os.Stderr, "Error: --format prom does not apply to --enterprise; scrape the worker's /metrics for each org's numbers")
+			os.Exit(exitError)
+		}
+		if *token == "" {
+			*token = os.Getenv("GITHUB_TOKEN")
+		}
+		in := scanner.EnterpriseScanInput{
+			Enterprise:        *enterprise,
+			MaxConcurrentOrgs: *maxOrgs,
+			Scan: scanner.ScanInput{
+				Checks:              checks,
+				IncludeAccessAudit:  *accessAudit,
+				IncludeEvidence:     *evidence,
+				DeployKeyMaxAgeDays: *keyMaxAge,
+				ActiveWithinDays:    activeDays,
+				IncludeArchived:     *includeArchived,
+				ChildPerBatch:       *childPerBatch,
+				ActivityBatching:    *activityBatching,
+				Concurrency:         *concurrency,
+				WorkerAffinity:      *workerAffinity,
+				InitiatedBy:         *initiatedBy,
+				Reason:              *reason,
+				MaxAPIRequests:      *maxAPIRequests,
+				Deadline:            deadline,
+				MaxDurationSeconds:  maxDurationSeconds,
+				PriorityRepos:       splitList(*priorityRepos),
+				PriorityTopics:      splitList(*priorityTopics),
+				IncludeTopics:       topics,
+				ExcludeTopics:       excludeTopics,
+				ProgressWebhook:     progressWebhook,
+				Sinks:               sinks,
+				StragglerTimeout:    stragglers,
+			},
+		}
+		if *token != "" {
+			in.Token = token
+		}
+		os.Exit(runEnterprise(o, in, enterpriseCommand{
+			query:         *query,
+			cancelReason:  *cancelReason,
+			noPreflight:   *noPreflight,
+			noWait:        *noWait,
+			waitTimeout:   *waitTimeout,
+			minCompliance: *minCompliance,
+			file:          file,
+		}))
+	}
 
+	if *org == "" {
+		p, idOrg := scanner.WorkflowIDProvider(*workflowIDFlag)
+		*org = idOrg
+		if idOrg != "" {
+			*provider = p
+		}
+	}
 	if *org == "" {
 		fmt.Fprintln(os.Stderr, "Error: --org is required")
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitError)
+	}
+	gitlab := *provider == scanner.ProviderGitLab
+	validateOrg := scanner.ValidateOrgName
+	if gitlab {
+		validateOrg = scanner.ValidateGitLabGroup
+	}
+	if err := validateOrg(*org); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if err := scanner.ValidateProvider(*provider, checks); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if gitlab && len(teams) > 0 {
+		fmt.Fprintln(os.Stderr, "Error: --team is only supported on GitHub")
+		os.Exit(exitError)
 	}
 
-	if *token == "" {
+	switch {
+	case *token != "", *credentialRef != "":
+	case gitlab:
+		*token = os.Getenv("GITLAB_TOKEN")
+		if *token == "" {
+			fmt.Fprintln(o.info, "Note: No GitLab token. Scanning public projects only. Set GITLAB_TOKEN for private ones.")
+		}
+	default:
 		*token = os.Getenv("GITHUB_TOKEN")
-	}
-	if *token == "" {
-		fmt.Println("Note: No GitHub token. Scanning public repos only (60 req/hr). Set GITHUB_TOKEN for higher limits.")
+		if *token == "" {
+			fmt.Fprintln(o.info, "Note: No GitHub token. The worker's --github-token-source is used if it has one; otherwise public repos only (60 req/hr). Set GITHUB_TOKEN for higher limits.")
+		}
 	}
 
-	c, err := client.Dial(client.Options{HostPort: client.DefaultHostPort})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create Temporal client: %v\n", err)
-		os.Exit(1)
+	// A repo list or team selection gets its own ID so scans of different
+	// lists, or of a list and the whole org, don't replace each other.
+	workflowID := *workflowIDFlag
+	if workflowID == "" {
+		opts := []scanner.WorkflowIDOption{scanner.WithProvider(*provider), scanner.WithRepoList(repos), scanner.WithTeams(teams)}
+		switch {
+		case *unique && *idSuffix != "":
+			fmt.Fprintln(os.Stderr, "Error: use only one of --unique and --id-suffix")
+			os.Exit(exitError)
+		case *unique:
+			if *query || *describe || *tui || *results || *attach || *cancelReason != "" || *exportPath != "" || *terminateReason != "" || *resetFirst || *resetEvent != 0 {
+				fmt.Fprintln(os.Stderr, "Error: a --unique scan's ID can't be derived again; pass its --workflow-id")
+				os.Exit(exitError)
+			}
+			opts = append(opts, scanner.WithStartTime(time.Now()))
+		case *idSuffix != "":
+			opts = append(opts, scanner.WithSuffix(*idSuffix))
+		}
+		workflowID = scanner.ScanWorkflowID(*org, opts...)
 	}
-	defer c.Close()
 
-	workflowID := "security-scan-" + *org
+	c := dial()
+	defer c.Close()
 
 	if *query {
-		doQuery(c, workflowID, *org)
+		doQuery(c, o, workflowID, *org)
+		return
+	}
+	if *describe {
+		d, err := describeScan(context.Background(), c, workflowID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Describe failed: %v\n", err)
+			os.Exit(exitError)
+		}
+		o.described(d)
+		return
+	}
+	if *tui {
+		os.Exit(doTUI(c, workflowID, *org, *noColor))
+	}
+	if *results {
+		doResults(c, o, workflowID, *org, *resultsOffset, *resultsLimit)
 		return
 	}
 	if *cancelReason != "" {
-		doCancel(c, workflowID, *cancelReason)
+		doCancel(c, o, workflowID, *cancelReason)
+		return
+	}
+	if *exportPath != "" {
+		doExportHistory(c, o, workflowID, *exportPath, *timeline)
+		return
+	}
+	if *terminateReason != "" {
+		doTerminate(c, o, workflowID, *terminateReason, *yes)
 		return
 	}
+	if *resetFirst || *resetEvent != 0 {
+		doReset(c, o, workflowID, *resetEvent, *yes)
+		return
+	}
+	// Refuse before starting or waiting for a scan, not after it ran.
+	if !*noWait && !*ensure {
+		if err := file.check(reportPath(*org)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if *attach {
+		run := c.GetWorkflow(context.Background(), workflowID, *runIDFlag)
+		fmt.Fprintf(o.info, "Attached to %s; waiting for its report...\n\n", workflowID)
+		os.Exit(awaitReport(c, o, run, *org, file, *waitTimeout, *verbose, *noColor, *minCompliance))
+	}
 
 	// Start workflow
-	input := scanner.ScanInput{Org: *org}
-	if *token != "" {
+	input := scanner.ScanInput{
+		Org:                 *org,
+		Checks:              checks,
+		IncludeAccessAudit:  *accessAudit,
+		IncludeEvidence:     *evidence,
+		DeployKeyMaxAgeDays: *keyMaxAge,
+		ActiveWithinDays:    activeDays,
+		IncludeArchived:     *includeArchived,
+		ChildPerBatch:       *childPerBatch,
+		ActivityBatching:    *activityBatching,
+		Concurrency:         *concurrency,
+		WorkerAffinity:      *workerAffinity,
+		InitiatedBy:         *initiatedBy,
+		Reason:              *reason,
+		Repos:               repos,
+		Teams:               teams,
+		MaxAPIRequests:      *maxAPIRequests,
+		Deadline:            deadline,
+		MaxDurationSeconds:  maxDurationSeconds,
+		PriorityRepos:       splitList(*priorityRepos),
+		PriorityTopics:      splitList(*priorityTopics),
+		IncludeTopics:       topics,
+		ExcludeTopics:       excludeTopics,
+		ProgressWebhook:     progressWebhook,
+		Sinks:               sinks,
+		StragglerTimeout:    stragglers,
+	}
+	if *resumePath != "" || *resumeWorkflowID != "" {
+		if input.ResumeFrom, err = loadResumeState(*resumePath, *resumeWorkflowID, *org); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: resuming: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Fprintf(o.info, "Resuming: %d results carried over, %d repos left to scan\n",
+			len(input.ResumeFrom.Results)+countResults(input.ResumeFrom.Refs), len(input.ResumeFrom.UnscannedRepos))
+	}
+	if gitlab {
+		input.Provider = scanner.ProviderGitLab
+		input.GitLab = &scanner.GitLabOptions{IncludeSubgroups: *gitlabSubgroups}
+	}
+	if *batchDelay > 0 {
+		input.BatchDelay = &scanner.BatchDelay{Seconds: batchDelay.Seconds(), Jitter: *batchJitter}
+	}
+	if *progressEvery > 0 {
+		// Rounded up so a sub-second interval still enables the loop.
+		input.ProgressIntervalSeconds = int((*progressEvery + time.Second - 1) / time.Second)
+	}
+	if strings.HasPrefix(*suppressionsPath, "http://") || strings.HasPrefix(*suppressionsPath, "https://") {
+		input.SuppressionsSource = *suppressionsPath
+	} else if *suppressionsPath != "" {
+		data, err := os.ReadFile(*suppressionsPath)
+		if err == nil {
+			input.Suppressions, err = scanner.ParseSuppressions(data)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --suppressions: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	switch {
+	case *credentialType != "" || *credentialRef != "":
+		input.Credential = &scanner.Credential{Type: scanner.CredentialType(*credentialType), Reference: *credentialRef}
+		if input.Credential.Type == "" {
+			input.Credential.Type = scanner.CredentialPAT
+		}
+		if *credentialRef == "" && input.Credential.Type != scanner.CredentialNone {
+			input.Credential.Value = *token
+		}
+	case *token != "":
 		input.Token = token
 	}
+	// The pre-flight checks authenticate with an inline token only; a
+	// reference is the worker's to resolve.
+	cred := input.Credential
+	if cred == nil {
+		cred = scanner.PATCredential(input.Token)
+	}
+
+	// The workflow checks its input again once org config has filled it
+	// in; what is wrong already need not wait for a worker.
+	if err := input.Validate(); err != nil {
+		printError(os.Stderr, "Error", err)
+		os.Exit(exitError)
+	}
 
-	fmt.Printf("Starting security scan for '%s'...\n", *org)
-	fmt.Printf("  Workflow ID: %s\n", workflowID)
-	fmt.Printf("  Task Queue:  %s\n", taskQueue)
-	fmt.Printf("  Timeout:     %s\n\n", executionTimeout)
+	// The pre-flight checks use GitHub's API. A GitLab scan of a missing
+	// group fails on its first FetchOrgRepos attempt instead.
+	if !*noPreflight && !gitlab {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		err := preflightOrg(ctx, &http.Client{}, githubAPIURL(), *org, cred)
+		cancel()
+		var notFound *orgNotFoundError
+		switch {
+		case errors.As(err, &notFound), errors.Is(err, errInvalidToken):
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: could not check that '%s' exists (%v); starting anyway\n", *org, err)
+		case cred.Authorization() != "":
+			selected := checks
+			if *accessAudit {
+				if len(selected) == 0 {
+					selected = scanner.DefaultChecks()
+				}
+				selected = append(selected, scanner.CheckAccessAudit)
+			}
+			if err := preflightToken(o, githubAPIURL(), *org, cred, selected); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitError)
+			}
+		}
+	}
+
+	fmt.Fprintf(o.info, "Starting security scan for '%s'...\n", *org)
+	fmt.Fprintf(o.info, "  Workflow ID: %s\n", workflowID)
+	fmt.Fprintf(o.info, "  Task Queue:  %s\n", taskQueue)
+	fmt.Fprintf(o.info, "  Timeout:     %s\n\n", executionTimeout)
 
 	options := client.StartWorkflowOptions{
-		ID:                         workflowID,
-		TaskQueue:                  taskQueue,
-		WorkflowExecutionTimeout:   executionTimeout,
-		WorkflowIDReusePolicy:      enums.WORKFLOW_ID_REUSE_POLICY_TERMINATE_EXISTING,
+		ID:                       workflowID,
+		TaskQueue:                taskQueue,
+		WorkflowExecutionTimeout: executionTimeout,
+		Memo:                     scanner.ScanMemo(input),
 	}
 
-	we, err := c.ExecuteWorkflow(context.Background(), options, scanner.SecurityScanWorkflow, input)
+	if *ensure {
+		ack, err := ensureScan(context.Background(), c, options, input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		o.ensured(ack, time.Now())
+		return
+	}
+
+	we, err := scanner.StartScan(context.Background(), c, options, input, *force)
+	var running *scanner.ScanRunningError
+	if errors.As(err, &running) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to start workflow: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	if *noWait {
-		fmt.Println("Workflow started.")
-		fmt.Printf("  Query:  go run ./go_comparison/starter --org %s --query\n", *org)
-		fmt.Printf("  Cancel: go run ./go_comparison/starter --org %s --cancel \"reason\"\n", *org)
-		fmt.Printf("  UI:     http://localhost:8233/namespaces/default/workflows/%s\n", workflowID)
+		o.started(startedAck{WorkflowID: workflowID, RunID: we.GetRunID(), TaskQueue: taskQueue})
 		return
 	}
 
-	fmt.Println("Scanning... (use --query in another terminal to check progress)\n")
+	fmt.Fprint(o.info, "Scanning... (use --query in another terminal to check progress)\n\n")
+	os.Exit(awaitReport(c, o, we, *org, file, *waitTimeout, *verbose, *noColor, *minCompliance))
+}
 
+// awaitReport waits for run's report, at most waitTimeout when it is not
+// 0, prints it, saves it to file, and returns the exit code.
+func awaitReport(c client.Client, o output, run client.WorkflowRun, org string, file reportFile, waitTimeout time.Duration, verbose, noColor bool, minCompliance float64) int {
 	var result map[string]interface{}
-	err = we.Get(context.Background(), &result)
+	err := waitForScan(context.Background(), c, run, waitTimeout, waitCheckInterval, &result)
+	if code, ok := waitStopped(os.Stderr, run, err, waitTimeout); ok {
+		return code
+	}
+	if code, ok := scanDegraded(os.Stderr, err, renderOptions(os.Stderr, verbose, noColor)); ok {
+		return code
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Workflow failed: %v\n", err)
-		os.Exit(1)
+		printError(os.Stderr, "Workflow failed", err)
+		return exitError
 	}
 
-	printReport(result)
-	outPath := "security_scan_" + *org + ".json"
-	b, _ := json.MarshalIndent(result, "", "  ")
-	_ = os.WriteFile(outPath, b, 0644)
-	fmt.Printf("\nReport saved to %s\n", outPath)
+	o.report(result)
+	if !file.save(o.info, reportPath(org), result) {
+		return exitError
+	}
+	return reportExitCode(result, minCompliance)
+}
+
+func dial() client.Client {
+	c, err := client.Dial(client.Options{HostPort: client.DefaultHostPort, DataConverter: dataConverter()})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create Temporal client: %v\n", err)
+		os.Exit(exitError)
+	}
+	return c
 }
 
-func doQuery(c client.Client, workflowID, org string) {
+func doQuery(c client.Client, o output, workflowID, org string) {
 	ctx := context.Background()
-	handle := c.GetWorkflowHandle(workflowID)
 
-	var progress scanner.ScanProgress
-	err := handle.Query(ctx, "progress", &progress)
+	resp, err := c.QueryWorkflow(ctx, workflowID, "", "progress")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Query failed: %v\n", err)
 		fmt.Fprintf(os.Stderr, "Is a scan running? Start one with: go run ./go_comparison/starter --org %s\n", org)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
-
-	fmt.Printf("Security Scan Progress: %s\n", org)
-	fmt.Printf("  Status:       %s\n", progress.Status)
-	fmt.Printf("  Progress:     %d/%d repos (%.1f%%)\n",
-		progress.ScannedRepos, progress.TotalRepos, progress.PercentComplete())
-	fmt.Printf("  Compliant:    %d\n", progress.CompliantRepos)
-	fmt.Printf("  Non-compliant: %d\n", progress.NonCompliantRepos)
-	fmt.Printf("  Errors:       %d\n", progress.Errors)
+	var progress scanner.ScanProgress
+	if err := resp.Get(&progress); err != nil {
+		fmt.Fprintf(os.Stderr, "Decoding progress failed: %v\n", err)
+		os.Exit(exitError)
+	}
+	o.progress(org, progress)
 }
 
-func doCancel(c client.Client, workflowID, reason string) {
+func doCancel(c client.Client, o output, workflowID, reason string) {
 	ctx := context.Background()
-	handle := c.GetWorkflowHandle(workflowID)
-	fmt.Printf("Sending cancel signal to workflow '%s'...\n", workflowID)
-	fmt.Printf("  Reason: %s\n", reason)
-	err := handle.Signal(ctx, "cancel_scan", reason)
+	fmt.Fprintf(o.info, "Sending cancel signal to workflow '%s'...\n", workflowID)
+	fmt.Fprintf(o.info, "  Reason: %s\n", reason)
+	err := c.SignalWorkflow(ctx, workflowID, "", "cancel_scan", reason)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Signal failed: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
+	}
+	o.cancelSent(workflowID, reason)
+}
+
+// describeTarget prints the run a destructive command is about to act on,
+// so the wrong scan is caught before --yes is added, and returns it.
+func describeTarget(c client.Client, o output, workflowID string) *workflowpb.WorkflowExecutionInfo {
+	resp, err := c.DescribeWorkflowExecution(context.Background(), workflowID, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Describe failed: %v\n", err)
+		os.Exit(exitError)
+	}
+	info := resp.GetWorkflowExecutionInfo()
+	fmt.Fprintf(o.info, "Workflow: %s\n", workflowID)
+	fmt.Fprintf(o.info, "  Run ID:  %s\n", info.GetExecution().GetRunId())
+	fmt.Fprintf(o.info, "  Status:  %s\n", info.GetStatus())
+	fmt.Fprintf(o.info, "  Started: %s\n\n", info.GetStartTime().AsTime().Local().Format(time.RFC3339))
+	return info
+}
+
+// confirm exits unless --yes was given for a destructive command.
+func confirm(yes bool, action string) {
+	if !yes {
+		fmt.Fprintf(os.Stderr, "Refusing to %s without --yes. Check the run above, then re-run with --yes.\n", action)
+		os.Exit(exitError)
+	}
+}
+
+// doTerminate terminates the current run. Unlike --cancel it does not wait
+// for the workflow to cooperate, so no partial report is produced.
+func doTerminate(c client.Client, o output, workflowID, reason string, yes bool) {
+	info := describeTarget(c, o, workflowID)
+	if info.GetStatus() != enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		fmt.Fprintf(os.Stderr, "Error: the scan is %s, not running\n", info.GetStatus())
+		os.Exit(exitError)
+	}
+	confirm(yes, "terminate")
+
+	// Pin the run ID so a scan started since the describe is not hit.
+	runID := info.GetExecution().GetRunId()
+	if err := c.TerminateWorkflow(context.Background(), workflowID, runID, reason); err != nil {
+		fmt.Fprintf(os.Stderr, "Terminate failed: %v\n", err)
+		os.Exit(exitError)
+	}
+	o.terminated(terminateAck{WorkflowID: workflowID, RunID: runID, Reason: reason})
+}
+
+// doReset resets the current run to eventID, or to its first workflow task
+// when eventID is 0, starting a new run that replays history up to there.
+func doReset(c client.Client, o output, workflowID string, eventID int64, yes bool) {
+	ctx := context.Background()
+	info := describeTarget(c, o, workflowID)
+	runID := info.GetExecution().GetRunId()
+
+	if eventID == 0 {
+		iter := c.GetWorkflowHistory(ctx, workflowID, runID, false, enums.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+		for iter.HasNext() && eventID == 0 {
+			event, err := iter.Next()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Reading history failed: %v\n", err)
+				os.Exit(exitError)
+			}
+			if event.GetEventType() == enums.EVENT_TYPE_WORKFLOW_TASK_COMPLETED {
+				eventID = event.GetEventId()
+			}
+		}
+		if eventID == 0 {
+			fmt.Fprintln(os.Stderr, "Error: the scan has no completed workflow task to reset to")
+			os.Exit(exitError)
+		}
 	}
-	fmt.Println("\nSignal sent. The scan will stop after the current batch and produce a partial report.")
+	confirm(yes, "reset")
+
+	reason := fmt.Sprintf("reset to event %d via starter", eventID)
+	resp, err := c.ResetWorkflowExecution(ctx, &workflowservice.ResetWorkflowExecutionRequest{
+		Namespace:                 client.DefaultNamespace,
+		WorkflowExecution:         &commonpb.WorkflowExecution{WorkflowId: workflowID, RunId: runID},
+		Reason:                    reason,
+		WorkflowTaskFinishEventId: eventID,
+		RequestId:                 uuid.NewString(),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Reset failed: %v\n", err)
+		os.Exit(exitError)
+	}
+	o.reset(resetAck{WorkflowID: workflowID, OldRunID: runID, NewRunID: resp.GetRunId(), EventID: eventID})
+}
+
+// scanListing is one row of --list output.
+type scanListing struct {
+	WorkflowID string     `json:"workflow_id"`
+	RunID      string     `json:"run_id"`
+	Org        string     `json:"org"`
+	Status     string     `json:"status"`
+	StartTime  time.Time  `json:"start_time"`
+	CloseTime  *time.Time `json:"close_time,omitempty"`
+	// InitiatedBy is from the scan's memo; empty for scans before it.
+	InitiatedBy string                `json:"initiated_by,omitempty"`
+	Progress    *scanner.ScanProgress `json:"progress,omitempty"`
 }
 
-func printReport(result map[string]interface{}) {
-	fmt.Println()
-	fmt.Println("============================================================")
-	if cancelled, _ := result["cancelled"].(bool); cancelled {
-		fmt.Printf("  Security Scan CANCELLED: %v\n", result["org"])
-		fmt.Printf("  Reason: %v\n", result["cancel_reason"])
-		fmt.Printf("  Partial results (%v of %v repos scanned)\n",
-			result["repos_scanned_before_cancel"], result["total_repos"])
+// doList lists SecurityScanWorkflow executions, newest first, with live
+// progress for the running ones. Namespaces without advanced visibility
+// reject the list query, so it falls back to open executions only.
+func doList(c client.Client, o output, org string) {
+	ctx := context.Background()
+	query := "WorkflowType = 'SecurityScanWorkflow'"
+	if org != "" {
+		id := scanner.ScanWorkflowID(org)
+		query += fmt.Sprintf(" AND (WorkflowId = '%s' OR WorkflowId STARTS_WITH '%s/')", id, id)
+	}
+
+	var executions []*workflowpb.WorkflowExecutionInfo
+	resp, err := c.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+		Namespace: client.DefaultNamespace,
+		PageSize:  listLimit,
+		Query:     query,
+	})
+	if err == nil {
+		executions = resp.GetExecutions()
 	} else {
-		fmt.Printf("  Security Scan Complete: %v\n", result["org"])
+		fmt.Fprintf(os.Stderr, "Listing scans failed (%v); showing running scans only.\n", err)
+		open, err := c.ListOpenWorkflow(ctx, &workflowservice.ListOpenWorkflowExecutionsRequest{
+			Namespace:       client.DefaultNamespace,
+			MaximumPageSize: listLimit,
+			Filters: &workflowservice.ListOpenWorkflowExecutionsRequest_TypeFilter{
+				TypeFilter: &filterpb.WorkflowTypeFilter{Name: "SecurityScanWorkflow"},
+			},
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Listing running scans failed: %v\n", err)
+			os.Exit(exitError)
+		}
+		for _, e := range open.GetExecutions() {
+			if org == "" || scanner.WorkflowIDOrg(e.GetExecution().GetWorkflowId()) == org {
+				executions = append(executions, e)
+			}
+		}
+	}
+
+	listings := make([]scanListing, 0, len(executions))
+	for _, e := range executions {
+		l := scanListing{
+			WorkflowID: e.GetExecution().GetWorkflowId(),
+			RunID:      e.GetExecution().GetRunId(),
+			Org:        scanner.WorkflowIDOrg(e.GetExecution().GetWorkflowId()),
+			Status:     e.GetStatus().String(),
+			StartTime:  e.GetStartTime().AsTime(),
+		}
+		if e.GetCloseTime() != nil {
+			t := e.GetCloseTime().AsTime()
+			l.CloseTime = &t
+		}
+		if p := e.GetMemo().GetFields()["initiated_by"]; p != nil {
+			_ = dataConverter().FromPayload(p, &l.InitiatedBy)
+		}
+		if e.GetStatus() == enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+			// Best effort: a scan with no worker polling can't answer.
+			if val, err := c.QueryWorkflow(ctx, l.WorkflowID, l.RunID, "progress"); err == nil {
+				var p scanner.ScanProgress
+				if val.Get(&p) == nil {
+					l.Progress = &p
+				}
+			}
+		}
+		listings = append(listings, l)
+	}
+
+	o.list(listings)
+}
+
+// doRateLimit reports the token's GitHub budget and, given an org or repo
+// list, whether it covers a scan with the selected checks.
+func doRateLimit(o output, org, token string, repos []string, estimate scanner.ScanInput) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	var tokenPtr *string
+	if token != "" {
+		tokenPtr = &token
+	}
+
+	a := &scanner.Activities{HTTPClient: httpClient, BaseURL: githubAPIURL()}
+	status, err := a.GetRateLimit(ctx, scanner.PATCredential(tokenPtr))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
 	}
-	fmt.Println("============================================================")
-	fmt.Printf("  Total repositories:   %v\n", result["total_repos"])
-	fmt.Printf("  Fully compliant:      %v\n", result["fully_compliant"])
-	fmt.Printf("  Compliance rate:      %v\n", result["compliance_rate"])
-	fmt.Printf("  Secret scanning:      %v/%v\n", result["secret_scanning_enabled"], result["total_repos"])
-	fmt.Printf("  Dependabot alerts:    %v/%v\n", result["dependabot_enabled"], result["total_repos"])
-	fmt.Printf("  Code scanning (GHAS): %v/%v\n", result["code_scanning_enabled"], result["total_repos"])
-	if errs, ok := result["errors"].(float64); ok && errs > 0 {
-		fmt.Printf("  Errors:               %.0f\n", errs)
+	check := rateLimitCheck{RateLimitStatus: *status, Authenticated: tokenPtr != nil, Org: org}
+
+	switch {
+	case len(repos) > 0:
+		check.Repos = len(repos)
+		check.EstimatedCalls = len(repos) * estimate.APICallsPerRepo()
+	case org != "":
+		n, err := orgRepoCount(ctx, httpClient, org, tokenPtr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: counting repos in %s: %v\n", org, err)
+			return exitError
+		}
+		// Listing the org costs one call per page of 100.
+		check.Repos = n
+		check.EstimatedCalls = n*estimate.APICallsPerRepo() + (n+99)/100
+	}
+	if check.Repos > 0 {
+		check.WorstCaseSeconds = int(estimate.WorstCaseDuration(check.Repos).Seconds())
+	}
+	o.rateLimit(check)
+	return exitOK
+}
+
+// orgRepoCount returns the number of repos in org that the token can see.
+// Private repos are only counted for org members.
+func orgRepoCount(ctx context.Context, httpClient *http.Client, org string, token *string) (int, error) {
+	resp, err := githubGet(ctx, httpClient, githubAPIURL(), "/orgs/"+org, scanner.PATCredential(token))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("GitHub returned %s", resp.Status)
+	}
+	var info struct {
+		PublicRepos       int `json:"public_repos"`
+		TotalPrivateRepos int `json:"total_private_repos"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return 0, err
+	}
+	return info.PublicRepos + info.TotalPrivateRepos, nil
+}
+
+// stringList is a flag that can be given more than once.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, strings.TrimSpace(v))
+	return nil
+}
+
+// readRepos returns the explicit repo list from --repos-file or --repos, or
+// nil for a whole-org scan. All repos must share one owner.
+func readRepos(file, arg string) ([]string, error) {
+	var data []byte
+	var err error
+	switch {
+	case file != "" && arg != "":
+		return nil, errors.New("use only one of --repos-file and --repos")
+	case file != "":
+		data, err = os.ReadFile(file)
+	case arg == "-":
+		data, err = io.ReadAll(os.Stdin)
+	case arg != "":
+		data = []byte(strings.ReplaceAll(arg, ",", "\n"))
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	repos, dups, err := scanner.ParseRepoList(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dups {
+		fmt.Fprintf(os.Stderr, "Warning: %s is listed more than once; scanning it once\n", d)
+	}
+	if len(repos) == 0 {
+		return nil, errors.New("the repo list is empty")
+	}
+	owner, _, _ := strings.Cut(repos[0], "/")
+	for _, r := range repos[1:] {
+		if o, _, _ := strings.Cut(r, "/"); !strings.EqualFold(o, owner) {
+			return nil, fmt.Errorf("all repos must be in one org: %s and %s", repos[0], r)
+		}
+	}
+	return repos, nil
+}
+
+// parseDays parses a day count such as "180d" or "180".
+// splitList splits a comma-separated flag, dropping blanks.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func parseDays(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("want a positive number of days like 180d, got %q", s)
+	}
+	return n, nil
+}
+
+func printChecks() {
+	defaults := map[string]bool{}
+	for _, name := range scanner.DefaultChecks() {
+		defaults[name] = true
 	}
-	if repos, ok := result["non_compliant_repos"].([]interface{}); ok && len(repos) > 0 {
-		fmt.Println("\n  Non-compliant repos:")
-		for _, r := range repos {
-			fmt.Printf("    - %v\n", r)
+	fmt.Println("Available checks (* = run by default):")
+	for _, c := range scanner.CheckRegistry {
+		mark := " "
+		if defaults[c.Name] {
+			mark = "*"
 		}
+		fmt.Printf("  %s %-16s %s (+%d API calls/repo)\n", mark, c.Name, c.Description, c.APICalls)
 	}
-	fmt.Println("============================================================")
 }
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// Exit codes, the same for every command.
+const (
+	exitOK    = 0
+	exitError = 1 // infrastructure or input error
+
+	// exitComplianceFailure means the scan worked but the org falls short:
+	// the compliance rate is under --min-compliance, or --diff found
+	// regressions.
+	exitComplianceFailure = 2
+
+	// exitCancelled means the report is partial: the scan was cancelled,
+	// ran out of API budget or reached its deadline.
+	exitCancelled = 3
+
+	// exitStillRunning means --wait-timeout passed before the scan
+	// finished; it is still running and --attach resumes waiting.
+	exitStillRunning = 4
+
+	// exitDegraded means the scan failed with SCAN_DEGRADED: too many repos
+	// errored for the report to count as a compliance result.
+	exitDegraded = 5
+)
+
+// output sends a command's result to out and everything else to info.
+// With --json, out gets exactly one JSON document and info is stderr, so
+// stdout can be piped straight to jq.
+type output struct {
+	out, info io.Writer
+	json      bool
+	render    scanner.RenderOptions
+
+	// format, FormatOCSF or FormatProm, prints the final report in that
+	// format (--format ocsf, --format prom); the other commands print as
+	// without it.
+	format scanner.ReportFormat
+}
+
+func newOutput(asJSON bool, render scanner.RenderOptions) output {
+	if asJSON {
+		return output{out: os.Stdout, info: os.Stderr, json: true}
+	}
+	return output{out: os.Stdout, info: os.Stdout, render: render}
+}
+
+// withFormat makes report print the report in format on out and moves
+// everything else to stderr, as with --json.
+func (o output) withFormat(format scanner.ReportFormat) output {
+	o.format = format
+	o.info = os.Stderr
+	return o
+}
+
+// renderOptions turns --verbose and --no-color into RenderOptions for f.
+// Color needs a terminal and is also off when NO_COLOR is set
+// (https://no-color.org).
+func renderOptions(f *os.File, verbose, noColor bool) scanner.RenderOptions {
+	opts := scanner.RenderOptions{Verbose: verbose}
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return opts
+	}
+	if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		opts.Color = true
+	}
+	return opts
+}
+
+func (o output) writeJSON(v interface{}) {
+	b, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Fprintln(o.out, string(b))
+}
+
+// startedAck is the --json output of --no-wait.
+type startedAck struct {
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+	TaskQueue  string `json:"task_queue"`
+}
+
+func (o output) started(ack startedAck) {
+	if o.json {
+		o.writeJSON(ack)
+		return
+	}
+	fmt.Fprintln(o.out, "Workflow started.")
+	fmt.Fprintf(o.out, "  Query:  go run ./go_comparison/starter --workflow-id %s --query\n", ack.WorkflowID)
+	fmt.Fprintf(o.out, "  Cancel: go run ./go_comparison/starter --workflow-id %s --cancel \"reason\"\n", ack.WorkflowID)
+	fmt.Fprintf(o.out, "  Wait:   %s\n", reattachCommand(ack.WorkflowID, ack.RunID, 0))
+	fmt.Fprintf(o.out, "  UI:     http://localhost:8233/namespaces/default/workflows/%s\n", ack.WorkflowID)
+}
+
+// progress prints a ScanProgress; --json prints it verbatim.
+func (o output) progress(org string, p scanner.ScanProgress) {
+	if o.json {
+		o.writeJSON(p)
+		return
+	}
+	fmt.Fprintf(o.out, "Security Scan Progress: %s\n", org)
+	fmt.Fprintf(o.out, "  Status:       %s\n", p.Status)
+	switch p.Status {
+	case scanner.ScanStarting, scanner.ScanFetchingRepos:
+		fmt.Fprintf(o.out, "  Progress:     %s\n", listingProgress(p))
+	case scanner.ScanPaused:
+		if p.NextBatchAt != nil {
+			fmt.Fprintf(o.out, "  Next batch:   %s\n", p.NextBatchAt.Format(time.RFC3339))
+		}
+		if p.Deadline != nil {
+			fmt.Fprintf(o.out, "  Deadline:     %s\n", p.Deadline.Format(time.RFC3339))
+		}
+		fallthrough
+	default:
+		fmt.Fprintf(o.out, "  Progress:     %d/%d repos (%.1f%%)\n",
+			p.ScannedRepos, p.TotalRepos, p.PercentComplete())
+	}
+	fmt.Fprintf(o.out, "  Compliant:    %d\n", p.CompliantRepos)
+	fmt.Fprintf(o.out, "  Non-compliant: %d\n", p.NonCompliantRepos)
+	if p.IndeterminateRepos > 0 {
+		fmt.Fprintf(o.out, "  Indeterminate: %d\n", p.IndeterminateRepos)
+	}
+	fmt.Fprintf(o.out, "  Errors:       %d\n", p.Errors)
+	if p.CancelledInFlight > 0 {
+		fmt.Fprintf(o.out, "  Cancelled in flight: %d\n", p.CancelledInFlight)
+	}
+	fmt.Fprintf(o.out, "  Started:      %s\n", p.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(o.out, "  Updated:      %s\n", p.UpdatedAt.Format(time.RFC3339))
+	fmt.Fprintf(o.out, "  Run ID:       %s\n", p.RunID)
+}
+
+// results prints a scan's per-repo results as a table; --json prints them
+// as the query returned them: the results, or with paged the page.
+func (o output) results(org, workflowID string, page scanner.ResultsPage, paged bool) {
+	if o.json {
+		if paged {
+			o.writeJSON(page)
+		} else {
+			o.writeJSON(page.Results)
+		}
+		return
+	}
+	fmt.Fprintf(o.out, "Security Scan Results: %s\n", org)
+	scanner.RenderResults(o.out, page.Results, o.render)
+	if paged {
+		fmt.Fprintf(o.out, "\n  Page: %d-%d of %d held inline\n", page.Offset+min(1, len(page.Results)), page.Offset+len(page.Results), page.Total)
+		if page.NextOffset > 0 {
+			fmt.Fprintf(o.out, "  Next: go run ./go_comparison/starter --workflow-id %s --results --results-limit %d --results-offset %d\n",
+				workflowID, len(page.Results), page.NextOffset)
+		}
+	}
+}
+
+// cancelAck is the --json output of --cancel.
+type cancelAck struct {
+	WorkflowID string `json:"workflow_id"`
+	Signal     string `json:"signal"`
+	Reason     string `json:"reason"`
+}
+
+func (o output) cancelSent(workflowID, reason string) {
+	if o.json {
+		o.writeJSON(cancelAck{WorkflowID: workflowID, Signal: "cancel_scan", Reason: reason})
+		return
+	}
+	fmt.Fprintln(o.out, "\nSignal sent. The scan will stop after the current batch and produce a partial report.")
+}
+
+// report prints the final report; --json prints it as the workflow
+// returned it, which is also what is saved to disk and what
+// scanner.ParseReport reads, --format ocsf prints its findings and
+// --format prom its Prometheus gauges.
+func (o output) report(result map[string]interface{}) {
+	if o.json {
+		o.writeJSON(result)
+		return
+	}
+	if o.format != "" {
+		b, err := formatResult(result, o.format)
+		if err != nil {
+			fmt.Fprintf(o.info, "Formatting the report as %s failed: %v\n", o.format, err)
+			return
+		}
+		_, _ = o.out.Write(b)
+		return
+	}
+	renderResult(o.out, result, o.render)
+}
+
+// formatResult is the workflow's report in format.
+func formatResult(result interface{}, format scanner.ReportFormat) ([]byte, error) {
+	b, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	r, err := scanner.ParseReport(b)
+	if err != nil {
+		return nil, err
+	}
+	return scanner.FormatReport(r, format)
+}
+
+// enterpriseReport prints an enterprise scan's report; --json prints it
+// as saved.
+func (o output) enterpriseReport(r scanner.EnterpriseReport) {
+	if o.json {
+		o.writeJSON(r)
+		return
+	}
+	scanner.RenderEnterpriseReport(o.out, r, o.render)
+}
+
+// enterpriseProgress prints an EnterpriseProgress; --json prints it
+// verbatim.
+func (o output) enterpriseProgress(p scanner.EnterpriseProgress) {
+	if o.json {
+		o.writeJSON(p)
+		return
+	}
+	fmt.Fprintf(o.out, "Enterprise Scan Progress: %s\n", p.Enterprise)
+	if p.Orgs == 0 {
+		fmt.Fprintln(o.out, "  Progress:     listing orgs")
+	} else {
+		fmt.Fprintf(o.out, "  Progress:     %d/%d orgs\n", p.OrgsDone, p.Orgs)
+	}
+	if len(p.RunningOrgs) > 0 {
+		fmt.Fprintf(o.out, "  Scanning:     %s\n", strings.Join(p.RunningOrgs, ", "))
+	}
+	if p.Cancelled {
+		fmt.Fprintln(o.out, "  Cancelled:    finishing the running org scans")
+	}
+}
+
+func (o output) list(listings []scanListing) {
+	if o.json {
+		o.writeJSON(listings)
+		return
+	}
+	if len(listings) == 0 {
+		fmt.Fprintln(o.out, "No scans found.")
+		return
+	}
+	fmt.Fprintf(o.out, "%-40s %-20s %-12s %-20s %-16s %s\n", "WORKFLOW ID", "ORG", "STATUS", "STARTED", "BY", "PROGRESS")
+	for _, l := range listings {
+		progress := "-"
+		if p := l.Progress; p != nil {
+			progress = listProgress(*p)
+		}
+		by := l.InitiatedBy
+		if by == "" {
+			by = "-"
+		}
+		fmt.Fprintf(o.out, "%-40s %-20s %-12s %-20s %-16s %s\n",
+			l.WorkflowID, l.Org, l.Status, l.StartTime.UTC().Format(time.RFC3339), by, progress)
+	}
+}
+
+// listProgress is the PROGRESS column of --list for a running scan.
+func listProgress(p scanner.ScanProgress) string {
+	switch {
+	case p.Status == scanner.ScanStarting, p.Status == scanner.ScanFetchingRepos:
+		return listingProgress(p)
+	case p.Status.IsTerminal():
+		// Done scanning; the report is being written.
+		return fmt.Sprintf("%d/%d %s, reporting", p.ScannedRepos, p.TotalRepos, p.Status)
+	}
+	return fmt.Sprintf("%d/%d (%.1f%%) %s", p.ScannedRepos, p.TotalRepos, p.PercentComplete(), p.Status)
+}
+
+// listingProgress is a scan's progress while it lists its repos.
+func listingProgress(p scanner.ScanProgress) string {
+	if p.ReposListed > 0 {
+		return fmt.Sprintf("listing repos (%d so far)", p.ReposListed)
+	}
+	return "listing repos"
+}
+
+func (o output) diff(d scanner.ReportDiff) {
+	if o.json {
+		o.writeJSON(d)
+		return
+	}
+	printDiff(o.out, d)
+}
+
+// rateLimitCheck is the result of --rate-limit. EstimatedCalls and
+// WorstCaseSeconds are 0 unless an org or repo list was given.
+type rateLimitCheck struct {
+	scanner.RateLimitStatus
+	Authenticated  bool   `json:"authenticated"`
+	Org            string `json:"org,omitempty"`
+	Repos          int    `json:"repos,omitempty"`
+	EstimatedCalls int    `json:"estimated_calls,omitempty"`
+	// WorstCaseSeconds is how long the scan takes if every activity runs
+	// until its schedule-to-close timeout.
+	WorstCaseSeconds int `json:"worst_case_seconds,omitempty"`
+}
+
+// Sufficient reports whether the remaining core budget covers the scan.
+func (c rateLimitCheck) Sufficient() bool {
+	return c.Core.Remaining >= c.EstimatedCalls
+}
+
+func (o output) rateLimit(c rateLimitCheck) {
+	if o.json {
+		o.writeJSON(struct {
+			rateLimitCheck
+			Sufficient bool `json:"sufficient"`
+		}{c, c.Sufficient()})
+		return
+	}
+	auth := "unauthenticated"
+	if c.Authenticated {
+		auth = "authenticated"
+	}
+	fmt.Fprintf(o.out, "GitHub rate limit (%s):\n", auth)
+	for _, api := range []struct {
+		name  string
+		limit scanner.RateLimit
+	}{{"core", c.Core}, {"search", c.Search}, {"graphql", c.GraphQL}} {
+		if api.limit.Limit == 0 {
+			continue
+		}
+		reset := api.limit.Reset.Local()
+		fmt.Fprintf(o.out, "  %-8s %d/%d remaining, resets %s (in %s)\n", api.name,
+			api.limit.Remaining, api.limit.Limit, reset.Format("15:04:05 MST"),
+			time.Until(reset).Round(time.Second))
+	}
+	if c.EstimatedCalls == 0 {
+		return
+	}
+	target := c.Org
+	if target == "" {
+		target = "the repo list"
+	}
+	fmt.Fprintf(o.out, "\n  A scan of %s (%d repos) needs about %d core calls.\n", target, c.Repos, c.EstimatedCalls)
+	if c.WorstCaseSeconds > 0 {
+		fmt.Fprintf(o.out, "  If every activity runs out its timeouts, it takes at most %s.\n",
+			time.Duration(c.WorstCaseSeconds)*time.Second)
+	}
+	if !c.Sufficient() {
+		fmt.Fprintf(o.info, "  Warning: only %d remaining; the scan will stall until the reset at %s.\n",
+			c.Core.Remaining, c.Core.Reset.Local().Format("15:04:05 MST"))
+	}
+}
+
+// terminateAck is the result of --terminate.
+type terminateAck struct {
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+	Reason     string `json:"reason"`
+}
+
+func (o output) terminated(ack terminateAck) {
+	if o.json {
+		o.writeJSON(ack)
+		return
+	}
+	fmt.Fprintf(o.out, "Terminated run %s of '%s'.\n", ack.RunID, ack.WorkflowID)
+	fmt.Fprintln(o.out, "No report was produced; start a new scan to get one.")
+}
+
+// resetAck is the result of a reset.
+type resetAck struct {
+	WorkflowID string `json:"workflow_id"`
+	OldRunID   string `json:"old_run_id"`
+	NewRunID   string `json:"new_run_id"`
+	EventID    int64  `json:"event_id"`
+}
+
+func (o output) reset(ack resetAck) {
+	if o.json {
+		o.writeJSON(ack)
+	} else {
+		fmt.Fprintf(o.out, "Reset '%s' to event %d.\n", ack.WorkflowID, ack.EventID)
+		fmt.Fprintf(o.out, "  Old run: %s (terminated)\n", ack.OldRunID)
+		fmt.Fprintf(o.out, "  New run: %s\n", ack.NewRunID)
+	}
+	fmt.Fprintln(o.info, "\nActivities that were in flight in the old run may still complete once;")
+	fmt.Fprintln(o.info, "their results are discarded and the new run schedules them again.")
+}
+
+// scanDegraded prints to w why a scan failed as degraded, with the partial
+// report when it came with one, and returns exitDegraded; false when err is
+// not a SCAN_DEGRADED failure.
+func scanDegraded(w io.Writer, err error, opts scanner.RenderOptions) (int, bool) {
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != scanner.ErrTypeScanDegraded {
+		return 0, false
+	}
+	fmt.Fprintf(w, "Scan degraded: %s\n", appErr.Message())
+	var result map[string]interface{}
+	if appErr.HasDetails() && appErr.Details(&result) == nil {
+		fmt.Fprintln(w, "Partial report follows; do not record it as a compliance result.")
+		renderResult(w, result, opts)
+	}
+	return exitDegraded, true
+}
+
+// reportExitCode is the exit code for a finished scan's report.
+func reportExitCode(result map[string]interface{}, minCompliance float64) int {
+	if cancelled, _ := result["cancelled"].(bool); cancelled {
+		return exitCancelled
+	}
+	if usage, _ := result["api_usage"].(map[string]interface{}); usage != nil {
+		if exceeded, _ := usage["budget_exceeded"].(bool); exceeded {
+			return exitCancelled
+		}
+	}
+	if reached, _ := result["deadline_reached"].(bool); reached {
+		return exitCancelled
+	}
+	if minCompliance > 0 {
+		b, _ := json.Marshal(result)
+		r, err := scanner.ParseReport(b)
+		if err != nil || r.Rate() < minCompliance {
+			return exitComplianceFailure
+		}
+	}
+	return exitOK
+}
+
+// diffReports compares two saved reports and returns the exit code. Reports
+// of different major schema versions are refused.
+func diffReports(o output, oldPath, newPath string) int {
+	var reports [2]scanner.Report
+	for i, path := range []string{oldPath, newPath} {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			reports[i], err = scanner.ParseReport(data)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: reading report %s: %v\n", path, err)
+			return exitError
+		}
+	}
+	if err := scanner.CheckComparable(reports[0], reports[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot diff %s and %s: %v\n", oldPath, newPath, err)
+		return exitError
+	}
+	d := scanner.CompareReports(reports[0], reports[1])
+	o.diff(d)
+	if d.HasRegressions() {
+		return exitComplianceFailure
+	}
+	return exitOK
+}
+
+// renderResult renders a report as returned by the workflow.
+func renderResult(w io.Writer, result map[string]interface{}, opts scanner.RenderOptions) {
+	b, err := json.Marshal(result)
+	if err == nil {
+		var r scanner.Report
+		if r, err = scanner.ParseReport(b); err == nil {
+			scanner.RenderReport(w, r, opts)
+			return
+		}
+	}
+	fmt.Fprintf(w, "Rendering report failed: %v\n", err)
+}
+
+func printDiff(w io.Writer, d scanner.ReportDiff) {
+	fmt.Fprintf(w, "Report diff: %s\n", d.Org)
+	fmt.Fprintf(w, "  Compliance rate:  %.1f%% -> %.1f%% (%+.1f)\n", d.OldRate, d.NewRate, d.RateDelta)
+	if d.ScoreDelta != nil {
+		fmt.Fprintf(w, "  Compliance score: %+.1f\n", *d.ScoreDelta)
+	}
+	fmt.Fprintf(w, "  Errors:           %d -> %d (%+d)\n", d.OldErrors, d.NewErrors, d.ErrorsDelta)
+	if !d.HasChanges() {
+		fmt.Fprintln(w, "\n  No changes.")
+		return
+	}
+
+	printChecksRepos := func(title string, m map[string][]string) {
+		if len(m) == 0 {
+			return
+		}
+		checks := make([]string, 0, len(m))
+		for c := range m {
+			checks = append(checks, c)
+		}
+		sort.Strings(checks)
+		fmt.Fprintf(w, "\n  %s:\n", title)
+		for _, c := range checks {
+			fmt.Fprintf(w, "    %s: %s\n", c, strings.Join(m[c], ", "))
+		}
+	}
+	printChecksRepos("Regressions", d.Regressions)
+	printChecksRepos("Improvements", d.Improvements)
+
+	if len(d.Added) > 0 {
+		fmt.Fprintf(w, "\n  Added repos:   %s\n", strings.Join(d.Added, ", "))
+	}
+	if len(d.Removed) > 0 {
+		fmt.Fprintf(w, "\n  Removed repos: %s\n", strings.Join(d.Removed, ", "))
+	}
+	for _, section := range []struct {
+		title    string
+		findings []scanner.SuppressedFinding
+	}{
+		{"Newly suppressed", d.NewlySuppressed},
+		{"No longer suppressed", d.Unsuppressed},
+		{"Suppression changed", d.SuppressionChanged},
+	} {
+		if len(section.findings) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n  %s:\n", section.title)
+		for _, f := range section.findings {
+			fmt.Fprintf(w, "    - %s %s: %s\n", f.Repository, f.Check, f.Justification)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// testOutput returns an output writing to buffers instead of stdout/stderr.
+func testOutput(asJSON bool) (output, *bytes.Buffer, *bytes.Buffer) {
+	var out, info bytes.Buffer
+	o := output{out: &out, info: &info, json: asJSON}
+	if !asJSON {
+		o.info = &out
+	}
+	return o, &out, &info
+}
+
+func TestJSONStarted(t *testing.T) {
+	o, out, _ := testOutput(true)
+	o.started(startedAck{WorkflowID: "security-scan-acme", RunID: "run-1", TaskQueue: taskQueue})
+	require.JSONEq(t, `{"workflow_id":"security-scan-acme","run_id":"run-1","task_queue":"security-scanner-go"}`, out.String())
+}
+
+func TestJSONProgressIsVerbatim(t *testing.T) {
+	p := scanner.ScanProgress{
+		Org: "acme", TotalRepos: 40, ScannedRepos: 10, CompliantRepos: 7, NonCompliantRepos: 3,
+		Status: scanner.ScanScanning, WorkflowID: "security-scan-acme", RunID: "run-1",
+		StartedAt: time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC),
+	}
+	o, out, _ := testOutput(true)
+	o.progress("acme", p)
+
+	var got scanner.ScanProgress
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	require.Equal(t, p, got)
+
+	o, out, _ = testOutput(false)
+	o.progress("acme", p)
+	require.Contains(t, out.String(), "10/40 repos (25.0%)")
+}
+
+func TestProgressByStatus(t *testing.T) {
+	next := time.Date(2026, 3, 2, 14, 5, 0, 0, time.UTC)
+	o, out, _ := testOutput(false)
+	o.progress("acme", scanner.ScanProgress{Status: scanner.ScanFetchingRepos})
+	require.Contains(t, out.String(), "  Progress:     listing repos\n")
+
+	o, out, _ = testOutput(false)
+	o.progress("acme", scanner.ScanProgress{Status: scanner.ScanFetchingRepos, ReposListed: 2300})
+	require.Contains(t, out.String(), "  Progress:     listing repos (2300 so far)\n")
+
+	o, out, _ = testOutput(false)
+	o.progress("acme", scanner.ScanProgress{Status: scanner.ScanPaused, NextBatchAt: &next, ScannedRepos: 100, TotalRepos: 400})
+	require.Contains(t, out.String(), "  Next batch:   2026-03-02T14:05:00Z\n  Progress:     100/400 repos (25.0%)\n")
+
+	require.Equal(t, "listing repos", listProgress(scanner.ScanProgress{Status: scanner.ScanStarting}))
+	require.Equal(t, "100/400 (25.0%) sleeping", listProgress(scanner.ScanProgress{Status: scanner.ScanPaused, ScannedRepos: 100, TotalRepos: 400}))
+	require.Equal(t, "120/400 cancelled, reporting", listProgress(scanner.ScanProgress{Status: scanner.ScanCancelled, ScannedRepos: 120, TotalRepos: 400}))
+}
+
+func TestJSONCancelAck(t *testing.T) {
+	o, out, _ := testOutput(true)
+	o.cancelSent("security-scan-acme", "change freeze")
+	require.JSONEq(t, `{"workflow_id":"security-scan-acme","signal":"cancel_scan","reason":"change freeze"}`, out.String())
+}
+
+func TestJSONReportIsParseable(t *testing.T) {
+	report := map[string]interface{}{
+		"org": "acme", "total_repos": 2, "fully_compliant": 1, "compliance_rate": "50.0%",
+		"non_compliant_repos": []string{"web"},
+		"repo_failures":       map[string][]string{"api": {}, "web": {scanner.CheckDependabot}},
+	}
+	o, out, info := testOutput(true)
+	o.report(report)
+	fmt.Fprintln(o.info, "Report saved to security_scan_acme.json")
+
+	r, err := scanner.ParseReport(out.Bytes())
+	require.NoError(t, err, "stdout holds only the report")
+	require.Equal(t, 50.0, r.Rate())
+	require.Equal(t, []string{"web"}, r.NonCompliantRepos)
+	require.Contains(t, info.String(), "Report saved")
+
+	o, out, _ = testOutput(false)
+	o.report(report)
+	require.Contains(t, out.String(), "Compliance rate:      50.0%")
+
+	o, out, _ = testOutput(false)
+	o = o.withFormat(scanner.FormatOCSF)
+	o.report(report)
+	var finding scanner.OCSFFinding
+	require.NoError(t, json.Unmarshal(out.Bytes(), &finding), "stdout holds one finding per line")
+	require.Equal(t, "acme/web", finding.Resources[0].Name)
+	require.Equal(t, scanner.CheckDependabot, finding.Compliance.Control)
+}
+
+func TestReportExitCode(t *testing.T) {
+	passing := map[string]interface{}{"total_repos": 10, "fully_compliant": 9, "compliance_rate": "90.0%"}
+	require.Equal(t, exitOK, reportExitCode(passing, 0))
+	require.Equal(t, exitOK, reportExitCode(passing, 90))
+	require.Equal(t, exitComplianceFailure, reportExitCode(passing, 95))
+
+	cancelled := map[string]interface{}{"cancelled": true, "compliance_rate": "10.0%"}
+	require.Equal(t, exitCancelled, reportExitCode(cancelled, 95), "cancellation wins over the threshold")
+
+	stopped := map[string]interface{}{"deadline_reached": true, "unscanned_repos": []interface{}{"api"}, "compliance_rate": "100.0%"}
+	require.Equal(t, exitCancelled, reportExitCode(stopped, 0), "a scan stopped at its deadline is partial")
+}
+
+func TestScanDegradedExitCode(t *testing.T) {
+	var buf bytes.Buffer
+	partial := map[string]interface{}{"org": "acme", "total_repos": 40, "errors": 30, "compliance_rate": "20.0%"}
+	degraded := temporal.NewApplicationError("scan degraded: 30 of 40 repos errored", scanner.ErrTypeScanDegraded, partial)
+	code, ok := scanDegraded(&buf, degraded, scanner.RenderOptions{})
+	require.True(t, ok)
+	require.Equal(t, exitDegraded, code)
+	require.NotEqual(t, exitError, code, "a degraded scan is told apart from an infrastructure error")
+	require.Contains(t, buf.String(), "Scan degraded: scan degraded: 30 of 40 repos errored")
+	require.Contains(t, buf.String(), "Partial report follows")
+
+	_, ok = scanDegraded(&buf, temporal.NewApplicationError("boom", "OTHER"), scanner.RenderOptions{})
+	require.False(t, ok, "other failures keep exitError")
+	_, ok = scanDegraded(&buf, nil, scanner.RenderOptions{})
+	require.False(t, ok)
+}
+
+func TestEnterpriseExitCode(t *testing.T) {
+	passing := scanner.EnterpriseReport{TotalRepos: 10, FullyCompliant: 9, ComplianceRate: "90.0%"}
+	require.Equal(t, exitOK, enterpriseExitCode(passing, 90))
+	require.Equal(t, exitComplianceFailure, enterpriseExitCode(passing, 95))
+
+	failed := passing
+	failed.FailedOrgs = map[string]string{"gone": "organization 'gone' not found"}
+	require.Equal(t, exitError, enterpriseExitCode(failed, 0), "an org without a report makes the report incomplete")
+
+	cancelled := failed
+	cancelled.Cancelled = true
+	require.Equal(t, exitCancelled, enterpriseExitCode(cancelled, 95))
+}
+
+func TestDiffReportsExitCode(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+		return path
+	}
+	old := write("old.json", `{"org":"acme","total_repos":1,"fully_compliant":1,"compliance_rate":"100.0%","repo_failures":{"api":[]}}`)
+	cur := write("new.json", `{"org":"acme","total_repos":1,"fully_compliant":0,"compliance_rate":"0.0%","repo_failures":{"api":["dependabot"]}}`)
+
+	o, out, _ := testOutput(true)
+	require.Equal(t, exitComplianceFailure, diffReports(o, old, cur))
+	var d scanner.ReportDiff
+	require.NoError(t, json.Unmarshal(out.Bytes(), &d))
+	require.Equal(t, map[string][]string{scanner.CheckDependabot: {"api"}}, d.Regressions)
+
+	o, _, _ = testOutput(true)
+	require.Equal(t, exitOK, diffReports(o, cur, old))
+	require.Equal(t, exitError, diffReports(o, old, filepath.Join(dir, "missing.json")))
+
+	future := write("future.json", `{"schema_version":"2","org":"acme","total_repos":1,"fully_compliant":1,"compliance_rate":"100.0%"}`)
+	o, out, _ = testOutput(true)
+	require.Equal(t, exitError, diffReports(o, old, future))
+	require.Empty(t, out.String())
+}
+
+func TestRateLimitOutput(t *testing.T) {
+	reset := time.Now().Add(30 * time.Minute).Truncate(time.Second).UTC()
+	check := rateLimitCheck{
+		RateLimitStatus: scanner.RateLimitStatus{
+			Core:   scanner.RateLimit{Limit: 5000, Remaining: 900, Used: 4100, Reset: reset},
+			Search: scanner.RateLimit{Limit: 30, Remaining: 30, Reset: reset},
+		},
+		Authenticated:  true,
+		Org:            "acme",
+		Repos:          400,
+		EstimatedCalls: 1204,
+		// 400 repos are 40 groups of checks.
+		WorstCaseSeconds: int(scanner.ScanInput{}.WorstCaseDuration(400).Seconds()),
+	}
+
+	o, out, _ := testOutput(true)
+	o.rateLimit(check)
+	var got struct {
+		Core           scanner.RateLimit `json:"core"`
+		EstimatedCalls int               `json:"estimated_calls"`
+		Sufficient     bool              `json:"sufficient"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	require.Equal(t, check.Core, got.Core)
+	require.Equal(t, 1204, got.EstimatedCalls)
+	require.False(t, got.Sufficient)
+
+	o, out, _ = testOutput(false)
+	o.rateLimit(check)
+	require.Contains(t, out.String(), "core     900/5000 remaining")
+	require.NotContains(t, out.String(), "graphql", "unreported APIs are skipped")
+	require.Contains(t, out.String(), "about 1204 core calls")
+	require.Contains(t, out.String(), "Warning: only 900 remaining")
+	require.Contains(t, out.String(), "at most 10h32m0s")
+}
+
+func TestTerminateAndResetOutput(t *testing.T) {
+	o, out, _ := testOutput(true)
+	o.terminated(terminateAck{WorkflowID: "security-scan-acme", RunID: "run-1", Reason: "bad deploy"})
+	require.JSONEq(t, `{"workflow_id":"security-scan-acme","run_id":"run-1","reason":"bad deploy"}`, out.String())
+
+	o, out, info := testOutput(true)
+	o.reset(resetAck{WorkflowID: "security-scan-acme", OldRunID: "run-1", NewRunID: "run-2", EventID: 4})
+	require.JSONEq(t, `{"workflow_id":"security-scan-acme","old_run_id":"run-1","new_run_id":"run-2","event_id":4}`, out.String())
+	require.Contains(t, info.String(), "may still complete once")
+
+	o, out, _ = testOutput(false)
+	o.reset(resetAck{WorkflowID: "security-scan-acme", OldRunID: "run-1", NewRunID: "run-2", EventID: 4})
+	require.Contains(t, out.String(), "New run: run-2")
+	require.Contains(t, out.String(), "may still complete once")
+}
+
+func TestRenderOptionsColor(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	require.False(t, renderOptions(w, false, false).Color, "a pipe is not a terminal")
+	require.True(t, renderOptions(w, true, false).Verbose)
+
+	t.Setenv("NO_COLOR", "1")
+	require.False(t, renderOptions(os.Stdout, false, false).Color)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// githubAPIURL is the API root the starter calls: GITHUB_API_URL, as the
+// worker reads it, or the public API.
+func githubAPIURL() string {
+	if u := os.Getenv("GITHUB_API_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return scanner.DefaultGitHubAPI
+}
+
+// githubGet GETs path from the GitHub API, authenticated when cred has a
+// value.
+func githubGet(ctx context.Context, httpClient *http.Client, base, path string, cred *scanner.Credential) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if auth := cred.Authorization(); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	return httpClient.Do(req)
+}
+
+// errInvalidToken is a pre-flight 401; the scan would fail the same way.
+var errInvalidToken = errors.New("invalid GitHub API token")
+
+// orgNotFoundError is a pre-flight 404, with the closest org the token can
+// see when there is one.
+type orgNotFoundError struct {
+	org, suggestion string
+}
+
+func (e *orgNotFoundError) Error() string {
+	msg := fmt.Sprintf("organization '%s' not found", e.org)
+	if e.suggestion != "" {
+		msg += fmt.Sprintf(" — did you mean '%s'?", e.suggestion)
+	}
+	return msg
+}
+
+// preflightOrg checks with one GET /orgs/{org} that org exists, so a typo
+// fails here instead of after the workflow's retries. errInvalidToken and
+// *orgNotFoundError are definite; any other error means the check could not
+// be made.
+func preflightOrg(ctx context.Context, httpClient *http.Client, base, org string, cred *scanner.Credential) error {
+	resp, err := githubGet(ctx, httpClient, base, "/orgs/"+org, cred)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return errInvalidToken
+	case http.StatusNotFound:
+		return &orgNotFoundError{org: org, suggestion: suggestOrg(ctx, httpClient, base, org, cred)}
+	default:
+		return fmt.Errorf("GitHub returned %s", resp.Status)
+	}
+}
+
+// suggestOrg returns the token's org closest to org, or "". Without a token
+// there is nothing to compare against.
+func suggestOrg(ctx context.Context, httpClient *http.Client, base, org string, cred *scanner.Credential) string {
+	if cred.Authorization() == "" {
+		return ""
+	}
+	resp, err := githubGet(ctx, httpClient, base, "/user/orgs?per_page=100", cred)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	var orgs []struct {
+		Login string `json:"login"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&orgs) != nil {
+		return ""
+	}
+	logins := make([]string, len(orgs))
+	for i, o := range orgs {
+		logins[i] = o.Login
+	}
+	return scanner.SuggestOrg(org, logins)
+}
+
+// preflightToken runs the workflow's ValidateToken check and prints the
+// credential's type and what the token cannot evaluate. Only a check the
+// token can't evaluate at all is an error; the workflow would reject the
+// scan the same way.
+func preflightToken(o output, base, org string, cred *scanner.Credential, checks []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	a := &scanner.Activities{HTTPClient: &http.Client{}, BaseURL: base}
+	caps, err := a.ValidateToken(ctx, org, cred, checks)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not check the token's scopes (%v); starting anyway\n", err)
+		return nil
+	}
+	fmt.Fprintf(o.info, "Token: %s credential (%s token)\n", caps.CredentialType, caps.Kind)
+	for _, c := range caps.Checks {
+		switch c.Access {
+		case scanner.AccessNone:
+			fmt.Fprintf(o.info, "Token: %s will be reported as no access: %s\n", c.Check, c.Reason)
+		case scanner.AccessPartial:
+			fmt.Fprintf(o.info, "Token: %s is limited: %s\n", c.Check, c.Reason)
+		}
+	}
+	if n := len(caps.Unavailable()); n > 0 && n == len(caps.Checks) {
+		return errors.New("the token cannot evaluate any selected check")
+	}
+	return nil
+}
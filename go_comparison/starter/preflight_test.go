@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+	"github.com/salkimmich/temporal-security-scanner/go_comparison/internal/githubmock"
+)
+
+func TestPreflightOrg(t *testing.T) {
+	srv := httptest.NewServer(githubmock.New(githubmock.Config{Org: "temporalio", Repos: 3, Token: "secret"}))
+	defer srv.Close()
+	ctx := context.Background()
+	token, wrong := "secret", "wrong"
+
+	require.NoError(t, preflightOrg(ctx, srv.Client(), srv.URL, "temporalio", scanner.PATCredential(&token)))
+
+	err := preflightOrg(ctx, srv.Client(), srv.URL, "temporalo", scanner.PATCredential(&token))
+	var notFound *orgNotFoundError
+	require.True(t, errors.As(err, &notFound), "got %v", err)
+	require.EqualError(t, err, "organization 'temporalo' not found — did you mean 'temporalio'?")
+
+	err = preflightOrg(ctx, srv.Client(), srv.URL, "kubernetes", scanner.PATCredential(&token))
+	require.EqualError(t, err, "organization 'kubernetes' not found")
+
+	require.ErrorIs(t, preflightOrg(ctx, srv.Client(), srv.URL, "temporalio", scanner.PATCredential(&wrong)), errInvalidToken)
+}
+
+func TestPreflightOrgUnavailable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	err := preflightOrg(context.Background(), srv.Client(), srv.URL, "temporalio", nil)
+	require.Error(t, err)
+	var notFound *orgNotFoundError
+	require.False(t, errors.As(err, &notFound), "an outage is not a missing org")
+	require.NotErrorIs(t, err, errInvalidToken)
+}
+
+func TestPreflightToken(t *testing.T) {
+	// A classic token with no scopes can read public files but not Actions
+	// settings.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-OAuth-Scopes", "")
+		w.Write([]byte(`{"login":"acme"}`))
+	}))
+	defer srv.Close()
+	token := "ghp_test"
+
+	o, out, _ := testOutput(false)
+	require.NoError(t, preflightToken(o, srv.URL, "acme", scanner.PATCredential(&token), []string{"files", "actions"}))
+	require.Contains(t, out.String(), "Token: files is limited: needs repo scope for private repos")
+	require.Contains(t, out.String(), "Token: actions will be reported as no access: needs repo scope")
+
+	o, _, _ = testOutput(false)
+	require.Error(t, preflightToken(o, srv.URL, "acme", scanner.PATCredential(&token), []string{"actions"}))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// errResultsTooLarge means results_so_far's response is over the server's
+// or the client's message size limit; results_page still works.
+var errResultsTooLarge = errors.New("the results are too large for one query response")
+
+// queryResults reads a scan's per-repo results: all of them with
+// results_so_far, or with a limit one results_page. Both work on running
+// and closed scans.
+func queryResults(ctx context.Context, q resultsQuerier, workflowID string, offset, limit int) (scanner.ResultsPage, error) {
+	if limit > 0 {
+		resp, err := q.QueryWorkflow(ctx, workflowID, "", scanner.ResultsPageQuery, offset, limit)
+		if err != nil {
+			return scanner.ResultsPage{}, err
+		}
+		var page scanner.ResultsPage
+		if err := resp.Get(&page); err != nil {
+			return scanner.ResultsPage{}, fmt.Errorf("decoding the results: %w", err)
+		}
+		return page, nil
+	}
+	resp, err := q.QueryWorkflow(ctx, workflowID, "", scanner.ResultsSoFarQuery)
+	if err != nil {
+		if tooLarge(err) {
+			return scanner.ResultsPage{}, fmt.Errorf("%w: %v", errResultsTooLarge, err)
+		}
+		return scanner.ResultsPage{}, err
+	}
+	var results []scanner.RepoSecurityResult
+	if err := resp.Get(&results); err != nil {
+		return scanner.ResultsPage{}, fmt.Errorf("decoding the results: %w", err)
+	}
+	return scanner.ResultsPage{Results: results, Total: len(results)}, nil
+}
+
+// tooLarge reports whether err is a response over a size limit: the
+// client's gRPC receive limit, or the server's blob size limit.
+func tooLarge(err error) bool {
+	var exhausted *serviceerror.ResourceExhausted
+	if errors.As(err, &exhausted) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "larger than max") || strings.Contains(msg, "exceeds limit")
+}
+
+// doResults prints a scan's per-repo results (--results).
+func doResults(c resultsQuerier, o output, workflowID, org string, offset, limit int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	page, err := queryResults(ctx, c, workflowID, offset, limit)
+	if errors.Is(err, errResultsTooLarge) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Read them a page at a time: go run ./go_comparison/starter --workflow-id %s --results --results-limit %d [--results-offset N]\n",
+			workflowID, scanner.DefaultResultsPageSize)
+		os.Exit(exitError)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Results query failed: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Has the scan started? Start one with: go run ./go_comparison/starter --org %s\n", org)
+		os.Exit(exitError)
+	}
+	o.results(org, workflowID, page, limit > 0)
+}
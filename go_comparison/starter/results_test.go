@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/converter"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// pagingQuerier answers results_so_far with results, or err if set, and
+// results_page with a page of them.
+type pagingQuerier struct {
+	t       *testing.T
+	results []scanner.RepoSecurityResult
+	err     error
+}
+
+func (q pagingQuerier) QueryWorkflow(_ context.Context, workflowID, runID, queryType string, args ...interface{}) (converter.EncodedValue, error) {
+	require.Equal(q.t, "security-scan-acme", workflowID)
+	require.Empty(q.t, runID, "the latest run, running or closed")
+	var v interface{}
+	switch queryType {
+	case scanner.ResultsSoFarQuery:
+		if q.err != nil {
+			return nil, q.err
+		}
+		v = q.results
+	case scanner.ResultsPageQuery:
+		require.Len(q.t, args, 2)
+		v = scanner.PageResults(q.results, args[0].(int), args[1].(int))
+	default:
+		q.t.Fatalf("unexpected query %s", queryType)
+	}
+	b, err := json.Marshal(v)
+	return encodedJSON(b), err
+}
+
+func TestQueryResults(t *testing.T) {
+	q := pagingQuerier{t: t, results: []scanner.RepoSecurityResult{{Repository: "api"}, {Repository: "web"}, {Repository: "docs"}}}
+	page, err := queryResults(context.Background(), q, "security-scan-acme", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, scanner.ResultsPage{Results: q.results, Total: 3}, page)
+
+	page, err = queryResults(context.Background(), q, "security-scan-acme", 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, scanner.ResultsPage{Results: q.results[1:2], Offset: 1, Total: 3, NextOffset: 2}, page)
+
+	for _, tooBig := range []error{
+		serviceerror.NewResourceExhausted(0, "grpc: received message larger than max (8388608 vs. 4194304)"),
+		serviceerror.NewInvalidArgument("Blob data size exceeds limit."),
+	} {
+		q.err = tooBig
+		_, err = queryResults(context.Background(), q, "security-scan-acme", 0, 0)
+		require.ErrorIs(t, err, errResultsTooLarge)
+	}
+	q.err = serviceerror.NewNotFound("workflow not found")
+	_, err = queryResults(context.Background(), q, "security-scan-acme", 0, 0)
+	require.False(t, errors.Is(err, errResultsTooLarge))
+}
+
+func TestResultsOutput(t *testing.T) {
+	results := []scanner.RepoSecurityResult{{Repository: "api", SecretScanning: scanner.StatusDisabled}}
+
+	o, out, _ := testOutput(true)
+	o.results("acme", "security-scan-acme", scanner.ResultsPage{Results: results, Total: 1}, false)
+	var got []scanner.RepoSecurityResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	require.Equal(t, results, got, "--json prints the query's results verbatim")
+
+	o, out, _ = testOutput(false)
+	o.results("acme", "security-scan-acme", scanner.ResultsPage{Results: results, Offset: 200, Total: 450, NextOffset: 201}, true)
+	require.Contains(t, out.String(), "Security Scan Results: acme\n")
+	require.Contains(t, out.String(), "    api   disabled")
+	require.Contains(t, out.String(), "  Page: 201-201 of 450 held inline\n")
+	require.Contains(t, out.String(), "--workflow-id security-scan-acme --results --results-limit 1 --results-offset 201\n")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// resumable returns an error unless r, read from source, is the report of
+// a scan of org that stopped before scanning every repo.
+func resumable(r scanner.Report, org, source string) error {
+	switch {
+	case r.Org != org:
+		return fmt.Errorf("%s is a scan of '%s', not '%s'", source, r.Org, org)
+	case !r.Cancelled && !r.DeadlineReached:
+		return fmt.Errorf("%s is not a cancelled or deadline-stopped scan", source)
+	case r.WorkflowID == "" || r.RunID == "":
+		return fmt.Errorf("%s does not name its workflow run", source)
+	}
+	return nil
+}
+
+// readResumeReport reads --resume: the saved report of the scan of org to
+// continue.
+func readResumeReport(path, org string) (scanner.Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return scanner.Report{}, err
+	}
+	r, err := scanner.ParseReport(data)
+	if err != nil {
+		return scanner.Report{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, resumable(r, org, path)
+}
+
+// fetchResumeReport is --resume-workflow-id: the report of the latest run
+// of workflowID, which must have finished.
+func fetchResumeReport(ctx context.Context, c client.Client, workflowID, org string) (scanner.Report, error) {
+	resp, err := c.DescribeWorkflowExecution(ctx, workflowID, "")
+	if err != nil {
+		return scanner.Report{}, err
+	}
+	info := resp.GetWorkflowExecutionInfo()
+	if info.GetStatus() == enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		return scanner.Report{}, fmt.Errorf("%s is still running; cancel it first (--cancel)", workflowID)
+	}
+	var r scanner.Report
+	if err := c.GetWorkflow(ctx, workflowID, info.GetExecution().GetRunId()).Get(ctx, &r); err != nil {
+		return scanner.Report{}, fmt.Errorf("reading the report of %s: %w", workflowID, err)
+	}
+	return r, resumable(r, org, workflowID)
+}
+
+// resultsQuerier is the part of client.Client that resumeState uses.
+type resultsQuerier interface {
+	QueryWorkflow(ctx context.Context, workflowID, runID, queryType string, args ...interface{}) (converter.EncodedValue, error)
+}
+
+// resumeState is the ResumeState that continues the scan r reports on. The
+// results it held inline come from its results_so_far query, which a
+// worker answers by replaying the finished run; the rest are in its
+// results_blob_refs.
+func resumeState(ctx context.Context, q resultsQuerier, r scanner.Report) (*scanner.ResumeState, error) {
+	resp, err := q.QueryWorkflow(ctx, r.WorkflowID, r.RunID, scanner.ResultsSoFarQuery)
+	if err != nil {
+		return nil, fmt.Errorf("querying the results of %s: %w", r.WorkflowID, err)
+	}
+	state := &scanner.ResumeState{
+		UnscannedRepos: r.UnscannedRepos,
+		Refs:           r.ResultsBlobRefs,
+		RunIDs:         append(append([]string(nil), r.ResumedFromRunIDs...), r.RunID),
+	}
+	if err := resp.Get(&state.Results); err != nil {
+		return nil, fmt.Errorf("decoding the results of %s: %w", r.WorkflowID, err)
+	}
+	return state, nil
+}
+
+// loadResumeState builds --resume or --resume-workflow-id's ResumeState.
+func loadResumeState(path, workflowID, org string) (*scanner.ResumeState, error) {
+	if path != "" && workflowID != "" {
+		return nil, fmt.Errorf("--resume and --resume-workflow-id are alternatives")
+	}
+	c := dial()
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var r scanner.Report
+	var err error
+	if path != "" {
+		r, err = readResumeReport(path, org)
+	} else {
+		r, err = fetchResumeReport(ctx, c, workflowID, org)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resumeState(ctx, c, r)
+}
+
+// countResults is how many results refs hold.
+func countResults(refs []scanner.BlobRef) int {
+	n := 0
+	for _, ref := range refs {
+		n += ref.Count
+	}
+	return n
+}
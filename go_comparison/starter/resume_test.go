@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+func TestReadResumeReport(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+		return path
+	}
+	stopped := write("stopped.json", `{"org":"acme","workflow_id":"security-scan-acme","run_id":"run-2","deadline_reached":true,"unscanned_repos":["api","web"]}`)
+	r, err := readResumeReport(stopped, "acme")
+	require.NoError(t, err)
+	require.Equal(t, []string{"api", "web"}, r.UnscannedRepos)
+
+	_, err = readResumeReport(stopped, "globex")
+	require.ErrorContains(t, err, "a scan of 'acme'")
+	_, err = readResumeReport(write("done.json", `{"org":"acme","workflow_id":"security-scan-acme","run_id":"run-2"}`), "acme")
+	require.ErrorContains(t, err, "not a cancelled or deadline-stopped scan")
+}
+
+// fakeQuerier answers results_so_far with results.
+type fakeQuerier struct {
+	t       *testing.T
+	results []scanner.RepoSecurityResult
+}
+
+func (q fakeQuerier) QueryWorkflow(_ context.Context, workflowID, runID, queryType string, _ ...interface{}) (converter.EncodedValue, error) {
+	require.Equal(q.t, "security-scan-acme", workflowID)
+	require.Equal(q.t, "run-2", runID)
+	require.Equal(q.t, "results_so_far", queryType)
+	b, err := json.Marshal(q.results)
+	return encodedJSON(b), err
+}
+
+type encodedJSON []byte
+
+func (e encodedJSON) HasValue() bool                 { return len(e) > 0 }
+func (e encodedJSON) Get(valuePtr interface{}) error { return json.Unmarshal(e, valuePtr) }
+
+func TestResumeState(t *testing.T) {
+	r := scanner.Report{
+		Org: "acme", WorkflowID: "security-scan-acme", RunID: "run-2",
+		Cancelled: true, UnscannedRepos: []string{"web"},
+		ResultsBlobRefs:   []scanner.BlobRef{{URI: "file:///tmp/results-0000.json", Count: 100}},
+		ResumedFromRunIDs: []string{"run-1"},
+	}
+	q := fakeQuerier{t: t, results: []scanner.RepoSecurityResult{{Repository: "api"}}}
+	state, err := resumeState(context.Background(), q, r)
+	require.NoError(t, err)
+	require.Equal(t, &scanner.ResumeState{
+		UnscannedRepos: []string{"web"},
+		Results:        []scanner.RepoSecurityResult{{Repository: "api"}},
+		Refs:           r.ResultsBlobRefs,
+		RunIDs:         []string{"run-1", "run-2"},
+	}, state)
+}
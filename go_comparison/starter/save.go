@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// reportFile is where the starter saves a finished scan's report: --output,
+// --no-save and --overwrite.
+type reportFile struct {
+	// path is --output; empty means the scan's default path.
+	path      string
+	noSave    bool
+	overwrite bool
+	// format is FormatProm to save the report's Prometheus gauges, for
+	// node_exporter's textfile collector, instead of its JSON.
+	format scanner.ReportFormat
+}
+
+// reportPath is the default path of org's report.
+func reportPath(org string) string {
+	return "security_scan_" + org + ".json"
+}
+
+// enterpriseReportPath is the default path of an enterprise scan's report.
+func enterpriseReportPath(enterprise string) string {
+	return "security_scan_enterprise_" + enterprise + ".json"
+}
+
+// pathFor is f's path, or defaultPath without --output; a .prom file
+// with --format prom.
+func (f reportFile) pathFor(defaultPath string) string {
+	switch {
+	case f.path != "":
+		return f.path
+	case f.format == scanner.FormatProm:
+		return strings.TrimSuffix(defaultPath, ".json") + ".prom"
+	}
+	return defaultPath
+}
+
+// check fails when the report could not be saved without --overwrite, so
+// the starter can say so before it starts a scan, not after the scan ran.
+func (f reportFile) check(defaultPath string) error {
+	if f.noSave || f.overwrite {
+		return nil
+	}
+	path := f.pathFor(defaultPath)
+	if _, err := os.Stat(path); err == nil {
+		return reportExistsError(path)
+	}
+	return nil
+}
+
+// save writes report to f's path, or defaultPath, and says where on info.
+// It returns false when the report could not be saved, after saying why on
+// stderr; the caller has printed the report already, so it is not lost.
+func (f reportFile) save(info io.Writer, defaultPath string, report interface{}) bool {
+	if f.noSave {
+		return true
+	}
+	path := f.pathFor(defaultPath)
+	write := writeReportFile
+	if f.format == scanner.FormatProm {
+		write = writePromFile
+	}
+	if err := write(path, report, f.overwrite); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: saving the report: %v\n", err)
+		fmt.Fprintln(os.Stderr, "The report above was not saved.")
+		return false
+	}
+	fmt.Fprintf(info, "\nReport saved to %s\n", path)
+	return true
+}
+
+func reportExistsError(path string) error {
+	return fmt.Errorf("%s already exists; use --overwrite to replace it or --output to save the report elsewhere", path)
+}
+
+// writeReportFile writes v to path as indented JSON. It writes a
+// temporary file next to path, syncs it and renames it over path, so path
+// is never left half-written, creating path's directory if need be. An
+// existing path is only replaced with overwrite.
+func writeReportFile(path string, v interface{}, overwrite bool) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b, overwrite)
+}
+
+// writePromFile writes the Prometheus gauges of report, a scan's report
+// as the workflow returned it, to path as writeReportFile does.
+func writePromFile(path string, report interface{}, overwrite bool) error {
+	b, err := formatResult(report, scanner.FormatProm)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b, overwrite)
+}
+
+// writeFileAtomic writes b to path for writeReportFile.
+func writeFileAtomic(path string, b []byte, overwrite bool) error {
+	if !overwrite {
+		if _, err := os.Stat(path); err == nil {
+			return reportExistsError(path)
+		}
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		// CreateTemp's file is private; reports were always world-readable.
+		err = os.Chmod(f.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// tempFiles lists the temporary files writeReportFile left in dir.
+func tempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	require.NoError(t, err)
+	return matches
+}
+
+func TestWriteReportFileCreatesParentDirectories(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "reports", "2026", "acme.json")
+
+	require.NoError(t, writeReportFile(path, map[string]int{"total_repos": 3}, false))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var got map[string]int
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.Equal(t, 3, got["total_repos"])
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+	require.Empty(t, tempFiles(t, filepath.Dir(path)))
+}
+
+func TestWriteReportFileRefusesToOverwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acme.json")
+	require.NoError(t, os.WriteFile(path, []byte("last week's report"), 0o644))
+
+	err := writeReportFile(path, map[string]int{"total_repos": 3}, false)
+	require.ErrorContains(t, err, "already exists")
+	b, _ := os.ReadFile(path)
+	require.Equal(t, "last week's report", string(b))
+
+	require.NoError(t, writeReportFile(path, map[string]int{"total_repos": 3}, true))
+	b, _ = os.ReadFile(path)
+	require.JSONEq(t, `{"total_repos":3}`, string(b))
+	require.Empty(t, tempFiles(t, filepath.Dir(path)))
+}
+
+func TestWriteReportFileRenameFailureLeavesNoTempFile(t *testing.T) {
+	dir := t.TempDir()
+	// A directory in the report's place cannot be renamed over.
+	path := filepath.Join(dir, "acme.json")
+	require.NoError(t, os.MkdirAll(filepath.Join(path, "keep"), 0o755))
+
+	require.Error(t, writeReportFile(path, map[string]int{"total_repos": 3}, true))
+	require.Empty(t, tempFiles(t, dir))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+}
+
+func TestWriteReportFilePermissionDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root may write to any directory")
+	}
+	dir := filepath.Join(t.TempDir(), "readonly")
+	require.NoError(t, os.Mkdir(dir, 0o500))
+	t.Cleanup(func() { _ = os.Chmod(dir, 0o755) })
+
+	err := writeReportFile(filepath.Join(dir, "acme.json"), map[string]int{}, false)
+	require.ErrorIs(t, err, os.ErrPermission)
+	entries, _ := os.ReadDir(dir)
+	require.Empty(t, entries)
+}
+
+func TestWriteReportFileParentIsAFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "reports"), nil, 0o644))
+
+	require.Error(t, writeReportFile(filepath.Join(dir, "reports", "acme.json"), map[string]int{}, false))
+}
+
+func TestWriteReportFileMarshalFailureKeepsExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acme.json")
+	require.NoError(t, os.WriteFile(path, []byte("last week's report"), 0o644))
+
+	require.Error(t, writeReportFile(path, map[string]interface{}{"bad": make(chan int)}, true))
+	b, _ := os.ReadFile(path)
+	require.Equal(t, "last week's report", string(b))
+}
+
+func TestReportFileCheck(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "security_scan_acme.json")
+	require.NoError(t, os.WriteFile(existing, nil, 0o644))
+
+	require.ErrorContains(t, reportFile{}.check(existing), "--overwrite")
+	require.NoError(t, reportFile{overwrite: true}.check(existing))
+	require.NoError(t, reportFile{noSave: true}.check(existing))
+	require.NoError(t, reportFile{path: filepath.Join(dir, "other.json")}.check(existing))
+}
+
+func TestReportFileSave(t *testing.T) {
+	dir := t.TempDir()
+	var info bytes.Buffer
+
+	require.True(t, reportFile{noSave: true}.save(&info, filepath.Join(dir, "default.json"), map[string]int{}))
+	require.Empty(t, info.String())
+	_, err := os.Stat(filepath.Join(dir, "default.json"))
+	require.True(t, os.IsNotExist(err))
+
+	path := filepath.Join(dir, "out", "acme.json")
+	require.True(t, reportFile{path: path}.save(&info, filepath.Join(dir, "default.json"), map[string]int{}))
+	require.Contains(t, info.String(), "Report saved to "+path)
+
+	info.Reset()
+	require.False(t, reportFile{path: path}.save(&info, "", map[string]int{}), "the report exists now")
+	require.Empty(t, info.String())
+}
+
+func TestReportFileSavesPrometheusGauges(t *testing.T) {
+	dir := t.TempDir()
+	f := reportFile{format: scanner.FormatProm}
+	require.Equal(t, filepath.Join(dir, "security_scan_acme.prom"), f.pathFor(filepath.Join(dir, "security_scan_acme.json")))
+
+	result := map[string]interface{}{"org": "acme", "total_repos": 4, "fully_compliant": 3, "compliance_rate": "75.0%"}
+	var info bytes.Buffer
+	require.True(t, f.save(&info, filepath.Join(dir, "security_scan_acme.json"), result))
+	b, err := os.ReadFile(filepath.Join(dir, "security_scan_acme.prom"))
+	require.NoError(t, err)
+	require.Contains(t, string(b), "# TYPE scanner_compliance_rate gauge\nscanner_compliance_rate{org=\"acme\",provider=\"github\"} 0.75\n")
+	require.Contains(t, string(b), "scanner_repos{org=\"acme\",provider=\"github\"} 4\n")
+
+	// --output is taken as given, for the textfile collector's directory.
+	out := filepath.Join(dir, "textfile", "scanner_acme.prom")
+	require.True(t, reportFile{path: out, format: scanner.FormatProm}.save(&info, "", result))
+	require.FileExists(t, out)
+	require.Empty(t, tempFiles(t, filepath.Dir(out)))
+}
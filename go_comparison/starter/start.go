@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+	"os/user"
+)
+
+// localUser is the default --initiated-by: the local account's name.
+func localUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
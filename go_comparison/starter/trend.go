@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// maxMoversShown caps the movers printed by --history; --json has all.
+const maxMoversShown = 10
+
+// trendHistory is where --history reads past scans: the workers' blob
+// store when SCAN_BLOB_STORE is set, otherwise the reports saved in
+// reportsDir.
+func trendHistory(provider, reportsDir string) (scanner.ScanHistory, error) {
+	if uri := os.Getenv("SCAN_BLOB_STORE"); uri != "" {
+		store, err := scanner.OpenBlobStore(uri)
+		if err != nil {
+			return nil, err
+		}
+		return scanner.BlobHistory{Store: store, Provider: provider}, nil
+	}
+	return scanner.DirHistory{Dir: reportsDir}, nil
+}
+
+// showTrend prints org's compliance over its last lastN scans and returns
+// the exit code.
+func showTrend(o output, history scanner.ScanHistory, org string, lastN int) int {
+	t, err := history.GetComplianceTrend(context.Background(), org, lastN)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: reading the scan history of %s: %v\n", org, err)
+		return exitError
+	}
+	o.trend(*t)
+	return exitOK
+}
+
+func (o output) trend(t scanner.ComplianceTrend) {
+	if o.json {
+		o.writeJSON(t)
+		return
+	}
+	printTrend(o.out, t)
+}
+
+func printTrend(w io.Writer, t scanner.ComplianceTrend) {
+	if len(t.Points) == 0 {
+		fmt.Fprintf(w, "No saved scans of %s.\n", t.Org)
+		return
+	}
+	first, last := t.Points[0], t.Points[len(t.Points)-1]
+	rates := make([]float64, len(t.Points))
+	for i, p := range t.Points {
+		rates[i] = p.ComplianceRate
+	}
+	fmt.Fprintf(w, "Compliance trend: %s (last %d scans)\n", t.Org, len(t.Points))
+	fmt.Fprintf(w, "  %s  %.1f%% -> %.1f%% (%+.1f)\n\n", sparkline(rates), first.ComplianceRate, last.ComplianceRate, last.ComplianceRate-first.ComplianceRate)
+
+	fmt.Fprintf(w, "  %-22s %8s %7s %14s  %s\n", "Completed", "Rate", "Repos", "Non-compliant", "By")
+	for _, p := range t.Points {
+		line := fmt.Sprintf("  %-22s %7.1f%% %7d %14d  %s", p.CompletedAt, p.ComplianceRate, p.TotalRepos, len(p.NonCompliant), p.InitiatedBy)
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+
+	if len(last.Adoption) > 0 {
+		checks := make([]string, 0, len(last.Adoption))
+		for c := range last.Adoption {
+			checks = append(checks, c)
+		}
+		sort.Strings(checks)
+		fmt.Fprintln(w, "\n  Check adoption (first -> last scan):")
+		for _, check := range checks {
+			before, ok := first.Adoption[check]
+			if !ok {
+				fmt.Fprintf(w, "    %-26s     new -> %d/%d\n", check, last.Adoption[check], last.TotalRepos)
+				continue
+			}
+			fmt.Fprintf(w, "    %-26s %3d/%-3d -> %d/%d\n", check, before, first.TotalRepos, last.Adoption[check], last.TotalRepos)
+		}
+	}
+
+	if len(t.Movers) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n  Recent flips:")
+	for i, m := range t.Movers {
+		if i == maxMoversShown {
+			fmt.Fprintf(w, "    ... and %d more (--json lists all)\n", len(t.Movers)-i)
+			break
+		}
+		state := "now non-compliant"
+		if m.Compliant {
+			state = "now compliant"
+		}
+		fmt.Fprintf(w, "    %-40s %-18s %s (%d flips)\n", m.Repository, state, m.FlippedAt, m.Flips)
+	}
+}
+
+// sparkBlocks are the sparkline's levels, lowest first.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values scaled between their minimum and maximum, so
+// small moves still show; a flat series is drawn mid-height.
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		level := len(sparkBlocks) / 2
+		if hi > lo {
+			level = int((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+func TestSparkline(t *testing.T) {
+	require.Equal(t, "▁▄█", sparkline([]float64{50, 75, 100}))
+	require.Equal(t, "▅▅", sparkline([]float64{80, 80}))
+	require.Empty(t, sparkline(nil))
+}
+
+func TestShowTrend(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"security_scan_acme_2026-01-05.json": `{"org":"acme","run_id":"run-1","completed_at":"2026-01-05T10:00:00Z","total_repos":2,"fully_compliant":1,"compliance_rate":"50.0%","dependabot_enabled":1,"non_compliant_repos":["api"]}`,
+		"security_scan_acme.json":            `{"org":"acme","run_id":"run-2","completed_at":"2026-01-12T10:00:00Z","total_repos":2,"fully_compliant":2,"compliance_rate":"100.0%","dependabot_enabled":2,"non_compliant_repos":[]}`,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644))
+	}
+	history := scanner.DirHistory{Dir: dir}
+
+	o, out, _ := testOutput(false)
+	require.Equal(t, exitOK, showTrend(o, history, "acme", 12))
+	text := out.String()
+	require.Contains(t, text, "Compliance trend: acme (last 2 scans)")
+	require.Contains(t, text, "▁█  50.0% -> 100.0% (+50.0)")
+	require.Contains(t, text, "dependabot                   1/2   -> 2/2")
+	require.Regexp(t, `api\s+now compliant\s+2026-01-12T10:00:00Z \(1 flips\)`, text)
+
+	o, out, _ = testOutput(true)
+	require.Equal(t, exitOK, showTrend(o, history, "acme", 1))
+	var trend scanner.ComplianceTrend
+	require.NoError(t, json.Unmarshal(out.Bytes(), &trend))
+	require.Len(t, trend.Points, 1)
+	require.Equal(t, "run-2", trend.Points[0].RunID)
+
+	o, out, _ = testOutput(false)
+	require.Equal(t, exitOK, showTrend(o, history, "globex", 12))
+	require.Equal(t, "No saved scans of globex.\n", out.String())
+
+	o, _, _ = testOutput(false)
+	require.Equal(t, exitError, showTrend(o, failingHistory{}, "acme", 12))
+}
+
+func TestPrintTrendCapsMovers(t *testing.T) {
+	var movers []scanner.RepoMover
+	for i := 0; i < maxMoversShown+3; i++ {
+		movers = append(movers, scanner.RepoMover{Repository: "repo", FlippedAt: "2026-01-12T10:00:00Z", Flips: 1})
+	}
+	var b strings.Builder
+	printTrend(&b, scanner.ComplianceTrend{Org: "acme", Points: []scanner.TrendPoint{{}, {}}, Movers: movers})
+	require.Equal(t, maxMoversShown, strings.Count(b.String(), "now non-compliant"))
+	require.Contains(t, b.String(), "... and 3 more (--json lists all)")
+}
+
+type failingHistory struct{}
+
+func (failingHistory) GetComplianceTrend(context.Context, string, int) (*scanner.ComplianceTrend, error) {
+	return nil, errors.New("store unavailable")
+}
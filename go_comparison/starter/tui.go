@@ -0,0 +1,554 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// --tui is a full-screen view of a running scan. Everything on it comes
+// from the scan's progress and results_page queries, and its keys send the
+// scan's own signals, so it works on any scan the starter can name, started
+// from here or not. tuiState holds what is shown: update applies an event
+// and says which signals to send, and view draws it on a screen of a given
+// size. Neither does I/O; runTUI and doTUI connect them to the server and
+// the terminal.
+
+const (
+	// tuiPollInterval is how often --tui queries the scan.
+	tuiPollInterval = time.Second
+	// tuiQueryTimeout bounds each of those queries.
+	tuiQueryTimeout = 10 * time.Second
+	// tuiKeep is how many completed repos and errors --tui remembers.
+	tuiKeep = 200
+	// tuiErrorLines is how many of the errors the error ticker shows.
+	tuiErrorLines = 3
+	// tuiMaxReason bounds the cancel reason typed at the prompt.
+	tuiMaxReason = 200
+	// tuiDefaultReason is the cancel reason when none is typed.
+	tuiDefaultReason = "Cancelled from --tui"
+)
+
+// Keys --tui reads besides letters.
+const (
+	keyCtrlC     = 0x03
+	keyBackspace = 0x08
+	keyEnter     = '\r'
+	keyEscape    = 0x1b
+	keyDelete    = 0x7f
+)
+
+// Colors of the view; none are used without color.
+const (
+	tuiReset  = "\x1b[0m"
+	tuiBold   = "\x1b[1m"
+	tuiRed    = "\x1b[31m"
+	tuiGreen  = "\x1b[32m"
+	tuiYellow = "\x1b[33m"
+)
+
+// tuiMode is what keys mean at the moment.
+type tuiMode int
+
+const (
+	tuiNormal        tuiMode = iota
+	tuiConfirmCancel         // c was pressed; y goes on to the reason
+	tuiCancelReason          // typing the cancel reason
+)
+
+// tuiEvent is one of tuiProgress, tuiResults, tuiKey, tuiSent and
+// tuiPollError.
+type tuiEvent interface{}
+
+// tuiProgress is an answer to the progress query.
+type tuiProgress scanner.ScanProgress
+
+// tuiResults is an answer to results_page.
+type tuiResults scanner.ResultsPage
+
+// tuiKey is a key pressed.
+type tuiKey rune
+
+// tuiSent is the outcome of sending a signal update asked for.
+type tuiSent struct {
+	signal string
+	err    error
+}
+
+// tuiPollError is a query that failed.
+type tuiPollError struct{ err error }
+
+// tuiSignal is a signal to send to the scan.
+type tuiSignal struct {
+	name string
+	arg  interface{}
+}
+
+// tuiState is what --tui shows.
+type tuiState struct {
+	org, workflowID string
+
+	progress scanner.ScanProgress
+	answered bool // the progress query has answered at least once
+	// offset is the next results_page offset: the results read so far.
+	offset int
+	recent []scanner.RepoSecurityResult // completed repos, newest last
+	errors []string                     // repos that errored, newest last
+
+	mode   tuiMode
+	reason []rune
+	// notice says what the last key did; pollErr why the view may be
+	// stale.
+	notice, pollErr string
+	quit            bool
+}
+
+func newTUIState(org, workflowID string) *tuiState {
+	return &tuiState{org: org, workflowID: workflowID}
+}
+
+// update applies ev and returns the signals to send.
+func (s *tuiState) update(ev tuiEvent) []tuiSignal {
+	switch ev := ev.(type) {
+	case tuiProgress:
+		s.progress, s.answered, s.pollErr = scanner.ScanProgress(ev), true, ""
+	case tuiResults:
+		s.addResults(scanner.ResultsPage(ev))
+	case tuiPollError:
+		s.pollErr = "Query failed: " + ev.err.Error()
+	case tuiSent:
+		s.notice = sentNotice(ev)
+	case tuiKey:
+		return s.key(rune(ev))
+	}
+	return nil
+}
+
+// addResults appends a results_page answer. Once the scan offloads its
+// results to the blob store it holds fewer inline than were read, and
+// reading starts again from the first it still holds.
+func (s *tuiState) addResults(page scanner.ResultsPage) {
+	if page.Total < s.offset {
+		s.offset = 0
+		return
+	}
+	for _, r := range page.Results {
+		if r.Error != nil {
+			s.errors = appendKept(s.errors, r.Repository+": "+*r.Error)
+		}
+	}
+	s.recent = append(s.recent, page.Results...)
+	if len(s.recent) > tuiKeep {
+		s.recent = append([]scanner.RepoSecurityResult(nil), s.recent[len(s.recent)-tuiKeep:]...)
+	}
+	s.offset = page.Offset + len(page.Results)
+}
+
+func appendKept(lines []string, line string) []string {
+	lines = append(lines, line)
+	if len(lines) > tuiKeep {
+		lines = append([]string(nil), lines[len(lines)-tuiKeep:]...)
+	}
+	return lines
+}
+
+func sentNotice(ev tuiSent) string {
+	if ev.err != nil {
+		return fmt.Sprintf("Sending %s failed: %v", ev.signal, ev.err)
+	}
+	switch ev.signal {
+	case scanner.PauseScanSignal:
+		return "Pause sent: the scan holds before its next batch."
+	case scanner.ResumeScanSignal:
+		return "Resume sent."
+	default:
+		return "Cancel sent: the scan stops after the current batch and writes a partial report."
+	}
+}
+
+// key applies a key press in the current mode.
+func (s *tuiState) key(k rune) []tuiSignal {
+	if k == keyCtrlC {
+		s.quit = true
+		return nil
+	}
+	switch s.mode {
+	case tuiConfirmCancel:
+		if k == 'y' || k == 'Y' {
+			s.mode, s.reason = tuiCancelReason, nil
+			return nil
+		}
+		s.mode, s.notice = tuiNormal, "Not cancelled."
+	case tuiCancelReason:
+		switch {
+		case k == keyEnter || k == '\n':
+			reason := strings.TrimSpace(string(s.reason))
+			if reason == "" {
+				reason = tuiDefaultReason
+			}
+			s.mode, s.reason = tuiNormal, nil
+			return []tuiSignal{{"cancel_scan", reason}}
+		case k == keyEscape:
+			s.mode, s.reason, s.notice = tuiNormal, nil, "Not cancelled."
+		case k == keyBackspace || k == keyDelete:
+			if len(s.reason) > 0 {
+				s.reason = s.reason[:len(s.reason)-1]
+			}
+		case unicode.IsPrint(k) && len(s.reason) < tuiMaxReason:
+			s.reason = append(s.reason, k)
+		}
+	default:
+		switch k {
+		case 'q', 'Q':
+			s.quit = true
+		case 'p', 'P', 'r', 'R', 'c', 'C':
+			if s.progress.Status.IsTerminal() {
+				s.notice = "The scan has finished."
+				return nil
+			}
+			switch unicode.ToLower(k) {
+			case 'p':
+				return []tuiSignal{{scanner.PauseScanSignal, nil}}
+			case 'r':
+				return []tuiSignal{{scanner.ResumeScanSignal, nil}}
+			default:
+				s.mode = tuiConfirmCancel
+			}
+		}
+	}
+	return nil
+}
+
+// view draws the state on a width×height screen, one string per row,
+// each exactly width runes wide before any color. Without color the same
+// words are shown, so nothing depends on color to be read.
+func (s *tuiState) view(width, height int, color bool) []string {
+	paint := func(code, text string) string { return tuiPaint(color, code, text) }
+	p := s.progress
+	status := "waiting for the scan"
+	if s.answered {
+		status = string(p.Status)
+	}
+
+	var top []string
+	top = append(top, paint(tuiBold, spread(fmt.Sprintf("Security scan: %s (%s)", s.org, s.workflowID), "["+status+"]", width)))
+	top = append(top, s.bar(width, color))
+	counts := fmt.Sprintf("Compliant %d  Non-compliant %d  Indeterminate %d  Errors %d",
+		p.CompliantRepos, p.NonCompliantRepos, p.IndeterminateRepos, p.Errors)
+	if p.Batches > 0 {
+		counts = fmt.Sprintf("Batch %d of %d  ", p.Batch, p.Batches) + counts
+	}
+	top = append(top, fit(counts, width))
+	state, stateColor := s.stateLine()
+	top = append(top, paint(stateColor, fit(state, width)))
+
+	errorRows := min(len(s.errors), tuiErrorLines)
+	bottom := []string{rule(fmt.Sprintf("Errors (%d)", len(s.errors)), width)}
+	if errorRows == 0 {
+		bottom = append(bottom, fit("  none", width))
+	}
+	for _, e := range s.errors[len(s.errors)-errorRows:] {
+		bottom = append(bottom, paint(tuiRed, fit("  "+e, width)))
+	}
+	bottom = append(bottom, rule("", width))
+	notice, noticeColor := s.notice, ""
+	if s.pollErr != "" {
+		notice, noticeColor = s.pollErr, tuiYellow
+	}
+	bottom = append(bottom, paint(noticeColor, fit(notice, width)), fit(s.prompt(), width))
+
+	// Recent repos get the rows left over, newest first.
+	rows := max(height-len(top)-len(bottom)-1, 0)
+	middle := []string{rule(fmt.Sprintf("Recent repos (%d read)", s.offset), width)}
+	for i := len(s.recent) - 1; i >= 0 && len(middle) <= rows; i-- {
+		text, c := repoLine(&s.recent[i])
+		middle = append(middle, paint(c, fit("  "+text, width)))
+	}
+	for len(middle) <= rows {
+		middle = append(middle, fit("", width))
+	}
+
+	lines := append(append(top, middle...), bottom...)
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return lines
+}
+
+// bar is the progress bar row: repos scanned over total, or the listing so
+// far while the repos are listed.
+func (s *tuiState) bar(width int, color bool) string {
+	p := s.progress
+	if p.Status == scanner.ScanStarting || p.Status == scanner.ScanFetchingRepos {
+		return fit(listingProgress(p), width)
+	}
+	suffix := fmt.Sprintf("  %d/%d repos  %5.1f%%", p.ScannedRepos, p.TotalRepos, p.PercentComplete())
+	inner := width - 2 - utf8.RuneCountInString(suffix)
+	if inner < 1 {
+		return fit(strings.TrimSpace(suffix), width)
+	}
+	filled := 0
+	if p.TotalRepos > 0 {
+		filled = min(inner*p.ScannedRepos/p.TotalRepos, inner)
+	}
+	done, todo := "#", "-"
+	if color {
+		done, todo = "█", "░"
+	}
+	return "[" + tuiPaint(color, tuiGreen, strings.Repeat(done, filled)) + strings.Repeat(todo, inner-filled) + "]" + suffix
+}
+
+func tuiPaint(color bool, code, text string) string {
+	if !color || code == "" {
+		return text
+	}
+	return code + text + tuiReset
+}
+
+// stateLine explains a scan that is not simply scanning.
+func (s *tuiState) stateLine() (string, string) {
+	p := s.progress
+	switch {
+	case p.Status.IsTerminal():
+		return "The scan has finished (" + string(p.Status) + "); press q to quit.", tuiGreen
+	case p.Status == scanner.ScanHeld:
+		return fmt.Sprintf("Held before batch %d; press r to resume.", p.Batch+1), tuiYellow
+	case p.PauseRequested:
+		return "Pause requested: the scan holds when this batch finishes.", tuiYellow
+	case p.Status == scanner.ScanPaused && p.NextBatchAt != nil:
+		return "Sleeping between batches until " + p.NextBatchAt.Local().Format("15:04:05") + ".", ""
+	}
+	return "", ""
+}
+
+// prompt is the bottom row: the keys, or the cancel confirmation.
+func (s *tuiState) prompt() string {
+	switch s.mode {
+	case tuiConfirmCancel:
+		return "Cancel the scan? It stops after the current batch. [y/N]"
+	case tuiCancelReason:
+		return "Reason: " + string(s.reason) + "_  (Enter sends, Esc aborts)"
+	}
+	return "p pause  r resume  c cancel  q quit (the scan keeps running)"
+}
+
+// repoLine describes a completed repo and the color to show it in.
+func repoLine(r *scanner.RepoSecurityResult) (string, string) {
+	switch {
+	case r.Disappeared != nil:
+		return r.Repository + "  disappeared", tuiYellow
+	case r.Error != nil:
+		return r.Repository + "  error: " + *r.Error, tuiRed
+	}
+	switch scanner.DefaultCompliancePolicy().Outcome(r) {
+	case scanner.OutcomeCompliant:
+		return r.Repository + "  compliant", tuiGreen
+	case scanner.OutcomeIndeterminate:
+		return r.Repository + "  indeterminate", tuiYellow
+	}
+	return r.Repository + "  non-compliant", tuiRed
+}
+
+// fit pads or cuts text to width runes, marking a cut with "…".
+func fit(text string, width int) string {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+	n := utf8.RuneCountInString(text)
+	if n <= width {
+		return text + strings.Repeat(" ", width-n)
+	}
+	if width < 1 {
+		return ""
+	}
+	return string([]rune(text)[:width-1]) + "…"
+}
+
+// spread puts left and right at either end of a width-rune row, cutting
+// left if they do not both fit.
+func spread(left, right string, width int) string {
+	gap := width - utf8.RuneCountInString(right)
+	if gap < 2 {
+		return fit(left, width)
+	}
+	return fit(left, gap-1) + " " + right
+}
+
+// rule is a horizontal line, titled unless title is empty.
+func rule(title string, width int) string {
+	if title != "" {
+		title = "─ " + title + " "
+	}
+	n := utf8.RuneCountInString(title)
+	if n >= width {
+		return fit(title, width)
+	}
+	return title + strings.Repeat("─", width-n)
+}
+
+// tuiClient is the part of client.Client that --tui uses.
+type tuiClient interface {
+	resultsQuerier
+	SignalWorkflow(ctx context.Context, workflowID, runID, signalName string, arg interface{}) error
+}
+
+// runTUI polls the scan on each tick and sends the signals keys ask for,
+// drawing after each, until q or until keys closes.
+func runTUI(ctx context.Context, c tuiClient, s *tuiState, keys <-chan rune, tick <-chan time.Time, draw func(*tuiState)) {
+	pollTUI(ctx, c, s)
+	draw(s)
+	for !s.quit {
+		select {
+		case <-ctx.Done():
+			return
+		case k, ok := <-keys:
+			if !ok {
+				return
+			}
+			for _, sig := range s.update(tuiKey(k)) {
+				err := c.SignalWorkflow(ctx, s.workflowID, "", sig.name, sig.arg)
+				s.update(tuiSent{sig.name, err})
+			}
+		case <-tick:
+			pollTUI(ctx, c, s)
+		}
+		draw(s)
+	}
+}
+
+// pollTUI queries the scan's progress and the results completed since the
+// last poll.
+func pollTUI(ctx context.Context, c tuiClient, s *tuiState) {
+	ctx, cancel := context.WithTimeout(ctx, tuiQueryTimeout)
+	defer cancel()
+	var p scanner.ScanProgress
+	resp, err := c.QueryWorkflow(ctx, s.workflowID, "", "progress")
+	if err == nil {
+		err = resp.Get(&p)
+	}
+	if err != nil {
+		s.update(tuiPollError{err})
+		return
+	}
+	s.update(tuiProgress(p))
+	for {
+		page, err := queryResults(ctx, c, s.workflowID, s.offset, scanner.DefaultResultsPageSize)
+		if err != nil {
+			s.update(tuiPollError{err})
+			return
+		}
+		s.update(tuiResults(page))
+		if page.NextOffset == 0 {
+			return
+		}
+	}
+}
+
+// doTUI runs --tui on the terminal until q, leaving the scan running.
+// The terminal is put in raw mode, so keys arrive as they are pressed, and
+// drawn on the alternate screen, which is restored on exit. Without a
+// terminal, or when raw mode cannot be set, it falls back to watchPlain.
+func doTUI(c tuiClient, workflowID, org string, noColor bool) int {
+	ticker := time.NewTicker(tuiPollInterval)
+	defer ticker.Stop()
+	s := newTUIState(org, workflowID)
+
+	fd := int(os.Stdin.Fd())
+	if !isTerminal(os.Stdout) {
+		fmt.Fprintln(os.Stderr, "--tui: stdout is not a terminal; printing progress lines instead")
+		return watchPlain(context.Background(), c, s, ticker.C, os.Stdout)
+	}
+	saved, err := term.MakeRaw(fd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--tui: cannot set raw mode (%v); printing progress lines instead\n", err)
+		return watchPlain(context.Background(), c, s, ticker.C, os.Stdout)
+	}
+	color := !noColor && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+
+	keys := make(chan rune)
+	go func() {
+		defer close(keys)
+		in := bufio.NewReader(os.Stdin)
+		for {
+			r, _, err := in.ReadRune()
+			if err != nil {
+				return
+			}
+			keys <- r
+		}
+	}()
+
+	runTUI(context.Background(), c, s, keys, ticker.C, func(s *tuiState) {
+		width, height := terminalSize()
+		fmt.Print("\x1b[H" + strings.Join(s.view(width, height, color), "\x1b[K\r\n") + "\x1b[K\x1b[J")
+	})
+
+	fmt.Print("\x1b[?25h\x1b[?1049l")
+	_ = term.Restore(fd, saved)
+	fmt.Printf("Left %s running. Wait for its report with: %s\n", workflowID, reattachCommand(workflowID, "", 0))
+	return exitOK
+}
+
+// watchPlain is --tui without a screen to draw on: it prints a line of
+// progress whenever it changes, until the scan ends. Keys are not read;
+// Ctrl-C stops watching and leaves the scan running.
+func watchPlain(ctx context.Context, c tuiClient, s *tuiState, tick <-chan time.Time, out io.Writer) int {
+	last := ""
+	for {
+		pollTUI(ctx, c, s)
+		if line := s.plainLine(); line != last {
+			fmt.Fprintln(out, line)
+			last = line
+		}
+		if s.answered && s.progress.Status.IsTerminal() {
+			return exitOK
+		}
+		select {
+		case <-ctx.Done():
+			return exitOK
+		case <-tick:
+		}
+	}
+}
+
+// plainLine is the scan's state as watchPlain prints it.
+func (s *tuiState) plainLine() string {
+	switch {
+	case s.pollErr != "":
+		return s.pollErr
+	case !s.answered:
+		return "Waiting for the scan to answer..."
+	}
+	p := s.progress
+	if p.Status == scanner.ScanStarting || p.Status == scanner.ScanFetchingRepos {
+		return fmt.Sprintf("%s  %s", p.Status, listingProgress(p))
+	}
+	return fmt.Sprintf("%s  %d/%d repos  %.1f%%  Errors %d", p.Status, p.ScannedRepos, p.TotalRepos, p.PercentComplete(), p.Errors)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalSize is the terminal's size, or 80×24 if it cannot be read.
+func terminalSize() (width, height int) {
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 && h > 0 {
+		return w, h
+	}
+	return 80, 24
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite testdata/tui golden files")
+
+func errText(s string) *string { return &s }
+
+// tuiFixture is a scan held after its second batch, with a repo of each
+// outcome read.
+func tuiFixture() *tuiState {
+	s := newTUIState("acme", "security-scan-acme")
+	s.update(tuiProgress{
+		Org: "acme", Status: scanner.ScanHeld, PauseRequested: true, Batch: 2, Batches: 5,
+		TotalRepos: 48, ScannedRepos: 20, CompliantRepos: 15, NonCompliantRepos: 3, IndeterminateRepos: 1, Errors: 1,
+	})
+	enabled := scanner.CheckResult{Status: scanner.StatusEnabled}
+	compliant := map[string]scanner.CheckResult{
+		scanner.CheckSecretScanning: enabled, scanner.CheckDependabot: enabled, scanner.CheckCodeScanning: enabled,
+	}
+	s.update(tuiResults{Total: 4, Results: []scanner.RepoSecurityResult{
+		{Repository: "api", Checks: compliant},
+		{Repository: "web", Checks: map[string]scanner.CheckResult{
+			scanner.CheckSecretScanning: enabled, scanner.CheckDependabot: {Status: scanner.StatusDisabled}, scanner.CheckCodeScanning: enabled,
+		}},
+		{Repository: "billing", Error: errText("403 Forbidden: Resource not accessible by integration")},
+		{Repository: "docs", Disappeared: &scanner.DisappearedRepo{}},
+	}})
+	return s
+}
+
+func TestTUIViewGolden(t *testing.T) {
+	prompt := tuiFixture()
+	for _, k := range "cyfreeze" {
+		prompt.update(tuiKey(k))
+	}
+	starting := newTUIState("acme", "security-scan-acme")
+	starting.update(tuiPollError{errors.New("context deadline exceeded")})
+
+	for name, tc := range map[string]struct {
+		state         *tuiState
+		width, height int
+		color         bool
+	}{
+		"held":          {tuiFixture(), 80, 20, false},
+		"held-color":    {tuiFixture(), 80, 20, true},
+		"cancel-prompt": {prompt, 80, 20, false},
+		"narrow":        {tuiFixture(), 40, 14, false},
+		"starting":      {starting, 80, 16, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			lines := tc.state.view(tc.width, tc.height, tc.color)
+			require.Len(t, lines, tc.height)
+			if !tc.color {
+				for i, line := range lines {
+					require.Equal(t, tc.width, utf8.RuneCountInString(line), "row %d: %q", i, line)
+				}
+			}
+			got := strings.Join(lines, "\n") + "\n"
+			path := filepath.Join("testdata", "tui", name+".golden")
+			if *updateGolden {
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+			}
+			want, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, string(want), got)
+		})
+	}
+}
+
+func TestTUIViewWithoutColorHasNoEscapes(t *testing.T) {
+	for _, line := range tuiFixture().view(80, 20, false) {
+		require.NotContains(t, line, "\x1b")
+	}
+	require.Contains(t, strings.Join(tuiFixture().view(80, 20, true), "\n"), tuiGreen)
+}
+
+func TestTUIKeys(t *testing.T) {
+	s := tuiFixture()
+	require.Equal(t, []tuiSignal{{scanner.PauseScanSignal, nil}}, s.update(tuiKey('p')))
+	require.Equal(t, []tuiSignal{{scanner.ResumeScanSignal, nil}}, s.update(tuiKey('r')))
+
+	// c asks first; anything but y backs out.
+	require.Nil(t, s.update(tuiKey('c')))
+	require.Equal(t, tuiConfirmCancel, s.mode)
+	require.Nil(t, s.update(tuiKey('n')))
+	require.Equal(t, tuiNormal, s.mode)
+	require.Equal(t, "Not cancelled.", s.notice)
+
+	// Keys typed at the reason prompt are the reason, not commands.
+	s.update(tuiKey('c'))
+	s.update(tuiKey('y'))
+	for _, k := range "freezeq" {
+		require.Nil(t, s.update(tuiKey(k)))
+	}
+	require.False(t, s.quit)
+	s.update(tuiKey(keyDelete))
+	require.Equal(t, []tuiSignal{{"cancel_scan", "freeze"}}, s.update(tuiKey(keyEnter)))
+	require.Equal(t, tuiNormal, s.mode)
+
+	// An empty reason gets a default; Esc aborts.
+	s.update(tuiKey('c'))
+	s.update(tuiKey('y'))
+	require.Equal(t, []tuiSignal{{"cancel_scan", tuiDefaultReason}}, s.update(tuiKey(keyEnter)))
+	s.update(tuiKey('c'))
+	s.update(tuiKey('y'))
+	s.update(tuiKey('x'))
+	require.Nil(t, s.update(tuiKey(keyEscape)))
+	require.Equal(t, tuiNormal, s.mode)
+	require.Empty(t, s.reason)
+
+	require.Nil(t, s.update(tuiKey('q')))
+	require.True(t, s.quit)
+}
+
+func TestTUIKeysAfterTheScanFinished(t *testing.T) {
+	s := tuiFixture()
+	s.update(tuiProgress{Status: scanner.ScanCompleted})
+	for _, k := range "prc" {
+		require.Nil(t, s.update(tuiKey(k)))
+		require.Equal(t, tuiNormal, s.mode)
+	}
+	require.Equal(t, "The scan has finished.", s.notice)
+}
+
+func TestTUISentNotices(t *testing.T) {
+	s := tuiFixture()
+	s.update(tuiSent{scanner.PauseScanSignal, nil})
+	require.Contains(t, s.notice, "holds before its next batch")
+	s.update(tuiSent{"cancel_scan", errors.New("workflow not found")})
+	require.Equal(t, "Sending cancel_scan failed: workflow not found", s.notice)
+}
+
+func TestTUIResults(t *testing.T) {
+	s := tuiFixture()
+	require.Equal(t, 4, s.offset)
+	require.Equal(t, []string{"billing: 403 Forbidden: Resource not accessible by integration"}, s.errors)
+
+	// Offloading leaves fewer results inline than were read; reading
+	// starts over from the first still held.
+	s.update(tuiResults{Offset: 2, Total: 2})
+	require.Zero(t, s.offset)
+	s.update(tuiResults{Total: 1, Results: []scanner.RepoSecurityResult{{Repository: "cli"}}})
+	require.Equal(t, 1, s.offset)
+	require.Equal(t, "cli", s.recent[len(s.recent)-1].Repository)
+
+	for i := 0; i < tuiKeep; i++ {
+		s.update(tuiResults{Offset: s.offset, Total: s.offset + 1, Results: []scanner.RepoSecurityResult{
+			{Repository: fmt.Sprintf("svc-%03d", i), Error: errText("timeout")},
+		}})
+	}
+	require.Len(t, s.recent, tuiKeep)
+	require.Len(t, s.errors, tuiKeep)
+	require.Equal(t, "svc-199: timeout", s.errors[len(s.errors)-1])
+}
+
+// fakeTUIScan answers progress and results_page for a scan and records
+// the signals sent to it.
+type fakeTUIScan struct {
+	t        *testing.T
+	progress scanner.ScanProgress
+	results  []scanner.RepoSecurityResult
+	signals  []tuiSignal
+}
+
+func (f *fakeTUIScan) QueryWorkflow(_ context.Context, workflowID, runID, queryType string, args ...interface{}) (converter.EncodedValue, error) {
+	require.Equal(f.t, "security-scan-acme", workflowID)
+	require.Empty(f.t, runID)
+	var v interface{}
+	switch queryType {
+	case "progress":
+		v = f.progress
+	case scanner.ResultsPageQuery:
+		v = scanner.PageResults(f.results, args[0].(int), args[1].(int))
+	default:
+		f.t.Fatalf("unexpected query %s", queryType)
+	}
+	b, err := json.Marshal(v)
+	return encodedJSON(b), err
+}
+
+func (f *fakeTUIScan) SignalWorkflow(_ context.Context, workflowID, runID, signalName string, arg interface{}) error {
+	require.Equal(f.t, "security-scan-acme", workflowID)
+	require.Empty(f.t, runID)
+	f.signals = append(f.signals, tuiSignal{signalName, arg})
+	return nil
+}
+
+func TestRunTUI(t *testing.T) {
+	scan := &fakeTUIScan{t: t, progress: scanner.ScanProgress{Status: scanner.ScanScanning, TotalRepos: 500, ScannedRepos: 450}}
+	for i := 0; i < 450; i++ {
+		scan.results = append(scan.results, scanner.RepoSecurityResult{Repository: fmt.Sprintf("svc-%03d", i)})
+	}
+	keys := make(chan rune, 16)
+	tick := make(chan time.Time)
+	for _, k := range "pcyfreeze\r" {
+		keys <- k
+	}
+	drawn := make(chan struct{}, 32)
+	done := make(chan struct{})
+	s := newTUIState("acme", "security-scan-acme")
+	go func() {
+		defer close(done)
+		runTUI(context.Background(), scan, s, keys, tick, func(*tuiState) { drawn <- struct{}{} })
+	}()
+
+	// The first poll reads every result, a page at a time; the next reads
+	// only the new ones.
+	<-drawn
+	scan.results = append(scan.results[:450:450], scanner.RepoSecurityResult{Repository: "svc-450"})
+	tick <- time.Now()
+	keys <- 'q'
+	<-done
+
+	require.Equal(t, []tuiSignal{{scanner.PauseScanSignal, nil}, {"cancel_scan", "freeze"}}, scan.signals)
+	require.Equal(t, 451, s.offset)
+	require.Equal(t, "svc-450", s.recent[len(s.recent)-1].Repository)
+	require.Equal(t, 450, s.progress.ScannedRepos)
+	require.Len(t, drawn, len("pcyfreeze\r")+2)
+}
+
+// progressSeq is a fakeTUIScan whose progress query answers with each of
+// seq in turn, then the last one.
+type progressSeq struct {
+	*fakeTUIScan
+	seq []scanner.ScanProgress
+}
+
+func (f *progressSeq) QueryWorkflow(ctx context.Context, workflowID, runID, queryType string, args ...interface{}) (converter.EncodedValue, error) {
+	if queryType == "progress" {
+		f.progress = f.seq[0]
+		if len(f.seq) > 1 {
+			f.seq = f.seq[1:]
+		}
+	}
+	return f.fakeTUIScan.QueryWorkflow(ctx, workflowID, runID, queryType, args...)
+}
+
+func TestWatchPlain(t *testing.T) {
+	scan := &progressSeq{fakeTUIScan: &fakeTUIScan{t: t}, seq: []scanner.ScanProgress{
+		{Status: scanner.ScanFetchingRepos, ReposListed: 100},
+		{Status: scanner.ScanScanning, TotalRepos: 20, ScannedRepos: 5},
+		{Status: scanner.ScanScanning, TotalRepos: 20, ScannedRepos: 5},
+		{Status: scanner.ScanScanning, TotalRepos: 20, ScannedRepos: 20, Errors: 1},
+		{Status: scanner.ScanCompleted, TotalRepos: 20, ScannedRepos: 20, Errors: 1},
+	}}
+	tick := make(chan time.Time, 10)
+	for i := 0; i < cap(tick); i++ {
+		tick <- time.Now()
+	}
+
+	var out strings.Builder
+	code := watchPlain(context.Background(), scan, newTUIState("acme", "security-scan-acme"), tick, &out)
+
+	require.Equal(t, exitOK, code)
+	require.Equal(t, strings.Join([]string{
+		"fetching_repos  listing repos (100 so far)",
+		"scanning  5/20 repos  25.0%  Errors 0",
+		"scanning  20/20 repos  100.0%  Errors 1",
+		"completed  20/20 repos  100.0%  Errors 1",
+	}, "\n")+"\n", out.String(), "an unchanged poll prints nothing")
+	require.Len(t, tick, 6, "it stops once the scan has ended")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"go.temporal.io/sdk/temporal"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// printError writes err after prefix, e.g. "Error: ...". The problems of
+// an invalid input, found by Validate before the start or by the workflow
+// after it, are listed one per line.
+func printError(w io.Writer, prefix string, err error) {
+	v := invalidInput(err)
+	if v == nil {
+		fmt.Fprintf(w, "%s: %v\n", prefix, err)
+		return
+	}
+	fmt.Fprintf(w, "%s: invalid %s:\n", prefix, v.Input)
+	for _, fe := range v.Errors {
+		fmt.Fprintf(w, "  - %s: %s\n", fe.Field, fe.Problem)
+	}
+}
+
+// invalidInput is the validation error in err, or nil.
+func invalidInput(err error) *scanner.ValidationError {
+	var v *scanner.ValidationError
+	if errors.As(err, &v) {
+		return v
+	}
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) && appErr.Type() == scanner.ErrTypeInvalidInput && appErr.HasDetails() {
+		var details scanner.ValidationError
+		if appErr.Details(&details) == nil && len(details.Errors) > 0 {
+			return &details
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+func TestPrintError(t *testing.T) {
+	invalid := scanner.ScanInput{Org: "acme", Repos: []string{"globex/api"}, Concurrency: -1}.Validate()
+	want := "Error: invalid scan input:\n" +
+		"  - repos[0]: repo \"globex/api\" is not in org \"acme\"\n" +
+		"  - concurrency: concurrency must not be negative, got -1\n"
+
+	var buf bytes.Buffer
+	printError(&buf, "Error", invalid)
+	require.Equal(t, want, buf.String())
+
+	// The same, from the workflow's failure.
+	var v *scanner.ValidationError
+	require.True(t, errors.As(invalid, &v))
+	failed := fmt.Errorf("workflow execution error: %w",
+		temporal.NewNonRetryableApplicationError(invalid.Error(), scanner.ErrTypeInvalidInput, nil, *v))
+	buf.Reset()
+	printError(&buf, "Error", failed)
+	require.Equal(t, want, buf.String())
+
+	// Anything else prints as before.
+	buf.Reset()
+	printError(&buf, "Workflow failed", temporal.NewNonRetryableApplicationError("invalid org", scanner.ErrTypeInvalidInput, nil))
+	require.Equal(t, "Workflow failed: invalid org (type: INVALID_INPUT, retryable: false)\n", buf.String())
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+)
+
+// waitCheckInterval is how often waitForScan asks the server whether the
+// scan was closed without a result.
+const waitCheckInterval = 30 * time.Second
+
+// errWaitTimeout means --wait-timeout passed with the scan still running.
+var errWaitTimeout = errors.New("the scan is still running")
+
+// scanClosedError is a scan that closed without a result: terminated,
+// timed out or cancelled by another client.
+type scanClosedError struct {
+	Status enums.WorkflowExecutionStatus
+}
+
+func (e *scanClosedError) Error() string {
+	return "the scan was closed with status " + e.Status.String()
+}
+
+// scanDescriber is the part of client.Client that waitForScan uses.
+type scanDescriber interface {
+	DescribeWorkflowExecution(ctx context.Context, workflowID, runID string) (*workflowservice.DescribeWorkflowExecutionResponse, error)
+}
+
+// waitForScan waits for run's result, at most timeout when it is not 0,
+// and returns errWaitTimeout when that passes first. Every checkEvery it
+// describes the run, so a scan terminated or timed out meanwhile is
+// reported as a *scanClosedError with its close status.
+func waitForScan(ctx context.Context, c scanDescriber, run client.WorkflowRun, timeout, checkEvery time.Duration, result interface{}) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	getCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	done := make(chan error, 1)
+	go func() { done <- run.Get(getCtx, result) }()
+
+	check := time.NewTicker(checkEvery)
+	defer check.Stop()
+	for {
+		select {
+		case err := <-done:
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return errWaitTimeout
+			}
+			return err
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return errWaitTimeout
+			}
+			return ctx.Err()
+		case <-check.C:
+			resp, err := c.DescribeWorkflowExecution(ctx, run.GetID(), run.GetRunID())
+			if err != nil {
+				continue // Get reports the errors that matter
+			}
+			switch status := resp.GetWorkflowExecutionInfo().GetStatus(); status {
+			case enums.WORKFLOW_EXECUTION_STATUS_TERMINATED,
+				enums.WORKFLOW_EXECUTION_STATUS_TIMED_OUT,
+				enums.WORKFLOW_EXECUTION_STATUS_CANCELED:
+				return &scanClosedError{Status: status}
+			}
+		}
+	}
+}
+
+// reattachCommand is the starter command that resumes waiting for a run.
+func reattachCommand(workflowID, runID string, timeout time.Duration) string {
+	cmd := "go run ./go_comparison/starter --workflow-id " + workflowID + " --attach"
+	if runID != "" {
+		cmd += " --run-id " + runID
+	}
+	if timeout > 0 {
+		cmd += " --wait-timeout " + timeout.String()
+	}
+	return cmd
+}
+
+// waitStopped prints to w why waiting for run ended without a report and
+// returns the exit code; false when err is not from waitForScan itself.
+func waitStopped(w io.Writer, run client.WorkflowRun, err error, timeout time.Duration) (int, bool) {
+	var closed *scanClosedError
+	switch {
+	case errors.Is(err, errWaitTimeout):
+		fmt.Fprintf(w, "Still running after %s; stopped waiting. The scan carries on.\n", timeout)
+		fmt.Fprintf(w, "  Re-attach: %s\n", reattachCommand(run.GetID(), run.GetRunID(), timeout))
+		return exitStillRunning, true
+	case errors.As(err, &closed):
+		fmt.Fprintf(w, "Scan %s: %v; there is no report.\n", run.GetID(), err)
+		if closed.Status == enums.WORKFLOW_EXECUTION_STATUS_CANCELED {
+			return exitCancelled, true
+		}
+		return exitError, true
+	}
+	return 0, false
+}
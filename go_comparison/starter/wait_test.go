@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/enums/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+)
+
+// blockedRun is a run whose Get waits for result, or for its context.
+type blockedRun struct {
+	client.WorkflowRun
+	result chan string
+}
+
+func (r blockedRun) GetID() string    { return "security-scan-acme" }
+func (r blockedRun) GetRunID() string { return "run-1" }
+
+func (r blockedRun) Get(ctx context.Context, valuePtr interface{}) error {
+	select {
+	case s := <-r.result:
+		*valuePtr.(*string) = s
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func described(status enums.WorkflowExecutionStatus) *workflowservice.DescribeWorkflowExecutionResponse {
+	return &workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{Status: status},
+	}
+}
+
+func TestWaitForScan(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("result", func(t *testing.T) {
+		run := blockedRun{result: make(chan string, 1)}
+		run.result <- "report"
+		var got string
+		require.NoError(t, waitForScan(ctx, new(mocks.Client), run, time.Minute, time.Hour, &got))
+		assert.Equal(t, "report", got)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		run := blockedRun{result: make(chan string)}
+		var got string
+		err := waitForScan(ctx, new(mocks.Client), run, 10*time.Millisecond, time.Hour, &got)
+		assert.ErrorIs(t, err, errWaitTimeout)
+	})
+
+	t.Run("terminated", func(t *testing.T) {
+		c := new(mocks.Client)
+		c.On("DescribeWorkflowExecution", mock.Anything, "security-scan-acme", "run-1").
+			Return(described(enums.WORKFLOW_EXECUTION_STATUS_RUNNING), nil).Once()
+		c.On("DescribeWorkflowExecution", mock.Anything, "security-scan-acme", "run-1").
+			Return(described(enums.WORKFLOW_EXECUTION_STATUS_TERMINATED), nil)
+		run := blockedRun{result: make(chan string)}
+		var got string
+		err := waitForScan(ctx, c, run, 0, time.Millisecond, &got)
+		var closed *scanClosedError
+		require.ErrorAs(t, err, &closed)
+		assert.Equal(t, enums.WORKFLOW_EXECUTION_STATUS_TERMINATED, closed.Status)
+	})
+}
+
+func TestWaitStopped(t *testing.T) {
+	run := blockedRun{}
+
+	var buf bytes.Buffer
+	code, ok := waitStopped(&buf, run, errWaitTimeout, 45*time.Minute)
+	require.True(t, ok)
+	assert.Equal(t, exitStillRunning, code)
+	assert.Contains(t, buf.String(), "--workflow-id security-scan-acme --attach --run-id run-1 --wait-timeout 45m0s")
+
+	buf.Reset()
+	code, ok = waitStopped(&buf, run, &scanClosedError{Status: enums.WORKFLOW_EXECUTION_STATUS_TIMED_OUT}, 0)
+	require.True(t, ok)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, buf.String(), "closed with status TimedOut")
+
+	code, ok = waitStopped(&buf, run, &scanClosedError{Status: enums.WORKFLOW_EXECUTION_STATUS_CANCELED}, 0)
+	require.True(t, ok)
+	assert.Equal(t, exitCancelled, code)
+
+	_, ok = waitStopped(&buf, run, nil, 0)
+	assert.False(t, ok)
+}
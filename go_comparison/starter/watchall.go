@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// --watch-all follows every running SecurityScanWorkflow at once, e.g. the
+// org scans of a compliance sweep. Each refresh lists the scans running, or
+// closed since the watch began, so a scan started meanwhile joins the table
+// and one that finished between refreshes still gets its final row. Running
+// scans answer the progress query; closed ones are read from their result,
+// which needs no worker. The watch ends once every scan it has seen is done.
+
+const (
+	// watchQueryTimeout bounds each progress query and result read.
+	watchQueryTimeout = 10 * time.Second
+	// watchPageSize is how many scans each list request returns.
+	watchPageSize = 100
+)
+
+// Final statuses of a scan besides its ScanStatus: how the workflow closed
+// when it did so without a report.
+const (
+	watchFailed     = "failed"
+	watchTerminated = "terminated"
+	watchTimedOut   = "timed_out"
+)
+
+// watchClient is the part of client.Client that --watch-all uses.
+type watchClient interface {
+	resultsQuerier
+	ListWorkflow(ctx context.Context, request *workflowservice.ListWorkflowExecutionsRequest) (*workflowservice.ListWorkflowExecutionsResponse, error)
+	GetWorkflow(ctx context.Context, workflowID, runID string) client.WorkflowRun
+}
+
+// watchRow is one scan in the --watch-all table, and one line of
+// --json-stream.
+type watchRow struct {
+	Org        string `json:"org"`
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+	// Status is the scan's ScanStatus, or how its workflow closed:
+	// failed, terminated or timed_out.
+	Status          string     `json:"status"`
+	ScannedRepos    int        `json:"scanned_repos"`
+	TotalRepos      int        `json:"total_repos"`
+	PercentComplete float64    `json:"percent_complete"`
+	Errors          int        `json:"errors"`
+	StartedAt       time.Time  `json:"started_at"`
+	ClosedAt        *time.Time `json:"closed_at,omitempty"`
+	ElapsedSeconds  int64      `json:"elapsed_seconds"`
+	Done            bool       `json:"done"`
+	// RefreshedAt is when the row was read; every row of a refresh has
+	// the same time.
+	RefreshedAt time.Time `json:"refreshed_at"`
+}
+
+// progress fills r from a running scan's progress.
+func (r *watchRow) progress(p scanner.ScanProgress) {
+	r.Status = string(p.Status)
+	r.ScannedRepos, r.TotalRepos, r.Errors = p.ScannedRepos, p.TotalRepos, p.Errors
+	r.PercentComplete = p.PercentComplete()
+	if !p.StartedAt.IsZero() {
+		// The scan's start, not its run's, after a continue-as-new.
+		r.StartedAt = p.StartedAt
+	}
+}
+
+// report fills r from a scan's report.
+func (r *watchRow) report(rep scanner.Report) {
+	r.Status = string(scanner.ScanCompleted)
+	r.TotalRepos, r.Errors = rep.TotalRepos, rep.Errors
+	switch {
+	case rep.Cancelled:
+		r.Status, r.ScannedRepos = string(scanner.ScanCancelled), rep.ReposScannedBeforeCancel
+	case rep.APIUsage != nil && rep.APIUsage.BudgetExceeded:
+		r.Status = string(scanner.ScanBudgetExceeded)
+	case rep.DeadlineReached:
+		r.Status = string(scanner.ScanDeadlineReached)
+	default:
+		r.ScannedRepos = rep.TotalRepos
+	}
+	if rep.TotalRepos == 0 && !rep.Cancelled {
+		r.Status = string(scanner.ScanEmpty)
+	}
+	if r.ScannedRepos > r.TotalRepos {
+		r.ScannedRepos = r.TotalRepos
+	}
+	if r.TotalRepos > 0 {
+		r.PercentComplete = float64(r.ScannedRepos) / float64(r.TotalRepos) * 100
+	}
+}
+
+// exitCode is what a scan that ended in r's status makes --watch-all
+// return.
+func (r *watchRow) exitCode() int {
+	switch r.Status {
+	case string(scanner.ScanCompleted), string(scanner.ScanEmpty):
+		return exitOK
+	case string(scanner.ScanCancelled), string(scanner.ScanBudgetExceeded), string(scanner.ScanDeadlineReached):
+		return exitCancelled
+	}
+	return exitError
+}
+
+// scanWatch is what --watch-all has seen, by workflow ID.
+type scanWatch struct {
+	since time.Time
+	rows  map[string]*watchRow
+}
+
+func newScanWatch(since time.Time) *scanWatch {
+	return &scanWatch{since: since, rows: map[string]*watchRow{}}
+}
+
+// listQuery finds the scans running, and those closed since the watch
+// began.
+func (w *scanWatch) listQuery() string {
+	return fmt.Sprintf("WorkflowType = 'SecurityScanWorkflow' AND (ExecutionStatus = 'Running' OR CloseTime >= '%s')",
+		w.since.UTC().Format(time.RFC3339))
+}
+
+// refresh lists the scans and updates their rows as of now.
+func (w *scanWatch) refresh(ctx context.Context, c watchClient, now time.Time) error {
+	latest := map[string]*workflowpb.WorkflowExecutionInfo{}
+	var token []byte
+	for {
+		resp, err := c.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+			Namespace:     client.DefaultNamespace,
+			PageSize:      watchPageSize,
+			NextPageToken: token,
+			Query:         w.listQuery(),
+		})
+		if err != nil {
+			return fmt.Errorf("listing scans: %w", err)
+		}
+		for _, e := range resp.GetExecutions() {
+			// A scan that continued as new goes on in its latest run.
+			id := e.GetExecution().GetWorkflowId()
+			if cur := latest[id]; cur == nil || e.GetStartTime().AsTime().After(cur.GetStartTime().AsTime()) {
+				latest[id] = e
+			}
+		}
+		if token = resp.GetNextPageToken(); len(token) == 0 {
+			break
+		}
+	}
+
+	for id, e := range latest {
+		row := w.rows[id]
+		if row != nil && row.Done && row.RunID == e.GetExecution().GetRunId() {
+			continue
+		}
+		if row == nil || row.Done {
+			// New, or a new scan under the ID of one that ended.
+			row = &watchRow{Org: scanner.WorkflowIDOrg(id), WorkflowID: id, StartedAt: e.GetStartTime().AsTime()}
+			w.rows[id] = row
+		}
+		row.RunID = e.GetExecution().GetRunId()
+		w.update(ctx, c, row, e.GetStatus())
+		if row.Done && e.GetCloseTime() != nil {
+			t := e.GetCloseTime().AsTime()
+			row.ClosedAt = &t
+		}
+	}
+	for _, row := range w.rows {
+		row.RefreshedAt = now
+		end := now
+		if row.ClosedAt != nil {
+			end = *row.ClosedAt
+		}
+		row.ElapsedSeconds = int64(end.Sub(row.StartedAt).Seconds())
+	}
+	return nil
+}
+
+// update reads row's scan, in its workflow status.
+func (w *scanWatch) update(ctx context.Context, c watchClient, row *watchRow, status enums.WorkflowExecutionStatus) {
+	ctx, cancel := context.WithTimeout(ctx, watchQueryTimeout)
+	defer cancel()
+	switch status {
+	case enums.WORKFLOW_EXECUTION_STATUS_RUNNING:
+		// Best effort: a scan with no worker polling can't answer, and
+		// keeps its last row.
+		if val, err := c.QueryWorkflow(ctx, row.WorkflowID, row.RunID, "progress"); err == nil {
+			var p scanner.ScanProgress
+			if val.Get(&p) == nil {
+				row.progress(p)
+			}
+		}
+		if row.Status == "" {
+			row.Status = string(scanner.ScanStarting)
+		}
+	case enums.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+		var rep scanner.Report
+		if err := c.GetWorkflow(ctx, row.WorkflowID, row.RunID).Get(ctx, &rep); err != nil {
+			return // read again on the next refresh
+		}
+		row.report(rep)
+		row.Done = true
+	case enums.WORKFLOW_EXECUTION_STATUS_FAILED:
+		row.Status = watchFailed
+		err := c.GetWorkflow(ctx, row.WorkflowID, row.RunID).Get(ctx, nil)
+		var appErr *temporal.ApplicationError
+		if errors.As(err, &appErr) && appErr.Type() == scanner.ErrTypeScanDegraded {
+			row.Status = string(scanner.ScanDegraded)
+			var rep scanner.Report
+			if appErr.HasDetails() && appErr.Details(&rep) == nil {
+				row.Errors = rep.Errors
+			}
+		}
+		row.Done = true
+	case enums.WORKFLOW_EXECUTION_STATUS_CANCELED:
+		row.Status, row.Done = string(scanner.ScanCancelled), true
+	case enums.WORKFLOW_EXECUTION_STATUS_TERMINATED:
+		row.Status, row.Done = watchTerminated, true
+	case enums.WORKFLOW_EXECUTION_STATUS_TIMED_OUT:
+		row.Status, row.Done = watchTimedOut, true
+	}
+}
+
+// sorted is the rows by org, then workflow ID.
+func (w *scanWatch) sorted() []*watchRow {
+	rows := make([]*watchRow, 0, len(w.rows))
+	for _, r := range w.rows {
+		rows = append(rows, r)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Org != rows[j].Org {
+			return rows[i].Org < rows[j].Org
+		}
+		return rows[i].WorkflowID < rows[j].WorkflowID
+	})
+	return rows
+}
+
+// done reports whether every scan seen has ended.
+func (w *scanWatch) done() bool {
+	for _, r := range w.rows {
+		if !r.Done {
+			return false
+		}
+	}
+	return true
+}
+
+// exitCode is exitError when a scan failed, was degraded, terminated or
+// timed out; exitCancelled when one was cancelled or stopped early; and
+// exitOK when every scan completed.
+func (w *scanWatch) exitCode() int {
+	code := exitOK
+	for _, r := range w.rows {
+		switch c := r.exitCode(); {
+		case c == exitError:
+			return exitError
+		case c == exitCancelled:
+			code = exitCancelled
+		}
+	}
+	return code
+}
+
+// writeWatchTable draws rows as a table on out.
+func writeWatchTable(out io.Writer, rows []*watchRow, now time.Time) {
+	running := 0
+	for _, r := range rows {
+		if !r.Done {
+			running++
+		}
+	}
+	fmt.Fprintf(out, "%d scans, %d running (refreshed %s)\n\n", len(rows), running, now.Format("15:04:05"))
+	fmt.Fprintf(out, "%-24s %-18s %-22s %-7s %s\n", "ORG", "STATUS", "PROGRESS", "ERRORS", "ELAPSED")
+	for _, r := range rows {
+		progress := "-"
+		if r.TotalRepos > 0 {
+			progress = fmt.Sprintf("%d/%d (%.1f%%)", r.ScannedRepos, r.TotalRepos, r.PercentComplete)
+		}
+		fmt.Fprintf(out, "%-24s %-18s %-22s %-7d %s\n",
+			r.Org, r.Status, progress, r.Errors, time.Duration(r.ElapsedSeconds)*time.Second)
+	}
+}
+
+// writeJSONStream writes one JSON line per row.
+func writeJSONStream(out io.Writer, rows []*watchRow) {
+	enc := json.NewEncoder(out)
+	for _, r := range rows {
+		_ = enc.Encode(r)
+	}
+}
+
+// watchAll runs --watch-all: it refreshes on each tick until every scan it
+// has seen has ended, drawing each refresh, and returns the exit code.
+func watchAll(ctx context.Context, c watchClient, w *scanWatch, tick <-chan time.Time, now func() time.Time, draw func([]*watchRow, time.Time)) int {
+	for {
+		t := now()
+		if err := w.refresh(ctx, c, t); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		if len(w.rows) == 0 {
+			fmt.Fprintln(os.Stderr, "No scans running.")
+			return exitOK
+		}
+		draw(w.sorted(), t)
+		if w.done() {
+			return w.exitCode()
+		}
+		select {
+		case <-tick:
+		case <-ctx.Done():
+			return exitError
+		}
+	}
+}
+
+// doWatchAll runs --watch-all against the server, as a table redrawn in
+// place on a terminal, or as --json-stream lines.
+func doWatchAll(c watchClient, interval time.Duration, jsonStream bool) int {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	clear := isTerminal(os.Stdout) && !jsonStream
+	return watchAll(context.Background(), c, newScanWatch(time.Now()), ticker.C, time.Now, func(rows []*watchRow, now time.Time) {
+		switch {
+		case jsonStream:
+			writeJSONStream(os.Stdout, rows)
+		case clear:
+			var b strings.Builder
+			writeWatchTable(&b, rows, now)
+			fmt.Print("\x1b[H\x1b[2J" + b.String())
+		default:
+			writeWatchTable(os.Stdout, rows, now)
+			fmt.Println()
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/enums/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+var watchStart = time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+
+func execution(id, runID string, status enums.WorkflowExecutionStatus, startMin int) *workflowpb.WorkflowExecutionInfo {
+	e := &workflowpb.WorkflowExecutionInfo{
+		Execution: &commonpb.WorkflowExecution{WorkflowId: id, RunId: runID},
+		Status:    status,
+		StartTime: timestamppb.New(watchStart.Add(time.Duration(startMin) * time.Minute)),
+	}
+	if status != enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		e.CloseTime = timestamppb.New(watchStart.Add(time.Duration(startMin+10) * time.Minute))
+	}
+	return e
+}
+
+// fakeWatchServer lists the next of its listings on each refresh, and
+// answers progress and results by run ID.
+type fakeWatchServer struct {
+	t        *testing.T
+	listings [][]*workflowpb.WorkflowExecutionInfo
+	progress map[string]scanner.ScanProgress
+	results  map[string]interface{} // a scanner.Report or an error
+	lists    int
+}
+
+func (f *fakeWatchServer) ListWorkflow(_ context.Context, req *workflowservice.ListWorkflowExecutionsRequest) (*workflowservice.ListWorkflowExecutionsResponse, error) {
+	require.Contains(f.t, req.GetQuery(), "CloseTime >= '2026-03-02T14:00:00Z'")
+	i := f.lists
+	if i >= len(f.listings) {
+		i = len(f.listings) - 1
+	}
+	f.lists++
+	return &workflowservice.ListWorkflowExecutionsResponse{Executions: f.listings[i]}, nil
+}
+
+func (f *fakeWatchServer) QueryWorkflow(_ context.Context, workflowID, runID, queryType string, args ...interface{}) (converter.EncodedValue, error) {
+	require.Equal(f.t, "progress", queryType)
+	b, err := json.Marshal(f.progress[runID])
+	return encodedJSON(b), err
+}
+
+func (f *fakeWatchServer) GetWorkflow(_ context.Context, workflowID, runID string) client.WorkflowRun {
+	return finishedRun{result: f.results[runID]}
+}
+
+// finishedRun is a closed run with its result.
+type finishedRun struct {
+	client.WorkflowRun
+	result interface{}
+}
+
+func (r finishedRun) Get(_ context.Context, valuePtr interface{}) error {
+	if err, ok := r.result.(error); ok {
+		return err
+	}
+	b, err := json.Marshal(r.result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, valuePtr)
+}
+
+func TestWatchAll(t *testing.T) {
+	const (
+		running   = enums.WORKFLOW_EXECUTION_STATUS_RUNNING
+		completed = enums.WORKFLOW_EXECUTION_STATUS_COMPLETED
+		failed    = enums.WORKFLOW_EXECUTION_STATUS_FAILED
+		continued = enums.WORKFLOW_EXECUTION_STATUS_CONTINUED_AS_NEW
+	)
+	degraded := temporal.NewApplicationError("scan degraded: 30 of 40 repos errored", scanner.ErrTypeScanDegraded, scanner.Report{Errors: 30})
+	f := &fakeWatchServer{
+		t: t,
+		listings: [][]*workflowpb.WorkflowExecutionInfo{
+			{
+				execution("security-scan-acme", "acme-1", running, 0),
+				execution("security-scan-globex", "globex-1", running, 1),
+			},
+			// acme finished and initech started and was cancelled between
+			// refreshes; globex continued as new.
+			{
+				execution("security-scan-acme", "acme-1", completed, 0),
+				execution("security-scan-globex", "globex-1", continued, 1),
+				execution("security-scan-globex", "globex-2", running, 5),
+				execution("security-scan-initech", "initech-1", completed, 2),
+			},
+			{
+				execution("security-scan-acme", "acme-1", completed, 0),
+				execution("security-scan-globex", "globex-2", failed, 5),
+				execution("security-scan-initech", "initech-1", completed, 2),
+			},
+		},
+		progress: map[string]scanner.ScanProgress{
+			"acme-1":   {Status: scanner.ScanScanning, TotalRepos: 100, ScannedRepos: 50, Errors: 1},
+			"globex-1": {Status: scanner.ScanFetchingRepos},
+			"globex-2": {Status: scanner.ScanScanning, TotalRepos: 40, ScannedRepos: 20, Errors: 15, StartedAt: watchStart.Add(time.Minute)},
+		},
+		results: map[string]interface{}{
+			"acme-1":    scanner.Report{TotalRepos: 100, Errors: 2},
+			"initech-1": scanner.Report{TotalRepos: 80, Cancelled: true, ReposScannedBeforeCancel: 30},
+			"globex-2":  degraded,
+		},
+	}
+
+	tick := make(chan time.Time, 3)
+	for i := 0; i < 3; i++ {
+		tick <- time.Time{}
+	}
+	minute := 0
+	now := func() time.Time { minute += 5; return watchStart.Add(time.Duration(minute) * time.Minute) }
+	var draws [][]watchRow
+	code := watchAll(context.Background(), f, newScanWatch(watchStart), tick, now, func(rows []*watchRow, _ time.Time) {
+		var copied []watchRow
+		for _, r := range rows {
+			copied = append(copied, *r)
+		}
+		draws = append(draws, copied)
+	})
+
+	require.Equal(t, exitError, code, "globex was degraded")
+	require.Len(t, draws, 3, "until every scan ended")
+
+	first := draws[0]
+	require.Len(t, first, 2)
+	require.Equal(t, "acme", first[0].Org)
+	require.Equal(t, string(scanner.ScanScanning), first[0].Status)
+	require.Equal(t, 50.0, first[0].PercentComplete)
+	require.Equal(t, int64(300), first[0].ElapsedSeconds)
+	require.Equal(t, string(scanner.ScanFetchingRepos), first[1].Status)
+
+	second := draws[1]
+	require.Len(t, second, 3, "initech joined though it never ran during a refresh")
+	require.Equal(t, watchRow{
+		Org: "acme", WorkflowID: "security-scan-acme", RunID: "acme-1",
+		Status: string(scanner.ScanCompleted), ScannedRepos: 100, TotalRepos: 100, PercentComplete: 100, Errors: 2,
+		StartedAt: watchStart, ClosedAt: timePtr(watchStart.Add(10 * time.Minute)), ElapsedSeconds: 600,
+		Done: true, RefreshedAt: watchStart.Add(10 * time.Minute),
+	}, second[0])
+	require.Equal(t, "globex-2", second[1].RunID)
+	require.Equal(t, watchStart.Add(time.Minute), second[1].StartedAt, "the scan's start, not its latest run's")
+	require.Equal(t, string(scanner.ScanCancelled), second[2].Status)
+	require.Equal(t, 30, second[2].ScannedRepos)
+
+	last := draws[2]
+	require.Equal(t, string(scanner.ScanDegraded), last[1].Status)
+	require.Equal(t, 30, last[1].Errors)
+	require.True(t, last[1].Done)
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func TestWatchAllNothingRunning(t *testing.T) {
+	f := &fakeWatchServer{t: t, listings: [][]*workflowpb.WorkflowExecutionInfo{nil}}
+	code := watchAll(context.Background(), f, newScanWatch(watchStart), nil, time.Now, func([]*watchRow, time.Time) {
+		t.Fatal("nothing to draw")
+	})
+	require.Equal(t, exitOK, code)
+}
+
+func TestWatchAllExitCode(t *testing.T) {
+	for want, statuses := range map[int][]string{
+		exitOK:        {"completed", "empty"},
+		exitCancelled: {"completed", "budget_exceeded"},
+		exitError:     {"cancelled", "terminated", "completed"},
+	} {
+		w := newScanWatch(watchStart)
+		for i, s := range statuses {
+			w.rows[s+string(rune('a'+i))] = &watchRow{Status: s, Done: true}
+		}
+		require.Equal(t, want, w.exitCode(), "%v", statuses)
+	}
+}
+
+func TestWatchAllOutput(t *testing.T) {
+	rows := []*watchRow{
+		{Org: "acme", Status: "scanning", ScannedRepos: 450, TotalRepos: 500, PercentComplete: 90, Errors: 3, ElapsedSeconds: 754},
+		{Org: "globex", Status: "starting"},
+		{Org: "initech", Status: "completed", ScannedRepos: 80, TotalRepos: 80, PercentComplete: 100, ElapsedSeconds: 60, Done: true},
+	}
+	var buf bytes.Buffer
+	writeWatchTable(&buf, rows, watchStart)
+	require.Equal(t, strings.Join([]string{
+		"3 scans, 2 running (refreshed 14:00:00)",
+		"",
+		"ORG                      STATUS             PROGRESS               ERRORS  ELAPSED",
+		"acme                     scanning           450/500 (90.0%)        3       12m34s",
+		"globex                   starting           -                      0       0s",
+		"initech                  completed          80/80 (100.0%)         0       1m0s",
+		"",
+	}, "\n"), buf.String())
+
+	buf.Reset()
+	writeJSONStream(&buf, rows)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	var row watchRow
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &row))
+	require.Equal(t, *rows[0], row)
+}
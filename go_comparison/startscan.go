@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+// forceAttempts bounds how often StartScan terminates a running scan to
+// start its own, should another starter keep winning the race.
+const forceAttempts = 3
+
+// ScanStarter is the part of client.Client that StartScan uses.
+type ScanStarter interface {
+	ExecuteWorkflow(ctx context.Context, options client.StartWorkflowOptions, workflow interface{}, args ...interface{}) (client.WorkflowRun, error)
+	TerminateWorkflow(ctx context.Context, workflowID, runID, reason string, details ...interface{}) error
+}
+
+// ScanRunningError is a start refused because a scan is already running
+// under the workflow ID.
+type ScanRunningError struct {
+	WorkflowID, RunID string
+}
+
+func (e *ScanRunningError) Error() string {
+	return fmt.Sprintf("a scan is already running as %s (run %s); use --attach to wait for it, --ensure to join it, or --force with --reason to replace it",
+		e.WorkflowID, e.RunID)
+}
+
+// StartScan starts the scan of input under options.ID. A scan already
+// running there is only replaced with force, in which case it is
+// terminated with a reason naming input's initiator and reason first, so
+// its history says who replaced it and why. Unless options has a memo, the
+// scan's is ScanMemo(input).
+func StartScan(ctx context.Context, c ScanStarter, options client.StartWorkflowOptions, input ScanInput, force bool) (client.WorkflowRun, error) {
+	// A finished scan's ID may be reused; a running one is only replaced
+	// by the terminate below.
+	options.WorkflowIDReusePolicy = enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE
+	options.WorkflowExecutionErrorWhenAlreadyStarted = true
+	if options.Memo == nil {
+		options.Memo = ScanMemo(input)
+	}
+
+	for attempt := 1; ; attempt++ {
+		run, err := c.ExecuteWorkflow(ctx, options, SecurityScanWorkflow, input)
+		var running *serviceerror.WorkflowExecutionAlreadyStarted
+		if err == nil || !errors.As(err, &running) {
+			return run, err
+		}
+		if !force || attempt == forceAttempts {
+			return nil, &ScanRunningError{WorkflowID: options.ID, RunID: running.RunId}
+		}
+		reason := fmt.Sprintf("replaced by a scan started by %s: %s", input.InitiatedBy, input.Reason)
+		var gone *serviceerror.NotFound
+		if err := c.TerminateWorkflow(ctx, options.ID, running.RunId, reason); err != nil && !errors.As(err, &gone) {
+			return nil, fmt.Errorf("terminating the running scan: %w", err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+type startedRun struct {
+	client.WorkflowRun
+	runID string
+}
+
+func (r startedRun) GetRunID() string { return r.runID }
+
+// fakeStarter runs one scan per workflow ID, as the server does.
+type fakeStarter struct {
+	running map[string]string // workflow ID -> run ID
+	starts  int
+	options []client.StartWorkflowOptions
+	reasons []string
+}
+
+func (s *fakeStarter) ExecuteWorkflow(_ context.Context, options client.StartWorkflowOptions, _ interface{}, _ ...interface{}) (client.WorkflowRun, error) {
+	s.options = append(s.options, options)
+	if runID, ok := s.running[options.ID]; ok {
+		return nil, serviceerror.NewWorkflowExecutionAlreadyStarted("Workflow execution is already running", "", runID)
+	}
+	s.starts++
+	s.running[options.ID] = fmt.Sprintf("run-%d", s.starts)
+	return startedRun{runID: s.running[options.ID]}, nil
+}
+
+func (s *fakeStarter) TerminateWorkflow(_ context.Context, workflowID, runID, reason string, _ ...interface{}) error {
+	if s.running[workflowID] != runID {
+		return serviceerror.NewNotFound("workflow execution already completed")
+	}
+	delete(s.running, workflowID)
+	s.reasons = append(s.reasons, reason)
+	return nil
+}
+
+func TestStartScan(t *testing.T) {
+	s := &fakeStarter{running: map[string]string{}}
+	options := client.StartWorkflowOptions{ID: "security-scan-acme", TaskQueue: TaskQueue}
+	input := ScanInput{Org: "acme", InitiatedBy: "alice", Reason: "incident INC-1234"}
+
+	run, err := StartScan(context.Background(), s, options, input, false)
+	require.NoError(t, err)
+	require.Equal(t, "run-1", run.GetRunID())
+	require.Equal(t, enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE, s.options[0].WorkflowIDReusePolicy)
+	require.True(t, s.options[0].WorkflowExecutionErrorWhenAlreadyStarted)
+	require.Equal(t, ScanMemo(input), s.options[0].Memo)
+
+	// A running scan is not replaced without force.
+	_, err = StartScan(context.Background(), s, options, input, false)
+	var running *ScanRunningError
+	require.True(t, errors.As(err, &running))
+	require.Contains(t, err.Error(), "already running as security-scan-acme (run run-1)")
+	require.Empty(t, s.reasons)
+
+	// With force it is terminated, saying who replaced it and why.
+	run, err = StartScan(context.Background(), s, options, input, true)
+	require.NoError(t, err)
+	require.Equal(t, "run-2", run.GetRunID())
+	require.Equal(t, []string{"replaced by a scan started by alice: incident INC-1234"}, s.reasons)
+}
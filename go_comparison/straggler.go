@@ -0,0 +1,68 @@
+package scanner
+
+// =============================================================================
+// Stragglers — releasing a batch from its slowest repos
+// =============================================================================
+//
+// A batch is done when its last repo is, so one pathological repo (a huge
+// alert list, a run of 5xx retries) holds the whole scan back while the
+// batch's other results wait. With ScanInput.StragglerTimeout, scanBatch
+// gives each batch a soft deadline once half its repos are done:
+//
+//	deadline = batch start + max(Multiple × median, MinSeconds)
+//
+// where the median is how long the batch took to get half its repos done,
+// which is the median of all of them, however long the rest take. The
+// repos still running at the deadline are released: their activities are
+// cancelled, and the scan moves on. Once every batch has run, the scan
+// rescans them in a second pass (ScanRetryingFailures) with no soft
+// deadline, bounded only by the scan activities' timeouts (timeouts.go).
+//
+// Released repos are not errors. The report lists them in
+// timed_out_in_batch_repos; the second pass's results count as any
+// other, so one that fails there is an error of its own category. A scan
+// stopped before the second pass reports them as unscanned.
+// =============================================================================
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultStragglerMinSeconds is StragglerTimeout.MinSeconds when unset.
+const defaultStragglerMinSeconds = 30
+
+// StragglerTimeout releases the repos that take much longer than the rest
+// of their batch, for a second pass.
+type StragglerTimeout struct {
+	// Multiple is how many times the batch's median repo duration a repo
+	// may take before it is released. It must be more than 1.
+	Multiple float64 `json:"multiple"`
+	// MinSeconds is the least a batch waits, however fast its median;
+	// 30 when zero.
+	MinSeconds float64 `json:"min_seconds,omitempty"`
+}
+
+func (s *StragglerTimeout) validate() error {
+	if s.Multiple <= 1 {
+		return fmt.Errorf("straggler timeout multiple must be more than 1, got %g", s.Multiple)
+	}
+	if s.MinSeconds < 0 {
+		return fmt.Errorf("straggler timeout min_seconds must not be negative, got %g", s.MinSeconds)
+	}
+	return nil
+}
+
+// releaseAfter is how long after its start a batch whose median repo took
+// median releases its stragglers.
+func (s *StragglerTimeout) releaseAfter(median time.Duration) time.Duration {
+	min := s.MinSeconds
+	if min == 0 {
+		min = defaultStragglerMinSeconds
+	}
+	after := time.Duration(s.Multiple * float64(median))
+	if floor := time.Duration(min * float64(time.Second)); after < floor {
+		return floor
+	}
+	return after
+}
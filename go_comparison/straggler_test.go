@@ -0,0 +1,89 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestStragglerReleaseAfter(t *testing.T) {
+	s := &StragglerTimeout{Multiple: 3}
+	require.Equal(t, time.Minute, s.releaseAfter(20*time.Second))
+	require.Equal(t, 30*time.Second, s.releaseAfter(time.Second), "no sooner than MinSeconds")
+	s.MinSeconds = 2
+	require.Equal(t, 3*time.Second, s.releaseAfter(time.Second))
+
+	require.NoError(t, s.validate())
+	require.ErrorContains(t, (&StragglerTimeout{Multiple: 1}).validate(), "more than 1")
+	require.ErrorContains(t, (&StragglerTimeout{Multiple: 2, MinSeconds: -1}).validate(), "must not be negative")
+}
+
+func TestWorkflowReleasesStragglerFromItsBatch(t *testing.T) {
+	for _, childPerBatch := range []bool{false, true} {
+		env := newTestEnv(t)
+		env.RegisterWorkflow(ScanBatchWorkflow)
+		onListOrgRepos(env, fakeRepos(10))
+		// repo-004 hangs the first time and is quick in the second pass;
+		// repo-007 is a hard error.
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-004", mock.Anything, mock.Anything).
+			After(time.Hour).Return(compliantUnless()).Once()
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-007", mock.Anything, mock.Anything).
+			After(20*time.Second).Return(nil, newCheckError(ErrorNotFound, 404, nil, "no such repo"))
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			After(20 * time.Second).Return(compliantUnless())
+		start := env.Now()
+
+		env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+			Org:              "acme",
+			ChildPerBatch:    childPerBatch,
+			StragglerTimeout: &StragglerTimeout{Multiple: 3},
+		})
+
+		require.NoError(t, env.GetWorkflowError())
+		// Released at three times the 20s median, then rescanned.
+		require.Less(t, env.Now().Sub(start), 2*time.Minute, "the batch did not wait an hour for repo-004")
+		env.AssertNumberOfCalls(t, "CheckRepoSecurity", 10+1)
+
+		var report map[string]interface{}
+		require.NoError(t, env.GetWorkflowResult(&report))
+		require.Equal(t, []interface{}{"repo-004"}, report["timed_out_in_batch_repos"])
+		require.EqualValues(t, 9, report["total_repos"], "repo-004's second pass counts like any scan")
+		require.EqualValues(t, 1, report["errors"], "only the hard error")
+		require.Equal(t, map[string]interface{}{"NOT_FOUND": float64(1)}, report["errors_by_category"])
+		require.Nil(t, report["unscanned_repos"])
+	}
+}
+
+func TestWorkflowWithoutStragglerTimeoutWaitsForTheBatch(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(10))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-004", mock.Anything, mock.Anything).
+		After(50 * time.Second).Return(compliantUnless())
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 10)
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Nil(t, report["timed_out_in_batch_repos"])
+}
+
+func TestWorkflowRejectsBadStragglerTimeout(t *testing.T) {
+	for _, in := range []ScanInput{
+		{Org: "acme", StragglerTimeout: &StragglerTimeout{Multiple: 0.5}},
+		{Org: "acme", ActivityBatching: true, StragglerTimeout: &StragglerTimeout{Multiple: 3}},
+	} {
+		env := newTestEnv(t)
+		env.ExecuteWorkflow(SecurityScanWorkflow, in)
+		var appErr *temporal.ApplicationError
+		require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+		require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+	}
+}
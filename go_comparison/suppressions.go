@@ -0,0 +1,218 @@
+package scanner
+
+// =============================================================================
+// Suppressions — accepted risks that should not be flagged every scan
+// =============================================================================
+//
+// Some repos are out of compliance on purpose (archived mirrors, third-party
+// forks). A suppression names the repos and check results it covers, why the
+// risk is accepted, and until when. Suppressed findings do not make a repo
+// non-compliant but are still listed in the report with their justification,
+// and an expired suppression stops applying and is called out instead.
+// =============================================================================
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"gopkg.in/yaml.v3"
+)
+
+// suppressionDateLayout is the format of Suppression.Expires.
+const suppressionDateLayout = "2006-01-02"
+
+// Suppression accepts a risk for the repos matching Repo.
+type Suppression struct {
+	// Repo is a path.Match pattern on the repo name, e.g. "mirror-*".
+	Repo string `json:"repo" yaml:"repo"`
+	// Checks lists the suppressed results by RepoSecurityResult.Checks key
+	// or by check name (covering every result the check records). Empty
+	// suppresses every check.
+	Checks        []string `json:"checks,omitempty" yaml:"checks"`
+	Justification string   `json:"justification" yaml:"justification"`
+	// Expires is the last day (YYYY-MM-DD, UTC) the suppression applies.
+	// Empty never expires.
+	Expires string `json:"expires,omitempty" yaml:"expires"`
+}
+
+// Validate rejects a suppression with a bad pattern or date, an unknown
+// check, or no justification.
+func (s Suppression) Validate() error {
+	if s.Repo == "" {
+		return fmt.Errorf("suppression has no repo pattern")
+	}
+	if _, err := path.Match(s.Repo, ""); err != nil {
+		return fmt.Errorf("suppression repo pattern %q: %w", s.Repo, err)
+	}
+	if strings.TrimSpace(s.Justification) == "" {
+		return fmt.Errorf("suppression for %q has no justification", s.Repo)
+	}
+	for _, c := range s.Checks {
+		if _, ok := resultChecks[c]; !ok && lookupCheck(c) == nil {
+			return fmt.Errorf("suppression for %q names unknown check %q", s.Repo, c)
+		}
+	}
+	if s.Expires != "" {
+		if _, err := time.Parse(suppressionDateLayout, s.Expires); err != nil {
+			return fmt.Errorf("suppression for %q: expires %q is not YYYY-MM-DD", s.Repo, s.Expires)
+		}
+	}
+	return nil
+}
+
+// Expired reports whether the suppression no longer applies at now. It
+// applies through the whole of its Expires day.
+func (s Suppression) Expired(now time.Time) bool {
+	if s.Expires == "" {
+		return false
+	}
+	day, err := time.Parse(suppressionDateLayout, s.Expires)
+	return err != nil || !now.Before(day.AddDate(0, 0, 1))
+}
+
+// covers reports whether the suppression applies to the result key of repo.
+func (s Suppression) covers(repo, key string) bool {
+	if ok, _ := path.Match(s.Repo, repo); !ok {
+		return false
+	}
+	if len(s.Checks) == 0 {
+		return true
+	}
+	for _, c := range s.Checks {
+		if c == key || c == resultChecks[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSuppressions validates each suppression.
+func ValidateSuppressions(sups []Suppression) error {
+	for _, s := range sups {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitExpired separates suppressions that still apply at now from
+// expired ones.
+func splitExpired(sups []Suppression, now time.Time) (active, expired []Suppression) {
+	for _, s := range sups {
+		if s.Expired(now) {
+			expired = append(expired, s)
+		} else {
+			active = append(active, s)
+		}
+	}
+	return active, expired
+}
+
+// SuppressedFinding is a failed required check that a suppression excused.
+type SuppressedFinding struct {
+	Repository    string `json:"repository"`
+	Check         string `json:"check"`
+	Justification string `json:"justification"`
+	Expires       string `json:"expires,omitempty"`
+}
+
+// evaluate returns the failed required checks of r that sups do not cover,
+// and the findings they excused. r is compliant when failed is empty;
+// p.outcome tells non-compliant from indeterminate otherwise.
+func (p CompliancePolicy) evaluate(r *RepoSecurityResult, sups []Suppression) (failed []string, suppressed []SuppressedFinding) {
+	for _, key := range p.failedResults(r) {
+		s := matchSuppression(sups, r.Repository, key)
+		if s == nil {
+			failed = append(failed, key)
+			continue
+		}
+		suppressed = append(suppressed, SuppressedFinding{
+			Repository:    r.Repository,
+			Check:         key,
+			Justification: s.Justification,
+			Expires:       s.Expires,
+		})
+	}
+	return failed, suppressed
+}
+
+func matchSuppression(sups []Suppression, repo, key string) *Suppression {
+	for i := range sups {
+		if sups[i].covers(repo, key) {
+			return &sups[i]
+		}
+	}
+	return nil
+}
+
+// ParseSuppressions reads suppressions from YAML (or JSON), either a list
+// or a document with a top-level "suppressions" list, and validates them.
+func ParseSuppressions(data []byte) ([]Suppression, error) {
+	var sups []Suppression
+	if err := yaml.Unmarshal(data, &sups); err != nil {
+		var doc struct {
+			Suppressions []Suppression `yaml:"suppressions"`
+		}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parsing suppressions: %w", err)
+		}
+		sups = doc.Suppressions
+	}
+	if err := ValidateSuppressions(sups); err != nil {
+		return nil, err
+	}
+	return sups, nil
+}
+
+// ErrTypeInvalidSuppressions is the ApplicationError type for a
+// suppressions file that cannot be read or parsed.
+const ErrTypeInvalidSuppressions = "INVALID_SUPPRESSIONS"
+
+// LoadSuppressions reads suppressions from a file on the worker or an
+// http(s) URL. A missing or malformed file is not retryable.
+func (a *Activities) LoadSuppressions(ctx context.Context, source string) ([]Suppression, error) {
+	var data []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidSuppressions, err)
+		}
+		resp, err := a.HTTPClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetching suppressions: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			msg := fmt.Sprintf("fetching suppressions from %s: status %d", source, resp.StatusCode)
+			if resp.StatusCode >= 500 {
+				return nil, fmt.Errorf("%s", msg)
+			}
+			return nil, temporal.NewNonRetryableApplicationError(msg, ErrTypeInvalidSuppressions, nil)
+		}
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return nil, fmt.Errorf("reading suppressions: %w", err)
+		}
+	} else {
+		var err error
+		data, err = os.ReadFile(strings.TrimPrefix(source, "file://"))
+		if err != nil {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidSuppressions, err)
+		}
+	}
+
+	sups, err := ParseSuppressions(data)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidSuppressions, err)
+	}
+	activity.GetLogger(ctx).Info("Loaded suppressions", "source", source, "count", len(sups))
+	return sups, nil
+}
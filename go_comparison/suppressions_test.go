@@ -0,0 +1,141 @@
+package scanner
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestSuppressionValidate(t *testing.T) {
+	ok := Suppression{Repo: "mirror-*", Checks: []string{CheckCodeScanning, CheckFiles}, Justification: "mirror", Expires: "2026-12-31"}
+	require.NoError(t, ok.Validate())
+
+	for name, s := range map[string]Suppression{
+		"no repo":          {Justification: "x"},
+		"bad pattern":      {Repo: "[", Justification: "x"},
+		"no justification": {Repo: "a", Justification: "  "},
+		"unknown check":    {Repo: "a", Justification: "x", Checks: []string{"branch_protection"}},
+		"bad date":         {Repo: "a", Justification: "x", Expires: "31/12/2026"},
+	} {
+		require.Error(t, s.Validate(), name)
+	}
+}
+
+func TestSuppressionExpired(t *testing.T) {
+	s := Suppression{Repo: "a", Justification: "x", Expires: "2026-03-01"}
+	require.False(t, s.Expired(time.Date(2026, 3, 1, 23, 59, 59, 0, time.UTC)), "applies through its last day")
+	require.True(t, s.Expired(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)))
+	require.False(t, Suppression{Repo: "a", Justification: "x"}.Expired(time.Now()))
+}
+
+func TestSuppressionCovers(t *testing.T) {
+	s := Suppression{Repo: "mirror-*", Checks: []string{CheckCodeScanning, CheckFiles}}
+	require.True(t, s.covers("mirror-linux", CheckCodeScanning))
+	require.True(t, s.covers("mirror-linux", ResultSecurityPolicy), "a check name covers its results")
+	require.False(t, s.covers("mirror-linux", CheckDependabot))
+	require.False(t, s.covers("linux", CheckCodeScanning))
+	require.True(t, Suppression{Repo: "*"}.covers("any", CheckDependabot), "no checks covers every check")
+}
+
+func TestParseSuppressions(t *testing.T) {
+	data, err := os.ReadFile("testdata/suppressions/suppressions.yaml")
+	require.NoError(t, err)
+	sups, err := ParseSuppressions(data)
+	require.NoError(t, err)
+	require.Len(t, sups, 2)
+	require.Equal(t, []string{CheckCodeScanning, CheckFiles}, sups[0].Checks)
+	require.Equal(t, "legacy-billing", sups[1].Repo)
+
+	sups, err = ParseSuppressions([]byte(`[{"repo": "a", "justification": "json works too"}]`))
+	require.NoError(t, err)
+	require.Len(t, sups, 1)
+
+	_, err = ParseSuppressions([]byte("- repo: a\n"))
+	require.ErrorContains(t, err, "no justification")
+}
+
+func TestLoadSuppressions(t *testing.T) {
+	data, err := os.ReadFile("testdata/suppressions/suppressions.yaml")
+	require.NoError(t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/suppressions.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+
+	a := &Activities{HTTPClient: srv.Client()}
+	env := newActivityEnv(a)
+
+	for _, source := range []string{"testdata/suppressions/suppressions.yaml", srv.URL + "/suppressions.yaml"} {
+		val, err := env.ExecuteActivity(a.LoadSuppressions, source)
+		require.NoError(t, err, source)
+		var sups []Suppression
+		require.NoError(t, val.Get(&sups))
+		require.Len(t, sups, 2, source)
+	}
+
+	for _, source := range []string{"testdata/suppressions/missing.yaml", srv.URL + "/missing.yaml"} {
+		_, err := env.ExecuteActivity(a.LoadSuppressions, source)
+		var appErr *temporal.ApplicationError
+		require.True(t, errors.As(err, &appErr), source)
+		require.Equal(t, ErrTypeInvalidSuppressions, appErr.Type())
+		require.True(t, appErr.NonRetryable())
+	}
+}
+
+func TestWorkflowSuppressions(t *testing.T) {
+	env := newTestEnv(t)
+	env.SetStartTime(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	onListOrgRepos(env, fakeRepos(4))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("repo-001", "repo-002", "repo-003"))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Suppressions: []Suppression{
+		{Repo: "repo-001", Justification: "third-party fork"},
+		{Repo: "repo-002", Checks: []string{CheckDependabot}, Justification: "only dependabot is excused"},
+		{Repo: "repo-003", Justification: "lapsed", Expires: "2026-02-28"},
+	}})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 2, report["fully_compliant"])
+	require.ElementsMatch(t, []interface{}{"repo-002", "repo-003"}, report["non_compliant_repos"])
+
+	suppressed, _ := report["suppressed"].([]interface{})
+	require.NotEmpty(t, suppressed)
+	for _, f := range suppressed {
+		require.Contains(t, []interface{}{"repo-001", "repo-002"}, f.(map[string]interface{})["repository"])
+	}
+	require.Equal(t, []interface{}{map[string]interface{}{
+		"repo": "repo-003", "justification": "lapsed", "expires": "2026-02-28",
+	}}, report["expired_suppressions"])
+
+	val, err := env.QueryWorkflow("progress")
+	require.NoError(t, err)
+	var progress ScanProgress
+	require.NoError(t, val.Get(&progress))
+	require.Equal(t, 2, progress.CompliantRepos)
+}
+
+func TestWorkflowRejectsInvalidSuppressions(t *testing.T) {
+	env := newTestEnv(t)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Suppressions: []Suppression{{Repo: "a"}}})
+
+	require.True(t, env.IsWorkflowCompleted())
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+}
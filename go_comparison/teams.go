@@ -0,0 +1,102 @@
+package scanner
+
+// =============================================================================
+// Team scans — scanning the repos of selected GitHub teams
+// =============================================================================
+//
+// Security reviews are often scoped to a team rather than the whole org.
+// ScanInput.Teams holds team slugs; when it is set the workflow lists the
+// repos of those teams with FetchTeamRepos instead of FetchOrgRepos. A repo
+// shared by several selected teams is scanned once.
+//
+// The team endpoints need the read:org scope on a classic token (or the
+// Members organization permission on a fine-grained one). The token
+// capability pre-flight fails the scan up front when a classic token lacks
+// it; a fine-grained token that lacks it fails on its first listing.
+// =============================================================================
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// teamScopes are the classic scopes that can list an org's teams.
+var teamScopes = []string{"read:org", "write:org", "admin:org"}
+
+// ValidateTeamSlug checks that s can be a GitHub team slug.
+func ValidateTeamSlug(s string) error {
+	if !repoNamePart.MatchString(s) {
+		return fmt.Errorf("%q is not a team slug", s)
+	}
+	return nil
+}
+
+// CanListTeams reports whether the token can list team repos, and if not,
+// why. Only classic tokens list their scopes; any other token is assumed
+// to be able to until GitHub says otherwise.
+func (c *TokenCapabilities) CanListTeams() (bool, string) {
+	if c == nil || c.Kind != TokenClassic {
+		return true, ""
+	}
+	for _, s := range c.Scopes {
+		for _, want := range teamScopes {
+			if s == want {
+				return true, ""
+			}
+		}
+	}
+	return false, "listing team repos needs the read:org scope"
+}
+
+// FetchTeamRepos lists the repos of every team in input.Teams, each repo
+// once with the selected teams it belongs to. A team that does not exist fails non-retryably, naming the slug.
+func (a *Activities) FetchTeamRepos(ctx context.Context, input ScanInput) ([]RepoInfo, error) {
+	cred, err := a.githubToken(ctx, input.credential())
+	if err != nil {
+		return nil, err
+	}
+	var repos []RepoInfo
+	seen := make(map[string]int)
+	for _, slug := range input.Teams {
+		notFound := temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("team '%s' not found in organization '%s'", slug, input.Org), "NOT_FOUND", nil)
+		denied := temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("token cannot list the repos of team '%s' (needs read:org)", slug), "UNAUTHORIZED", nil)
+		teamRepos, err := a.listGitHubRepos(ctx, "/orgs/"+input.Org+"/teams/"+slug+"/repos", cred, notFound, denied)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range teamRepos {
+			key := strings.ToLower(r.FullName)
+			if i, ok := seen[key]; ok {
+				repos[i].Teams = append(repos[i].Teams, slug)
+				continue
+			}
+			seen[key] = len(repos)
+			r.Teams = []string{slug}
+			repos = append(repos, r)
+		}
+	}
+
+	activity.GetLogger(ctx).Info("Fetched team repositories", "count", len(repos), "org", input.Org, "teams", input.Teams)
+	return repos, nil
+}
+
+// teamRepos maps each team to the names of its repos in repos, for the
+// report's team_repos. It is nil unless the repos came from FetchTeamRepos.
+func teamRepos(repos []RepoInfo) map[string][]string {
+	var out map[string][]string
+	for _, r := range repos {
+		for _, team := range r.Teams {
+			if out == nil {
+				out = make(map[string][]string)
+			}
+			out[team] = append(out[team], r.Name)
+		}
+	}
+	return out
+}
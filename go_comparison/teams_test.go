@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func teamReposPage(slug, page string) string {
+	return "/orgs/acme-corp/teams/" + slug + "/repos?per_page=100&page=" + page
+}
+
+func TestFetchTeamRepos(t *testing.T) {
+	_, a := newFakeGitHub(t, map[string]fakeResponse{
+		teamReposPage("platform", "1"): {http.StatusOK, "team_repos_platform.json"},
+		teamReposPage("payments", "1"): {http.StatusOK, "team_repos_payments.json"},
+	})
+
+	val, err := newActivityEnv(a).ExecuteActivity(a.FetchTeamRepos, ScanInput{Org: "acme-corp", Teams: []string{"platform", "payments"}})
+	require.NoError(t, err)
+	var repos []RepoInfo
+	require.NoError(t, val.Get(&repos))
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.Name)
+	}
+	require.Equal(t, []string{"service-000", "service-001", "infra-terraform", "legacy-billing"}, names,
+		"service-001 belongs to both teams and is listed once")
+	require.Equal(t, []string{"platform", "payments"}, repos[1].Teams)
+	require.Equal(t, map[string][]string{
+		"platform": {"service-000", "service-001", "infra-terraform"},
+		"payments": {"service-001", "legacy-billing"},
+	}, teamRepos(repos))
+	require.Nil(t, teamRepos(fakeRepos(2)), "not a team scan")
+}
+
+func TestFetchTeamReposFailures(t *testing.T) {
+	for name, tc := range map[string]struct {
+		resp     fakeResponse
+		wantType string
+		wantMsg  string
+	}{
+		"unknown team": {fakeResponse{http.StatusNotFound, "not_found.json"}, "NOT_FOUND", "team 'payments' not found"},
+		"token denied": {fakeResponse{http.StatusForbidden, "contents_forbidden.json"}, "UNAUTHORIZED", "read:org"},
+		"rate limited": {fakeResponse{http.StatusForbidden, "rate_limited.json"}, "", "rate limit"},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			_, a := newFakeGitHub(t, map[string]fakeResponse{
+				teamReposPage("platform", "1"): {http.StatusOK, "team_repos_platform.json"},
+				teamReposPage("payments", "1"): tc.resp,
+			})
+
+			_, err := newActivityEnv(a).ExecuteActivity(a.FetchTeamRepos, ScanInput{Org: "acme-corp", Teams: []string{"platform", "payments"}})
+			require.ErrorContains(t, err, tc.wantMsg)
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(err, &appErr))
+			if tc.wantType == "" {
+				require.False(t, appErr.NonRetryable())
+				return
+			}
+			require.Equal(t, tc.wantType, appErr.Type())
+			require.True(t, appErr.NonRetryable())
+		})
+	}
+}
+
+func TestWorkflowScansTeams(t *testing.T) {
+	env := newTestEnv(t)
+	teams := []string{"platform", "payments"}
+	env.OnActivity("FetchTeamRepos", mock.Anything, mock.MatchedBy(func(in ScanInput) bool {
+		return len(in.Teams) == 2
+	})).Return(func(context.Context, ScanInput) ([]RepoInfo, error) {
+		repos := fakeRepos(3)
+		repos[0].Teams = []string{"platform"}
+		repos[1].Teams = []string{"platform", "payments"}
+		repos[2].Teams = []string{"payments"}
+		return repos, nil
+	})
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "acme", mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Teams: teams})
+
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNotCalled(t, "FetchOrgReposPage", mock.Anything, mock.Anything)
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 3, report.TotalRepos)
+	require.Equal(t, teams, report.Teams)
+	require.Equal(t, map[string][]string{
+		"platform": {"repo-000", "repo-001"},
+		"payments": {"repo-001", "repo-002"},
+	}, report.TeamRepos)
+}
+
+func TestWorkflowTeamsNeedReadOrg(t *testing.T) {
+	env := newTestEnv(t)
+	token := "ghp_test"
+	env.OnActivity("ValidateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&TokenCapabilities{Kind: TokenClassic, Scopes: []string{"repo"}, Checks: []CheckCapability{
+			{Check: CheckSecretScanning, Access: AccessFull},
+		}}, nil)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Token: &token, Teams: []string{"platform"}})
+
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr), "got %v", env.GetWorkflowError())
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+	require.Contains(t, appErr.Error(), "read:org")
+}
+
+func TestCanListTeams(t *testing.T) {
+	var none *TokenCapabilities
+	ok, _ := none.CanListTeams()
+	require.True(t, ok)
+	ok, _ = (&TokenCapabilities{Kind: TokenFineGrained}).CanListTeams()
+	require.True(t, ok, "fine-grained tokens are not known to lack it")
+	ok, _ = (&TokenCapabilities{Kind: TokenClassic, Scopes: []string{"repo", "read:org"}}).CanListTeams()
+	require.True(t, ok)
+	ok, reason := (&TokenCapabilities{Kind: TokenClassic, Scopes: []string{"repo"}}).CanListTeams()
+	require.False(t, ok)
+	require.Contains(t, reason, "read:org")
+}
@@ -0,0 +1,177 @@
+package scanner
+
+// =============================================================================
+// Activity timeouts — per kind of activity, with a bound on retries
+// =============================================================================
+//
+// Each kind of activity has its own timeouts, next to its retry policy
+// (see retrypolicy.go):
+//
+//	           start-to-close   schedule-to-close   heartbeat
+//	fetch      2m               30m                 30s
+//	scan       2m               15m                 -
+//	report     30s              2m                  -
+//
+// Start-to-close bounds one attempt; schedule-to-close bounds all of them,
+// so a repo whose checks keep failing cannot hold its batch open for the
+// whole of its retry policy. ScanInput.Timeouts overrides them per scan.
+// The timeouts a scan ends up with must satisfy heartbeat < start-to-close
+// < schedule-to-close, or the scan is rejected before it starts.
+//
+// WorstCaseDuration adds them up for the starter's --rate-limit estimate.
+// Timeouts are not part of the workflow's commands, so changing them is
+// safe for running scans.
+// =============================================================================
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// ActivityTimeouts are the timeouts of one kind of activity, in seconds.
+// In an override, zero keeps the default.
+type ActivityTimeouts struct {
+	StartToCloseSeconds    int `json:"start_to_close_seconds,omitempty"`
+	ScheduleToCloseSeconds int `json:"schedule_to_close_seconds,omitempty"`
+	// HeartbeatSeconds is for the activities that heartbeat: the fetch
+	// activities and, with ActivityBatching, CheckRepoSecurityBatch, which
+	// otherwise expects a heartbeat per repo's start-to-close. Report
+	// activities do not heartbeat.
+	HeartbeatSeconds int `json:"heartbeat_seconds,omitempty"`
+}
+
+// Timeouts overrides the scan's activity timeouts; nil keeps a kind's
+// defaults.
+type Timeouts struct {
+	Fetch  *ActivityTimeouts `json:"fetch,omitempty"`
+	Scan   *ActivityTimeouts `json:"scan,omitempty"`
+	Report *ActivityTimeouts `json:"report,omitempty"`
+}
+
+// Default activity timeouts; see the table above.
+var (
+	defaultFetchTimeouts  = ActivityTimeouts{StartToCloseSeconds: 120, ScheduleToCloseSeconds: 30 * 60, HeartbeatSeconds: 30}
+	defaultScanTimeouts   = ActivityTimeouts{StartToCloseSeconds: 120, ScheduleToCloseSeconds: 15 * 60}
+	defaultReportTimeouts = ActivityTimeouts{StartToCloseSeconds: 30, ScheduleToCloseSeconds: 2 * 60}
+)
+
+// merge returns t with the fields o sets replaced.
+func (t ActivityTimeouts) merge(o *ActivityTimeouts) ActivityTimeouts {
+	if o == nil {
+		return t
+	}
+	if o.StartToCloseSeconds != 0 {
+		t.StartToCloseSeconds = o.StartToCloseSeconds
+	}
+	if o.ScheduleToCloseSeconds != 0 {
+		t.ScheduleToCloseSeconds = o.ScheduleToCloseSeconds
+	}
+	if o.HeartbeatSeconds != 0 {
+		t.HeartbeatSeconds = o.HeartbeatSeconds
+	}
+	return t
+}
+
+// validate checks that heartbeat < start-to-close < schedule-to-close.
+func (t ActivityTimeouts) validate() error {
+	switch {
+	case t.StartToCloseSeconds <= 0 || t.ScheduleToCloseSeconds <= 0 || t.HeartbeatSeconds < 0:
+		return fmt.Errorf("timeouts must be positive, got start-to-close %ds, schedule-to-close %ds, heartbeat %ds",
+			t.StartToCloseSeconds, t.ScheduleToCloseSeconds, t.HeartbeatSeconds)
+	case t.HeartbeatSeconds > 0 && t.HeartbeatSeconds >= t.StartToCloseSeconds:
+		return fmt.Errorf("heartbeat timeout (%ds) must be shorter than start-to-close (%ds)",
+			t.HeartbeatSeconds, t.StartToCloseSeconds)
+	case t.StartToCloseSeconds >= t.ScheduleToCloseSeconds:
+		return fmt.Errorf("start-to-close timeout (%ds) must be shorter than schedule-to-close (%ds)",
+			t.StartToCloseSeconds, t.ScheduleToCloseSeconds)
+	}
+	return nil
+}
+
+func (t ActivityTimeouts) startToClose() time.Duration {
+	return time.Duration(t.StartToCloseSeconds) * time.Second
+}
+
+func (t ActivityTimeouts) scheduleToClose() time.Duration {
+	return time.Duration(t.ScheduleToCloseSeconds) * time.Second
+}
+
+func (t ActivityTimeouts) heartbeat() time.Duration {
+	return time.Duration(t.HeartbeatSeconds) * time.Second
+}
+
+// options are activity options with t and retryPolicy.
+func (t ActivityTimeouts) options(retryPolicy *temporal.RetryPolicy) workflow.ActivityOptions {
+	return workflow.ActivityOptions{
+		StartToCloseTimeout:    t.startToClose(),
+		ScheduleToCloseTimeout: t.scheduleToClose(),
+		HeartbeatTimeout:       t.heartbeat(),
+		RetryPolicy:            retryPolicy,
+	}
+}
+
+// fetch, scan and report are the scan's timeouts with its overrides
+// applied. t may be nil.
+func (t *Timeouts) fetch() ActivityTimeouts {
+	if t == nil {
+		return defaultFetchTimeouts
+	}
+	return defaultFetchTimeouts.merge(t.Fetch)
+}
+
+func (t *Timeouts) scan() ActivityTimeouts {
+	if t == nil {
+		return defaultScanTimeouts
+	}
+	return defaultScanTimeouts.merge(t.Scan)
+}
+
+func (t *Timeouts) report() ActivityTimeouts {
+	if t == nil {
+		return defaultReportTimeouts
+	}
+	return defaultReportTimeouts.merge(t.Report)
+}
+
+// validate checks the timeouts each kind ends up with.
+func (t *Timeouts) validate() error {
+	if t.report().HeartbeatSeconds != 0 {
+		return fmt.Errorf("report activity: report activities do not heartbeat, so they take no heartbeat timeout")
+	}
+	for _, kind := range []struct {
+		name     string
+		timeouts ActivityTimeouts
+	}{{"fetch", t.fetch()}, {"scan", t.scan()}, {"report", t.report()}} {
+		if err := kind.timeouts.validate(); err != nil {
+			return fmt.Errorf("%s activity: %w", kind.name, err)
+		}
+	}
+	return nil
+}
+
+// WorstCaseDuration is how long a scan of repos repos can take when every
+// activity runs until its schedule-to-close timeout: the listing, one scan
+// timeout per group of concurrent checks (per Concurrency repos in a
+// window, each slot's repos one after another), the pauses of BatchDelay at
+// their longest, and the report. Cancellation, the deadline, and
+// MaxDurationSeconds can only make it shorter.
+func (in ScanInput) WorstCaseDuration(repos int) time.Duration {
+	size := scanBatchSize
+	if in.Concurrency > 0 {
+		size = in.Concurrency
+	}
+	groups := (repos + size - 1) / size
+	d := in.Timeouts.fetch().scheduleToClose() +
+		time.Duration(groups)*in.Timeouts.scan().scheduleToClose() +
+		in.Timeouts.report().scheduleToClose()
+	if in.BatchDelay != nil && groups > 1 {
+		// Batch children pause between their groups too, so this bounds
+		// the pauses of every batch size.
+		pause := time.Duration(in.BatchDelay.Seconds * (1 + in.BatchDelay.Jitter) * float64(time.Second))
+		d += time.Duration(groups-1) * pause
+	}
+	return d
+}
@@ -0,0 +1,88 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestTimeoutOverrides(t *testing.T) {
+	var none *Timeouts
+	require.Equal(t, defaultFetchTimeouts, none.fetch())
+	require.Equal(t, defaultScanTimeouts, none.scan())
+	require.Equal(t, defaultReportTimeouts, none.report())
+	require.NoError(t, none.validate())
+
+	scan := (&Timeouts{Scan: &ActivityTimeouts{ScheduleToCloseSeconds: 600}}).scan()
+	require.Equal(t, 600, scan.ScheduleToCloseSeconds)
+	require.Equal(t, defaultScanTimeouts.StartToCloseSeconds, scan.StartToCloseSeconds, "unset fields keep the default")
+
+	for name, tc := range map[string]struct {
+		timeouts *Timeouts
+		err      string
+	}{
+		"fine":                  {&Timeouts{Fetch: &ActivityTimeouts{StartToCloseSeconds: 300, HeartbeatSeconds: 60}}, ""},
+		"negative":              {&Timeouts{Scan: &ActivityTimeouts{StartToCloseSeconds: -1}}, "scan activity: timeouts must be positive"},
+		"heartbeat too long":    {&Timeouts{Fetch: &ActivityTimeouts{HeartbeatSeconds: 120}}, "fetch activity: heartbeat timeout (120s) must be shorter than start-to-close (120s)"},
+		"start-to-close beyond": {&Timeouts{Scan: &ActivityTimeouts{StartToCloseSeconds: 900}}, "scan activity: start-to-close timeout (900s) must be shorter than schedule-to-close (900s)"},
+		"report heartbeat":      {&Timeouts{Report: &ActivityTimeouts{HeartbeatSeconds: 5}}, "report activities do not heartbeat"},
+	} {
+		err := tc.timeouts.validate()
+		if tc.err == "" {
+			require.NoError(t, err, name)
+		} else {
+			require.ErrorContains(t, err, tc.err, name)
+		}
+	}
+}
+
+func TestWorkflowRejectsBadTimeouts(t *testing.T) {
+	env := newTestEnv(t)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org:      "acme",
+		Timeouts: &Timeouts{Report: &ActivityTimeouts{StartToCloseSeconds: 300}},
+	})
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+	require.Contains(t, appErr.Error(), "timeouts: report activity: start-to-close timeout (300s) must be shorter than schedule-to-close (120s)")
+}
+
+func TestScheduleToCloseBoundsScanRetries(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-001", mock.Anything, mock.Anything).
+		Return(nil, serverError)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	// The retries wait 5s, then 15s; a 10s schedule-to-close allows only
+	// the first.
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org:      "acme",
+		Timeouts: &Timeouts{Scan: &ActivityTimeouts{StartToCloseSeconds: 5, ScheduleToCloseSeconds: 10}},
+	})
+
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 2+2)
+}
+
+func TestWorstCaseDuration(t *testing.T) {
+	require.Equal(t, 30*time.Minute+15*time.Minute+2*time.Minute, ScanInput{}.WorstCaseDuration(10))
+	require.Equal(t, 30*time.Minute+3*15*time.Minute+2*time.Minute, ScanInput{}.WorstCaseDuration(21))
+
+	in := ScanInput{
+		BatchDelay: &BatchDelay{Seconds: 10, Jitter: 0.5},
+		Timeouts: &Timeouts{
+			Fetch:  &ActivityTimeouts{ScheduleToCloseSeconds: 60},
+			Scan:   &ActivityTimeouts{ScheduleToCloseSeconds: 300},
+			Report: &ActivityTimeouts{ScheduleToCloseSeconds: 60},
+		},
+	}
+	// Three groups of checks, with two pauses of up to 15s between them.
+	require.Equal(t, time.Minute+3*5*time.Minute+2*15*time.Second+time.Minute, in.WorstCaseDuration(30))
+}
@@ -0,0 +1,276 @@
+package scanner
+
+// =============================================================================
+// Token capabilities — which checks the scan's token can actually evaluate
+// =============================================================================
+//
+// A token without the right scope or permission does not fail a check; it
+// makes GitHub answer 403 or 404, which reads as "disabled" or "unknown" in
+// the report. ValidateToken finds out up front. Classic tokens list their
+// scopes in the X-OAuth-Scopes header of any authenticated response.
+// Fine-grained and GitHub App tokens have no such header, so one sample repo
+// is probed with the same endpoints the checks call, after a few are read
+// to see whether the token was granted them (see repoaccess.go).
+//
+// The workflow runs it first when the scan has a token, records the result
+// under token_capabilities in the report, and reports checks the token
+// cannot evaluate at all as StatusNoAccess without calling GitHub for them.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// Token kinds in TokenCapabilities.Kind.
+const (
+	TokenNone        = "none"
+	TokenClassic     = "classic"
+	TokenFineGrained = "fine-grained" // also GitHub App installation tokens
+)
+
+// TokenAccess is how much of a check a token can evaluate.
+type TokenAccess string
+
+const (
+	// AccessFull means the check can be evaluated on every repo.
+	AccessFull TokenAccess = "full"
+	// AccessPartial means some repos (e.g. private ones) cannot be
+	// evaluated; the check still runs.
+	AccessPartial TokenAccess = "partial"
+	// AccessNone means the check cannot be evaluated on any repo; the
+	// workflow reports it as StatusNoAccess without running it.
+	AccessNone TokenAccess = "none"
+	// AccessUnknown means the token could not be tested for the check;
+	// the check still runs.
+	AccessUnknown TokenAccess = "unknown"
+)
+
+// CheckCapability is what a token can do for one check.
+type CheckCapability struct {
+	Check  string      `json:"check"`
+	Access TokenAccess `json:"access"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+// TokenCapabilities is the report ValidateToken returns.
+type TokenCapabilities struct {
+	Kind string `json:"kind"`
+	// CredentialType is the type of the Credential the scan used.
+	CredentialType CredentialType `json:"credential_type,omitempty"`
+	// Scopes are a classic token's OAuth scopes.
+	Scopes []string `json:"scopes,omitempty"`
+	// ProbedRepo is the repo a fine-grained token was tested against, and
+	// RepoAccess how many of the org's repos it could not read.
+	ProbedRepo string            `json:"probed_repo,omitempty"`
+	RepoAccess *RepoAccessSample `json:"repo_access,omitempty"`
+	Checks     []CheckCapability `json:"checks"`
+}
+
+// Unavailable returns the checks with AccessNone.
+func (c *TokenCapabilities) Unavailable() []string {
+	if c == nil {
+		return nil
+	}
+	var out []string
+	for _, cc := range c.Checks {
+		if cc.Access == AccessNone {
+			out = append(out, cc.Check)
+		}
+	}
+	return out
+}
+
+// classicScopes lists, per check, the classic scopes that cover every repo
+// and those that cover public repos only. "" in public means no scope is
+// needed for public repos.
+var classicScopes = map[string]struct{ full, public []string }{
+	CheckSecretScanning: {[]string{"repo"}, []string{"public_repo"}},
+	CheckDependabot:     {[]string{"repo"}, []string{"public_repo"}},
+	CheckCodeScanning:   {[]string{"repo", "security_events"}, []string{"public_repo"}},
+	CheckFiles:          {[]string{"repo"}, []string{""}},
+	CheckActions:        {[]string{"repo"}, nil},
+	CheckAccessAudit:    {[]string{"repo"}, nil},
+	// Reading security updates needs admin access, even on public repos.
+	CheckSecurityUpdates:  {[]string{"repo"}, nil},
+	CheckDependabotConfig: {[]string{"repo"}, []string{""}},
+	// Listing webhooks needs admin access, even on public repos.
+	CheckWebhooks: {[]string{"repo"}, nil},
+}
+
+// ValidateToken reports which of checks (empty means DefaultChecks) cred
+// can evaluate for org, and which type of credential it is. An invalid
+// token or missing org fails non-retryably, so a scan with either stops
+// before fetching any repo.
+func (a *Activities) ValidateToken(ctx context.Context, org string, cred *Credential, checks []string) (*TokenCapabilities, error) {
+	cred, err := a.githubToken(ctx, cred)
+	if err != nil {
+		return nil, err
+	}
+	names := newCheckSet(checks).names()
+	caps := &TokenCapabilities{Kind: TokenNone, CredentialType: CredentialNone}
+	if cred == nil {
+		for _, c := range names {
+			caps.Checks = append(caps.Checks, CheckCapability{Check: c, Access: AccessPartial, Reason: "no token: public repos only"})
+		}
+		return caps, nil
+	}
+
+	caps.CredentialType = cred.kind()
+	headers := map[string]string{
+		"Accept":        "application/vnd.github+json",
+		"Authorization": cred.Authorization(),
+	}
+	resp, body, err := a.get(ctx, a.apiURL("/orgs/%s", org), headers)
+	if err != nil {
+		return nil, fmt.Errorf("validating token: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, temporal.NewNonRetryableApplicationError("invalid GitHub API token", "UNAUTHORIZED", nil)
+	case http.StatusNotFound:
+		return nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("organization '%s' not found", org), "NOT_FOUND", nil)
+	default:
+		return nil, wrapGitHubError(resp, body)
+	}
+
+	if _, classic := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; classic {
+		caps.Kind = TokenClassic
+		caps.Scopes = parseScopes(resp.Header.Get("X-OAuth-Scopes"))
+		for _, c := range names {
+			caps.Checks = append(caps.Checks, classicCapability(c, caps.Scopes))
+		}
+		return caps, nil
+	}
+
+	caps.Kind = TokenFineGrained
+	if err := a.probeToken(ctx, org, headers, names, caps); err != nil {
+		return nil, err
+	}
+	return caps, nil
+}
+
+func parseScopes(header string) []string {
+	var scopes []string
+	for _, s := range strings.Split(header, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+func classicCapability(check string, scopes []string) CheckCapability {
+	has := func(want []string) bool {
+		for _, w := range want {
+			if w == "" {
+				return true
+			}
+			for _, s := range scopes {
+				if s == w {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	rule := classicScopes[check]
+	switch {
+	case has(rule.full):
+		return CheckCapability{Check: check, Access: AccessFull}
+	case has(rule.public):
+		return CheckCapability{Check: check, Access: AccessPartial,
+			Reason: fmt.Sprintf("needs %s scope for private repos", strings.Join(rule.full, " or "))}
+	}
+	return CheckCapability{Check: check, Access: AccessNone,
+		Reason: fmt.Sprintf("needs %s scope", strings.Join(rule.full, " or "))}
+}
+
+// probeToken tests a fine-grained token against the first repo in org it
+// can read, with the endpoints each check calls. A denied request means the
+// token lacks the permission everywhere it was granted the same way. The
+// repos it cannot read at all are sampled too (see repoaccess.go).
+func (a *Activities) probeToken(ctx context.Context, org string, headers map[string]string, names []string, caps *TokenCapabilities) error {
+	sample, repo, err := a.sampleRepoAccess(ctx, org, headers)
+	if err != nil {
+		return err
+	}
+	caps.RepoAccess = sample
+	if repo == "" {
+		reason := "no repo to test the token against"
+		if sample != nil {
+			reason = "the token could not read any sampled repo"
+		}
+		for _, c := range names {
+			caps.Checks = append(caps.Checks, CheckCapability{Check: c, Access: AccessUnknown, Reason: reason})
+		}
+		return nil
+	}
+	caps.ProbedRepo = org + "/" + repo
+
+	probes := map[string]string{
+		CheckSecretScanning:   "/repos/%s/%s",
+		CheckDependabot:       "/repos/%s/%s/vulnerability-alerts",
+		CheckCodeScanning:     "/repos/%s/%s/code-scanning/alerts?per_page=1",
+		CheckFiles:            "/repos/%s/%s/contents/",
+		CheckActions:          "/repos/%s/%s/actions/permissions",
+		CheckAccessAudit:      "/repos/%s/%s/keys?per_page=1",
+		CheckSecurityUpdates:  "/repos/%s/%s/automated-security-fixes",
+		CheckDependabotConfig: "/repos/%s/%s/contents/",
+		CheckWebhooks:         "/repos/%s/%s/hooks?per_page=1",
+	}
+	for _, c := range names {
+		status, body, err := a.checkEndpoint(ctx, a.apiURL(probes[c], org, repo), headers)
+		if err != nil {
+			return fmt.Errorf("validating token: %w", err)
+		}
+		caps.Checks = append(caps.Checks, probeCapability(c, caps.ProbedRepo, status, body))
+	}
+	return nil
+}
+
+func probeCapability(check, repo string, status int, body []byte) CheckCapability {
+	denied := status == http.StatusForbidden && permissionDenied(body)
+	// The deploy key and webhook listings answer 404 on private repos
+	// without admin.
+	if (check == CheckAccessAudit || check == CheckWebhooks) && status == http.StatusNotFound {
+		denied = true
+	}
+	if denied {
+		return CheckCapability{Check: check, Access: AccessNone,
+			Reason: fmt.Sprintf("token was denied on %s: %s", repo, sanitizeErrorMessage(githubMessage(body)))}
+	}
+	if check == CheckSecretScanning && status == http.StatusOK {
+		var r struct {
+			SecurityAndAnalysis json.RawMessage `json:"security_and_analysis"`
+		}
+		if json.Unmarshal(body, &r) == nil && (len(r.SecurityAndAnalysis) == 0 || string(r.SecurityAndAnalysis) == "null") {
+			return CheckCapability{Check: check, Access: AccessPartial,
+				Reason: fmt.Sprintf("%s has no security_and_analysis for this token; repos may read as disabled without the Administration permission", repo)}
+		}
+	}
+	return CheckCapability{Check: check, Access: AccessFull}
+}
+
+// permissionDenied reports whether a 403 body is GitHub refusing the token,
+// as opposed to e.g. a feature being off ("Advanced Security must be
+// enabled") or a rate limit.
+func permissionDenied(body []byte) bool {
+	msg := githubMessage(body)
+	return strings.HasPrefix(msg, "Resource not accessible") || strings.HasPrefix(msg, "Must have admin rights")
+}
+
+func githubMessage(body []byte) string {
+	var e struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &e)
+	return e.Message
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func validateToken(t *testing.T, a *Activities, checks []string) (*TokenCapabilities, error) {
+	t.Helper()
+	token := "ghp_test"
+	val, err := newActivityEnv(a).ExecuteActivity(a.ValidateToken, "acme-corp", PATCredential(&token), checks)
+	if err != nil {
+		return nil, err
+	}
+	var caps TokenCapabilities
+	require.NoError(t, val.Get(&caps))
+	return &caps, nil
+}
+
+func TestValidateTokenClassicScopes(t *testing.T) {
+	f, a := newFakeGitHub(t, map[string]fakeResponse{
+		"/orgs/acme-corp": {http.StatusOK, "org.json"},
+	})
+	f.Header = http.Header{"X-Oauth-Scopes": {"public_repo, read:org"}}
+
+	caps, err := validateToken(t, a, []string{CheckSecretScanning, CheckCodeScanning, CheckFiles, CheckActions})
+	require.NoError(t, err)
+	require.Equal(t, TokenClassic, caps.Kind)
+	require.Equal(t, []string{"public_repo", "read:org"}, caps.Scopes)
+	require.Equal(t, []CheckCapability{
+		{Check: CheckSecretScanning, Access: AccessPartial, Reason: "needs repo scope for private repos"},
+		{Check: CheckCodeScanning, Access: AccessPartial, Reason: "needs repo or security_events scope for private repos"},
+		{Check: CheckFiles, Access: AccessPartial, Reason: "needs repo scope for private repos"},
+		{Check: CheckActions, Access: AccessNone, Reason: "needs repo scope"},
+	}, caps.Checks)
+	require.Equal(t, []string{CheckActions}, caps.Unavailable())
+	require.Len(t, f.Requests(), 1, "classic tokens need no probing")
+
+	f.Header = http.Header{"X-Oauth-Scopes": {"repo"}}
+	caps, err = validateToken(t, a, nil)
+	require.NoError(t, err)
+	require.Empty(t, caps.Unavailable())
+	for _, c := range caps.Checks {
+		require.Equal(t, AccessFull, c.Access, c.Check)
+	}
+}
+
+func TestValidateTokenFineGrainedProbe(t *testing.T) {
+	f, a := newFakeGitHub(t, map[string]fakeResponse{
+		"/orgs/acme-corp":                                              {http.StatusOK, "org.json"},
+		"/orgs/acme-corp/repos?per_page=10":                            {http.StatusOK, "org_repos_sample.json"},
+		"/repos/acme-corp/service-000":                                 {http.StatusOK, "repo_no_security_and_analysis.json"},
+		"/repos/acme-corp/service-001":                                 {http.StatusOK, "repo_no_security_and_analysis.json"},
+		"/repos/acme-corp/service-002":                                 {http.StatusOK, "repo_no_security_and_analysis.json"},
+		"/repos/acme-corp/service-003":                                 {http.StatusOK, "repo_no_security_and_analysis.json"},
+		"/repos/acme-corp/service-004":                                 {http.StatusOK, "repo_no_security_and_analysis.json"},
+		"/repos/acme-corp/service-000/vulnerability-alerts":            {http.StatusForbidden, "contents_forbidden.json"},
+		"/repos/acme-corp/service-000/code-scanning/alerts?per_page=1": {http.StatusForbidden, "code_scanning_ghas_disabled.json"},
+	})
+
+	caps, err := validateToken(t, a, nil)
+	require.NoError(t, err)
+	require.Equal(t, TokenFineGrained, caps.Kind)
+	require.Equal(t, "acme-corp/service-000", caps.ProbedRepo)
+	require.Equal(t, &RepoAccessSample{Sampled: 5}, caps.RepoAccess)
+	require.Len(t, caps.Checks, 3)
+	require.Equal(t, AccessPartial, caps.Checks[0].Access, "no security_and_analysis block")
+	require.Equal(t, CheckCapability{
+		Check: CheckDependabot, Access: AccessNone,
+		Reason: "token was denied on acme-corp/service-000: Resource not accessible by integration",
+	}, caps.Checks[1])
+	require.Equal(t, AccessFull, caps.Checks[2].Access, "GHAS being off is not a token problem")
+	require.Equal(t, []string{CheckDependabot}, caps.Unavailable())
+	require.Equal(t, "token ghp_test", f.Requests()[0].Header.Get("Authorization"))
+}
+
+func TestValidateTokenFailures(t *testing.T) {
+	for name, tc := range map[string]struct {
+		resp    fakeResponse
+		errType string
+	}{
+		"bad token":   {fakeResponse{http.StatusUnauthorized, "bad_credentials.json"}, "UNAUTHORIZED"},
+		"missing org": {fakeResponse{http.StatusNotFound, "not_found.json"}, "NOT_FOUND"},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			_, a := newFakeGitHub(t, map[string]fakeResponse{"/orgs/acme-corp": tc.resp})
+			_, err := validateToken(t, a, nil)
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(err, &appErr), "got %v", err)
+			require.Equal(t, tc.errType, appErr.Type())
+			require.True(t, appErr.NonRetryable())
+		})
+	}
+}
+
+func TestWorkflowSkipsChecksTheTokenCannotEvaluate(t *testing.T) {
+	env := newTestEnv(t)
+	token := "ghp_test"
+	caps := &TokenCapabilities{Kind: TokenClassic, Scopes: []string{"public_repo"}, Checks: []CheckCapability{
+		{Check: CheckSecretScanning, Access: AccessPartial},
+		{Check: CheckDependabot, Access: AccessNone, Reason: "needs repo scope"},
+		{Check: CheckCodeScanning, Access: AccessPartial},
+	}}
+	env.OnActivity("ValidateToken", mock.Anything, "acme", PATCredential(&token), DefaultChecks()).Return(caps, nil)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "acme", mock.Anything, PATCredential(&token), []string{CheckSecretScanning, CheckCodeScanning}).
+		Return(func(_ context.Context, _, repo string, _ *Credential, _ []string) (*RepoSecurityResult, error) {
+			return &RepoSecurityResult{Repository: repo, SecretScanning: StatusEnabled, CodeScanning: StatusEnabled}, nil
+		})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Token: &token})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report struct {
+		FullyCompliant    int                 `json:"fully_compliant"`
+		DependabotEnabled int                 `json:"dependabot_enabled"`
+		RepoFailures      map[string][]string `json:"repo_failures"`
+		TokenCapabilities *TokenCapabilities  `json:"token_capabilities"`
+	}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 0, report.FullyCompliant, "no access still fails a required check")
+	require.Equal(t, 0, report.DependabotEnabled)
+	require.Equal(t, []string{CheckDependabot}, report.RepoFailures["repo-000"])
+	require.Equal(t, caps, report.TokenCapabilities)
+}
+
+func TestWorkflowFailsWhenTokenCanEvaluateNothing(t *testing.T) {
+	env := newTestEnv(t)
+	token := "ghp_test"
+	env.OnActivity("ValidateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&TokenCapabilities{Kind: TokenClassic, Checks: []CheckCapability{
+			{Check: CheckActions, Access: AccessNone},
+		}}, nil)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Token: &token, Checks: []string{CheckActions}})
+
+	require.True(t, env.IsWorkflowCompleted())
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr), "got %v", env.GetWorkflowError())
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+}
@@ -0,0 +1,119 @@
+package scanner
+
+// =============================================================================
+// Topic scoping — scanning repos by their GitHub topics
+// =============================================================================
+//
+// Orgs tag repos with topics such as tier-1 or pci. ScanInput.IncludeTopics
+// scans only the repos with at least one of them, and ExcludeTopics leaves
+// out the repos with any of its. Both compare case-insensitively, as GitHub
+// does, and read the topics from the repo listing, so filtering costs no
+// API calls; a repo's result carries the IncludeTopics it matched. Repos
+// named in ScanInput.Repos are not listed and have no topics, so the
+// filters do not combine with it. A filtered scan covers part of the org,
+// so it neither pages nor becomes the baseline (see scanhistory.go).
+//
+// The report's by_topic section breaks compliance down per topic, as
+// by_visibility does per visibility. A repo with several topics counts in
+// each of their buckets, so the buckets overlap; repos without topics are
+// bucketed as "untagged".
+// =============================================================================
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BucketUntagged is the by_topic bucket of repos without topics.
+const BucketUntagged = "untagged"
+
+// validateTopics checks the topic filters.
+func (in ScanInput) validateTopics() error {
+	if len(in.IncludeTopics) == 0 && len(in.ExcludeTopics) == 0 {
+		return nil
+	}
+	if len(in.Repos) > 0 {
+		return fmt.Errorf("include_topics and exclude_topics cannot be combined with repos, which have no topics")
+	}
+	for _, t := range append(append([]string{}, in.IncludeTopics...), in.ExcludeTopics...) {
+		if strings.TrimSpace(t) == "" {
+			return fmt.Errorf("topic filters must not be empty")
+		}
+	}
+	for _, t := range in.IncludeTopics {
+		if hasTopic(in.ExcludeTopics, t) {
+			return fmt.Errorf("topic %q is both included and excluded", t)
+		}
+	}
+	return nil
+}
+
+// hasTopic reports whether topics holds topic, ignoring case.
+func hasTopic(topics []string, topic string) bool {
+	for _, t := range topics {
+		if strings.EqualFold(t, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTopics returns the IncludeTopics r has, lowercased and sorted, and
+// whether the topic filters keep r.
+func (in ScanInput) matchTopics(r RepoInfo) ([]string, bool) {
+	for _, t := range in.ExcludeTopics {
+		if hasTopic(r.Topics, t) {
+			return nil, false
+		}
+	}
+	if len(in.IncludeTopics) == 0 {
+		return nil, true
+	}
+	var matched []string
+	for _, t := range in.IncludeTopics {
+		if hasTopic(r.Topics, t) && !hasTopic(matched, t) {
+			matched = append(matched, strings.ToLower(t))
+		}
+	}
+	sort.Strings(matched)
+	return matched, len(matched) > 0
+}
+
+// filterTopics returns the repos the topic filters keep, the names of
+// those they leave out, and the topics each kept repo matched.
+func (in ScanInput) filterTopics(repos []RepoInfo) ([]RepoInfo, []string, map[string][]string) {
+	if len(in.IncludeTopics) == 0 && len(in.ExcludeTopics) == 0 {
+		return repos, nil, nil
+	}
+	kept := repos[:0]
+	var skipped []string
+	matched := map[string][]string{}
+	for _, r := range repos {
+		topics, ok := in.matchTopics(r)
+		if !ok {
+			skipped = append(skipped, r.Name)
+			continue
+		}
+		if len(topics) > 0 {
+			matched[r.Name] = topics
+		}
+		kept = append(kept, r)
+	}
+	sort.Strings(skipped)
+	return kept, skipped, matched
+}
+
+// topicBuckets are the by_topic buckets of a repo with meta.
+func topicBuckets(meta RepoMetadata) []string {
+	var buckets []string
+	for _, t := range meta.Topics {
+		if t = strings.ToLower(t); !hasTopic(buckets, t) {
+			buckets = append(buckets, t)
+		}
+	}
+	if len(buckets) == 0 {
+		return []string{BucketUntagged}
+	}
+	return buckets
+}
@@ -0,0 +1,140 @@
+package scanner
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func topicRepo(name string, topics ...string) RepoInfo {
+	return RepoInfo{Name: name, FullName: "acme/" + name, RepoMetadata: RepoMetadata{Visibility: "private", Topics: topics}}
+}
+
+func TestFilterTopics(t *testing.T) {
+	repos := []RepoInfo{
+		topicRepo("api", "tier-1", "go"),
+		topicRepo("web", "Tier-1", "deprecated"),
+		topicRepo("billing", "pci", "tier-2"),
+		topicRepo("docs"),
+	}
+
+	in := ScanInput{IncludeTopics: []string{"TIER-1", "pci"}, ExcludeTopics: []string{"deprecated"}}
+	kept, skipped, matched := in.filterTopics(append([]RepoInfo{}, repos...))
+	var names []string
+	for _, r := range kept {
+		names = append(names, r.Name)
+	}
+	require.Equal(t, []string{"api", "billing"}, names)
+	require.Equal(t, []string{"docs", "web"}, skipped)
+	require.Equal(t, map[string][]string{"api": {"tier-1"}, "billing": {"pci"}}, matched)
+
+	// Excluding alone keeps the untagged repos and matches nothing.
+	kept, skipped, matched = ScanInput{ExcludeTopics: []string{"Deprecated"}}.filterTopics(append([]RepoInfo{}, repos...))
+	require.Len(t, kept, 3)
+	require.Equal(t, []string{"web"}, skipped)
+	require.Empty(t, matched)
+
+	// Without filters the list is untouched.
+	kept, skipped, _ = ScanInput{}.filterTopics(repos)
+	require.Equal(t, repos, kept)
+	require.Empty(t, skipped)
+}
+
+func TestValidateTopics(t *testing.T) {
+	require.NoError(t, ScanInput{IncludeTopics: []string{"tier-1"}, ExcludeTopics: []string{"deprecated"}}.validateTopics())
+	require.ErrorContains(t, ScanInput{IncludeTopics: []string{"tier-1"}, Repos: []string{"acme/api"}}.validateTopics(), "cannot be combined with repos")
+	require.ErrorContains(t, ScanInput{ExcludeTopics: []string{" "}}.validateTopics(), "must not be empty")
+	require.ErrorContains(t, ScanInput{IncludeTopics: []string{"pci"}, ExcludeTopics: []string{"PCI"}}.validateTopics(), `topic "pci" is both included and excluded`)
+}
+
+func TestGenerateReportByTopic(t *testing.T) {
+	repo := func(name string, code SecurityStatus, topics ...string) RepoSecurityResult {
+		r := RepoSecurityResult{Repository: name, Metadata: &RepoMetadata{Visibility: "private", Topics: topics}}
+		r.setCheck(CheckSecretScanning, CheckResult{Status: StatusEnabled})
+		r.setCheck(CheckDependabot, CheckResult{Status: StatusEnabled})
+		r.setCheck(CheckCodeScanning, CheckResult{Status: code})
+		return r
+	}
+	results := []RepoSecurityResult{
+		repo("api", StatusEnabled, "tier-1", "go"),
+		repo("web", StatusNotConfigured, "Tier-1"),
+		repo("docs", StatusEnabled),
+	}
+	env := newActivityEnv(&Activities{})
+	val, err := env.ExecuteActivity("GenerateReport", "acme", results,
+		[]BlobRef(nil), DefaultCompliancePolicy(), []string(nil), []Suppression(nil))
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, val.Get(&report))
+
+	require.Equal(t, map[string]ComplianceBreakdown{
+		"tier-1":       {Repos: 2, FullyCompliant: 1, NonCompliant: 1, ComplianceRate: "50.0%", TopNonCompliantRepos: []string{"web"}},
+		"go":           {Repos: 1, FullyCompliant: 1, ComplianceRate: "100.0%"},
+		BucketUntagged: {Repos: 1, FullyCompliant: 1, ComplianceRate: "100.0%"},
+	}, report.ByTopic)
+	require.NoError(t, report.Validate())
+}
+
+func TestValidateTopicBreakdown(t *testing.T) {
+	r := Report{TotalRepos: 3, FullyCompliant: 1, ComplianceRate: "33.3%", NonCompliantRepos: []string{"a", "b"}}
+	r.ByTopic = map[string]ComplianceBreakdown{
+		"tier-1": {Repos: 3, FullyCompliant: 1, NonCompliant: 2, ComplianceRate: "33.3%"},
+		"pci":    {Repos: 2, NonCompliant: 2, ComplianceRate: "0.0%"},
+	}
+	require.NoError(t, r.Validate(), "buckets overlap")
+
+	r.ByTopic["pci"] = ComplianceBreakdown{Repos: 4, NonCompliant: 2, ComplianceRate: "0.0%"}
+	require.ErrorContains(t, r.Validate(), "by_topic[pci] has 4 repos")
+
+	r.ByTopic = map[string]ComplianceBreakdown{"pci": {Repos: 2, NonCompliant: 2, ComplianceRate: "0.0%"}}
+	require.ErrorContains(t, r.Validate(), "by_topic adds up to 2 repos, fewer than 3")
+}
+
+func TestRenderReportByTopic(t *testing.T) {
+	r := renderFixture()
+	r.SkippedByTopic = 4
+	r.ByTopic = map[string]ComplianceBreakdown{
+		"tier-1": {Repos: 2, FullyCompliant: 1, NonCompliant: 1, ComplianceRate: "50.0%", TopNonCompliantRepos: []string{"web"}},
+	}
+	var out bytes.Buffer
+	RenderReport(&out, r, RenderOptions{Verbose: true})
+	require.Contains(t, out.String(), "  Skipped (topic):      4\n")
+	require.Contains(t, out.String(), "By topic:\n    tier-1          2 repos   50.0%  behind: web\n")
+}
+
+func TestWorkflowScansOnlyMatchingTopics(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, []RepoInfo{
+		topicRepo("api", "tier-1"),
+		topicRepo("web", "TIER-1", "deprecated"),
+		topicRepo("billing", "Tier-1", "pci"),
+		topicRepo("docs"),
+	})
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("billing"))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", IncludeTopics: []string{"tier-1"}, ExcludeTopics: []string{"Deprecated"}})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 2, report.TotalRepos)
+	require.Equal(t, 2, report.SkippedByTopic)
+	require.Equal(t, []string{"tier-1"}, report.IncludeTopics)
+	require.Equal(t, []string{"Deprecated"}, report.ExcludeTopics)
+	require.Equal(t, ComplianceBreakdown{Repos: 2, FullyCompliant: 1, NonCompliant: 1, ComplianceRate: "50.0%", TopNonCompliantRepos: []string{"billing"}}, report.ByTopic["tier-1"])
+	require.Equal(t, []string{"pci", "tier-1"}, sortedKeys(report.ByTopic))
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 2)
+
+	val, err := env.QueryWorkflow("results_so_far")
+	require.NoError(t, err)
+	var results []RepoSecurityResult
+	require.NoError(t, val.Get(&results))
+	require.Len(t, results, 2)
+	for _, r := range results {
+		require.Equal(t, []string{"tier-1"}, r.MatchedTopics, r.Repository)
+	}
+}
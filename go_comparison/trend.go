@@ -0,0 +1,245 @@
+package scanner
+
+// =============================================================================
+// Compliance trend — how an org's compliance moved over its last scans
+// =============================================================================
+//
+// A ComplianceTrend is a point per completed full-org scan, oldest first:
+// the compliance rate, per-check adoption and the non-compliant repos. It
+// comes from a ScanHistory:
+//
+//	BlobHistory  the worker's blob store. SaveLastReport appends each scan
+//	             to history/<provider>/<org>/trend.json next to the
+//	             baseline (see scanhistory.go), keeping the last
+//	             MaxTrendPoints.
+//	DirHistory   a directory of saved reports, security_scan_<org>*.json as
+//	             the starter writes them, for setups without a blob store.
+//
+// Movers are the repos whose compliance flipped between two scans of the
+// trend, most recent flip first. Points only list the non-compliant repos,
+// so a repo that joins the org failing a check counts as a flip too.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// MaxTrendPoints is how many scans BlobHistory keeps per org: two years of
+// weekly scans.
+const MaxTrendPoints = 104
+
+// TrendPoint is one scan of a ComplianceTrend.
+type TrendPoint struct {
+	CompletedAt    string  `json:"completed_at"`
+	RunID          string  `json:"run_id,omitempty"`
+	InitiatedBy    string  `json:"initiated_by,omitempty"`
+	Reason         string  `json:"reason,omitempty"`
+	ComplianceRate float64 `json:"compliance_rate"`
+	TotalRepos     int     `json:"total_repos"`
+	// Adoption is the repos passing each check the scan ran.
+	Adoption     map[string]int `json:"adoption,omitempty"`
+	NonCompliant []string       `json:"non_compliant_repos"`
+}
+
+// NewTrendPoint is r as a point of a trend.
+func NewTrendPoint(r Report) TrendPoint {
+	p := TrendPoint{
+		CompletedAt:    r.CompletedAt,
+		RunID:          r.RunID,
+		InitiatedBy:    r.InitiatedBy,
+		Reason:         r.Reason,
+		ComplianceRate: r.Rate(),
+		TotalRepos:     r.TotalRepos,
+		Adoption:       r.Adoption(),
+		NonCompliant:   r.NonCompliantRepos,
+	}
+	if p.NonCompliant == nil {
+		p.NonCompliant = []string{}
+	}
+	return p
+}
+
+// RepoMover is a repo whose compliance flipped within a trend.
+type RepoMover struct {
+	Repository string `json:"repository"`
+	// Compliant is the repo's state after its last flip.
+	Compliant bool `json:"compliant"`
+	// FlippedAt is the completed_at of the scan that saw the last flip.
+	FlippedAt string `json:"flipped_at"`
+	// Flips is how often the repo flipped within the trend.
+	Flips int `json:"flips"`
+}
+
+// ComplianceTrend is an org's last scans, oldest first.
+type ComplianceTrend struct {
+	Org    string       `json:"org"`
+	Points []TrendPoint `json:"points"`
+	Movers []RepoMover  `json:"movers"`
+}
+
+// ScanHistory is where past scans of an org are kept.
+type ScanHistory interface {
+	// GetComplianceTrend returns the last lastN scans of org, all of them
+	// when lastN is 0. An org that was never scanned has an empty trend.
+	GetComplianceTrend(ctx context.Context, org string, lastN int) (*ComplianceTrend, error)
+}
+
+// NewComplianceTrend is the trend of the last lastN of points, which must
+// be oldest first, with the movers between them.
+func NewComplianceTrend(org string, points []TrendPoint, lastN int) *ComplianceTrend {
+	if lastN > 0 && len(points) > lastN {
+		points = points[len(points)-lastN:]
+	}
+	t := &ComplianceTrend{Org: org, Points: points, Movers: []RepoMover{}}
+	if t.Points == nil {
+		t.Points = []TrendPoint{}
+	}
+	movers := make(map[string]*RepoMover)
+	for i := 1; i < len(points); i++ {
+		before, after := stringSet(points[i-1].NonCompliant), stringSet(points[i].NonCompliant)
+		flip := func(repo string, compliant bool) {
+			m := movers[repo]
+			if m == nil {
+				m = &RepoMover{Repository: repo}
+				movers[repo] = m
+			}
+			m.Compliant, m.FlippedAt = compliant, points[i].CompletedAt
+			m.Flips++
+		}
+		for repo := range after {
+			if !before[repo] {
+				flip(repo, false)
+			}
+		}
+		for repo := range before {
+			if !after[repo] {
+				flip(repo, true)
+			}
+		}
+	}
+	for _, repo := range sortedKeys(movers) {
+		t.Movers = append(t.Movers, *movers[repo])
+	}
+	sort.SliceStable(t.Movers, func(i, j int) bool {
+		return t.Movers[i].FlippedAt > t.Movers[j].FlippedAt
+	})
+	return t
+}
+
+func stringSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, s := range items {
+		set[s] = true
+	}
+	return set
+}
+
+// trendKey is the blob store key of org's trend points.
+func trendKey(provider, org string) string {
+	return "history/" + providerName(provider) + "/" + org + "/trend.json"
+}
+
+// loadTrendPoints reads org's trend points from store; none if there are
+// none yet.
+func loadTrendPoints(ctx context.Context, store BlobStore, provider, org string) ([]TrendPoint, error) {
+	data, err := store.Get(ctx, store.URI(trendKey(provider, org)))
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading trend: %w", err)
+	}
+	var points []TrendPoint
+	if err := json.Unmarshal(data, &points); err != nil {
+		return nil, fmt.Errorf("decoding trend: %w", err)
+	}
+	return points, nil
+}
+
+// appendTrendPoint adds p to org's trend, dropping the oldest points past
+// MaxTrendPoints. A point whose run is already in the trend replaces it,
+// so a retried save does not count the scan twice.
+func appendTrendPoint(ctx context.Context, store BlobStore, provider, org string, p TrendPoint) error {
+	points, err := loadTrendPoints(ctx, store, provider, org)
+	if err != nil {
+		return err
+	}
+	if n := len(points); n > 0 && p.RunID != "" && points[n-1].RunID == p.RunID {
+		points = points[:n-1]
+	}
+	points = append(points, p)
+	if len(points) > MaxTrendPoints {
+		points = points[len(points)-MaxTrendPoints:]
+	}
+	data, err := json.Marshal(points)
+	if err != nil {
+		return fmt.Errorf("encoding trend: %w", err)
+	}
+	if _, err := store.Put(ctx, trendKey(provider, org), data); err != nil {
+		return fmt.Errorf("saving trend: %w", err)
+	}
+	return nil
+}
+
+// BlobHistory is the scan history the workers keep in a blob store.
+type BlobHistory struct {
+	Store BlobStore
+	// Provider of the orgs; empty means GitHub.
+	Provider string
+}
+
+// GetComplianceTrend implements ScanHistory.
+func (h BlobHistory) GetComplianceTrend(ctx context.Context, org string, lastN int) (*ComplianceTrend, error) {
+	points, err := loadTrendPoints(ctx, h.Store, h.Provider, org)
+	if err != nil {
+		return nil, err
+	}
+	return NewComplianceTrend(org, points, lastN), nil
+}
+
+// DirHistory is the scan history in a directory of saved reports, as the
+// starter writes them: security_scan_<org>.json, usually renamed with a
+// date when kept, e.g. security_scan_acme_2026-03-02.json.
+type DirHistory struct {
+	Dir string
+}
+
+// GetComplianceTrend implements ScanHistory. Reports of other orgs whose
+// name starts the same, cancelled scans and copies of a run already read
+// are left out.
+func (h DirHistory) GetComplianceTrend(_ context.Context, org string, lastN int) (*ComplianceTrend, error) {
+	paths, err := filepath.Glob(filepath.Join(h.Dir, "security_scan_"+org+"*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var points []TrendPoint
+	runs := make(map[string]bool)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		r, err := ParseReport(data)
+		if err != nil {
+			return nil, fmt.Errorf("reading report %s: %w", path, err)
+		}
+		if r.Org != org || r.Cancelled || r.CompletedAt == "" {
+			continue
+		}
+		if r.RunID != "" {
+			if runs[r.RunID] {
+				continue
+			}
+			runs[r.RunID] = true
+		}
+		points = append(points, NewTrendPoint(r))
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].CompletedAt < points[j].CompletedAt })
+	return NewComplianceTrend(org, points, lastN), nil
+}
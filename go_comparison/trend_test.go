@@ -0,0 +1,135 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewComplianceTrend(t *testing.T) {
+	points := []TrendPoint{
+		{CompletedAt: "2026-01-05T10:00:00Z", NonCompliant: []string{"api", "web"}},
+		{CompletedAt: "2026-01-12T10:00:00Z", NonCompliant: []string{"api"}},
+		{CompletedAt: "2026-01-19T10:00:00Z", NonCompliant: []string{"api", "web"}},
+		{CompletedAt: "2026-01-26T10:00:00Z", NonCompliant: []string{"web", "docs"}},
+	}
+	trend := NewComplianceTrend("acme", points, 0)
+	require.Len(t, trend.Points, 4)
+	require.Equal(t, []RepoMover{
+		{Repository: "api", Compliant: true, FlippedAt: "2026-01-26T10:00:00Z", Flips: 1},
+		{Repository: "docs", Compliant: false, FlippedAt: "2026-01-26T10:00:00Z", Flips: 1},
+		{Repository: "web", Compliant: false, FlippedAt: "2026-01-19T10:00:00Z", Flips: 2},
+	}, trend.Movers)
+
+	// Only flips within the window count.
+	trend = NewComplianceTrend("acme", points, 2)
+	require.Equal(t, points[2:], trend.Points)
+	require.Equal(t, []string{"api", "docs"}, []string{trend.Movers[0].Repository, trend.Movers[1].Repository}, "web last flipped before the window")
+
+	trend = NewComplianceTrend("acme", nil, 12)
+	require.Empty(t, trend.Points)
+	require.Empty(t, trend.Movers)
+	b, err := json.Marshal(trend)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"org":"acme","points":[],"movers":[]}`, string(b), "dashboards get arrays, not null")
+}
+
+func TestNewTrendPoint(t *testing.T) {
+	secret, dependabot := 4, 3
+	p := NewTrendPoint(Report{
+		CompletedAt: "2026-01-05T10:00:00Z", RunID: "run-1", TotalRepos: 4, ComplianceRate: "75.0%",
+		SecretScanningEnabled: &secret, DependabotEnabled: &dependabot, NonCompliantRepos: []string{"api"},
+	})
+	require.Equal(t, TrendPoint{
+		CompletedAt: "2026-01-05T10:00:00Z", RunID: "run-1", ComplianceRate: 75, TotalRepos: 4,
+		Adoption:     map[string]int{CheckSecretScanning: 4, CheckDependabot: 3},
+		NonCompliant: []string{"api"},
+	}, p)
+}
+
+func TestDirHistory(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644))
+	}
+	write("security_scan_acme.json", `{"org":"acme","run_id":"run-3","completed_at":"2026-01-19T10:00:00Z","total_repos":2,"compliance_rate":"100.0%","non_compliant_repos":[]}`)
+	write("security_scan_acme_2026-01-12.json", `{"org":"acme","run_id":"run-2","completed_at":"2026-01-12T10:00:00Z","total_repos":2,"compliance_rate":"50.0%","non_compliant_repos":["api"]}`)
+	write("security_scan_acme_copy.json", `{"org":"acme","run_id":"run-2","completed_at":"2026-01-12T10:00:00Z","total_repos":2,"compliance_rate":"50.0%","non_compliant_repos":["api"]}`)
+	write("security_scan_acme_2026-01-05.json", `{"org":"acme","run_id":"run-1","completed_at":"2026-01-05T10:00:00Z","total_repos":2,"compliance_rate":"100.0%","non_compliant_repos":[]}`)
+	write("security_scan_acme_cancelled.json", `{"org":"acme","run_id":"run-4","completed_at":"2026-01-20T10:00:00Z","cancelled":true,"total_repos":1,"compliance_rate":"0.0%","non_compliant_repos":["api"]}`)
+	write("security_scan_acme-labs.json", `{"org":"acme-labs","run_id":"run-9","completed_at":"2026-01-19T10:00:00Z","total_repos":1,"compliance_rate":"0.0%","non_compliant_repos":["x"]}`)
+
+	trend, err := DirHistory{Dir: dir}.GetComplianceTrend(context.Background(), "acme", 12)
+	require.NoError(t, err)
+	var runs []string
+	for _, p := range trend.Points {
+		runs = append(runs, p.RunID)
+	}
+	require.Equal(t, []string{"run-1", "run-2", "run-3"}, runs)
+	require.Equal(t, []RepoMover{{Repository: "api", Compliant: true, FlippedAt: "2026-01-19T10:00:00Z", Flips: 2}}, trend.Movers)
+
+	trend, err = DirHistory{Dir: dir}.GetComplianceTrend(context.Background(), "globex", 12)
+	require.NoError(t, err)
+	require.Empty(t, trend.Points)
+
+	write("security_scan_acme_broken.json", `{`)
+	_, err = DirHistory{Dir: dir}.GetComplianceTrend(context.Background(), "acme", 12)
+	require.ErrorContains(t, err, "security_scan_acme_broken.json")
+}
+
+func TestBlobHistory(t *testing.T) {
+	store := &FileBlobStore{Dir: t.TempDir()}
+	pagedScan(t, store, nil)
+
+	a := &Activities{BlobStore: store}
+	env := newActivityEnv(a)
+	for _, r := range []Report{
+		{Org: "acme", RunID: "run-2", CompletedAt: "2099-01-12T10:00:00Z", TotalRepos: 3, ComplianceRate: "66.7%", NonCompliantRepos: []string{"repo-001"}},
+		{Org: "acme", RunID: "run-3", CompletedAt: "2099-01-19T10:00:00Z", TotalRepos: 3, ComplianceRate: "66.7%", NonCompliantRepos: []string{"repo-002"}},
+	} {
+		b, err := json.Marshal(r)
+		require.NoError(t, err)
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal(b, &m))
+		_, err = env.ExecuteActivity(a.SaveLastReport, ProviderGitHub, "acme", m)
+		require.NoError(t, err)
+	}
+
+	trend, err := BlobHistory{Store: store}.GetComplianceTrend(context.Background(), "acme", 0)
+	require.NoError(t, err)
+	require.Len(t, trend.Points, 3)
+	require.Equal(t, 100.0, trend.Points[0].ComplianceRate, "the scan's own point")
+	require.Equal(t, 3, trend.Points[0].Adoption[CheckSecretScanning])
+
+	trend, err = BlobHistory{Store: store}.GetComplianceTrend(context.Background(), "acme", 2)
+	require.NoError(t, err)
+	require.Len(t, trend.Points, 2)
+	require.Equal(t, []RepoMover{
+		{Repository: "repo-001", Compliant: true, FlippedAt: "2099-01-19T10:00:00Z", Flips: 1},
+		{Repository: "repo-002", Compliant: false, FlippedAt: "2099-01-19T10:00:00Z", Flips: 1},
+	}, trend.Movers)
+
+	trend, err = BlobHistory{Store: store, Provider: ProviderGitLab}.GetComplianceTrend(context.Background(), "acme", 0)
+	require.NoError(t, err)
+	require.Empty(t, trend.Points)
+}
+
+func TestTrendKeepsMaxPoints(t *testing.T) {
+	store := &FileBlobStore{Dir: t.TempDir()}
+	ctx := context.Background()
+	for i := 0; i < MaxTrendPoints+2; i++ {
+		require.NoError(t, appendTrendPoint(ctx, store, "", "acme", TrendPoint{RunID: string(rune('a' + i%26)), TotalRepos: i}))
+	}
+	// A retried save of the last run replaces its point.
+	require.NoError(t, appendTrendPoint(ctx, store, "", "acme", TrendPoint{RunID: string(rune('a' + (MaxTrendPoints+1)%26)), TotalRepos: 999}))
+
+	points, err := loadTrendPoints(ctx, store, "", "acme")
+	require.NoError(t, err)
+	require.Len(t, points, MaxTrendPoints)
+	require.Equal(t, 2, points[0].TotalRepos)
+	require.Equal(t, 999, points[len(points)-1].TotalRepos)
+}
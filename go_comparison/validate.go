@@ -0,0 +1,239 @@
+package scanner
+
+// =============================================================================
+// Input validation — every problem with a scan's input, by field
+// =============================================================================
+//
+// ScanInput.Validate checks every rule the workflow enforces before it
+// fetches a repo and reports all the broken ones at once, each under the
+// JSON name of its field: a starter with three typos hears about three, not
+// one per attempt. The starter calls it before starting a scan; the workflow
+// calls it once org config has filled in the input (see orgconfig.go), so
+// scans started by a Schedule or another client are held to the same rules,
+// and fails with an ErrTypeInvalidInput error whose details are the
+// ValidationError. EnterpriseScanInput.Validate does the same for enterprise
+// scans, with the shared scan's fields under "scan.".
+// =============================================================================
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// FieldError is one problem with one field of a scan's input.
+type FieldError struct {
+	// Field is the field's JSON name, e.g. "repos[2]" or
+	// "compliance_policy.scoring".
+	Field   string `json:"field"`
+	Problem string `json:"problem"`
+}
+
+func (e FieldError) Error() string { return e.Field + ": " + e.Problem }
+
+// ValidationError lists every problem found with an input.
+type ValidationError struct {
+	// Input names what was validated, e.g. "scan input".
+	Input  string       `json:"input"`
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		problems[i] = fe.Error()
+	}
+	return "invalid " + e.Input + ": " + strings.Join(problems, "; ")
+}
+
+// add records err, if any, against field.
+func (e *ValidationError) add(field string, err error) {
+	if err != nil {
+		e.addf(field, "%s", err)
+	}
+}
+
+func (e *ValidationError) addf(field, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Problem: fmt.Sprintf(format, args...)})
+}
+
+// err is e, or nil when it holds no problems.
+func (e *ValidationError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate returns a *ValidationError listing every problem with in, or
+// nil.
+func (in ScanInput) Validate() error {
+	v := in.validation(true)
+	v.add("suppressions", ValidateSuppressions(in.Suppressions))
+	return v.err()
+}
+
+// validation checks everything Validate does but Suppressions, which the
+// workflow has always checked after recording its start; checking them
+// earlier would change the history of runs that fail on them. Without
+// strict it skips the rules that came with Validate, for runs recorded
+// before them (see changeInputRules).
+func (in ScanInput) validation(strict bool) *ValidationError {
+	v := &ValidationError{Input: "scan input"}
+	if in.Org == "" && len(in.Repos) > 0 {
+		v.addf("org", "org must be set to the owner of repos")
+	} else {
+		v.add("org", in.validateOrg())
+	}
+	in.validateSettings(v, "", strict)
+	return v
+}
+
+// validateSettings adds to v the problems with every field of in but Org
+// and Suppressions, prefixing their names with prefix.
+func (in ScanInput) validateSettings(v *ValidationError, prefix string, strict bool) {
+	field := func(name string) string { return prefix + name }
+
+	checksOK := ValidateChecks(in.Checks) == nil
+	v.add(field("checks"), ValidateChecks(in.Checks))
+	checks := in.checks()
+	v.add(field("provider"), ValidateProvider(in.Provider, checks.names()))
+	v.add(field("credential"), in.validateCredential())
+
+	// A custom policy must not require a check that will not run.
+	if p := in.CompliancePolicy; p != nil {
+		if checksOK {
+			for _, c := range p.requiredChecks() {
+				if !checks[c] {
+					v.addf(field("compliance_policy"), "compliance policy requires check %q, which is not selected", c)
+				}
+			}
+		}
+		if p.Scoring != nil {
+			v.add(field("compliance_policy.scoring"), p.Scoring.Validate())
+		}
+		v.add(field("compliance_policy.webhook_allowed_domains"), p.validateWebhookAllowlist())
+	}
+	if strict {
+		in.validateCounts(v, prefix)
+	}
+
+	in.validateRepos(v, prefix)
+	if in.BatchDelay != nil {
+		v.add(field("batch_delay"), in.BatchDelay.validate())
+	}
+	if in.MaxAPIRequests < 0 {
+		v.addf(field("max_api_requests"), "max API requests must not be negative, got %d", in.MaxAPIRequests)
+	}
+	v.add(field("priority_repos"), in.validatePriority())
+	topics := "include_topics"
+	if len(in.IncludeTopics) == 0 {
+		topics = "exclude_topics"
+	}
+	v.add(field(topics), in.validateTopics())
+	if in.MaxDurationSeconds < 0 {
+		v.addf(field("max_duration_seconds"), "max duration must not be negative, got %ds", in.MaxDurationSeconds)
+	}
+	if in.Timeouts != nil {
+		v.add(field("timeouts"), in.Timeouts.validate())
+	}
+	v.add(field("retry_policies"), in.RetryPolicies.validate())
+	v.add(field("concurrency"), in.validateConcurrency())
+	v.add(field("worker_affinity"), in.validateWorkerAffinity())
+	if in.StragglerTimeout != nil {
+		if in.ActivityBatching {
+			v.addf(field("straggler_timeout"), "straggler timeout does not apply with activity batching")
+		}
+		v.add(field("straggler_timeout"), in.StragglerTimeout.validate())
+	}
+	if in.ProgressWebhook != nil {
+		v.add(field("progress_webhook"), in.ProgressWebhook.validate())
+	}
+	for i, spec := range in.Sinks {
+		v.add(field(fmt.Sprintf("sinks[%d]", i)), spec.validate())
+	}
+	if in.ResumeFrom != nil {
+		v.add(field("resume_from"), in.ResumeFrom.validate())
+	}
+	v.add(field("initiated_by"), ValidateInitiator(CleanScanLabel(in.InitiatedBy), ""))
+	v.add(field("reason"), ValidateInitiator("", CleanScanLabel(in.Reason)))
+}
+
+// validateCounts adds to v the problems with FailurePolicy, the day and
+// interval counts and PriorityTopics, which went unchecked before Validate.
+func (in ScanInput) validateCounts(v *ValidationError, prefix string) {
+	if p := in.FailurePolicy; p != nil {
+		if p.MaxErrorRatio < 0 || p.MaxErrorRatio > 1 {
+			v.addf(prefix+"failure_policy.max_error_ratio", "max error ratio must be between 0 and 1, got %g", p.MaxErrorRatio)
+		}
+		if p.MinRepos < 0 {
+			v.addf(prefix+"failure_policy.min_repos", "min repos must not be negative, got %d", p.MinRepos)
+		}
+	}
+	for _, c := range []struct {
+		field string
+		n     int
+	}{
+		{"active_within_days", in.ActiveWithinDays},
+		{"deploy_key_max_age_days", in.DeployKeyMaxAgeDays},
+		{"progress_interval_seconds", in.ProgressIntervalSeconds},
+	} {
+		if c.n < 0 {
+			v.addf(prefix+c.field, "must not be negative, got %d", c.n)
+		}
+	}
+	for _, t := range in.PriorityTopics {
+		if strings.TrimSpace(t) == "" {
+			v.addf(prefix+"priority_topics", "priority topics must not be empty")
+			break
+		}
+	}
+}
+
+// Validate returns a *ValidationError listing every problem with in, or
+// nil.
+func (in EnterpriseScanInput) Validate() error {
+	return in.validation(true).err()
+}
+
+// validation checks everything Validate does. Without strict it checks
+// only what EnterpriseScanWorkflow checked before Validate: the enterprise,
+// the fields an enterprise scan owns, and the scan's checks.
+func (in EnterpriseScanInput) validation(strict bool) *ValidationError {
+	v := &ValidationError{Input: "enterprise scan input"}
+	v.add("enterprise", ValidateEnterpriseSlug(in.Enterprise))
+	if in.MaxConcurrentOrgs < 0 {
+		v.addf("max_concurrent_orgs", "max concurrent orgs must not be negative, got %d", in.MaxConcurrentOrgs)
+	}
+	if in.Scan.Org != "" {
+		v.addf("scan.org", "an enterprise scan finds its orgs; leave the scan's org empty")
+	}
+	if len(in.Scan.Repos) > 0 || len(in.Scan.Teams) > 0 {
+		v.addf("scan.repos", "an enterprise scan cannot select repos or teams")
+	}
+	if providerName(in.Scan.Provider) != ProviderGitHub {
+		v.addf("scan.provider", "enterprise scans are only supported on GitHub")
+	}
+	switch {
+	case len(v.Errors) > 0:
+		// The scan's own rules would repeat these.
+	case strict:
+		in.Scan.validateSettings(v, "scan.", true)
+		v.add("scan.suppressions", ValidateSuppressions(in.Scan.Suppressions))
+	default:
+		v.add("scan.checks", ValidateChecks(in.Scan.Checks))
+	}
+	return v
+}
+
+// invalidInputError is the workflow's non-retryable failure for err, with
+// a *ValidationError as its details.
+func invalidInputError(err error) error {
+	var v *ValidationError
+	if errors.As(err, &v) {
+		return temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidInput, nil, *v)
+	}
+	return temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidInput, nil)
+}
@@ -0,0 +1,174 @@
+package scanner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestScanInputValidate(t *testing.T) {
+	require.NoError(t, ScanInput{Org: "acme"}.Validate())
+	require.NoError(t, ScanInput{
+		Org:              "acme",
+		Repos:            []string{"acme/api", " ACME/web "},
+		Checks:           []string{CheckSecretScanning, CheckFiles},
+		CompliancePolicy: &CompliancePolicy{RequireCodeowners: true},
+		FailurePolicy:    &FailurePolicy{MaxErrorRatio: 1},
+		PriorityRepos:    []string{"api-*"},
+		Sinks:            []ReportSinkSpec{{Type: "file", Options: map[string]string{"dir": "/var/reports"}}},
+	}.Validate())
+
+	tooLong := strings.Repeat("x", MaxReasonLength+1)
+	for _, tc := range []struct {
+		name  string
+		in    ScanInput
+		field string
+		want  string
+	}{
+		{"empty org", ScanInput{}, "org", "org"},
+		{"malformed org", ScanInput{Org: "-acme"}, "org", "-acme"},
+		{"empty org with repos", ScanInput{Repos: []string{"acme/api"}}, "org", "must be set to the owner of repos"},
+		{"malformed GitLab group", ScanInput{Org: "acme//x", Provider: ProviderGitLab}, "org", "acme//x"},
+		{"unknown check", ScanInput{Org: "acme", Checks: []string{"telepathy"}}, "checks", "telepathy"},
+		{"unknown provider", ScanInput{Org: "acme", Provider: "svn"}, "provider", `unknown provider "svn"`},
+		{"check the provider lacks", ScanInput{Org: "acme", Provider: ProviderGitLab, Checks: []string{CheckActions}}, "provider", "does not support"},
+		{"policy requires an unselected check", ScanInput{Org: "acme", CompliancePolicy: &CompliancePolicy{RequireCodeowners: true}}, "compliance_policy", `requires check "files"`},
+		{"bad scoring weight", ScanInput{Org: "acme", CompliancePolicy: &CompliancePolicy{Scoring: &ScoringPolicy{Weights: map[string]float64{"nope": 1}}}}, "compliance_policy.scoring", `unknown check result "nope"`},
+		{"webhook allowlist URL", ScanInput{Org: "acme", CompliancePolicy: &CompliancePolicy{WebhookAllowedDomains: []string{"https://hooks.acme.com"}}}, "compliance_policy.webhook_allowed_domains", "is not a domain name"},
+		{"error ratio above 1", ScanInput{Org: "acme", FailurePolicy: &FailurePolicy{MaxErrorRatio: 1.5}}, "failure_policy.max_error_ratio", "between 0 and 1"},
+		{"negative min repos", ScanInput{Org: "acme", FailurePolicy: &FailurePolicy{MinRepos: -1}}, "failure_policy.min_repos", "must not be negative"},
+		{"negative active days", ScanInput{Org: "acme", ActiveWithinDays: -7}, "active_within_days", "must not be negative, got -7"},
+		{"negative deploy key age", ScanInput{Org: "acme", DeployKeyMaxAgeDays: -1}, "deploy_key_max_age_days", "must not be negative"},
+		{"negative progress interval", ScanInput{Org: "acme", ProgressIntervalSeconds: -1}, "progress_interval_seconds", "must not be negative"},
+		{"empty priority topic", ScanInput{Org: "acme", PriorityTopics: []string{"pci", " "}}, "priority_topics", "must not be empty"},
+		{"malformed repo", ScanInput{Org: "acme", Repos: []string{"acme/api", "api"}}, "repos[1]", "api"},
+		{"repo of another org", ScanInput{Org: "acme", Repos: []string{"globex/api"}}, "repos[0]", `repo "globex/api" is not in org "acme"`},
+		{"malformed team", ScanInput{Org: "acme", Teams: []string{"Platform Team"}}, "teams[0]", "Platform Team"},
+		{"teams and repos", ScanInput{Org: "acme", Teams: []string{"platform"}, Repos: []string{"acme/api"}}, "teams", "cannot be combined"},
+		{"teams on GitLab", ScanInput{Org: "acme", Provider: ProviderGitLab, Teams: []string{"platform"}}, "teams", "only supported on GitHub"},
+		{"active days with repos", ScanInput{Org: "acme", Repos: []string{"acme/api"}, ActiveWithinDays: 30}, "active_within_days", "cannot be combined with repos"},
+		{"negative batch delay", ScanInput{Org: "acme", BatchDelay: &BatchDelay{Seconds: -1}}, "batch_delay", "must not be negative"},
+		{"batch delay jitter", ScanInput{Org: "acme", BatchDelay: &BatchDelay{Jitter: 2}}, "batch_delay", "between 0 and 1"},
+		{"negative API budget", ScanInput{Org: "acme", MaxAPIRequests: -1}, "max_api_requests", "must not be negative"},
+		{"malformed priority glob", ScanInput{Org: "acme", PriorityRepos: []string{"api-["}}, "priority_repos", "not a valid glob"},
+		{"empty priority glob", ScanInput{Org: "acme", PriorityRepos: []string{""}}, "priority_repos", "not a valid glob"},
+		{"topic included and excluded", ScanInput{Org: "acme", IncludeTopics: []string{"pci"}, ExcludeTopics: []string{"PCI"}}, "include_topics", "both included and excluded"},
+		{"empty excluded topic", ScanInput{Org: "acme", ExcludeTopics: []string{""}}, "exclude_topics", "must not be empty"},
+		{"topics with repos", ScanInput{Org: "acme", Repos: []string{"acme/api"}, IncludeTopics: []string{"pci"}}, "include_topics", "cannot be combined with repos"},
+		{"negative max duration", ScanInput{Org: "acme", MaxDurationSeconds: -1}, "max_duration_seconds", "must not be negative"},
+		{"inverted timeouts", ScanInput{Org: "acme", Timeouts: &Timeouts{Report: &ActivityTimeouts{StartToCloseSeconds: 300}}}, "timeouts", "must be shorter than schedule-to-close"},
+		{"negative retry attempts", ScanInput{Org: "acme", RetryPolicies: &RetryPolicies{Report: &RetryOverride{MaximumAttempts: -1}}}, "retry_policies", "report retry policy"},
+		{"negative concurrency", ScanInput{Org: "acme", Concurrency: -1}, "concurrency", "must not be negative"},
+		{"concurrency with batching", ScanInput{Org: "acme", Concurrency: 5, ChildPerBatch: true}, "concurrency", "child_per_batch"},
+		{"worker affinity per batch", ScanInput{Org: "acme", WorkerAffinity: true, ChildPerBatch: true}, "worker_affinity", "does not combine"},
+		{"straggler timeout with activity batching", ScanInput{Org: "acme", ActivityBatching: true, StragglerTimeout: &StragglerTimeout{Multiple: 2}}, "straggler_timeout", "does not apply with activity batching"},
+		{"straggler multiple", ScanInput{Org: "acme", StragglerTimeout: &StragglerTimeout{Multiple: 1}}, "straggler_timeout", "more than 1"},
+		{"progress webhook scheme", ScanInput{Org: "acme", ProgressWebhook: &ProgressWebhook{URL: "ftp://dash.acme.com"}}, "progress_webhook", "not an http(s) URL"},
+		{"unknown sink", ScanInput{Org: "acme", Sinks: []ReportSinkSpec{{Type: "file", Options: map[string]string{"dir": "/var/reports"}}, {Type: "s3"}}}, "sinks[1]", `unknown sink type "s3"`},
+		{"empty resume state", ScanInput{Org: "acme", ResumeFrom: &ResumeState{}}, "resume_from", "nothing to resume"},
+		{"long initiator", ScanInput{Org: "acme", InitiatedBy: strings.Repeat("x", MaxInitiatedByLength+1)}, "initiated_by", "more than"},
+		{"long reason", ScanInput{Org: "acme", Reason: tooLong}, "reason", "more than"},
+		{"unjustified suppression", ScanInput{Org: "acme", Suppressions: []Suppression{{Repo: "mirror-*"}}}, "suppressions", "justification"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.in.Validate()
+			var v *ValidationError
+			require.True(t, errors.As(err, &v), "%v", err)
+			require.Len(t, v.Errors, 1, "%v", err)
+			require.Equal(t, tc.field, v.Errors[0].Field)
+			require.Contains(t, v.Errors[0].Problem, tc.want)
+			require.ErrorContains(t, err, "invalid scan input: "+tc.field+": ")
+		})
+	}
+}
+
+func TestScanInputValidateReportsEveryProblem(t *testing.T) {
+	err := ScanInput{
+		Org:            "acme",
+		Repos:          []string{"globex/api", "acme/web", "web"},
+		Checks:         []string{"telepathy"},
+		MaxAPIRequests: -1,
+		Concurrency:    -2,
+	}.Validate()
+	var v *ValidationError
+	require.True(t, errors.As(err, &v))
+	var fields []string
+	for _, fe := range v.Errors {
+		fields = append(fields, fe.Field)
+	}
+	require.Equal(t, []string{"checks", "repos[0]", "repos[2]", "max_api_requests", "concurrency"}, fields)
+	require.ErrorContains(t, err, `invalid scan input: checks: `)
+	require.ErrorContains(t, err, `; repos[0]: repo "globex/api" is not in org "acme"; `)
+}
+
+func TestScanInputValidationBeforeInputRules(t *testing.T) {
+	in := ScanInput{Org: "acme", ActiveWithinDays: -1, FailurePolicy: &FailurePolicy{MinRepos: -1}, PriorityTopics: []string{""}}
+	require.Error(t, in.Validate())
+	require.NoError(t, in.validation(false).err(), "runs recorded before Validate skip its new rules")
+}
+
+func TestEnterpriseScanInputValidate(t *testing.T) {
+	require.NoError(t, EnterpriseScanInput{Enterprise: "acme-corp"}.Validate())
+
+	for _, tc := range []struct {
+		name   string
+		in     EnterpriseScanInput
+		fields []string
+	}{
+		{"malformed slug", EnterpriseScanInput{Enterprise: "acme corp"}, []string{"enterprise"}},
+		{"negative concurrency", EnterpriseScanInput{Enterprise: "acme", MaxConcurrentOrgs: -1}, []string{"max_concurrent_orgs"}},
+		{"org and repos", EnterpriseScanInput{Enterprise: "acme", Scan: ScanInput{Org: "acme", Repos: []string{"acme/api"}}}, []string{"scan.org", "scan.repos"}},
+		{"teams", EnterpriseScanInput{Enterprise: "acme", Scan: ScanInput{Teams: []string{"platform"}}}, []string{"scan.repos"}},
+		{"GitLab", EnterpriseScanInput{Enterprise: "acme", Scan: ScanInput{Provider: ProviderGitLab}}, []string{"scan.provider"}},
+		{"the scan's settings", EnterpriseScanInput{Enterprise: "acme", Scan: ScanInput{Checks: []string{"telepathy"}, Concurrency: -1}}, []string{"scan.checks", "scan.concurrency"}},
+		{"the scan's suppressions", EnterpriseScanInput{Enterprise: "acme", Scan: ScanInput{Suppressions: []Suppression{{Repo: "x"}}}}, []string{"scan.suppressions"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.in.Validate()
+			var v *ValidationError
+			require.True(t, errors.As(err, &v), "%v", err)
+			var fields []string
+			for _, fe := range v.Errors {
+				fields = append(fields, fe.Field)
+			}
+			require.Equal(t, tc.fields, fields)
+			require.ErrorContains(t, err, "invalid enterprise scan input: ")
+		})
+	}
+
+	// Before Validate, only the scan's checks were checked.
+	in := EnterpriseScanInput{Enterprise: "acme", Scan: ScanInput{Concurrency: -1}}
+	require.NoError(t, in.validation(false).err())
+}
+
+func TestWorkflowRejectsInvalidInputByField(t *testing.T) {
+	env := newTestEnv(t)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", MaxAPIRequests: -1, Concurrency: -1})
+
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+	var details ValidationError
+	require.NoError(t, appErr.Details(&details))
+	require.Equal(t, "scan input", details.Input)
+	require.Equal(t, []FieldError{
+		{Field: "max_api_requests", Problem: "max API requests must not be negative, got -1"},
+		{Field: "concurrency", Problem: "concurrency must not be negative, got -1"},
+	}, details.Errors)
+	env.AssertNumberOfCalls(t, "FetchOrgReposPage", 0)
+}
+
+func TestWorkflowInputRulesVersion(t *testing.T) {
+	env := newTestEnv(t)
+	env.OnGetVersion(changeInputRules, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+	onListOrgRepos(env, fakeRepos(1))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(compliantUnless())
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ProgressIntervalSeconds: -1})
+	require.NoError(t, env.GetWorkflowError(), "a run recorded before the rule keeps going")
+}
@@ -0,0 +1,98 @@
+package scanner
+
+// =============================================================================
+// Workflow versioning policy
+// =============================================================================
+//
+// Every change to SecurityScanWorkflow or ScanBatchWorkflow that alters the
+// commands they issue (an activity added, removed, reordered or renamed, a
+// timer, a child) is guarded by a change ID, so that scans recorded before
+// it keep replaying the old branch (see replay_test.go):
+//
+//  1. Add a change ID below and its newest version to workflowChanges.
+//  2. Branch on changeVersion(ctx, id) at the point that changes; never
+//     call workflow.GetVersion directly (TestChangeIDsAreCentralized).
+//  3. A later change to the same point raises the version and keeps the
+//     older branches until no run recorded on them can still be open.
+//
+// Reserved IDs name changes that are planned but not yet made. They are
+// not in workflowChanges, and nothing calls GetVersion with them: a
+// GetVersion call records a marker in every new run, even one that only
+// returns DefaultVersion.
+// =============================================================================
+
+import "go.temporal.io/sdk/workflow"
+
+// Change IDs in use.
+const (
+	changeActionsSecurity   = "actions-security"   // CheckActionsSecurity per repo
+	changeTokenCapabilities = "token-capabilities" // ValidateToken before the scan
+	changeResultsOffload    = "results-offload"    // StoreResults claim checks
+	changeAPIUsage          = "api-usage"          // GetAPIUsage for the report
+	changeLocalReport       = "local-report"       // BuildReport local activity instead of GenerateReport
+	changeForwardFindings   = "forward-findings"   // ForwardFindings after the report
+	changeJiraIssues        = "jira-issues"        // CreateJiraIssues after the report
+	changeScanHistory       = "scan-history"       // baseline, paging and saving the report; 2: complete reports only; 3: whole-org scans only
+	changeComplianceMetrics = "compliance-metrics" // EmitComplianceMetrics after the report
+	changeReportSections    = "report-sections"    // post-report steps no longer change the generated report
+	changeRepoMetadata      = "repo-metadata"      // results carry RepoMetadata, which moves the offload point
+	changeBatchCollection   = "batch-collection"   // scanBatch stops waiting on cancel and cancels the batch's activities
+	changeSkipArchived      = "skip-archived"      // archived repos are listed in the report instead of scanned
+	changeReportSinks       = "report-sinks"       // DeliverReport per ScanInput.Sinks after the report
+	changeScanInitiator     = "scan-initiator"     // InitiatedBy and Reason upserted into the memo; 2: the whole ScanMemo
+	changeDisappearedRepos  = "disappeared-repos"  // no further checks of a repo gone since the listing
+	changeRepoPages         = "repo-pages"         // FetchOrgReposPage per page instead of FetchOrgRepos
+	changeRemediationPlan   = "remediation-plan"   // AnalyzeRemediation local activity after the report
+	changeAuditLog          = "audit-log"          // AppendAuditLog at the scan's start and end; 2: only with an audit log
+	changeOrgConfig         = "org-config"         // ResolveScanConfig local activity before input validation
+	changeRepoAccess        = "repo-access"        // no further checks of a repo the token cannot read
+	changeInputRules        = "input-rules"        // the input rules that came with Validate (see validate.go)
+	changeBatchStop         = "batch-stop"         // CheckRepoSecurityBatch is cancelled on stop and returns what it skipped
+)
+
+// Reserved change IDs.
+const (
+	// changeActivityArgs will guard struct arguments for the scan
+	// activities (CheckRepoSecurity and friends) in place of positional
+	// ones.
+	changeActivityArgs = "activity-args"
+	// A change to how the report is built raises changeLocalReport to 2.
+)
+
+// workflowChanges is the newest version of every change ID in use.
+var workflowChanges = map[string]workflow.Version{
+	changeActionsSecurity:   1,
+	changeTokenCapabilities: 1,
+	changeResultsOffload:    1,
+	changeAPIUsage:          1,
+	changeLocalReport:       1,
+	changeForwardFindings:   1,
+	changeJiraIssues:        1,
+	changeScanHistory:       3,
+	changeComplianceMetrics: 1,
+	changeReportSections:    1,
+	changeRepoMetadata:      1,
+	changeBatchCollection:   1,
+	changeSkipArchived:      1,
+	changeReportSinks:       1,
+	changeScanInitiator:     2,
+	changeDisappearedRepos:  1,
+	changeRepoPages:         1,
+	changeRemediationPlan:   1,
+	changeAuditLog:          2,
+	changeOrgConfig:         1,
+	changeRepoAccess:        1,
+	changeInputRules:        1,
+	changeBatchStop:         1,
+}
+
+// changeVersion is the version of change this run takes: DefaultVersion
+// for runs recorded before the change, its newest version for new runs.
+// An ID missing from workflowChanges is a bug, and panics.
+func changeVersion(ctx workflow.Context, change string) workflow.Version {
+	newest, ok := workflowChanges[change]
+	if !ok {
+		panic("change ID " + change + " is not in workflowChanges")
+	}
+	return workflow.GetVersion(ctx, change, workflow.DefaultVersion, newest)
+}
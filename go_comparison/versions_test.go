@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChangeIDsAreCentralized enforces the policy in versions.go: only
+// changeVersion calls workflow.GetVersion, and only with IDs that are in
+// workflowChanges.
+func TestChangeIDsAreCentralized(t *testing.T) {
+	consts := make(map[string]string) // Go name -> change ID
+	f, err := parser.ParseFile(token.NewFileSet(), "versions.go", nil, 0)
+	require.NoError(t, err)
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				id, err := strconv.Unquote(vs.Values[i].(*ast.BasicLit).Value)
+				require.NoError(t, err)
+				consts[name.Name] = id
+			}
+		}
+	}
+
+	calls := regexp.MustCompile(`changeVersion\(\w+, (\w+)\)`)
+	files, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+	used := make(map[string]bool)
+	for _, file := range files {
+		if file == "versions.go" || strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.NotContains(t, string(src), "workflow.GetVersion(", "%s: use changeVersion", file)
+		for _, m := range calls.FindAllStringSubmatch(string(src), -1) {
+			id, ok := consts[m[1]]
+			require.True(t, ok, "%s: %s is not a change ID constant", file, m[1])
+			require.Contains(t, workflowChanges, id, "%s: %s is not in workflowChanges", file, id)
+			used[id] = true
+		}
+	}
+	for id := range workflowChanges {
+		require.True(t, used[id], "%s is in workflowChanges but never used", id)
+	}
+	require.NotContains(t, workflowChanges, changeActivityArgs, "reserved")
+}
+
+// TestWorkflowLeavesGeneratedReportAlone checks report-sections: the
+// post-report steps each get the report as generated, and the sections
+// only show up in the result.
+func TestWorkflowLeavesGeneratedReportAlone(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(2))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("repo-001"))
+	env.OnActivity("ForwardFindings", mock.Anything, mock.Anything).
+		Return(&ForwardResult{Destination: "https://splunk.example/services/collector", Mode: ForwardPerFinding, EventsSent: 1}, nil)
+	var jiraInput JiraInput
+	env.OnActivity("CreateJiraIssues", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, in JiraInput) (*RemediationResult, error) {
+			jiraInput = in
+			return &RemediationResult{Tracker: "jira", Project: "SEC"}, nil
+		})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.NotNil(t, report.Forwarding)
+	require.NotNil(t, report.Remediation)
+	require.NotContains(t, jiraInput.Report, "forwarding", "Jira got the report as generated")
+}
@@ -0,0 +1,200 @@
+package scanner
+
+// =============================================================================
+// Webhooks — where a repo sends its events
+// =============================================================================
+//
+// A webhook delivers every push, issue and pull request of its repo to a
+// URL. CheckWebhooks reads GET /repos/{org}/{repo}/hooks, which needs admin
+// access to the repo, and flags each hook that
+//
+//   - delivers over plain http://, so its payloads can be read or altered
+//     in transit (plain_http);
+//   - has no secret, so the receiver cannot tell GitHub's deliveries from
+//     forged ones (no_secret);
+//   - points outside CompliancePolicy.WebhookAllowedDomains, when the
+//     policy sets one (domain_not_allowed).
+//
+// The result's WebhookFindings lists every hook with the issues found;
+// the check is StatusMisconfigured when any hook has one. The allowlist is
+// part of the policy, which the activity does not see, so its issues are
+// added when the workflow records the result and again, for results it
+// did not record, when the report is built (applyWebhookAllowlist).
+//
+// Hook URLs can embed credentials, e.g. ?token=..., so a finding keeps the
+// URL without its query string, fragment or userinfo. Only the first 100
+// hooks of a repo are read.
+// =============================================================================
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Webhook issues.
+const (
+	WebhookPlainHTTP        = "plain_http"
+	WebhookNoSecret         = "no_secret"
+	WebhookDomainNotAllowed = "domain_not_allowed"
+)
+
+// WebhookFinding is one hook of a repo and what is wrong with it.
+type WebhookFinding struct {
+	ID int64 `json:"id"`
+	// URL is the hook's URL without its query string, fragment or
+	// userinfo.
+	URL    string `json:"url"`
+	Active bool   `json:"active"`
+	// Issues is empty for a hook with none.
+	Issues []string `json:"issues,omitempty"`
+}
+
+// host is the hook's host name, lowercased.
+func (f WebhookFinding) host() string {
+	u, err := url.Parse(f.URL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func (f *WebhookFinding) addIssue(issue string) {
+	for _, i := range f.Issues {
+		if i == issue {
+			return
+		}
+	}
+	f.Issues = append(f.Issues, issue)
+}
+
+// sanitizeWebhookURL strips from raw what may carry a credential.
+func sanitizeWebhookURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(unparseable URL)"
+	}
+	u.User, u.RawQuery, u.ForceQuery, u.Fragment, u.RawFragment = nil, "", false, "", ""
+	return u.String()
+}
+
+// checkWebhooks records the webhooks result: enabled when no hook has an
+// issue, StatusMisconfigured when one does.
+func (a *Activities) checkWebhooks(ctx context.Context, org, repoName string, headers map[string]string, result *RepoSecurityResult) error {
+	ctx = evidenceFor(ctx, CheckWebhooks)
+	status, body, err := a.checkEndpoint(ctx, a.apiURL("/repos/%s/%s/hooks?per_page=100", org, repoName), headers)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusOK:
+	case http.StatusForbidden, http.StatusNotFound:
+		// GitHub answers 404, not 403, when the token is not a repo admin.
+		result.setCheck(CheckWebhooks, CheckResult{Status: StatusNoAccess, Message: "listing webhooks needs admin access"})
+		return nil
+	default:
+		return statusError("listing webhooks of "+repoName, status, body)
+	}
+	var hooks []struct {
+		ID     int64 `json:"id"`
+		Active bool  `json:"active"`
+		Config struct {
+			URL    string `json:"url"`
+			Secret string `json:"secret"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(body, &hooks); err != nil {
+		return parseError("webhooks of "+repoName, err)
+	}
+	findings := []WebhookFinding{}
+	for _, h := range hooks {
+		f := WebhookFinding{ID: h.ID, URL: sanitizeWebhookURL(h.Config.URL), Active: h.Active}
+		if u, err := url.Parse(h.Config.URL); err == nil && strings.EqualFold(u.Scheme, "http") {
+			f.addIssue(WebhookPlainHTTP)
+		}
+		if h.Config.Secret == "" {
+			f.addIssue(WebhookNoSecret)
+		}
+		findings = append(findings, f)
+	}
+	result.WebhookFindings = findings
+	result.setCheck(CheckWebhooks, webhooksResult(findings))
+	return nil
+}
+
+// webhooksResult is the webhooks result for findings.
+func webhooksResult(findings []WebhookFinding) CheckResult {
+	flagged := 0
+	for _, f := range findings {
+		if len(f.Issues) > 0 {
+			flagged++
+		}
+	}
+	if flagged == 0 {
+		return CheckResult{Status: StatusEnabled}
+	}
+	return CheckResult{Status: StatusMisconfigured, Message: fmt.Sprintf("%d of %d webhooks insecure", flagged, len(findings))}
+}
+
+// webhookDomainAllowed reports whether host is one of domains or a
+// subdomain of one.
+func webhookDomainAllowed(host string, domains []string) bool {
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimPrefix(d, "."))
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyWebhookAllowlist flags r's hooks outside the policy's
+// WebhookAllowedDomains. It is a no-op without an allowlist, for a result
+// without a readable webhooks check, and when applied again.
+func (p CompliancePolicy) applyWebhookAllowlist(r *RepoSecurityResult) {
+	if len(p.WebhookAllowedDomains) == 0 || len(r.WebhookFindings) == 0 || r.Check(CheckWebhooks).Status.indeterminate() {
+		return
+	}
+	for i := range r.WebhookFindings {
+		if f := &r.WebhookFindings[i]; !webhookDomainAllowed(f.host(), p.WebhookAllowedDomains) {
+			f.addIssue(WebhookDomainNotAllowed)
+		}
+	}
+	r.setCheck(CheckWebhooks, webhooksResult(r.WebhookFindings))
+}
+
+// validateWebhookAllowlist checks that WebhookAllowedDomains holds bare
+// domain names.
+func (p CompliancePolicy) validateWebhookAllowlist() error {
+	for _, d := range p.WebhookAllowedDomains {
+		if strings.TrimSpace(d) == "" || strings.ContainsAny(d, ":/?# ") {
+			return fmt.Errorf("webhook_allowed_domains entry %q is not a domain name", d)
+		}
+	}
+	return nil
+}
+
+// webhookIssueCounts counts the hooks with each issue across results.
+type webhookIssueCounts map[string]int
+
+func (c webhookIssueCounts) add(r *RepoSecurityResult) {
+	for _, f := range r.WebhookFindings {
+		for _, issue := range f.Issues {
+			c[issue]++
+		}
+	}
+}
+
+// String is the counts in issue order, e.g. "plain_http 2, no_secret 1".
+func (c webhookIssueCounts) String() string {
+	var parts []string
+	for _, issue := range []string{WebhookPlainHTTP, WebhookNoSecret, WebhookDomainNotAllowed} {
+		if c[issue] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", issue, c[issue]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
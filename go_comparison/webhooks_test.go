@@ -0,0 +1,186 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRepoSecurityWebhooks(t *testing.T) {
+	const repoPath = "/repos/acme-corp/payments-api"
+	hooksPage := repoPath + "/hooks?per_page=100"
+
+	scan := func(t *testing.T, hooks fakeResponse) RepoSecurityResult {
+		t.Helper()
+		_, a := newFakeGitHub(t, map[string]fakeResponse{
+			repoPath:  {http.StatusOK, "repo_secret_scanning_enabled.json"},
+			hooksPage: hooks,
+		})
+		val, err := newActivityEnv(a).ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), []string{CheckSecretScanning, CheckWebhooks})
+		require.NoError(t, err)
+		var result RepoSecurityResult
+		require.NoError(t, val.Get(&result))
+		require.Nil(t, result.Error)
+		return result
+	}
+
+	t.Run("flags plain http and missing secrets, strips credentials", func(t *testing.T) {
+		result := scan(t, fakeResponse{http.StatusOK, "repo_hooks.json"})
+		require.Equal(t, []WebhookFinding{
+			{ID: 7001, URL: "https://ci.acme-corp.example/hooks/github", Active: true},
+			{ID: 7002, URL: "http://legacy-deploy.acme-corp.example/trigger", Active: true, Issues: []string{WebhookPlainHTTP, WebhookNoSecret}},
+			{ID: 7003, URL: "https://hooks.chat-vendor.example/incoming", Active: false},
+		}, result.WebhookFindings)
+		require.Equal(t, CheckResult{Status: StatusMisconfigured, Message: "1 of 3 webhooks insecure"}, result.Check(CheckWebhooks))
+
+		// The allowlist adds its issues once, however often it is applied.
+		policy := CompliancePolicy{RequireSecureWebhooks: true, WebhookAllowedDomains: []string{"acme-corp.example"}}
+		policy.applyWebhookAllowlist(&result)
+		policy.applyWebhookAllowlist(&result)
+		require.Empty(t, result.WebhookFindings[0].Issues)
+		require.Equal(t, []string{WebhookDomainNotAllowed}, result.WebhookFindings[2].Issues)
+		require.Equal(t, "2 of 3 webhooks insecure", result.Check(CheckWebhooks).Message)
+		require.False(t, policy.IsCompliant(&result))
+	})
+
+	t.Run("no hooks is secure", func(t *testing.T) {
+		result := scan(t, fakeResponse{http.StatusOK, "org_repos_empty.json"})
+		require.Empty(t, result.WebhookFindings)
+		require.Equal(t, StatusEnabled, result.Check(CheckWebhooks).Status)
+		require.True(t, CompliancePolicy{RequireSecureWebhooks: true}.IsCompliant(&result))
+	})
+
+	for name, resp := range map[string]fakeResponse{
+		"403 is no access": {http.StatusForbidden, "admin_required.json"},
+		"404 is no access": {http.StatusNotFound, "not_found.json"},
+	} {
+		resp := resp
+		t.Run(name, func(t *testing.T) {
+			result := scan(t, resp)
+			require.Nil(t, result.WebhookFindings)
+			require.Equal(t, CheckResult{Status: StatusNoAccess, Message: "listing webhooks needs admin access"}, result.Check(CheckWebhooks))
+		})
+	}
+}
+
+func TestCheckRepoSecurityWebhooksError(t *testing.T) {
+	const repoPath = "/repos/acme-corp/payments-api"
+	for name, tc := range map[string]struct {
+		hooks   fakeResponse
+		want    ErrorCategory
+		message string
+	}{
+		"429 is a rate limit": {
+			fakeResponse{http.StatusTooManyRequests, "rate_limited.json"}, ErrorRateLimit,
+			"listing webhooks of payments-api: unexpected status 429: API rate limit exceeded",
+		},
+		"502 is a server error": {
+			fakeResponse{http.StatusBadGateway, ""}, ErrorServerError,
+			"listing webhooks of payments-api: unexpected status 502",
+		},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			_, a := newFakeGitHub(t, map[string]fakeResponse{
+				repoPath:                         {http.StatusOK, "repo_secret_scanning_enabled.json"},
+				repoPath + "/hooks?per_page=100": tc.hooks,
+			})
+
+			err := a.checkWebhooks(context.Background(), "acme-corp", "payments-api", nil, &RepoSecurityResult{})
+			se := NewScanError(err)
+			require.Equal(t, tc.want, se.Category)
+			require.Equal(t, tc.hooks.Status, se.HTTPStatus)
+			require.True(t, se.Retryable)
+
+			// CheckRepoSecurity records it as the check's error.
+			val, err := newActivityEnv(a).ExecuteActivity(a.CheckRepoSecurity, "acme-corp", "payments-api", (*Credential)(nil), []string{CheckSecretScanning, CheckWebhooks})
+			require.NoError(t, err)
+			var result RepoSecurityResult
+			require.NoError(t, val.Get(&result))
+			check := result.Check(CheckWebhooks)
+			require.Equal(t, StatusError, check.Status)
+			require.Contains(t, check.Message, tc.message)
+		})
+	}
+}
+
+func TestWebhookAllowlist(t *testing.T) {
+	for host, want := range map[string]bool{
+		"acme-corp.example":         true,
+		"ci.acme-corp.example":      true,
+		"evil-acme-corp.example":    false,
+		"acme-corp.example.evil.io": false,
+	} {
+		require.Equal(t, want, webhookDomainAllowed(host, []string{".acme-corp.example"}), host)
+	}
+
+	require.NoError(t, CompliancePolicy{WebhookAllowedDomains: []string{"acme-corp.example"}}.validateWebhookAllowlist())
+	for _, bad := range []string{"", "https://acme-corp.example", "acme-corp.example/hooks"} {
+		require.Error(t, CompliancePolicy{WebhookAllowedDomains: []string{bad}}.validateWebhookAllowlist(), bad)
+	}
+
+	// Without a readable webhooks result there is nothing to flag.
+	r := RepoSecurityResult{WebhookFindings: []WebhookFinding{{URL: "https://elsewhere.example/"}}}
+	r.setCheck(CheckWebhooks, CheckResult{Status: StatusNoAccess})
+	CompliancePolicy{WebhookAllowedDomains: []string{"acme-corp.example"}}.applyWebhookAllowlist(&r)
+	require.Empty(t, r.WebhookFindings[0].Issues)
+}
+
+func TestReportCountsWebhooks(t *testing.T) {
+	results := []RepoSecurityResult{{Repository: "a"}, {Repository: "b"}, {Repository: "c"}}
+	results[0].WebhookFindings = []WebhookFinding{{ID: 1, URL: "https://ci.acme-corp.example/"}}
+	results[0].setCheck(CheckWebhooks, CheckResult{Status: StatusEnabled})
+	results[1].WebhookFindings = []WebhookFinding{
+		{ID: 2, URL: "http://ci.acme-corp.example/", Issues: []string{WebhookPlainHTTP, WebhookNoSecret}},
+		{ID: 3, URL: "https://hooks.vendor.example/"},
+	}
+	results[1].setCheck(CheckWebhooks, webhooksResult(results[1].WebhookFindings))
+	results[2].setCheck(CheckWebhooks, CheckResult{Status: StatusNoAccess})
+
+	policy := CompliancePolicy{RequireSecureWebhooks: true, WebhookAllowedDomains: []string{"acme-corp.example"}}
+	env := newActivityEnv(&Activities{})
+	val, err := env.ExecuteActivity("GenerateReport", "acme", results, []BlobRef(nil), policy, []string{CheckWebhooks}, []Suppression(nil))
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, val.Get(&report))
+	require.NoError(t, report.Validate())
+	require.Equal(t, 1, *report.WebhooksSecure)
+	require.Equal(t, map[string]int{WebhookPlainHTTP: 1, WebhookNoSecret: 1, WebhookDomainNotAllowed: 1}, report.WebhookFindings)
+	require.Equal(t, 1, report.FullyCompliant)
+	require.Equal(t, 1, report.Indeterminate)
+
+	var buf bytes.Buffer
+	RenderReport(&buf, report, RenderOptions{})
+	require.Contains(t, buf.String(), "  Secure webhooks:      1/3\n")
+	require.Contains(t, buf.String(), "  Insecure webhooks:    plain_http 1, no_secret 1, domain_not_allowed 1\n")
+}
+
+func TestWorkflowRejectsBadWebhookAllowlist(t *testing.T) {
+	env := newTestEnv(t)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org:              "acme",
+		Checks:           []string{CheckWebhooks},
+		CompliancePolicy: &CompliancePolicy{RequireSecureWebhooks: true, WebhookAllowedDomains: []string{"https://acme.example"}},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.ErrorContains(t, env.GetWorkflowError(), `webhook_allowed_domains entry "https://acme.example" is not a domain name`)
+}
+
+func TestValidateTokenWebhooksNeedAdmin(t *testing.T) {
+	_, a := newFakeGitHub(t, map[string]fakeResponse{
+		"/orgs/acme-corp":                               {http.StatusOK, "org.json"},
+		"/orgs/acme-corp/repos?per_page=10":             {http.StatusOK, "org_repos_sample.json"},
+		"/repos/acme-corp/service-000":                  {http.StatusOK, "repo_no_security_and_analysis.json"},
+		"/repos/acme-corp/service-001":                  {http.StatusOK, "repo_no_security_and_analysis.json"},
+		"/repos/acme-corp/service-002":                  {http.StatusOK, "repo_no_security_and_analysis.json"},
+		"/repos/acme-corp/service-003":                  {http.StatusOK, "repo_no_security_and_analysis.json"},
+		"/repos/acme-corp/service-004":                  {http.StatusOK, "repo_no_security_and_analysis.json"},
+		"/repos/acme-corp/service-000/hooks?per_page=1": {http.StatusNotFound, "not_found.json"},
+	})
+	caps, err := validateToken(t, a, []string{CheckWebhooks})
+	require.NoError(t, err)
+	require.Equal(t, []string{CheckWebhooks}, caps.Unavailable())
+}
@@ -0,0 +1,146 @@
+package scanner
+
+// =============================================================================
+// Sliding window — a concurrency cap instead of batches
+// =============================================================================
+//
+// A batch is done when its slowest repo is, so when repo durations vary
+// widely most of a batch's slots sit idle while it waits for one repo.
+// With ScanInput.Concurrency, scanWindow keeps up to that many repos in
+// flight for the whole scan instead, starting the next repo as soon as one
+// finishes. The cap is a count of running repos the workflow selects on,
+// so the order repos start in is as deterministic as the batch loop's.
+//
+// Results are recorded as each repo completes, as in batch mode; without
+// ProgressWebhook.EveryRepos the webhook publishes every Concurrency
+// repos, a window's worth. Cancellation, a spent API budget and the
+// deadline stop new repos from starting. Cancellation, and the deadline
+// once its grace has run out, also cancel the repos in flight, and
+// scanWindow drains them before it returns, so no activity of the scan
+// outlives it.
+//
+// The window replaces batching: it does not combine with ChildPerBatch,
+// ActivityBatching, BatchDelay or StragglerTimeout. It is opt-in, so
+// running scans replay as they were.
+// =============================================================================
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// validateConcurrency checks that ScanInput.Concurrency is not negative
+// and, when set, that no batching option is set with it.
+func (in ScanInput) validateConcurrency() error {
+	if in.Concurrency < 0 {
+		return fmt.Errorf("concurrency must not be negative, got %d", in.Concurrency)
+	}
+	if in.Concurrency == 0 {
+		return nil
+	}
+	var batching []string
+	if in.ChildPerBatch {
+		batching = append(batching, "child_per_batch")
+	}
+	if in.ActivityBatching {
+		batching = append(batching, "activity_batching")
+	}
+	if in.BatchDelay != nil {
+		batching = append(batching, "batch_delay")
+	}
+	if in.StragglerTimeout != nil {
+		batching = append(batching, "straggler_timeout")
+	}
+	if len(batching) > 0 {
+		return fmt.Errorf("concurrency replaces batching and does not combine with %v", batching)
+	}
+	return nil
+}
+
+// scanWindow scans in.Repos with up to limit repos in flight, in order, and
+// calls onResult for each result in completion order; an error from
+// onResult ends the scan with that error. A repo whose CheckRepoSecurity
+// failed is passed with Error set. It reports whether the scan's API
+// budget ran out; repos cut short by it are not passed to onResult.
+//
+// Once stopStarting reports true, no further repo is started, and the ones
+// in flight finish; those not started are returned as unstarted. Once stop
+// reports true, the repos in flight are cancelled as well and returned as
+// inFlight, without being passed to onResult.
+func scanWindow(ctx, scanCtx workflow.Context, in ScanBatchInput, limit int, actionsVersion workflow.Version, stopStarting, stop func() bool, onResult func(*RepoSecurityResult) error) (budgetExceeded bool, inFlight, unstarted []string, err error) {
+	runChecks := in.Checks
+	if len(in.NoAccess) > 0 {
+		runChecks = subtract(in.Checks, in.NoAccess)
+	}
+	checks := newCheckSet(runChecks)
+
+	activityCtx, cancelActivities := workflow.WithCancel(scanCtx)
+	defer cancelActivities()
+
+	resultCh := workflow.NewChannel(ctx)
+	running := make(map[string]bool, limit)
+	start := func(repoName string) {
+		running[repoName] = true
+		workflow.Go(ctx, func(gCtx workflow.Context) {
+			result, exceeded := checkRepo(gCtx, activityCtx, in, runChecks, checks, actionsVersion, repoName)
+			if exceeded {
+				budgetExceeded = true
+			}
+			resultCh.Send(gCtx, repoDone{repo: repoName, result: result})
+		})
+	}
+
+	// As in scanBatch, a selector waits for either the next result or stop.
+	stopped, settle := workflow.NewFuture(ctx)
+	collected := false
+	workflow.Go(ctx, func(gCtx workflow.Context) {
+		_ = workflow.Await(gCtx, func() bool { return collected || stop() })
+		settle.Set(nil, nil)
+	})
+	sel := workflow.NewSelector(ctx)
+	sel.AddReceive(resultCh, func(c workflow.ReceiveChannel, _ bool) {
+		var done repoDone
+		c.Receive(ctx, &done)
+		delete(running, done.repo)
+		if done.result != nil && err == nil {
+			err = onResult(done.result)
+		}
+	})
+	halted := false
+	sel.AddFuture(stopped, func(workflow.Future) { halted = true })
+
+	next := 0
+	for !halted && err == nil {
+		for len(running) < limit && next < len(in.Repos) && !budgetExceeded && !stopStarting() {
+			start(in.Repos[next])
+			next++
+		}
+		if len(running) == 0 {
+			break
+		}
+		sel.Select(ctx)
+	}
+	collected = true
+	unstarted = in.Repos[next:]
+
+	if len(running) > 0 {
+		// Drain the repos in flight: their activities are cancelled, so
+		// each sends at once.
+		cancelActivities()
+		for _, repo := range in.Repos[:next] {
+			if running[repo] {
+				inFlight = append(inFlight, repo)
+			}
+		}
+		for len(running) > 0 {
+			var done repoDone
+			resultCh.Receive(ctx, &done)
+			delete(running, done.repo)
+		}
+	}
+	if err != nil {
+		return budgetExceeded, nil, nil, err
+	}
+	return budgetExceeded, inFlight, unstarted, nil
+}
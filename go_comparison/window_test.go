@@ -0,0 +1,130 @@
+package scanner
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+// scanDuration is how long a scan of 20 repos takes on the simulated
+// clock when repo-000 and repo-010 take 100s and the rest 5s.
+func scanDuration(t *testing.T, in ScanInput) time.Duration {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(20))
+	for _, slow := range []string{"repo-000", "repo-010"} {
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, slow, mock.Anything, mock.Anything).
+			After(100 * time.Second).Return(compliantUnless())
+	}
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(5 * time.Second).Return(compliantUnless())
+	start := env.Now()
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, in)
+
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 20)
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 20, report["total_repos"])
+	return env.Now().Sub(start)
+}
+
+func TestWindowBeatsBatchesForSkewedDurations(t *testing.T) {
+	batches := scanDuration(t, ScanInput{Org: "acme"})
+	window := scanDuration(t, ScanInput{Org: "acme", Concurrency: scanBatchSize})
+
+	// Each batch waits 100s for its slow repo; the window runs both slow
+	// repos alongside the fast ones.
+	require.GreaterOrEqual(t, batches, 200*time.Second)
+	require.Less(t, window, 120*time.Second)
+}
+
+func TestWindowCapsRepoConcurrency(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(6))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(10 * time.Second).Return(compliantUnless())
+	start := env.Now()
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Concurrency: 2})
+
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 6)
+	elapsed := env.Now().Sub(start)
+	require.GreaterOrEqual(t, elapsed, 30*time.Second, "two at a time")
+	require.Less(t, elapsed, 40*time.Second)
+}
+
+func TestWindowCancelDrainsInFlight(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(10))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(10 * time.Second).Return(compliantUnless())
+
+	// At 15s, repos 0-2 are done and 3-5 in flight.
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("cancel_scan", "change freeze")
+	}, 15*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Concurrency: 3})
+
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 6)
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, true, report["cancelled"])
+	require.EqualValues(t, 3, report["repos_scanned_before_cancel"])
+	require.Equal(t, []interface{}{"repo-003", "repo-004", "repo-005"}, report["cancelled_in_flight_repos"])
+	require.Len(t, report["unscanned_repos"], 7)
+}
+
+func TestWindowPublishesProgressPerWindow(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(12))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	var mu sync.Mutex
+	scannedBySequence := map[int]int{}
+	env.OnActivity("PublishProgress", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			u := args.Get(1).(PublishProgressInput).Update
+			mu.Lock()
+			defer mu.Unlock()
+			scannedBySequence[u.Sequence] = u.Progress.ScannedRepos
+		}).
+		Return(nil)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{
+		Org:             "acme",
+		Concurrency:     4,
+		ProgressWebhook: &ProgressWebhook{URL: "http://dash.internal/progress"},
+	})
+
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, map[int]int{1: 4, 2: 8, 3: 12, 4: 12}, scannedBySequence, "every window's worth, then the end of the scan")
+}
+
+func TestWorkflowRejectsBadConcurrency(t *testing.T) {
+	for _, in := range []ScanInput{
+		{Org: "acme", Concurrency: -1},
+		{Org: "acme", Concurrency: 20, ChildPerBatch: true},
+		{Org: "acme", Concurrency: 20, StragglerTimeout: &StragglerTimeout{Multiple: 3}},
+	} {
+		env := newTestEnv(t)
+		env.ExecuteWorkflow(SecurityScanWorkflow, in)
+		var appErr *temporal.ApplicationError
+		require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+		require.Equal(t, ErrTypeInvalidInput, appErr.Type())
+	}
+	require.EqualError(t, ScanInput{Concurrency: 5, ActivityBatching: true, BatchDelay: &BatchDelay{Seconds: 1}}.validateConcurrency(),
+		"concurrency replaces batching and does not combine with [activity_batching batch_delay]")
+}
+
+func TestWorstCaseDurationWithConcurrency(t *testing.T) {
+	require.Equal(t, 30*time.Minute+2*15*time.Minute+2*time.Minute, ScanInput{Concurrency: 25}.WorstCaseDuration(50))
+}
@@ -0,0 +1,37 @@
+package main
+
+// =============================================================================
+// Audit log
+// =============================================================================
+//
+// With --audit-log set, every scan's start and end are recorded there (see
+// audit.go in the scanner package): a local NDJSON file the worker appends
+// to, or s3://bucket/prefix for one object per record, with credentials
+// from the AWS_* variables. Workers sharing a task queue should share the
+// log, since a scan's start and end may be recorded by different workers.
+// =============================================================================
+
+import (
+	"flag"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// auditFlags configure the worker's AuditLog.
+type auditFlags struct {
+	uri string
+}
+
+func registerAuditFlags(fs *flag.FlagSet) *auditFlags {
+	f := &auditFlags{}
+	fs.StringVar(&f.uri, "audit-log", "", "Record every scan's start and end in this NDJSON file, or under s3://bucket/prefix (default: off)")
+	return f
+}
+
+// open builds the configured AuditLog, or nil when auditing is off.
+func (f *auditFlags) open() (scanner.AuditLog, error) {
+	if f.uri == "" {
+		return nil, nil
+	}
+	return scanner.OpenAuditLog(f.uri)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+func parseAuditFlags(t *testing.T, args ...string) *auditFlags {
+	t.Helper()
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	f := registerAuditFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return f
+}
+
+func TestAuditFlags(t *testing.T) {
+	log, err := parseAuditFlags(t).open()
+	require.NoError(t, err)
+	require.Nil(t, log, "auditing is off by default")
+
+	log, err = parseAuditFlags(t, "--audit-log", "/var/log/scanner/audit.ndjson").open()
+	require.NoError(t, err)
+	require.Equal(t, "/var/log/scanner/audit.ndjson", log.(*scanner.FileAuditLog).Path)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	_, err = parseAuditFlags(t, "--audit-log", "s3://audit-bucket/scanner").open()
+	require.ErrorContains(t, err, "AWS_ACCESS_KEY_ID")
+
+	_, err = parseAuditFlags(t, "--audit-log", "gs://audit-bucket").open()
+	require.Error(t, err)
+}
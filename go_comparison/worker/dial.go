@@ -0,0 +1,181 @@
+package main
+
+// =============================================================================
+// Startup connection — wait for Temporal instead of crash-looping
+// =============================================================================
+//
+// In Kubernetes the worker often starts before the Temporal frontend is
+// ready, and a DNS blip at startup is not a reason to kill the pod. connect
+// retries the initial dial with capped exponential backoff and jitter until
+// WORKER_DIAL_MAX_WAIT runs out, logging each attempt. Errors retrying
+// cannot fix, such as a namespace that does not exist or a TLS handshake the
+// server rejects, still exit immediately.
+// =============================================================================
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+)
+
+// defaultDialMaxWait is how long the worker keeps trying to reach Temporal
+// at startup when WORKER_DIAL_MAX_WAIT is not set.
+const defaultDialMaxWait = 2 * time.Minute
+
+// dialRetry is the backoff connect uses between dial attempts.
+type dialRetry struct {
+	// MaxWait bounds the time spent retrying; 0 means a single attempt.
+	MaxWait         time.Duration
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+
+	// now, sleep, and jitter are replaced in tests. jitter returns a
+	// number in [0, 1).
+	now    func() time.Time
+	sleep  func(context.Context, time.Duration) error
+	jitter func() float64
+}
+
+func defaultDialRetry(maxWait time.Duration) dialRetry {
+	return dialRetry{
+		MaxWait:         maxWait,
+		InitialInterval: time.Second,
+		MaxInterval:     30 * time.Second,
+		now:             time.Now,
+		sleep:           sleepContext,
+		jitter:          rand.Float64,
+	}
+}
+
+// backoff is the wait after the given failed attempt (1-based): the
+// interval doubles from InitialInterval up to MaxInterval, and a random
+// half of it is dropped so a fleet of workers does not retry in lockstep.
+func (r dialRetry) backoff(attempt int) time.Duration {
+	d := r.InitialInterval
+	for i := 1; i < attempt && d < r.MaxInterval; i++ {
+		d *= 2
+	}
+	if d > r.MaxInterval {
+		d = r.MaxInterval
+	}
+	return d/2 + time.Duration(r.jitter()*float64(d/2))
+}
+
+// connect calls dial until it succeeds, fails with an error retrying cannot
+// fix, or retry.MaxWait has passed.
+func connect(ctx context.Context, logger *slog.Logger, retry dialRetry, dial func() (client.Client, error)) (client.Client, error) {
+	start := retry.now()
+	for attempt := 1; ; attempt++ {
+		c, err := dial()
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("Connected to Temporal", "attempt", attempt, "elapsed", retry.now().Sub(start).Round(time.Millisecond))
+			}
+			return c, nil
+		}
+		if !retryableDialError(err) {
+			logger.Error("Cannot connect to Temporal; not retrying", "attempt", attempt, "error", err)
+			return nil, err
+		}
+
+		elapsed := retry.now().Sub(start)
+		wait := retry.backoff(attempt)
+		if remaining := retry.MaxWait - elapsed; wait > remaining {
+			wait = remaining
+		}
+		if wait <= 0 {
+			return nil, fmt.Errorf("giving up after %d attempts over %s: %w", attempt, elapsed.Round(time.Second), err)
+		}
+		logger.Warn("Temporal not reachable; retrying",
+			"attempt", attempt, "error", err, "retry_in", wait.Round(time.Millisecond), "max_wait", retry.MaxWait)
+		if err := retry.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryableDialError reports whether err looks like the server or network
+// not being ready yet, as opposed to a configuration mistake.
+func retryableDialError(err error) bool {
+	if tlsError(err) {
+		return false
+	}
+	var unavailable *serviceerror.Unavailable
+	var deadline *serviceerror.DeadlineExceeded
+	var exhausted *serviceerror.ResourceExhausted
+	var netErr net.Error
+	switch {
+	case errors.As(err, &unavailable), errors.As(err, &deadline), errors.As(err, &exhausted):
+		return true
+	case errors.As(err, &netErr):
+		return true
+	case errors.Is(err, context.DeadlineExceeded):
+		return true
+	}
+	return false
+}
+
+// tlsError reports whether err is a certificate or handshake failure. gRPC
+// reports those as Unavailable with the cause flattened into the message,
+// so the message is checked too.
+func tlsError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var header tls.RecordHeaderError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalid) || errors.As(err, &hostname) || errors.As(err, &header) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "handshake failed") || strings.Contains(msg, "x509:") || strings.Contains(msg, "tls:")
+}
+
+// dialTemporal dials and then checks that the namespace exists, so a typo
+// in it fails at startup rather than when the worker starts polling.
+func dialTemporal(opts client.Options) (client.Client, error) {
+	c, err := client.Dial(opts)
+	if err != nil {
+		return nil, err
+	}
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = client.DefaultNamespace
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = c.WorkflowService().DescribeNamespace(ctx, &workflowservice.DescribeNamespaceRequest{Namespace: namespace})
+	var notFound *serviceerror.NamespaceNotFound
+	var denied *serviceerror.PermissionDenied
+	switch {
+	case err == nil, errors.As(err, &denied):
+		// A worker may be allowed to poll without describing namespaces.
+		return c, nil
+	case errors.As(err, &notFound):
+		c.Close()
+		return nil, fmt.Errorf("namespace %q not found: %w", namespace, err)
+	}
+	c.Close()
+	return nil, err
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
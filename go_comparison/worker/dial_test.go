@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+// fakeClient stands in for a connected client; connect never calls it.
+type fakeClient struct{ client.Client }
+
+// fakeRetry is a dialRetry on a fake clock that records every wait.
+func fakeRetry(maxWait time.Duration) (dialRetry, *[]time.Duration) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	var waits []time.Duration
+	r := defaultDialRetry(maxWait)
+	r.now = func() time.Time { return now }
+	r.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		now = now.Add(d)
+		return nil
+	}
+	r.jitter = func() float64 { return 0.5 }
+	return r, &waits
+}
+
+var quietLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestConnectRetriesUntilReachable(t *testing.T) {
+	retry, waits := fakeRetry(time.Minute)
+	attempts := 0
+	c, err := connect(context.Background(), quietLogger, retry, func() (client.Client, error) {
+		attempts++
+		if attempts < 4 {
+			return nil, fmt.Errorf("failed reaching server: %w", serviceerror.NewUnavailable("connection refused"))
+		}
+		return fakeClient{}, nil
+	})
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.Equal(t, 4, attempts)
+	// 1s, 2s, 4s, each with a quarter dropped by the fixed jitter.
+	require.Equal(t, []time.Duration{750 * time.Millisecond, 1500 * time.Millisecond, 3 * time.Second}, *waits)
+}
+
+func TestConnectGivesUpAfterMaxWait(t *testing.T) {
+	retry, waits := fakeRetry(10 * time.Second)
+	attempts := 0
+	_, err := connect(context.Background(), quietLogger, retry, func() (client.Client, error) {
+		attempts++
+		return nil, &net.DNSError{Err: "no such host", Name: "temporal-frontend", IsTemporary: true}
+	})
+	require.ErrorContains(t, err, "giving up after")
+	require.ErrorContains(t, err, "no such host")
+
+	var total time.Duration
+	for _, w := range *waits {
+		total += w
+	}
+	require.Equal(t, 10*time.Second, total, "the last wait is cut to the time left")
+	require.Equal(t, len(*waits)+1, attempts)
+}
+
+func TestConnectFatalErrorsExitImmediately(t *testing.T) {
+	for name, dialErr := range map[string]error{
+		"unknown namespace": fmt.Errorf("namespace %q not found: %w", "scans", serviceerror.NewNamespaceNotFound("scans")),
+		"bad TLS":           fmt.Errorf("failed reaching server: %w", serviceerror.NewUnavailable(`connection error: desc = "transport: authentication handshake failed: x509: certificate signed by unknown authority"`)),
+		"unauthenticated":   serviceerror.NewPermissionDenied("request unauthorized", ""),
+	} {
+		dialErr := dialErr
+		t.Run(name, func(t *testing.T) {
+			retry, waits := fakeRetry(time.Minute)
+			attempts := 0
+			_, err := connect(context.Background(), quietLogger, retry, func() (client.Client, error) {
+				attempts++
+				return nil, dialErr
+			})
+			require.True(t, errors.Is(err, dialErr))
+			require.Equal(t, 1, attempts)
+			require.Empty(t, *waits)
+		})
+	}
+}
+
+func TestConnectNoRetryWhenMaxWaitIsZero(t *testing.T) {
+	retry, waits := fakeRetry(0)
+	_, err := connect(context.Background(), quietLogger, retry, func() (client.Client, error) {
+		return nil, serviceerror.NewUnavailable("connection refused")
+	})
+	require.ErrorContains(t, err, "giving up after 1 attempts")
+	require.Empty(t, *waits)
+}
+
+func TestConnectStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	retry := defaultDialRetry(time.Hour)
+	cancel()
+	_, err := connect(ctx, quietLogger, retry, func() (client.Client, error) {
+		return nil, serviceerror.NewUnavailable("connection refused")
+	})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBackoffCapsAtMaxInterval(t *testing.T) {
+	retry, _ := fakeRetry(time.Hour)
+	retry.jitter = func() float64 { return 0 }
+	require.Equal(t, 500*time.Millisecond, retry.backoff(1))
+	require.Equal(t, 8*time.Second, retry.backoff(5))
+	require.Equal(t, 15*time.Second, retry.backoff(20), "half of MaxInterval with no jitter added")
+}
@@ -0,0 +1,64 @@
+package main
+
+// =============================================================================
+// SIEM forwarding — Splunk HTTP Event Collector
+// =============================================================================
+//
+// With --splunk-hec-url set, every scan ends by sending its findings to
+// that collector (see forward.go in the scanner package). The HEC token is
+// read from SPLUNK_HEC_TOKEN so it stays out of the process list.
+// =============================================================================
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// hecFlags configure the worker's HECConfig.
+type hecFlags struct {
+	url        string
+	index      string
+	source     string
+	sourceType string
+	mode       string
+	ack        bool
+}
+
+func registerHECFlags(fs *flag.FlagSet) *hecFlags {
+	f := &hecFlags{}
+	fs.StringVar(&f.url, "splunk-hec-url", "", "Forward findings to this Splunk HTTP Event Collector, e.g. https://splunk:8088 (token from $SPLUNK_HEC_TOKEN)")
+	fs.StringVar(&f.index, "splunk-hec-index", "", "The index for forwarded events (default: the token's)")
+	fs.StringVar(&f.source, "splunk-hec-source", scanner.DefaultHECSource, "The source of forwarded events")
+	fs.StringVar(&f.sourceType, "splunk-hec-sourcetype", "", "The sourcetype of forwarded events (default depends on --splunk-hec-mode)")
+	fs.StringVar(&f.mode, "splunk-hec-mode", string(scanner.ForwardPerFinding), "One event per failed check (finding) or per scanned repo (repo)")
+	fs.BoolVar(&f.ack, "splunk-hec-ack", false, "Wait for indexer acknowledgment of each batch")
+	return f
+}
+
+// open builds the configured HECConfig, or nil when forwarding is off.
+func (f *hecFlags) open() (*scanner.HECConfig, error) {
+	if f.url == "" {
+		return nil, nil
+	}
+	token := os.Getenv("SPLUNK_HEC_TOKEN")
+	if token == "" {
+		return nil, errors.New("--splunk-hec-url needs SPLUNK_HEC_TOKEN")
+	}
+	mode := scanner.ForwardMode(f.mode)
+	if mode != scanner.ForwardPerFinding && mode != scanner.ForwardPerRepo {
+		return nil, fmt.Errorf("unknown --splunk-hec-mode %q: want finding or repo", f.mode)
+	}
+	return &scanner.HECConfig{
+		URL:        f.url,
+		Token:      token,
+		Index:      f.index,
+		Source:     f.source,
+		SourceType: f.sourceType,
+		Mode:       mode,
+		UseAck:     f.ack,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+func parseHECFlags(t *testing.T, args ...string) *hecFlags {
+	t.Helper()
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	f := registerHECFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return f
+}
+
+func TestHECFlags(t *testing.T) {
+	t.Setenv("SPLUNK_HEC_TOKEN", "")
+	cfg, err := parseHECFlags(t).open()
+	require.NoError(t, err)
+	require.Nil(t, cfg, "forwarding is off by default")
+
+	_, err = parseHECFlags(t, "--splunk-hec-url", "https://splunk:8088").open()
+	require.ErrorContains(t, err, "SPLUNK_HEC_TOKEN")
+
+	t.Setenv("SPLUNK_HEC_TOKEN", "hec-token")
+	cfg, err = parseHECFlags(t, "--splunk-hec-url", "https://splunk:8088", "--splunk-hec-index", "security",
+		"--splunk-hec-mode", "repo", "--splunk-hec-ack").open()
+	require.NoError(t, err)
+	require.Equal(t, &scanner.HECConfig{
+		URL: "https://splunk:8088", Token: "hec-token", Index: "security",
+		Source: scanner.DefaultHECSource, Mode: scanner.ForwardPerRepo, UseAck: true,
+	}, cfg)
+
+	_, err = parseHECFlags(t, "--splunk-hec-url", "https://splunk:8088", "--splunk-hec-mode", "batch").open()
+	require.Error(t, err)
+}
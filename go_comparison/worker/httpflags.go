@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// registerHTTPFlags adds the --http-* flags, which fill the HTTP settings
+// of the returned config.
+func registerHTTPFlags(fs *flag.FlagSet) *scanner.ActivitiesConfig {
+	cfg := &scanner.ActivitiesConfig{}
+	fs.DurationVar(&cfg.RequestTimeout, "http-timeout", scanner.DefaultRequestTimeout, "Upper bound on one GitHub/GitLab request, body included")
+	fs.DurationVar(&cfg.DialTimeout, "http-dial-timeout", scanner.DefaultDialTimeout, "Timeout for connecting to the API")
+	fs.DurationVar(&cfg.TLSHandshakeTimeout, "http-tls-timeout", scanner.DefaultTLSHandshakeTimeout, "Timeout for the TLS handshake")
+	fs.DurationVar(&cfg.ResponseHeaderTimeout, "http-response-header-timeout", scanner.DefaultResponseHeaderTimeout, "Timeout waiting for response headers after sending a request")
+	fs.IntVar(&cfg.MaxIdleConnsPerHost, "http-max-idle-conns-per-host", scanner.DefaultMaxIdleConnsPerHost, "Idle connections kept per API host (the batch concurrency is 10)")
+	fs.StringVar(&cfg.ProxyURL, "http-proxy", "", "Send API requests through this proxy (default: HTTPS_PROXY/NO_PROXY)")
+	fs.StringVar(&cfg.UserAgent, "user-agent", "", "User-Agent sent to the API (default "+scanner.DefaultUserAgent()+")")
+	return cfg
+}
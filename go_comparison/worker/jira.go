@@ -0,0 +1,65 @@
+package main
+
+// =============================================================================
+// Remediation issues — Jira Cloud
+// =============================================================================
+//
+// With --jira-url set, every scan ends by filing or updating one Jira issue
+// per team with non-compliant repos (see jira.go in the scanner package).
+// The API token is read from JIRA_API_TOKEN; --jira-email is its owner.
+// =============================================================================
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// jiraFlags configure the worker's JiraConfig.
+type jiraFlags struct {
+	url       string
+	email     string
+	project   string
+	issueType string
+	maxCreate int
+	dryRun    bool
+}
+
+func registerJiraFlags(fs *flag.FlagSet) *jiraFlags {
+	f := &jiraFlags{}
+	fs.StringVar(&f.url, "jira-url", "", "File remediation issues in this Jira Cloud site, e.g. https://acme.atlassian.net (token from $JIRA_API_TOKEN)")
+	fs.StringVar(&f.email, "jira-email", "", "With --jira-url, the account that owns the API token")
+	fs.StringVar(&f.project, "jira-project", "", "With --jira-url, the project key to file issues in")
+	fs.StringVar(&f.issueType, "jira-issue-type", scanner.DefaultJiraIssueType, "The type of filed issues")
+	fs.IntVar(&f.maxCreate, "jira-max-create", scanner.DefaultJiraMaxCreate, "Create at most this many issues per scan (updates are not capped)")
+	fs.BoolVar(&f.dryRun, "jira-dry-run", false, "Look issues up and report what would change, without writing to Jira")
+	return f
+}
+
+// open builds the configured JiraConfig, or nil when Jira is off.
+func (f *jiraFlags) open() (*scanner.JiraConfig, error) {
+	if f.url == "" {
+		return nil, nil
+	}
+	if f.email == "" || f.project == "" {
+		return nil, errors.New("--jira-url needs --jira-email and --jira-project")
+	}
+	token := os.Getenv("JIRA_API_TOKEN")
+	if token == "" {
+		return nil, errors.New("--jira-url needs JIRA_API_TOKEN")
+	}
+	if f.maxCreate < 0 {
+		return nil, errors.New("--jira-max-create cannot be negative")
+	}
+	return &scanner.JiraConfig{
+		URL:       f.url,
+		Email:     f.email,
+		APIToken:  token,
+		Project:   f.project,
+		IssueType: f.issueType,
+		MaxCreate: f.maxCreate,
+		DryRun:    f.dryRun,
+	}, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+func parseJiraFlags(t *testing.T, args ...string) *jiraFlags {
+	t.Helper()
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	f := registerJiraFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return f
+}
+
+func TestJiraFlags(t *testing.T) {
+	t.Setenv("JIRA_API_TOKEN", "")
+	cfg, err := parseJiraFlags(t).open()
+	require.NoError(t, err)
+	require.Nil(t, cfg, "Jira is off by default")
+
+	site := []string{"--jira-url", "https://acme.atlassian.net", "--jira-email", "bot@acme.io", "--jira-project", "SEC"}
+	_, err = parseJiraFlags(t, site...).open()
+	require.ErrorContains(t, err, "JIRA_API_TOKEN")
+
+	t.Setenv("JIRA_API_TOKEN", "jira-token")
+	cfg, err = parseJiraFlags(t, append(site, "--jira-dry-run", "--jira-max-create", "3")...).open()
+	require.NoError(t, err)
+	require.Equal(t, &scanner.JiraConfig{
+		URL: "https://acme.atlassian.net", Email: "bot@acme.io", APIToken: "jira-token", Project: "SEC",
+		IssueType: scanner.DefaultJiraIssueType, MaxCreate: 3, DryRun: true,
+	}, cfg)
+
+	for _, args := range [][]string{
+		{"--jira-url", "https://acme.atlassian.net", "--jira-project", "SEC"},
+		{"--jira-url", "https://acme.atlassian.net", "--jira-email", "bot@acme.io"},
+		append(site, "--jira-max-create", "-1"),
+	} {
+		_, err := parseJiraFlags(t, args...).open()
+		require.Error(t, err, args)
+	}
+}
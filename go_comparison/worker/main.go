@@ -23,7 +23,7 @@ package main
 //     )
 //     await worker.run()
 //
-// GO: (below)
+// GO: (below, registration in runworker.go of the scanner package)
 //     w := worker.New(c, TaskQueue, worker.Options{})
 //     w.RegisterWorkflow(scanner.SecurityScanWorkflow)
 //     w.RegisterActivity(&activities)  // Register the struct instance
@@ -33,42 +33,186 @@ package main
 // activities (because they block the event loop). Go activities are natively
 // synchronous — the Go SDK handles concurrency internally via goroutines.
 // This is one area where Go's concurrency model is genuinely simpler.
+//
+// SCANNER_CONFIG names a YAML file of flag names to values, with an env
+// section for the variables below; flags beat the environment, which beats
+// the file (see config.go in the scanner package). --print-config prints
+// the merged settings and exits.
 // =============================================================================
 
 import (
+	"context"
+	"flag"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
 	"go.temporal.io/sdk/worker"
 
 	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
 )
 
-// TaskQueue is separate from the Python worker so both can run against the same server.
-const TaskQueue = "security-scanner-go"
+// configOptions are the environment variables the worker reads, which a
+// SCANNER_CONFIG file may set.
+var configOptions = scanner.ConfigOptions{
+	Env: []string{
+		"WORKER_DIAL_MAX_WAIT", "WORKER_METRICS_ADDR", "SCAN_PAYLOAD_COMPRESSION", "SCAN_BLOB_STORE",
+		"GITHUB_API_URL", "GITLAB_API_URL", "GITHUB_TOKEN",
+		"VAULT_ADDR", "VAULT_NAMESPACE", "VAULT_TOKEN",
+		"AWS_REGION", "AWS_ENDPOINT_URL", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+		"SPLUNK_HEC_TOKEN", "JIRA_API_TOKEN", "PAGERDUTY_ROUTING_KEY", "DD_API_KEY", "DD_SITE",
+		"REDIS_PASSWORD",
+	},
+	FlagEnv: map[string][]string{
+		"vault-addr":      {"VAULT_ADDR"},
+		"vault-namespace": {"VAULT_NAMESPACE"},
+		"datadog-site":    {"DD_SITE"},
+	},
+	Secrets: []string{
+		"GITHUB_TOKEN", "VAULT_TOKEN", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+		"SPLUNK_HEC_TOKEN", "JIRA_API_TOKEN", "PAGERDUTY_ROUTING_KEY", "DD_API_KEY", "REDIS_PASSWORD",
+	},
+}
 
 func main() {
+	secrets := registerSecretFlags(flag.CommandLine)
+	activityConfig := registerHTTPFlags(flag.CommandLine)
+	hec := registerHECFlags(flag.CommandLine)
+	datadog := registerDatadogFlags(flag.CommandLine)
+	jira := registerJiraFlags(flag.CommandLine)
+	pagerDuty := registerPagerDutyFlags(flag.CommandLine)
+	audit := registerAuditFlags(flag.CommandLine)
+	rateLimit := registerRateLimitFlags(flag.CommandLine)
+	orgConfig := registerOrgConfigFlags(flag.CommandLine)
+	versioning := registerVersioningFlags(flag.CommandLine)
+	maxSessions := flag.Int("max-concurrent-sessions", 100, "How many worker_affinity scans this worker runs the checks of at once")
+	progressCache := flag.Bool("progress-cache", false, "Keep each scan's latest progress in the worker and serve it on WORKER_METRICS_ADDR at /progress/{workflowID}, for when the progress query is unavailable")
+	printConfig := flag.Bool(scanner.PrintConfigFlag, false, "Print the settings merged from the command line, environment, SCANNER_CONFIG and defaults, with secrets redacted, and exit")
+	flag.Parse()
+
+	cfg, err := scanner.LoadConfig(flag.CommandLine, os.Getenv("SCANNER_CONFIG"), configOptions)
+	if err != nil {
+		log.Fatalln("Invalid SCANNER_CONFIG:", err)
+	}
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+	if *maxSessions < 1 {
+		log.Fatalln("--max-concurrent-sessions must be at least 1")
+	}
+
 	// Connect to Temporal server
 	// Python: client = await Client.connect("localhost:7233")
-	c, err := client.Dial(client.Options{
-		HostPort: client.DefaultHostPort, // localhost:7233
+	//
+	// The first dial is retried for up to WORKER_DIAL_MAX_WAIT (default 2m)
+	// so a worker that starts before Temporal waits instead of crash-looping.
+	maxWait := defaultDialMaxWait
+	if v := os.Getenv("WORKER_DIAL_MAX_WAIT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid WORKER_DIAL_MAX_WAIT %q: want a duration such as 90s", v)
+		}
+		maxWait = d
+	}
+	//
+	// With SCAN_PAYLOAD_COMPRESSION set ("on" for 1024, or a size in bytes),
+	// payloads that large or larger are zlib-compressed; see codec.go. It is
+	// off by default. The starter reads the same variable, and both always
+	// decode compressed payloads.
+	threshold, err := scanner.ParseCompressionThreshold(os.Getenv("SCAN_PAYLOAD_COMPRESSION"))
+	if err != nil {
+		log.Fatalln("Invalid SCAN_PAYLOAD_COMPRESSION:", err)
+	}
+	opts := client.Options{
+		HostPort:      client.DefaultHostPort, // localhost:7233
+		DataConverter: scanner.NewDataConverter(threshold),
+	}
+	// With WORKER_METRICS_ADDR, the SDK's metrics and the scan workflow's
+	// own (see workflowmetrics.go) are served on /metrics too.
+	metricsAddr := os.Getenv("WORKER_METRICS_ADDR")
+	var sdkMetrics *scanner.PromMetricsHandler
+	if metricsAddr != "" {
+		sdkMetrics = scanner.NewPromMetricsHandler()
+		opts.MetricsHandler = sdkMetrics
+	}
+	c, err := connect(context.Background(), slog.Default(), defaultDialRetry(maxWait), func() (client.Client, error) {
+		return dialTemporal(opts)
 	})
 	if err != nil {
 		log.Fatalln("Unable to create Temporal client:", err)
 	}
 	defer c.Close()
 
-	// Create worker
+	// Worker options
 	// Python: Worker(client, task_queue=TASK_QUEUE, ...)
-	w := worker.New(c, TaskQueue, worker.Options{})
-
-	// Register workflow
-	// Python: workflows=[SecurityScanWorkflow]
-	w.RegisterWorkflow(scanner.SecurityScanWorkflow)
+	//
+	// apiUsage counts each scan's GitHub/GitLab requests and enforces
+	// ScanInput.MaxAPIRequests; its interceptor tells activities which scan
+	// they belong to. WORKER_METRICS_ADDR (e.g. :9090) serves the counts on
+	// /metrics for Prometheus, along with the compliance gauges of each
+	// org's last completed scan on this worker (see prometheus.go), the
+	// rate limiter's counters (see ratelimit.go) and the metrics handler's.
+	//
+	// Scans with worker_affinity run their checks in a session on one
+	// worker (see session.go); --max-concurrent-sessions caps how many this
+	// worker takes.
+	//
+	// --progress-cache also serves each scan's progress, as this worker's
+	// activities last saw it, on /progress/{workflowID} (see
+	// progresscache.go).
+	apiUsage := scanner.NewAPIUsageTracker()
+	rateLimiter, err := rateLimit.open()
+	if err != nil {
+		log.Fatalln("Invalid rate limiter settings:", err)
+	}
+	workerOptions := worker.Options{
+		Interceptors:                      []interceptor.WorkerInterceptor{apiUsage.Interceptor()},
+		EnableSessionWorker:               true,
+		MaxConcurrentSessionExecutionSize: *maxSessions,
+	}
+	var cache *scanner.ProgressCache
+	if *progressCache {
+		if metricsAddr == "" {
+			log.Fatalln("--progress-cache needs WORKER_METRICS_ADDR to serve the progress on")
+		}
+		cache = scanner.NewProgressCache()
+		workerOptions.Interceptors = append(workerOptions.Interceptors, cache.Interceptor())
+	}
+	// --worker-versioning pins each scan to the build it started on (see
+	// versioning.go for the rollout).
+	if err := versioning.apply(&workerOptions); err != nil {
+		log.Fatalln("Invalid worker versioning settings:", err)
+	}
+	var latestReports *scanner.LatestReports
+	if metricsAddr != "" {
+		latestReports = scanner.NewLatestReports()
+		mux := http.NewServeMux()
+		sources := []scanner.PromSource{apiUsage, latestReports}
+		if rateLimiter != nil {
+			sources = append(sources, rateLimiter)
+		}
+		sources = append(sources, sdkMetrics)
+		mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			if err := scanner.WritePromSources(w, sources...); err != nil {
+				log.Println("Writing /metrics:", err)
+			}
+		})
+		if cache != nil {
+			mux.Handle(scanner.ProgressCachePath, cache)
+		}
+		go func() {
+			log.Fatalln("Metrics server failed:", http.ListenAndServe(metricsAddr, mux))
+		}()
+	}
 
-	// Create activity struct with dependencies and register it.
+	// Create activity struct with dependencies; RunWorker registers it
+	// along with the workflows.
 	//
 	// This is the key difference: Go registers a *struct instance*.
 	// All methods on that struct become available as activities.
@@ -84,12 +228,81 @@ func main() {
 	//   - Each function is independent
 	//   - Dependencies passed as parameters or via module globals
 	//   - For testing, you register different functions entirely
-	activities := &scanner.Activities{
-		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	//
+	// SCAN_BLOB_STORE picks where large result sets are offloaded (see
+	// blobstore.go): a directory, file:///path, or s3://bucket/prefix.
+	// Every worker on the task queue must be able to read it, so there is
+	// no default: a local directory would split one scan's chunks, and
+	// each org's baseline and trend, across hosts. Without it results stay
+	// inline and there is no scan history.
+	blobURI := os.Getenv("SCAN_BLOB_STORE")
+	var blobStore scanner.BlobStore
+	if blobURI == "" {
+		blobURI = "none"
+		log.Println("No SCAN_BLOB_STORE: results are not offloaded, and scans keep no history, so --pagerduty-threshold never pages and the starter's --history only sees saved report files. Set it to a store every worker can read.")
+	} else if blobStore, err = scanner.OpenBlobStore(blobURI); err != nil {
+		log.Fatalln("Unable to open blob store:", err)
+	}
+	//
+	// GITHUB_API_URL points the activities at another API root, e.g. the
+	// offline fake from ./go_comparison/mockgithub. GITLAB_API_URL does the
+	// same for a self-managed GitLab instance.
+	//
+	// Scans started without a token use the one from --github-token-source
+	// (see secrets.go): GITHUB_TOKEN by default, or Vault or AWS Secrets
+	// Manager so the token is in neither the history nor the environment.
+	// A scan whose credential names a reference gets the token from
+	// SCANNER_CREDENTIAL_<REFERENCE> (see credential.go).
+	// The --http-* flags tune the activities' HTTP client (see httpclient.go).
+	secretSource, err := secrets.open(&http.Client{Timeout: 30 * time.Second})
+	if err != nil {
+		log.Fatalln("Invalid GitHub token source:", err)
+	}
+	activityConfig.BaseURL = os.Getenv("GITHUB_API_URL")
+	activityConfig.GitLabURL = os.Getenv("GITLAB_API_URL")
+	activityConfig.BlobStore = blobStore
+	activityConfig.APIUsage = apiUsage
+	activityConfig.Secrets = secretSource
+	activityConfig.Credentials = scanner.EnvCredentialSource{}
+	activityConfig.LatestReports = latestReports
+	activityConfig.RateLimiter = rateLimiter
+	// --splunk-hec-url forwards every scan's findings (see forwarding.go).
+	activityConfig.HEC, err = hec.open()
+	if err != nil {
+		log.Fatalln("Invalid Splunk HEC settings:", err)
+	}
+	// --jira-url files remediation issues per team (see jira.go).
+	activityConfig.Jira, err = jira.open()
+	if err != nil {
+		log.Fatalln("Invalid Jira settings:", err)
+	}
+	// --pagerduty-threshold pages on compliance regressions (see pagerduty.go).
+	activityConfig.PagerDuty, err = pagerDuty.open()
+	if err != nil {
+		log.Fatalln("Invalid PagerDuty settings:", err)
+	}
+	// --datadog-metrics graphs every scan's numbers (see metrics.go).
+	activityConfig.Datadog, err = datadog.open()
+	if err != nil {
+		log.Fatalln("Invalid Datadog settings:", err)
+	}
+	// --audit-log records every scan's start and end (see audit.go).
+	activityConfig.AuditLog, err = audit.open()
+	if err != nil {
+		log.Fatalln("Invalid audit log:", err)
+	}
+	// --org-config supplies per-org scan defaults (see orgconfig.go).
+	activityConfig.OrgConfigs, err = orgConfig.open()
+	if err != nil {
+		log.Fatalln("Invalid org config:", err)
+	}
+	activities, err := scanner.NewActivities(*activityConfig)
+	if err != nil {
+		log.Fatalln("Invalid HTTP settings:", err)
 	}
-	w.RegisterActivity(activities)
 
-	log.Printf("Worker started on task queue '%s'", TaskQueue)
+	log.Printf("Worker started on task queue '%s' (build ID: %s, versioning: %t, blob store: %s, GitHub token: %s)",
+		scanner.TaskQueue, workerOptions.BuildID, workerOptions.UseBuildIDForVersioning, blobURI, secrets.source)
 
 	// Run the worker until interrupted.
 	//
@@ -98,7 +311,11 @@ func main() {
 	// worker.InterruptCh() returns a channel that closes on SIGINT/SIGTERM.
 	// This is Go's idiomatic signal handling. Python's asyncio.run() handles
 	// this via its event loop.
-	err = w.Run(worker.InterruptCh())
+	err = scanner.RunWorker(scanner.WorkerConfig{
+		Client:     c,
+		Options:    workerOptions,
+		Activities: activities,
+	}, worker.InterruptCh())
 	if err != nil {
 		log.Fatalln("Worker failed:", err)
 	}
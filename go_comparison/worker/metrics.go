@@ -0,0 +1,69 @@
+package main
+
+// =============================================================================
+// Compliance metrics — Datadog
+// =============================================================================
+//
+// With --datadog-metrics set, every scan ends by sending its compliance
+// gauges to Datadog (see metrics.go in the scanner package):
+//
+//	api     the metrics API; the key is read from DD_API_KEY
+//	statsd  a DogStatsD agent at --dogstatsd-addr
+// =============================================================================
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// datadogFlags configure the worker's DatadogConfig.
+type datadogFlags struct {
+	transport  string
+	site       string
+	statsdAddr string
+	tags       string
+}
+
+func registerDatadogFlags(fs *flag.FlagSet) *datadogFlags {
+	f := &datadogFlags{}
+	fs.StringVar(&f.transport, "datadog-metrics", "", "Send compliance metrics to Datadog: api (key from $DD_API_KEY) or statsd (default: off)")
+	fs.StringVar(&f.site, "datadog-site", "", "With api, the Datadog site (default $DD_SITE or datadoghq.com)")
+	fs.StringVar(&f.statsdAddr, "dogstatsd-addr", scanner.DefaultDogStatsdAddr, "With statsd, the DogStatsD agent's host:port")
+	fs.StringVar(&f.tags, "datadog-tags", "", "Comma-separated tags added to every metric, e.g. env:prod")
+	return f
+}
+
+// open builds the configured DatadogConfig, or nil when metrics are off.
+func (f *datadogFlags) open() (*scanner.DatadogConfig, error) {
+	var tags []string
+	for _, tag := range strings.Split(f.tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	switch scanner.DatadogTransport(f.transport) {
+	case "":
+		return nil, nil
+	case scanner.DatadogAPI:
+		key := os.Getenv("DD_API_KEY")
+		if key == "" {
+			return nil, errors.New("--datadog-metrics api needs DD_API_KEY")
+		}
+		site := f.site
+		if site == "" {
+			site = os.Getenv("DD_SITE")
+		}
+		if site == "" {
+			site = scanner.DefaultDatadogSite
+		}
+		return &scanner.DatadogConfig{Transport: scanner.DatadogAPI, APIKey: key, Site: site, Tags: tags}, nil
+	case scanner.DatadogStatsd:
+		return &scanner.DatadogConfig{Transport: scanner.DatadogStatsd, StatsdAddr: f.statsdAddr, Tags: tags}, nil
+	}
+	return nil, fmt.Errorf("unknown --datadog-metrics %q: want api or statsd", f.transport)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+func parseDatadogFlags(t *testing.T, args ...string) *datadogFlags {
+	t.Helper()
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	f := registerDatadogFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return f
+}
+
+func TestDatadogFlags(t *testing.T) {
+	t.Setenv("DD_SITE", "datadoghq.eu")
+	t.Setenv("DD_API_KEY", "")
+	cfg, err := parseDatadogFlags(t).open()
+	require.NoError(t, err)
+	require.Nil(t, cfg, "metrics are off by default")
+
+	_, err = parseDatadogFlags(t, "--datadog-metrics", "api").open()
+	require.ErrorContains(t, err, "DD_API_KEY")
+
+	t.Setenv("DD_API_KEY", "dd-key")
+	cfg, err = parseDatadogFlags(t, "--datadog-metrics", "api", "--datadog-tags", "env:prod, team:sec").open()
+	require.NoError(t, err)
+	require.Equal(t, &scanner.DatadogConfig{
+		Transport: scanner.DatadogAPI, APIKey: "dd-key", Site: "datadoghq.eu", Tags: []string{"env:prod", "team:sec"},
+	}, cfg)
+
+	cfg, err = parseDatadogFlags(t, "--datadog-metrics", "statsd").open()
+	require.NoError(t, err)
+	require.Equal(t, &scanner.DatadogConfig{Transport: scanner.DatadogStatsd, StatsdAddr: scanner.DefaultDogStatsdAddr}, cfg)
+
+	_, err = parseDatadogFlags(t, "--datadog-metrics", "prometheus").open()
+	require.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package main
+
+// =============================================================================
+// Org config registry
+// =============================================================================
+//
+// With --org-config set, scans of the orgs listed there get their
+// defaults from it (see orgconfig.go in the scanner package): a YAML file
+// mapping org names to defaults, or a directory of them. SIGHUP reloads
+// it; a reload that fails is logged and the previous entries stay in use.
+// Each worker reads its own copy, so workers sharing a task queue should
+// be given the same one.
+// =============================================================================
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// orgConfigFlags configure the worker's OrgConfigRegistry.
+type orgConfigFlags struct {
+	path string
+}
+
+func registerOrgConfigFlags(fs *flag.FlagSet) *orgConfigFlags {
+	f := &orgConfigFlags{}
+	fs.StringVar(&f.path, "org-config", "", "YAML file, or directory of them, of per-org scan defaults; reloaded on SIGHUP (default: package defaults for every org)")
+	return f
+}
+
+// open loads the configured registry and reloads it on SIGHUP, or returns
+// nil when there is none.
+func (f *orgConfigFlags) open() (*scanner.OrgConfigRegistry, error) {
+	if f.path == "" {
+		return nil, nil
+	}
+	registry, err := scanner.LoadOrgConfigRegistry(f.path)
+	if err != nil {
+		return nil, err
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go reloadOrgConfigs(registry, hup)
+	return registry, nil
+}
+
+// reloadOrgConfigs reloads registry each time hup delivers, until it is
+// closed.
+func reloadOrgConfigs(registry *scanner.OrgConfigRegistry, hup <-chan os.Signal) {
+	for range hup {
+		if err := registry.Reload(); err != nil {
+			log.Printf("Keeping the previous org config: %v", err)
+			continue
+		}
+		log.Printf("Reloaded org config from %s (%d orgs)", registry.Path, len(registry.Orgs()))
+	}
+}
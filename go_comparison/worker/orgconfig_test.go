@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseOrgConfigFlags(t *testing.T, args ...string) *orgConfigFlags {
+	t.Helper()
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	f := registerOrgConfigFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return f
+}
+
+func TestOrgConfigFlags(t *testing.T) {
+	registry, err := parseOrgConfigFlags(t).open()
+	require.NoError(t, err)
+	require.Nil(t, registry, "every org gets package defaults by default")
+
+	path := filepath.Join(t.TempDir(), "orgs.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("acme: {concurrency: 4}\n"), 0o644))
+	registry, err = parseOrgConfigFlags(t, "--org-config", path).open()
+	require.NoError(t, err)
+	require.Equal(t, []string{"acme"}, registry.Orgs())
+
+	_, err = parseOrgConfigFlags(t, "--org-config", filepath.Join(t.TempDir(), "missing.yaml")).open()
+	require.Error(t, err)
+}
+
+func TestReloadOrgConfigsOnHangup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orgs.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("acme: {}\n"), 0o644))
+	registry, err := parseOrgConfigFlags(t, "--org-config", path).open()
+	require.NoError(t, err)
+
+	// Each hangup reloads; a bad file keeps the entries of the last good one.
+	hup := make(chan os.Signal, 1)
+	require.NoError(t, os.WriteFile(path, []byte("acme: {}\nglobex: {}\n"), 0o644))
+	hup <- syscall.SIGHUP
+	close(hup)
+	reloadOrgConfigs(registry, hup)
+	require.Equal(t, []string{"acme", "globex"}, registry.Orgs())
+
+	hup = make(chan os.Signal, 1)
+	require.NoError(t, os.WriteFile(path, []byte("acme: {concurrency: -1}\n"), 0o644))
+	hup <- syscall.SIGHUP
+	close(hup)
+	reloadOrgConfigs(registry, hup)
+	require.Equal(t, []string{"acme", "globex"}, registry.Orgs())
+}
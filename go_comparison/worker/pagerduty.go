@@ -0,0 +1,55 @@
+package main
+
+// =============================================================================
+// Paging — PagerDuty
+// =============================================================================
+//
+// With --pagerduty-threshold set, a full-org scan pages on-call when its
+// compliance rate is below the threshold and worse than the org's last scan,
+// and resolves the page once the rate recovers (see pagerduty.go in the
+// scanner package). The service's Events API v2 integration key is read
+// from PAGERDUTY_ROUTING_KEY. The last scan is kept in SCAN_BLOB_STORE,
+// which every worker must share for paging to see it; without it no scan
+// has a baseline, so nothing pages.
+// =============================================================================
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// pagerDutyFlags configure the worker's PagerDutyConfig.
+type pagerDutyFlags struct {
+	threshold float64
+	severity  string
+}
+
+func registerPagerDutyFlags(fs *flag.FlagSet) *pagerDutyFlags {
+	f := &pagerDutyFlags{}
+	fs.Float64Var(&f.threshold, "pagerduty-threshold", 0, "Page when the compliance rate regresses below this percentage (key from $PAGERDUTY_ROUTING_KEY; default: off)")
+	fs.StringVar(&f.severity, "pagerduty-severity", scanner.DefaultPagerDutySeverity, "The severity of pages: critical, error, warning or info")
+	return f
+}
+
+// open builds the configured PagerDutyConfig, or nil when paging is off.
+func (f *pagerDutyFlags) open() (*scanner.PagerDutyConfig, error) {
+	if f.threshold == 0 {
+		return nil, nil
+	}
+	if f.threshold < 0 || f.threshold > 100 {
+		return nil, errors.New("--pagerduty-threshold must be a percentage between 0 and 100")
+	}
+	switch f.severity {
+	case "critical", "error", "warning", "info":
+	default:
+		return nil, errors.New("--pagerduty-severity must be critical, error, warning or info")
+	}
+	key := os.Getenv("PAGERDUTY_ROUTING_KEY")
+	if key == "" {
+		return nil, errors.New("--pagerduty-threshold needs PAGERDUTY_ROUTING_KEY")
+	}
+	return &scanner.PagerDutyConfig{RoutingKey: key, Threshold: f.threshold, Severity: f.severity}, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+func parsePagerDutyFlags(t *testing.T, args ...string) *pagerDutyFlags {
+	t.Helper()
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	f := registerPagerDutyFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return f
+}
+
+func TestPagerDutyFlags(t *testing.T) {
+	t.Setenv("PAGERDUTY_ROUTING_KEY", "")
+	cfg, err := parsePagerDutyFlags(t).open()
+	require.NoError(t, err)
+	require.Nil(t, cfg, "paging is off by default")
+
+	_, err = parsePagerDutyFlags(t, "--pagerduty-threshold", "80").open()
+	require.ErrorContains(t, err, "PAGERDUTY_ROUTING_KEY")
+
+	t.Setenv("PAGERDUTY_ROUTING_KEY", "pd-key")
+	cfg, err = parsePagerDutyFlags(t, "--pagerduty-threshold", "80", "--pagerduty-severity", "error").open()
+	require.NoError(t, err)
+	require.Equal(t, &scanner.PagerDutyConfig{RoutingKey: "pd-key", Threshold: 80, Severity: "error"}, cfg)
+
+	for _, args := range [][]string{
+		{"--pagerduty-threshold", "120"},
+		{"--pagerduty-threshold", "-5"},
+		{"--pagerduty-threshold", "80", "--pagerduty-severity", "sev1"},
+	} {
+		_, err := parsePagerDutyFlags(t, args...).open()
+		require.Error(t, err, args)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+// =============================================================================
+// Rate limiting
+// =============================================================================
+//
+// --rate-limiter paces each token's GitHub requests by the budget GitHub
+// reports for it (see ratelimiter.go in the scanner package):
+//
+//	local               each worker paces its own requests
+//	redis://host:6379   every worker pointed at the server paces together;
+//	                    rediss:// for TLS, password in the URL or
+//	                    $REDIS_PASSWORD
+//
+// Replicas sharing a token should share Redis; with local pacing each
+// spends the token's budget as if it were alone.
+// =============================================================================
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// rateLimitFlags configure the worker's RateLimiter.
+type rateLimitFlags struct {
+	limiter string
+	burst   int
+	maxWait time.Duration
+}
+
+func registerRateLimitFlags(fs *flag.FlagSet) *rateLimitFlags {
+	f := &rateLimitFlags{}
+	fs.StringVar(&f.limiter, "rate-limiter", "", "Pace GitHub requests by each token's remaining budget: local, or a redis:// URL shared by every worker (default: off)")
+	fs.IntVar(&f.burst, "rate-limit-burst", scanner.DefaultRateLimitBurst, "With --rate-limiter, how many requests a token may send at once")
+	fs.DurationVar(&f.maxWait, "rate-limit-max-wait", scanner.DefaultRateLimitMaxWait, "With --rate-limiter, the longest a request waits for its turn before failing to be retried")
+	return f
+}
+
+// open builds the configured RateLimiter, or nil when rate limiting is off.
+func (f *rateLimitFlags) open() (*scanner.RateLimiter, error) {
+	if f.burst < 1 {
+		return nil, errors.New("--rate-limit-burst must be at least 1")
+	}
+	if f.maxWait <= 0 {
+		return nil, errors.New("--rate-limit-max-wait must be positive")
+	}
+	var buckets scanner.RateBuckets
+	switch {
+	case f.limiter == "":
+		return nil, nil
+	case f.limiter == "local":
+	case strings.HasPrefix(f.limiter, "redis://") || strings.HasPrefix(f.limiter, "rediss://"):
+		redis, err := scanner.NewRedisRateBuckets(f.limiter)
+		if err != nil {
+			return nil, err
+		}
+		buckets = redis
+	default:
+		return nil, fmt.Errorf("unknown --rate-limiter %q: want local or a redis:// URL", f.limiter)
+	}
+	l := scanner.NewRateLimiter(buckets)
+	l.Burst, l.MaxWait = f.burst, f.maxWait
+	return l, nil
+}
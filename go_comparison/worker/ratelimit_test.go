@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+func parseRateLimitFlags(t *testing.T, args ...string) *rateLimitFlags {
+	t.Helper()
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	f := registerRateLimitFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return f
+}
+
+func TestRateLimitFlags(t *testing.T) {
+	l, err := parseRateLimitFlags(t).open()
+	require.NoError(t, err)
+	require.Nil(t, l, "rate limiting is off by default")
+
+	l, err = parseRateLimitFlags(t, "--rate-limiter", "local").open()
+	require.NoError(t, err)
+	require.Nil(t, l.Buckets)
+	require.Equal(t, scanner.DefaultRateLimitBurst, l.Burst)
+
+	t.Setenv("REDIS_PASSWORD", "s3cret")
+	l, err = parseRateLimitFlags(t, "--rate-limiter", "redis://redis.internal:6379/1", "--rate-limit-burst", "4", "--rate-limit-max-wait", "5s").open()
+	require.NoError(t, err)
+	redis := l.Buckets.(*scanner.RedisRateBuckets)
+	require.Equal(t, "redis.internal:6379", redis.Addr)
+	require.Equal(t, "s3cret", redis.Password)
+	require.Equal(t, 1, redis.DB)
+	require.Equal(t, 4, l.Burst)
+	require.Equal(t, 5*time.Second, l.MaxWait)
+
+	for _, args := range [][]string{
+		{"--rate-limiter", "memcached://cache:11211"},
+		{"--rate-limiter", "redis://"},
+		{"--rate-limiter", "local", "--rate-limit-burst", "0"},
+		{"--rate-limiter", "local", "--rate-limit-max-wait", "0s"},
+	} {
+		_, err := parseRateLimitFlags(t, args...).open()
+		require.Error(t, err, args)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+// =============================================================================
+// GitHub token source — keeping the PAT out of the worker's environment
+// =============================================================================
+//
+// Scans started without a token use the one the worker fetches from
+// --github-token-source (see secrets.go in the scanner package):
+//
+//	env    GITHUB_TOKEN, or the variable named by --github-token-env (default)
+//	vault  a Vault KV v2 secret; VAULT_ADDR and VAULT_TOKEN as for the vault CLI
+//	aws    an AWS Secrets Manager secret; AWS_* variables as for the S3 blob store
+//
+// Vault and AWS tokens are cached for --github-token-ttl, so a rotated token
+// is picked up within that long.
+// =============================================================================
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// secretFlags configure the worker's SecretSource.
+type secretFlags struct {
+	source         string
+	envVar         string
+	vaultAddr      string
+	vaultMount     string
+	vaultPath      string
+	vaultKey       string
+	vaultNamespace string
+	awsSecretID    string
+	awsSecretKey   string
+	ttl            time.Duration
+}
+
+func registerSecretFlags(fs *flag.FlagSet) *secretFlags {
+	f := &secretFlags{}
+	fs.StringVar(&f.source, "github-token-source", "env", "Where to get the GitHub token for scans started without one: env, vault or aws")
+	fs.StringVar(&f.envVar, "github-token-env", "GITHUB_TOKEN", "With env, the variable holding the token")
+	fs.StringVar(&f.vaultAddr, "vault-addr", "", "With vault, the Vault server (default $VAULT_ADDR)")
+	fs.StringVar(&f.vaultMount, "vault-mount", "secret", "With vault, the KV v2 engine's mount")
+	fs.StringVar(&f.vaultPath, "vault-path", "", "With vault, the secret's path within the mount, e.g. scanner/github")
+	fs.StringVar(&f.vaultKey, "vault-key", "token", "With vault, the secret field holding the token")
+	fs.StringVar(&f.vaultNamespace, "vault-namespace", "", "With vault, the Vault Enterprise namespace (default $VAULT_NAMESPACE)")
+	fs.StringVar(&f.awsSecretID, "aws-secret-id", "", "With aws, the secret's name or ARN")
+	fs.StringVar(&f.awsSecretKey, "aws-secret-key", "", "With aws, the JSON field holding the token (empty: the whole secret string)")
+	fs.DurationVar(&f.ttl, "github-token-ttl", scanner.DefaultSecretTTL, "With vault or aws, how long to cache the token")
+	return f
+}
+
+// open builds the configured SecretSource.
+func (f *secretFlags) open(client *http.Client) (scanner.SecretSource, error) {
+	switch f.source {
+	case "env", "":
+		return scanner.EnvSecretSource{Var: f.envVar}, nil
+	case "vault":
+		// The variables are read here rather than as the flags' defaults
+		// so that a config file's env section can set them.
+		if f.vaultAddr == "" {
+			f.vaultAddr = os.Getenv("VAULT_ADDR")
+		}
+		if f.vaultNamespace == "" {
+			f.vaultNamespace = os.Getenv("VAULT_NAMESPACE")
+		}
+		if f.vaultAddr == "" || f.vaultPath == "" {
+			return nil, errors.New("vault needs --vault-addr (or VAULT_ADDR) and --vault-path")
+		}
+		token := os.Getenv("VAULT_TOKEN")
+		if token == "" {
+			return nil, errors.New("vault needs VAULT_TOKEN")
+		}
+		return scanner.NewCachedSecretSource(&scanner.VaultSecretSource{
+			Addr:       f.vaultAddr,
+			Token:      token,
+			Namespace:  f.vaultNamespace,
+			Mount:      f.vaultMount,
+			Path:       f.vaultPath,
+			Key:        f.vaultKey,
+			HTTPClient: client,
+		}, f.ttl), nil
+	case "aws":
+		if f.awsSecretID == "" {
+			return nil, errors.New("aws needs --aws-secret-id")
+		}
+		s, err := scanner.NewAWSSecretSourceFromEnv(f.awsSecretID, f.awsSecretKey)
+		if err != nil {
+			return nil, err
+		}
+		s.HTTPClient = client
+		return scanner.NewCachedSecretSource(s, f.ttl), nil
+	}
+	return nil, fmt.Errorf("unknown --github-token-source %q: want env, vault or aws", f.source)
+}
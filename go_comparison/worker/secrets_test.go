@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+func parseSecretFlags(t *testing.T, args ...string) *secretFlags {
+	t.Helper()
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	f := registerSecretFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return f
+}
+
+func TestSecretFlags(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	t.Setenv("VAULT_TOKEN", "hvs.test")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	s, err := parseSecretFlags(t).open(nil)
+	require.NoError(t, err)
+	require.Equal(t, scanner.EnvSecretSource{Var: "GITHUB_TOKEN"}, s)
+
+	s, err = parseSecretFlags(t, "--github-token-source", "vault", "--vault-addr", "https://vault:8200", "--vault-path", "scanner/github").open(nil)
+	require.NoError(t, err)
+	require.IsType(t, &scanner.CachedSecretSource{}, s)
+
+	s, err = parseSecretFlags(t, "--github-token-source", "aws", "--aws-secret-id", "scanner/github").open(nil)
+	require.NoError(t, err)
+	require.IsType(t, &scanner.CachedSecretSource{}, s)
+
+	for _, args := range [][]string{
+		{"--github-token-source", "vault", "--vault-path", "scanner/github"},
+		{"--github-token-source", "vault", "--vault-addr", "https://vault:8200"},
+		{"--github-token-source", "aws"},
+		{"--github-token-source", "keychain"},
+	} {
+		_, err := parseSecretFlags(t, args...).open(nil)
+		require.Error(t, err, args)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+// =============================================================================
+// Worker versioning — Build IDs
+// =============================================================================
+//
+// Every worker polls with a Build ID: --build-id, by default the Version
+// stamped at build time (see models.go). With --worker-versioning the
+// server also routes by it: a scan stays on the set of builds it started
+// on, so a deploy that changes workflow code never replays an in-flight
+// scan on the new code. Its batch children and activities run on the same
+// set as the scan.
+//
+// Rollout of a workflow change, with v1 running:
+//
+//  1. Build the new worker: go build -ldflags "-X ...go_comparison.Version=v2".
+//  2. Make v2 the default for new scans:
+//     go run ./go_comparison/starter --promote-build-id v2
+//     Scans started from now on are dispatched only to v2 workers; until
+//     they poll, new scans wait in the queue.
+//  3. Start the v2 workers with --worker-versioning next to the v1 ones.
+//  4. Keep the v1 workers until their scans have finished (the UI's
+//     workflow list filtered by BuildIds, or `temporal task-queue
+//     get-build-id-reachability`), then stop them.
+//
+// Rolling back is promoting v1 again. The very first versioned deploy
+// needs step 2 too: a task queue without Build IDs sends nothing to
+// versioned workers.
+// =============================================================================
+
+import (
+	"errors"
+	"flag"
+
+	"go.temporal.io/sdk/worker"
+
+	scanner "github.com/salkimmich/temporal-security-scanner/go_comparison"
+)
+
+// versioningFlags set the worker's Build ID.
+type versioningFlags struct {
+	buildID    string
+	versioning bool
+}
+
+func registerVersioningFlags(fs *flag.FlagSet) *versioningFlags {
+	f := &versioningFlags{}
+	fs.StringVar(&f.buildID, "build-id", scanner.Version, "This worker's Build ID (default: the version it was built with)")
+	fs.BoolVar(&f.versioning, "worker-versioning", false, "Only take scans started on this Build ID's compatible set; promote it first with the starter's --promote-build-id")
+	return f
+}
+
+// apply sets the Build ID options on opts.
+func (f *versioningFlags) apply(opts *worker.Options) error {
+	if f.versioning && (f.buildID == "" || f.buildID == "dev") {
+		return errors.New("--worker-versioning needs a release Build ID: build with -ldflags \"-X ...go_comparison.Version=<version>\" or set --build-id")
+	}
+	opts.BuildID = f.buildID
+	opts.UseBuildIDForVersioning = f.versioning
+	return nil
+}
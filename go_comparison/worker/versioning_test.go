@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/worker"
+)
+
+func parseVersioningFlags(t *testing.T, args ...string) *versioningFlags {
+	t.Helper()
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	f := registerVersioningFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return f
+}
+
+func TestVersioningFlags(t *testing.T) {
+	var opts worker.Options
+	require.NoError(t, parseVersioningFlags(t).apply(&opts))
+	require.Equal(t, "dev", opts.BuildID, "the build's version")
+	require.False(t, opts.UseBuildIDForVersioning)
+
+	opts = worker.Options{}
+	require.NoError(t, parseVersioningFlags(t, "--build-id", "v2", "--worker-versioning").apply(&opts))
+	require.Equal(t, "v2", opts.BuildID)
+	require.True(t, opts.UseBuildIDForVersioning)
+
+	err := parseVersioningFlags(t, "--worker-versioning").apply(&worker.Options{})
+	require.ErrorContains(t, err, "needs a release Build ID")
+	err = parseVersioningFlags(t, "--worker-versioning", "--build-id", "").apply(&worker.Options{})
+	require.Error(t, err)
+}
@@ -27,13 +27,24 @@ package scanner
 // =============================================================================
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
+// skippedSampleSize caps the inactive repo names listed in the report.
+const skippedSampleSize = 20
+
+// ScanStatusKey is the search attribute the progress loop keeps set to
+// ScanProgress.Status, so running scans can be listed by phase.
+var ScanStatusKey = temporal.NewSearchAttributeKeyKeyword("ScanStatus")
+
 // SecurityScanWorkflow is the main workflow function.
 //
 // STRUCTURAL DIFFERENCE #1: Workflow shape.
@@ -64,13 +75,36 @@ func SecurityScanWorkflow(ctx workflow.Context, input ScanInput) (map[string]int
 
 	// ─── State (Python: self._progress, self._results) ───
 	// Go uses local variables; Python uses instance attributes.
+	//
+	// workflow.Now (not time.Now) keeps timestamps identical across replays.
+	info := workflow.GetInfo(ctx)
+	startedAt := workflow.Now(ctx)
+
+	// Activities and batch children started from ctx count their API
+	// requests against this run (see apiusage.go).
+	ctx = withAPIScope(ctx, apiScope{
+		WorkflowID:  info.WorkflowExecution.ID,
+		RunID:       info.WorkflowExecution.RunID,
+		MaxRequests: input.MaxAPIRequests,
+	})
 	progress := ScanProgress{
-		Org:    input.Org,
-		Status: "starting",
+		Org:        input.Org,
+		Status:     ScanStarting,
+		WorkflowID: info.WorkflowExecution.ID,
+		RunID:      info.WorkflowExecution.RunID,
+		StartedAt:  startedAt,
+		UpdatedAt:  startedAt,
 	}
 	var results []RepoSecurityResult
+	var resultRefs []BlobRef // claim checks for results offloaded to blob storage
+	resultsBytes := 0        // serialized size of results still held inline
+	errorsByCategory := make(map[ErrorCategory]int)
+	var retryLater []string // errored repos whose failure was not the repo's
+	var cancelledInFlight []string
 	cancelRequested := false
 	cancelReason := ""
+	// Counted through the worker's metrics handler (see workflowmetrics.go).
+	metrics := newScanMetrics(ctx, input.Org, startedAt)
 
 	// ─── Signal Handler ───
 	//
@@ -90,12 +124,23 @@ func SecurityScanWorkflow(ctx workflow.Context, input ScanInput) (map[string]int
 
 	// Drain cancel signals asynchronously so they don't block the main flow.
 	// This goroutine sets the flag; the batch loop checks it.
+	var currentChild workflow.ChildWorkflowFuture // running ScanBatchWorkflow, if any
 	workflow.Go(ctx, func(gCtx workflow.Context) {
 		var reason string
 		cancelCh.Receive(gCtx, &reason)
 		cancelRequested = true
 		cancelReason = reason
+		metrics.cancelReceived()
 		logger.Info("Cancellation requested", "reason", reason)
+		// Forward to the running batch so it stops between groups too.
+		cancelBatchChild(gCtx, currentChild, reason)
+	})
+
+	// pause_scan and resume_scan hold the batch loop between batches (see
+	// pause.go).
+	paused := false
+	watchPause(ctx, logger, func(p bool) {
+		paused, progress.PauseRequested, progress.UpdatedAt = p, p, workflow.Now(ctx)
 	})
 
 	// ─── Query Handlers ───
@@ -109,6 +154,8 @@ func SecurityScanWorkflow(ctx workflow.Context, input ScanInput) (map[string]int
 	//
 	// Go: Imperative via workflow.SetQueryHandler inside the function.
 	// The query reads from closure variables (progress, results).
+	// Once results are offloaded (see Step 2b), results_so_far only returns
+	// the ones still held inline; progress always has the full counts.
 	//
 	// Python's approach is cleaner for simple cases.
 	// Go's approach is more flexible (you can register/unregister dynamically).
@@ -119,13 +166,21 @@ func SecurityScanWorkflow(ctx workflow.Context, input ScanInput) (map[string]int
 		return nil, fmt.Errorf("registering progress query: %w", err)
 	}
 
-	err = workflow.SetQueryHandler(ctx, "results_so_far", func() ([]RepoSecurityResult, error) {
+	err = workflow.SetQueryHandler(ctx, ResultsSoFarQuery, func() ([]RepoSecurityResult, error) {
 		return results, nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("registering results query: %w", err)
 	}
 
+	// results_page is results_so_far a page at a time (see results.go).
+	err = workflow.SetQueryHandler(ctx, ResultsPageQuery, func(offset, limit int) (ResultsPage, error) {
+		return PageResults(results, offset, limit), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("registering results page query: %w", err)
+	}
+
 	err = workflow.SetQueryHandler(ctx, "is_cancelled", func() (bool, error) {
 		return cancelRequested, nil
 	})
@@ -133,6 +188,12 @@ func SecurityScanWorkflow(ctx workflow.Context, input ScanInput) (map[string]int
 		return nil, fmt.Errorf("registering is_cancelled query: %w", err)
 	}
 
+	// Callers that find this scan already running attach with the
+	// update_scan_config update (see attach.go).
+	if err := setScanConfigUpdate(ctx, input, func() ScanProgress { return progress }); err != nil {
+		return nil, err
+	}
+
 	// ─── Activity Options ───
 	//
 	// DIFFERENCE #3: How activity options are applied.
@@ -161,47 +222,296 @@ func SecurityScanWorkflow(ctx workflow.Context, input ScanInput) (map[string]int
 	//         non_retryable_error_types=["ValueError"],
 	//     )
 	//
-	// Go (below): Same fields, different syntax.
+	// Go (FetchRetryPolicy and the others in retrypolicy.go): Same fields,
+	// different syntax.
 	// Note: Go uses NonRetryableErrorTypes matching on error *type names*,
 	// while Python matches on exception class names. Same concept.
-	retryPolicy := &temporal.RetryPolicy{
-		InitialInterval:    2 * time.Second,
-		BackoffCoefficient: 2.0,
-		MaximumInterval:    60 * time.Second,
-		MaximumAttempts:    5,
-	}
+	//
+	// Timeouts are per kind of activity too (see timeouts.go).
+	reportRetryPolicy := input.RetryPolicies.reportRetryPolicy()
+	reportTimeouts := input.Timeouts.report()
 
 	// Context with activity options (reusable across multiple activity calls)
-	fetchCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-		StartToCloseTimeout: 120 * time.Second,
-		HeartbeatTimeout:    30 * time.Second,
-		RetryPolicy:         retryPolicy,
-	})
+	fetchCtx := workflow.WithActivityOptions(ctx, input.Timeouts.fetch().options(input.RetryPolicies.fetchRetryPolicy()))
 
-	scanCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-		StartToCloseTimeout: 60 * time.Second,
-		RetryPolicy:         retryPolicy,
-	})
+	scanCtx := workflow.WithActivityOptions(ctx, scanActivityOptions(input.Timeouts.scan(), input.RetryPolicies.scanRetryPolicy()))
 
-	reportCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-		StartToCloseTimeout: 30 * time.Second,
-		RetryPolicy:         retryPolicy,
-	})
+	reportCtx := workflow.WithActivityOptions(ctx, reportTimeouts.options(reportRetryPolicy))
+
+	// ─── Org defaults ───
+	//
+	// The worker's org config registry fills in what the input leaves
+	// empty (see orgconfig.go), before the input is validated. The token
+	// and credential stay out of the local activity's result, which also
+	// says whether the worker keeps an audit log.
+	var scanConfig *ScanConfig
+	auditLog := false
+	if changeVersion(ctx, changeOrgConfig) >= 1 {
+		localCtx := workflow.WithLocalActivityOptions(ctx, workflow.LocalActivityOptions{
+			StartToCloseTimeout: 10 * time.Second,
+		})
+		var resolved ResolvedScanInput
+		if err := workflow.ExecuteLocalActivity(localCtx, "ResolveScanConfig", input).Get(ctx, &resolved); err != nil {
+			return nil, fmt.Errorf("resolving org config: %w", err)
+		}
+		resolved.Input.Token, resolved.Input.Credential = input.Token, input.Credential
+		input, scanConfig, auditLog = resolved.Input, &resolved.Config, resolved.AuditLog
+	}
+
+	// ─── Input validation ───
+	//
+	// A typo in the org or a check name should fail fast, not after
+	// retrying FetchOrgRepos or fetching every repo. Every problem is
+	// reported at once, by field (see validate.go).
+	if err := input.validation(changeVersion(ctx, changeInputRules) >= 1).err(); err != nil {
+		return nil, invalidInputError(err)
+	}
+	checks := input.checks()
+	checkNames := checks.names()
+	cred := input.credential()
+	provider := providerName(input.Provider)
+
+	// The default policy follows the selected checks.
+	compliance := DefaultCompliancePolicy().forChecks(checks)
+	if input.CompliancePolicy != nil {
+		compliance = *input.CompliancePolicy
+	}
+	input.InitiatedBy, input.Reason = CleanScanLabel(input.InitiatedBy), CleanScanLabel(input.Reason)
+	// Who started the scan and why go in its memo (see initiator.go),
+	// since version 2 with the rest of its parameters (see memo.go).
+	if v := changeVersion(ctx, changeScanInitiator); v >= 1 {
+		input.InitiatedBy = scanInitiator(ctx, input.InitiatedBy)
+		if v >= 2 {
+			tagScanMemo(ctx, input)
+		} else {
+			tagInitiator(ctx, input)
+		}
+	}
+
+	// The run is recorded in the worker's audit log once its input has
+	// validated, and again on each return below, through finish (see
+	// audit.go). Since version 2, only when the worker keeps an audit log.
+	audited := false
+	if v := changeVersion(ctx, changeAuditLog); v >= 1 && (v < 2 || auditLog) {
+		if err := auditScanStarted(ctx, input, &progress); err != nil {
+			return nil, err
+		}
+		audited = true
+	}
+	// finish returns the run's result, recording its end first.
+	finish := func(result map[string]interface{}, err error) (map[string]interface{}, error) {
+		if audited {
+			auditScanFinished(ctx, input, &progress, result, err)
+		}
+		return result, err
+	}
+
+	// Suppressions are split once against the workflow's start time so
+	// replays agree on which ones expired.
+	if err := ValidateSuppressions(input.Suppressions); err != nil {
+		return finish(nil, temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeInvalidInput, nil))
+	}
+	suppressions := input.Suppressions
+	if input.SuppressionsSource != "" {
+		var loaded []Suppression
+		err = workflow.ExecuteActivity(fetchCtx, "LoadSuppressions", input.SuppressionsSource).Get(ctx, &loaded)
+		if err != nil {
+			return finish(nil, fmt.Errorf("loading suppressions: %w", err))
+		}
+		suppressions = append(append([]Suppression(nil), suppressions...), loaded...)
+	}
+	activeSuppressions, expiredSuppressions := splitExpired(suppressions, progress.StartedAt)
+	if len(expiredSuppressions) > 0 {
+		logger.Warn("Ignoring expired suppressions", "count", len(expiredSuppressions))
+	}
+
+	// ─── Step 0: Token capabilities ───
+	//
+	// Checks the token cannot evaluate on any repo are reported as no access
+	// up front instead of costing a doomed request per repo. Scans without a
+	// token or credential, runs started before this step, and providers
+	// other than GitHub skip it.
+	var capabilities *TokenCapabilities
+	if cred != nil && cred.kind() != CredentialNone && provider == ProviderGitHub && changeVersion(ctx, changeTokenCapabilities) >= 1 {
+		err = workflow.ExecuteActivity(fetchCtx, "ValidateToken", input.Org, cred, checkNames).Get(ctx, &capabilities)
+		var appErr *temporal.ApplicationError
+		switch {
+		case errors.As(err, &appErr) && appErr.NonRetryable():
+			return finish(nil, fmt.Errorf("validating token: %w", err))
+		case err != nil:
+			logger.Warn("Token check failed; running every check", "error", err)
+		}
+	}
+	noAccess := capabilities.Unavailable()
+	if len(noAccess) > 0 {
+		if len(noAccess) == len(checkNames) {
+			return finish(nil, temporal.NewNonRetryableApplicationError(
+				fmt.Sprintf("the token cannot evaluate any selected check (%s)", strings.Join(noAccess, ", ")),
+				ErrTypeInvalidInput, nil))
+		}
+		logger.Warn("Token cannot evaluate some checks; reporting them as no access", "checks", noAccess)
+	}
+	if warning := capabilities.RepoAccessWarning(); warning != "" {
+		logger.Warn("Token cannot read many of the org's repos", "warning", warning)
+	}
+
+	if len(input.Teams) > 0 {
+		if ok, reason := capabilities.CanListTeams(); !ok {
+			return finish(nil, temporal.NewNonRetryableApplicationError(reason, ErrTypeInvalidInput, nil))
+		}
+	}
 
 	// ─── Step 1: Fetch repositories ───
-	logger.Info("Starting security scan", "org", input.Org)
+	logger.Info("Starting security scan", "org", input.Org, "provider", provider, "checks", checkNames)
+	progress.Status, progress.UpdatedAt = ScanFetchingRepos, workflow.Now(ctx)
 
 	var repos []RepoInfo
-	// In Go, ExecuteActivity returns a Future. .Get() blocks until complete.
-	// In Python, execute_activity is awaited directly.
-	err = workflow.ExecuteActivity(fetchCtx, "FetchOrgRepos", input).Get(ctx, &repos)
-	if err != nil {
-		return nil, fmt.Errorf("fetching repos: %w", err)
+	// scanOrg is the org the repos are checked under: input.Org, or the
+	// login it was renamed to.
+	scanOrg, renamedTo := input.Org, ""
+	switch {
+	case len(input.Repos) > 0:
+		repos = input.explicitRepos()
+	case len(input.Teams) > 0:
+		err = workflow.ExecuteActivity(fetchCtx, "FetchTeamRepos", input).Get(ctx, &repos)
+		if err != nil {
+			return finish(nil, fmt.Errorf("fetching team repos: %w", err))
+		}
+	default:
+		// In Go, ExecuteActivity returns a Future. .Get() blocks until complete.
+		// In Python, execute_activity is awaited directly.
+		// A GitHub org is listed a page per activity (see repopages.go).
+		if provider == ProviderGitHub && changeVersion(ctx, changeRepoPages) >= 1 {
+			repos, err = listOrgRepoPages(fetchCtx, input, &progress)
+		} else {
+			err = workflow.ExecuteActivity(fetchCtx, "FetchOrgRepos", input).Get(ctx, &repos)
+		}
+		if err != nil {
+			return finish(nil, fmt.Errorf("fetching repos: %w", err))
+		}
+		// A renamed GitHub org is scanned under its new login (see
+		// orgrename.go).
+		if provider == ProviderGitHub {
+			if renamedTo = orgRenamedTo(input.Org, repos); renamedTo != "" {
+				logger.Warn("Organization was renamed; scanning under its new login", "org", input.Org, "renamed_to", renamedTo)
+				scanOrg = renamedTo
+			}
+		}
+	}
+
+	repos, duplicateRepos := normalizeRepos(repos)
+	if len(duplicateRepos) > 0 {
+		logger.Warn("Dropping duplicate repos", "count", len(duplicateRepos), "repos", duplicateRepos)
+	}
+
+	// A resumed scan picks up the repos a stopped scan did not get to.
+	// That scan's results are this one's too: held inline, they are
+	// returned by results_so_far and offloaded with the rest.
+	var priorRefs []BlobRef
+	if input.ResumeFrom != nil {
+		var gone []string
+		repos, gone = input.ResumeFrom.filter(repos)
+		if len(gone) > 0 {
+			logger.Warn("Repos to resume are no longer listed", "count", len(gone), "repos", gone)
+		}
+		results = append(results, input.ResumeFrom.Results...)
+		for _, r := range input.ResumeFrom.Results {
+			if b, err := json.Marshal(r); err == nil {
+				resultsBytes += len(b)
+			}
+		}
+		priorRefs = input.ResumeFrom.Refs
+	}
+
+	// Archived repos cannot have their settings changed, so they are
+	// listed in the report rather than counted against compliance, unless
+	// the scan asks for them. Older runs scanned them like any other.
+	var archivedRepos []string
+	archived := map[string]bool{}
+	if changeVersion(ctx, changeSkipArchived) >= 1 {
+		kept := repos[:0]
+		for _, r := range repos {
+			switch {
+			case !r.Archived:
+				kept = append(kept, r)
+			case input.IncludeArchived:
+				archived[r.Name] = true
+				kept = append(kept, r)
+			default:
+				archivedRepos = append(archivedRepos, r.Name)
+			}
+		}
+		repos = kept
+		if len(archivedRepos) > 0 {
+			sort.Strings(archivedRepos)
+			logger.Info("Skipping archived repos", "skipped", len(archivedRepos))
+		}
+	}
+
+	// The topic filters read the listing, so they filter the same way on
+	// replay (see topics.go).
+	repos, skippedByTopic, matchedTopics := input.filterTopics(repos)
+	if len(skippedByTopic) > 0 {
+		logger.Info("Skipping repos by topic", "skipped", len(skippedByTopic),
+			"include_topics", input.IncludeTopics, "exclude_topics", input.ExcludeTopics)
+	}
+
+	// Dormant repos are skipped rather than reported as non-compliant
+	// forever. The cutoff is relative to the workflow's start, so replays
+	// filter identically.
+	var skippedInactive []string
+	if input.ActiveWithinDays > 0 {
+		cutoff := progress.StartedAt.AddDate(0, 0, -input.ActiveWithinDays)
+		active := repos[:0]
+		for _, r := range repos {
+			if r.ActiveSince(cutoff) {
+				active = append(active, r)
+			} else {
+				skippedInactive = append(skippedInactive, r.Name)
+			}
+		}
+		repos = active
+		logger.Info("Skipping inactive repos", "skipped", len(skippedInactive),
+			"active_within_days", input.ActiveWithinDays)
+	}
+
+	// Priority repos go first, so a scan cut short has covered them.
+	repos, priority := input.prioritize(repos)
+	priorityScanned := 0
+	if len(priority) > 0 {
+		logger.Info("Scanning priority repos first", "priority", len(priority))
 	}
 
 	progress.TotalRepos = len(repos)
-	progress.Status = "scanning"
-	logger.Info("Found repos, beginning scan", "count", len(repos))
+	progress.Status = ScanScanning
+	progress.UpdatedAt = workflow.Now(ctx)
+	estimatedCalls := len(repos) * input.APICallsPerRepo()
+	logger.Info("Found repos, beginning scan", "count", len(repos), "estimated_api_calls", estimatedCalls)
+
+	// Opt-in, so runs without it record no timers and replay unchanged.
+	// Stopped before the report so nothing fires after scanning ends.
+	stopProgress := func() {}
+	if input.ProgressIntervalSeconds > 0 {
+		stopProgress = startProgressLoop(ctx, time.Duration(input.ProgressIntervalSeconds)*time.Second, &progress, input.InitiatedBy)
+	}
+	defer stopProgress()
+	publisher := newProgressPublisher(input.ProgressWebhook)
+
+	// Opt-in too. When the in-flight batch's grace runs out, a batch child
+	// is stopped like a cancelled one.
+	deadline := &deadlineTimer{}
+	stopDeadline := func() {}
+	if d, ok := input.deadline(progress.StartedAt); ok {
+		progress.Deadline = &d
+		deadline, stopDeadline = startDeadline(ctx, d, func(gCtx workflow.Context) {
+			cancelBatchChild(gCtx, currentChild, "deadline reached")
+		})
+	}
+	defer stopDeadline()
+	// unscanned are the repos cancellation, the deadline or the API budget
+	// stopped the scan before.
+	var unscanned []string
+	stoppedAtDeadline := false
 
 	// ─── Step 2: Scan in parallel batches ───
 	//
@@ -224,97 +534,680 @@ func SecurityScanWorkflow(ctx workflow.Context, input ScanInput) (map[string]int
 	// Python developers will find asyncio.gather() more natural.
 	//
 	// BOTH achieve the same outcome: 10 activities running concurrently per batch.
-	batchSize := 10
+	//
+	// With ChildPerBatch, each batch of childBatchSize repos is scanned by a
+	// ScanBatchWorkflow child instead, so the parent's history only records
+	// one child per batch rather than every activity.
+	//
+	// With ActivityBatching, CheckRepoSecurityBatch checks activityBatchSize
+	// repos per activity, so the parent takes that many at a time.
+	//
+	// With Concurrency, scanWindow keeps that many repos in flight instead
+	// of batching them (see window.go).
+	batchSize := scanBatchSize
+	switch {
+	case input.ChildPerBatch:
+		batchSize = childBatchSize
+	case input.ActivityBatching:
+		batchSize = activityBatchSize
+	}
+
+	offloadLimit := input.ResultsOffloadBytes
+	if offloadLimit == 0 {
+		offloadLimit = DefaultResultsOffloadBytes
+	}
+	offloadVersion := workflow.DefaultVersion
+
+	// Runs started before CheckActionsSecurity existed replay without it.
+	actionsVersion := changeVersion(ctx, changeActionsSecurity)
+
+	// The listing's metadata goes into each repo's result. It makes the
+	// results larger, and so offloads them sooner, so older runs replay
+	// without it.
+	var metadata map[string]*RepoMetadata
+	if changeVersion(ctx, changeRepoMetadata) >= 1 {
+		metadata = make(map[string]*RepoMetadata, len(repos))
+		for _, r := range repos {
+			if !r.RepoMetadata.isZero() {
+				m := r.RepoMetadata
+				metadata[r.Name] = &m
+			}
+		}
+	}
+
+	// Set once a request is refused for ScanInput.MaxAPIRequests; the scan
+	// then stops like a cancelled one.
+	budgetExceeded := false
+	// timedOutInBatch are the stragglers released from their batches, for
+	// the second pass (see straggler.go).
+	var timedOutInBatch []string
+	// disappeared are the repos gone since the listing (see disappeared.go).
+	var disappeared []DisappearedRepo
+
+	// checked are the repos with a result of any kind.
+	checked := make(map[string]bool, len(repos))
+	record := func(result *RepoSecurityResult) {
+		checked[result.Repository] = true
+		if m := metadata[result.Repository]; m != nil {
+			result.Metadata = m
+		}
+		result.Archived = archived[result.Repository]
+		result.MatchedTopics = matchedTopics[result.Repository]
+		switch {
+		case result.Disappeared != nil:
+			// Gone since the listing: neither scanned nor an error.
+			disappeared = append(disappeared, *result.Disappeared)
+			progress.DisappearedRepos++
+		case result.Error != nil:
+			progress.Errors++
+			category := result.FailureCategory()
+			errorsByCategory[category]++
+			metrics.repoErrored(category)
+			if category.Retryable() {
+				retryLater = append(retryLater, result.Repository)
+			}
+		default:
+			// The policy's webhook allowlist is applied here, where the
+			// policy is known, so the result queries and pages return
+			// carries the domain issues too.
+			compliance.applyWebhookAllowlist(result)
+			results = append(results, *result)
+			if b, err := json.Marshal(result); err == nil {
+				resultsBytes += len(b)
+			}
+			progress.ScannedRepos++
+			metrics.repoScanned()
+			if priority[result.Repository] {
+				priorityScanned++
+			}
+			failed, _ := compliance.evaluate(result, activeSuppressions)
+			switch compliance.outcome(result, failed) {
+			case OutcomeCompliant:
+				progress.CompliantRepos++
+			case OutcomeIndeterminate:
+				progress.IndeterminateRepos++
+			default:
+				progress.NonCompliantRepos++
+			}
+		}
+		progress.UpdatedAt = workflow.Now(ctx)
+		publisher.repoChecked(ctx, progress)
+	}
+
+	batchTemplate := ScanBatchInput{
+		Org:                 scanOrg,
+		Credential:          cred,
+		Checks:              checkNames,
+		DeployKeyMaxAgeDays: input.DeployKeyMaxAgeDays,
+		Provider:            input.Provider,
+		NoAccess:            noAccess,
+		BatchDelay:          input.BatchDelay,
+		ActivityBatching:    input.ActivityBatching,
+	}
+	if input.RetryPolicies != nil {
+		batchTemplate.ScanRetry = input.RetryPolicies.Scan
+	}
+	if input.Timeouts != nil {
+		batchTemplate.ScanTimeouts = input.Timeouts.Scan
+	}
+	batchTemplate.Stragglers = input.StragglerTimeout
+	batchTemplate.IncludeEvidence = input.IncludeEvidence
 
-	for batchStart := 0; batchStart < len(repos); batchStart += batchSize {
+	// Repos cut short in flight are unscanned, and cancelled ones
+	// unless it was the deadline that cut them short.
+	cutShort := func(inFlight []string) {
+		if len(inFlight) == 0 {
+			return
+		}
+		unscanned = append(unscanned, inFlight...)
+		if cancelRequested {
+			cancelledInFlight = append(cancelledInFlight, inFlight...)
+			progress.CancelledInFlight = len(cancelledInFlight)
+		} else {
+			stoppedAtDeadline = true
+		}
+	}
+
+	// stopBefore stops the scan before rest if it was cancelled, ran out of
+	// API budget, or reached its deadline.
+	stopBefore := func(rest []string) bool {
 		// Check cancellation between batches — same pattern as Python.
 		// Python: if self._cancel_requested: break
 		// Go: just check the flag set by the signal goroutine.
-		if cancelRequested {
+		switch {
+		case cancelRequested:
 			logger.Info("Scan cancelled", "reason", cancelReason,
 				"scanned", progress.ScannedRepos)
-			progress.Status = "cancelled"
-			break
+			progress.Status = ScanCancelled
+			unscanned = append(unscanned, rest...)
+		case budgetExceeded:
+			logger.Warn("API budget spent; stopping the scan",
+				"max_api_requests", input.MaxAPIRequests, "scanned", progress.ScannedRepos)
+			progress.Status = ScanBudgetExceeded
+		case deadline.reached:
+			logger.Info("Deadline reached; stopping the scan",
+				"deadline", progress.Deadline, "scanned", progress.ScannedRepos)
+			progress.Status = ScanDeadlineReached
+			stoppedAtDeadline = true
+			unscanned = append(unscanned, rest...)
+		default:
+			return false
+		}
+		return true
+	}
+
+	// ─── Step 2b: Claim-check large result sets ───
+	//
+	// Results travel to GenerateReport as one payload. Past the offload
+	// threshold, hand the accumulated chunk to StoreResults and keep only
+	// the BlobRef, so no payload ever approaches the 2 MiB limit. Batches
+	// offload after each batch, a window after each result.
+	offload := func() error {
+		if offloadLimit <= 0 || resultsBytes <= offloadLimit {
+			return nil
+		}
+		if offloadVersion == workflow.DefaultVersion {
+			offloadVersion = changeVersion(ctx, changeResultsOffload)
+		}
+		if offloadVersion >= 1 {
+			var ref BlobRef
+			err := workflow.ExecuteActivity(reportCtx, "StoreResults", len(resultRefs), results).Get(ctx, &ref)
+			if err != nil {
+				return fmt.Errorf("offloading results: %w", err)
+			}
+			if ref.URI == "" {
+				// The worker has no blob store: keep the results inline
+				// and stop asking.
+				offloadLimit = -1
+				return nil
+			}
+			resultRefs = append(resultRefs, ref)
+			results = nil
+			resultsBytes = 0
+		}
+		return nil
+	}
+
+	// With WorkerAffinity the checks run in a session on one worker (see
+	// session.go); scanIn runs them there, or on scanCtx.
+	var session *scanSession
+	if input.WorkerAffinity {
+		session = openScanSession(ctx, scanCtx)
+		defer session.close()
+	}
+	scanIn := func(repos []string, onResult func(*RepoSecurityResult) error, run func(workflow.Context, []string, func(*RepoSecurityResult) error) error) error {
+		if session == nil {
+			return run(scanCtx, repos, onResult)
 		}
+		return session.scan(ctx, repos, onResult, run)
+	}
+	// recordAll is record for scanIn; recording never fails.
+	recordAll := func(result *RepoSecurityResult) error {
+		record(result)
+		return nil
+	}
 
-		batchEnd := batchStart + batchSize
-		if batchEnd > len(repos) {
-			batchEnd = len(repos)
+	repoNames := make([]string, len(repos))
+	for i, r := range repos {
+		repoNames[i] = r.Name
+	}
+	stopped := false
+	if input.Concurrency > 0 {
+		completed := 0
+		err := scanIn(repoNames, func(result *RepoSecurityResult) error {
+			record(result)
+			if completed++; completed%input.Concurrency == 0 {
+				publisher.batchDone(ctx, progress)
+				metrics.batchDone(ctx, progress)
+			}
+			return offload()
+		}, func(windowCtx workflow.Context, names []string, onResult func(*RepoSecurityResult) error) error {
+			window := batchTemplate
+			window.Repos = names
+			exceeded, inFlight, unstarted, err := scanWindow(ctx, windowCtx, window, input.Concurrency, actionsVersion,
+				func() bool { return cancelRequested || deadline.reached },
+				func() bool { return cancelRequested || deadline.expired },
+				onResult)
+			if err != nil {
+				return err
+			}
+			budgetExceeded = budgetExceeded || exceeded
+			cutShort(inFlight)
+			if len(unstarted) > 0 {
+				stopped = stopBefore(unstarted)
+			}
+			return nil
+		})
+		if err != nil {
+			return finish(nil, err)
 		}
-		batch := repos[batchStart:batchEnd]
+	} else {
+		progress.Batches = (len(repos) + batchSize - 1) / batchSize
+		for batchIndex, batchStart := 0, 0; batchStart < len(repos); batchIndex, batchStart = batchIndex+1, batchStart+batchSize {
+			// Spread batches out when asked. The pause is a timer, not a
+			// sleep, and cancellation cuts it short.
+			if batchIndex > 0 && input.BatchDelay != nil && !budgetExceeded {
+				wait := input.BatchDelay.next(ctx)
+				next := workflow.Now(ctx).Add(wait)
+				progress.Status, progress.NextBatchAt, progress.UpdatedAt = ScanPaused, &next, workflow.Now(ctx)
+				if err := pauseBetweenBatches(ctx, wait, func() bool { return cancelRequested || deadline.reached }); err != nil {
+					return finish(nil, err)
+				}
+				progress.Status, progress.NextBatchAt, progress.UpdatedAt = ScanScanning, nil, workflow.Now(ctx)
+			}
+			if paused {
+				if err := holdWhilePaused(ctx, &progress, func() bool { return !paused || cancelRequested || deadline.reached }); err != nil {
+					return finish(nil, err)
+				}
+			}
 
-		// Create a channel to collect results from concurrent activities
-		resultCh := workflow.NewChannel(ctx)
+			if stopped = stopBefore(repoNames[batchStart:]); stopped {
+				break
+			}
+			progress.Batch = batchIndex + 1
 
-		// Launch concurrent activities using workflow.Go (NOT native goroutines)
-		for _, repo := range batch {
-			// Capture loop variable (same reason as Python's closure gotcha)
-			repoName := repo.Name
-			workflow.Go(ctx, func(gCtx workflow.Context) {
-				var result RepoSecurityResult
-				err := workflow.ExecuteActivity(scanCtx, "CheckRepoSecurity",
-					input.Org, repoName, input.Token,
-				).Get(gCtx, &result)
+			batchEnd := batchStart + batchSize
+			if batchEnd > len(repos) {
+				batchEnd = len(repos)
+			}
+			batchInput := batchTemplate
+			batchInput.Repos = repoNames[batchStart:batchEnd]
 
+			if input.ChildPerBatch {
+				// The child's results arrive together when it completes, so
+				// progress advances a whole child batch at a time.
+				batchResult, err := runBatchChild(ctx, batchInput, batchIndex, &currentChild)
 				if err != nil {
-					// Send error result
-					errMsg := err.Error()
-					resultCh.Send(gCtx, &RepoSecurityResult{
-						Repository: repoName,
-						Error:      &errMsg,
-					})
-				} else {
-					resultCh.Send(gCtx, &result)
+					return finish(nil, err)
 				}
-			})
-		}
+				for i := range batchResult.Results {
+					record(&batchResult.Results[i])
+				}
+				budgetExceeded = batchResult.BudgetExceeded
+				cutShort(batchResult.CancelledInFlight)
+				timedOutInBatch = append(timedOutInBatch, batchResult.TimedOutInBatch...)
+			} else {
+				_ = scanIn(batchInput.Repos, recordAll, func(batchCtx workflow.Context, names []string, onResult func(*RepoSecurityResult) error) error {
+					batch := batchInput
+					batch.Repos = names
+					exceeded, inFlight, stragglers := scanBatch(ctx, batchCtx, batch, actionsVersion, func() bool { return cancelRequested || deadline.expired },
+						func(result *RepoSecurityResult) { _ = onResult(result) })
+					budgetExceeded = budgetExceeded || exceeded
+					cutShort(inFlight)
+					timedOutInBatch = append(timedOutInBatch, stragglers...)
+					return nil
+				})
+			}
+			progress.TimedOutInBatch = len(timedOutInBatch)
+			publisher.batchDone(ctx, progress)
+			metrics.batchDone(ctx, progress)
 
-		// Collect all results from this batch
-		for i := 0; i < len(batch); i++ {
-			var result *RepoSecurityResult
-			resultCh.Receive(ctx, &result)
+			if err := offload(); err != nil {
+				return finish(nil, err)
+			}
+		}
+	}
 
-			if result.Error != nil {
-				progress.Errors++
-			} else {
-				results = append(results, *result)
-				progress.ScannedRepos++
-				if result.IsFullyCompliant() {
-					progress.CompliantRepos++
-				} else {
-					progress.NonCompliantRepos++
-				}
+	// ─── Step 2c: Rescan the stragglers ───
+	//
+	// The repos released from their batches get a second pass once every
+	// batch has run, scanBatchSize at a time and with no soft deadline. A
+	// scan stopped before or during it leaves them unscanned.
+	if len(timedOutInBatch) > 0 && !stopped {
+		logger.Info("Rescanning stragglers", "count", len(timedOutInBatch))
+		progress.Status, progress.UpdatedAt = ScanRetryingFailures, workflow.Now(ctx)
+		retry := batchTemplate
+		retry.Stragglers = nil
+		for start := 0; start < len(timedOutInBatch); start += scanBatchSize {
+			if stopBefore(timedOutInBatch[start:]) {
+				break
+			}
+			end := start + scanBatchSize
+			if end > len(timedOutInBatch) {
+				end = len(timedOutInBatch)
+			}
+			_ = scanIn(timedOutInBatch[start:end], recordAll, func(batchCtx workflow.Context, names []string, onResult func(*RepoSecurityResult) error) error {
+				retry.Repos = names
+				exceeded, inFlight, _ := scanBatch(ctx, batchCtx, retry, actionsVersion, func() bool { return cancelRequested || deadline.expired },
+					func(result *RepoSecurityResult) { _ = onResult(result) })
+				budgetExceeded = budgetExceeded || exceeded
+				cutShort(inFlight)
+				return nil
+			})
+			publisher.batchDone(ctx, progress)
+			metrics.batchDone(ctx, progress)
+		}
+	} else if len(timedOutInBatch) > 0 && (progress.Status == ScanCancelled || progress.Status == ScanDeadlineReached) {
+		unscanned = append(unscanned, timedOutInBatch...)
+	}
+	// The API budget stops a scan between batches, but cuts short the
+	// repos it refused in flight, or that a batch activity skipped; with
+	// the batches it never started they are unscanned, as for a deadline.
+	if budgetExceeded {
+		listed := make(map[string]bool, len(unscanned))
+		for _, name := range unscanned {
+			listed[name] = true
+		}
+		for _, name := range repoNames {
+			if !checked[name] && !listed[name] {
+				unscanned = append(unscanned, name)
 			}
 		}
 	}
 
+	stopProgress()
+	stopDeadline()
+	if session != nil {
+		session.close()
+	}
+
 	// ─── Step 3: Generate report ───
 	// Generate a report even on cancellation — partial data is still valuable.
-	if progress.Status != "cancelled" {
-		progress.Status = "completed"
+	switch {
+	case progress.Status == ScanCancelled, progress.Status == ScanBudgetExceeded, progress.Status == ScanDeadlineReached:
+	case stoppedAtDeadline:
+		// The deadline cut the last batch short.
+		progress.Status = ScanDeadlineReached
+	case progress.TotalRepos == 0:
+		progress.Status = ScanEmpty
+	default:
+		progress.Status = ScanCompleted
 	}
+	progress.CompletedAt = workflow.Now(ctx)
+	progress.UpdatedAt = progress.CompletedAt
+	publisher.publish(ctx, progress)
 	logger.Info("Scan complete",
 		"scanned", progress.ScannedRepos,
 		"total", progress.TotalRepos,
 		"cancelled", cancelRequested,
 	)
 
+	reportInput := ReportInput{
+		Org:                  input.Org,
+		Provider:             provider,
+		Results:              results,
+		Refs:                 append(append([]BlobRef(nil), priorRefs...), resultRefs...),
+		Policy:               compliance,
+		Checks:               checkNames,
+		Suppressions:         activeSuppressions,
+		Errors:               progress.Errors,
+		ErrorsByCategory:     errorsByCategory,
+		RetryLater:           retryLater,
+		EstimatedAPICalls:    estimatedCalls,
+		ExpiredSuppressions:  expiredSuppressions,
+		ActiveWithinDays:     input.ActiveWithinDays,
+		SkippedInactive:      skippedInactive,
+		ArchivedRepos:        archivedRepos,
+		IncludeTopics:        input.IncludeTopics,
+		ExcludeTopics:        input.ExcludeTopics,
+		SkippedByTopic:       skippedByTopic,
+		Deadline:             progress.Deadline,
+		DeadlineReached:      stoppedAtDeadline,
+		UnscannedRepos:       unscanned,
+		TimedOutInBatch:      timedOutInBatch,
+		ResumedFromRunIDs:    resumedFromRunIDs(input.ResumeFrom),
+		OrgRenamedTo:         renamedTo,
+		PriorityRepos:        len(priority),
+		PriorityReposScanned: priorityScanned,
+		RepoList:             len(input.Repos) > 0,
+		Teams:                input.Teams,
+		TeamRepos:            teamRepos(repos),
+		DuplicateRepos:       len(duplicateRepos),
+		WorkflowID:           progress.WorkflowID,
+		RunID:                progress.RunID,
+		StartedAt:            progress.StartedAt,
+		CompletedAt:          progress.CompletedAt,
+		TokenCapabilities:    capabilities,
+		InitiatedBy:          input.InitiatedBy,
+		Reason:               input.Reason,
+		Disappeared:          disappeared,
+	}
+	// The run's API usage so far, as counted by this worker. Runs started
+	// before it was reported replay without the lookup.
+	if changeVersion(ctx, changeAPIUsage) >= 1 {
+		usageCtx := workflow.WithLocalActivityOptions(ctx, workflow.LocalActivityOptions{
+			StartToCloseTimeout: 10 * time.Second,
+		})
+		err = workflow.ExecuteLocalActivity(usageCtx, "GetAPIUsage", progress.WorkflowID, progress.RunID).Get(ctx, &reportInput.APIUsage)
+		if err != nil {
+			logger.Warn("Could not read API usage", "error", err)
+		}
+	}
+	if session != nil {
+		reportInput.WorkerSessions = session.created
+	}
+	if budgetExceeded {
+		// Another worker may have refused the request; say so regardless.
+		if reportInput.APIUsage == nil {
+			reportInput.APIUsage = &APIUsage{ByCategory: map[string]int{}}
+		}
+		reportInput.APIUsage.MaxRequests = input.MaxAPIRequests
+		reportInput.APIUsage.BudgetExceeded = true
+	}
+	if cancelRequested {
+		reportInput.Cancelled = true
+		reportInput.CancelReason = cancelReason
+		reportInput.ReposScannedBeforeCancel = progress.ScannedRepos
+		reportInput.CancelledInFlight = cancelledInFlight
+	}
+
+	// Aggregation is pure in-memory work, so it runs as a local activity:
+	// one marker in history instead of a scheduled/started/completed round
+	// trip. Runs started before that replay the original activity and add
+	// the run metadata here. worker_version is stamped by the report
+	// generator either way, since both run on the worker.
 	var report map[string]interface{}
-	err = workflow.ExecuteActivity(reportCtx, "GenerateReport",
-		input.Org, results,
-	).Get(ctx, &report)
-	if err != nil {
-		return nil, fmt.Errorf("generating report: %w", err)
+	if changeVersion(ctx, changeLocalReport) >= 1 {
+		localCtx := workflow.WithLocalActivityOptions(ctx, workflow.LocalActivityOptions{
+			StartToCloseTimeout:    reportTimeouts.startToClose(),
+			ScheduleToCloseTimeout: reportTimeouts.scheduleToClose(),
+			RetryPolicy:            reportRetryPolicy,
+		})
+		err = workflow.ExecuteLocalActivity(localCtx, "BuildReport", reportInput).Get(ctx, &report)
+		if err != nil {
+			return finish(nil, fmt.Errorf("generating report: %w", err))
+		}
+	} else {
+		err = workflow.ExecuteActivity(reportCtx, "GenerateReport",
+			input.Org, results, resultRefs, compliance, checkNames, activeSuppressions,
+		).Get(ctx, &report)
+		if err != nil {
+			return finish(nil, fmt.Errorf("generating report: %w", err))
+		}
+		reportInput.addRunMetadata(report)
 	}
 
-	// Add cancellation metadata if applicable
-	if cancelRequested {
-		report["cancelled"] = true
-		report["cancel_reason"] = cancelReason
-		report["repos_scanned_before_cancel"] = progress.ScannedRepos
+	// ─── Step 4: Failure policy ───
+	//
+	// A scan where (nearly) every repo errored is broken, not non-compliant.
+	// Returning a typed ApplicationError with the partial report as details
+	// lets callers tell the two apart instead of recording a bogus 0%.
+	policy := DefaultFailurePolicy()
+	if input.FailurePolicy != nil {
+		policy = *input.FailurePolicy
+	}
+	attempted := progress.ScannedRepos + progress.Errors
+	if policy.IsDegraded(attempted, progress.Errors) {
+		progress.Status = ScanDegraded
+		logger.Warn("Scan degraded", "errors", progress.Errors, "attempted", attempted)
+		return finish(nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("scan degraded: %d of %d repos errored", progress.Errors, attempted),
+			ErrTypeScanDegraded,
+			nil,
+			report,
+		))
+	}
+
+	// Steps 5 to 9 add sections to the report. Runs from before
+	// report-sections wrote them into the generated report as they came in,
+	// so each later step was given the sections of the ones before it. Newer
+	// runs leave the generated report as it is, give every step that, and
+	// collect the sections in final.
+	final := report
+	if changeVersion(ctx, changeReportSections) >= 1 {
+		final = make(map[string]interface{}, len(report)+3)
+		for k, v := range report {
+			final[k] = v
+		}
+	}
+	if scanConfig != nil {
+		final["scan_config"] = scanConfig
+	}
+
+	// ─── Step 4b: Remediation plan ───
+	//
+	// Which org-level changes would fix the most non-compliant repos (see
+	// remediationplan.go). Like the report, it is computed on the worker
+	// from the results, so it runs as a local activity. A plan that cannot
+	// be computed leaves the report without one.
+	if changeVersion(ctx, changeRemediationPlan) >= 1 {
+		localCtx := workflow.WithLocalActivityOptions(ctx, workflow.LocalActivityOptions{
+			StartToCloseTimeout:    reportTimeouts.startToClose(),
+			ScheduleToCloseTimeout: reportTimeouts.scheduleToClose(),
+			RetryPolicy:            reportRetryPolicy,
+		})
+		var plan *RemediationPlan
+		if err := workflow.ExecuteLocalActivity(localCtx, "AnalyzeRemediation", reportInput).Get(ctx, &plan); err != nil {
+			logger.Warn("Remediation plan failed", "error", err)
+		} else if plan != nil {
+			final["remediation_plan"] = plan
+		}
+	}
+
+	// A scan that lists sinks is delivered to those in step 9 instead of
+	// the worker's integrations in steps 5, 6 and 8 (see sinks.go).
+	workerSinks := len(input.Sinks) == 0
+
+	// ─── Step 5: Forwarding ───
+	//
+	// Workers with a SIEM configured push the findings there; the report
+	// records how that went. Runs started before forwarding existed replay
+	// without it.
+	if workerSinks && changeVersion(ctx, changeForwardFindings) >= 1 {
+		if fwd := forwardFindings(ctx, report); fwd != nil {
+			final["forwarding"] = fwd
+		}
+	}
+
+	// ─── Step 6: Remediation issues ───
+	//
+	// Workers with Jira configured file one issue per team with failures;
+	// the report lists the issues.
+	if workerSinks && changeVersion(ctx, changeJiraIssues) >= 1 {
+		if issues := createJiraIssues(ctx, report); issues != nil {
+			final["remediation"] = issues
+		}
+	}
+
+	// ─── Step 7: Baseline and paging ───
+	//
+	// A full-org scan is compared with the org's last report, pages on-call
+	// if compliance regressed below the worker's threshold (or resolves the
+	// page once it recovers), and becomes the next scan's baseline. A scan
+	// that narrows the repo set covers part of the org, so it skips this;
+	// so does a partial report (cancelled, stopped at its deadline or out
+	// of API budget), whose rate says nothing about the repos it never
+	// scanned. Runs recorded before version 2 paged on partial reports and
+	// only kept a cancelled one out of the baseline; before version 3,
+	// only team and repo-list scans counted as narrowed.
+	v := changeVersion(ctx, changeScanHistory)
+	wholeOrg := len(input.Teams) == 0 && len(input.Repos) == 0
+	if v >= 3 {
+		wholeOrg = !input.narrowsRepos()
+	}
+	if v >= 1 && wholeOrg {
+		complete := !cancelRequested && !stoppedAtDeadline && !budgetExceeded
+		if complete || v < 2 {
+			baseline := loadBaseline(ctx, provider, input.Org)
+			if paging := triggerPagerDuty(ctx, report, baseline); paging != nil {
+				final["paging"] = paging
+			}
+			if !cancelRequested {
+				saveBaseline(ctx, provider, input.Org, final)
+			}
+		}
 	}
 
-	return report, nil
+	// ─── Step 8: Metrics ───
+	//
+	// Workers with Datadog configured graph the scan's numbers there.
+	if workerSinks && changeVersion(ctx, changeComplianceMetrics) >= 1 {
+		emitComplianceMetrics(ctx, report)
+	}
+
+	// ─── Step 9: Delivery ───
+	//
+	// The report, with the sections above, goes to every sink the scan
+	// lists; the delivery section says how each went. Runs recorded before
+	// sinks had none to list, so only scans with sinks take the version.
+	if !workerSinks && changeVersion(ctx, changeReportSinks) >= 1 {
+		final["delivery"] = deliverReport(ctx, input.Sinks, final)
+	}
+
+	return finish(final, nil)
+}
+
+// startProgressLoop logs progress and upserts ScanStatusKey, and
+// ScanInitiatedByKey when initiatedBy is set, every interval until the
+// returned func is called. The loop waits on a workflow timer, not
+// time.Sleep, so replays see the same ticks; stopping cancels the pending
+// timer and ends the goroutine.
+func startProgressLoop(ctx workflow.Context, interval time.Duration, progress *ScanProgress, initiatedBy string) workflow.CancelFunc {
+	loopCtx, stop := workflow.WithCancel(ctx)
+	workflow.Go(loopCtx, func(gCtx workflow.Context) {
+		logger := workflow.GetLogger(gCtx)
+		for {
+			if err := workflow.NewTimer(gCtx, interval).Get(gCtx, nil); err != nil {
+				return // stopped
+			}
+			logger.Info("Scan progress",
+				"status", progress.Status,
+				"scanned", progress.ScannedRepos,
+				"total", progress.TotalRepos,
+				"errors", progress.Errors,
+				"percent", fmt.Sprintf("%.1f", progress.PercentComplete()),
+			)
+			attrs := []temporal.SearchAttributeUpdate{ScanStatusKey.ValueSet(string(progress.Status))}
+			if initiatedBy != "" {
+				attrs = append(attrs, ScanInitiatedByKey.ValueSet(initiatedBy))
+			}
+			if err := workflow.UpsertTypedSearchAttributes(gCtx, attrs...); err != nil {
+				logger.Warn("Failed to upsert scan status", "error", err)
+			}
+		}
+	})
+	return stop
+}
+
+// cancelBatchChild sends cancel_scan to the running ScanBatchWorkflow, if
+// any, once it has started.
+func cancelBatchChild(ctx workflow.Context, child workflow.ChildWorkflowFuture, reason string) {
+	if child == nil {
+		return
+	}
+	if err := child.GetChildWorkflowExecution().Get(ctx, nil); err == nil {
+		_ = child.SignalChildWorkflow(ctx, "cancel_scan", reason).Get(ctx, nil)
+	}
+}
+
+// githubRetryPolicy is the retry policy for activities that call GitHub.
+func githubRetryPolicy() *temporal.RetryPolicy {
+	return &temporal.RetryPolicy{
+		InitialInterval:    2 * time.Second,
+		BackoffCoefficient: 2.0,
+		MaximumInterval:    60 * time.Second,
+		MaximumAttempts:    5,
+	}
+}
+
+// scanActivityOptions are the options for the per-repo check activities,
+// which do not heartbeat.
+func scanActivityOptions(timeouts ActivityTimeouts, retryPolicy *temporal.RetryPolicy) workflow.ActivityOptions {
+	return workflow.ActivityOptions{
+		StartToCloseTimeout:    timeouts.startToClose(),
+		ScheduleToCloseTimeout: timeouts.scheduleToClose(),
+		RetryPolicy:            retryPolicy,
+	}
 }
 
 // =============================================================================
@@ -0,0 +1,827 @@
+package scanner
+
+// =============================================================================
+// Workflow unit tests — Go vs Python
+// =============================================================================
+//
+// PYTHON (tests/test_workflow.py) uses WorkflowEnvironment.start_time_skipping()
+// and registers mock activities decorated with @activity.defn(name=...).
+//
+// GO uses testsuite.TestWorkflowEnvironment. It runs the workflow in-process
+// with a time-skipping clock, and activities are mocked per call with
+// env.OnActivity(...). No server, no worker, no network.
+// =============================================================================
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+// newTestEnv returns a test environment with the real activities registered,
+// so the workflow's string activity names resolve and can be mocked.
+func newTestEnv(t *testing.T) *testsuite.TestWorkflowEnvironment {
+	t.Helper()
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{})
+	mockActionsSecurity(env)
+	return env
+}
+
+// hardenedActions is what the default CheckActionsSecurity mock returns.
+var hardenedActions = &ActionsSecurity{
+	ActionsEnabled:             true,
+	AllowedActions:             AllowedActionsSelected,
+	DefaultWorkflowPermissions: WorkflowPermissionsRead,
+}
+
+// mockActionsSecurity reports every repo as hardenedActions. Tests that need
+// other settings build their own environment.
+func mockActionsSecurity(env *testsuite.TestWorkflowEnvironment) {
+	env.OnActivity("CheckActionsSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(hardenedActions, nil)
+}
+
+// fakeRepos builds n repositories named repo-000, repo-001, ...
+func fakeRepos(n int) []RepoInfo {
+	repos := make([]RepoInfo, n)
+	for i := range repos {
+		name := fmt.Sprintf("repo-%03d", i)
+		repos[i] = RepoInfo{Name: name, FullName: "acme/" + name}
+	}
+	return repos
+}
+
+// onListOrgRepos mocks FetchOrgReposPage to list repos in pages of 100.
+// The mock is a function, so it runs through the worker's interceptors.
+func onListOrgRepos(env *testsuite.TestWorkflowEnvironment, repos []RepoInfo) *testsuite.MockCallWrapper {
+	return env.OnActivity("FetchOrgReposPage", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, in RepoPageInput) (*RepoPage, error) {
+			start := (in.Page - 1) * 100
+			if start > len(repos) {
+				start = len(repos)
+			}
+			end := start + 100
+			if end > len(repos) {
+				end = len(repos)
+			}
+			return &RepoPage{Repos: repos[start:end], HasMore: end < len(repos)}, nil
+		})
+}
+
+// compliantUnless returns a CheckRepoSecurity mock that reports every repo as
+// fully compliant except those in nonCompliant.
+func compliantUnless(nonCompliant ...string) func(context.Context, string, string, *Credential, []string) (*RepoSecurityResult, error) {
+	skip := make(map[string]bool)
+	for _, r := range nonCompliant {
+		skip[r] = true
+	}
+	return func(_ context.Context, _, repoName string, _ *Credential, _ []string) (*RepoSecurityResult, error) {
+		r := &RepoSecurityResult{
+			Repository:       repoName,
+			SecretScanning:   StatusEnabled,
+			DependabotAlerts: StatusEnabled,
+			CodeScanning:     StatusEnabled,
+		}
+		if skip[repoName] {
+			r.CodeScanning = StatusNotConfigured
+		}
+		return r, nil
+	}
+}
+
+func TestWorkflowHappyPathAggregation(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "acme", mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("repo-002"))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, "acme", report["org"])
+	require.EqualValues(t, 3, report["total_repos"])
+	require.EqualValues(t, 2, report["fully_compliant"])
+	require.Equal(t, "66.7%", report["compliance_rate"])
+	require.EqualValues(t, 3, report["secret_scanning_enabled"])
+	require.EqualValues(t, 2, report["code_scanning_enabled"])
+	require.EqualValues(t, 0, report["errors"])
+	require.Equal(t, []interface{}{"repo-002"}, report["non_compliant_repos"])
+	require.NotContains(t, report, "cancelled")
+	require.NotContains(t, report, "access_audit")
+}
+
+func TestWorkflowCustomCompliancePolicy(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, "acme", mock.Anything, mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, org, repoName string, cred *Credential, checks []string) (*RepoSecurityResult, error) {
+			r, _ := compliantUnless()(ctx, org, repoName, cred, checks)
+			has := repoName != "repo-001"
+			r.HasCodeowners = &has
+			return r, nil
+		})
+
+	policy := DefaultCompliancePolicy()
+	policy.RequireCodeowners = true
+	checks := append(DefaultChecks(), CheckFiles)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Checks: checks, CompliancePolicy: &policy})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 2, report["fully_compliant"])
+	require.EqualValues(t, 2, report["codeowners_present"])
+	require.Equal(t, []interface{}{"repo-001"}, report["non_compliant_repos"])
+
+	val, err := env.QueryWorkflow("progress")
+	require.NoError(t, err)
+	var progress ScanProgress
+	require.NoError(t, val.Get(&progress))
+	require.Equal(t, 1, progress.NonCompliantRepos)
+}
+
+func TestWorkflowFinalStatus(t *testing.T) {
+	for _, tc := range []struct {
+		repos int
+		want  ScanStatus
+	}{{2, ScanCompleted}, {0, ScanEmpty}} {
+		env := newTestEnv(t)
+		onListOrgRepos(env, fakeRepos(tc.repos))
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(compliantUnless())
+
+		env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+		require.True(t, env.IsWorkflowCompleted())
+		require.NoError(t, env.GetWorkflowError())
+		val, err := env.QueryWorkflow("progress")
+		require.NoError(t, err)
+		var progress ScanProgress
+		require.NoError(t, val.Get(&progress))
+		require.Equal(t, tc.want, progress.Status)
+		require.True(t, progress.Status.IsTerminal())
+	}
+}
+
+func TestWorkflowActionsSecurity(t *testing.T) {
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{})
+	onListOrgRepos(env, fakeRepos(5))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	env.OnActivity("CheckActionsSecurity", mock.Anything, mock.Anything, "repo-000", mock.Anything).
+		Return(hardenedActions, nil)
+	env.OnActivity("CheckActionsSecurity", mock.Anything, mock.Anything, "repo-001", mock.Anything).
+		Return(&ActionsSecurity{ActionsEnabled: true, AllowedActions: AllowedActionsAll, DefaultWorkflowPermissions: WorkflowPermissionsWrite}, nil)
+	env.OnActivity("CheckActionsSecurity", mock.Anything, mock.Anything, "repo-002", mock.Anything).
+		Return(&ActionsSecurity{ActionsEnabled: false}, nil)
+	env.OnActivity("CheckActionsSecurity", mock.Anything, mock.Anything, "repo-003", mock.Anything).
+		Return(nil, temporal.NewNonRetryableApplicationError("boom", "TEST", nil))
+	env.OnActivity("CheckActionsSecurity", mock.Anything, mock.Anything, "repo-004", mock.Anything).
+		Return((*ActionsSecurity)(nil), nil)
+
+	policy := DefaultCompliancePolicy()
+	policy.RequireReadOnlyWorkflowToken = true
+	checks := append(DefaultChecks(), CheckActions)
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", Checks: checks, CompliancePolicy: &policy})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	// A failed or unreadable Actions check leaves the settings unknown; the
+	// repo still counts, as indeterminate.
+	require.EqualValues(t, 5, report["total_repos"])
+	require.EqualValues(t, 0, report["errors"])
+	require.EqualValues(t, 2, report["actions_enabled"])
+	require.EqualValues(t, 1, report["actions_restricted"])
+	require.EqualValues(t, 2, report["read_only_workflow_token"])
+	require.EqualValues(t, 2, report["fully_compliant"])
+	require.ElementsMatch(t, []interface{}{"repo-001"}, report["non_compliant_repos"])
+	require.ElementsMatch(t, []interface{}{"repo-003", "repo-004"}, report["indeterminate_repos"])
+	require.Equal(t, "66.7%", report["compliance_rate"])
+}
+
+func TestWorkflowResultsCarryRepoMetadata(t *testing.T) {
+	env := newTestEnv(t)
+	repos := fakeRepos(2)
+	repos[0].RepoMetadata = RepoMetadata{DefaultBranch: "main", Visibility: "internal", Language: "Go", Topics: []string{"payments"}}
+	onListOrgRepos(env, repos)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	val, err := env.QueryWorkflow("results_so_far")
+	require.NoError(t, err)
+	var results []RepoSecurityResult
+	require.NoError(t, val.Get(&results))
+	require.Len(t, results, 2)
+	byRepo := map[string]*RepoMetadata{}
+	for _, r := range results {
+		byRepo[r.Repository] = r.Metadata
+	}
+	require.Equal(t, &repos[0].RepoMetadata, byRepo["repo-000"])
+	require.Nil(t, byRepo["repo-001"], "no metadata, none attached")
+}
+
+func TestWorkflowSkipsInactiveRepos(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	ago := func(days int) *time.Time {
+		t := start.AddDate(0, 0, -days)
+		return &t
+	}
+	repos := fakeRepos(4)
+	repos[0].PushedAt = ago(1)
+	repos[1].PushedAt = ago(180) // exactly at the cutoff counts as active
+	repos[2].PushedAt = ago(181)
+	repos[3].PushedAt = nil // empty repo, never pushed
+
+	env := newTestEnv(t)
+	env.SetStartTime(start)
+	onListOrgRepos(env, repos)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ActiveWithinDays: 180})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 2, report["total_repos"])
+	require.EqualValues(t, 2, report["skipped_inactive"])
+	require.Equal(t, []interface{}{"repo-002", "repo-003"}, report["skipped_inactive_sample"])
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 2)
+}
+
+func TestWorkflowSkipsArchivedRepos(t *testing.T) {
+	repos := fakeRepos(4)
+	repos[1].Archived = true
+	repos[3].Archived = true
+
+	env := newTestEnv(t)
+	onListOrgRepos(env, repos)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("repo-003"))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 2)
+	val, err := env.QueryWorkflow("progress")
+	require.NoError(t, err)
+	var progress ScanProgress
+	require.NoError(t, val.Get(&progress))
+	require.Equal(t, 2, progress.TotalRepos, "archived repos are not in the progress total")
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 2, report["total_repos"])
+	require.EqualValues(t, 2, report["archived"])
+	require.Equal(t, []interface{}{"repo-001", "repo-003"}, report["archived_repos"])
+	require.Equal(t, "100.0%", report["compliance_rate"], "the non-compliant archived repo does not count")
+}
+
+func TestWorkflowIncludeArchivedFlagsResults(t *testing.T) {
+	repos := fakeRepos(3)
+	repos[1].Archived = true
+
+	env := newTestEnv(t)
+	onListOrgRepos(env, repos)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", IncludeArchived: true})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 3)
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 3, report["total_repos"])
+	require.NotContains(t, report, "archived_repos")
+
+	val, err := env.QueryWorkflow("results_so_far")
+	require.NoError(t, err)
+	var results []RepoSecurityResult
+	require.NoError(t, val.Get(&results))
+	archived := map[string]bool{}
+	for _, r := range results {
+		archived[r.Repository] = r.Archived
+	}
+	require.Equal(t, map[string]bool{"repo-000": false, "repo-001": true, "repo-002": false}, archived)
+}
+
+func TestWorkflowAccessAudit(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(4))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	env.OnActivity("AuditRepoAccess", mock.Anything, mock.Anything, "repo-000", mock.Anything, 30).
+		Return(&AccessAudit{DeployKeys: 1}, nil)
+	env.OnActivity("AuditRepoAccess", mock.Anything, mock.Anything, "repo-001", mock.Anything, 30).
+		Return(&AccessAudit{
+			DeployKeys:           2,
+			FlaggedDeployKeys:    []FlaggedDeployKey{{ID: 1, Title: "bot"}},
+			OutsideCollaborators: []OutsideCollaborator{{Login: "alice", Permission: "admin"}},
+		}, nil)
+	env.OnActivity("AuditRepoAccess", mock.Anything, mock.Anything, "repo-002", mock.Anything, 30).
+		Return(&AccessAudit{
+			DeployKeys:        1,
+			FlaggedDeployKeys: []FlaggedDeployKey{{ID: 2, Title: "old", ReadOnly: true, Stale: true}},
+			NoAccess:          []string{AccessCollaborators},
+		}, nil)
+	env.OnActivity("AuditRepoAccess", mock.Anything, mock.Anything, "repo-003", mock.Anything, 30).
+		Return(&AccessAudit{NoAccess: []string{AccessDeployKeys, AccessCollaborators}}, nil)
+
+	input := ScanInput{Org: "acme", IncludeAccessAudit: true, DeployKeyMaxAgeDays: 30}
+	env.ExecuteWorkflow(SecurityScanWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report struct {
+		EstimatedAPICalls int `json:"estimated_api_calls"`
+		AccessAudit       struct {
+			ReposAudited         int              `json:"repos_audited"`
+			ReposNoAccess        int              `json:"repos_no_access"`
+			DeployKeys           int              `json:"deploy_keys"`
+			FlaggedDeployKeys    int              `json:"flagged_deploy_keys"`
+			OutsideCollaborators int              `json:"outside_collaborators"`
+			WorstOffenders       []AccessOffender `json:"worst_offenders"`
+		} `json:"access_audit"`
+	}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 4*input.APICallsPerRepo(), report.EstimatedAPICalls)
+	require.Equal(t, 4, report.AccessAudit.ReposAudited)
+	require.Equal(t, 2, report.AccessAudit.ReposNoAccess)
+	require.Equal(t, 4, report.AccessAudit.DeployKeys)
+	require.Equal(t, 2, report.AccessAudit.FlaggedDeployKeys)
+	require.Equal(t, 1, report.AccessAudit.OutsideCollaborators)
+	require.Equal(t, []AccessOffender{
+		{Repository: "repo-001", FlaggedDeployKeys: 1, OutsideCollaborators: 1},
+		{Repository: "repo-002", FlaggedDeployKeys: 1},
+	}, report.AccessAudit.WorstOffenders)
+}
+
+func TestWorkflowCancelSignalBetweenBatches(t *testing.T) {
+	env := newTestEnv(t)
+	env.OnGetVersion(changeBatchCollection, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+	onListOrgRepos(env, fakeRepos(25))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(time.Minute).Return(compliantUnless())
+
+	// Arrives while the first batch is still running; a run from before
+	// batch-collection finishes that batch and stops before the second.
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("cancel_scan", "change freeze")
+	}, 30*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, true, report["cancelled"])
+	require.Equal(t, "change freeze", report["cancel_reason"])
+	require.EqualValues(t, 10, report["repos_scanned_before_cancel"])
+	require.EqualValues(t, 10, report["total_repos"])
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 10)
+}
+
+func TestWorkflowCancelStopsWaitingForBatch(t *testing.T) {
+	env := newTestEnv(t)
+	env.SetStartTime(time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC))
+	onListOrgRepos(env, fakeRepos(25))
+	for _, repo := range []string{"repo-000", "repo-001", "repo-002", "repo-003", "repo-004"} {
+		env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, repo, mock.Anything, mock.Anything).
+			Return(compliantUnless())
+	}
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(time.Hour).Return(compliantUnless())
+
+	cancelledActivities := 0
+	env.SetOnActivityCanceledListener(func(*activity.Info) { cancelledActivities++ })
+
+	// Arrives while half of the first batch is still running; the
+	// workflow stops waiting for it instead of sitting out the hour, and
+	// cancels the checks still running.
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("cancel_scan", "change freeze")
+	}, 30*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, true, report["cancelled"])
+	require.EqualValues(t, 5, report["repos_scanned_before_cancel"])
+	require.EqualValues(t, 5, report["total_repos"])
+	require.Equal(t, []interface{}{"repo-005", "repo-006", "repo-007", "repo-008", "repo-009"}, report["cancelled_in_flight_repos"])
+	require.Equal(t, "2026-03-02T14:00:30Z", report["completed_at"], "the workflow stopped waiting when cancelled")
+	val, err := env.QueryWorkflow("progress")
+	require.NoError(t, err)
+	var progress ScanProgress
+	require.NoError(t, val.Get(&progress))
+	require.Equal(t, 5, progress.CancelledInFlight)
+	require.Equal(t, ScanCancelled, progress.Status)
+	require.Equal(t, 5, cancelledActivities)
+	env.AssertNumberOfCalls(t, "CheckRepoSecurity", 10)
+}
+
+// runCancelledReportScan scans 25 repos, one of which errors, and cancels
+// after the first batch, so the report carries every kind of run metadata.
+func runCancelledReportScan(t *testing.T, reportVersion workflow.Version) map[string]interface{} {
+	t.Helper()
+	env := newTestEnv(t)
+	env.SetStartTime(time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC))
+	env.OnGetVersion("local-report", workflow.DefaultVersion, 1).Return(reportVersion)
+	// Lets the first batch finish, so the scan has results and an error.
+	env.OnGetVersion(changeBatchCollection, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+	onListOrgRepos(env, fakeRepos(25))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-003", mock.Anything, mock.Anything).
+		Return(nil, temporal.NewNonRetryableApplicationError("boom", "TEST", nil))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(time.Minute).Return(compliantUnless("repo-002"))
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("cancel_scan", "change freeze")
+	}, 30*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	return report
+}
+
+func TestWorkflowLocalReportMatchesActivityReport(t *testing.T) {
+	legacy := runCancelledReportScan(t, workflow.DefaultVersion)
+	local := runCancelledReportScan(t, 1)
+
+	require.Equal(t, legacy, local)
+	require.Equal(t, true, local["cancelled"])
+	require.Equal(t, "change freeze", local["cancel_reason"])
+	require.EqualValues(t, 9, local["repos_scanned_before_cancel"])
+	require.EqualValues(t, 1, local["errors"])
+	require.Equal(t, "2026-03-02T14:00:00Z", local["started_at"])
+}
+
+func TestWorkflowActivityErrorsCountedAsErrors(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(4))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-001", mock.Anything, mock.Anything).
+		Return(nil, temporal.NewNonRetryableApplicationError("boom", "TEST", nil))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.EqualValues(t, 1, report["errors"])
+	require.EqualValues(t, 3, report["total_repos"])
+	require.EqualValues(t, 3, report["fully_compliant"])
+}
+
+func TestWorkflowErrorsByCategory(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(5))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-001", mock.Anything, mock.Anything).
+		Return(nil, newCheckError(ErrorRateLimit, 403, nil, "reading repo repo-001: unexpected status 403"))
+	gone := &RepoSecurityResult{Repository: "repo-002"}
+	gone.setScanError(ScanError{Category: ErrorNotFound, Message: "Repository not found", HTTPStatus: 404})
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-002", mock.Anything, mock.Anything).
+		Return(gone, nil)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-003", mock.Anything, mock.Anything).
+		Return(nil, temporal.NewNonRetryableApplicationError("boom", "TEST", nil))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report Report
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 3, report.Errors)
+	require.Equal(t, map[string]int{"RATE_LIMIT": 1, "NOT_FOUND": 1, "UNKNOWN": 1}, report.ErrorsByCategory)
+	require.Equal(t, []string{"repo-001"}, report.RetryLaterRepos, "only the rate-limited repo is worth retrying")
+	require.NoError(t, report.Validate())
+}
+
+func TestWorkflowDegradedWhenEveryRepoErrors(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, temporal.NewNonRetryableApplicationError("bad credentials", "UNAUTHORIZED", nil))
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	err := env.GetWorkflowError()
+	require.Error(t, err)
+
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, ErrTypeScanDegraded, appErr.Type())
+
+	var partial map[string]interface{}
+	require.NoError(t, appErr.Details(&partial))
+	require.EqualValues(t, 3, partial["errors"])
+	require.EqualValues(t, 0, partial["total_repos"])
+}
+
+// progressLogger counts the progress loop's log lines.
+type progressLogger struct {
+	nopLogger
+	ticks int
+}
+
+func (l *progressLogger) Info(msg string, _ ...interface{}) {
+	if msg == "Scan progress" {
+		l.ticks++
+	}
+}
+
+func TestWorkflowProgressLoop(t *testing.T) {
+	tests := []struct {
+		name      string
+		cancelAt  time.Duration // 0: run to completion
+		wantTicks int
+	}{
+		// Three one-minute batches: ticks at 50s, 100s and 150s, then the
+		// loop is stopped at 180s before the report.
+		{name: "completed", wantTicks: 3},
+		// Cancelled during the first batch, which the scan then stops
+		// waiting for, before the first tick.
+		{name: "cancelled", cancelAt: 30 * time.Second, wantTicks: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &progressLogger{}
+			var s testsuite.WorkflowTestSuite
+			s.SetLogger(logger)
+			env := s.NewTestWorkflowEnvironment()
+			env.RegisterActivity(&Activities{})
+			mockActionsSecurity(env)
+			onListOrgRepos(env, fakeRepos(25))
+			env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				After(time.Minute).Return(compliantUnless())
+			env.OnUpsertTypedSearchAttributes(mock.Anything).Return(nil)
+			if tt.cancelAt > 0 {
+				env.RegisterDelayedCallback(func() {
+					env.SignalWorkflow("cancel_scan", "change freeze")
+				}, tt.cancelAt)
+			}
+
+			env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ProgressIntervalSeconds: 50})
+
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+			require.Equal(t, tt.wantTicks, logger.ticks)
+			env.AssertNumberOfCalls(t, "workflow.UpsertTypedSearchAttributes", tt.wantTicks)
+		})
+	}
+}
+
+func TestWorkflowQueriesMidRun(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(15))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(time.Minute).Return(compliantUnless("repo-004"))
+
+	// First batch finishes at 1m, second is still in flight at 90s.
+	queried := false
+	env.RegisterDelayedCallback(func() {
+		queried = true
+
+		val, err := env.QueryWorkflow("progress")
+		require.NoError(t, err)
+		var progress ScanProgress
+		require.NoError(t, val.Get(&progress))
+		require.Equal(t, ScanScanning, progress.Status)
+		require.Equal(t, 15, progress.TotalRepos)
+		require.Equal(t, 10, progress.ScannedRepos)
+		require.Equal(t, 9, progress.CompliantRepos)
+		require.Equal(t, 1, progress.NonCompliantRepos)
+		require.InDelta(t, 66.7, progress.PercentComplete(), 0.1)
+
+		val, err = env.QueryWorkflow("results_so_far")
+		require.NoError(t, err)
+		var results []RepoSecurityResult
+		require.NoError(t, val.Get(&results))
+		require.Len(t, results, 10)
+
+		val, err = env.QueryWorkflow("is_cancelled")
+		require.NoError(t, err)
+		var cancelled bool
+		require.NoError(t, val.Get(&cancelled))
+		require.False(t, cancelled)
+	}, 90*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.True(t, queried, "delayed query callback never ran")
+}
+
+func TestWorkflowBatchBoundaries(t *testing.T) {
+	for _, n := range []int{0, 5, 10, 11, 20} {
+		n := n
+		t.Run(fmt.Sprintf("%d_repos", n), func(t *testing.T) {
+			env := newTestEnv(t)
+			onListOrgRepos(env, fakeRepos(n))
+			env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				After(time.Minute).Return(compliantUnless())
+
+			env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+			env.AssertNumberOfCalls(t, "CheckRepoSecurity", n)
+
+			var report map[string]interface{}
+			require.NoError(t, env.GetWorkflowResult(&report))
+			require.EqualValues(t, n, report["total_repos"])
+			require.EqualValues(t, n, report["fully_compliant"])
+
+			// Each batch of up to 10 runs concurrently, so elapsed workflow
+			// time is one activity duration per batch.
+			batches := (n + 9) / 10
+			var progress ScanProgress
+			val, err := env.QueryWorkflow("progress")
+			require.NoError(t, err)
+			require.NoError(t, val.Get(&progress))
+			require.Equal(t, time.Duration(batches)*time.Minute, progress.CompletedAt.Sub(progress.StartedAt))
+		})
+	}
+}
+
+// newTestEnvWithBlobStore is newTestEnv with a FileBlobStore in a temp dir,
+// so StoreResults and GenerateReport run for real against the claim checks.
+func newTestEnvWithBlobStore(t *testing.T) *testsuite.TestWorkflowEnvironment {
+	t.Helper()
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{BlobStore: &FileBlobStore{Dir: t.TempDir()}})
+	mockActionsSecurity(env)
+	return env
+}
+
+// offloadReport is the subset of the report the claim-check tests inspect.
+type offloadReport struct {
+	TotalRepos     int       `json:"total_repos"`
+	FullyCompliant int       `json:"fully_compliant"`
+	Refs           []BlobRef `json:"results_blob_refs"`
+}
+
+func TestWorkflowOffloadsResultsPastThreshold(t *testing.T) {
+	env := newTestEnvWithBlobStore(t)
+	onListOrgRepos(env, fakeRepos(25))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless("repo-007"))
+
+	// A full batch of 10 results is ~1.75 KB, the final batch of 5 is not.
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ResultsOffloadBytes: 1500})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report offloadReport
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 25, report.TotalRepos)
+	require.Equal(t, 24, report.FullyCompliant)
+	require.Len(t, report.Refs, 2)
+	for _, ref := range report.Refs {
+		require.Equal(t, 10, ref.Count)
+		require.NotEmpty(t, ref.SHA256)
+	}
+
+	// Only the last batch is still held inline.
+	val, err := env.QueryWorkflow("results_so_far")
+	require.NoError(t, err)
+	var inline []RepoSecurityResult
+	require.NoError(t, val.Get(&inline))
+	require.Len(t, inline, 5)
+}
+
+func TestWorkflowKeepsResultsInlineBelowThreshold(t *testing.T) {
+	for name, limit := range map[string]int{"default": 0, "disabled": -1} {
+		limit := limit
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnvWithBlobStore(t)
+			onListOrgRepos(env, fakeRepos(25))
+			env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(compliantUnless())
+
+			env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ResultsOffloadBytes: limit})
+
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+
+			var report map[string]interface{}
+			require.NoError(t, env.GetWorkflowResult(&report))
+			require.EqualValues(t, 25, report["total_repos"])
+			require.NotContains(t, report, "results_blob_refs")
+		})
+	}
+}
+
+func TestWorkflowOffloadsLargeResultsAtDefaultThreshold(t *testing.T) {
+	env := newTestEnvWithBlobStore(t)
+	onListOrgRepos(env, fakeRepos(1500))
+	// ~1 KB per result pushes 1,500 repos well past DefaultResultsOffloadBytes.
+	padding := strings.Repeat("x", 1000)
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(func(_ context.Context, _, repoName string, _ *Credential, _ []string) (*RepoSecurityResult, error) {
+			return &RepoSecurityResult{
+				Repository:       repoName + "-" + padding,
+				SecretScanning:   StatusEnabled,
+				DependabotAlerts: StatusEnabled,
+				CodeScanning:     StatusEnabled,
+			}, nil
+		})
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var report offloadReport
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, 1500, report.TotalRepos)
+	require.Equal(t, 1500, report.FullyCompliant)
+	require.NotEmpty(t, report.Refs)
+
+	offloaded := 0
+	for _, ref := range report.Refs {
+		offloaded += ref.Count
+		// A chunk never exceeds the threshold by more than one batch.
+		require.Less(t, ref.Bytes, DefaultResultsOffloadBytes+20*1024)
+	}
+
+	val, err := env.QueryWorkflow("results_so_far")
+	require.NoError(t, err)
+	var inline []RepoSecurityResult
+	require.NoError(t, val.Get(&inline))
+	require.Equal(t, 1500, offloaded+len(inline))
+}
+
+func TestWorkflowOffloadWithoutBlobStoreKeepsResultsInline(t *testing.T) {
+	env := newTestEnv(t)
+	onListOrgRepos(env, fakeRepos(25))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+	stored := 0
+	env.OnActivity("StoreResults", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { stored++ }).
+		Return(nil, nil)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme", ResultsOffloadBytes: 1})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, 1, stored, "a worker without a blob store is asked once")
+
+	val, err := env.QueryWorkflow("results_so_far")
+	require.NoError(t, err)
+	var inline []RepoSecurityResult
+	require.NoError(t, val.Get(&inline))
+	require.Len(t, inline, 25)
+}
@@ -0,0 +1,110 @@
+package scanner
+
+// =============================================================================
+// Workflow IDs — one scheme for every caller that starts a scan
+// =============================================================================
+//
+// A scan's workflow ID is security-scan-<org>, optionally followed by
+// /-separated parts: /repos-<hash> for an explicit repo list, /teams-<slugs>
+// for a team scan, and a free-form suffix such as a date. Two scans with the
+// same ID replace each other, so anything that must not collide with the
+// plain ad-hoc scan of an org (a scheduled run, a repo-list or team scan)
+// adds a part. The org is always the text before the first /, so it can be
+// read back with WorkflowIDOrg. Scans of another provider prefix it with the
+// provider and a colon, and write the /s of a nested GitLab group as colons:
+// security-scan-gitlab:acme:platform.
+// =============================================================================
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// workflowIDPrefix starts every SecurityScanWorkflow ID.
+const workflowIDPrefix = "security-scan-"
+
+// WorkflowIDOption adds a part to a ScanWorkflowID.
+type WorkflowIDOption func(*workflowIDParts)
+
+type workflowIDParts struct {
+	provider string
+	repos    []string
+	teams    []string
+	suffix   string
+}
+
+// WithProvider marks the ID as a scan of another provider than GitHub, so
+// a GitLab group and a GitHub org of the same name get different IDs.
+func WithProvider(provider string) WorkflowIDOption {
+	return func(p *workflowIDParts) { p.provider = provider }
+}
+
+// WithRepoList marks the ID as a scan of exactly these repos, so different
+// lists get different IDs.
+func WithRepoList(repos []string) WorkflowIDOption {
+	return func(p *workflowIDParts) { p.repos = repos }
+}
+
+// WithTeams marks the ID as a scan of these teams' repos, e.g.
+// security-scan-acme/teams-platform+payments.
+func WithTeams(teams []string) WorkflowIDOption {
+	return func(p *workflowIDParts) { p.teams = teams }
+}
+
+// WithSuffix appends a caller-chosen suffix, e.g. "nightly".
+func WithSuffix(suffix string) WorkflowIDOption {
+	return func(p *workflowIDParts) { p.suffix = suffix }
+}
+
+// WithStartTime appends t as a UTC timestamp, making the ID unique per
+// start (to the second).
+func WithStartTime(t time.Time) WorkflowIDOption {
+	return WithSuffix(t.UTC().Format("20060102T150405Z"))
+}
+
+// ScanWorkflowID returns the workflow ID for a scan of org.
+func ScanWorkflowID(org string, opts ...WorkflowIDOption) string {
+	var p workflowIDParts
+	for _, opt := range opts {
+		opt(&p)
+	}
+	id := workflowIDPrefix + org
+	if provider := providerName(p.provider); provider != ProviderGitHub {
+		id = workflowIDPrefix + provider + ":" + strings.ReplaceAll(org, "/", ":")
+	}
+	if len(p.repos) > 0 {
+		id += "/repos-" + RepoListHash(p.repos)
+	}
+	if len(p.teams) > 0 {
+		teams := make([]string, len(p.teams))
+		for i, t := range p.teams {
+			teams[i] = strings.ToLower(t)
+		}
+		sort.Strings(teams)
+		id += "/teams-" + strings.Join(teams, "+")
+	}
+	if p.suffix != "" {
+		id += "/" + p.suffix
+	}
+	return id
+}
+
+// WorkflowIDOrg returns the org of a ScanWorkflowID, or "" if id is not one.
+func WorkflowIDOrg(id string) string {
+	_, org := WorkflowIDProvider(id)
+	return org
+}
+
+// WorkflowIDProvider returns the provider and org of a ScanWorkflowID, or
+// two empty strings if id is not one.
+func WorkflowIDProvider(id string) (provider, org string) {
+	if !strings.HasPrefix(id, workflowIDPrefix) {
+		return "", ""
+	}
+	org, _, _ = strings.Cut(strings.TrimPrefix(id, workflowIDPrefix), "/")
+	if provider, group, ok := strings.Cut(org, ":"); ok {
+		return provider, strings.ReplaceAll(group, ":", "/")
+	}
+	return ProviderGitHub, org
+}
@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanWorkflowID(t *testing.T) {
+	repos := []string{"acme/api", "acme/web"}
+	start := time.Date(2026, 3, 2, 9, 30, 0, 0, time.FixedZone("PST", -8*3600))
+
+	for _, tt := range []struct {
+		id   string
+		want string
+	}{
+		{ScanWorkflowID("acme"), "security-scan-acme"},
+		{ScanWorkflowID("acme", WithSuffix("nightly")), "security-scan-acme/nightly"},
+		{ScanWorkflowID("acme", WithStartTime(start)), "security-scan-acme/20260302T173000Z"},
+		{ScanWorkflowID("acme", WithRepoList(repos)), "security-scan-acme/repos-" + RepoListHash(repos)},
+		{ScanWorkflowID("acme", WithRepoList(nil)), "security-scan-acme"},
+		{ScanWorkflowID("acme", WithTeams([]string{"Platform", "payments"})), "security-scan-acme/teams-payments+platform"},
+		{
+			ScanWorkflowID("acme", WithRepoList(repos), WithSuffix("nightly")),
+			"security-scan-acme/repos-" + RepoListHash(repos) + "/nightly",
+		},
+	} {
+		require.Equal(t, tt.want, tt.id)
+		require.Equal(t, "acme", WorkflowIDOrg(tt.id))
+	}
+
+	require.Equal(t, "my-org", WorkflowIDOrg(ScanWorkflowID("my-org", WithSuffix("adhoc"))))
+	require.Equal(t, "", WorkflowIDOrg("some-other-workflow"))
+}
+
+func TestScanWorkflowIDProvider(t *testing.T) {
+	require.Equal(t, "security-scan-acme", ScanWorkflowID("acme", WithProvider(ProviderGitHub)))
+
+	id := ScanWorkflowID("acme/platform", WithProvider(ProviderGitLab), WithSuffix("nightly"))
+	require.Equal(t, "security-scan-gitlab:acme:platform/nightly", id)
+	provider, org := WorkflowIDProvider(id)
+	require.Equal(t, ProviderGitLab, provider)
+	require.Equal(t, "acme/platform", org)
+	require.Equal(t, "acme/platform", WorkflowIDOrg(id))
+
+	provider, org = WorkflowIDProvider(ScanWorkflowID("acme"))
+	require.Equal(t, ProviderGitHub, provider)
+	require.Equal(t, "acme", org)
+}
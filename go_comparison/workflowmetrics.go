@@ -0,0 +1,247 @@
+package scanner
+
+// =============================================================================
+// Workflow metrics — scan progress through the SDK's metrics handler
+// =============================================================================
+//
+// SecurityScanWorkflow counts its progress with workflow.GetMetricsHandler,
+// tagged with the org, so the numbers belong to the scan rather than to
+// the activities of whichever worker ran them:
+//
+//	scanner_workflow_batches_completed    batches finished, a cancelled one included
+//	scanner_workflow_repos_scanned        repos checked
+//	scanner_workflow_repo_errors          repos that could not be checked, tagged category
+//	scanner_workflow_cancellations        cancel_scan signals received
+//	scanner_workflow_repos_per_minute     repos checked or errored per minute since the start
+//
+// The SDK's handler drops what a workflow emits while it replays, so the
+// calls need no version gate: they write nothing to history, and a replay
+// does not count a repo twice.
+//
+// PromMetricsHandler is a client.MetricsHandler for the worker's
+// /metrics: set as the client's MetricsHandler, it collects these and the
+// SDK's own metrics (temporal_*) and writes them as Prometheus text with
+// the rest of the worker's numbers.
+// =============================================================================
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Workflow metric names.
+const (
+	MetricWorkflowBatches       = "scanner_workflow_batches_completed"
+	MetricWorkflowReposScanned  = "scanner_workflow_repos_scanned"
+	MetricWorkflowRepoErrors    = "scanner_workflow_repo_errors"
+	MetricWorkflowCancellations = "scanner_workflow_cancellations"
+	MetricWorkflowReposPerMin   = "scanner_workflow_repos_per_minute"
+)
+
+// MetricTagCategory is the tag of MetricWorkflowRepoErrors with the
+// error's ErrorCategory.
+const MetricTagCategory = "category"
+
+// workflowMetricHelp is the HELP text of the workflow metrics.
+var workflowMetricHelp = map[string]string{
+	MetricWorkflowBatches:       "Batches the scan workflow finished, a cancelled one included.",
+	MetricWorkflowReposScanned:  "Repos the scan workflow checked.",
+	MetricWorkflowRepoErrors:    "Repos the scan workflow could not check, by error category.",
+	MetricWorkflowCancellations: "cancel_scan signals the scan workflow received.",
+	MetricWorkflowReposPerMin:   "Repos checked or errored per minute since the scan started.",
+}
+
+// scanMetrics emits a scan's workflow metrics.
+type scanMetrics struct {
+	handler   client.MetricsHandler
+	startedAt time.Time
+}
+
+// newScanMetrics tags the workflow's metrics handler with org.
+func newScanMetrics(ctx workflow.Context, org string, startedAt time.Time) *scanMetrics {
+	return &scanMetrics{
+		handler:   workflow.GetMetricsHandler(ctx).WithTags(map[string]string{MetricTagOrg: org}),
+		startedAt: startedAt,
+	}
+}
+
+func (m *scanMetrics) repoScanned() {
+	m.handler.Counter(MetricWorkflowReposScanned).Inc(1)
+}
+
+func (m *scanMetrics) repoErrored(category ErrorCategory) {
+	m.handler.WithTags(map[string]string{MetricTagCategory: string(category)}).Counter(MetricWorkflowRepoErrors).Inc(1)
+}
+
+func (m *scanMetrics) cancelReceived() {
+	m.handler.Counter(MetricWorkflowCancellations).Inc(1)
+}
+
+// batchDone counts a batch and updates the rate from the repos progress
+// has checked or errored so far. The rate is left alone until workflow
+// time has passed.
+func (m *scanMetrics) batchDone(ctx workflow.Context, progress ScanProgress) {
+	m.handler.Counter(MetricWorkflowBatches).Inc(1)
+	if elapsed := workflow.Now(ctx).Sub(m.startedAt); elapsed > 0 {
+		done := progress.ScannedRepos + progress.Errors
+		m.handler.Gauge(MetricWorkflowReposPerMin).Update(float64(done) / elapsed.Minutes())
+	}
+}
+
+// promNameInvalid is what promName replaces.
+var promNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+// PromMetricsHandler is a client.MetricsHandler that keeps counters,
+// gauges and timers in memory for WritePrometheus. Timers are written as
+// summaries in seconds. The handlers WithTags returns share the same
+// metrics.
+type PromMetricsHandler struct {
+	store *promStore
+	tags  map[string]string
+}
+
+// promStore holds the metrics of a PromMetricsHandler, by name and then
+// by their sorted tags.
+type promStore struct {
+	mu      sync.Mutex
+	kinds   map[string]string
+	samples map[string]map[string]*promSeries
+}
+
+// promSeries is one metric's value for one set of tags; count is a
+// timer's number of samples.
+type promSeries struct {
+	labels []string
+	value  float64
+	count  float64
+}
+
+// NewPromMetricsHandler returns a handler with no metrics.
+func NewPromMetricsHandler() *PromMetricsHandler {
+	return &PromMetricsHandler{store: &promStore{
+		kinds:   map[string]string{},
+		samples: map[string]map[string]*promSeries{},
+	}}
+}
+
+// WithTags returns a handler that adds tags to those of h.
+func (h *PromMetricsHandler) WithTags(tags map[string]string) client.MetricsHandler {
+	merged := make(map[string]string, len(h.tags)+len(tags))
+	for k, v := range h.tags {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return &PromMetricsHandler{store: h.store, tags: merged}
+}
+
+// Counter returns the counter name.
+func (h *PromMetricsHandler) Counter(name string) client.MetricsCounter {
+	return counterFunc(func(d int64) {
+		h.store.update(name, "counter", h.tags, func(s *promSeries) { s.value += float64(d) })
+	})
+}
+
+// Gauge returns the gauge name.
+func (h *PromMetricsHandler) Gauge(name string) client.MetricsGauge {
+	return gaugeFunc(func(v float64) {
+		h.store.update(name, "gauge", h.tags, func(s *promSeries) { s.value = v })
+	})
+}
+
+// Timer returns the timer name.
+func (h *PromMetricsHandler) Timer(name string) client.MetricsTimer {
+	return timerFunc(func(d time.Duration) {
+		h.store.update(name, "summary", h.tags, func(s *promSeries) {
+			s.value += d.Seconds()
+			s.count++
+		})
+	})
+}
+
+type counterFunc func(int64)
+
+func (f counterFunc) Inc(d int64) { f(d) }
+
+type gaugeFunc func(float64)
+
+func (f gaugeFunc) Update(v float64) { f(v) }
+
+type timerFunc func(time.Duration)
+
+func (f timerFunc) Record(d time.Duration) { f(d) }
+
+// update applies fn to the series of name and tags. Names and tag keys
+// the exposition format does not allow have the offending characters
+// replaced; a name used before as another kind of metric is ignored.
+func (s *promStore) update(name, kind string, tags map[string]string, fn func(*promSeries)) {
+	name = promNameInvalid.ReplaceAllString(name, "_")
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var labels []string
+	for _, k := range keys {
+		label := strings.ReplaceAll(promNameInvalid.ReplaceAllString(k, "_"), ":", "_")
+		labels = append(labels, strings.TrimLeft(label, "_"), tags[k])
+	}
+	key := strings.Join(labels, "\x00")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k, ok := s.kinds[name]; ok && k != kind {
+		return
+	}
+	s.kinds[name] = kind
+	series := s.samples[name]
+	if series == nil {
+		series = map[string]*promSeries{}
+		s.samples[name] = series
+	}
+	if series[key] == nil {
+		series[key] = &promSeries{labels: labels}
+	}
+	fn(series[key])
+}
+
+// WritePrometheus writes the handler's metrics to w in the Prometheus text
+// exposition format, sorted by name and then by tags.
+func (h *PromMetricsHandler) WritePrometheus(w io.Writer) error {
+	return writePromGauges(w, h.promGauges())
+}
+
+func (h *PromMetricsHandler) promGauges() []promGauge {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	var gauges []promGauge
+	for _, name := range sortedKeys(h.store.samples) {
+		kind := h.store.kinds[name]
+		help := workflowMetricHelp[name]
+		if help == "" {
+			help = "Reported through the Temporal SDK's metrics handler."
+		}
+		g := promGauge{name: name, help: help, kind: kind}
+		series := h.store.samples[name]
+		for _, key := range sortedKeys(series) {
+			s := series[key]
+			if kind == "summary" {
+				g.samples = append(g.samples,
+					promSample{labels: s.labels, value: s.value, suffix: "_sum"},
+					promSample{labels: s.labels, value: s.count, suffix: "_count"})
+				continue
+			}
+			g.samples = append(g.samples, promSample{labels: s.labels, value: s.value})
+		}
+		gauges = append(gauges, g)
+	}
+	return gauges
+}
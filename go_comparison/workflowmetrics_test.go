@@ -0,0 +1,185 @@
+package scanner
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// capturingMetrics records what is emitted through it, by metric name and
+// then by its tags as "k=v,...".
+type capturingMetrics struct {
+	mu       *sync.Mutex
+	tags     map[string]string
+	counters map[string]map[string]int64
+	gauges   map[string]map[string]float64
+}
+
+func newCapturingMetrics() *capturingMetrics {
+	return &capturingMetrics{
+		mu:       &sync.Mutex{},
+		counters: map[string]map[string]int64{},
+		gauges:   map[string]map[string]float64{},
+	}
+}
+
+func (c *capturingMetrics) key() string {
+	var parts []string
+	for _, k := range sortedKeys(c.tags) {
+		parts = append(parts, k+"="+c.tags[k])
+	}
+	return strings.Join(parts, ",")
+}
+
+func (c *capturingMetrics) WithTags(tags map[string]string) client.MetricsHandler {
+	merged := map[string]string{}
+	for k, v := range c.tags {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	child := *c
+	child.tags = merged
+	return &child
+}
+
+func (c *capturingMetrics) Counter(name string) client.MetricsCounter {
+	return counterFunc(func(d int64) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.counters[name] == nil {
+			c.counters[name] = map[string]int64{}
+		}
+		c.counters[name][c.key()] += d
+	})
+}
+
+func (c *capturingMetrics) Gauge(name string) client.MetricsGauge {
+	return gaugeFunc(func(v float64) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.gauges[name] == nil {
+			c.gauges[name] = map[string]float64{}
+		}
+		c.gauges[name][c.key()] = v
+	})
+}
+
+func (c *capturingMetrics) Timer(string) client.MetricsTimer {
+	return timerFunc(func(time.Duration) {})
+}
+
+func TestWorkflowMetricNames(t *testing.T) {
+	// Dashboards and alerts query these; renaming one breaks them.
+	require.Equal(t, []string{
+		"scanner_workflow_batches_completed",
+		"scanner_workflow_repos_scanned",
+		"scanner_workflow_repo_errors",
+		"scanner_workflow_cancellations",
+		"scanner_workflow_repos_per_minute",
+	}, []string{
+		MetricWorkflowBatches, MetricWorkflowReposScanned, MetricWorkflowRepoErrors,
+		MetricWorkflowCancellations, MetricWorkflowReposPerMin,
+	})
+	for name := range workflowMetricHelp {
+		require.Regexp(t, promMetricName, name)
+	}
+}
+
+func TestWorkflowMetricsCancelledScan(t *testing.T) {
+	metrics := newCapturingMetrics()
+	var s testsuite.WorkflowTestSuite
+	s.SetMetricsHandler(metrics)
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{})
+	mockActionsSecurity(env)
+	env.SetStartTime(time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC))
+
+	// 25 repos in batches of 10, one of which errors. The first batch
+	// takes a minute; the cancellation arrives half way into the second.
+	onListOrgRepos(env, fakeRepos(25))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, "repo-003", mock.Anything, mock.Anything).
+		Return(nil, temporal.NewNonRetryableApplicationError("not found", "NOT_FOUND", nil))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		After(time.Minute).Return(compliantUnless())
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("cancel_scan", "change freeze")
+	}, 90*time.Second)
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&report))
+	require.Equal(t, true, report["cancelled"])
+
+	org := "org=acme"
+	require.Equal(t, map[string]int64{org: 2}, metrics.counters[MetricWorkflowBatches], "the first batch and the one the cancellation cut short")
+	require.Equal(t, map[string]int64{org: 9}, metrics.counters[MetricWorkflowReposScanned])
+	require.Equal(t, map[string]int64{"category=NOT_FOUND," + org: 1}, metrics.counters[MetricWorkflowRepoErrors])
+	require.Equal(t, map[string]int64{org: 1}, metrics.counters[MetricWorkflowCancellations])
+	// Ten repos done by the second batch's end, a minute and a half in.
+	require.InDelta(t, 10/1.5, metrics.gauges[MetricWorkflowReposPerMin][org], 1e-9)
+}
+
+func TestPromMetricsHandler(t *testing.T) {
+	h := NewPromMetricsHandler()
+	acme := h.WithTags(map[string]string{MetricTagOrg: "acme"})
+	acme.Counter(MetricWorkflowReposScanned).Inc(3)
+	acme.Counter(MetricWorkflowReposScanned).Inc(2)
+	h.WithTags(map[string]string{MetricTagOrg: `say "hi"`}).Counter(MetricWorkflowReposScanned).Inc(1)
+	acme.Gauge(MetricWorkflowReposPerMin).Update(4)
+	acme.Gauge(MetricWorkflowReposPerMin).Update(2.5)
+	sdk := h.WithTags(map[string]string{"namespace": "default", "task-queue": "scans"})
+	sdk.Timer("temporal_activity_execution_latency").Record(1500 * time.Millisecond)
+	sdk.Timer("temporal_activity_execution_latency").Record(500 * time.Millisecond)
+	sdk.Counter("temporal.request").Inc(1)
+	// A name reused as another kind of metric is ignored.
+	acme.Gauge(MetricWorkflowReposScanned).Update(100)
+
+	var b strings.Builder
+	require.NoError(t, h.WritePrometheus(&b))
+	require.Equal(t, `# HELP scanner_workflow_repos_per_minute Repos checked or errored per minute since the scan started.
+# TYPE scanner_workflow_repos_per_minute gauge
+scanner_workflow_repos_per_minute{org="acme"} 2.5
+# HELP scanner_workflow_repos_scanned Repos the scan workflow checked.
+# TYPE scanner_workflow_repos_scanned counter
+scanner_workflow_repos_scanned{org="acme"} 5
+scanner_workflow_repos_scanned{org="say \"hi\""} 1
+# HELP temporal_activity_execution_latency Reported through the Temporal SDK's metrics handler.
+# TYPE temporal_activity_execution_latency summary
+temporal_activity_execution_latency_sum{namespace="default",task_queue="scans"} 2
+temporal_activity_execution_latency_count{namespace="default",task_queue="scans"} 2
+# HELP temporal_request Reported through the Temporal SDK's metrics handler.
+# TYPE temporal_request counter
+temporal_request{namespace="default",task_queue="scans"} 1
+`, b.String())
+}
+
+func TestPromMetricsHandlerFromWorkflow(t *testing.T) {
+	h := NewPromMetricsHandler()
+	var s testsuite.WorkflowTestSuite
+	s.SetMetricsHandler(h)
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{})
+	mockActionsSecurity(env)
+	onListOrgRepos(env, fakeRepos(3))
+	env.OnActivity("CheckRepoSecurity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(compliantUnless())
+
+	env.ExecuteWorkflow(SecurityScanWorkflow, ScanInput{Org: "acme"})
+	require.NoError(t, env.GetWorkflowError())
+
+	var b strings.Builder
+	require.NoError(t, h.WritePrometheus(&b))
+	require.Contains(t, b.String(), "scanner_workflow_repos_scanned{org=\"acme\"} 3\n")
+	require.Contains(t, b.String(), "scanner_workflow_batches_completed{org=\"acme\"} 1\n")
+}